@@ -0,0 +1,1347 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/asn"
+	"github.com/aftermath2/acceptlnd/catalog"
+	"github.com/aftermath2/acceptlnd/chain"
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/flood"
+	"github.com/aftermath2/acceptlnd/graph"
+	"github.com/aftermath2/acceptlnd/hook"
+	"github.com/aftermath2/acceptlnd/lightning"
+	"github.com/aftermath2/acceptlnd/metrics"
+	"github.com/aftermath2/acceptlnd/policy"
+	"github.com/aftermath2/acceptlnd/reachability"
+	"github.com/aftermath2/acceptlnd/registry"
+	"github.com/aftermath2/acceptlnd/reputation"
+	"github.com/aftermath2/acceptlnd/store"
+	"github.com/aftermath2/acceptlnd/webhook"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// selfServicesLabel identifies the requests accepted for coming from the operator's own services.
+const selfServicesLabel = "self_services"
+
+// chainLabel identifies the chained channel acceptor in the decisions it took part in.
+const chainLabel = "chain"
+
+// limitsLabel identifies the requests decided for exceeding the limits.
+const limitsLabel = "limits"
+
+// staleGraphLabel identifies the requests decided because LND's graph was out of sync.
+const staleGraphLabel = "stale_graph"
+
+// sweepConfTarget is the number of blocks the fee rate used to estimate the cost of sweeping the
+// channels is expected to confirm in.
+const sweepConfTarget = 6
+
+// errInternal is returned when the request can't be evaluated as LND failed to provide the
+// information needed.
+var errInternal = errors.New("Internal server error")
+
+// overflowMessage is the error returned to the peers whose requests are rejected because too many
+// are being evaluated.
+const overflowMessage = "Too many requests, try again later"
+
+// acceptor handles the channel requests received from the lightning node.
+type acceptor struct {
+	client       lightning.Client
+	db           store.Storage
+	policies     atomic.Pointer[[]*policy.Policy]
+	selfServices atomic.Pointer[[]string]
+	network      atomic.Pointer[neighborhood]
+	// nodeInfo is nil until our node information is fetched or after it's invalidated.
+	nodeInfo atomic.Pointer[cachedNodeInfo]
+	// experiment is nil if there is no experiment running.
+	experiment atomic.Pointer[config.Experiment]
+	random     *randomness
+	// language of the rejection reasons sent to the peers and the webhook.
+	language string
+	// graphSnapshotPath is where the graph snapshots are saved, empty if they aren't.
+	graphSnapshotPath string
+	// slots limits the number of requests evaluated concurrently.
+	slots          chan struct{}
+	rejectOverflow bool
+	// flood is nil if the flood protection is disabled.
+	flood *flood.Detector
+	// autoBlock is nil if the nodes rejected repeatedly are not blocked.
+	autoBlock *config.AutoBlocklist
+	// chain is nil if there isn't another channel acceptor chained.
+	chain    *chain.Server
+	torExits map[netip.Addr]struct{}
+	// asns is nil if the autonomous systems of the peers are unknown.
+	asns    *asn.Database
+	origins peerOrigins
+	// webhook is nil if the decisions are not posted anywhere.
+	webhook *webhook.Dispatcher
+	// slo is nil if the response times are not tracked against an objective.
+	slo *sloTracker
+	// precomputed is nil if the information of the peers is not loaded ahead of time.
+	precomputed *peerCache
+	// acceptHook is nil if no command is run for the accepted channels.
+	acceptHook *hook.Runner
+	// limits is nil if they are disabled.
+	limits *config.Limits
+	// staleGraph is nil if the requests are evaluated regardless of the graph sync.
+	staleGraph *config.StaleGraph
+	// resubscribe is nil if the channel acceptor isn't registered again after LND restarts.
+	resubscribe *config.Resubscribe
+	// registered is the last time the channel acceptor was registered, in unix nanoseconds.
+	registered atomic.Int64
+	// graphSynced is the last time LND reported being synced to the graph, in unix nanoseconds.
+	// It's initialized to the start time so LND has time to sync after a restart.
+	graphSynced atomic.Int64
+	// stats counts the decisions taken since the start, for the node platforms.
+	stats decisionStats
+	// registry identifies the well-known services among the peers.
+	registry *registry.Registry
+	// reachability is nil if the peers addresses can't be tested.
+	reachability *reachability.Checker
+	halfLife     time.Duration
+	// timeout is LND's channel acceptor timeout, zero if it must be read from LND.
+	timeout time.Duration
+	// detectGaps enables the detection of the channels opened without a decision, disabled in
+	// watch-only mode as no requests are decided.
+	detectGaps bool
+	// labelChannels labels the funding transactions of the channels once they are tagged.
+	labelChannels bool
+	// active and lastForwards are only used by the channels monitor to detect flapping channels
+	// and new forwards.
+	active       map[string]bool
+	lastForwards time.Time
+}
+
+// neighborhood contains the nodes around ours in the channel graph.
+type neighborhood struct {
+	peers map[string]struct{}
+	reach map[string]struct{}
+}
+
+// newAcceptor returns a new channel requests handler, the database is optional.
+func newAcceptor(client lightning.Client, db store.Storage, config config.Config) *acceptor {
+	maxEvaluations := config.MaxConcurrentEvaluations
+	if maxEvaluations <= 0 {
+		maxEvaluations = 1
+	}
+
+	a := &acceptor{
+		client:            client,
+		db:                db,
+		slots:             make(chan struct{}, maxEvaluations),
+		rejectOverflow:    config.OverflowAction == "reject",
+		halfLife:          config.ReputationHalfLife,
+		timeout:           config.AcceptorTimeout,
+		graphSnapshotPath: config.GraphSnapshotPath,
+		active:            make(map[string]bool),
+		lastForwards:      time.Now(),
+		random:            newRandomness(config.Seed, false),
+		language:          config.Language,
+		registry:          registry.New(config.Registry),
+		detectGaps:        config.WatchOnly == nil,
+		labelChannels:     config.LabelChannels,
+	}
+	a.stats.startedAt = time.Now()
+	if a.halfLife == 0 {
+		a.halfLife = reputation.DefaultHalfLife
+	}
+	if config.Flood != nil {
+		if config.Cluster && db != nil {
+			a.flood = flood.NewShared(*config.Flood, db)
+		} else {
+			a.flood = flood.New(*config.Flood)
+		}
+	}
+	if db != nil {
+		a.autoBlock = config.AutoBlocklist
+	}
+	if config.Limits != nil {
+		limits := *config.Limits
+		if limits.Action == "" {
+			limits.Action = "reject"
+		}
+		a.limits = &limits
+	}
+	if config.ResponseSLO != nil {
+		a.slo = newSLOTracker(*config.ResponseSLO)
+	}
+	if config.Precompute != nil {
+		a.precomputed = newPeerCache(*config.Precompute)
+	}
+	if config.Resubscribe != nil {
+		resubscribe := *config.Resubscribe
+		if resubscribe.OnError == "" {
+			resubscribe.OnError = "reject"
+		}
+		a.resubscribe = &resubscribe
+	}
+	if config.StaleGraph != nil {
+		staleGraph := *config.StaleGraph
+		if staleGraph.Action == "" {
+			staleGraph.Action = "reject"
+		}
+		a.staleGraph = &staleGraph
+	}
+	a.graphSynced.Store(time.Now().UnixNano())
+	a.setPolicies(config.Policies, config.SelfServices, config.Experiment)
+	return a
+}
+
+// setPolicies atomically replaces the set of policies enforced, the nodes that bypass them and the
+// experiment, requests being evaluated keep using the previous ones.
+func (a *acceptor) setPolicies(
+	policies []*policy.Policy,
+	selfServices []string,
+	experiment *config.Experiment,
+) {
+	a.policies.Store(&policies)
+	a.selfServices.Store(&selfServices)
+	if experiment == nil {
+		a.experiment.Store(nil)
+		return
+	}
+	exp := *experiment
+	if exp.Mode == "" {
+		exp.Mode = config.ExperimentShadow
+	}
+	a.experiment.Store(&exp)
+}
+
+func (a *acceptor) getPolicies() []*policy.Policy {
+	return *a.policies.Load()
+}
+
+// isSelfService returns whether the node is one of the operator's own services, whose requests
+// are accepted without evaluating the policies.
+func (a *acceptor) isSelfService(publicKey string) bool {
+	for _, pubKey := range *a.selfServices.Load() {
+		if pubKey == publicKey {
+			return true
+		}
+	}
+	return false
+}
+
+// handleChannelRequests listens to the ChannnelAcceptor RPC stream and accepts/rejects requests
+// until the context is cancelled.
+func (a *acceptor) handleChannelRequests(ctx context.Context) error {
+	stream, err := a.client.ChannelAcceptor(ctx)
+	if err != nil {
+		return errors.Wrap(err, "subscribing to the channel acceptor stream")
+	}
+	a.registered.Store(time.Now().UnixNano())
+	metrics.SetConnected(true)
+
+	deadline := evaluationDeadline(a.acceptorTimeout(ctx))
+	slog.Info("Listening for channel requests", slog.Duration("evaluation_deadline", deadline))
+
+	var (
+		wg     sync.WaitGroup
+		sendMu sync.Mutex
+	)
+	defer wg.Wait()
+
+	send := func(resp *lnrpc.ChannelAcceptResponse) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		return stream.Send(resp)
+	}
+
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "receiving channel request")
+		}
+		received := time.Now()
+		idCtx := withCorrelationID(ctx)
+		slog.DebugContext(idCtx, "Channel opening request", slog.Any("request", req))
+
+		if a.isFlooding(idCtx, req) {
+			if err := a.reject(idCtx, req, overflowMessage, send); err != nil {
+				return err
+			}
+			continue
+		}
+		if a.isAutoBlocked(idCtx, hex.EncodeToString(req.NodePubkey)) {
+			if err := a.reject(idCtx, req, blockedMessage, send); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Give up before LND does, so there's time left to deliver the response
+		reqCtx, cancel := context.WithTimeout(idCtx, deadline)
+		if !a.acquire(reqCtx) {
+			cancel()
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			metrics.CountOverflow()
+			if err := a.reject(idCtx, req, overflowMessage, send); err != nil {
+				return err
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer cancel()
+
+			if err := a.respond(reqCtx, req, received, send); err != nil {
+				slog.ErrorContext(reqCtx, "Sending channel response", lightning.ErrorAttrs(err))
+			}
+		}()
+	}
+}
+
+// acceptorTimeout returns the time LND waits for our responses. The configured value takes
+// precedence, otherwise it's read from LND, falling back to LND's default if that fails.
+func (a *acceptor) acceptorTimeout(ctx context.Context) time.Duration {
+	if a.timeout > 0 {
+		return a.timeout
+	}
+
+	timeout, err := lightning.AcceptorTimeout(ctx, a.client)
+	if err != nil {
+		slog.Debug("Using LND's default channel acceptor timeout", lightning.ErrorAttrs(err))
+		return lightning.DefaultAcceptorTimeout
+	}
+	return timeout
+}
+
+// evaluationDeadline returns the time available to evaluate a request, leaving a margin of a fifth
+// of LND's timeout (at least a second) to deliver the response.
+func evaluationDeadline(timeout time.Duration) time.Duration {
+	margin := max(timeout/5, time.Second)
+	if margin >= timeout {
+		margin = timeout / 2
+	}
+	return timeout - margin
+}
+
+// isFlooding records the request in the flood detector and returns whether it comes from a
+// blocked node or uses a blocked funding amount. The operator's own services are never blocked.
+func (a *acceptor) isFlooding(ctx context.Context, req *lnrpc.ChannelAcceptRequest) bool {
+	publicKey := hex.EncodeToString(req.NodePubkey)
+	if a.flood == nil || a.isSelfService(publicKey) {
+		return false
+	}
+
+	blocked, detections := a.flood.Check(publicKey, req.FundingAmt)
+	for _, detection := range detections {
+		metrics.CountFloodBlock(detection.Kind)
+		slog.WarnContext(ctx, "Request flood detected, blocking temporarily",
+			slog.String("kind", detection.Kind),
+			slog.String("key", detection.Key),
+			slog.Int("requests", detection.Requests),
+			slog.Time("until", detection.Until),
+		)
+	}
+	return blocked
+}
+
+// reject responds to the request with the error message without evaluating it.
+func (a *acceptor) reject(
+	ctx context.Context,
+	req *lnrpc.ChannelAcceptRequest,
+	message string,
+	send func(*lnrpc.ChannelAcceptResponse) error,
+) error {
+	resp := &lnrpc.ChannelAcceptResponse{
+		PendingChanId: req.PendingChanId,
+		Error:         catalog.Translate(a.language, message),
+	}
+	if err := send(resp); err != nil {
+		return errors.Wrap(err, "sending channel response")
+	}
+
+	res := newResponse(req)
+	res.err = message
+	logResponse(ctx, res)
+	return nil
+}
+
+// acquire reserves an evaluation slot. If all of them are taken, it waits until one is released
+// or it returns false immediately if overflowing requests must be rejected.
+func (a *acceptor) acquire(ctx context.Context) bool {
+	if a.rejectOverflow {
+		select {
+		case a.slots <- struct{}{}:
+		default:
+			return false
+		}
+	} else {
+		select {
+		case <-ctx.Done():
+			return false
+		case a.slots <- struct{}{}:
+		}
+	}
+
+	metrics.EvaluationStarted()
+	return true
+}
+
+func (a *acceptor) release() {
+	<-a.slots
+	metrics.EvaluationFinished()
+}
+
+// respond evaluates the request, sends the response and records the decision. The evaluation
+// slot is released before tarpitting the response, so delayed rejections don't hold it.
+//
+// The time since the request was received is measured up to the tarpit, if any, as the delay is
+// deliberate.
+func (a *acceptor) respond(
+	ctx context.Context,
+	req *lnrpc.ChannelAcceptRequest,
+	received time.Time,
+	send func(*lnrpc.ChannelAcceptResponse) error,
+) error {
+	resp, peer, decision, err := a.handleRequest(ctx, req)
+	a.release()
+	if errors.Is(err, errInternal) && a.inGracePeriod(time.Now()) {
+		decision.policies = []string{gracePeriodLabel}
+		slog.WarnContext(ctx, "Request not evaluated during the grace period",
+			slog.String("action", a.resubscribe.OnError))
+		if a.resubscribe.OnError == "accept" {
+			err = nil
+		}
+	}
+
+	var reason string
+	if err != nil {
+		reason = err.Error()
+		resp.Error = catalog.Translate(a.language, reason)
+	} else {
+		resp.Accept = true
+		// The channel is pending now, policies limiting the number of channels must count it
+		a.invalidateNodeInfo()
+		for _, fix := range policy.FixResponse(req, resp) {
+			slog.WarnContext(ctx, "Fixing inconsistent response parameters", slog.String("fix", fix))
+		}
+	}
+
+	var tarpitErr *policy.TarpitError
+	tarpitted := errors.As(err, &tarpitErr)
+	elapsed := time.Since(received)
+	if tarpitted {
+		tarpit(ctx, tarpitErr.Delay, a.random.float64(req.PendingChanId, "tarpit"))
+	}
+
+	if err := send(resp); err != nil {
+		return err
+	}
+	if !tarpitted {
+		elapsed = time.Since(received)
+	}
+	a.observeResponse(ctx, elapsed)
+
+	res := newResponse(req)
+	res.accepted = resp.Accept
+	res.err = reason
+	res.policies = decision.policies
+	res.tags = decision.tags
+	res.warnings = decision.warnings
+	if peer != nil && peer.Node != nil {
+		res.alias = peer.Node.Alias
+	}
+	logResponse(ctx, res)
+	a.stats.count(resp.Accept, time.Now())
+	metrics.CountDecision(resp.Accept, decision.tags)
+	for _, label := range decision.warned {
+		metrics.CountWarning(label)
+	}
+
+	record := store.Decision{
+		ID:            res.id,
+		CorrelationID: correlationID(ctx),
+		PublicKey:     res.publicKey,
+		Capacity:      res.capacity,
+		Accepted:      resp.Accept,
+		Error:         reason,
+		Policies:      decision.policies,
+		Tags:          decision.tags,
+		Warnings:      decision.warnings,
+		Experiment:    decision.experiment,
+		At:            time.Now(),
+	}
+	if resp.Accept {
+		record.Response = responseParameters(resp)
+	}
+	if a.acceptHook != nil && a.acceptHook.Applies(record) {
+		// The request context is cancelled once the response is sent
+		go a.runAcceptHook(context.WithoutCancel(ctx), record)
+	}
+
+	if a.db == nil {
+		return nil
+	}
+
+	if err := a.db.AddDecision(record); err != nil {
+		slog.ErrorContext(ctx, "Recording decision", slog.Any("error", err))
+	}
+	if err := a.db.CountDecision(record.PublicKey, record.Accepted, record.At); err != nil {
+		slog.ErrorContext(ctx, "Recording peer history", slog.Any("error", err))
+	}
+	if a.webhook != nil {
+		// Notifications carry the reason in the language the peer received it
+		notification := record
+		notification.Error = resp.Error
+		if err := a.webhook.Send(notification); err != nil {
+			slog.ErrorContext(ctx, "Queuing decision for the webhook", slog.Any("error", err))
+		}
+	}
+
+	if resp.Accept {
+		tag := store.Tag{
+			PendingChanID: res.id,
+			PublicKey:     res.publicKey,
+			Capacity:      res.capacity,
+			Policies:      decision.policies,
+			Tags:          decision.tags,
+			Reputation:    decision.reputation,
+			AcceptedAt:    time.Now(),
+		}
+		if err := a.db.AddPendingTag(tag); err != nil {
+			slog.ErrorContext(ctx, "Tagging accepted channel", slog.Any("error", err))
+		}
+	}
+
+	// Only the requests evaluated by the policies affect the reputation and the automatic blocks
+	if peer != nil {
+		event := reputation.Rejected
+		if resp.Accept {
+			event = reputation.Accepted
+		}
+		a.addEvent(res.publicKey, event)
+		if !resp.Accept {
+			a.countRejection(ctx, res.publicKey)
+		}
+	}
+
+	return nil
+}
+
+func (a *acceptor) runAcceptHook(ctx context.Context, record store.Decision) {
+	if err := a.acceptHook.Run(ctx, record); err != nil {
+		slog.ErrorContext(ctx, "Running accept hook", slog.Any("error", err))
+	}
+}
+
+// responseParameters returns the channel parameters negotiated in the response.
+func responseParameters(resp *lnrpc.ChannelAcceptResponse) *store.Response {
+	return &store.Response{
+		CSVDelay:        resp.CsvDelay,
+		ReserveSat:      resp.ReserveSat,
+		InFlightMaxMsat: resp.InFlightMaxMsat,
+		MaxHTLCCount:    resp.MaxHtlcCount,
+		MinHTLCIn:       resp.MinHtlcIn,
+		MinAcceptDepth:  resp.MinAcceptDepth,
+		ZeroConf:        resp.ZeroConf,
+		UpfrontShutdown: resp.UpfrontShutdown,
+	}
+}
+
+func (a *acceptor) addEvent(publicKey string, event reputation.Event) {
+	if _, err := a.db.AddEvent(publicKey, event, time.Now(), a.halfLife); err != nil {
+		slog.Error("Updating reputation", slog.Any("error", err))
+	}
+}
+
+// tarpit waits a random time between half the delay and the full delay, so the tarpit can't be
+// told apart by the response time. The fraction (0-1) of the second half waited is received.
+func tarpit(ctx context.Context, delay time.Duration, fraction float64) {
+	delay = tarpitDelay(delay, fraction)
+	slog.DebugContext(ctx, "Tarpitting response", slog.Duration("delay", delay))
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// tarpitDelay returns the time waited by the tarpit, the fraction (0-1) of the second half of the
+// delay in addition to the first half.
+func tarpitDelay(delay time.Duration, fraction float64) time.Duration {
+	return delay/2 + time.Duration(fraction*float64(delay/2))
+}
+
+func (a *acceptor) handleRequest(
+	ctx context.Context,
+	req *lnrpc.ChannelAcceptRequest,
+) (*lnrpc.ChannelAcceptResponse, *lnrpc.NodeInfo, decision, error) {
+	resp := &lnrpc.ChannelAcceptResponse{Accept: false, PendingChanId: req.PendingChanId}
+
+	if a.isSelfService(hex.EncodeToString(req.NodePubkey)) {
+		resp.ZeroConf = req.WantsZeroConf
+		return resp, nil, decision{policies: []string{selfServicesLabel}}, nil
+	}
+
+	if override, ok := a.override(ctx, hex.EncodeToString(req.NodePubkey)); ok {
+		if !override.Accept {
+			return resp, nil, decision{policies: []string{overrideLabel}}, errors.New(blockedMessage)
+		}
+		return resp, nil, decision{policies: []string{overrideLabel}}, nil
+	}
+
+	node, err := a.getNodeInfo(ctx)
+	if err != nil {
+		return resp, nil, decision{}, errInternal
+	}
+
+	if decided, err := a.checkGraphSync(ctx, node); decided {
+		return resp, nil, decision{policies: []string{staleGraphLabel}}, err
+	}
+
+	if a.limits != nil {
+		decided, err := a.checkLimits(ctx, req)
+		if decided {
+			return resp, nil, decision{policies: []string{limitsLabel}}, err
+		}
+	}
+
+	getPeerInfoReq := &lnrpc.NodeInfoRequest{
+		PubKey:          hex.EncodeToString(req.NodePubkey),
+		IncludeChannels: true,
+	}
+	peer, precomputed := a.precomputed.get(getPeerInfoReq.PubKey, time.Now())
+	if a.precomputed != nil {
+		metrics.CountPrecomputedLookup(precomputed)
+	}
+	if !precomputed {
+		peer, err = a.client.GetNodeInfo(ctx, getPeerInfoReq)
+	}
+	if status.Code(err) == codes.NotFound {
+		// Unannounced nodes, like mobile wallets, are evaluated as nodes without channels
+		slog.DebugContext(ctx, "Peer not found in the graph", slog.String("public_key", getPeerInfoReq.PubKey))
+		peer, err = &lnrpc.NodeInfo{Node: &lnrpc.LightningNode{PubKey: getPeerInfoReq.PubKey}}, nil
+	}
+	if err != nil {
+		return resp, nil, decision{}, errInternal
+	}
+	if peer.Node == nil {
+		slog.WarnContext(ctx, "Peer node information is missing", slog.String("public_key", getPeerInfoReq.PubKey))
+		return resp, nil, decision{}, errInternal
+	}
+	slog.DebugContext(ctx, "Peer node information", slog.Any("node", peer))
+
+	facts := a.gatherFacts(ctx, req, peer)
+
+	decision, err := evaluatePolicies(a.getPolicies(), req, resp, node, peer, facts)
+	experiment := a.experiment.Load()
+	sampled := experiment != nil &&
+		a.random.float64(req.PendingChanId, "experiment") < experiment.Percentage/100
+	if sampled {
+		control := evaluation{resp: resp, decision: decision, err: err}
+		enforced := a.runExperiment(ctx, experiment, req, node, peer, facts, control)
+		resp, decision, err = enforced.resp, enforced.decision, enforced.err
+	}
+	decision.reputation = facts.Reputation
+	if a.chain != nil {
+		var consulted bool
+		consulted, err = a.chain.Combine(ctx, req, resp, err)
+		if consulted {
+			decision.policies = append(decision.policies, chainLabel)
+		}
+	}
+	return resp, peer, decision, err
+}
+
+// checkLimits returns whether the peer exceeds the limits, in which case the request is decided
+// by the configured action. Only the summary of the peer is requested so its channels are not
+// loaded.
+func (a *acceptor) checkLimits(ctx context.Context, req *lnrpc.ChannelAcceptRequest) (bool, error) {
+	publicKey := hex.EncodeToString(req.NodePubkey)
+	peer, err := a.client.GetNodeInfo(ctx, &lnrpc.NodeInfoRequest{PubKey: publicKey})
+	if status.Code(err) == codes.NotFound {
+		return false, nil
+	}
+	if err != nil {
+		return true, errInternal
+	}
+
+	if peer.NumChannels <= a.limits.MaxPeerChannels {
+		return false, nil
+	}
+
+	slog.WarnContext(ctx, "Peer exceeds the limits", slog.String("public_key", publicKey),
+		slog.Any("channels", peer.NumChannels), slog.String("action", a.limits.Action))
+	if a.limits.Action == "accept" {
+		return true, nil
+	}
+	return true, errors.New("Node has too many channels")
+}
+
+// checkGraphSync records whether LND is synced to the graph and returns whether it has been out of
+// sync for longer than allowed, in which case the request is decided by the configured action.
+func (a *acceptor) checkGraphSync(ctx context.Context, node *lnrpc.GetInfoResponse) (bool, error) {
+	now := time.Now()
+	if node.SyncedToGraph {
+		a.graphSynced.Store(now.UnixNano())
+	}
+	age := a.graphSyncAge(now)
+	metrics.SetGraphSyncAge(age)
+
+	if a.staleGraph == nil || age <= a.staleGraph.MaxAge {
+		return false, nil
+	}
+
+	slog.WarnContext(ctx, "Graph is stale", slog.Duration("sync_age", age), slog.String("action", a.staleGraph.Action))
+	if a.staleGraph.Action == "accept" {
+		return true, nil
+	}
+	return true, errors.New("Node graph is out of sync, try again later")
+}
+
+// graphSyncAge returns the time elapsed since LND was last seen synced to the graph.
+func (a *acceptor) graphSyncAge(now time.Time) time.Duration {
+	age := now.Sub(time.Unix(0, a.graphSynced.Load()))
+	if age < 0 {
+		return 0
+	}
+	return age
+}
+
+// setHistory adds the counts of our previous decisions on the peer requests to the facts.
+func setHistory(facts *policy.Facts, history store.History) {
+	facts.Acceptances = history.Accepted
+	facts.Rejections = history.Rejected
+	facts.LastDecision = history.LastDecision
+}
+
+// gatherFacts collects the information about the request that the lightning node doesn't provide.
+func (a *acceptor) gatherFacts(
+	ctx context.Context,
+	req *lnrpc.ChannelAcceptRequest,
+	peer *lnrpc.NodeInfo,
+) *policy.Facts {
+	facts := &policy.Facts{Now: time.Now(), KnownAs: a.registry.Tags(peer.Node.PubKey)}
+
+	if a.db != nil {
+		firstSeen, err := a.db.FirstSeen(peer.Node.PubKey, facts.Now)
+		if err != nil {
+			slog.ErrorContext(ctx, "Getting peer first seen time", slog.Any("error", err))
+		}
+		facts.FirstSeen = firstSeen
+
+		score, err := a.db.Score(peer.Node.PubKey)
+		if err != nil {
+			slog.ErrorContext(ctx, "Getting peer reputation", slog.Any("error", err))
+		}
+		facts.Reputation = score.At(facts.Now, a.halfLife)
+
+		history, err := a.db.History(peer.Node.PubKey)
+		if err != nil {
+			slog.ErrorContext(ctx, "Getting peer history", slog.Any("error", err))
+		}
+		setHistory(facts, history)
+	}
+
+	if network := a.network.Load(); network != nil {
+		facts.Peers = network.peers
+		facts.Reach = network.reach
+	}
+
+	if usesConnection(a.getPolicies()) {
+		address, err := a.peerAddress(ctx, peer.Node.PubKey)
+		if err != nil {
+			slog.ErrorContext(ctx, "Getting peer address", lightning.ErrorAttrs(err))
+		}
+		facts.Address = address
+		facts.TorExit = a.isTorExit(address)
+	}
+
+	if usesOrigins(a.getPolicies()) {
+		origins, err := a.channelOrigins(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Getting channel peers origins", lightning.ErrorAttrs(err))
+		} else {
+			facts.SameIPChannels, facts.SameASNChannels = origins.count(peer.Node.PubKey)
+		}
+	}
+
+	if a.reachability != nil && usesReachability(a.getPolicies()) {
+		addresses := make([]string, 0, len(peer.Node.Addresses))
+		for _, address := range peer.Node.Addresses {
+			addresses = append(addresses, address.Addr)
+		}
+		facts.Reachable = a.reachability.Reachable(ctx, peer.Node.PubKey, addresses)
+	}
+
+	if usesEscalation(a.getPolicies()) {
+		uptime, err := a.maxChannelUptime(ctx, req.NodePubkey)
+		if err != nil {
+			slog.ErrorContext(ctx, "Getting peer channels uptime", lightning.ErrorAttrs(err))
+		}
+		facts.MaxChannelUptime = uptime
+	}
+
+	if usesInboundCapacity(a.getPolicies()) {
+		inbound, err := a.inboundCapacity(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Getting inbound capacity", lightning.ErrorAttrs(err))
+		}
+		facts.InboundCapacity = inbound
+	}
+
+	if usesOnchain(a.getPolicies()) {
+		feeRate, err := a.client.EstimateFeeRate(ctx, &walletrpc.EstimateFeeRequest{ConfTarget: sweepConfTarget})
+		if err != nil {
+			slog.ErrorContext(ctx, "Estimating fee rate", lightning.ErrorAttrs(err))
+		} else {
+			facts.FeeRate = uint64(feeRate.SatPerKw)
+		}
+	}
+
+	return facts
+}
+
+// peerAddress returns the address the peer is connected from, which may not be announced.
+func (a *acceptor) peerAddress(ctx context.Context, publicKey string) (string, error) {
+	resp, err := a.client.ListPeers(ctx, &lnrpc.ListPeersRequest{})
+	if err != nil {
+		return "", errors.Wrap(err, "listing peers")
+	}
+
+	for _, peer := range resp.Peers {
+		if peer.PubKey == publicKey {
+			return peer.Address, nil
+		}
+	}
+	return "", nil
+}
+
+// isTorExit returns whether the host of the address is in the Tor exit relays list.
+func (a *acceptor) isTorExit(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	_, ok := a.torExits[addr.Unmap()]
+	return ok
+}
+
+// loadTorExits reads a list of IP addresses, one per line. Empty lines and comments starting with
+// # are ignored.
+func loadTorExits(path string) (map[netip.Addr]struct{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading Tor exit list")
+	}
+
+	exits := make(map[netip.Addr]struct{})
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		addr, err := netip.ParseAddr(line)
+		if err != nil {
+			return nil, errors.Errorf("Tor exit list line %d: invalid address %q", i+1, line)
+		}
+		exits[addr.Unmap()] = struct{}{}
+	}
+	return exits, nil
+}
+
+// maxChannelUptime returns the longest uptime of the peer channels with us, including the ones
+// recorded in the database that may be closed now.
+func (a *acceptor) maxChannelUptime(ctx context.Context, publicKey []byte) (time.Duration, error) {
+	resp, err := a.client.ListChannels(ctx, &lnrpc.ListChannelsRequest{Peer: publicKey})
+	if err != nil {
+		return 0, errors.Wrap(err, "listing channels")
+	}
+
+	var maxUptime time.Duration
+	for _, channel := range resp.Channels {
+		if uptime := time.Duration(channel.Uptime) * time.Second; uptime > maxUptime {
+			maxUptime = uptime
+		}
+	}
+
+	if a.db == nil {
+		return maxUptime, nil
+	}
+
+	return a.db.MaxUptime(hex.EncodeToString(publicKey), maxUptime)
+}
+
+// inboundCapacity returns the sum of the remote balances of the channels our peers opened.
+func (a *acceptor) inboundCapacity(ctx context.Context) (uint64, error) {
+	resp, err := a.client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+	if err != nil {
+		return 0, errors.Wrap(err, "listing channels")
+	}
+
+	var inbound uint64
+	for _, channel := range resp.Channels {
+		if !channel.Initiator {
+			inbound += uint64(channel.RemoteBalance)
+		}
+	}
+	return inbound, nil
+}
+
+// monitorChannels periodically records the uptime of our channels, so peers keep their history
+// even if the channels are closed between requests, and tags the channels accepted once they are
+// open.
+func (a *acceptor) monitorChannels(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := a.recordChannels(ctx); err != nil {
+			slog.Warn("Monitoring channels", lightning.ErrorAttrs(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *acceptor) recordChannels(ctx context.Context) error {
+	resp, err := a.client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+	if err != nil {
+		return errors.Wrap(err, "listing channels")
+	}
+	node, err := a.getNodeInfo(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+
+	for _, channel := range resp.Channels {
+		uptime := time.Duration(channel.Uptime) * time.Second
+		if _, err := a.db.MaxUptime(channel.RemotePubkey, uptime); err != nil {
+			return err
+		}
+
+		if wasActive, ok := a.active[channel.ChannelPoint]; ok && wasActive && !channel.Active {
+			a.addEvent(channel.RemotePubkey, reputation.Flapped)
+		}
+		a.active[channel.ChannelPoint] = channel.Active
+
+		if channel.Initiator {
+			continue
+		}
+		// Channels are tagged shortly after confirming, the estimation is off by a few minutes at
+		// most and a block of margin keeps the tags accepted right before the channel. The
+		// unconfirmed zero-conf channels only have an alias, they were opened since the last check
+		openedAt := now
+		if height, ok := channelHeight(channel); ok {
+			openedAt = heightTime(node.BlockHeight, height, now)
+		}
+		openedAt = openedAt.Add(blockInterval)
+		tagged, err := a.db.TagChannel(channel.RemotePubkey, channel.ChannelPoint, uint64(channel.Capacity), openedAt)
+		if err != nil {
+			return err
+		}
+		if tagged && a.labelChannels {
+			a.labelChannel(ctx, channel.ChannelPoint)
+		}
+	}
+
+	if a.detectGaps {
+		if err := a.recordUnmanagedChannels(ctx, resp.Channels); err != nil {
+			return err
+		}
+	}
+
+	if err := a.db.PruneDecisions(now.Add(-decisionsRetention)); err != nil {
+		return err
+	}
+	if err := a.db.PrunePendingTags(now.Add(-pendingTagsRetention)); err != nil {
+		return err
+	}
+	if err := a.pruneRejections(); err != nil {
+		return err
+	}
+
+	if err := a.recordForceCloses(ctx); err != nil {
+		return err
+	}
+
+	return a.recordForwards(ctx, resp.Channels)
+}
+
+// decisionsRetention is how long the decisions are kept in the database.
+const decisionsRetention = 90 * 24 * time.Hour
+
+// pendingTagsRetention is how long the tags of the channels accepted wait for them to open. LND
+// forgets the channels opened by peers that don't confirm within 2016 blocks.
+const pendingTagsRetention = 2016 * blockInterval
+
+// recordForceCloses penalizes the nodes that force closed channels with us, once per channel.
+func (a *acceptor) recordForceCloses(ctx context.Context) error {
+	resp, err := a.client.ClosedChannels(ctx, &lnrpc.ClosedChannelsRequest{RemoteForce: true})
+	if err != nil {
+		return errors.Wrap(err, "listing closed channels")
+	}
+
+	for _, channel := range resp.Channels {
+		id := string(reputation.ForceClosed) + ":" + channel.ChannelPoint
+		_, err := a.db.AddEventOnce(id, channel.RemotePubkey, reputation.ForceClosed, time.Now(), a.halfLife)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// forwardingPageSize is the maximum number of forwarding events requested at once.
+const forwardingPageSize = 10_000
+
+// recordForwards rewards the nodes whose channels routed payments since the last check, once per
+// check regardless of the number of payments.
+func (a *acceptor) recordForwards(ctx context.Context, channels []*lnrpc.Channel) error {
+	peers := make(map[uint64]string, len(channels))
+	for _, channel := range channels {
+		peers[channel.ChanId] = channel.RemotePubkey
+	}
+
+	now := time.Now()
+	routed := make(map[string]struct{})
+	req := &lnrpc.ForwardingHistoryRequest{
+		StartTime:    uint64(a.lastForwards.Unix()),
+		EndTime:      uint64(now.Unix()),
+		NumMaxEvents: forwardingPageSize,
+	}
+	for {
+		resp, err := a.client.ForwardingHistory(ctx, req)
+		if err != nil {
+			return errors.Wrap(err, "getting forwarding history")
+		}
+
+		for _, forward := range resp.ForwardingEvents {
+			for _, chanID := range []uint64{forward.ChanIdIn, forward.ChanIdOut} {
+				if publicKey, ok := peers[chanID]; ok {
+					routed[publicKey] = struct{}{}
+				}
+			}
+		}
+
+		if len(resp.ForwardingEvents) < forwardingPageSize {
+			break
+		}
+		req.IndexOffset = resp.LastOffsetIndex
+	}
+	a.lastForwards = now
+
+	for publicKey := range routed {
+		a.addEvent(publicKey, reputation.Routed)
+	}
+	return nil
+}
+
+// monitorGraph periodically takes a snapshot of the channel graph to know our peers and the nodes
+// we can reach through them. The snapshot is skipped if no policy needs it.
+func (a *acceptor) monitorGraph(ctx context.Context, interval time.Duration) {
+	// A recent snapshot saved by a previous run postpones the first call to DescribeGraph
+	var wait time.Duration
+	if usesGraph(a.getPolicies()) {
+		if age, ok := a.loadGraphSnapshot(ctx); ok && age < interval {
+			wait = interval - age
+		}
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		if usesGraph(a.getPolicies()) {
+			if err := a.updateNeighborhood(ctx); err != nil {
+				slog.Warn("Monitoring graph", lightning.ErrorAttrs(err))
+			}
+		}
+		timer.Reset(interval)
+	}
+}
+
+// loadGraphSnapshot seeds the neighborhood with the snapshot saved by a previous run, so the
+// policies that need the graph can be evaluated before the first call to DescribeGraph. It
+// returns the age of the snapshot, and false if there is none.
+func (a *acceptor) loadGraphSnapshot(ctx context.Context) (time.Duration, bool) {
+	if a.graphSnapshotPath == "" {
+		return 0, false
+	}
+
+	info, err := os.Stat(a.graphSnapshotPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("Loading graph snapshot", slog.Any("error", err))
+		}
+		return 0, false
+	}
+
+	snapshot, err := graph.Load(a.graphSnapshotPath)
+	if err != nil {
+		slog.Warn("Loading graph snapshot", slog.Any("error", err))
+		return 0, false
+	}
+
+	node, err := a.client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	if err != nil {
+		slog.Warn("Loading graph snapshot", lightning.ErrorAttrs(errors.Wrap(err, "getting node information")))
+		return 0, false
+	}
+
+	a.network.Store(newNeighborhood(snapshot, node.IdentityPubkey))
+	age := time.Since(info.ModTime())
+	slog.Info("Graph snapshot loaded", slog.String("path", a.graphSnapshotPath), slog.Duration("age", age))
+	return age, true
+}
+
+func (a *acceptor) updateNeighborhood(ctx context.Context) error {
+	node, err := a.client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	if err != nil {
+		return errors.Wrap(err, "getting node information")
+	}
+
+	channelGraph, err := a.client.DescribeGraph(ctx, &lnrpc.ChannelGraphRequest{})
+	if err != nil {
+		return errors.Wrap(err, "describing graph")
+	}
+
+	a.network.Store(newNeighborhood(graph.New(channelGraph), node.IdentityPubkey))
+
+	if a.graphSnapshotPath != "" {
+		if err := graph.Save(a.graphSnapshotPath, channelGraph); err != nil {
+			slog.Warn("Saving graph snapshot", slog.Any("error", err))
+		}
+	}
+	return nil
+}
+
+// newNeighborhood returns the nodes around ours in the graph snapshot.
+func newNeighborhood(snapshot *graph.Snapshot, publicKey string) *neighborhood {
+	network := &neighborhood{
+		peers: make(map[string]struct{}),
+		reach: snapshot.Reach(publicKey),
+	}
+	for _, peer := range snapshot.Neighbors(publicKey) {
+		network.peers[peer] = struct{}{}
+	}
+	return network
+}
+
+// usesGraph returns whether any policy needs the graph snapshot.
+func usesGraph(policies []*policy.Policy) bool {
+	return anyNode(policies, func(n *policy.Node) bool {
+		return n.NewReach != nil || n.PeerOverlap != nil
+	})
+}
+
+// usesConnection returns whether any policy has requirements on the address the peer is connected
+// from.
+func usesConnection(policies []*policy.Policy) bool {
+	return anyNode(policies, func(n *policy.Node) bool { return n.Connection != nil })
+}
+
+// usesReachability returns whether any policy requires testing the peer announced addresses.
+func usesReachability(policies []*policy.Policy) bool {
+	return anyNode(policies, func(n *policy.Node) bool { return n.Reachable != nil })
+}
+
+// anyNode returns whether fn is true for the node requirements of any policy or condition set.
+func anyNode(policies []*policy.Policy, fn func(*policy.Node) bool) bool {
+	for _, p := range policies {
+		if p.Node != nil && fn(p.Node) {
+			return true
+		}
+		if p.Conditions == nil {
+			continue
+		}
+		for _, c := range append([]*policy.Conditions{p.Conditions}, p.Conditions.Any...) {
+			if c.Node != nil && fn(c.Node) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func usesEscalation(policies []*policy.Policy) bool {
+	for _, p := range policies {
+		if p.Escalation != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func usesInboundCapacity(policies []*policy.Policy) bool {
+	for _, p := range policies {
+		if p.MaxInboundCapacity != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func usesOnchain(policies []*policy.Policy) bool {
+	for _, p := range policies {
+		if p.Onchain != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// decision describes which policies took part in a request decision.
+type decision struct {
+	// Labels of the policies applied, if the request was rejected the last one did it.
+	policies []string
+	// Tags of the policies applied, without duplicates.
+	tags []string
+	// Violations of the requirements with the warn severity, prefixed with the policy label.
+	warnings []string
+	// Labels of the policies of each warning.
+	warned []string
+	// Verdict not enforced of the experiment the request was included in, if any.
+	experiment *store.Experiment
+	// Reputation of the peer when the request was evaluated.
+	reputation float64
+}
+
+func (d *decision) add(index int, p *policy.Policy) {
+	d.policies = append(d.policies, p.Label(index))
+	for _, tag := range p.Tags {
+		if !slices.Contains(d.tags, tag) {
+			d.tags = append(d.tags, tag)
+		}
+	}
+}
+
+// evaluatePolicies enforces the policies from top to bottom, returning the ones that applied and
+// the first rejection.
+func evaluatePolicies(
+	policies []*policy.Policy,
+	req *lnrpc.ChannelAcceptRequest,
+	resp *lnrpc.ChannelAcceptResponse,
+	node *lnrpc.GetInfoResponse,
+	peer *lnrpc.NodeInfo,
+	facts *policy.Facts,
+) (decision, error) {
+	var d decision
+	facts.Warnings = nil
+	for i, p := range policies {
+		if !p.Applies(req, node, peer, facts) {
+			continue
+		}
+
+		d.add(i, p)
+		err := p.Enforce(req, resp, node, peer, facts)
+		for _, warning := range facts.Warnings[len(d.warnings):] {
+			d.warnings = append(d.warnings, p.Label(i)+": "+warning)
+			d.warned = append(d.warned, p.Label(i))
+		}
+		if err != nil {
+			return d, err
+		}
+	}
+
+	return d, nil
+}
+
+// decisionMessage is the message used to log channel request decisions.
+const decisionMessage = "New request received"
+
+type response struct {
+	id        string
+	publicKey string
+	alias     string
+	err       string
+	policies  []string
+	tags      []string
+	warnings  []string
+	capacity  uint64
+	// pushAmt is the amount pushed to us, in satoshis.
+	pushAmt        uint64
+	commitmentType lnrpc.CommitmentType
+	private        bool
+	zeroConf       bool
+	accepted       bool
+}
+
+// newResponse returns the response log of the request, without its decision.
+func newResponse(req *lnrpc.ChannelAcceptRequest) response {
+	return response{
+		id:             hex.EncodeToString(req.PendingChanId),
+		publicKey:      hex.EncodeToString(req.NodePubkey),
+		capacity:       req.FundingAmt,
+		pushAmt:        req.PushAmt / 1000,
+		commitmentType: req.CommitmentType,
+		private:        req.ChannelFlags != uint32(lnwire.FFAnnounceChannel),
+		zeroConf:       req.WantsZeroConf,
+	}
+}
+
+func logResponse(ctx context.Context, res response) {
+	args := []any{
+		slog.Bool("accepted", res.accepted),
+		slog.String("id", res.id),
+		slog.String("public_key", res.publicKey),
+		slog.String("alias", res.alias),
+		slog.Uint64("capacity", res.capacity),
+		slog.Uint64("push_amt", res.pushAmt),
+		slog.String("commitment_type", res.commitmentType.String()),
+		slog.Bool("private", res.private),
+		slog.Bool("zero_conf", res.zeroConf),
+		slog.String("policies", strings.Join(res.policies, ",")),
+	}
+	if len(res.tags) > 0 {
+		args = append(args, slog.String("tags", strings.Join(res.tags, ",")))
+	}
+	if len(res.warnings) > 0 {
+		args = append(args, slog.String("warnings", strings.Join(res.warnings, "; ")))
+	}
+	if !res.accepted {
+		args = append(args, slog.String("error", res.err))
+		if len(res.policies) > 0 {
+			args = append(args, slog.String("rejected_by", res.policies[len(res.policies)-1]))
+		}
+	}
+
+	slog.InfoContext(ctx, decisionMessage, args...)
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/asn"
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/graph"
+	"github.com/aftermath2/acceptlnd/lightning"
+	"github.com/aftermath2/acceptlnd/lightning/fake"
+	"github.com/aftermath2/acceptlnd/store"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+// peersClient answers with the peers and channels set, the rest of the calls aren't used.
+type peersClient struct {
+	lightning.Client
+	peers    []*lnrpc.Peer
+	channels []*lnrpc.Channel
+	pending  []*lnrpc.PendingChannelsResponse_PendingOpenChannel
+}
+
+func (c *peersClient) ListPeers(
+	context.Context,
+	*lnrpc.ListPeersRequest,
+	...grpc.CallOption,
+) (*lnrpc.ListPeersResponse, error) {
+	return &lnrpc.ListPeersResponse{Peers: c.peers}, nil
+}
+
+func (c *peersClient) ListChannels(
+	context.Context,
+	*lnrpc.ListChannelsRequest,
+	...grpc.CallOption,
+) (*lnrpc.ListChannelsResponse, error) {
+	return &lnrpc.ListChannelsResponse{Channels: c.channels}, nil
+}
+
+func (c *peersClient) PendingChannels(
+	context.Context,
+	*lnrpc.PendingChannelsRequest,
+	...grpc.CallOption,
+) (*lnrpc.PendingChannelsResponse, error) {
+	return &lnrpc.PendingChannelsResponse{PendingOpenChannels: c.pending}, nil
+}
+
+func TestOriginAddr(t *testing.T) {
+	exit := netip.MustParseAddr("198.51.100.7")
+	a := &acceptor{torExits: map[netip.Addr]struct{}{exit: {}}}
+
+	cases := []struct {
+		desc     string
+		address  string
+		expected string
+	}{
+		{desc: "IPv4", address: "203.0.113.5:9735", expected: "203.0.113.5"},
+		{desc: "IPv6", address: "[2001:db8::1]:9735", expected: "2001:db8::1"},
+		{desc: "IPv4-mapped", address: "[::ffff:203.0.113.5]:9735", expected: "203.0.113.5"},
+		{desc: "Loopback", address: "127.0.0.1:50000"},
+		{desc: "IPv6 loopback", address: "[::1]:50000"},
+		{desc: "Mapped loopback", address: "[::ffff:127.0.0.1]:50000"},
+		{desc: "Tor exit", address: "198.51.100.7:9735"},
+		{desc: "Mapped Tor exit", address: "[::ffff:198.51.100.7]:9735"},
+		{desc: "Onion", address: "abcdef.onion:9735"},
+		{desc: "Without port", address: "203.0.113.5"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			addr := a.originAddr(tc.address)
+			if tc.expected == "" {
+				assert.False(t, addr.IsValid())
+				return
+			}
+			assert.Equal(t, netip.MustParseAddr(tc.expected), addr)
+		})
+	}
+}
+
+func TestChannelOrigins(t *testing.T) {
+	ctx := context.Background()
+	asns, err := asn.Parse(strings.NewReader("203.0.113.0\t203.0.113.127\t64500\tZZ\tEXAMPLE\n"))
+	assert.NoError(t, err)
+
+	client := &peersClient{
+		peers: []*lnrpc.Peer{
+			{PubKey: "a", Address: "203.0.113.5:9735"},
+			{PubKey: "b", Address: "[::ffff:203.0.113.5]:9735"},
+			{PubKey: "c", Address: "203.0.113.9:9735"},
+			{PubKey: "tor", Address: "127.0.0.1:50000"},
+			{PubKey: "new", Address: "203.0.113.5:9735"},
+		},
+		channels: []*lnrpc.Channel{
+			{RemotePubkey: "a"},
+			{RemotePubkey: "b"},
+			{RemotePubkey: "tor"},
+		},
+		pending: []*lnrpc.PendingChannelsResponse_PendingOpenChannel{
+			{Channel: &lnrpc.PendingChannelsResponse_PendingChannel{RemoteNodePub: "c"}},
+			{},
+		},
+	}
+	a := &acceptor{client: client, asns: asns}
+
+	snapshot, err := a.channelOrigins(ctx)
+	assert.NoError(t, err)
+
+	sameIP, sameASN := snapshot.count("new")
+	assert.Equal(t, uint32(2), sameIP)
+	assert.Equal(t, uint32(3), sameASN)
+
+	// Peers connected through Tor have no origin
+	sameIP, sameASN = snapshot.count("tor")
+	assert.Zero(t, sameIP)
+	assert.Zero(t, sameASN)
+
+	t.Run("Offline peer", func(t *testing.T) {
+		// a disconnects but keeps its channel, its last address still counts
+		client.peers = client.peers[1:]
+		snapshot, err := a.channelOrigins(ctx)
+		assert.NoError(t, err)
+
+		sameIP, _ := snapshot.count("new")
+		assert.Equal(t, uint32(2), sameIP)
+
+		// Once the channel is closed as well, a is forgotten
+		client.channels = client.channels[1:]
+		snapshot, err = a.channelOrigins(ctx)
+		assert.NoError(t, err)
+
+		sameIP, _ = snapshot.count("new")
+		assert.Equal(t, uint32(1), sameIP)
+		assert.NotContains(t, a.origins.addrs, "a")
+	})
+
+	t.Run("Without ASN database", func(t *testing.T) {
+		a := &acceptor{client: client}
+		snapshot, err := a.channelOrigins(ctx)
+		assert.NoError(t, err)
+
+		sameIP, sameASN := snapshot.count("new")
+		assert.Equal(t, uint32(1), sameIP)
+		assert.Zero(t, sameASN)
+	})
+
+	t.Run("Unknown origin", func(t *testing.T) {
+		sameIP, sameASN := snapshot.count("unknown")
+		assert.Zero(t, sameIP)
+		assert.Zero(t, sameASN)
+	})
+}
+
+func TestAcquire(t *testing.T) {
+	t.Run("Wait", func(t *testing.T) {
+		a := newAcceptor(nil, nil, config.Config{MaxConcurrentEvaluations: 2})
+		assert.True(t, a.acquire(context.Background()))
+		assert.True(t, a.acquire(context.Background()))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.False(t, a.acquire(ctx))
+
+		// Waiting requests take the slots released
+		acquired := make(chan bool)
+		go func() {
+			acquired <- a.acquire(context.Background())
+		}()
+		a.release()
+		assert.True(t, <-acquired)
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		a := newAcceptor(nil, nil, config.Config{MaxConcurrentEvaluations: 1, OverflowAction: "reject"})
+		assert.True(t, a.acquire(context.Background()))
+		assert.False(t, a.acquire(context.Background()))
+
+		a.release()
+		assert.True(t, a.acquire(context.Background()))
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		a := newAcceptor(nil, nil, config.Config{OverflowAction: "reject"})
+		assert.True(t, a.acquire(context.Background()))
+		assert.False(t, a.acquire(context.Background()))
+	})
+}
+
+func TestEvaluationDeadline(t *testing.T) {
+	cases := []struct {
+		timeout  time.Duration
+		expected time.Duration
+	}{
+		{timeout: 15 * time.Second, expected: 12 * time.Second},
+		{timeout: time.Minute, expected: 48 * time.Second},
+		// The margin is at least a second
+		{timeout: 3 * time.Second, expected: 2 * time.Second},
+		// Unless it would take the whole timeout
+		{timeout: time.Second, expected: 500 * time.Millisecond},
+		{timeout: 500 * time.Millisecond, expected: 250 * time.Millisecond},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.timeout.String(), func(t *testing.T) {
+			assert.Equal(t, tc.expected, evaluationDeadline(tc.timeout))
+		})
+	}
+}
+
+// channelsClient lists the channels set, the rest of the calls are answered by the fake client.
+type channelsClient struct {
+	*fake.Client
+	channels []*lnrpc.Channel
+}
+
+func (c *channelsClient) ListChannels(
+	context.Context,
+	*lnrpc.ListChannelsRequest,
+	...grpc.CallOption,
+) (*lnrpc.ListChannelsResponse, error) {
+	return &lnrpc.ListChannelsResponse{Channels: c.channels}, nil
+}
+
+func TestRecordChannelsTags(t *testing.T) {
+	const (
+		tip   = 850_000
+		day   = 144
+		alias = uint64(16_000_000) << 40
+	)
+	// The snapshot tip is the height of its most recent channel
+	snapshot := graph.New(&lnrpc.ChannelGraph{
+		Edges: []*lnrpc.ChannelEdge{{Node1Pub: "x", Node2Pub: "y", ChannelId: tip << 40}},
+	})
+
+	cases := []struct {
+		desc    string
+		channel *lnrpc.Channel
+		tagged  bool
+	}{
+		{
+			desc:    "Opened after the acceptance",
+			channel: &lnrpc.Channel{ChanId: (tip - 2*day) << 40},
+			tagged:  true,
+		},
+		{
+			desc:    "Opened before the acceptance",
+			channel: &lnrpc.Channel{ChanId: (tip - 6*day) << 40},
+		},
+		{
+			desc:    "Unconfirmed zero conf",
+			channel: &lnrpc.Channel{ChanId: alias, ZeroConf: true},
+			tagged:  true,
+		},
+		{
+			desc:    "Zero conf confirmed before the acceptance",
+			channel: &lnrpc.Channel{ChanId: alias, ZeroConf: true, ZeroConfConfirmedScid: (tip - 6*day) << 40},
+		},
+		{
+			desc:    "Opened by us",
+			channel: &lnrpc.Channel{ChanId: (tip - 2*day) << 40, Initiator: true},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			db := store.NewMemory()
+			tag := store.Tag{
+				PendingChanID: "id",
+				PublicKey:     "peer",
+				Capacity:      1_000_000,
+				AcceptedAt:    time.Now().Add(-3 * 24 * time.Hour),
+			}
+			assert.NoError(t, db.AddPendingTag(tag))
+
+			tc.channel.ChannelPoint = "txid:0"
+			tc.channel.RemotePubkey = tag.PublicKey
+			tc.channel.Capacity = int64(tag.Capacity)
+			client := &channelsClient{Client: fake.New(snapshot, "us"), channels: []*lnrpc.Channel{tc.channel}}
+			a := newAcceptor(client, db, config.Config{})
+			assert.NoError(t, a.recordChannels(context.Background()))
+
+			tags, err := db.Tags()
+			assert.NoError(t, err)
+			_, tagged := tags["txid:0"]
+			assert.Equal(t, tc.tagged, tagged)
+		})
+	}
+}
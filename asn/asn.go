@@ -0,0 +1,132 @@
+// Package asn maps IP addresses to the autonomous systems announcing them, so the channels of
+// peers hosted by the same provider can be told apart from the rest.
+package asn
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// notRouted is the number the databases use for the ranges no autonomous system announces.
+const notRouted = 0
+
+// ipRange is a range of addresses announced by an autonomous system.
+type ipRange struct {
+	start netip.Addr
+	end   netip.Addr
+	asn   uint32
+}
+
+// Database holds the address ranges sorted by their first address.
+type Database struct {
+	ranges []ipRange
+}
+
+// Load reads the database in the file, compressed with gzip if its name ends with .gz.
+func Load(path string) (*Database, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening ASN database")
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, errors.Wrap(err, "decompressing ASN database")
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	return Parse(r)
+}
+
+// Parse reads a database in the tab separated format published by iptoasn.com, with the first
+// and last addresses of a range and the number of the autonomous system announcing it as the
+// leading columns. Empty lines and comments starting with # are ignored.
+func Parse(r io.Reader) (*Database, error) {
+	var ranges []ipRange
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+		if len(fields) < 3 {
+			return nil, errors.Errorf("ASN database line %d: expected at least 3 columns", line)
+		}
+		start, err := netip.ParseAddr(fields[0])
+		if err != nil {
+			return nil, errors.Errorf("ASN database line %d: invalid address %q", line, fields[0])
+		}
+		end, err := netip.ParseAddr(fields[1])
+		if err != nil {
+			return nil, errors.Errorf("ASN database line %d: invalid address %q", line, fields[1])
+		}
+		start, end = start.Unmap(), end.Unmap()
+		if start.Is4() != end.Is4() || end.Less(start) {
+			return nil, errors.Errorf("ASN database line %d: invalid range %s-%s", line, start, end)
+		}
+		asn, err := strconv.ParseUint(strings.TrimPrefix(fields[2], "AS"), 10, 32)
+		if err != nil {
+			return nil, errors.Errorf("ASN database line %d: invalid AS number %q", line, fields[2])
+		}
+
+		if asn == notRouted {
+			continue
+		}
+		ranges = append(ranges, ipRange{start: start, end: end, asn: uint32(asn)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "reading ASN database")
+	}
+
+	slices.SortFunc(ranges, func(a, b ipRange) int { return a.start.Compare(b.start) })
+	return &Database{ranges: ranges}, nil
+}
+
+// Lookup returns the number of the autonomous system announcing the address and whether it's
+// announced by any.
+func (d *Database) Lookup(addr netip.Addr) (uint32, bool) {
+	if d == nil || !addr.IsValid() {
+		return 0, false
+	}
+	addr = addr.Unmap()
+
+	// Index of the first range starting after the address
+	i, _ := slices.BinarySearchFunc(d.ranges, addr, func(r ipRange, addr netip.Addr) int {
+		if r.start.Compare(addr) <= 0 {
+			return -1
+		}
+		return 1
+	})
+	if i == 0 {
+		return 0, false
+	}
+
+	r := d.ranges[i-1]
+	if addr.Is4() != r.end.Is4() || r.end.Less(addr) {
+		return 0, false
+	}
+	return r.asn, true
+}
+
+// Len returns the number of ranges announced.
+func (d *Database) Len() int {
+	if d == nil {
+		return 0
+	}
+	return len(d.ranges)
+}
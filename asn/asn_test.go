@@ -0,0 +1,150 @@
+package asn
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookup(t *testing.T) {
+	db, err := Load("./testdata/ip2asn.tsv")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, db.Len())
+
+	cases := []struct {
+		desc     string
+		addr     netip.Addr
+		expected uint32
+		ok       bool
+	}{
+		{
+			desc:     "First address",
+			addr:     netip.MustParseAddr("1.0.0.0"),
+			expected: 13335,
+			ok:       true,
+		},
+		{
+			desc:     "Last address",
+			addr:     netip.MustParseAddr("203.0.113.127"),
+			expected: 64500,
+			ok:       true,
+		},
+		{
+			desc:     "Next range",
+			addr:     netip.MustParseAddr("203.0.113.128"),
+			expected: 64501,
+			ok:       true,
+		},
+		{
+			desc:     "Mapped IPv4",
+			addr:     netip.MustParseAddr("::ffff:203.0.113.10"),
+			expected: 64500,
+			ok:       true,
+		},
+		{
+			desc:     "IPv6",
+			addr:     netip.MustParseAddr("2001:db8::1"),
+			expected: 64500,
+			ok:       true,
+		},
+		{
+			desc: "Not routed",
+			addr: netip.MustParseAddr("1.0.2.1"),
+		},
+		{
+			desc: "Between ranges",
+			addr: netip.MustParseAddr("10.0.0.1"),
+		},
+		{
+			desc: "Before the first range",
+			addr: netip.MustParseAddr("0.0.0.1"),
+		},
+		{
+			desc: "After the last range",
+			addr: netip.MustParseAddr("2001:db9::1"),
+		},
+		{
+			desc: "Invalid",
+			addr: netip.Addr{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			asn, ok := db.Lookup(tc.addr)
+			assert.Equal(t, tc.expected, asn)
+			assert.Equal(t, tc.ok, ok)
+		})
+	}
+
+	t.Run("Nil", func(t *testing.T) {
+		var db *Database
+		_, ok := db.Lookup(netip.MustParseAddr("1.0.0.1"))
+		assert.False(t, ok)
+	})
+}
+
+func TestLoadGzip(t *testing.T) {
+	content, err := os.ReadFile("./testdata/ip2asn.tsv")
+	assert.NoError(t, err)
+
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	_, err = gz.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+
+	path := filepath.Join(t.TempDir(), "ip2asn.tsv.gz")
+	assert.NoError(t, os.WriteFile(path, b.Bytes(), 0o600))
+
+	db, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, db.Len())
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		desc     string
+		content  string
+		expected string
+	}{
+		{
+			desc:     "Missing columns",
+			content:  "1.0.0.0\t1.0.0.255\n",
+			expected: "ASN database line 1: expected at least 3 columns",
+		},
+		{
+			desc:     "Invalid start",
+			content:  "1.0.0\t1.0.0.255\t13335\n",
+			expected: `ASN database line 1: invalid address "1.0.0"`,
+		},
+		{
+			desc:     "Invalid range",
+			content:  "# header\n1.0.0.255\t1.0.0.0\t13335\n",
+			expected: "ASN database line 2: invalid range 1.0.0.255-1.0.0.0",
+		},
+		{
+			desc:     "Mixed families",
+			content:  "1.0.0.0\t2001:db8::\t13335\n",
+			expected: "ASN database line 1: invalid range 1.0.0.0-2001:db8::",
+		},
+		{
+			desc:     "Invalid AS number",
+			content:  "1.0.0.0\t1.0.0.255\tcloudflare\n",
+			expected: `ASN database line 1: invalid AS number "cloudflare"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tc.content))
+			assert.EqualError(t, err, tc.expected)
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/asn"
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/lightning"
+	"github.com/aftermath2/acceptlnd/policy"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// auditResult is the decision the current policies take on an existing channel, as if the peer
+// requested it today.
+type auditResult struct {
+	channel  *lnrpc.Channel
+	alias    string
+	err      error
+	policies []string
+}
+
+// runAudit evaluates our open channels against the current policies and reports the ones that
+// would be rejected.
+func runAudit(args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	configPath := fs.String("config", "acceptlnd.yml", "Path to the configuration file")
+	all := fs.Bool("all", false, "List the channels that would be accepted too")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	config, err := config.Load(*configPath)
+	if err != nil {
+		return err
+	}
+
+	client, err := lightning.NewClient(config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	a := newAcceptor(client, openPeerDatabase(config), config)
+	if a.db != nil {
+		defer a.db.Close()
+	}
+	if config.ASNDatabasePath != "" {
+		a.asns, err = asn.Load(config.ASNDatabasePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	results, err := a.audit(context.Background(), time.Now())
+	if err != nil {
+		return err
+	}
+	return writeAudit(os.Stdout, results, *all)
+}
+
+// audit evaluates every open channel as a request of the same parameters sent by the peer. Our
+// node is evaluated as it was before the channel was opened, so it doesn't count towards the
+// limits on our channels.
+//
+// Nothing is recorded: first seen times are estimated from the channels block heights and the
+// uptimes are the ones reported by LND.
+func (a *acceptor) audit(ctx context.Context, now time.Time) ([]auditResult, error) {
+	node, err := a.client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "getting node information")
+	}
+
+	resp, err := a.client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing channels")
+	}
+
+	if _, ok := a.loadGraphSnapshot(ctx); !ok {
+		if err := a.updateNeighborhood(ctx); err != nil {
+			return nil, err
+		}
+	}
+	network := a.network.Load()
+
+	var inbound uint64
+	firstSeen := make(map[string]time.Time)
+	uptimes := make(map[string]time.Duration)
+	for _, channel := range resp.Channels {
+		if !channel.Initiator {
+			inbound += uint64(channel.RemoteBalance)
+		}
+
+		height := uint32(channel.ChanId >> 40)
+		openedAt := now
+		if height != 0 && height <= node.BlockHeight {
+			openedAt = now.Add(-time.Duration(node.BlockHeight-height) * blockInterval)
+		}
+		if seen, ok := firstSeen[channel.RemotePubkey]; !ok || openedAt.Before(seen) {
+			firstSeen[channel.RemotePubkey] = openedAt
+		}
+
+		if uptime := time.Duration(channel.Uptime) * time.Second; uptime > uptimes[channel.RemotePubkey] {
+			uptimes[channel.RemotePubkey] = uptime
+		}
+	}
+
+	policies := a.getPolicies()
+	var origins *originSnapshot
+	if usesOrigins(policies) {
+		origins, err = a.channelOrigins(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]auditResult, 0, len(resp.Channels))
+	for _, channel := range resp.Channels {
+		publicKey := channel.RemotePubkey
+		peer, err := a.client.GetNodeInfo(ctx, &lnrpc.NodeInfoRequest{PubKey: publicKey, IncludeChannels: true})
+		if status.Code(err) == codes.NotFound {
+			peer, err = &lnrpc.NodeInfo{Node: &lnrpc.LightningNode{PubKey: publicKey}}, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "getting peer information")
+		}
+
+		facts := &policy.Facts{
+			Now:              now,
+			FirstSeen:        firstSeen[publicKey],
+			MaxChannelUptime: uptimes[publicKey],
+			Peers:            network.peers,
+			Reach:            network.reach,
+			InboundCapacity:  inbound,
+			KnownAs:          a.registry.Tags(publicKey),
+		}
+		if !channel.Initiator {
+			facts.InboundCapacity -= uint64(channel.RemoteBalance)
+		}
+		if origins != nil {
+			// The channel audited is counted if the peer origin is known
+			facts.SameIPChannels, facts.SameASNChannels = origins.count(publicKey)
+			facts.SameIPChannels = max(facts.SameIPChannels, 1) - 1
+			facts.SameASNChannels = max(facts.SameASNChannels, 1) - 1
+		}
+		if a.db != nil {
+			score, err := a.db.Score(publicKey)
+			if err != nil {
+				return nil, err
+			}
+			facts.Reputation = score.At(now, a.halfLife)
+
+			history, err := a.db.History(publicKey)
+			if err != nil {
+				return nil, err
+			}
+			setHistory(facts, history)
+		}
+		if usesConnection(policies) {
+			address, err := a.peerAddress(ctx, publicKey)
+			if err != nil {
+				return nil, err
+			}
+			facts.Address = address
+			facts.TorExit = a.isTorExit(address)
+		}
+
+		before := proto.Clone(node).(*lnrpc.GetInfoResponse)
+		if channel.Active && before.NumActiveChannels > 0 {
+			before.NumActiveChannels--
+		} else if !channel.Active && before.NumInactiveChannels > 0 {
+			before.NumInactiveChannels--
+		}
+
+		req := auditRequest(channel)
+		chanResp := &lnrpc.ChannelAcceptResponse{PendingChanId: req.PendingChanId}
+		decision, err := evaluatePolicies(policies, req, chanResp, before, peer, facts)
+		results = append(results, auditResult{
+			channel:  channel,
+			alias:    peer.Node.Alias,
+			err:      err,
+			policies: decision.policies,
+		})
+	}
+
+	return results, nil
+}
+
+// auditRequest returns the request the peer would send to open the channel. The parameters are
+// taken from the constraints the channel imposes on our side, except for the dust limit.
+func auditRequest(channel *lnrpc.Channel) *lnrpc.ChannelAcceptRequest {
+	nodePubkey, _ := hex.DecodeString(channel.RemotePubkey)
+	req := &lnrpc.ChannelAcceptRequest{
+		NodePubkey:     nodePubkey,
+		PendingChanId:  []byte(channel.ChannelPoint),
+		FundingAmt:     uint64(channel.Capacity),
+		CommitmentType: channel.CommitmentType,
+		WantsZeroConf:  channel.ZeroConf,
+		WantsScidAlias: len(channel.AliasScids) > 0,
+	}
+	if !channel.Private {
+		req.ChannelFlags = uint32(lnwire.FFAnnounceChannel)
+	}
+	if !channel.Initiator {
+		req.PushAmt = channel.PushAmountSat * 1000
+	}
+
+	if c := channel.LocalConstraints; c != nil {
+		req.CsvDelay = c.CsvDelay
+		req.ChannelReserve = c.ChanReserveSat
+		req.MaxValueInFlight = c.MaxPendingAmtMsat
+		req.MinHtlc = c.MinHtlcMsat
+		req.MaxAcceptedHtlcs = c.MaxAcceptedHtlcs
+	}
+	if c := channel.RemoteConstraints; c != nil {
+		req.DustLimit = c.DustLimitSat
+	}
+	return req
+}
+
+// writeAudit prints the channels that would be rejected, or all of them, and a summary.
+func writeAudit(w io.Writer, results []auditResult, all bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Channel point\tPeer\tAlias\tCapacity\tOpener\tDecision\tPolicies\t")
+
+	rejected := 0
+	for _, result := range results {
+		if result.err != nil {
+			rejected++
+		} else if !all {
+			continue
+		}
+
+		c := result.channel
+		opener := "peer"
+		if c.Initiator {
+			opener = "us"
+		}
+		verdict := "accepted"
+		if result.err != nil {
+			verdict = result.err.Error()
+		}
+		values := []string{
+			c.ChannelPoint, c.RemotePubkey, result.alias, strconv.FormatInt(c.Capacity, 10), opener,
+			verdict, strings.Join(result.policies, ", "),
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t")+"\t")
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\n%d of %d channels would be rejected by the current policies\n",
+		rejected, len(results))
+	return err
+}
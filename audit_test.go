@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/graph"
+	"github.com/aftermath2/acceptlnd/lightning/fake"
+	"github.com/aftermath2/acceptlnd/policy"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAudit(t *testing.T) {
+	old := "02" + strings.Repeat("aa", 32)
+	recent := "02" + strings.Repeat("bb", 32)
+	snapshot := graph.New(&lnrpc.ChannelGraph{
+		Nodes: []*lnrpc.LightningNode{{PubKey: "us"}, {PubKey: old, Alias: "old"}, {PubKey: recent}},
+		Edges: []*lnrpc.ChannelEdge{
+			// Opened by the peer 50000 blocks before the tip
+			{ChannelId: 800_000 << 40, ChanPoint: "old:0", Capacity: 2_000_000, Node1Pub: old, Node2Pub: "us"},
+			{ChannelId: 850_000 << 40, ChanPoint: "recent:0", Capacity: 500_000, Node1Pub: "us", Node2Pub: recent},
+		},
+	})
+	minCapacity := uint64(1_000_000)
+	minFirstSeen := uint64(30 * 24 * 60 * 60)
+
+	cases := []struct {
+		desc     string
+		policy   *policy.Policy
+		rejected []string
+	}{
+		{
+			desc:   "Accepted",
+			policy: &policy.Policy{},
+		},
+		{
+			desc: "Capacity",
+			policy: &policy.Policy{
+				Request: &policy.Request{ChannelCapacity: &policy.Range[uint64]{Min: &minCapacity}},
+			},
+			rejected: []string{"recent:0"},
+		},
+		{
+			desc: "First seen estimated from the block height",
+			policy: &policy.Policy{
+				Node: &policy.Node{FirstSeenAge: &policy.Range[uint64]{Min: &minFirstSeen}},
+			},
+			rejected: []string{"recent:0"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			a := newAcceptor(fake.New(snapshot, "us"), nil, config.Config{Policies: []*policy.Policy{tc.policy}})
+			results, err := a.audit(context.Background(), time.Now())
+			assert.NoError(t, err)
+			assert.Len(t, results, 2)
+
+			var rejected []string
+			for _, result := range results {
+				if result.err != nil {
+					rejected = append(rejected, result.channel.ChannelPoint)
+				}
+				if result.channel.RemotePubkey == old {
+					assert.Equal(t, "old", result.alias)
+				}
+			}
+			assert.Equal(t, tc.rejected, rejected)
+		})
+	}
+}
+
+func TestAuditRequest(t *testing.T) {
+	peer := "02" + strings.Repeat("aa", 32)
+	publicKey, err := hex.DecodeString(peer)
+	assert.NoError(t, err)
+
+	cases := []struct {
+		desc     string
+		channel  *lnrpc.Channel
+		expected *lnrpc.ChannelAcceptRequest
+	}{
+		{
+			desc: "Opened by the peer",
+			channel: &lnrpc.Channel{
+				RemotePubkey:   peer,
+				ChannelPoint:   "txid:0",
+				Capacity:       1_000_000,
+				PushAmountSat:  10_000,
+				CommitmentType: lnrpc.CommitmentType_ANCHORS,
+				ZeroConf:       true,
+				AliasScids:     []uint64{1},
+				LocalConstraints: &lnrpc.ChannelConstraints{
+					CsvDelay:          144,
+					ChanReserveSat:    10_000,
+					MaxPendingAmtMsat: 990_000_000,
+					MinHtlcMsat:       1,
+					MaxAcceptedHtlcs:  483,
+				},
+				RemoteConstraints: &lnrpc.ChannelConstraints{DustLimitSat: 354},
+			},
+			expected: &lnrpc.ChannelAcceptRequest{
+				NodePubkey:       publicKey,
+				PendingChanId:    []byte("txid:0"),
+				FundingAmt:       1_000_000,
+				PushAmt:          10_000_000,
+				CommitmentType:   lnrpc.CommitmentType_ANCHORS,
+				WantsZeroConf:    true,
+				WantsScidAlias:   true,
+				ChannelFlags:     uint32(lnwire.FFAnnounceChannel),
+				CsvDelay:         144,
+				ChannelReserve:   10_000,
+				MaxValueInFlight: 990_000_000,
+				MinHtlc:          1,
+				MaxAcceptedHtlcs: 483,
+				DustLimit:        354,
+			},
+		},
+		{
+			desc: "Private opened by us",
+			channel: &lnrpc.Channel{
+				RemotePubkey:  peer,
+				ChannelPoint:  "txid:1",
+				Capacity:      500_000,
+				PushAmountSat: 10_000,
+				Initiator:     true,
+				Private:       true,
+			},
+			expected: &lnrpc.ChannelAcceptRequest{
+				NodePubkey:    publicKey,
+				PendingChanId: []byte("txid:1"),
+				FundingAmt:    500_000,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.expected, auditRequest(tc.channel))
+		})
+	}
+}
+
+func TestWriteAudit(t *testing.T) {
+	results := []auditResult{
+		{
+			channel:  &lnrpc.Channel{ChannelPoint: "a:0", RemotePubkey: "peer", Capacity: 1_000_000},
+			alias:    "node",
+			policies: []string{"#0"},
+		},
+		{
+			channel:  &lnrpc.Channel{ChannelPoint: "b:0", RemotePubkey: "peer", Capacity: 500_000, Initiator: true},
+			err:      errors.New("Channel capacity is lower than 1000000"),
+			policies: []string{"#0"},
+		},
+	}
+
+	cases := []struct {
+		desc     string
+		all      bool
+		expected string
+	}{
+		{
+			desc: "Rejected",
+			expected: "Channel point  Peer  Alias  Capacity  Opener  Decision                                Policies  \n" +
+				"b:0            peer         500000    us      Channel capacity is lower than 1000000  #0        \n" +
+				"\n1 of 2 channels would be rejected by the current policies\n",
+		},
+		{
+			desc: "All",
+			all:  true,
+			expected: "Channel point  Peer  Alias  Capacity  Opener  Decision                                Policies  \n" +
+				"a:0            peer  node   1000000   peer    accepted                                #0        \n" +
+				"b:0            peer         500000    us      Channel capacity is lower than 1000000  #0        \n" +
+				"\n1 of 2 channels would be rejected by the current policies\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			assert.NoError(t, writeAudit(&buf, results, tc.all))
+			assert.Equal(t, tc.expected, buf.String())
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/metrics"
+	"github.com/aftermath2/acceptlnd/webhook"
+)
+
+// autoBlockPrefix prefixes the keys of the rejections counted and the nodes blocked automatically
+// in the storage, where they are kept along the flood protection ones.
+const autoBlockPrefix = "auto:"
+
+// autoBlockAlert identifies the alerts about nodes blocked automatically.
+const autoBlockAlert = "auto_blocklist"
+
+// blockedMessage is the error returned to the nodes blocked automatically, the same one the
+// policies blocklists return.
+const blockedMessage = "Node is blocked"
+
+// permanentBlock is the expiration of the permanent blocks, the latest time the storage encodes.
+var permanentBlock = time.Unix(0, math.MaxInt64)
+
+// isAutoBlocked returns whether the node was blocked after repeated rejections. The operator's
+// own services are never blocked.
+func (a *acceptor) isAutoBlocked(ctx context.Context, publicKey string) bool {
+	if a.autoBlock == nil || a.isSelfService(publicKey) {
+		return false
+	}
+
+	blocks, err := a.db.Blocks(time.Now())
+	if err != nil {
+		slog.ErrorContext(ctx, "Reading automatic blocks", slog.Any("error", err))
+		return false
+	}
+	_, ok := blocks[autoBlockPrefix+publicKey]
+	return ok
+}
+
+// autoBlocked returns the public keys of the nodes blocked automatically.
+func (a *acceptor) autoBlocked() []string {
+	if a.autoBlock == nil {
+		return nil
+	}
+
+	blocks, err := a.db.Blocks(time.Now())
+	if err != nil {
+		slog.Error("Reading automatic blocks", slog.Any("error", err))
+		return nil
+	}
+
+	var publicKeys []string
+	for key := range blocks {
+		if publicKey, ok := strings.CutPrefix(key, autoBlockPrefix); ok {
+			publicKeys = append(publicKeys, publicKey)
+		}
+	}
+	return publicKeys
+}
+
+// countRejection records a rejection of the node and blocks it if it was rejected too many times
+// within the window.
+func (a *acceptor) countRejection(ctx context.Context, publicKey string) {
+	if a.autoBlock == nil || a.isSelfService(publicKey) {
+		return
+	}
+
+	now := time.Now()
+	key := autoBlockPrefix + publicKey
+	rejections, err := a.db.CountRequest(key, now, a.autoBlock.Within)
+	if err != nil {
+		slog.ErrorContext(ctx, "Counting rejection", slog.Any("error", err))
+		return
+	}
+	if rejections < a.autoBlock.AfterRejections {
+		return
+	}
+
+	until := permanentBlock
+	if a.autoBlock.Duration > 0 {
+		until = now.Add(a.autoBlock.Duration)
+	}
+	blocked, err := a.db.Block(key, now, until)
+	if err != nil {
+		slog.ErrorContext(ctx, "Blocking node", slog.Any("error", err))
+		return
+	}
+	// Another instance sharing the database may have blocked it already
+	if !blocked {
+		return
+	}
+
+	metrics.CountAutoBlock()
+	attrs := []any{slog.String("public_key", publicKey), slog.Int("rejections", rejections)}
+	message := fmt.Sprintf("Node %s blocked permanently after %d rejections within %s",
+		publicKey, rejections, a.autoBlock.Within)
+	if a.autoBlock.Duration > 0 {
+		attrs = append(attrs, slog.Time("until", until))
+		message = fmt.Sprintf("Node %s blocked until %s after %d rejections within %s",
+			publicKey, until.UTC().Format(time.RFC3339), rejections, a.autoBlock.Within)
+	}
+	slog.WarnContext(ctx, "Node blocked after repeated rejections", attrs...)
+
+	if a.webhook == nil {
+		return
+	}
+	alert := webhook.Alert{Alert: autoBlockAlert, Message: message, At: now}
+	if err := a.webhook.SendAlert(alert); err != nil {
+		slog.ErrorContext(ctx, "Queuing alert for the webhook", slog.Any("error", err))
+	}
+}
+
+// pruneRejections deletes the rejections that fell out of the window and the blocks expired.
+func (a *acceptor) pruneRejections() error {
+	if a.autoBlock == nil {
+		return nil
+	}
+	return a.db.PruneRequests(autoBlockPrefix, time.Now().Add(-a.autoBlock.Within))
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoBlock(t *testing.T) {
+	ctx := context.Background()
+
+	cases := []struct {
+		desc       string
+		duration   time.Duration
+		publicKey  string
+		rejections int
+		blocked    bool
+	}{
+		{
+			desc:       "Below the threshold",
+			publicKey:  "peer",
+			rejections: 2,
+		},
+		{
+			desc:       "Permanent",
+			publicKey:  "peer",
+			rejections: 3,
+			blocked:    true,
+		},
+		{
+			desc:       "Temporary",
+			duration:   time.Hour,
+			publicKey:  "peer",
+			rejections: 4,
+			blocked:    true,
+		},
+		{
+			desc:       "Self service",
+			publicKey:  "self",
+			rejections: 3,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			db := store.NewMemory()
+			autoBlock := &config.AutoBlocklist{AfterRejections: 3, Within: time.Hour, Duration: tc.duration}
+			a := newAcceptor(nil, db, config.Config{AutoBlocklist: autoBlock, SelfServices: []string{"self"}})
+
+			for i := 0; i < tc.rejections; i++ {
+				a.countRejection(ctx, tc.publicKey)
+			}
+
+			assert.Equal(t, tc.blocked, a.isAutoBlocked(ctx, tc.publicKey))
+			assert.False(t, a.isAutoBlocked(ctx, "other"))
+			if !tc.blocked {
+				assert.Empty(t, a.autoBlocked())
+				return
+			}
+			assert.Equal(t, []string{tc.publicKey}, a.autoBlocked())
+
+			blocks, err := db.Blocks(time.Now())
+			assert.NoError(t, err)
+			until := blocks[autoBlockPrefix+tc.publicKey]
+			if tc.duration == 0 {
+				assert.Equal(t, permanentBlock, until)
+				return
+			}
+			assert.WithinDuration(t, time.Now().Add(tc.duration), until, time.Minute)
+
+			blocks, err = db.Blocks(time.Now().Add(2 * tc.duration))
+			assert.NoError(t, err)
+			assert.Empty(t, blocks)
+		})
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		a := newAcceptor(nil, nil, config.Config{AutoBlocklist: &config.AutoBlocklist{AfterRejections: 1}})
+		a.countRejection(ctx, "peer")
+		assert.False(t, a.isAutoBlocked(ctx, "peer"))
+		assert.Nil(t, a.autoBlocked())
+		assert.NoError(t, a.pruneRejections())
+	})
+}
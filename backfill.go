@@ -0,0 +1,229 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"flag"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/lightning"
+	"github.com/aftermath2/acceptlnd/reputation"
+	"github.com/aftermath2/acceptlnd/store"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/pkg/errors"
+)
+
+// blockInterval is the average time between blocks, used to estimate when the channels were
+// opened and closed from their heights.
+const blockInterval = 10 * time.Minute
+
+// backfillChannel is a channel opened by a peer before acceptLND recorded its decisions.
+type backfillChannel struct {
+	channelPoint string
+	publicKey    string
+	capacity     uint64
+	openedAt     time.Time
+	// Zero unless the peer force closed the channel
+	forceClosedAt time.Time
+}
+
+// runBackfill records the channels peers opened with us in the past, open and closed, so the
+// reputation, first seen ages and reports of nodes adopting acceptLND reflect their history.
+func runBackfill(args []string) error {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	configPath := fs.String("config", "acceptlnd.yml", "Path to the configuration file")
+	dryRun := fs.Bool("dry-run", false, "Print the channels found without recording them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	config, err := config.Load(*configPath)
+	if err != nil {
+		return err
+	}
+	if config.DatabasePath == "" || config.DatabaseBackend == store.BackendMemory {
+		return errors.New("the configuration has no database_path, decisions are not recorded")
+	}
+
+	client, err := lightning.NewClient(config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	channels, err := listBackfillChannels(context.Background(), client, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		for _, channel := range channels {
+			fmt.Printf("%s %s %d %s\n", channel.channelPoint, channel.publicKey, channel.capacity,
+				channel.openedAt.Format(time.DateOnly))
+		}
+		fmt.Printf("Found %d channels opened by peers\n", len(channels))
+		return nil
+	}
+
+	db, err := store.Open(config.DatabaseBackend, config.DatabasePath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	halfLife := config.ReputationHalfLife
+	if halfLife == 0 {
+		halfLife = reputation.DefaultHalfLife
+	}
+
+	recorded, err := backfill(db, channels, halfLife, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Recorded %d of %d channels opened by peers\n", recorded, len(channels))
+	return nil
+}
+
+// listBackfillChannels returns the open and closed channels initiated by peers. Their times are
+// estimated from the block heights.
+func listBackfillChannels(
+	ctx context.Context,
+	client lightning.Client,
+	now time.Time,
+) ([]backfillChannel, error) {
+	node, err := client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "getting node information")
+	}
+	timeAt := func(height uint32) time.Time {
+		return heightTime(node.BlockHeight, height, now)
+	}
+
+	open, err := client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing channels")
+	}
+	closed, err := client.ClosedChannels(ctx, &lnrpc.ClosedChannelsRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing closed channels")
+	}
+
+	var channels []backfillChannel
+	for _, channel := range open.Channels {
+		if channel.Initiator {
+			continue
+		}
+		channels = append(channels, backfillChannel{
+			channelPoint: channel.ChannelPoint,
+			publicKey:    channel.RemotePubkey,
+			capacity:     uint64(channel.Capacity),
+			openedAt:     timeAt(uint32(channel.ChanId >> 40)),
+		})
+	}
+	for _, channel := range closed.Channels {
+		if channel.OpenInitiator != lnrpc.Initiator_INITIATOR_REMOTE {
+			continue
+		}
+		c := backfillChannel{
+			channelPoint: channel.ChannelPoint,
+			publicKey:    channel.RemotePubkey,
+			capacity:     uint64(channel.Capacity),
+			openedAt:     timeAt(uint32(channel.ChanId >> 40)),
+		}
+		if channel.CloseType == lnrpc.ChannelCloseSummary_REMOTE_FORCE_CLOSE {
+			c.forceClosedAt = timeAt(channel.CloseHeight)
+		}
+		channels = append(channels, c)
+	}
+
+	return channels, nil
+}
+
+// heightTime estimates when the block at the height was mined, given the current tip. Unknown
+// heights, like the ones of the SCID aliases of the unconfirmed channels, are taken as now.
+func heightTime(tip, height uint32, now time.Time) time.Time {
+	if height == 0 || height > tip {
+		return now
+	}
+	return now.Add(-time.Duration(tip-height) * blockInterval)
+}
+
+// backfillEvent is a reputation event of a backfilled channel.
+type backfillEvent struct {
+	id      string
+	channel backfillChannel
+	event   reputation.Event
+	at      time.Time
+}
+
+// backfill records the acceptance and the force close of the channels as reputation events, once
+// per channel so it can be run again safely, and returns the number of channels recorded. Events
+// are applied chronologically, as that's how scores decay.
+//
+// Peers are marked as seen when they opened their first channel, and decisions flagged as
+// backfilled are created for the channels opened within the decisions retention period, older ones
+// would be pruned anyway.
+func backfill(
+	db store.Storage,
+	channels []backfillChannel,
+	halfLife time.Duration,
+	now time.Time,
+) (int, error) {
+	events := make([]backfillEvent, 0, len(channels))
+	for _, channel := range channels {
+		events = append(events, backfillEvent{
+			id:      "backfill:" + string(reputation.Accepted) + ":" + channel.channelPoint,
+			channel: channel,
+			event:   reputation.Accepted,
+			at:      channel.openedAt,
+		})
+		if !channel.forceClosedAt.IsZero() {
+			// Same ID the channels monitor uses, so the force close is not penalized twice
+			events = append(events, backfillEvent{
+				id:      string(reputation.ForceClosed) + ":" + channel.channelPoint,
+				channel: channel,
+				event:   reputation.ForceClosed,
+				at:      channel.forceClosedAt,
+			})
+		}
+	}
+	slices.SortStableFunc(events, func(a, b backfillEvent) int {
+		return cmp.Compare(a.at.UnixNano(), b.at.UnixNano())
+	})
+
+	recorded := 0
+	for _, e := range events {
+		applied, err := db.AddEventOnce(e.id, e.channel.publicKey, e.event, e.at, halfLife)
+		if err != nil {
+			return recorded, err
+		}
+		if !applied || e.event != reputation.Accepted {
+			continue
+		}
+		recorded++
+
+		if _, err := db.FirstSeen(e.channel.publicKey, e.at); err != nil {
+			return recorded, err
+		}
+
+		if e.at.After(now.Add(-decisionsRetention)) {
+			decision := store.Decision{
+				ID:         e.channel.channelPoint,
+				PublicKey:  e.channel.publicKey,
+				Capacity:   e.channel.capacity,
+				Accepted:   true,
+				Backfilled: true,
+				At:         e.at,
+			}
+			if err := db.AddDecision(decision); err != nil {
+				return recorded, err
+			}
+		}
+	}
+
+	return recorded, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/graph"
+	"github.com/aftermath2/acceptlnd/lightning/fake"
+	"github.com/aftermath2/acceptlnd/policy"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/pkg/errors"
+)
+
+// runBench replays synthetic channel requests from the nodes of a graph snapshot against the
+// policies, reporting the evaluation latency and allocations.
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	configPath := fs.String("config", "acceptlnd.yml", "Path to the configuration file")
+	peersPath := fs.String("peers", "", "Path to a graph snapshot, in JSON format (lncli describegraph) or exported by acceptlnd graph")
+	publicKey := fs.String("node", "", "Public key of our node in the graph")
+	rps := fs.Int("rps", 0, "Requests per second, zero evaluates them as fast as possible")
+	requests := fs.Int("requests", 1000, "Number of requests to evaluate")
+	seed := fs.Int64("seed", 1, "Seed used to generate the requests")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *peersPath == "" {
+		return errors.New("a graph snapshot must be provided with -peers")
+	}
+	if *requests <= 0 {
+		return errors.New("the number of requests must be greater than zero")
+	}
+
+	config, err := config.LoadPolicies(*configPath)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := graph.Load(*peersPath)
+	if err != nil {
+		return err
+	}
+
+	publicKeys := snapshot.PublicKeys()
+	if len(publicKeys) == 0 {
+		return errors.New("the graph snapshot has no nodes")
+	}
+
+	node := &lnrpc.GetInfoResponse{
+		IdentityPubkey: *publicKey,
+		BlockHeight:    snapshot.BlockHeight(),
+	}
+	if info, ok := snapshot.NodeInfo(*publicKey); ok {
+		node.NumActiveChannels = info.NumChannels
+	}
+
+	facts := &policy.Facts{
+		Peers: make(map[string]struct{}),
+		Reach: snapshot.Reach(*publicKey),
+	}
+	for _, peer := range snapshot.Neighbors(*publicKey) {
+		facts.Peers[peer] = struct{}{}
+	}
+
+	var interval time.Duration
+	if *rps > 0 {
+		interval = time.Second / time.Duration(*rps)
+	}
+
+	rnd := rand.New(rand.NewSource(*seed))
+	latencies := make([]time.Duration, 0, *requests)
+	accepted := 0
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	for i := 0; i < *requests; i++ {
+		if interval > 0 {
+			time.Sleep(time.Until(start.Add(time.Duration(i) * interval)))
+		}
+
+		peer, _ := snapshot.NodeInfo(publicKeys[rnd.Intn(len(publicKeys))])
+		req := fake.Request(rnd, peer)
+		resp := &lnrpc.ChannelAcceptResponse{PendingChanId: req.PendingChanId}
+
+		t := time.Now()
+		facts.Now = t
+		_, err := evaluatePolicies(config.Policies, req, resp, node, peer, facts)
+		latencies = append(latencies, time.Since(t))
+
+		if err == nil {
+			accepted++
+		}
+	}
+
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	printBenchResults(benchResults{
+		latencies: latencies,
+		accepted:  accepted,
+		elapsed:   elapsed,
+		allocs:    after.Mallocs - before.Mallocs,
+		bytes:     after.TotalAlloc - before.TotalAlloc,
+	})
+	return nil
+}
+
+type benchResults struct {
+	latencies []time.Duration
+	accepted  int
+	elapsed   time.Duration
+	allocs    uint64
+	bytes     uint64
+}
+
+func printBenchResults(res benchResults) {
+	n := len(res.latencies)
+	slices.Sort(res.latencies)
+
+	percentile := func(p float64) time.Duration {
+		return res.latencies[int(float64(n-1)*p)]
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Requests\t%d\n", n)
+	fmt.Fprintf(w, "Accepted\t%d\n", res.accepted)
+	fmt.Fprintf(w, "Rejected\t%d\n", n-res.accepted)
+	fmt.Fprintf(w, "Elapsed\t%s\n", res.elapsed)
+	fmt.Fprintf(w, "Throughput\t%.2f req/s\n", float64(n)/res.elapsed.Seconds())
+	fmt.Fprintf(w, "p50\t%s\n", percentile(0.5))
+	fmt.Fprintf(w, "p90\t%s\n", percentile(0.9))
+	fmt.Fprintf(w, "p99\t%s\n", percentile(0.99))
+	fmt.Fprintf(w, "Max\t%s\n", res.latencies[n-1])
+	fmt.Fprintf(w, "Allocs/req\t%d\n", res.allocs/uint64(n))
+	fmt.Fprintf(w, "Bytes/req\t%d\n", res.bytes/uint64(n))
+	w.Flush()
+}
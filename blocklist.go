@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/flood"
+
+	"github.com/pkg/errors"
+)
+
+// defaultBlocklistInterval is the time between blocklist writes when it's not configured.
+const defaultBlocklistInterval = time.Minute
+
+// blocklist returns the public keys of the nodes whose requests are rejected whatever they ask
+// for: the ones in the block lists of the policies without conditions, unless an override accepts
+// them, the ones rejected by an override and the ones blocked by the flood protection or after
+// repeated rejections. The operator's own services are never included.
+func (a *acceptor) blocklist() []string {
+	overrides := a.overrides()
+	keys := make(map[string]struct{})
+	for _, p := range a.getPolicies() {
+		if p.Conditions != nil || p.BlockList == nil {
+			continue
+		}
+		for _, publicKey := range *p.BlockList {
+			if override, ok := overrides[publicKey]; !ok || !override.Accept {
+				keys[publicKey] = struct{}{}
+			}
+		}
+	}
+
+	for publicKey, override := range overrides {
+		if !override.Accept {
+			keys[publicKey] = struct{}{}
+		}
+	}
+
+	if a.flood != nil {
+		for key := range a.flood.Blocked() {
+			if publicKey, ok := strings.CutPrefix(key, flood.KindNode+":"); ok {
+				keys[publicKey] = struct{}{}
+			}
+		}
+	}
+
+	for _, publicKey := range a.autoBlocked() {
+		keys[publicKey] = struct{}{}
+	}
+
+	list := make([]string, 0, len(keys))
+	for publicKey := range keys {
+		if !a.isSelfService(publicKey) {
+			list = append(list, publicKey)
+		}
+	}
+	slices.Sort(list)
+	return list
+}
+
+// exportBlocklist writes the blocklist to the file periodically, replacing it only when the list
+// changed.
+func (a *acceptor) exportBlocklist(ctx context.Context, path string, interval time.Duration) {
+	if interval == 0 {
+		interval = defaultBlocklistInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last []string
+	for {
+		list := a.blocklist()
+		if last == nil || !slices.Equal(list, last) {
+			if err := writeBlocklist(path, list); err != nil {
+				slog.Warn("Exporting blocklist", slog.Any("error", err))
+			} else {
+				last = list
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// writeBlocklist replaces the file with the public keys received, one per line.
+func writeBlocklist(path string, list []string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "creating blocklist file")
+	}
+	defer os.Remove(tmp.Name())
+
+	var content strings.Builder
+	for _, publicKey := range list {
+		content.WriteString(publicKey + "\n")
+	}
+	if _, err := tmp.WriteString(content.String()); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "writing blocklist file")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "writing blocklist file")
+	}
+
+	return errors.Wrap(os.Rename(tmp.Name(), path), "replacing blocklist file")
+}
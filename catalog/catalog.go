@@ -0,0 +1,136 @@
+// Package catalog translates the rejection reasons sent to the peers and included in the
+// notifications, so node runners serving non-English users can address them in their language.
+package catalog
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Languages available.
+const (
+	English = "en"
+	Spanish = "es"
+	German  = "de"
+)
+
+// Languages is the list of the languages available.
+var Languages = []string{English, Spanish, German}
+
+var (
+	commitmentTypesPattern = regexp.MustCompile(`^Commitment type is not in (.+)$`)
+	connectionNotInPattern = regexp.MustCompile(`^Node connection address is not in (.+)$`)
+	connectionInPattern    = regexp.MustCompile(`^Node connection address is in (.+)$`)
+	missingPolicyPattern   = regexp.MustCompile(`^(.+) unknown, channel (\d+) has no routing policy$`)
+	effectiveFeePattern    = regexp.MustCompile(`^Channels effective fee rate at (\d+) sats$`)
+	// Range violations, see policy.Range.Reason and policy.StatRange.Reason
+	violationPattern = regexp.MustCompile(`^(.+?) (?:(mean|median|mode|range|min|max) value )?` +
+		`(?:is not between (\S+) and (\S+)|is lower than (\S+)|is higher than (\S+))$`)
+)
+
+// catalog holds the messages of a language. The formats receive the translated arguments in the
+// same order as the English message.
+type catalog struct {
+	// Messages without arguments.
+	messages map[string]string
+	// Subjects of the range violations, like "Channel capacity".
+	subjects map[string]string
+	// Operations of the statistic ranges.
+	operations map[string]string
+
+	commitmentTypes string
+	connectionNotIn string
+	connectionIn    string
+	missingPolicy   string
+	effectiveFee    string
+	// violation joins the subject and the predicate.
+	violation string
+	// aggregate joins the statistic range operation and the predicate.
+	aggregate string
+	between   string
+	lower     string
+	higher    string
+}
+
+var catalogs = map[string]catalog{
+	Spanish: spanish,
+	German:  german,
+}
+
+// Supported returns whether the language is available, the empty string is English.
+func Supported(language string) bool {
+	if language == "" || language == English {
+		return true
+	}
+	_, ok := catalogs[language]
+	return ok
+}
+
+// Translate returns the message in the language. Messages that are not in the catalog, like the
+// ones received from a chained acceptor, are returned unchanged.
+func Translate(language, message string) string {
+	c, ok := catalogs[language]
+	if !ok {
+		return message
+	}
+	if translated, ok := c.translate(message); ok {
+		return translated
+	}
+	return message
+}
+
+func (c catalog) translate(message string) (string, bool) {
+	if translated, ok := c.messages[message]; ok {
+		return translated, true
+	}
+
+	if m := commitmentTypesPattern.FindStringSubmatch(message); m != nil {
+		return fmt.Sprintf(c.commitmentTypes, m[1]), true
+	}
+	if m := connectionNotInPattern.FindStringSubmatch(message); m != nil {
+		return fmt.Sprintf(c.connectionNotIn, m[1]), true
+	}
+	if m := connectionInPattern.FindStringSubmatch(message); m != nil {
+		return fmt.Sprintf(c.connectionIn, m[1]), true
+	}
+	if m := missingPolicyPattern.FindStringSubmatch(message); m != nil {
+		subject, ok := c.subject(m[1])
+		if !ok {
+			return "", false
+		}
+		return fmt.Sprintf(c.missingPolicy, subject, m[2]), true
+	}
+
+	m := violationPattern.FindStringSubmatch(message)
+	if m == nil {
+		return "", false
+	}
+	subject, ok := c.subject(m[1])
+	if !ok {
+		return "", false
+	}
+
+	var predicate string
+	switch {
+	case m[3] != "":
+		predicate = fmt.Sprintf(c.between, m[3], m[4])
+	case m[5] != "":
+		predicate = fmt.Sprintf(c.lower, m[5])
+	default:
+		predicate = fmt.Sprintf(c.higher, m[6])
+	}
+	if m[2] != "" {
+		predicate = fmt.Sprintf(c.aggregate, c.operations[m[2]], predicate)
+	}
+	return fmt.Sprintf(c.violation, subject, predicate), true
+}
+
+func (c catalog) subject(subject string) (string, bool) {
+	if translated, ok := c.subjects[subject]; ok {
+		return translated, true
+	}
+	if m := effectiveFeePattern.FindStringSubmatch(subject); m != nil {
+		return fmt.Sprintf(c.effectiveFee, m[1]), true
+	}
+	return "", false
+}
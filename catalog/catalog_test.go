@@ -0,0 +1,116 @@
+package catalog
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslate(t *testing.T) {
+	cases := []struct {
+		desc     string
+		language string
+		message  string
+		expected string
+	}{
+		{
+			desc:     "English",
+			language: English,
+			message:  "Node is blocked",
+			expected: "Node is blocked",
+		},
+		{
+			desc:     "Unknown language",
+			language: "fr",
+			message:  "Node is blocked",
+			expected: "Node is blocked",
+		},
+		{
+			desc:     "Message",
+			language: Spanish,
+			message:  "Node is blocked",
+			expected: "El nodo está bloqueado",
+		},
+		{
+			desc:     "Range",
+			language: Spanish,
+			message:  "Channel capacity is lower than 1000000",
+			expected: "Capacidad del canal: menor que 1000000",
+		},
+		{
+			desc:     "Range between",
+			language: German,
+			message:  "Node age is not between 100 and 200",
+			expected: "Alter des Knotens: außerhalb von 100 bis 200",
+		},
+		{
+			desc:     "Statistic range",
+			language: German,
+			message:  "Channels fee rates median value is higher than 500",
+			expected: "Gebührensätze der Kanäle: Median größer als 500",
+		},
+		{
+			desc:     "Effective fee",
+			language: Spanish,
+			message:  "Channels effective fee rate at 100000 sats mean value is higher than 1000",
+			expected: "Tasa de comisión efectiva de los canales a 100000 sats: media mayor que 1000",
+		},
+		{
+			desc:     "Missing policy",
+			language: German,
+			message:  "Channels fee rates unknown, channel 123 has no routing policy",
+			expected: "Gebührensätze der Kanäle: unbekannt, Kanal 123 hat keine Routing-Richtlinie",
+		},
+		{
+			desc:     "Arguments",
+			language: Spanish,
+			message:  "Node connection address is not in [clearnet]",
+			expected: "La dirección de conexión del nodo no está en [clearnet]",
+		},
+		{
+			desc:     "Unknown subject",
+			language: Spanish,
+			message:  "Chained acceptor score is lower than 3",
+			expected: "Chained acceptor score is lower than 3",
+		},
+		{
+			desc:     "Unknown message",
+			language: German,
+			message:  "Rejected by the chained acceptor",
+			expected: "Rejected by the chained acceptor",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.expected, Translate(tc.language, tc.message))
+		})
+	}
+}
+
+func TestCatalogsComplete(t *testing.T) {
+	keys := func(m map[string]string) []string {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		return keys
+	}
+
+	for language, c := range catalogs {
+		t.Run(language, func(t *testing.T) {
+			assert.Equal(t, keys(spanish.messages), keys(c.messages))
+			assert.Equal(t, keys(spanish.subjects), keys(c.subjects))
+			assert.Equal(t, keys(spanish.operations), keys(c.operations))
+		})
+	}
+}
+
+func TestSupported(t *testing.T) {
+	for _, language := range append(Languages, "") {
+		assert.True(t, Supported(language), language)
+	}
+	assert.False(t, Supported("fr"))
+}
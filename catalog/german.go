@@ -0,0 +1,98 @@
+package catalog
+
+var german = catalog{
+	messages: map[string]string{
+		"Internal server error":                             "Interner Serverfehler",
+		"Too many requests, try again later":                "Zu viele Anfragen, versuche es später erneut",
+		"Node graph is out of sync, try again later":        "Der Graph des Knotens ist nicht synchronisiert, versuche es später erneut",
+		"Node has too many channels":                        "Der Knoten hat zu viele Kanäle",
+		"Channel rejected":                                  "Kanal abgelehnt",
+		"No new channels are accepted":                      "Es werden keine neuen Kanäle angenommen",
+		"Node is not allowed":                               "Der Knoten ist nicht zugelassen",
+		"Node is blocked":                                   "Der Knoten ist gesperrt",
+		"Private channels are not accepted":                 "Private Kanäle werden nicht angenommen",
+		"Zero conf channels are not accepted":               "Kanäle ohne Bestätigungen werden nicht angenommen",
+		"Maximum total inbound capacity reached":            "Maximale eingehende Gesamtkapazität erreicht",
+		"Maximum number of channels reached":                "Die maximale Anzahl an Kanälen ist erreicht",
+		"Maximum number of channels per IP address reached": "Die maximale Anzahl an Kanälen pro IP-Adresse ist erreicht",
+		"Maximum number of channels per ASN reached":        "Die maximale Anzahl an Kanälen pro ASN ist erreicht",
+		"Node has channels with base fees higher than zero": "Der Knoten hat Kanäle mit Grundgebühren über null",
+		"Node is not connected through Tor":                 "Der Knoten ist nicht über Tor verbunden",
+		"Node is connected through Tor":                     "Der Knoten ist über Tor verbunden",
+		"Node doesn't have both clearnet and tor addresses": "Der Knoten hat nicht sowohl Clearnet- als auch Tor-Adressen",
+		"Node doesn't have the desired feature flags":       "Der Knoten hat nicht die geforderten Feature-Flags",
+		"Node is not reachable on its announced addresses":  "Der Knoten ist unter seinen angekündigten Adressen nicht erreichbar",
+		"Node is not known as any of the categories":        "Der Knoten ist in keiner der Kategorien bekannt",
+		"Node is reachable on its announced addresses":      "Der Knoten ist unter seinen angekündigten Adressen erreichbar",
+		"Node doesn't look like a mobile wallet":            "Der Knoten sieht nicht wie eine mobile Wallet aus",
+		"Node looks like a mobile wallet":                   "Der Knoten sieht wie eine mobile Wallet aus",
+		"Node doesn't support splicing":                     "Der Knoten unterstützt kein Splicing",
+		"Node supports splicing":                            "Der Knoten unterstützt Splicing",
+		"Node doesn't support dual funding":                 "Der Knoten unterstützt keine duale Finanzierung",
+		"Node supports dual funding":                        "Der Knoten unterstützt duale Finanzierung",
+		"Pushed amount lower than expected":                 "Übertragener Betrag niedriger als erwartet",
+		"Node not found in the graph":                       "Der Knoten wurde im Graph nicht gefunden",
+	},
+	subjects: map[string]string{
+		"Node age":                          "Alter des Knotens",
+		"Node capacity":                     "Kapazität des Knotens",
+		"Node number of channels":           "Anzahl der Kanäle des Knotens",
+		"Node first seen age":               "Zeit seit der ersten Anfrage des Knotens",
+		"Node graph freshness":              "Alter der Knotenankündigung",
+		"Node new reach":                    "Neue Reichweite des Knotens",
+		"Node peer overlap ratio":           "Anteil gemeinsamer Partner des Knotens",
+		"Node reputation":                   "Reputation des Knotens",
+		"Node previous rejections":          "Bisherige Ablehnungen des Knotens",
+		"Node previous acceptances":         "Bisherige Annahmen des Knotens",
+		"Node last decision age":            "Zeit seit der letzten Entscheidung über den Knoten",
+		"Capacity":                          "Kapazität der Kanäle",
+		"Block height":                      "Blockhöhe der Kanäle",
+		"Time lock delta":                   "Time-Lock-Delta der Kanäle",
+		"Channels minimum HTLC":             "Minimaler HTLC der Kanäle",
+		"Channels maximum HTLC":             "Maximaler HTLC der Kanäle",
+		"Channels last update":              "Letzte Aktualisierung der Kanäle",
+		"Channels together":                 "Gemeinsame Kanäle",
+		"Channels fee rates":                "Gebührensätze der Kanäle",
+		"Channels base fees":                "Grundgebühren der Kanäle",
+		"Channels inbound fee rates":        "Eingehende Gebührensätze der Kanäle",
+		"Channels inbound base fees":        "Eingehende Grundgebühren der Kanäle",
+		"Channels announcing inbound fees":  "Kanäle mit eingehenden Gebühren",
+		"Disabled channels":                 "Deaktivierte Kanäle",
+		"Peers fee rates":                   "Gebührensätze der Partner",
+		"Peers base fees":                   "Grundgebühren der Partner",
+		"Peers inbound fee rates":           "Eingehende Gebührensätze der Partner",
+		"Peers inbound base fees":           "Eingehende Grundgebühren der Partner",
+		"Peers disabled channels":           "Von den Partnern deaktivierte Kanäle",
+		"Fee rates toward us":               "Gebührensätze zu uns",
+		"Base fees toward us":               "Grundgebühren zu uns",
+		"Inbound fee rates toward us":       "Eingehende Gebührensätze zu uns",
+		"Inbound base fees toward us":       "Eingehende Grundgebühren zu uns",
+		"Channel capacity":                  "Kanalkapazität",
+		"Channel capacity for new peers":    "Kanalkapazität für neue Partner",
+		"Channel reserve":                   "Kanalreserve",
+		"Check sequence verify delay":       "CSV-Verzögerung",
+		"Maximum accepted HTLCs":            "Maximal akzeptierte HTLCs",
+		"Minimum HTLCs":                     "Minimaler HTLC",
+		"Maximum value in flight":           "Maximaler Wert in Transit",
+		"Commitment transaction dust limit": "Dust-Limit der Commitment-Transaktion",
+		"Channel sweep cost ratio":          "Anteil der Sweep-Kosten des Kanals",
+	},
+	operations: map[string]string{
+		"mean":   "Mittelwert",
+		"median": "Median",
+		"mode":   "Modus",
+		"range":  "Spannweite",
+		"min":    "Minimum",
+		"max":    "Maximum",
+	},
+	commitmentTypes: "Der Commitment-Typ ist nicht in %s",
+	connectionNotIn: "Die Verbindungsadresse des Knotens ist nicht in %s",
+	connectionIn:    "Die Verbindungsadresse des Knotens ist in %s",
+	missingPolicy:   "%s: unbekannt, Kanal %s hat keine Routing-Richtlinie",
+	effectiveFee:    "Effektiver Gebührensatz der Kanäle bei %s sats",
+	violation:       "%s: %s",
+	aggregate:       "%s %s",
+	between:         "außerhalb von %s bis %s",
+	lower:           "kleiner als %s",
+	higher:          "größer als %s",
+}
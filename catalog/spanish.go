@@ -0,0 +1,98 @@
+package catalog
+
+var spanish = catalog{
+	messages: map[string]string{
+		"Internal server error":                             "Error interno del servidor",
+		"Too many requests, try again later":                "Demasiadas solicitudes, inténtalo más tarde",
+		"Node graph is out of sync, try again later":        "El grafo del nodo no está sincronizado, inténtalo más tarde",
+		"Node has too many channels":                        "El nodo tiene demasiados canales",
+		"Channel rejected":                                  "Canal rechazado",
+		"No new channels are accepted":                      "No se aceptan canales nuevos",
+		"Node is not allowed":                               "El nodo no está permitido",
+		"Node is blocked":                                   "El nodo está bloqueado",
+		"Private channels are not accepted":                 "No se aceptan canales privados",
+		"Zero conf channels are not accepted":               "No se aceptan canales sin confirmaciones",
+		"Maximum number of channels reached":                "Se alcanzó el número máximo de canales",
+		"Maximum number of channels per IP address reached": "Se alcanzó el número máximo de canales por dirección IP",
+		"Maximum number of channels per ASN reached":        "Se alcanzó el número máximo de canales por ASN",
+		"Maximum total inbound capacity reached":            "Se alcanzó la capacidad entrante total máxima",
+		"Node has channels with base fees higher than zero": "El nodo tiene canales con comisiones base mayores que cero",
+		"Node is not connected through Tor":                 "El nodo no está conectado a través de Tor",
+		"Node is connected through Tor":                     "El nodo está conectado a través de Tor",
+		"Node doesn't have both clearnet and tor addresses": "El nodo no tiene direcciones clearnet y tor a la vez",
+		"Node doesn't have the desired feature flags":       "El nodo no tiene las funcionalidades requeridas",
+		"Node is not reachable on its announced addresses":  "El nodo no es accesible en sus direcciones anunciadas",
+		"Node is not known as any of the categories":        "El nodo no es conocido en ninguna de las categorías",
+		"Node is reachable on its announced addresses":      "El nodo es accesible en sus direcciones anunciadas",
+		"Node doesn't look like a mobile wallet":            "El nodo no parece una billetera móvil",
+		"Node looks like a mobile wallet":                   "El nodo parece una billetera móvil",
+		"Node doesn't support splicing":                     "El nodo no soporta splicing",
+		"Node supports splicing":                            "El nodo soporta splicing",
+		"Node doesn't support dual funding":                 "El nodo no soporta financiamiento dual",
+		"Node supports dual funding":                        "El nodo soporta financiamiento dual",
+		"Pushed amount lower than expected":                 "Monto enviado menor que el esperado",
+		"Node not found in the graph":                       "El nodo no se encuentra en el grafo",
+	},
+	subjects: map[string]string{
+		"Node age":                          "Antigüedad del nodo",
+		"Node capacity":                     "Capacidad del nodo",
+		"Node number of channels":           "Número de canales del nodo",
+		"Node first seen age":               "Tiempo desde la primera solicitud del nodo",
+		"Node graph freshness":              "Antigüedad del anuncio del nodo",
+		"Node new reach":                    "Alcance nuevo del nodo",
+		"Node peer overlap ratio":           "Proporción de pares en común con el nodo",
+		"Node reputation":                   "Reputación del nodo",
+		"Node previous rejections":          "Rechazos previos del nodo",
+		"Node previous acceptances":         "Aceptaciones previas del nodo",
+		"Node last decision age":            "Tiempo desde la última decisión sobre el nodo",
+		"Capacity":                          "Capacidad de los canales",
+		"Block height":                      "Altura de bloque de los canales",
+		"Time lock delta":                   "Time lock delta de los canales",
+		"Channels minimum HTLC":             "HTLC mínimo de los canales",
+		"Channels maximum HTLC":             "HTLC máximo de los canales",
+		"Channels last update":              "Última actualización de los canales",
+		"Channels together":                 "Canales en común",
+		"Channels fee rates":                "Tasas de comisión de los canales",
+		"Channels base fees":                "Comisiones base de los canales",
+		"Channels inbound fee rates":        "Tasas de comisión entrantes de los canales",
+		"Channels inbound base fees":        "Comisiones base entrantes de los canales",
+		"Channels announcing inbound fees":  "Canales que anuncian comisiones entrantes",
+		"Disabled channels":                 "Canales deshabilitados",
+		"Peers fee rates":                   "Tasas de comisión de los pares",
+		"Peers base fees":                   "Comisiones base de los pares",
+		"Peers inbound fee rates":           "Tasas de comisión entrantes de los pares",
+		"Peers inbound base fees":           "Comisiones base entrantes de los pares",
+		"Peers disabled channels":           "Canales deshabilitados por los pares",
+		"Fee rates toward us":               "Tasas de comisión hacia nosotros",
+		"Base fees toward us":               "Comisiones base hacia nosotros",
+		"Inbound fee rates toward us":       "Tasas de comisión entrantes hacia nosotros",
+		"Inbound base fees toward us":       "Comisiones base entrantes hacia nosotros",
+		"Channel capacity":                  "Capacidad del canal",
+		"Channel capacity for new peers":    "Capacidad del canal para pares nuevos",
+		"Channel reserve":                   "Reserva del canal",
+		"Check sequence verify delay":       "Retraso CSV",
+		"Maximum accepted HTLCs":            "Máximo de HTLCs aceptados",
+		"Minimum HTLCs":                     "HTLC mínimo",
+		"Maximum value in flight":           "Valor máximo en tránsito",
+		"Commitment transaction dust limit": "Límite de dust de la transacción de compromiso",
+		"Channel sweep cost ratio":          "Proporción del costo de barrido del canal",
+	},
+	operations: map[string]string{
+		"mean":   "media",
+		"median": "mediana",
+		"mode":   "moda",
+		"range":  "rango",
+		"min":    "mínimo",
+		"max":    "máximo",
+	},
+	commitmentTypes: "El tipo de compromiso no está en %s",
+	connectionNotIn: "La dirección de conexión del nodo no está en %s",
+	connectionIn:    "La dirección de conexión del nodo está en %s",
+	missingPolicy:   "%s: desconocido, el canal %s no tiene política de enrutamiento",
+	effectiveFee:    "Tasa de comisión efectiva de los canales a %s sats",
+	violation:       "%s: %s",
+	aggregate:       "%s %s",
+	between:         "fuera del rango de %s a %s",
+	lower:           "menor que %s",
+	higher:          "mayor que %s",
+}
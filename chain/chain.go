@@ -0,0 +1,242 @@
+// Package chain lets other channel acceptors, like legacy scripts, connect to acceptLND as if it
+// was LND, so their verdicts can be combined with the policies ones.
+package chain
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+
+	"github.com/aftermath2/acceptlnd/config"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// ErrUnavailable is returned when there is no channel acceptor connected to evaluate a request,
+// or it disconnected before responding.
+var ErrUnavailable = errors.New("chained channel acceptor unavailable")
+
+// defaultHost is the host the server listens on when the address doesn't have one.
+const defaultHost = "127.0.0.1"
+
+// Server implements LND's ChannelAcceptor RPC, the rest of the methods are unimplemented.
+type Server struct {
+	lnrpc.UnimplementedLightningServer
+
+	address string
+	or      bool
+	grpc    *grpc.Server
+
+	mu      sync.Mutex
+	stream  lnrpc.Lightning_ChannelAcceptorServer
+	pending map[string]chan *lnrpc.ChannelAcceptResponse
+}
+
+// New returns a new server for other channel acceptors to connect to.
+func New(config config.Chain) (*Server, error) {
+	var opts []grpc.ServerOption
+	if config.CertificatePath != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertificatePath, config.KeyPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "loading chain certificate")
+		}
+		tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	// Listening on all the interfaces must be explicit
+	address := config.Address
+	if host, port, err := net.SplitHostPort(address); err == nil && host == "" {
+		address = net.JoinHostPort(defaultHost, port)
+	}
+
+	s := &Server{
+		address: address,
+		or:      config.Mode == "or",
+		grpc:    grpc.NewServer(opts...),
+		pending: make(map[string]chan *lnrpc.ChannelAcceptResponse),
+	}
+	lnrpc.RegisterLightningServer(s.grpc, s)
+	return s, nil
+}
+
+// ListenAndServe listens on the configured address and serves the incoming connections.
+func (s *Server) ListenAndServe() error {
+	lis, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return errors.Wrap(err, "listening")
+	}
+
+	slog.Info("Waiting for a chained channel acceptor", slog.String("address", s.address))
+	return s.Serve(lis)
+}
+
+// Serve serves the connections accepted by the listener.
+func (s *Server) Serve(lis net.Listener) error {
+	return s.grpc.Serve(lis)
+}
+
+// Stop closes the listener and the open connections.
+func (s *Server) Stop() {
+	s.grpc.Stop()
+}
+
+// ChannelAcceptor forwards the channel requests to the connected acceptor and dispatches its
+// responses. Only one acceptor can be connected at the same time.
+func (s *Server) ChannelAcceptor(stream lnrpc.Lightning_ChannelAcceptorServer) error {
+	s.mu.Lock()
+	if s.stream != nil {
+		s.mu.Unlock()
+		return status.Error(codes.AlreadyExists, "a channel acceptor is already connected")
+	}
+	s.stream = stream
+	s.mu.Unlock()
+	slog.Info("Chained channel acceptor connected")
+
+	defer func() {
+		s.mu.Lock()
+		s.stream = nil
+		for id, ch := range s.pending {
+			close(ch)
+			delete(s.pending, id)
+		}
+		s.mu.Unlock()
+		slog.Warn("Chained channel acceptor disconnected")
+	}()
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			if err == io.EOF || status.Code(err) == codes.Canceled {
+				return nil
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		ch, ok := s.pending[string(resp.PendingChanId)]
+		delete(s.pending, string(resp.PendingChanId))
+		s.mu.Unlock()
+		if !ok {
+			slog.Warn("Unexpected response from the chained channel acceptor")
+			continue
+		}
+		ch <- resp
+	}
+}
+
+// Evaluate sends the request to the connected acceptor and waits for its response until the
+// context is cancelled.
+func (s *Server) Evaluate(
+	ctx context.Context,
+	req *lnrpc.ChannelAcceptRequest,
+) (*lnrpc.ChannelAcceptResponse, error) {
+	id := string(req.PendingChanId)
+	ch := make(chan *lnrpc.ChannelAcceptResponse, 1)
+
+	s.mu.Lock()
+	if s.stream == nil {
+		s.mu.Unlock()
+		return nil, ErrUnavailable
+	}
+	s.pending[id] = ch
+	err := s.stream.Send(req)
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, id)
+		s.mu.Unlock()
+	}()
+
+	if err != nil {
+		return nil, errors.Wrap(err, "sending request to the chained channel acceptor")
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case resp, ok := <-ch:
+		if !ok {
+			return nil, ErrUnavailable
+		}
+		return resp, nil
+	}
+}
+
+// Combine merges the verdict of the connected acceptor with the one of the policies, given by err,
+// and returns the resulting error. With the "and" mode the acceptor is only consulted if the
+// policies accept the request, with the "or" mode if they reject it, in which case the acceptor's
+// response parameters are combined with the policies ones in resp if it accepts. It also reports
+// whether the acceptor was consulted.
+//
+// If the acceptor is not available, the request is rejected in "and" mode and the policies
+// verdict is kept in "or" mode.
+func (s *Server) Combine(
+	ctx context.Context,
+	req *lnrpc.ChannelAcceptRequest,
+	resp *lnrpc.ChannelAcceptResponse,
+	err error,
+) (bool, error) {
+	// The verdict is already decided by the policies
+	if (s.or && err == nil) || (!s.or && err != nil) {
+		return false, err
+	}
+
+	chained, chainErr := s.Evaluate(ctx, req)
+	if chainErr != nil {
+		slog.Error("Evaluating request with the chained channel acceptor", slog.Any("error", chainErr))
+		if s.or {
+			return true, err
+		}
+		return true, errors.New("Internal server error")
+	}
+
+	if !chained.Accept {
+		if s.or {
+			return true, err
+		}
+		if chained.Error != "" {
+			return true, errors.New(chained.Error)
+		}
+		return true, errors.New("Channel rejected")
+	}
+
+	if s.or {
+		combineParameters(resp, chained)
+	}
+	return true, nil
+}
+
+// combineParameters merges the channel parameters of the chained acceptor response into the
+// policies one, keeping the most conservative value of each, so the acceptor can't loosen what the
+// policies set. Zero values are left to LND's defaults, and zero conf channels are only accepted if
+// both ask for them.
+func combineParameters(resp, chained *lnrpc.ChannelAcceptResponse) {
+	if resp.UpfrontShutdown == "" {
+		resp.UpfrontShutdown = chained.UpfrontShutdown
+	}
+	resp.CsvDelay = max(resp.CsvDelay, chained.CsvDelay)
+	resp.ReserveSat = max(resp.ReserveSat, chained.ReserveSat)
+	resp.InFlightMaxMsat = minSet(resp.InFlightMaxMsat, chained.InFlightMaxMsat)
+	resp.MaxHtlcCount = minSet(resp.MaxHtlcCount, chained.MaxHtlcCount)
+	resp.MinHtlcIn = max(resp.MinHtlcIn, chained.MinHtlcIn)
+	resp.MinAcceptDepth = max(resp.MinAcceptDepth, chained.MinAcceptDepth)
+	resp.ZeroConf = resp.ZeroConf && chained.ZeroConf
+}
+
+// minSet returns the lowest of the values that are set, zero if none is.
+func minSet[T uint32 | uint64](a, b T) T {
+	if a == 0 || b == 0 {
+		return max(a, b)
+	}
+	return min(a, b)
+}
@@ -0,0 +1,254 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// connectAcceptor connects a channel acceptor to the server that accepts the requests funding at
+// least 1M sats, with a CSV delay of 144 blocks.
+func connectAcceptor(t *testing.T, s *Server) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	go s.Serve(listener)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient(listener.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	stream, err := lnrpc.NewLightningClient(conn).ChannelAcceptor(context.Background())
+	assert.NoError(t, err)
+
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				return
+			}
+
+			resp := &lnrpc.ChannelAcceptResponse{PendingChanId: req.PendingChanId}
+			if req.FundingAmt >= 1_000_000 {
+				resp.Accept = true
+				resp.CsvDelay = 144
+			} else {
+				resp.Error = "Channel too small"
+			}
+			if err := stream.Send(resp); err != nil {
+				return
+			}
+		}
+	}()
+
+	assert.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.stream != nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestCombine(t *testing.T) {
+	rejection := errors.New("Node capacity is lower than 100000000")
+
+	cases := []struct {
+		desc         string
+		mode         string
+		err          error
+		expectedErr  string
+		amount       uint64
+		expectedCsv  uint32
+		consulted    bool
+		disconnected bool
+	}{
+		{
+			desc:      "And both accept",
+			mode:      "and",
+			amount:    2_000_000,
+			consulted: true,
+		},
+		{
+			desc:        "And chained rejects",
+			mode:        "and",
+			amount:      500_000,
+			consulted:   true,
+			expectedErr: "Channel too small",
+		},
+		{
+			desc:        "And policies reject",
+			mode:        "and",
+			amount:      2_000_000,
+			err:         rejection,
+			expectedErr: rejection.Error(),
+		},
+		{
+			desc:         "And unavailable",
+			mode:         "and",
+			amount:       2_000_000,
+			consulted:    true,
+			disconnected: true,
+			expectedErr:  "Internal server error",
+		},
+		{
+			desc:   "Or policies accept",
+			amount: 500_000,
+			mode:   "or",
+		},
+		{
+			desc:        "Or chained accepts",
+			mode:        "or",
+			amount:      2_000_000,
+			err:         rejection,
+			consulted:   true,
+			expectedCsv: 144,
+		},
+		{
+			desc:        "Or both reject",
+			mode:        "or",
+			amount:      500_000,
+			err:         rejection,
+			consulted:   true,
+			expectedErr: rejection.Error(),
+		},
+		{
+			desc:         "Or unavailable",
+			mode:         "or",
+			amount:       2_000_000,
+			err:          rejection,
+			consulted:    true,
+			disconnected: true,
+			expectedErr:  rejection.Error(),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := New(config.Chain{Address: "localhost:0", Mode: tc.mode})
+			assert.NoError(t, err)
+			if !tc.disconnected {
+				connectAcceptor(t, s)
+			}
+
+			req := &lnrpc.ChannelAcceptRequest{PendingChanId: []byte{1}, FundingAmt: tc.amount}
+			resp := &lnrpc.ChannelAcceptResponse{PendingChanId: req.PendingChanId}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			consulted, err := s.Combine(ctx, req, resp, tc.err)
+			assert.Equal(t, tc.consulted, consulted)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedCsv, resp.CsvDelay)
+		})
+	}
+}
+
+func TestSingleAcceptor(t *testing.T) {
+	s, err := New(config.Chain{Address: "localhost:0"})
+	assert.NoError(t, err)
+	connectAcceptor(t, s)
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	go s.Serve(listener)
+
+	conn, err := grpc.NewClient(listener.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	stream, err := lnrpc.NewLightningClient(conn).ChannelAcceptor(context.Background())
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.ErrorContains(t, err, "already connected")
+}
+
+func TestCombineParameters(t *testing.T) {
+	cases := []struct {
+		desc     string
+		resp     *lnrpc.ChannelAcceptResponse
+		chained  *lnrpc.ChannelAcceptResponse
+		expected *lnrpc.ChannelAcceptResponse
+	}{
+		{
+			desc:     "Policies defaults",
+			resp:     &lnrpc.ChannelAcceptResponse{},
+			chained:  &lnrpc.ChannelAcceptResponse{CsvDelay: 144, MaxHtlcCount: 30, UpfrontShutdown: "bc1q"},
+			expected: &lnrpc.ChannelAcceptResponse{CsvDelay: 144, MaxHtlcCount: 30, UpfrontShutdown: "bc1q"},
+		},
+		{
+			desc: "Most conservative",
+			resp: &lnrpc.ChannelAcceptResponse{
+				CsvDelay:        288,
+				ReserveSat:      10_000,
+				InFlightMaxMsat: 500_000_000,
+				MaxHtlcCount:    100,
+				MinHtlcIn:       1_000,
+				MinAcceptDepth:  6,
+				UpfrontShutdown: "bc1p",
+			},
+			chained: &lnrpc.ChannelAcceptResponse{
+				CsvDelay:        144,
+				ReserveSat:      20_000,
+				InFlightMaxMsat: 900_000_000,
+				MaxHtlcCount:    50,
+				MinHtlcIn:       1,
+				MinAcceptDepth:  1,
+				UpfrontShutdown: "bc1q",
+			},
+			expected: &lnrpc.ChannelAcceptResponse{
+				CsvDelay:        288,
+				ReserveSat:      20_000,
+				InFlightMaxMsat: 500_000_000,
+				MaxHtlcCount:    50,
+				MinHtlcIn:       1_000,
+				MinAcceptDepth:  6,
+				UpfrontShutdown: "bc1p",
+			},
+		},
+		{
+			desc:     "Zero conf granted by the chained acceptor only",
+			resp:     &lnrpc.ChannelAcceptResponse{MinAcceptDepth: 3},
+			chained:  &lnrpc.ChannelAcceptResponse{ZeroConf: true},
+			expected: &lnrpc.ChannelAcceptResponse{MinAcceptDepth: 3},
+		},
+		{
+			desc:     "Zero conf granted by both",
+			resp:     &lnrpc.ChannelAcceptResponse{ZeroConf: true},
+			chained:  &lnrpc.ChannelAcceptResponse{ZeroConf: true},
+			expected: &lnrpc.ChannelAcceptResponse{ZeroConf: true},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			combineParameters(tc.resp, tc.chained)
+			assert.Equal(t, tc.expected, tc.resp)
+		})
+	}
+}
+
+func TestNewDefaultHost(t *testing.T) {
+	s, err := New(config.Chain{Address: ":10019"})
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:10019", s.address)
+
+	s, err = New(config.Chain{Address: "0.0.0.0:10019"})
+	assert.NoError(t, err)
+	assert.Equal(t, "0.0.0.0:10019", s.address)
+}
@@ -1,11 +1,21 @@
 package config
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"io"
 	"log/slog"
 	"net"
+	"net/url"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/aftermath2/acceptlnd/catalog"
 	"github.com/aftermath2/acceptlnd/policy"
+	"github.com/aftermath2/acceptlnd/proxy"
 
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
@@ -13,51 +23,1110 @@ import (
 
 // Config is acceptLND's configuration schema.
 type Config struct {
-	RPCAddress      string           `yaml:"rpc_address,omitempty"`
-	CertificatePath string           `yaml:"certificate_path,omitempty"`
-	MacaroonPath    string           `yaml:"macaroon_path,omitempty"`
-	Policies        []*policy.Policy `yaml:"policies,omitempty"`
+	Version                  int              `yaml:"version,omitempty" default:"1" doc:"Version of the policies semantics, 1 or 2. Version 2 requires the peers listed in conditions.is to meet the rest of the conditions too."`
+	RPCAddress               string           `yaml:"rpc_address,omitempty" doc:"LND gRPC address (host:port), or the path of a unix socket (unix:///path/to/lnd.sock or unix:relative/path). Required."`
+	CertificatePath          string           `yaml:"certificate_path,omitempty" doc:"Path to LND's TLS certificate. Optional if tls.use_system_certs or tls.insecure_skip_verify are enabled."`
+	MacaroonPath             string           `yaml:"macaroon_path,omitempty" doc:"Path to the macaroon file. Required."`
+	TLS                      TLS              `yaml:"tls,omitempty" doc:"Options used to secure the connection with LND."`
+	Keepalive                Keepalive        `yaml:"keepalive,omitempty" doc:"Pings sent to LND to detect dead connections."`
+	Startup                  Startup          `yaml:"startup,omitempty" doc:"Wait for LND to be ready when it's starting or its wallet is locked."`
+	Resubscribe              *Resubscribe     `yaml:"resubscribe,omitempty" doc:"Register the channel acceptor again after LND restarts instead of exiting."`
+	HTTPAddress              string           `yaml:"http_address,omitempty" doc:"Address (host:port) the HTTP server exposing the health, metrics and tags endpoints listens on."`
+	DatabasePath             string           `yaml:"database_path,omitempty" doc:"Path to the database where the information about the requests is persisted, it's created if it doesn't exist. The connection URL with the postgres backend."`
+	DatabaseBackend          string           `yaml:"database_backend,omitempty" default:"bbolt" doc:"Storage implementation: bbolt, sqlite, postgres or memory, which doesn't need a database_path but loses the information on restarts."`
+	GraphSnapshotPath        string           `yaml:"graph_snapshot_path,omitempty" doc:"File the channel graph snapshots are saved to, to evaluate the policies that need it right after a restart."`
+	Proxy                    string           `yaml:"proxy,omitempty" doc:"SOCKS5 proxy URL (socks5://[user:password@]host:port) the connections to LND and external services go through."`
+	SelfServices             []string         `yaml:"self_services,omitempty" doc:"Public keys of the operator's own services, whose requests are accepted without evaluating the policies."`
+	Registry                 []RegistryEntry  `yaml:"registry,omitempty" doc:"Nodes added to the embedded registry of known services the policies match with node.known_as. Entries replace the embedded ones with the same public key."`
+	RegistryUpdate           *RegistryUpdate  `yaml:"registry_update,omitempty" doc:"Refresh the registry of known services from a signed remote document."`
+	MaxConcurrentEvaluations int              `yaml:"max_concurrent_evaluations,omitempty" default:"1" doc:"Maximum number of channel requests evaluated at the same time."`
+	OverflowAction           string           `yaml:"overflow_action,omitempty" default:"wait" doc:"What to do with the requests received while the maximum is reached: wait or reject."`
+	Flood                    *Flood           `yaml:"flood_protection,omitempty" doc:"Temporarily block nodes and funding amounts sending too many requests."`
+	AutoBlocklist            *AutoBlocklist   `yaml:"auto_blocklist,omitempty" doc:"Block the nodes whose requests are rejected repeatedly, so the next ones are rejected without evaluating them. Requires database_path."`
+	Cluster                  bool             `yaml:"cluster,omitempty" doc:"Keep the flood protection counters and blocks in the database, so the instances sharing it enforce the limits together. Requires the postgres database_backend. The limits on the number of channels are still checked against the channels of each node."`
+	LabelChannels            bool             `yaml:"label_channels,omitempty" doc:"Label the funding transactions of the channels accepted in LND's wallet with the policies that accepted them and the peer reputation. Requires a database."`
+	WatchOnly                *WatchOnly       `yaml:"watch_only,omitempty" doc:"Evaluate peers periodically instead of handling channel requests."`
+	Reachability             Reachability     `yaml:"reachability,omitempty" doc:"Options of the tests made to verify peers accept connections on their announced addresses."`
+	Limits                   *Limits          `yaml:"limits,omitempty" doc:"Decide the requests from peers with too many channels without evaluating the policies."`
+	StaleGraph               *StaleGraph      `yaml:"stale_graph,omitempty" doc:"Decide the requests received while LND's graph is out of sync without evaluating the policies."`
+	Webhook                  *Webhook         `yaml:"webhook,omitempty" doc:"Endpoint the decisions are posted to."`
+	Notify                   *Notify          `yaml:"notify,omitempty" doc:"Route the decisions and alerts to different destinations depending on rules, in addition to the webhook."`
+	AcceptHook               *AcceptHook      `yaml:"accept_hook,omitempty" doc:"Command run when channels are accepted, so external tools can prepare for them."`
+	BlocklistExport          *BlocklistExport `yaml:"blocklist_export,omitempty" doc:"File the nodes blocked are written to, for firewall tooling."`
+	Syslog                   *Syslog          `yaml:"syslog,omitempty" doc:"Send the decisions to the local syslog daemon or a remote server."`
+	Statsd                   *Statsd          `yaml:"statsd,omitempty" doc:"Send the metrics to a statsd server, in addition to exposing them to Prometheus."`
+	InfluxDB                 *InfluxDB        `yaml:"influxdb,omitempty" doc:"Write the metrics to InfluxDB, in addition to exposing them to Prometheus."`
+	TorExitListPath          string           `yaml:"tor_exit_list_path,omitempty" doc:"File with the IP addresses of the Tor exit relays, one per line."`
+	ASNDatabasePath          string           `yaml:"asn_database_path,omitempty" doc:"File mapping IP address ranges to the autonomous systems announcing them, in the iptoasn.com format. Required by max_channels_per_asn."`
+	Chain                    *Chain           `yaml:"chain,omitempty" doc:"Combine the policies verdicts with the ones of another channel acceptor."`
+	Middleware               *Middleware      `yaml:"middleware,omitempty" doc:"Evaluate the channels opened through LND's RPC by other tools, registering as an RPC middleware."`
+	HTLCInterceptor          *HTLCInterceptor `yaml:"htlc_interceptor,omitempty" doc:"Fail the probe-like HTLCs forwarded by the nodes in the blocklist."`
+	Precompute               *Precompute      `yaml:"precompute,omitempty" doc:"Load the information of the nodes likely to request channels in the background, so evaluating their requests doesn't wait for LND."`
+	ResponseSLO              *ResponseSLO     `yaml:"response_slo,omitempty" doc:"Alert when the responses take too long repeatedly, as LND decides the requests not answered in time."`
+	AcceptorTimeout          time.Duration    `yaml:"acceptor_timeout,omitempty" doc:"Time LND waits for the channel acceptor responses (its acceptortimeout option). Read from LND if not set."`
+	ReputationHalfLife       time.Duration    `yaml:"reputation_half_life,omitempty" default:"720h0m0s" doc:"Time it takes for a reputation event to lose half of its weight."`
+	Seed                     int64            `yaml:"seed,omitempty" doc:"Seed of the random choices made while evaluating requests, like the experiment sampling and the tarpit delays. A random one is used if it's zero."`
+	Language                 string           `yaml:"language,omitempty" default:"en" doc:"Language of the rejection reasons sent to the peers and to the webhook: en, es or de."`
+	StrictPolicies           bool             `yaml:"strict_policies,omitempty" doc:"Reject configurations with policies missing a name or description, or having neither conditions nor requirements."`
+	ReloadOnChange           bool             `yaml:"reload_on_change,omitempty" doc:"Reload the policies every time the configuration file changes, like on SIGHUP."`
+	StrictLNDVersion         bool             `yaml:"strict_lnd_version,omitempty" doc:"Fail on startup instead of warning when the LND version lacks fields the policies depend on, like inbound fees."`
+	Preset                   string           `yaml:"preset,omitempty" doc:"Built-in policies enforced before the configured ones: conservative, routing_node or lsp_provider. Policies named like a preset one replace it."`
+	Include                  []string         `yaml:"include,omitempty" doc:"Files whose policies are appended to the configured ones, in order. Glob patterns are accepted, relative to the configuration file directory."`
+	Policies                 []*policy.Policy `yaml:"policies,omitempty" doc:"Set of policies to enforce, in order."`
+	Experiment               *Experiment      `yaml:"experiment,omitempty" doc:"Alternative set of policies evaluated for a share of the requests, to measure the impact of changes before rolling them out."`
+	Tests                    []PolicyTest     `yaml:"tests,omitempty" doc:"Synthetic requests and the decisions the policies are expected to take on them, run with acceptlnd validate -run-tests."`
+}
+
+// TLS contains the options used to secure the connection with LND.
+type TLS struct {
+	UseSystemCerts        bool   `yaml:"use_system_certs,omitempty" doc:"Verify LND's certificate with the system certificate authorities, in addition to the one in certificate_path if it's set."`
+	InsecureSkipVerify    bool   `yaml:"insecure_skip_verify,omitempty" doc:"Do not verify LND's certificate. Discouraged, it allows anyone in the middle to impersonate the node."`
+	ClientCertificatePath string `yaml:"client_certificate_path,omitempty" doc:"Certificate presented to LND (or a proxy in front of it) for mutual TLS. Requires client_key_path."`
+	ClientKeyPath         string `yaml:"client_key_path,omitempty" doc:"Private key of the client certificate."`
+}
+
+// Keepalive contains the parameters of the pings sent to LND to detect dead connections.
+type Keepalive struct {
+	Time                time.Duration `yaml:"time,omitempty" default:"30s" doc:"Time without activity after which a ping is sent, at least 10s."`
+	Timeout             time.Duration `yaml:"timeout,omitempty" default:"20s" doc:"Time waited for the ping response before closing the connection."`
+	PermitWithoutStream bool          `yaml:"permit_without_stream,omitempty" doc:"Send pings even when there are no active streams."`
+}
+
+// Startup contains the options of the wait for LND to be ready to serve the channel acceptor.
+type Startup struct {
+	RetryInterval time.Duration `yaml:"retry_interval,omitempty" default:"5s" doc:"Time between the checks of LND's state while it's starting or its wallet is locked."`
+	Timeout       time.Duration `yaml:"timeout,omitempty" doc:"Longest time waited for LND to be ready, zero waits indefinitely."`
+}
+
+// Resubscribe contains the options of the channel acceptor registration after LND restarts or its
+// wallet is locked again.
+type Resubscribe struct {
+	GracePeriod time.Duration `yaml:"grace_period,omitempty" doc:"Time after every registration during which the requests that can't be evaluated, as LND is still loading, are decided by on_error."`
+	OnError     string        `yaml:"on_error,omitempty" default:"reject" doc:"What to do with the requests that can't be evaluated during the grace period: reject or accept."`
+}
+
+// Webhook contains the options of the delivery of the decisions to an HTTP endpoint.
+type Webhook struct {
+	URL         string        `yaml:"url,omitempty" doc:"HTTP(S) endpoint the decisions are posted to. Requires a database to queue the events."`
+	Timeout     time.Duration `yaml:"timeout,omitempty" default:"10s" doc:"Time waited for the endpoint to respond."`
+	MaxAttempts int           `yaml:"max_attempts,omitempty" doc:"Number of times the delivery of an event is attempted before discarding it, zero means forever."`
+}
+
+// RegistryEntry describes a known service.
+type RegistryEntry struct {
+	PublicKey string   `yaml:"public_key,omitempty" doc:"Node public key. Required."`
+	Name      string   `yaml:"name,omitempty" doc:"Name of the service."`
+	Tags      []string `yaml:"tags,omitempty" doc:"Categories of the service, like lsp, exchange or wallet. Required."`
+}
+
+// RegistryUpdate contains the options used to refresh the registry of known services from a
+// remote document signed with an ed25519 key.
+type RegistryUpdate struct {
+	URL          string        `yaml:"url,omitempty" doc:"URL of the JSON document with the registry entries. Required."`
+	SignatureURL string        `yaml:"signature_url,omitempty" doc:"URL of the hex encoded ed25519 signature of the document, the document URL with a .sig suffix by default."`
+	PublicKey    string        `yaml:"public_key,omitempty" doc:"Hex encoded ed25519 public key the document must be signed with. Required."`
+	Interval     time.Duration `yaml:"interval,omitempty" default:"24h0m0s" doc:"Time between refreshes."`
+	CachePath    string        `yaml:"cache_path,omitempty" doc:"File the last verified document is saved to, to use it right after a restart."`
+	Pinned       []string      `yaml:"pinned,omitempty" doc:"Public keys of the nodes whose embedded entries are kept whatever the remote document says."`
+}
+
+// Notification formats.
+const (
+	// FormatJSON posts the events as they are sent to the webhook, it's the default.
+	FormatJSON = "json"
+	// FormatTelegram posts a message to a Telegram chat through the Bot API.
+	FormatTelegram = "telegram"
+)
+
+// Notification event types.
+const (
+	EventAccepted = "accepted"
+	EventRejected = "rejected"
+	EventAlert    = "alert"
+)
+
+// Notify contains the destinations of the notifications and the rules deciding which events are
+// sent to each of them.
+type Notify struct {
+	Destinations []Destination `yaml:"destinations,omitempty" doc:"Endpoints the notifications can be sent to."`
+	Rules        []NotifyRule  `yaml:"rules,omitempty" doc:"Events sent to each destination. An event matching several rules with the same destination is sent once."`
+}
+
+// Destination is an endpoint the notifications are posted to.
+type Destination struct {
+	Name    string `yaml:"name,omitempty" doc:"Name the rules refer to the destination by. Required."`
+	Webhook `yaml:",inline"`
+	Format  string `yaml:"format,omitempty" default:"json" doc:"How the events are posted: json or telegram, which sends a text message to the Bot API sendMessage URL."`
+	ChatID  string `yaml:"chat_id,omitempty" doc:"Telegram chat the messages are sent to. Required with the telegram format."`
+}
+
+// NotifyRule sends the events matching all its filters to a destination.
+type NotifyRule struct {
+	Events      []string `yaml:"events,omitempty" doc:"Types of the events matched: accepted, rejected or alert. All of them if empty."`
+	MinCapacity uint64   `yaml:"min_capacity,omitempty" doc:"Minimum capacity of the channels matched, in sats. Alerts don't match rules filtering decisions."`
+	MaxCapacity uint64   `yaml:"max_capacity,omitempty" doc:"Maximum capacity of the channels matched, in sats."`
+	Policies    []string `yaml:"policies,omitempty" doc:"Names of the policies, the decisions matched must have been made by one of them."`
+	Tags        []string `yaml:"tags,omitempty" doc:"Tags the decisions matched must have at least one of."`
+	Destination string   `yaml:"destination,omitempty" doc:"Name of the destination the events matched are sent to. Required."`
+}
+
+// AcceptHook contains the options of the command run when channels are accepted, like rebalancers
+// or fee managers that prepare for large channels.
+type AcceptHook struct {
+	Command     []string      `yaml:"command,omitempty" doc:"Program and arguments run for every channel accepted, the decision is written to its standard input as JSON. Required."`
+	MinCapacity uint64        `yaml:"min_capacity,omitempty" doc:"Minimum capacity of the channels the command is run for, in sats."`
+	Timeout     time.Duration `yaml:"timeout,omitempty" default:"30s" doc:"Time the command can run before it's killed."`
+}
+
+// BlocklistExport contains the options of the file the effective blocklist is written to.
+type BlocklistExport struct {
+	Path     string        `yaml:"path,omitempty" doc:"File the public keys of the nodes blocked are written to, one per line. Required."`
+	Interval time.Duration `yaml:"interval,omitempty" default:"1m0s" doc:"Time between writes, the file is only replaced if the list changed."`
+}
+
+// Syslog contains the options used to send the decisions to syslog.
+type Syslog struct {
+	Network  string `yaml:"network,omitempty" doc:"Network of the remote server, udp or tcp. The messages are sent to the local daemon if it's not set."`
+	Address  string `yaml:"address,omitempty" doc:"Address (host:port) of the remote server. Required with network."`
+	Facility string `yaml:"facility,omitempty" default:"daemon" doc:"Facility of the messages: user, daemon or local0 to local7."`
+	Tag      string `yaml:"tag,omitempty" default:"acceptlnd" doc:"Name of the application sending the messages."`
+}
+
+// SyslogFacilities are the facilities the messages can be sent with.
+var SyslogFacilities = []string{
+	"user", "daemon", "local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// Statsd contains the options used to send the metrics to statsd.
+type Statsd struct {
+	Address string `yaml:"address,omitempty" doc:"Address (host:port) of the server the metrics are sent to over UDP. Required."`
+	Format  string `yaml:"format,omitempty" default:"dogstatsd" doc:"How the labels are sent: dogstatsd, as tags, or plain, appended to the metric names."`
+}
+
+// StatsdFormats are the formats the statsd labels can be sent in.
+var StatsdFormats = []string{"dogstatsd", "plain"}
+
+// InfluxDB contains the options used to write the metrics to InfluxDB.
+type InfluxDB struct {
+	URL      string        `yaml:"url,omitempty" doc:"Base URL of the InfluxDB server. Required."`
+	Org      string        `yaml:"org,omitempty" doc:"Organization the bucket belongs to."`
+	Bucket   string        `yaml:"bucket,omitempty" doc:"Bucket the metrics are written to, database/retention-policy for InfluxDB 1.8. Required."`
+	Token    string        `yaml:"token,omitempty" doc:"API token, username:password for InfluxDB 1.8."`
+	Interval time.Duration `yaml:"interval,omitempty" default:"10s" doc:"Time between writes."`
+}
+
+// Limits protect the evaluation latency and memory usage from peers with huge amounts of data,
+// their requests are decided without evaluating the policies.
+type Limits struct {
+	MaxPeerChannels uint32 `yaml:"max_peer_channels,omitempty" doc:"Maximum number of channels the peer can have. Required."`
+	Action          string `yaml:"action,omitempty" default:"reject" doc:"What to do with the requests from peers exceeding the limits: reject or accept."`
+}
+
+// StaleGraph contains the options used to decide the requests received while LND hasn't been
+// synced to the channel graph for too long, as the peers information may be outdated.
+type StaleGraph struct {
+	MaxAge time.Duration `yaml:"max_age,omitempty" doc:"Time LND can go without being synced to the graph before the requests are decided by action. Required."`
+	Action string        `yaml:"action,omitempty" default:"reject" doc:"What to do with the requests received while the graph is stale: reject or accept."`
+}
+
+// Precompute contains the options of the background job loading the information of the nodes
+// likely to request channels ahead of time.
+type Precompute struct {
+	Peers    int           `yaml:"peers,omitempty" default:"100" doc:"Number of nodes loaded: the ones whose requests were rejected recently first, then the ones opening the most channels in the network."`
+	Interval time.Duration `yaml:"interval,omitempty" default:"10m0s" doc:"Time between loads, also the longest the information of a node is used."`
+}
+
+// ResponseSLO contains the objective of the time taken to respond to channel requests.
+type ResponseSLO struct {
+	Target   time.Duration `yaml:"target,omitempty" doc:"Maximum time from receiving a request to sending its response, excluding tarpit delays. Required."`
+	Breaches int           `yaml:"breaches,omitempty" default:"3" doc:"Number of consecutive responses slower than the target that raise an alert."`
+}
+
+// Reachability contains the options of the tests made to verify nodes accept connections on their
+// announced addresses.
+type Reachability struct {
+	Timeout       time.Duration `yaml:"timeout,omitempty" default:"5s" doc:"Time waited for a connection to be established."`
+	CacheDuration time.Duration `yaml:"cache_duration,omitempty" default:"1h0m0s" doc:"Time the result of a test is reused for."`
+}
+
+// Flood contains the limits used to detect request floods, nodes or funding amounts exceeding
+// them are temporarily blocked.
+type Flood struct {
+	MaxRequests       int           `yaml:"max_requests,omitempty" doc:"Maximum number of requests from the same node within the window."`
+	MaxAmountRequests int           `yaml:"max_amount_requests,omitempty" doc:"Maximum number of requests with the same funding amount within the window, regardless of the node sending them."`
+	Window            time.Duration `yaml:"window,omitempty" doc:"Period in which the requests are counted."`
+	BlockDuration     time.Duration `yaml:"block_duration,omitempty" doc:"Time a node or amount stays blocked."`
+}
+
+// AutoBlocklist contains the options of the blocking of the nodes whose requests the policies
+// reject repeatedly.
+type AutoBlocklist struct {
+	AfterRejections int           `yaml:"after_rejections,omitempty" doc:"Number of rejections within the window after which the node is blocked. Required."`
+	Within          time.Duration `yaml:"within,omitempty" doc:"Period in which the rejections are counted. Required."`
+	Duration        time.Duration `yaml:"duration,omitempty" doc:"Time the node stays blocked, zero blocks it permanently."`
+}
+
+// WatchOnly contains the options of the watch-only mode, where channel requests are not handled
+// and peers are evaluated periodically instead.
+type WatchOnly struct {
+	Peers           []string      `yaml:"peers,omitempty" doc:"Public keys of the nodes evaluated, all the nodes in the graph if empty."`
+	ChannelCapacity uint64        `yaml:"channel_capacity,omitempty" doc:"Capacity of the channel the peers are evaluated as if they were requesting, in sats. Required."`
+	Interval        time.Duration `yaml:"interval,omitempty" default:"30m0s" doc:"Time between evaluations."`
+}
+
+// Experiment modes.
+const (
+	// ExperimentShadow records the experimental verdict but enforces the policies one.
+	ExperimentShadow = "shadow"
+	// ExperimentLive enforces the experimental verdict and records the policies one.
+	ExperimentLive = "live"
+)
+
+// Experiment contains an alternative set of policies evaluated for a share of the requests.
+type Experiment struct {
+	Name       string           `yaml:"name,omitempty" doc:"Name the experiment is identified by in the logs, metrics and decisions. Required."`
+	Percentage float64          `yaml:"percentage,omitempty" doc:"Share (0-100) of the requests evaluated by the experimental policies, picked randomly."`
+	Mode       string           `yaml:"mode,omitempty" default:"shadow" doc:"Whether the experimental verdict is only recorded (shadow) or enforced instead of the policies one (live)."`
+	Policies   []*policy.Policy `yaml:"policies,omitempty" doc:"Experimental set of policies, evaluated like the regular ones."`
+}
+
+// Chain contains the options of the server other channel acceptors connect to, as if it was LND,
+// so their verdicts are combined with the policies ones.
+type Chain struct {
+	Address         string `yaml:"address,omitempty" doc:"Address (host:port) the server listens on, the host defaults to 127.0.0.1. Only loopback addresses are allowed without TLS. Required."`
+	CertificatePath string `yaml:"certificate_path,omitempty" doc:"Certificate used to serve TLS, the connections are not encrypted if it's not set."`
+	KeyPath         string `yaml:"key_path,omitempty" doc:"Private key of the certificate."`
+	Mode            string `yaml:"mode,omitempty" default:"and" doc:"How the verdicts are combined: and, both must accept the request, or or."`
+}
+
+// Modes of the RPC middleware.
+const (
+	// MiddlewareObserve logs the verdicts of the channels opened without affecting them.
+	MiddlewareObserve = "observe"
+	// MiddlewareEnforce denies the calls opening channels the policies reject.
+	MiddlewareEnforce = "enforce"
+)
+
+// Middleware contains the options of the integration with LND's RPC middleware, which intercepts
+// the calls opening channels made through LND's RPC so the policies evaluate them too.
+type Middleware struct {
+	Name string `yaml:"name,omitempty" default:"acceptlnd" doc:"Name the middleware registers with, also the custom macaroon caveat of the calls intercepted in enforce mode."`
+	Mode string `yaml:"mode,omitempty" default:"observe" doc:"What to do with the calls rejected by the policies: observe, only log them, or enforce, deny them."`
+}
+
+// HTLCInterceptor contains the options of LND's HTLC interceptor, which fails the HTLCs forwarded
+// by the nodes in the blocklist. While it's registered, LND holds every HTLC until it's resolved.
+type HTLCInterceptor struct {
+	MaxAmount uint64 `yaml:"max_amount,omitempty" doc:"Largest HTLC failed, in sats, larger ones are forwarded as they are unlikely to be probes. Zero fails all of them."`
 }
 
 // Load reads the configuration file and returns a new object.
+//
+// If the path is "-", the configuration is read from the standard input. Values set in
+// environment variables take precedence over the ones in the file, which may be omitted if the
+// whole configuration is provided through them.
 func Load(path string) (Config, error) {
+	return load(path, true)
+}
+
+// LoadPolicies reads the configuration file like Load, but it only validates the policies. It's
+// meant for the commands that don't connect to LND.
+func LoadPolicies(path string) (Config, error) {
+	return load(path, false)
+}
+
+func load(path string, connect bool) (Config, error) {
 	if path == "" {
 		path = "acceptlnd.yml"
 	}
 
-	slog.Info("Configuration file: " + path)
+	content, err := read(path)
+	if err != nil {
+		if !os.IsNotExist(err) || !hasEnv() {
+			return Config{}, errors.Wrap(err, "opening file")
+		}
+		slog.Info("Configuration file not found, using environment variables only")
+	}
 
-	f, err := os.OpenFile(path, os.O_RDONLY, 0o600)
+	// The original content is kept to point at the lines of the invalid values
+	expanded, err := toYAML(path, content)
 	if err != nil {
-		return Config{}, errors.Wrap(err, "opening file")
+		return Config{}, err
+	}
+	expanded, err = interpolate(expanded)
+	if err != nil {
+		return Config{}, errors.Wrap(err, "interpolating environment variables")
 	}
-	defer f.Close()
 
 	var config Config
-	if err := yaml.NewDecoder(f).Decode(&config); err != nil {
+	if err := yaml.Unmarshal(expanded, &config); err != nil {
 		return Config{}, errors.Wrap(err, "decoding configuration")
 	}
 
-	if err := validate(config); err != nil {
+	if err := applyEnv(&config); err != nil {
+		return Config{}, err
+	}
+
+	if err := expandIncludes(&config, path); err != nil {
+		return Config{}, err
+	}
+	resolveNodeInfo(config.Tests, path)
+
+	if err := expandPreset(&config); err != nil {
+		return Config{}, err
+	}
+
+	if config.Version == 0 {
+		config.Version = policy.Version1
+	}
+	policy.SetVersion(config.Policies, config.Version)
+	if config.Experiment != nil {
+		policy.SetVersion(config.Experiment.Policies, config.Version)
+	}
+
+	validateFn := validatePolicies
+	if connect {
+		validateFn = validate
+	}
+	if err := validateFn(config); err != nil {
+		var pkErr *policy.PublicKeyError
+		if errors.As(err, &pkErr) {
+			if line := lineOf(content, pkErr.PublicKey); line > 0 {
+				err = errors.Wrapf(err, "line %d", line)
+			}
+		}
 		return Config{}, errors.Wrap(err, "validating configuration")
 	}
 
 	return config, nil
 }
 
+func read(path string) ([]byte, error) {
+	if path == "-" {
+		slog.Info("Reading configuration from the standard input")
+		return io.ReadAll(os.Stdin)
+	}
+
+	slog.Info("Configuration file: " + path)
+	return os.ReadFile(path)
+}
+
+// lineOf returns the number of the first line containing the value, or zero if it's not found.
+func lineOf(content []byte, value string) int {
+	if value == "" {
+		return 0
+	}
+
+	lines := bytes.Split(content, []byte("\n"))
+	for i, line := range lines {
+		item := bytes.TrimSpace(bytes.TrimPrefix(bytes.TrimSpace(line), []byte("-")))
+		if string(bytes.Trim(item, `"'`)) == value {
+			return i + 1
+		}
+	}
+
+	// Values may be in flow sequences like [a, b]
+	for i, line := range lines {
+		if bytes.Contains(line, []byte(value)) {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
 func validate(config Config) error {
-	_, _, err := net.SplitHostPort(config.RPCAddress)
-	if err != nil {
+	if path, ok := UnixSocketPath(config.RPCAddress); ok {
+		if path == "" {
+			return errors.New("invalid RPC address: missing unix socket path")
+		}
+		if config.Proxy != "" {
+			return errors.New("unix sockets cannot be used through a proxy")
+		}
+	} else if _, _, err := net.SplitHostPort(config.RPCAddress); err != nil {
 		return errors.Wrap(err, "invalid RPC address")
 	}
 
-	if _, err := os.Stat(config.CertificatePath); os.IsNotExist(err) {
-		return errors.New("the certificate file specified does not exist")
+	if config.HTTPAddress != "" {
+		if _, _, err := net.SplitHostPort(config.HTTPAddress); err != nil {
+			return errors.Wrap(err, "invalid HTTP address")
+		}
+	}
+
+	if config.Proxy != "" {
+		if err := proxy.Validate(config.Proxy); err != nil {
+			return err
+		}
+	}
+
+	if err := validateTLS(config); err != nil {
+		return err
+	}
+
+	if config.Keepalive.Time < 0 || config.Keepalive.Timeout < 0 {
+		return errors.New("keepalive durations must not be negative")
+	}
+	if config.Keepalive.Time != 0 && config.Keepalive.Time < 10*time.Second {
+		return errors.New("keepalive time must be at least 10 seconds")
+	}
+
+	if config.Startup.RetryInterval < 0 || config.Startup.Timeout < 0 {
+		return errors.New("startup durations must not be negative")
+	}
+
+	if config.Reachability.Timeout < 0 || config.Reachability.CacheDuration < 0 {
+		return errors.New("reachability durations must not be negative")
 	}
 
 	if _, err := os.Stat(config.MacaroonPath); os.IsNotExist(err) {
 		return errors.New("the macaroon file specified does not exist")
 	}
 
+	return validatePolicies(config)
+}
+
+// HasDatabase reports whether the information is stored, either in a file or in memory.
+func (c Config) HasDatabase() bool {
+	return c.DatabasePath != "" || c.DatabaseBackend == "memory"
+}
+
+// AllPolicies returns the policies enforced followed by the experiment ones, if any.
+func (c Config) AllPolicies() []*policy.Policy {
+	if c.Experiment == nil {
+		return c.Policies
+	}
+	return append(slices.Clone(c.Policies), c.Experiment.Policies...)
+}
+
+// UnixSocketPath returns the path of the unix socket if the address has the form "unix:path" or
+// "unix:///path".
+func UnixSocketPath(address string) (string, bool) {
+	path, ok := strings.CutPrefix(address, "unix:")
+	if !ok {
+		return "", false
+	}
+	return strings.TrimPrefix(path, "//"), true
+}
+
+func validateTLS(config Config) error {
+	optional := config.TLS.UseSystemCerts || config.TLS.InsecureSkipVerify
+	if config.CertificatePath != "" || !optional {
+		if _, err := os.Stat(config.CertificatePath); os.IsNotExist(err) {
+			return errors.New("the certificate file specified does not exist")
+		}
+	}
+
+	if (config.TLS.ClientCertificatePath == "") != (config.TLS.ClientKeyPath == "") {
+		return errors.New("both the client certificate and key must be specified")
+	}
+
+	for _, path := range []string{config.TLS.ClientCertificatePath, config.TLS.ClientKeyPath} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return errors.Errorf("the file %s does not exist", path)
+		}
+	}
+
 	return nil
 }
+
+// validatePolicies verifies the values that don't depend on the connection to LND.
+func validatePolicies(config Config) error {
+	if config.Version < 0 || config.Version > policy.LatestVersion {
+		return errors.Errorf("unknown version %d, the latest is %d", config.Version, policy.LatestVersion)
+	}
+
+	if config.MaxConcurrentEvaluations < 0 {
+		return errors.New("max_concurrent_evaluations must not be negative")
+	}
+
+	switch config.OverflowAction {
+	case "", "wait", "reject":
+	default:
+		return errors.Errorf("invalid overflow_action %q, expected wait or reject", config.OverflowAction)
+	}
+
+	if !catalog.Supported(config.Language) {
+		return errors.Errorf("invalid language %q, expected one of %s",
+			config.Language, strings.Join(catalog.Languages, ", "))
+	}
+
+	if config.AcceptorTimeout < 0 {
+		return errors.New("acceptor_timeout must not be negative")
+	}
+
+	if config.ReputationHalfLife < 0 {
+		return errors.New("reputation_half_life must not be negative")
+	}
+
+	if err := validateFlood(config.Flood); err != nil {
+		return errors.Wrap(err, "flood_protection")
+	}
+
+	if err := validateAutoBlocklist(config.AutoBlocklist, config.HasDatabase()); err != nil {
+		return errors.Wrap(err, "auto_blocklist")
+	}
+
+	switch config.DatabaseBackend {
+	case "", "bbolt", "sqlite", "postgres":
+		if config.DatabaseBackend != "" && config.DatabasePath == "" {
+			return errors.Errorf("database_path is required by the %s database_backend", config.DatabaseBackend)
+		}
+	case "memory":
+	default:
+		return errors.Errorf("invalid database_backend %q, expected bbolt, sqlite, postgres or memory",
+			config.DatabaseBackend)
+	}
+
+	// The other backends are files locked by a single process
+	if config.Cluster && config.DatabaseBackend != "postgres" {
+		return errors.New("cluster mode requires the postgres database_backend, shared by the instances")
+	}
+
+	// The channels are labeled from their tags
+	if config.LabelChannels && !config.HasDatabase() {
+		return errors.New("label_channels requires a database to tag the channels")
+	}
+
+	if err := validateWebhook(config.Webhook, config.HasDatabase()); err != nil {
+		return errors.Wrap(err, "webhook")
+	}
+
+	if err := validateNotify(config.Notify, config.HasDatabase()); err != nil {
+		return errors.Wrap(err, "notify")
+	}
+
+	if err := validateAcceptHook(config.AcceptHook); err != nil {
+		return errors.Wrap(err, "accept_hook")
+	}
+
+	if err := validateBlocklistExport(config.BlocklistExport); err != nil {
+		return errors.Wrap(err, "blocklist_export")
+	}
+
+	if err := validateSyslog(config.Syslog); err != nil {
+		return errors.Wrap(err, "syslog")
+	}
+
+	if err := validateStatsd(config.Statsd); err != nil {
+		return errors.Wrap(err, "statsd")
+	}
+
+	if err := validateInfluxDB(config.InfluxDB); err != nil {
+		return errors.Wrap(err, "influxdb")
+	}
+
+	if err := validateLimits(config.Limits); err != nil {
+		return errors.Wrap(err, "limits")
+	}
+
+	if err := validateStaleGraph(config.StaleGraph); err != nil {
+		return errors.Wrap(err, "stale_graph")
+	}
+
+	if err := validateResubscribe(config.Resubscribe); err != nil {
+		return errors.Wrap(err, "resubscribe")
+	}
+
+	if err := validatePrecompute(config.Precompute); err != nil {
+		return errors.Wrap(err, "precompute")
+	}
+
+	if err := validateResponseSLO(config.ResponseSLO); err != nil {
+		return errors.Wrap(err, "response_slo")
+	}
+
+	if err := validateChain(config.Chain); err != nil {
+		return errors.Wrap(err, "chain")
+	}
+
+	if err := validateMiddleware(config.Middleware); err != nil {
+		return errors.Wrap(err, "middleware")
+	}
+
+	if err := validateWatchOnly(config.WatchOnly); err != nil {
+		return errors.Wrap(err, "watch_only")
+	}
+
+	if err := ValidateRegistry(config.Registry); err != nil {
+		return errors.Wrap(err, "registry")
+	}
+
+	if err := validateRegistryUpdate(config.RegistryUpdate); err != nil {
+		return errors.Wrap(err, "registry_update")
+	}
+
+	if err := policy.ValidatePublicKeys("self_services", config.SelfServices); err != nil {
+		return err
+	}
+
+	if err := validatePolicyList(config.Policies, config.StrictPolicies); err != nil {
+		return err
+	}
+
+	if err := validateExperiment(config.Experiment, config.StrictPolicies); err != nil {
+		return errors.Wrap(err, "experiment")
+	}
+
+	if config.ASNDatabasePath == "" && limitsASN(config) {
+		return errors.New("max_channels_per_asn requires asn_database_path")
+	}
+
+	if err := validateTests(config.Tests); err != nil {
+		return errors.Wrap(err, "tests")
+	}
+
+	return nil
+}
+
+// validatePolicyList verifies the values of every policy and analyzes them together, so the
+// contradictions that would make them impossible to satisfy are caught before they are enforced.
+// The rest of the issues are logged as warnings.
+func validatePolicyList(policies []*policy.Policy, strict bool) error {
+	for i, p := range policies {
+		if err := p.Validate(); err != nil {
+			return errors.Wrapf(err, "policy %d", i)
+		}
+		if strict {
+			if err := p.ValidateStrict(); err != nil {
+				return errors.Wrapf(err, "policy %d", i)
+			}
+		}
+	}
+
+	for _, issue := range policy.Analyze(policies) {
+		if issue.Severity == policy.SeverityError {
+			return issue
+		}
+		slog.Warn("Policy issue", slog.Int("policy", issue.Policy), slog.String("issue", issue.Message))
+	}
+	return nil
+}
+
+// limitsASN returns whether any policy, including the experiment ones, limits the channels per
+// autonomous system.
+func limitsASN(config Config) bool {
+	for _, p := range config.AllPolicies() {
+		if p.MaxChannelsPerASN != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func validateExperiment(experiment *Experiment, strict bool) error {
+	if experiment == nil {
+		return nil
+	}
+
+	if experiment.Name == "" {
+		return errors.New("name must be set")
+	}
+	if experiment.Percentage <= 0 || experiment.Percentage > 100 {
+		return errors.New("percentage must be greater than 0 and at most 100")
+	}
+
+	switch experiment.Mode {
+	case "", ExperimentShadow, ExperimentLive:
+	default:
+		return errors.Errorf("invalid mode %q, expected %s or %s",
+			experiment.Mode, ExperimentShadow, ExperimentLive)
+	}
+
+	return validatePolicyList(experiment.Policies, strict)
+}
+
+func validateFlood(flood *Flood) error {
+	if flood == nil {
+		return nil
+	}
+
+	if flood.MaxRequests < 0 || flood.MaxAmountRequests < 0 {
+		return errors.New("limits must not be negative")
+	}
+	if flood.MaxRequests == 0 && flood.MaxAmountRequests == 0 {
+		return errors.New("max_requests or max_amount_requests must be set")
+	}
+	if flood.Window <= 0 {
+		return errors.New("window must be positive")
+	}
+	if flood.BlockDuration <= 0 {
+		return errors.New("block_duration must be positive")
+	}
+
+	return nil
+}
+
+// ValidateRegistry verifies the registry entries have a public key and categories.
+func ValidateRegistry(entries []RegistryEntry) error {
+	for i, entry := range entries {
+		if !policy.IsPublicKey(entry.PublicKey) {
+			return errors.Errorf("[%d].public_key: invalid public key %q", i, entry.PublicKey)
+		}
+		if len(entry.Tags) == 0 || slices.Contains(entry.Tags, "") {
+			return errors.Errorf("[%d].tags: must be set", i)
+		}
+	}
+	return nil
+}
+
+func validateRegistryUpdate(update *RegistryUpdate) error {
+	if update == nil {
+		return nil
+	}
+
+	addresses := []string{update.URL}
+	if update.SignatureURL != "" {
+		addresses = append(addresses, update.SignatureURL)
+	}
+	for _, address := range addresses {
+		u, err := url.Parse(address)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.Errorf("invalid url %q", address)
+		}
+	}
+	key, err := hex.DecodeString(update.PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return errors.Errorf("invalid ed25519 public key %q", update.PublicKey)
+	}
+	if update.Interval < 0 {
+		return errors.New("interval must not be negative")
+	}
+
+	return policy.ValidatePublicKeys("pinned", update.Pinned)
+}
+
+func validateAutoBlocklist(autoBlocklist *AutoBlocklist, hasDatabase bool) error {
+	if autoBlocklist == nil {
+		return nil
+	}
+
+	if autoBlocklist.AfterRejections <= 0 {
+		return errors.New("after_rejections must be positive")
+	}
+	if autoBlocklist.Within <= 0 {
+		return errors.New("within must be positive")
+	}
+	if autoBlocklist.Duration < 0 {
+		return errors.New("duration must not be negative")
+	}
+	if !hasDatabase {
+		return errors.New("a database is required to persist the blocks")
+	}
+
+	return nil
+}
+
+func validateWebhook(webhook *Webhook, hasDatabase bool) error {
+	if webhook == nil {
+		return nil
+	}
+
+	u, err := url.Parse(webhook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.Errorf("invalid url %q", webhook.URL)
+	}
+	if !hasDatabase {
+		return errors.New("a database is required to queue the events")
+	}
+	if webhook.Timeout < 0 || webhook.MaxAttempts < 0 {
+		return errors.New("timeout and max_attempts must not be negative")
+	}
+
+	return nil
+}
+
+func validateNotify(notify *Notify, hasDatabase bool) error {
+	if notify == nil {
+		return nil
+	}
+
+	names := make(map[string]bool, len(notify.Destinations))
+	for i, destination := range notify.Destinations {
+		if destination.Name == "" {
+			return errors.Errorf("destinations[%d]: name must be set", i)
+		}
+		if names[destination.Name] {
+			return errors.Errorf("destinations[%d]: duplicate name %q", i, destination.Name)
+		}
+		names[destination.Name] = true
+
+		if err := validateWebhook(&destination.Webhook, hasDatabase); err != nil {
+			return errors.Wrapf(err, "destinations[%d]", i)
+		}
+
+		switch destination.Format {
+		case "", FormatJSON:
+		case FormatTelegram:
+			if destination.ChatID == "" {
+				return errors.Errorf("destinations[%d]: chat_id is required with the telegram format", i)
+			}
+		default:
+			return errors.Errorf("destinations[%d]: invalid format %q, expected json or telegram", i,
+				destination.Format)
+		}
+	}
+
+	for i, rule := range notify.Rules {
+		for _, event := range rule.Events {
+			switch event {
+			case EventAccepted, EventRejected, EventAlert:
+			default:
+				return errors.Errorf("rules[%d]: invalid event %q, expected accepted, rejected or alert", i, event)
+			}
+		}
+		if rule.MaxCapacity != 0 && rule.MinCapacity > rule.MaxCapacity {
+			return errors.Errorf("rules[%d]: min_capacity is greater than max_capacity", i)
+		}
+		if !names[rule.Destination] {
+			return errors.Errorf("rules[%d]: unknown destination %q", i, rule.Destination)
+		}
+	}
+
+	return nil
+}
+
+func validateAcceptHook(hook *AcceptHook) error {
+	if hook == nil {
+		return nil
+	}
+
+	if len(hook.Command) == 0 || hook.Command[0] == "" {
+		return errors.New("command must be set")
+	}
+	if hook.Timeout < 0 {
+		return errors.New("timeout must not be negative")
+	}
+
+	return nil
+}
+
+func validateBlocklistExport(export *BlocklistExport) error {
+	if export == nil {
+		return nil
+	}
+
+	if export.Path == "" {
+		return errors.New("path must be set")
+	}
+	if export.Interval < 0 {
+		return errors.New("interval must not be negative")
+	}
+
+	return nil
+}
+
+func validateSyslog(syslog *Syslog) error {
+	if syslog == nil {
+		return nil
+	}
+
+	switch syslog.Network {
+	case "":
+		if syslog.Address != "" {
+			return errors.New("network must be set along with the address")
+		}
+	case "udp", "tcp":
+		if _, _, err := net.SplitHostPort(syslog.Address); err != nil {
+			return errors.Errorf("invalid address %q", syslog.Address)
+		}
+	default:
+		return errors.Errorf("unknown network %q, expected udp or tcp", syslog.Network)
+	}
+	if syslog.Facility != "" && !slices.Contains(SyslogFacilities, syslog.Facility) {
+		return errors.Errorf("unknown facility %q", syslog.Facility)
+	}
+
+	return nil
+}
+
+func validateStatsd(statsd *Statsd) error {
+	if statsd == nil {
+		return nil
+	}
+
+	if _, _, err := net.SplitHostPort(statsd.Address); err != nil {
+		return errors.Errorf("invalid address %q", statsd.Address)
+	}
+	if statsd.Format != "" && !slices.Contains(StatsdFormats, statsd.Format) {
+		return errors.Errorf("unknown format %q, expected dogstatsd or plain", statsd.Format)
+	}
+
+	return nil
+}
+
+func validateInfluxDB(influx *InfluxDB) error {
+	if influx == nil {
+		return nil
+	}
+
+	u, err := url.Parse(influx.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.Errorf("invalid url %q", influx.URL)
+	}
+	if influx.Bucket == "" {
+		return errors.New("bucket must be set")
+	}
+	if influx.Interval < 0 {
+		return errors.New("interval must not be negative")
+	}
+
+	return nil
+}
+
+func validateLimits(limits *Limits) error {
+	if limits == nil {
+		return nil
+	}
+
+	if limits.MaxPeerChannels == 0 {
+		return errors.New("max_peer_channels must be set")
+	}
+
+	switch limits.Action {
+	case "", "reject", "accept":
+	default:
+		return errors.Errorf("invalid action %q, expected reject or accept", limits.Action)
+	}
+
+	return nil
+}
+
+func validateResubscribe(resubscribe *Resubscribe) error {
+	if resubscribe == nil {
+		return nil
+	}
+
+	if resubscribe.GracePeriod < 0 {
+		return errors.New("grace_period must not be negative")
+	}
+
+	switch resubscribe.OnError {
+	case "", "reject", "accept":
+	default:
+		return errors.Errorf("invalid on_error %q, expected reject or accept", resubscribe.OnError)
+	}
+
+	return nil
+}
+
+func validateStaleGraph(staleGraph *StaleGraph) error {
+	if staleGraph == nil {
+		return nil
+	}
+
+	if staleGraph.MaxAge <= 0 {
+		return errors.New("max_age must be positive")
+	}
+
+	switch staleGraph.Action {
+	case "", "reject", "accept":
+	default:
+		return errors.Errorf("invalid action %q, expected reject or accept", staleGraph.Action)
+	}
+
+	return nil
+}
+
+func validatePrecompute(precompute *Precompute) error {
+	if precompute == nil {
+		return nil
+	}
+
+	if precompute.Peers < 0 {
+		return errors.New("peers must not be negative")
+	}
+	if precompute.Interval < 0 {
+		return errors.New("interval must not be negative")
+	}
+
+	return nil
+}
+
+func validateResponseSLO(slo *ResponseSLO) error {
+	if slo == nil {
+		return nil
+	}
+
+	if slo.Target <= 0 {
+		return errors.New("target must be positive")
+	}
+	if slo.Breaches < 0 {
+		return errors.New("breaches must not be negative")
+	}
+
+	return nil
+}
+
+func validateChain(chain *Chain) error {
+	if chain == nil {
+		return nil
+	}
+
+	if chain.Address == "" {
+		return errors.New("address must be set")
+	}
+	host, _, err := net.SplitHostPort(chain.Address)
+	if err != nil {
+		return errors.Wrap(err, "address")
+	}
+	if (chain.CertificatePath == "") != (chain.KeyPath == "") {
+		return errors.New("certificate_path and key_path must be set together")
+	}
+	// The requests and the macaroons sent along them would travel in the clear, and anyone
+	// reachable could connect as the chained acceptor
+	if chain.CertificatePath == "" && !isLoopback(host) {
+		return errors.Errorf("address %q is not a loopback address, certificate_path is required to listen on it",
+			chain.Address)
+	}
+
+	switch chain.Mode {
+	case "", "and", "or":
+	default:
+		return errors.Errorf("invalid mode %q, expected and or or", chain.Mode)
+	}
+
+	return nil
+}
+
+// isLoopback returns whether the host only accepts local connections. An empty host is defaulted
+// to a loopback address.
+func isLoopback(host string) bool {
+	if host == "" || host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func validateMiddleware(middleware *Middleware) error {
+	if middleware == nil {
+		return nil
+	}
+
+	switch middleware.Mode {
+	case "", MiddlewareObserve, MiddlewareEnforce:
+	default:
+		return errors.Errorf("invalid mode %q, expected %s or %s",
+			middleware.Mode, MiddlewareObserve, MiddlewareEnforce)
+	}
+
+	return nil
+}
+
+func validateWatchOnly(watchOnly *WatchOnly) error {
+	if watchOnly == nil {
+		return nil
+	}
+
+	if watchOnly.ChannelCapacity == 0 {
+		return errors.New("channel_capacity must be set")
+	}
+	if watchOnly.Interval < 0 {
+		return errors.New("interval must not be negative")
+	}
+
+	return policy.ValidatePublicKeys("peers", watchOnly.Peers)
+}
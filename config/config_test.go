@@ -1,12 +1,15 @@
 package config
 
 import (
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/aftermath2/acceptlnd/policy"
 
 	"github.com/lightningnetwork/lnd/lnrpc"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
 )
 
 func TestLoad(t *testing.T) {
@@ -29,6 +32,11 @@ func TestLoad(t *testing.T) {
 			path: "./testdata/invalid_config2.yml",
 			fail: true,
 		},
+		{
+			desc: "Invalid public key",
+			path: "./testdata/invalid_public_key.yml",
+			fail: true,
+		},
 		{
 			desc: "Non existent",
 			path: "",
@@ -50,6 +58,7 @@ func TestLoad(t *testing.T) {
 
 func TestValidate(t *testing.T) {
 	tru := true
+	min, max := uint64(2), uint64(1)
 
 	cases := []struct {
 		desc   string
@@ -78,6 +87,38 @@ func TestValidate(t *testing.T) {
 			},
 			fail: false,
 		},
+		{
+			desc: "Strict policies",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				StrictPolicies:  true,
+				Policies:        []*policy.Policy{{Name: "empty"}},
+			},
+			fail: true,
+		},
+		{
+			desc: "Channels per ASN without database",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Policies:        []*policy.Policy{{MaxChannelsPerASN: new(uint32)}},
+			},
+			fail: true,
+		},
+		{
+			desc: "Channels per ASN",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				ASNDatabasePath: "ip2asn-combined.tsv",
+				Policies:        []*policy.Policy{{MaxChannelsPerASN: new(uint32)}},
+			},
+			fail: false,
+		},
 		{
 			desc: "Invalid RPC address",
 			config: Config{
@@ -85,6 +126,14 @@ func TestValidate(t *testing.T) {
 			},
 			fail: true,
 		},
+		{
+			desc: "Invalid HTTP address",
+			config: Config{
+				RPCAddress:  "127.0.0.1:10001",
+				HTTPAddress: "localhost",
+			},
+			fail: true,
+		},
 		{
 			desc: "Invalid certificate path",
 			config: Config{
@@ -94,19 +143,1233 @@ func TestValidate(t *testing.T) {
 			fail: true,
 		},
 		{
-			desc: "Invalid macaroon path",
+			desc: "Invalid policy",
 			config: Config{
 				RPCAddress:      "127.0.0.1:10001",
 				CertificatePath: "./testdata/tls.mock",
-				MacaroonPath:    "admin.macaroon",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Policies: []*policy.Policy{
+					{
+						BlockList: &[]string{"public_key"},
+					},
+				},
 			},
 			fail: true,
 		},
-	}
-
-	for _, tc := range cases {
-		t.Run(tc.desc, func(t *testing.T) {
-			err := validate(tc.config)
+		{
+			desc: "Unix socket",
+			config: Config{
+				RPCAddress:      "unix:///var/run/lnd/lnd.sock",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+			},
+		},
+		{
+			desc: "Empty unix socket",
+			config: Config{
+				RPCAddress:      "unix:",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+			},
+			fail: true,
+		},
+		{
+			desc: "Unix socket through proxy",
+			config: Config{
+				RPCAddress:      "unix:lnd.sock",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Proxy:           "socks5://127.0.0.1:9050",
+			},
+			fail: true,
+		},
+		{
+			desc: "System certificates",
+			config: Config{
+				RPCAddress:   "127.0.0.1:10001",
+				MacaroonPath: "./testdata/acceptlnd.mock",
+				TLS:          TLS{UseSystemCerts: true},
+			},
+		},
+		{
+			desc: "Client certificate without key",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				TLS:             TLS{ClientCertificatePath: "./testdata/tls.mock"},
+			},
+			fail: true,
+		},
+		{
+			desc: "Invalid client key path",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				TLS: TLS{
+					ClientCertificatePath: "./testdata/tls.mock",
+					ClientKeyPath:         "./testdata/client.key",
+				},
+			},
+			fail: true,
+		},
+		{
+			desc: "Short keepalive",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Keepalive:       Keepalive{Time: time.Second},
+			},
+			fail: true,
+		},
+		{
+			desc: "Startup timeout",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Startup:         Startup{RetryInterval: time.Second, Timeout: time.Minute},
+			},
+		},
+		{
+			desc: "Negative startup timeout",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Startup:         Startup{Timeout: -time.Second},
+			},
+			fail: true,
+		},
+		{
+			desc: "Invalid proxy",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Proxy:           "http://127.0.0.1:9050",
+			},
+			fail: true,
+		},
+		{
+			desc: "Invalid self service",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				SelfServices:    []string{"loop"},
+			},
+			fail: true,
+		},
+		{
+			desc: "Registry",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Registry: []RegistryEntry{{
+					PublicKey: "02a2f2e5d7e1fbda0a7ef1e5d4b1f9c8e4c1e9b6e3a1f1a5d3b9c6e0f2d4c6b8a0",
+					Name:      "Friend",
+					Tags:      []string{"friend"},
+				}},
+			},
+		},
+		{
+			desc: "Registry invalid public key",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Registry:        []RegistryEntry{{PublicKey: "friend", Tags: []string{"friend"}}},
+			},
+			fail: true,
+		},
+		{
+			desc: "Registry without tags",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Registry: []RegistryEntry{{
+					PublicKey: "02a2f2e5d7e1fbda0a7ef1e5d4b1f9c8e4c1e9b6e3a1f1a5d3b9c6e0f2d4c6b8a0",
+				}},
+			},
+			fail: true,
+		},
+		{
+			desc: "Registry update",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				RegistryUpdate: &RegistryUpdate{
+					URL:       "https://example.com/known.json",
+					PublicKey: "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+					Interval:  time.Hour,
+					Pinned:    []string{"02a2f2e5d7e1fbda0a7ef1e5d4b1f9c8e4c1e9b6e3a1f1a5d3b9c6e0f2d4c6b8a0"},
+				},
+			},
+		},
+		{
+			desc: "Registry update invalid url",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				RegistryUpdate: &RegistryUpdate{
+					URL:       "example.com/known.json",
+					PublicKey: "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+				},
+			},
+			fail: true,
+		},
+		{
+			desc: "Registry update invalid public key",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				RegistryUpdate: &RegistryUpdate{
+					URL:       "https://example.com/known.json",
+					PublicKey: "02a2f2e5d7e1fbda0a7ef1e5d4b1f9c8e4c1e9b6e3a1f1a5d3b9c6e0f2d4c6b8a0",
+				},
+			},
+			fail: true,
+		},
+		{
+			desc: "Syslog",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Syslog:          &Syslog{Network: "udp", Address: "127.0.0.1:514", Facility: "local0"},
+			},
+		},
+		{
+			desc: "Syslog address without network",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Syslog:          &Syslog{Address: "127.0.0.1:514"},
+			},
+			fail: true,
+		},
+		{
+			desc: "Syslog unknown facility",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Syslog:          &Syslog{Facility: "kern"},
+			},
+			fail: true,
+		},
+		{
+			desc: "Statsd",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Statsd:          &Statsd{Address: "127.0.0.1:8125", Format: "plain"},
+			},
+		},
+		{
+			desc: "Statsd without address",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Statsd:          &Statsd{},
+			},
+			fail: true,
+		},
+		{
+			desc: "Statsd unknown format",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Statsd:          &Statsd{Address: "127.0.0.1:8125", Format: "graphite"},
+			},
+			fail: true,
+		},
+		{
+			desc: "InfluxDB",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				InfluxDB:        &InfluxDB{URL: "http://127.0.0.1:8086", Bucket: "acceptlnd"},
+			},
+		},
+		{
+			desc: "InfluxDB invalid url",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				InfluxDB:        &InfluxDB{URL: "127.0.0.1:8086", Bucket: "acceptlnd"},
+			},
+			fail: true,
+		},
+		{
+			desc: "InfluxDB without bucket",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				InfluxDB:        &InfluxDB{URL: "http://127.0.0.1:8086"},
+			},
+			fail: true,
+		},
+		{
+			desc: "Concurrent evaluations",
+			config: Config{
+				RPCAddress:               "127.0.0.1:10001",
+				CertificatePath:          "./testdata/tls.mock",
+				MacaroonPath:             "./testdata/acceptlnd.mock",
+				MaxConcurrentEvaluations: 10,
+				OverflowAction:           "reject",
+			},
+		},
+		{
+			desc: "Negative concurrent evaluations",
+			config: Config{
+				RPCAddress:               "127.0.0.1:10001",
+				CertificatePath:          "./testdata/tls.mock",
+				MacaroonPath:             "./testdata/acceptlnd.mock",
+				MaxConcurrentEvaluations: -1,
+			},
+			fail: true,
+		},
+		{
+			desc: "Invalid overflow action",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				OverflowAction:  "drop",
+			},
+			fail: true,
+		},
+		{
+			desc: "Flood protection",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Flood:           &Flood{MaxRequests: 5, Window: time.Minute, BlockDuration: time.Hour},
+			},
+		},
+		{
+			desc: "Flood protection without limits",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Flood:           &Flood{Window: time.Minute, BlockDuration: time.Hour},
+			},
+			fail: true,
+		},
+		{
+			desc: "Flood protection without window",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Flood:           &Flood{MaxAmountRequests: 5, BlockDuration: time.Hour},
+			},
+			fail: true,
+		},
+		{
+			desc: "Watch only",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				WatchOnly: &WatchOnly{
+					Peers:           []string{"02a2f2e5d7e1fbda0a7ef1e5d4b1f9c8e4c1e9b6e3a1f1a5d3b9c6e0f2d4c6b8a0"},
+					ChannelCapacity: 2_000_000,
+				},
+			},
+		},
+		{
+			desc: "Watch only without capacity",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				WatchOnly:       &WatchOnly{},
+			},
+			fail: true,
+		},
+		{
+			desc: "Watch only invalid peer",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				WatchOnly:       &WatchOnly{Peers: []string{"02abc"}, ChannelCapacity: 2_000_000},
+			},
+			fail: true,
+		},
+		{
+			desc: "Chain",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Chain:           &Chain{Address: "127.0.0.1:10010", Mode: "or"},
+			},
+		},
+		{
+			desc: "Middleware",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Middleware:      &Middleware{Mode: MiddlewareEnforce},
+			},
+		},
+		{
+			desc: "Middleware invalid mode",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Middleware:      &Middleware{Mode: "deny"},
+			},
+			fail: true,
+		},
+		{
+			desc: "Chain without address",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Chain:           &Chain{Mode: "and"},
+			},
+			fail: true,
+		},
+		{
+			desc: "Chain certificate without key",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Chain:           &Chain{Address: "127.0.0.1:10010", CertificatePath: "./testdata/tls.mock"},
+			},
+			fail: true,
+		},
+		{
+			desc: "Chain without host",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Chain:           &Chain{Address: ":10010"},
+			},
+		},
+		{
+			desc: "Chain public address with TLS",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Chain: &Chain{
+					Address:         "0.0.0.0:10010",
+					CertificatePath: "./testdata/tls.mock",
+					KeyPath:         "./testdata/tls.mock",
+				},
+			},
+		},
+		{
+			desc: "Chain public address without TLS",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Chain:           &Chain{Address: "0.0.0.0:10010"},
+			},
+			fail: true,
+		},
+		{
+			desc: "Chain invalid address",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Chain:           &Chain{Address: "localhost"},
+			},
+			fail: true,
+		},
+		{
+			desc: "Chain invalid mode",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Chain:           &Chain{Address: "127.0.0.1:10010", Mode: "xor"},
+			},
+			fail: true,
+		},
+		{
+			desc: "Negative reachability timeout",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Reachability:    Reachability{Timeout: -time.Second},
+			},
+			fail: true,
+		},
+		{
+			desc: "Limits",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Limits:          &Limits{MaxPeerChannels: 5000, Action: "accept"},
+			},
+		},
+		{
+			desc: "Limits without maximum",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Limits:          &Limits{Action: "reject"},
+			},
+			fail: true,
+		},
+		{
+			desc: "Limits invalid action",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Limits:          &Limits{MaxPeerChannels: 5000, Action: "skip"},
+			},
+			fail: true,
+		},
+		{
+			desc: "Accept hook",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				AcceptHook:      &AcceptHook{Command: []string{"/usr/local/bin/warmup", "-v"}, MinCapacity: 5_000_000},
+			},
+		},
+		{
+			desc: "Accept hook without command",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				AcceptHook:      &AcceptHook{MinCapacity: 5_000_000},
+			},
+			fail: true,
+		},
+		{
+			desc: "Blocklist export",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				BlocklistExport: &BlocklistExport{Path: "blocklist.txt", Interval: time.Minute},
+			},
+		},
+		{
+			desc: "Blocklist export without path",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				BlocklistExport: &BlocklistExport{Interval: time.Minute},
+			},
+			fail: true,
+		},
+		{
+			desc: "Experiment",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Experiment:      &Experiment{Name: "strict", Percentage: 10, Mode: ExperimentLive},
+			},
+		},
+		{
+			desc: "Experiment without percentage",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Experiment:      &Experiment{Name: "strict"},
+			},
+			fail: true,
+		},
+		{
+			desc: "Experiment with invalid policy",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Experiment: &Experiment{
+					Name:       "strict",
+					Percentage: 10,
+					Policies:   []*policy.Policy{{BlockList: &[]string{"invalid"}}},
+				},
+			},
+			fail: true,
+		},
+		{
+			desc: "Experiment with contradictory policy",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Experiment: &Experiment{
+					Name:       "strict",
+					Percentage: 10,
+					Policies: []*policy.Policy{{
+						Request: &policy.Request{
+							ChannelCapacity: &policy.Range[uint64]{Min: &min, Max: &max},
+						},
+					}},
+				},
+			},
+			fail: true,
+		},
+		{
+			desc: "Resubscribe",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Resubscribe:     &Resubscribe{GracePeriod: time.Minute, OnError: "accept"},
+			},
+		},
+		{
+			desc: "Resubscribe negative grace period",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Resubscribe:     &Resubscribe{GracePeriod: -time.Minute},
+			},
+			fail: true,
+		},
+		{
+			desc: "Resubscribe invalid on_error",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Resubscribe:     &Resubscribe{OnError: "retry"},
+			},
+			fail: true,
+		},
+		{
+			desc: "Stale graph",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				StaleGraph:      &StaleGraph{MaxAge: time.Hour, Action: "accept"},
+			},
+		},
+		{
+			desc: "Stale graph without maximum age",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				StaleGraph:      &StaleGraph{},
+			},
+			fail: true,
+		},
+		{
+			desc: "Stale graph invalid action",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				StaleGraph:      &StaleGraph{MaxAge: time.Hour, Action: "skip"},
+			},
+			fail: true,
+		},
+		{
+			desc: "Precompute",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Precompute:      &Precompute{Peers: 50, Interval: 5 * time.Minute},
+			},
+		},
+		{
+			desc: "Precompute negative peers",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Precompute:      &Precompute{Peers: -1},
+			},
+			fail: true,
+		},
+		{
+			desc: "Response SLO",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				ResponseSLO:     &ResponseSLO{Target: 10 * time.Second, Breaches: 3},
+			},
+		},
+		{
+			desc: "Response SLO without target",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				ResponseSLO:     &ResponseSLO{},
+			},
+			fail: true,
+		},
+		{
+			desc: "Webhook",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				DatabasePath:    "acceptlnd.db",
+				Webhook:         &Webhook{URL: "https://example.com/acceptlnd", MaxAttempts: 10},
+			},
+		},
+		{
+			desc: "Webhook without database",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Webhook:         &Webhook{URL: "https://example.com/acceptlnd"},
+			},
+			fail: true,
+		},
+		{
+			desc: "Webhook memory database",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				DatabaseBackend: "memory",
+				Webhook:         &Webhook{URL: "https://example.com/acceptlnd"},
+			},
+		},
+		{
+			desc: "SQLite database",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				DatabasePath:    "acceptlnd.sqlite",
+				DatabaseBackend: "sqlite",
+			},
+		},
+		{
+			desc: "Postgres database",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				DatabasePath:    "postgres://acceptlnd@localhost/acceptlnd",
+				DatabaseBackend: "postgres",
+			},
+		},
+		{
+			desc: "Auto blocklist",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				DatabasePath:    "acceptlnd.db",
+				AutoBlocklist: &AutoBlocklist{
+					AfterRejections: 5,
+					Within:          7 * 24 * time.Hour,
+					Duration:        30 * 24 * time.Hour,
+				},
+			},
+		},
+		{
+			desc: "Auto blocklist without database",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				AutoBlocklist:   &AutoBlocklist{AfterRejections: 5, Within: time.Hour},
+			},
+			fail: true,
+		},
+		{
+			desc: "Auto blocklist without window",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				DatabasePath:    "acceptlnd.db",
+				AutoBlocklist:   &AutoBlocklist{AfterRejections: 5},
+			},
+			fail: true,
+		},
+		{
+			desc: "Cluster without database",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Cluster:         true,
+				DatabaseBackend: "memory",
+			},
+			fail: true,
+		},
+		{
+			desc: "Cluster with a local database",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Cluster:         true,
+				DatabaseBackend: "sqlite",
+				DatabasePath:    "acceptlnd.db",
+			},
+			fail: true,
+		},
+		{
+			desc: "Cluster",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Cluster:         true,
+				DatabaseBackend: "postgres",
+				DatabasePath:    "postgres://localhost/acceptlnd",
+			},
+		},
+		{
+			desc: "Label channels without database",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				LabelChannels:   true,
+			},
+			fail: true,
+		},
+		{
+			desc: "Label channels",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				LabelChannels:   true,
+				DatabasePath:    "acceptlnd.db",
+			},
+		},
+		{
+			desc: "SQLite without database path",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				DatabaseBackend: "sqlite",
+			},
+			fail: true,
+		},
+		{
+			desc: "Unknown database backend",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				DatabasePath:    "acceptlnd.db",
+				DatabaseBackend: "mysql",
+			},
+			fail: true,
+		},
+		{
+			desc: "Webhook invalid URL",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				DatabasePath:    "acceptlnd.db",
+				Webhook:         &Webhook{URL: "example.com/acceptlnd"},
+			},
+			fail: true,
+		},
+		{
+			desc: "Notify",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				DatabasePath:    "acceptlnd.db",
+				Notify: &Notify{
+					Destinations: []Destination{
+						{Name: "log", Webhook: Webhook{URL: "https://example.com/log"}},
+						{
+							Name:    "telegram",
+							Webhook: Webhook{URL: "https://api.telegram.org/bot123:abc/sendMessage"},
+							Format:  FormatTelegram,
+							ChatID:  "-100123",
+						},
+					},
+					Rules: []NotifyRule{
+						{Events: []string{EventRejected}, Destination: "log"},
+						{Events: []string{EventAccepted}, MinCapacity: 10_000_000, Destination: "telegram"},
+					},
+				},
+			},
+		},
+		{
+			desc: "Notify unknown destination",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				DatabasePath:    "acceptlnd.db",
+				Notify: &Notify{
+					Destinations: []Destination{
+						{Name: "log", Webhook: Webhook{URL: "https://example.com/log"}},
+						{
+							Name:    "telegram",
+							Webhook: Webhook{URL: "https://api.telegram.org/bot123:abc/sendMessage"},
+							Format:  FormatTelegram,
+							ChatID:  "-100123",
+						},
+					},
+					Rules: []NotifyRule{{Destination: "email"}},
+				},
+			},
+			fail: true,
+		},
+		{
+			desc: "Notify invalid event",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				DatabasePath:    "acceptlnd.db",
+				Notify: &Notify{
+					Destinations: []Destination{
+						{Name: "log", Webhook: Webhook{URL: "https://example.com/log"}},
+						{
+							Name:    "telegram",
+							Webhook: Webhook{URL: "https://api.telegram.org/bot123:abc/sendMessage"},
+							Format:  FormatTelegram,
+							ChatID:  "-100123",
+						},
+					},
+					Rules: []NotifyRule{{Events: []string{"opened"}, Destination: "log"}},
+				},
+			},
+			fail: true,
+		},
+		{
+			desc: "Notify telegram without chat ID",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				DatabasePath:    "acceptlnd.db",
+				Notify: &Notify{
+					Destinations: []Destination{{
+						Name:    "telegram",
+						Webhook: Webhook{URL: "https://api.telegram.org/bot123:abc/sendMessage"},
+						Format:  FormatTelegram,
+					}},
+				},
+			},
+			fail: true,
+		},
+		{
+			desc: "Notify duplicate destination",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				DatabasePath:    "acceptlnd.db",
+				Notify: &Notify{
+					Destinations: []Destination{
+						{Name: "log", Webhook: Webhook{URL: "https://example.com/log"}},
+						{Name: "log", Webhook: Webhook{URL: "https://example.com/other"}},
+					},
+				},
+			},
+			fail: true,
+		},
+		{
+			desc: "Notify invalid capacity range",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				DatabasePath:    "acceptlnd.db",
+				Notify: &Notify{
+					Destinations: []Destination{
+						{Name: "log", Webhook: Webhook{URL: "https://example.com/log"}},
+						{
+							Name:    "telegram",
+							Webhook: Webhook{URL: "https://api.telegram.org/bot123:abc/sendMessage"},
+							Format:  FormatTelegram,
+							ChatID:  "-100123",
+						},
+					},
+					Rules: []NotifyRule{{MinCapacity: 2_000_000, MaxCapacity: 1_000_000, Destination: "log"}},
+				},
+			},
+			fail: true,
+		},
+		{
+			desc: "Unknown version",
+			config: Config{
+				Version:         3,
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+			},
+			fail: true,
+		},
+		{
+			desc: "Language",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Language:        "es",
+			},
+		},
+		{
+			desc: "Unknown language",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Language:        "fr",
+			},
+			fail: true,
+		},
+		{
+			desc: "Negative acceptor timeout",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				AcceptorTimeout: -time.Second,
+			},
+			fail: true,
+		},
+		{
+			desc: "Negative reputation half life",
+			config: Config{
+				RPCAddress:         "127.0.0.1:10001",
+				CertificatePath:    "./testdata/tls.mock",
+				MacaroonPath:       "./testdata/acceptlnd.mock",
+				ReputationHalfLife: -time.Hour,
+			},
+			fail: true,
+		},
+		{
+			desc: "Contradictory policy",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "./testdata/acceptlnd.mock",
+				Policies: []*policy.Policy{
+					{
+						Request: &policy.Request{
+							ChannelCapacity: &policy.Range[uint64]{Min: &min, Max: &max},
+						},
+					},
+				},
+			},
+			fail: true,
+		},
+		{
+			desc: "Invalid macaroon path",
+			config: Config{
+				RPCAddress:      "127.0.0.1:10001",
+				CertificatePath: "./testdata/tls.mock",
+				MacaroonPath:    "admin.macaroon",
+			},
+			fail: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := validate(tc.config)
+			if tc.fail {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestLoadFormats(t *testing.T) {
+	expected, err := Load("./testdata/config.yml")
+	assert.NoError(t, err)
+
+	for _, path := range []string{"./testdata/config.json", "./testdata/config.toml"} {
+		t.Run(path, func(t *testing.T) {
+			config, err := Load(path)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, config)
+		})
+	}
+
+	content, err := toYAML("acceptlnd.json", []byte(`{"policies": [{"max_total_inbound_capacity": 18446744073709551615}]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "policies:\n- max_total_inbound_capacity: 18446744073709551615\n", string(content))
+
+	_, err = toYAML("acceptlnd.toml", []byte("rpc_address = "))
+	assert.Error(t, err)
+}
+
+func TestLoadPolicies(t *testing.T) {
+	_, err := Load("./testdata/policies_only.yml")
+	assert.Error(t, err)
+
+	config, err := LoadPolicies("./testdata/policies_only.yml")
+	assert.NoError(t, err)
+	assert.Len(t, config.Policies, 1)
+	assert.Equal(t, policy.Version1, config.Version)
+
+	_, err = LoadPolicies("./testdata/invalid_public_key.yml")
+	assert.ErrorContains(t, err, "line 8")
+}
+
+func TestDecodeKeepalive(t *testing.T) {
+	var config Config
+	content := "keepalive:\n  time: 1m\n  timeout: 15s\n  permit_without_stream: true\n"
+	assert.NoError(t, yaml.Unmarshal([]byte(content), &config))
+	assert.Equal(t, Keepalive{
+		Time:                time.Minute,
+		Timeout:             15 * time.Second,
+		PermitWithoutStream: true,
+	}, config.Keepalive)
+}
+
+func TestUnixSocketPath(t *testing.T) {
+	cases := []struct {
+		address  string
+		path     string
+		isSocket bool
+	}{
+		{address: "unix:///var/run/lnd.sock", path: "/var/run/lnd.sock", isSocket: true},
+		{address: "unix:lnd.sock", path: "lnd.sock", isSocket: true},
+		{address: "unix:/lnd.sock", path: "/lnd.sock", isSocket: true},
+		{address: "127.0.0.1:10009"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.address, func(t *testing.T) {
+			path, ok := UnixSocketPath(tc.address)
+			assert.Equal(t, tc.isSocket, ok)
+			assert.Equal(t, tc.path, path)
+		})
+	}
+}
+
+func TestLoadInvalidPublicKey(t *testing.T) {
+	_, err := Load("./testdata/invalid_public_key.yml")
+	assert.ErrorContains(t, err, "line 8")
+}
+
+func TestLineOf(t *testing.T) {
+	content := []byte("allow_list:\n  - abcd\n  - 'abc'\nblock_list: [ab, xyz]\n")
+
+	assert.Equal(t, 2, lineOf(content, "abcd"))
+	assert.Equal(t, 3, lineOf(content, "abc"))
+	assert.Equal(t, 4, lineOf(content, "xyz"))
+	assert.Equal(t, 0, lineOf(content, "123"))
+	assert.Equal(t, 0, lineOf(content, ""))
+}
+
+func TestAllPolicies(t *testing.T) {
+	enforced := []*policy.Policy{{Name: "enforced"}}
+	config := Config{Policies: enforced}
+	assert.Equal(t, enforced, config.AllPolicies())
+
+	experimental := &policy.Policy{Name: "experimental"}
+	config.Experiment = &Experiment{Policies: []*policy.Policy{experimental}}
+	assert.Equal(t, []*policy.Policy{enforced[0], experimental}, config.AllPolicies())
+	assert.Len(t, config.Policies, 1)
+}
+
+func TestLoadTests(t *testing.T) {
+	tests, err := LoadTests("./testdata/tests.yml")
+	assert.NoError(t, err)
+
+	expected := []PolicyTest{
+		{
+			Name:    "small channel",
+			Request: TestRequest{Capacity: 500_000},
+			Peer: TestPeer{
+				FeatureFlags: []uint32{23},
+				FirstSeenAge: 720 * time.Hour,
+				Channels:     []TestChannel{{Capacity: 1_000_000, Age: 1000, Partner: "self"}},
+			},
+			Expect: TestExpect{Reason: "Channel capacity", Policy: "min-size"},
+		},
+		{
+			Name: "fixture peer",
+			// Relative to the directory of the tests file
+			Peer: TestPeer{NodeInfo: filepath.Join("testdata", "peers", "node.json")},
+		},
+	}
+	assert.Equal(t, expected, tests)
+
+	_, err = LoadTests("./testdata/missing.yml")
+	assert.Error(t, err)
+}
+
+func TestValidateTests(t *testing.T) {
+	cases := []struct {
+		desc  string
+		tests []PolicyTest
+		fail  bool
+	}{
+		{
+			desc:  "Valid",
+			tests: []PolicyTest{{Name: "accepted", Expect: TestExpect{Accept: true, Policy: "#0"}}},
+		},
+		{
+			desc:  "Missing name",
+			tests: []PolicyTest{{Expect: TestExpect{Accept: true}}},
+			fail:  true,
+		},
+		{
+			desc:  "Invalid public key",
+			tests: []PolicyTest{{Name: "invalid", Peer: TestPeer{PublicKey: "02abc"}}},
+			fail:  true,
+		},
+		{
+			desc:  "Accepted with reason",
+			tests: []PolicyTest{{Name: "contradiction", Expect: TestExpect{Accept: true, Reason: "capacity"}}},
+			fail:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := validateTests(tc.tests)
 			if tc.fail {
 				assert.Error(t, err)
 			} else {
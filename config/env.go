@@ -0,0 +1,121 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// envPrefix is prepended to the upper-cased configuration keys to build the environment variables
+// names, e.g. ACCEPTLND_RPC_ADDRESS.
+const envPrefix = "ACCEPTLND_"
+
+// envName returns the environment variable name of a configuration key.
+func envName(key string) string {
+	return envPrefix + strings.ToUpper(key)
+}
+
+// hasEnv returns whether any of the configuration fields is set in the environment.
+func hasEnv() bool {
+	found := false
+	forEachField(func(key string, _ []int) {
+		if _, ok := os.LookupEnv(envName(key)); ok {
+			found = true
+		}
+	})
+	return found
+}
+
+// applyEnv overrides the configuration values with the ones set in environment variables, which
+// take precedence over the configuration file.
+//
+// String fields take the variable value as is, the rest are decoded as YAML (or JSON), so even
+// policies can be specified.
+func applyEnv(config *Config) error {
+	var err error
+	forEachField(func(key string, path []int) {
+		if err != nil {
+			return
+		}
+
+		value, ok := os.LookupEnv(envName(key))
+		if !ok {
+			return
+		}
+
+		field := fieldByPath(reflect.ValueOf(config).Elem(), path)
+		if field.Kind() == reflect.String {
+			field.SetString(value)
+			return
+		}
+
+		v := reflect.New(field.Type())
+		if e := yaml.Unmarshal([]byte(value), v.Interface()); e != nil {
+			err = errors.Wrapf(e, "decoding %s", envName(key))
+			return
+		}
+		field.Set(v.Elem())
+	})
+
+	return err
+}
+
+// forEachField calls fn with every configuration key and the index path of its field, the nested
+// ones named after their parents keys joined with underscores, e.g. tls_insecure_skip_verify.
+// Parents come before their fields, so the variables of the nested keys override the ones of the
+// whole parent value.
+func forEachField(fn func(key string, path []int)) {
+	walkFields(reflect.TypeOf(Config{}), nil, "", nil, fn)
+}
+
+// walkFields calls fn with the key and index path of every field of the struct type t, descending
+// into the nested structs. parents holds the types being visited to stop at recursive ones.
+func walkFields(t reflect.Type, path []int, prefix string, parents []reflect.Type, fn func(key string, path []int)) {
+	parents = append(parents, t)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		key = prefix + key
+		fieldPath := append(slices.Clone(path), i)
+		fn(key, fieldPath)
+
+		if nested, ok := nestedStruct(field.Type); ok && !slices.Contains(parents, nested) {
+			walkFields(nested, fieldPath, key+"_", parents, fn)
+		}
+	}
+}
+
+// nestedStruct returns the struct type of the fields whose keys are configured individually,
+// structs or pointers to them that are decoded key by key.
+func nestedStruct(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || reflect.PointerTo(t).Implements(unmarshalerType) {
+		return nil, false
+	}
+	return t, true
+}
+
+var unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// fieldByPath returns the field at the index path, allocating the optional sections on the way.
+func fieldByPath(v reflect.Value, path []int) reflect.Value {
+	for i, index := range path {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(index)
+	}
+	return v
+}
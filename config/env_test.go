@@ -0,0 +1,94 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyEnv(t *testing.T) {
+	t.Setenv("ACCEPTLND_RPC_ADDRESS", "127.0.0.1:10009")
+	t.Setenv("ACCEPTLND_POLICIES", `[{"reject_private_channels": true}, {"max_channels": 10}]`)
+
+	config := Config{
+		RPCAddress:   "127.0.0.1:10001",
+		MacaroonPath: "acceptlnd.macaroon",
+	}
+	err := applyEnv(&config)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "127.0.0.1:10009", config.RPCAddress)
+	assert.Equal(t, "acceptlnd.macaroon", config.MacaroonPath)
+	assert.Len(t, config.Policies, 2)
+	assert.True(t, *config.Policies[0].RejectPrivateChannels)
+	assert.Equal(t, uint32(10), *config.Policies[1].MaxChannels)
+}
+
+func TestApplyEnvNested(t *testing.T) {
+	t.Setenv("ACCEPTLND_TLS_INSECURE_SKIP_VERIFY", "true")
+	t.Setenv("ACCEPTLND_FLOOD_PROTECTION", "{max_requests: 5, window: 1m}")
+	t.Setenv("ACCEPTLND_FLOOD_PROTECTION_WINDOW", "10m")
+
+	config := Config{TLS: TLS{ClientKeyPath: "client.key"}}
+	err := applyEnv(&config)
+	assert.NoError(t, err)
+
+	assert.True(t, config.TLS.InsecureSkipVerify)
+	assert.Equal(t, "client.key", config.TLS.ClientKeyPath)
+	// The nested keys override the ones of the whole section
+	assert.Equal(t, &Flood{MaxRequests: 5, Window: 10 * time.Minute}, config.Flood)
+	// Optional sections without variables are left unset
+	assert.Nil(t, config.Resubscribe)
+
+	t.Run("Optional section", func(t *testing.T) {
+		t.Setenv("ACCEPTLND_RESUBSCRIBE_GRACE_PERIOD", "30s")
+
+		config := Config{}
+		assert.NoError(t, applyEnv(&config))
+		assert.Equal(t, &Resubscribe{GracePeriod: 30 * time.Second}, config.Resubscribe)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		t.Setenv("ACCEPTLND_KEEPALIVE_TIME", "soon")
+
+		err := applyEnv(&Config{})
+		assert.ErrorContains(t, err, "ACCEPTLND_KEEPALIVE_TIME")
+	})
+}
+
+func TestApplyEnvInvalid(t *testing.T) {
+	t.Setenv("ACCEPTLND_POLICIES", "reject_all")
+
+	err := applyEnv(&Config{})
+	assert.Error(t, err)
+}
+
+func TestHasEnv(t *testing.T) {
+	assert.False(t, hasEnv())
+
+	t.Setenv("ACCEPTLND_MACAROON_PATH", "acceptlnd.macaroon")
+	assert.True(t, hasEnv())
+}
+
+func TestHasEnvNested(t *testing.T) {
+	t.Setenv("ACCEPTLND_TLS_INSECURE_SKIP_VERIFY", "true")
+	assert.True(t, hasEnv())
+}
+
+func TestLoadEnvOnly(t *testing.T) {
+	t.Setenv("ACCEPTLND_RPC_ADDRESS", "127.0.0.1:10001")
+	t.Setenv("ACCEPTLND_CERTIFICATE_PATH", "./testdata/tls.mock")
+	t.Setenv("ACCEPTLND_MACAROON_PATH", "./testdata/acceptlnd.mock")
+
+	config, err := Load("./testdata/non_existent.yml")
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:10001", config.RPCAddress)
+}
+
+func TestLoadEnvPrecedence(t *testing.T) {
+	t.Setenv("ACCEPTLND_RPC_ADDRESS", "localhost")
+
+	_, err := Load("./testdata/config.yml")
+	assert.Error(t, err)
+}
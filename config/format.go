@@ -0,0 +1,70 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// toYAML converts the configuration to YAML, which the rest of the loading works with, based on
+// the file extension: .json and .toml files are converted, the rest are read as YAML already.
+func toYAML(path string, content []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(content)) == 0 {
+		return content, nil
+	}
+
+	var document any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		// Numbers are kept as they are written, so the integers don't become floats
+		decoder.UseNumber()
+		if err := decoder.Decode(&document); err != nil {
+			return nil, errors.Wrap(err, "decoding JSON configuration")
+		}
+		document = jsonNumbers(document)
+	case ".toml":
+		var table map[string]any
+		if err := toml.Unmarshal(content, &table); err != nil {
+			return nil, errors.Wrap(err, "decoding TOML configuration")
+		}
+		document = table
+	default:
+		return content, nil
+	}
+
+	content, err := yaml.Marshal(document)
+	return content, errors.Wrap(err, "encoding configuration")
+}
+
+// jsonNumbers replaces the JSON numbers in the document with integers or floats.
+func jsonNumbers(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = jsonNumbers(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = jsonNumbers(item)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		// Integers above the int64 range, like the uint64 maximums
+		if n, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return n
+		}
+		if n, err := v.Float64(); err == nil {
+			return n
+		}
+	}
+	return value
+}
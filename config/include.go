@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/aftermath2/acceptlnd/policy"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// included is the content of the included files, which may only define policies.
+type included struct {
+	Policies []*policy.Policy `yaml:"policies"`
+}
+
+// expandIncludes appends the policies of the included files to the configured ones. The patterns
+// are relative to the directory of the configuration file, or the working directory if it's read
+// from the standard input, and the files matched by each of them are read in lexical order.
+func expandIncludes(config *Config, path string) error {
+	dir := "."
+	if path != "-" {
+		dir = filepath.Dir(path)
+	}
+
+	for _, pattern := range config.Include {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+
+		// The matches are sorted already
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return errors.Wrapf(err, "include %s", pattern)
+		}
+		if len(paths) == 0 {
+			return errors.Errorf("include %s: no files match the pattern", pattern)
+		}
+
+		for _, path := range paths {
+			policies, err := readIncluded(path)
+			if err != nil {
+				return errors.Wrapf(err, "include %s", path)
+			}
+			config.Policies = append(config.Policies, policies...)
+		}
+	}
+
+	return nil
+}
+
+// readIncluded returns the policies of the file, which may be written in any of the formats the
+// configuration accepts.
+func readIncluded(path string) ([]*policy.Policy, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening file")
+	}
+
+	content, err = toYAML(path, content)
+	if err != nil {
+		return nil, err
+	}
+	content, err = interpolate(content)
+	if err != nil {
+		return nil, errors.Wrap(err, "interpolating environment variables")
+	}
+
+	var file included
+	if err := yaml.UnmarshalStrict(content, &file); err != nil {
+		return nil, errors.Wrap(err, "decoding policies")
+	}
+	return file.Policies, nil
+}
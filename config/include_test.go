@@ -0,0 +1,49 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadInclude(t *testing.T) {
+	config, err := LoadPolicies("./testdata/include.yml")
+	assert.NoError(t, err)
+
+	names := make([]string, 0, len(config.Policies))
+	for _, p := range config.Policies {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"capacity", "routing", "exchanges", "wallets"}, names)
+}
+
+func TestExpandIncludes(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	write("rpc.yml", "rpc_address: 127.0.0.1:10001\n")
+	write("nested.yml", "include:\n  - rpc.yml\n")
+	write("invalid.toml", "policies = ")
+
+	cases := []struct {
+		desc    string
+		include []string
+	}{
+		{desc: "No matches", include: []string{"policies/*.yml"}},
+		{desc: "Missing file", include: []string{"missing.yml"}},
+		{desc: "Invalid pattern", include: []string{"[.yml"}},
+		{desc: "Other keys", include: []string{"rpc.yml"}},
+		{desc: "Nested include", include: []string{"nested.yml"}},
+		{desc: "Invalid format", include: []string{"invalid.toml"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := &Config{Include: tc.include}
+			assert.Error(t, expandIncludes(config, filepath.Join(dir, "acceptlnd.yml")))
+		})
+	}
+}
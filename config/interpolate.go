@@ -0,0 +1,116 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// envReference matches the environment variables referenced in the values, ${NAME} or
+// ${NAME:-default}, and the escaped references, $${NAME}.
+var envReference = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// interpolate replaces the environment variables referenced in the configuration values with their
+// content. Only the values are expanded, so the variables can't change the document structure.
+func interpolate(content []byte) ([]byte, error) {
+	if !bytes.Contains(content, []byte("${")) {
+		return content, nil
+	}
+
+	var document yaml.MapSlice
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, errors.Wrap(err, "decoding configuration")
+	}
+
+	expanded, err := expandValue(document)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err = yaml.Marshal(expanded)
+	return content, errors.Wrap(err, "encoding configuration")
+}
+
+func expandValue(value any) (any, error) {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		for i, item := range v {
+			expanded, err := expandValue(item.Value)
+			if err != nil {
+				if key, ok := item.Key.(string); ok {
+					return nil, errors.Wrap(err, key)
+				}
+				return nil, err
+			}
+			v[i].Value = expanded
+		}
+		return v, nil
+	case []any:
+		for i, item := range v {
+			expanded, err := expandValue(item)
+			if err != nil {
+				return nil, errors.Wrapf(err, "[%d]", i)
+			}
+			v[i] = expanded
+		}
+		return v, nil
+	case string:
+		return expandString(v)
+	default:
+		return value, nil
+	}
+}
+
+// expandString replaces the references in the string. Values consisting of a single reference
+// take the type of the variable content, so numbers and booleans can be set too.
+func expandString(s string) (any, error) {
+	var err error
+	expanded := envReference.ReplaceAllStringFunc(s, func(match string) string {
+		if match[1] == '$' {
+			return match[1:]
+		}
+
+		groups := envReference.FindStringSubmatch(match)
+		value, ok := os.LookupEnv(groups[1])
+		if !ok || value == "" {
+			if groups[2] == "" {
+				if ok {
+					return ""
+				}
+				if err == nil {
+					err = errors.Errorf("environment variable %s is not set", groups[1])
+				}
+				return match
+			}
+			value = groups[3]
+		}
+		return value
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if expanded == s || !isReference(s) {
+		return expanded, nil
+	}
+
+	var scalar any
+	if err := yaml.Unmarshal([]byte(expanded), &scalar); err != nil {
+		return expanded, nil
+	}
+	switch scalar.(type) {
+	case int, int64, uint64, float64, bool:
+		return scalar, nil
+	default:
+		return expanded, nil
+	}
+}
+
+// isReference returns whether the string is a single unescaped reference.
+func isReference(s string) bool {
+	loc := envReference.FindStringIndex(s)
+	return loc != nil && loc[0] == 0 && loc[1] == len(s) && s[1] != '$'
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("ACCEPTLND_TEST_HOST", "node.local")
+	t.Setenv("ACCEPTLND_TEST_CHANNELS", "20")
+	t.Setenv("ACCEPTLND_TEST_EMPTY", "")
+	t.Setenv("ACCEPTLND_TEST_STRUCTURE", "{a: b}")
+
+	cases := []struct {
+		desc     string
+		content  string
+		expected string
+		fail     bool
+	}{
+		{
+			desc:     "No references",
+			content:  "rpc_address: 127.0.0.1:10009 # comment\n",
+			expected: "rpc_address: 127.0.0.1:10009 # comment\n",
+		},
+		{
+			desc:     "Comment",
+			content:  "rpc_address: 127.0.0.1:10009 # ${ACCEPTLND_TEST_UNSET}\n",
+			expected: "rpc_address: 127.0.0.1:10009\n",
+		},
+		{
+			desc:     "Value",
+			content:  "rpc_address: ${ACCEPTLND_TEST_HOST}:10009\n",
+			expected: "rpc_address: node.local:10009\n",
+		},
+		{
+			desc:     "Number",
+			content:  "policies:\n  - max_channels: ${ACCEPTLND_TEST_CHANNELS}\n",
+			expected: "policies:\n- max_channels: 20\n",
+		},
+		{
+			desc:     "Quoted number",
+			content:  "macaroon_path: '/data/${ACCEPTLND_TEST_CHANNELS}'\n",
+			expected: "macaroon_path: /data/20\n",
+		},
+		{
+			desc:     "List entry",
+			content:  "self_services:\n  - ${ACCEPTLND_TEST_HOST}\n",
+			expected: "self_services:\n- node.local\n",
+		},
+		{
+			desc:     "Default",
+			content:  "rpc_address: ${ACCEPTLND_TEST_UNSET:-localhost}:${ACCEPTLND_TEST_EMPTY:-10009}\n",
+			expected: "rpc_address: localhost:10009\n",
+		},
+		{
+			desc:     "Empty",
+			content:  "rpc_address: localhost${ACCEPTLND_TEST_EMPTY}\n",
+			expected: "rpc_address: localhost\n",
+		},
+		{
+			desc:     "Escaped",
+			content:  "rpc_address: $${ACCEPTLND_TEST_HOST}\n",
+			expected: "rpc_address: ${ACCEPTLND_TEST_HOST}\n",
+		},
+		{
+			desc:     "Structure",
+			content:  "rpc_address: ${ACCEPTLND_TEST_STRUCTURE}\n",
+			expected: "rpc_address: '{a: b}'\n",
+		},
+		{
+			desc:    "Unset",
+			content: "rpc_address: ${ACCEPTLND_TEST_UNSET}\n",
+			fail:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			content, err := interpolate([]byte(tc.content))
+			if tc.fail {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, string(content))
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/policy"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// PolicyTest is a synthetic channel request and the decision the policies are expected to take
+// on it.
+type PolicyTest struct {
+	Name    string      `yaml:"name,omitempty" doc:"Name of the test, shown in the results. Required."`
+	Request TestRequest `yaml:"request,omitempty" doc:"Channel opening request received."`
+	Peer    TestPeer    `yaml:"peer,omitempty" doc:"Node requesting the channel."`
+	Expect  TestExpect  `yaml:"expect,omitempty" doc:"Decision expected."`
+}
+
+// TestRequest contains the parameters of a synthetic channel request.
+type TestRequest struct {
+	Capacity         uint64               `yaml:"capacity,omitempty" doc:"Channel size, in sats."`
+	PushAmount       uint64               `yaml:"push_amount,omitempty" doc:"Amount pushed to us, in sats."`
+	Private          bool                 `yaml:"private,omitempty" doc:"Whether the channel is private."`
+	ZeroConf         bool                 `yaml:"zero_conf,omitempty" doc:"Whether the peer wants a zero confirmation channel."`
+	ScidAlias        bool                 `yaml:"scid_alias,omitempty" doc:"Whether the peer wants an SCID alias."`
+	CommitmentType   lnrpc.CommitmentType `yaml:"commitment_type,omitempty" doc:"Channel commitment type, see lnrpc.CommitmentType."`
+	ChannelReserve   uint64               `yaml:"channel_reserve,omitempty" doc:"Channel reserve, in sats."`
+	CSVDelay         uint32               `yaml:"csv_delay,omitempty" doc:"CSV delay, in blocks."`
+	MaxAcceptedHTLCs uint32               `yaml:"max_accepted_htlcs,omitempty" doc:"Total number of incoming HTLCs the initiator will accept."`
+	MinHTLC          uint64               `yaml:"min_htlc,omitempty" doc:"Smallest HTLC the initiator will accept, in millisatoshis."`
+	MaxValueInFlight uint64               `yaml:"max_value_in_flight,omitempty" doc:"Maximum amount that can be pending in the channel, in millisatoshis."`
+	DustLimit        uint64               `yaml:"dust_limit,omitempty" doc:"Dust limit of the initiator's commitment transaction, in sats."`
+}
+
+// TestPeer describes the node sending a synthetic channel request.
+type TestPeer struct {
+	PublicKey    string        `yaml:"public_key,omitempty" doc:"Node public key, a fixed one is used if it's not set."`
+	Alias        string        `yaml:"alias,omitempty" doc:"Node alias."`
+	FeatureFlags []uint32      `yaml:"feature_flags,omitempty" doc:"Feature flags the node announces, see lnrpc.FeatureBit."`
+	Addresses    []string      `yaml:"addresses,omitempty" doc:"Addresses the node announces."`
+	Channels     []TestChannel `yaml:"channels,omitempty" doc:"Public channels of the node."`
+	FirstSeenAge time.Duration `yaml:"first_seen_age,omitempty" doc:"Time since the node requested a channel with us for the first time, zero if it never did."`
+	Reputation   float64       `yaml:"reputation,omitempty" doc:"Node reputation score."`
+	Rejections   uint64        `yaml:"previous_rejections,omitempty" doc:"Number of the node requests rejected before."`
+	Acceptances  uint64        `yaml:"previous_acceptances,omitempty" doc:"Number of the node requests accepted before."`
+	LastDecision time.Duration `yaml:"last_decision_age,omitempty" doc:"Time since the last decision on a request of the node, zero if there wasn't any."`
+	Address      string        `yaml:"address,omitempty" doc:"Address (host:port) the node is connected from."`
+	TorExit      bool          `yaml:"tor_exit,omitempty" doc:"Whether the address is a Tor exit relay."`
+	SameIP       uint32        `yaml:"same_ip_channels,omitempty" doc:"Number of our channels with peers connected from the same IP address as the node."`
+	SameASN      uint32        `yaml:"same_asn_channels,omitempty" doc:"Number of our channels with peers connected from the same autonomous system as the node."`
+	Reachable    bool          `yaml:"reachable,omitempty" doc:"Whether the node accepts connections on its announced addresses."`
+	NodeInfo     string        `yaml:"node_info,omitempty" doc:"Path to the node information in JSON format (lncli getnodeinfo --include_channels), relative to the file of the test, used instead of the graph fields above."`
+}
+
+// TestChannel is a public channel of a synthetic peer.
+type TestChannel struct {
+	Capacity       int64  `yaml:"capacity,omitempty" doc:"Channel size, in sats."`
+	Age            uint32 `yaml:"age,omitempty" doc:"Blocks since the channel was opened."`
+	Partner        string `yaml:"partner,omitempty" doc:"Public key of the node on the other side, our node if it's \"self\"."`
+	FeeRate        int64  `yaml:"fee_rate,omitempty" doc:"Fee rate the peer charges, in ppm."`
+	BaseFee        int64  `yaml:"base_fee,omitempty" doc:"Base fee the peer charges, in millisatoshis."`
+	Disabled       bool   `yaml:"disabled,omitempty" doc:"Whether the peer disabled the channel."`
+	TimeLockDelta  uint32 `yaml:"time_lock_delta,omitempty" doc:"Time lock delta of the peer side."`
+	MinHTLC        int64  `yaml:"min_htlc,omitempty" doc:"Smallest HTLC the peer forwards, in millisatoshis."`
+	MaxHTLC        uint64 `yaml:"max_htlc,omitempty" doc:"Largest HTLC the peer forwards, in millisatoshis."`
+	LastUpdateDiff uint32 `yaml:"last_update_diff,omitempty" doc:"Seconds since the peer last updated its policy."`
+}
+
+// TestExpect is the decision expected for a synthetic channel request.
+type TestExpect struct {
+	Accept bool   `yaml:"accept,omitempty" doc:"Whether the request must be accepted."`
+	Reason string `yaml:"reason,omitempty" doc:"Text the rejection reason must contain."`
+	Policy string `yaml:"policy,omitempty" doc:"Label of the policy that must reject the request or, if accepted, take part in the decision, its name or #index."`
+}
+
+// LoadTests reads a file containing a list of policy tests under the tests key, like the
+// configuration.
+func LoadTests(path string) ([]PolicyTest, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening tests file")
+	}
+
+	var file struct {
+		Tests []PolicyTest `yaml:"tests"`
+	}
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, errors.Wrap(err, "decoding tests")
+	}
+	if err := validateTests(file.Tests); err != nil {
+		return nil, errors.Wrap(err, "validating tests")
+	}
+	resolveNodeInfo(file.Tests, path)
+	return file.Tests, nil
+}
+
+// resolveNodeInfo makes the node information paths of the tests relative to the directory of the
+// file defining them, like the included files, or the working directory if it's read from the
+// standard input.
+func resolveNodeInfo(tests []PolicyTest, path string) {
+	dir := "."
+	if path != "-" {
+		dir = filepath.Dir(path)
+	}
+
+	for i, test := range tests {
+		if test.Peer.NodeInfo != "" && !filepath.IsAbs(test.Peer.NodeInfo) {
+			tests[i].Peer.NodeInfo = filepath.Join(dir, test.Peer.NodeInfo)
+		}
+	}
+}
+
+func validateTests(tests []PolicyTest) error {
+	for i, test := range tests {
+		if test.Name == "" {
+			return errors.Errorf("test %d: name must be set", i)
+		}
+		if test.Peer.PublicKey != "" {
+			if err := policy.ValidatePublicKeys("peer.public_key", []string{test.Peer.PublicKey}); err != nil {
+				return errors.Wrapf(err, "test %q", test.Name)
+			}
+		}
+		if test.Expect.Accept && test.Expect.Reason != "" {
+			return errors.Errorf("test %q: expect.reason requires the request to be rejected", test.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"embed"
+
+	"github.com/aftermath2/acceptlnd/policy"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Presets names.
+const (
+	PresetConservative = "conservative"
+	PresetRoutingNode  = "routing_node"
+	PresetLSPProvider  = "lsp_provider"
+)
+
+//go:embed presets/*.yml
+var presets embed.FS
+
+// Preset returns the policies of the built-in preset.
+func Preset(name string) ([]*policy.Policy, error) {
+	switch name {
+	case PresetConservative, PresetRoutingNode, PresetLSPProvider:
+	default:
+		return nil, errors.Errorf("unknown preset %q, expected %s, %s or %s",
+			name, PresetConservative, PresetRoutingNode, PresetLSPProvider)
+	}
+
+	content, err := presets.ReadFile("presets/" + name + ".yml")
+	if err != nil {
+		return nil, errors.Wrap(err, "reading preset")
+	}
+
+	var policies []*policy.Policy
+	if err := yaml.UnmarshalStrict(content, &policies); err != nil {
+		return nil, errors.Wrapf(err, "decoding preset %s", name)
+	}
+	return policies, nil
+}
+
+// expandPreset puts the preset policies before the configured ones. Configured policies named
+// like a preset one replace it in its position.
+func expandPreset(config *Config) error {
+	if config.Preset == "" {
+		return nil
+	}
+
+	policies, err := Preset(config.Preset)
+	if err != nil {
+		return errors.Wrap(err, "preset")
+	}
+
+	index := make(map[string]int, len(policies))
+	for i, p := range policies {
+		index[p.Name] = i
+	}
+	for _, p := range config.Policies {
+		if i, ok := index[p.Name]; ok && p.Name != "" {
+			policies[i] = p
+			continue
+		}
+		policies = append(policies, p)
+	}
+
+	config.Policies = policies
+	return nil
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/aftermath2/acceptlnd/policy"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreset(t *testing.T) {
+	for _, name := range []string{PresetConservative, PresetRoutingNode, PresetLSPProvider} {
+		t.Run(name, func(t *testing.T) {
+			policies, err := Preset(name)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, policies)
+			assert.NoError(t, validatePolicyList(policies, true))
+			assert.Empty(t, policy.Analyze(policies))
+		})
+	}
+
+	_, err := Preset("aggressive")
+	assert.Error(t, err)
+}
+
+func TestLoadPreset(t *testing.T) {
+	config, err := LoadPolicies("./testdata/preset.yml")
+	assert.NoError(t, err)
+
+	names := make([]string, 0, len(config.Policies))
+	for _, p := range config.Policies {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"routing-public", "routing-size", "routing-node", "routing-fees", "capacity"}, names)
+	assert.Nil(t, config.Policies[3].Node)
+	assert.Equal(t, "Fees are not taken into account.", config.Policies[3].Description)
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// referenceWidth is the column the documentation lines of the reference are wrapped at.
+const referenceWidth = 100
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// WriteReference writes a YAML document describing the configuration fields, with their
+// explanations and default values taken from the doc and default struct tags.
+//
+// Every line is commented out so it can be used as a starting point, uncommenting the options
+// needed. Only the top-level fields are included unless full is true, in which case the nested
+// sections and a policy with every requirement are expanded as well.
+func WriteReference(w io.Writer, full bool) error {
+	r := &reference{w: w, full: full}
+	r.writeStruct(reflect.TypeOf(Config{}), 0)
+	return r.err
+}
+
+type reference struct {
+	w    io.Writer
+	err  error
+	full bool
+}
+
+func (r *reference) writeStruct(t reflect.Type, depth int) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		key, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if options == "inline" {
+			r.writeStruct(indirect(field.Type), depth)
+			continue
+		}
+		if key == "" || key == "-" {
+			continue
+		}
+
+		if depth == 0 && i > 0 {
+			r.printf("\n")
+		}
+		r.writeDoc(field.Tag.Get("doc"), depth)
+		r.writeField(key, field.Type, field.Tag.Get("default"), depth)
+	}
+}
+
+func (r *reference) writeField(key string, t reflect.Type, def string, depth int) {
+	indent := strings.Repeat("  ", depth)
+	t = indirect(t)
+
+	switch {
+	case t == durationType:
+		r.printf("%s# %s: %s\n", indent, key, valueOr(def, "0s"))
+	case t.Kind() == reflect.Struct:
+		if !r.full && depth == 0 {
+			r.printf("%s# %s: {}\n", indent, key)
+			return
+		}
+		r.printf("%s# %s:\n", indent, key)
+		r.writeStruct(t, depth+1)
+	case t.Kind() == reflect.Slice && isStruct(t.Elem()):
+		if !r.full && depth == 0 {
+			r.printf("%s# %s: []\n", indent, key)
+			return
+		}
+		r.printf("%s# %s:\n", indent, key)
+		r.printf("%s  # -\n", indent)
+		r.writeStruct(indirect(t.Elem()), depth+2)
+	case t.Kind() == reflect.Slice:
+		r.printf("%s# %s: []\n", indent, key)
+	case t.Kind() == reflect.String:
+		r.printf("%s# %s: %s\n", indent, key, valueOr(def, `""`))
+	case t.Kind() == reflect.Bool:
+		r.printf("%s# %s: %s\n", indent, key, valueOr(def, "false"))
+	default:
+		r.printf("%s# %s: %s\n", indent, key, valueOr(def, "0"))
+	}
+}
+
+// writeDoc writes the explanation of a field wrapped at the reference width.
+func (r *reference) writeDoc(doc string, depth int) {
+	prefix := strings.Repeat("  ", depth) + "#"
+	line := prefix
+	for _, word := range strings.Fields(doc) {
+		if len(line)+1+len(word) > referenceWidth && line != prefix {
+			r.printf("%s\n", line)
+			line = prefix
+		}
+		line += " " + word
+	}
+	if line != prefix {
+		r.printf("%s\n", line)
+	}
+}
+
+func (r *reference) printf(format string, args ...any) {
+	if r.err != nil {
+		return
+	}
+	_, r.err = fmt.Fprintf(r.w, format, args...)
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+func isStruct(t reflect.Type) bool {
+	return indirect(t).Kind() == reflect.Struct
+}
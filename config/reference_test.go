@@ -0,0 +1,122 @@
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestWriteReference(t *testing.T) {
+	cases := []struct {
+		desc     string
+		contains []string
+		excludes []string
+		full     bool
+	}{
+		{
+			desc: "Top level",
+			contains: []string{
+				"# rpc_address: \"\"\n",
+				"# database_backend: bbolt\n",
+				"# reputation_half_life: 720h0m0s\n",
+				"# tls: {}\n",
+				"# policies: []\n",
+			},
+			excludes: []string{"  # use_system_certs: false\n", "# name:"},
+		},
+		{
+			desc: "Full",
+			full: true,
+			contains: []string{
+				"# rpc_address: \"\"\n",
+				"# tls:\n  # Verify LND's certificate",
+				"  # use_system_certs: false\n",
+				"  # timeout: 5s\n",
+				"# policies:\n  # -\n",
+				"    # name: \"\"\n",
+				"        # channels:\n",
+				"          # fee_rates:\n",
+				"            # operation: mean\n",
+			},
+			excludes: []string{"\n# tls: {}\n", "\n# policies: []\n"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := WriteReference(&buf, tc.full)
+			assert.NoError(t, err)
+
+			out := buf.String()
+			for _, s := range tc.contains {
+				assert.Contains(t, out, s)
+			}
+			for _, s := range tc.excludes {
+				assert.NotContains(t, out, s)
+			}
+
+			for _, line := range strings.Split(out, "\n") {
+				assert.LessOrEqual(t, len(line), referenceWidth, line)
+			}
+		})
+	}
+}
+
+// TestWriteReferenceUncommented verifies the reference becomes a valid configuration when the
+// options are uncommented.
+func TestWriteReferenceUncommented(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteReference(&buf, true)
+	assert.NoError(t, err)
+
+	option := regexp.MustCompile(`^( *)# ([a-z_]+:( .*)?|-)$`)
+	var lines []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if m := option.FindStringSubmatch(line); m != nil {
+			lines = append(lines, m[1]+m[2])
+		}
+	}
+
+	var config Config
+	err = yaml.UnmarshalStrict([]byte(strings.Join(lines, "\n")), &config)
+	assert.NoError(t, err)
+	assert.Len(t, config.Policies, 1)
+}
+
+// TestFieldsDocumented makes sure every configuration field is explained in the reference.
+func TestFieldsDocumented(t *testing.T) {
+	seen := make(map[reflect.Type]bool)
+	var check func(t *testing.T, typ reflect.Type)
+	check = func(t *testing.T, typ reflect.Type) {
+		typ = indirect(typ)
+		if typ.Kind() == reflect.Slice {
+			typ = indirect(typ.Elem())
+		}
+		if typ.Kind() != reflect.Struct || seen[typ] {
+			return
+		}
+		seen[typ] = true
+
+		for i := range typ.NumField() {
+			field := typ.Field(i)
+			key, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if options == "inline" {
+				check(t, field.Type)
+				continue
+			}
+			if key == "" || key == "-" {
+				continue
+			}
+			assert.NotEmpty(t, field.Tag.Get("doc"), "%s.%s", typ.Name(), field.Name)
+			check(t, field.Type)
+		}
+	}
+
+	check(t, reflect.TypeOf(Config{}))
+}
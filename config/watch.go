@@ -0,0 +1,77 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+)
+
+// watchDelay is the time waited after the last change of the file before reporting it, so the
+// tools writing it in several steps trigger a single reload.
+const watchDelay = 500 * time.Millisecond
+
+// Watcher reports the changes of a configuration file.
+type Watcher struct {
+	path    string
+	watcher *fsnotify.Watcher
+}
+
+// NewWatcher starts watching the configuration file. The directory is watched instead of the file
+// itself, so the files replaced by renaming another one over them are still followed.
+func NewWatcher(path string) (*Watcher, error) {
+	if path == "" || path == "-" {
+		return nil, errors.New("only configuration files can be watched")
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolving configuration path")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating file watcher")
+	}
+	if err := watcher.Add(filepath.Dir(abs)); err != nil {
+		watcher.Close()
+		return nil, errors.Wrap(err, "watching configuration directory")
+	}
+
+	return &Watcher{path: abs, watcher: watcher}, nil
+}
+
+// Run calls changed every time the file is written or created, which is what renaming another
+// file over it looks like, until the context is cancelled.
+func (w *Watcher) Run(ctx context.Context, changed func()) {
+	defer w.watcher.Close()
+
+	timer := time.NewTimer(watchDelay)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != w.path || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			timer.Reset(watchDelay)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			slog.Warn("Watching configuration file", slog.Any("error", err))
+		case <-timer.C:
+			changed()
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "acceptlnd.yml")
+	assert.NoError(t, os.WriteFile(path, []byte("policies: []\n"), 0o600))
+
+	watcher, err := NewWatcher(path)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan struct{}, 10)
+	go watcher.Run(ctx, func() { changes <- struct{}{} })
+
+	expectChange := func(t *testing.T) {
+		select {
+		case <-changes:
+		case <-time.After(5 * time.Second):
+			t.Fatal("change not reported")
+		}
+	}
+
+	t.Run("Other file", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "other.yml"), []byte("a: b\n"), 0o600))
+		select {
+		case <-changes:
+			t.Fatal("change of another file reported")
+		case <-time.After(2 * watchDelay):
+		}
+	})
+
+	t.Run("Write", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(path, []byte("policies: []\nversion: 2\n"), 0o600))
+		expectChange(t)
+	})
+
+	t.Run("Rename over", func(t *testing.T) {
+		tmp := filepath.Join(dir, "acceptlnd.yml.tmp")
+		assert.NoError(t, os.WriteFile(tmp, []byte("policies: []\n"), 0o600))
+		assert.NoError(t, os.Rename(tmp, path))
+		expectChange(t)
+	})
+}
+
+func TestNewWatcherStdin(t *testing.T) {
+	_, err := NewWatcher("-")
+	assert.Error(t, err)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+)
+
+// correlationKey is the context key of the correlation ID of the requests.
+type correlationKey struct{}
+
+// withCorrelationID returns a context carrying a new random ID, used to stitch together the log
+// lines and records of a channel request evaluated concurrently with others.
+func withCorrelationID(ctx context.Context) context.Context {
+	b := make([]byte, 8)
+	// crypto/rand never fails on the supported platforms
+	_, _ = rand.Read(b)
+	return context.WithValue(ctx, correlationKey{}, hex.EncodeToString(b))
+}
+
+// correlationID returns the ID of the request the context belongs to, empty if there is none.
+func correlationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// correlationHandler adds the correlation ID of the context to the records logged with it.
+type correlationHandler struct {
+	slog.Handler
+}
+
+func (h correlationHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := correlationID(ctx); id != "" {
+		record = record.Clone()
+		record.AddAttrs(slog.String("correlation_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h correlationHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return correlationHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h correlationHandler) WithGroup(name string) slog.Handler {
+	return correlationHandler{h.Handler.WithGroup(name)}
+}
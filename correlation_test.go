@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/graph"
+	"github.com/aftermath2/acceptlnd/lightning/fake"
+	"github.com/aftermath2/acceptlnd/policy"
+	"github.com/aftermath2/acceptlnd/store"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorrelationID(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	previous := slog.Default()
+	slog.SetDefault(slog.New(correlationHandler{handler}))
+	defer slog.SetDefault(previous)
+
+	peer := "02" + hex.EncodeToString(make([]byte, 32))
+	publicKey, err := hex.DecodeString(peer)
+	assert.NoError(t, err)
+	snapshot := graph.New(&lnrpc.ChannelGraph{
+		Nodes: []*lnrpc.LightningNode{{PubKey: "us"}, {PubKey: peer}},
+	})
+	maxCapacity := uint64(1_000_000)
+	cfg := config.Config{
+		Policies: []*policy.Policy{{
+			Request: &policy.Request{ChannelCapacity: &policy.Range[uint64]{Max: &maxCapacity}},
+		}},
+	}
+	db := store.NewMemory()
+	a := newAcceptor(fake.New(snapshot, "us"), db, cfg)
+	send := func(*lnrpc.ChannelAcceptResponse) error { return nil }
+
+	cases := []struct {
+		desc     string
+		capacity uint64
+	}{
+		{desc: "Accepted", capacity: 500_000},
+		{desc: "Rejected", capacity: 2_000_000},
+	}
+
+	ids := make(map[string]bool, len(cases))
+	for i, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			buf.Reset()
+			ctx := withCorrelationID(context.Background())
+			id := correlationID(ctx)
+			assert.Len(t, id, 16)
+			assert.False(t, ids[id], "correlation IDs must be unique")
+			ids[id] = true
+
+			req := &lnrpc.ChannelAcceptRequest{
+				NodePubkey:    publicKey,
+				PendingChanId: []byte{byte(i)},
+				FundingAmt:    tc.capacity,
+			}
+			assert.True(t, a.acquire(ctx))
+			assert.NoError(t, a.respond(ctx, req, time.Now(), send))
+
+			var messages []any
+			scanner := bufio.NewScanner(&buf)
+			for scanner.Scan() {
+				var record map[string]any
+				assert.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+				assert.Equal(t, id, record["correlation_id"], record["msg"])
+				messages = append(messages, record["msg"])
+			}
+			assert.Contains(t, messages, decisionMessage)
+
+			decisions, err := db.Decisions(time.Time{})
+			assert.NoError(t, err)
+			assert.Len(t, decisions, i+1)
+			assert.Equal(t, id, decisions[i].CorrelationID)
+		})
+	}
+}
+
+func TestCorrelationIDMissing(t *testing.T) {
+	assert.Empty(t, correlationID(context.Background()))
+
+	var buf bytes.Buffer
+	logger := slog.New(correlationHandler{slog.NewJSONHandler(&buf, nil)})
+	logger.InfoContext(context.Background(), "Message")
+	assert.NotContains(t, buf.String(), "correlation_id")
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/hook"
+	"github.com/aftermath2/acceptlnd/metrics"
+	"github.com/aftermath2/acceptlnd/reachability"
+	"github.com/aftermath2/acceptlnd/registry"
+	"github.com/aftermath2/acceptlnd/reputation"
+	"github.com/aftermath2/acceptlnd/syslog"
+	"github.com/aftermath2/acceptlnd/webhook"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestConfigDefaults makes sure the defaults documented in the configuration struct tags are the
+// ones applied when the fields aren't set.
+func TestConfigDefaults(t *testing.T) {
+	cases := []struct {
+		config   any
+		field    string
+		expected any
+	}{
+		{config: config.Config{}, field: "ReputationHalfLife", expected: reputation.DefaultHalfLife},
+		{config: config.Webhook{}, field: "Timeout", expected: webhook.DefaultTimeout},
+		{config: config.RegistryUpdate{}, field: "Interval", expected: registry.DefaultInterval},
+		{config: config.AcceptHook{}, field: "Timeout", expected: hook.DefaultTimeout},
+		{config: config.BlocklistExport{}, field: "Interval", expected: defaultBlocklistInterval},
+		{config: config.Syslog{}, field: "Facility", expected: syslog.DefaultFacility},
+		{config: config.Syslog{}, field: "Tag", expected: syslog.DefaultTag},
+		{config: config.InfluxDB{}, field: "Interval", expected: metrics.DefaultInfluxInterval},
+		{config: config.Precompute{}, field: "Peers", expected: defaultPrecomputePeers},
+		{config: config.Precompute{}, field: "Interval", expected: defaultPrecomputeInterval},
+		{config: config.ResponseSLO{}, field: "Breaches", expected: defaultSLOBreaches},
+		{config: config.Reachability{}, field: "Timeout", expected: reachability.DefaultTimeout},
+		{config: config.Reachability{}, field: "CacheDuration", expected: reachability.DefaultCacheDuration},
+		{config: config.WatchOnly{}, field: "Interval", expected: defaultWatchInterval},
+		{config: config.Middleware{}, field: "Name", expected: defaultMiddlewareName},
+	}
+
+	for _, tc := range cases {
+		typ := reflect.TypeOf(tc.config)
+		t.Run(typ.Name()+"."+tc.field, func(t *testing.T) {
+			field, ok := typ.FieldByName(tc.field)
+			assert.True(t, ok)
+			assert.Equal(t, fmt.Sprint(tc.expected), field.Tag.Get("default"))
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"math/rand"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/graph"
+	"github.com/aftermath2/acceptlnd/lightning/fake"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/pkg/errors"
+)
+
+// runDemo runs acceptLND against a simulated lightning node that receives synthetic channel
+// requests, so the policies can be tried out without a real node.
+func runDemo(args []string) error {
+	fs := flag.NewFlagSet("demo", flag.ExitOnError)
+	configPath := fs.String("config", "acceptlnd.yml", "Path to the configuration file")
+	peersPath := fs.String("peers", "", "Path to a graph snapshot, in JSON format (lncli describegraph) or exported by acceptlnd graph, a random one is generated if omitted")
+	publicKey := fs.String("node", "", "Public key of our node in the graph, the first node is used if omitted")
+	interval := fs.Duration("interval", time.Second, "Time between requests")
+	requests := fs.Int("requests", 20, "Number of requests to send")
+	seed := fs.Int64("seed", 1, "Seed used to generate the graph and the requests")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *interval <= 0 {
+		return errors.New("the interval must be greater than zero")
+	}
+
+	slog.SetDefault(slog.New(newPrettyHandler(os.Stdout, slog.LevelInfo)))
+
+	config, err := config.LoadPolicies(*configPath)
+	if err != nil {
+		return err
+	}
+
+	rnd := rand.New(rand.NewSource(*seed))
+
+	var snapshot *graph.Snapshot
+	if *peersPath != "" {
+		snapshot, err = graph.Load(*peersPath)
+		if err != nil {
+			return err
+		}
+	} else {
+		snapshot = graph.New(fake.Graph(rnd, 200, 1000))
+	}
+
+	if *publicKey == "" {
+		publicKeys := snapshot.PublicKeys()
+		if len(publicKeys) == 0 {
+			return errors.New("the graph snapshot has no nodes")
+		}
+		*publicKey = publicKeys[0]
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client := fake.New(snapshot, *publicKey)
+	acceptor := newAcceptor(client, nil, config)
+	if err := acceptor.updateNeighborhood(ctx); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- acceptor.handleChannelRequests(ctx)
+	}()
+
+	sent, accepted := 0, 0
+	err = client.Drive(ctx, rnd, *requests, *interval, func(_ *lnrpc.ChannelAcceptRequest, resp *lnrpc.ChannelAcceptResponse) {
+		sent++
+		if resp.Accept {
+			accepted++
+		}
+	})
+	cancel()
+	if err := <-errCh; err != nil {
+		return err
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	slog.Info("Demo finished", slog.Int("accepted", accepted), slog.Int("rejected", sent-accepted))
+	return nil
+}
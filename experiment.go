@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/metrics"
+	"github.com/aftermath2/acceptlnd/policy"
+	"github.com/aftermath2/acceptlnd/store"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// evaluation is the outcome of evaluating a request against a set of policies.
+type evaluation struct {
+	resp     *lnrpc.ChannelAcceptResponse
+	decision decision
+	err      error
+}
+
+// runExperiment evaluates the request against the experimental policies and returns the outcome
+// to enforce: the regular one in shadow mode and the experimental one in live mode. The outcome
+// not enforced is attached to the decision so both can be compared later.
+func (a *acceptor) runExperiment(
+	ctx context.Context,
+	experiment *config.Experiment,
+	req *lnrpc.ChannelAcceptRequest,
+	node *lnrpc.GetInfoResponse,
+	peer *lnrpc.NodeInfo,
+	facts *policy.Facts,
+	control evaluation,
+) evaluation {
+	// The facts are copied as the policies reset and append the warnings
+	variantFacts := *facts
+	variant := evaluation{resp: &lnrpc.ChannelAcceptResponse{Accept: false, PendingChanId: req.PendingChanId}}
+	variant.decision, variant.err = evaluatePolicies(experiment.Policies, req, variant.resp, node, peer,
+		&variantFacts)
+
+	metrics.CountExperimentDecision(experiment.Name, control.err == nil, variant.err == nil)
+	args := []any{
+		slog.String("experiment", experiment.Name),
+		slog.String("mode", experiment.Mode),
+		slog.Bool("accepted", control.err == nil),
+		slog.Bool("variant_accepted", variant.err == nil),
+		slog.String("variant_policies", strings.Join(variant.decision.policies, ",")),
+	}
+	if variant.err != nil {
+		args = append(args, slog.String("variant_error", variant.err.Error()))
+	}
+	slog.InfoContext(ctx, "Experiment evaluated", args...)
+
+	enforced, recorded := control, variant
+	if experiment.Mode == config.ExperimentLive {
+		enforced, recorded = variant, control
+	}
+
+	enforced.decision.experiment = &store.Experiment{
+		Name:     experiment.Name,
+		Mode:     experiment.Mode,
+		Accepted: recorded.err == nil,
+		Policies: recorded.decision.policies,
+	}
+	if recorded.err != nil {
+		enforced.decision.experiment.Error = recorded.err.Error()
+	}
+	return enforced
+}
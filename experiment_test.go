@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/policy"
+	"github.com/aftermath2/acceptlnd/store"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunExperiment(t *testing.T) {
+	tru := true
+	accept := &policy.Policy{Name: "accept"}
+	reject := &policy.Policy{Name: "reject", RejectAll: &tru}
+
+	accepted := evaluation{
+		resp:     &lnrpc.ChannelAcceptResponse{Accept: true},
+		decision: decision{policies: []string{"control"}},
+	}
+	rejected := evaluation{
+		resp:     &lnrpc.ChannelAcceptResponse{},
+		decision: decision{policies: []string{"control"}},
+		err:      errors.New("Rejected by control"),
+	}
+
+	cases := []struct {
+		desc             string
+		mode             string
+		policies         []*policy.Policy
+		control          evaluation
+		expectedAccepted bool
+		expectedPolicies []string
+		expected         store.Experiment
+	}{
+		{
+			desc:             "Shadow",
+			mode:             config.ExperimentShadow,
+			policies:         []*policy.Policy{reject},
+			control:          accepted,
+			expectedAccepted: true,
+			expectedPolicies: []string{"control"},
+			expected: store.Experiment{
+				Mode:     config.ExperimentShadow,
+				Error:    "No new channels are accepted",
+				Policies: []string{"reject"},
+			},
+		},
+		{
+			desc:             "Live",
+			mode:             config.ExperimentLive,
+			policies:         []*policy.Policy{reject},
+			control:          accepted,
+			expectedPolicies: []string{"reject"},
+			expected: store.Experiment{
+				Mode:     config.ExperimentLive,
+				Accepted: true,
+				Policies: []string{"control"},
+			},
+		},
+		{
+			desc:             "Live accepting",
+			mode:             config.ExperimentLive,
+			policies:         []*policy.Policy{accept},
+			control:          rejected,
+			expectedAccepted: true,
+			expectedPolicies: []string{"accept"},
+			expected: store.Experiment{
+				Mode:     config.ExperimentLive,
+				Error:    "Rejected by control",
+				Policies: []string{"control"},
+			},
+		},
+		{
+			desc:             "Shadow agreeing",
+			mode:             config.ExperimentShadow,
+			policies:         []*policy.Policy{accept},
+			control:          accepted,
+			expectedAccepted: true,
+			expectedPolicies: []string{"control"},
+			expected: store.Experiment{
+				Mode:     config.ExperimentShadow,
+				Accepted: true,
+				Policies: []string{"accept"},
+			},
+		},
+	}
+
+	req := &lnrpc.ChannelAcceptRequest{PendingChanId: []byte{1}, FundingAmt: 1_000_000}
+	node := &lnrpc.GetInfoResponse{IdentityPubkey: "us"}
+	peer := &lnrpc.NodeInfo{Node: &lnrpc.LightningNode{PubKey: "peer"}}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			experiment := &config.Experiment{Name: "test", Mode: tc.mode, Policies: tc.policies}
+			facts := &policy.Facts{Warnings: []string{"kept"}}
+
+			a := &acceptor{}
+			enforced := a.runExperiment(context.Background(), experiment, req, node, peer, facts, tc.control)
+			assert.Equal(t, tc.expectedAccepted, enforced.err == nil)
+			assert.Equal(t, tc.expectedPolicies, enforced.decision.policies)
+
+			tc.expected.Name = "test"
+			assert.Equal(t, &tc.expected, enforced.decision.experiment)
+
+			// The variant is evaluated on a copy of the facts
+			assert.Equal(t, []string{"kept"}, facts.Warnings)
+		})
+	}
+}
@@ -0,0 +1,240 @@
+// Package flood detects nodes sending an abnormal volume of channel requests and blocks them
+// temporarily.
+package flood
+
+import (
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/store"
+)
+
+// Kind of pattern detected.
+const (
+	KindNode   = "node"
+	KindAmount = "amount"
+)
+
+// Detection describes a newly blocked node or funding amount.
+type Detection struct {
+	Kind     string
+	Key      string
+	Requests int
+	Until    time.Time
+}
+
+// Detector counts the requests received in a sliding window, per node and per funding amount.
+// Nodes or amounts exceeding the limits are blocked until the block duration elapses.
+type Detector struct {
+	config config.Flood
+	now    func() time.Time
+	// shared holds the counters and blocks of all the instances in cluster mode.
+	shared store.Storage
+
+	mu        sync.Mutex
+	requests  map[string][]time.Time
+	blocked   map[string]time.Time
+	lastSweep time.Time
+}
+
+// New returns a flood detector.
+func New(config config.Flood) *Detector {
+	return &Detector{
+		config:   config,
+		now:      time.Now,
+		requests: make(map[string][]time.Time),
+		blocked:  make(map[string]time.Time),
+	}
+}
+
+// NewShared returns a flood detector whose counters and blocks are kept in the storage, so the
+// limits apply to the requests received by all the instances using it together.
+func NewShared(config config.Flood, db store.Storage) *Detector {
+	d := New(config)
+	d.shared = db
+	return d
+}
+
+// Check records a request and reports whether it must be rejected. Detections are returned only
+// the first time a node or amount is blocked, so they can be alerted once.
+//
+// If the shared storage fails, the request is counted locally so the instance remains protected.
+func (d *Detector) Check(publicKey string, amount uint64) (bool, []Detection) {
+	now := d.now()
+	if d.shared != nil {
+		blocked, detections, err := d.checkShared(now, publicKey, amount)
+		if err == nil {
+			return blocked, detections
+		}
+		slog.Error("Checking shared flood counters, counting locally", slog.Any("error", err))
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sweep(now)
+
+	nodeKey := KindNode + ":" + publicKey
+	amountKey := KindAmount + ":" + strconv.FormatUint(amount, 10)
+
+	var detections []Detection
+	if detection, ok := d.record(now, nodeKey, d.config.MaxRequests); ok {
+		detection.Kind, detection.Key = KindNode, publicKey
+		detections = append(detections, detection)
+	}
+	if detection, ok := d.record(now, amountKey, d.config.MaxAmountRequests); ok {
+		detection.Kind, detection.Key = KindAmount, strconv.FormatUint(amount, 10)
+		detections = append(detections, detection)
+	}
+
+	return d.isBlocked(now, nodeKey) || d.isBlocked(now, amountKey), detections
+}
+
+// checkShared is like Check, using the shared storage.
+func (d *Detector) checkShared(now time.Time, publicKey string, amount uint64) (bool, []Detection, error) {
+	d.mu.Lock()
+	sweep := now.Sub(d.lastSweep) >= d.config.Window
+	if sweep {
+		d.lastSweep = now
+	}
+	d.mu.Unlock()
+	if sweep {
+		for _, kind := range []string{KindNode, KindAmount} {
+			if err := d.shared.PruneRequests(kind+":", now.Add(-d.config.Window)); err != nil {
+				return false, nil, err
+			}
+		}
+	}
+
+	counters := []struct {
+		kind  string
+		key   string
+		limit int
+	}{
+		{kind: KindNode, key: publicKey, limit: d.config.MaxRequests},
+		{kind: KindAmount, key: strconv.FormatUint(amount, 10), limit: d.config.MaxAmountRequests},
+	}
+
+	var detections []Detection
+	for _, counter := range counters {
+		if counter.limit <= 0 {
+			continue
+		}
+
+		key := counter.kind + ":" + counter.key
+		requests, err := d.shared.CountRequest(key, now, d.config.Window)
+		if err != nil {
+			return false, nil, err
+		}
+		if requests <= counter.limit {
+			continue
+		}
+
+		// Only the instance that blocks the key reports the detection
+		until := now.Add(d.config.BlockDuration)
+		blocked, err := d.shared.Block(key, now, until)
+		if err != nil {
+			return false, nil, err
+		}
+		if blocked {
+			detections = append(detections, Detection{
+				Kind:     counter.kind,
+				Key:      counter.key,
+				Requests: requests,
+				Until:    until,
+			})
+		}
+	}
+
+	blocks, err := d.shared.Blocks(now)
+	if err != nil {
+		return false, nil, err
+	}
+	_, nodeBlocked := blocks[KindNode+":"+counters[0].key]
+	_, amountBlocked := blocks[KindAmount+":"+counters[1].key]
+	return nodeBlocked || amountBlocked, detections, nil
+}
+
+// Blocked returns the nodes and amounts blocked, prefixed by their kind ("node:<public key>",
+// "amount:<sats>"), and the time their block expires.
+func (d *Detector) Blocked() map[string]time.Time {
+	now := d.now()
+	if d.shared != nil {
+		blocked, err := d.shared.Blocks(now)
+		if err == nil {
+			return blocked
+		}
+		slog.Error("Reading shared flood blocks", slog.Any("error", err))
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	blocked := make(map[string]time.Time, len(d.blocked))
+	for key, until := range d.blocked {
+		if now.Before(until) {
+			blocked[key] = until
+		}
+	}
+	return blocked
+}
+
+// record adds a request to the key's window and blocks it if the limit is exceeded. A zero limit
+// disables the detection.
+func (d *Detector) record(now time.Time, key string, limit int) (Detection, bool) {
+	if limit <= 0 {
+		return Detection{}, false
+	}
+
+	requests := append(d.inWindow(now, key), now)
+	d.requests[key] = requests
+
+	if len(requests) <= limit || d.isBlocked(now, key) {
+		return Detection{}, false
+	}
+
+	until := now.Add(d.config.BlockDuration)
+	d.blocked[key] = until
+	return Detection{Requests: len(requests), Until: until}, true
+}
+
+// inWindow returns the key's requests received within the window.
+func (d *Detector) inWindow(now time.Time, key string) []time.Time {
+	requests := d.requests[key]
+	start := now.Add(-d.config.Window)
+	i := 0
+	for i < len(requests) && !requests[i].After(start) {
+		i++
+	}
+	return requests[i:]
+}
+
+func (d *Detector) isBlocked(now time.Time, key string) bool {
+	until, ok := d.blocked[key]
+	return ok && now.Before(until)
+}
+
+// sweep removes the expired entries once per window, so the memory used doesn't grow with every
+// node that ever sent a request.
+func (d *Detector) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < d.config.Window {
+		return
+	}
+	d.lastSweep = now
+
+	for key := range d.requests {
+		if requests := d.inWindow(now, key); len(requests) > 0 {
+			d.requests[key] = requests
+		} else {
+			delete(d.requests, key)
+		}
+	}
+	for key, until := range d.blocked {
+		if !now.Before(until) {
+			delete(d.blocked, key)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package flood
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetector(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	detector := New(config.Flood{
+		MaxRequests:       2,
+		MaxAmountRequests: 3,
+		Window:            time.Minute,
+		BlockDuration:     time.Hour,
+	})
+	detector.now = func() time.Time { return now }
+
+	check := func(publicKey string, amount uint64) (bool, []Detection) {
+		now = now.Add(time.Second)
+		return detector.Check(publicKey, amount)
+	}
+
+	blocked, detections := check("a", 1)
+	assert.False(t, blocked)
+	assert.Empty(t, detections)
+	blocked, _ = check("a", 2)
+	assert.False(t, blocked)
+
+	blocked, detections = check("a", 3)
+	assert.True(t, blocked)
+	assert.Equal(t, []Detection{{Kind: KindNode, Key: "a", Requests: 3, Until: now.Add(time.Hour)}}, detections)
+
+	// Detections are reported only once
+	blocked, detections = check("a", 4)
+	assert.True(t, blocked)
+	assert.Empty(t, detections)
+
+	// Same amount from different nodes
+	for _, publicKey := range []string{"b", "c", "d"} {
+		blocked, _ = check(publicKey, 100)
+		assert.False(t, blocked)
+	}
+	blocked, detections = check("e", 100)
+	assert.True(t, blocked)
+	assert.Equal(t, []Detection{{Kind: KindAmount, Key: "100", Requests: 4, Until: now.Add(time.Hour)}}, detections)
+
+	blocked, _ = check("e", 200)
+	assert.False(t, blocked)
+
+	assert.Len(t, detector.Blocked(), 2)
+	assert.Contains(t, detector.Blocked(), "node:a")
+	assert.Contains(t, detector.Blocked(), "amount:100")
+
+	// Blocks expire
+	now = now.Add(2 * time.Hour)
+	blocked, _ = check("a", 100)
+	assert.False(t, blocked)
+	assert.Empty(t, detector.Blocked())
+	assert.Len(t, detector.requests, 2)
+}
+
+func TestDetectorWindow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	detector := New(config.Flood{MaxRequests: 1, Window: time.Minute, BlockDuration: time.Hour})
+	detector.now = func() time.Time { return now }
+
+	for range 5 {
+		blocked, _ := detector.Check("a", 1)
+		assert.False(t, blocked)
+		now = now.Add(time.Minute)
+	}
+}
+
+func TestSharedDetector(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	db := store.NewMemory()
+	config := config.Flood{MaxRequests: 2, Window: time.Minute, BlockDuration: time.Hour}
+
+	// Two instances sharing the counters
+	var detectors []*Detector
+	for range 2 {
+		detector := NewShared(config, db)
+		detector.now = func() time.Time { return now }
+		detectors = append(detectors, detector)
+	}
+
+	for i := range 2 {
+		now = now.Add(time.Second)
+		blocked, detections := detectors[i].Check("a", uint64(i))
+		assert.False(t, blocked)
+		assert.Empty(t, detections)
+	}
+
+	// The limit is exceeded across the instances
+	now = now.Add(time.Second)
+	blocked, detections := detectors[0].Check("a", 3)
+	assert.True(t, blocked)
+	assert.Equal(t, []Detection{{Kind: KindNode, Key: "a", Requests: 3, Until: now.Add(time.Hour)}}, detections)
+
+	// The other instance sees the block, without reporting it again
+	now = now.Add(time.Second)
+	blocked, detections = detectors[1].Check("a", 4)
+	assert.True(t, blocked)
+	assert.Empty(t, detections)
+	assert.Contains(t, detectors[1].Blocked(), "node:a")
+
+	blocked, _ = detectors[1].Check("b", 5)
+	assert.False(t, blocked)
+
+	// Blocks expire
+	now = now.Add(2 * time.Hour)
+	blocked, _ = detectors[1].Check("a", 6)
+	assert.False(t, blocked)
+	assert.Empty(t, detectors[0].Blocked())
+}
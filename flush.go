@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/proxy"
+	"github.com/aftermath2/acceptlnd/store"
+	"github.com/aftermath2/acceptlnd/webhook"
+
+	"github.com/pkg/errors"
+)
+
+// runFlushQueue attempts to deliver every event in the webhook queue right away, or discards them.
+func runFlushQueue(args []string) error {
+	fs := flag.NewFlagSet("flush-queue", flag.ExitOnError)
+	configPath := fs.String("config", "acceptlnd.yml", "Path to the configuration file")
+	discard := fs.Bool("discard", false, "Delete the queued events without delivering them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	config, err := config.LoadPolicies(*configPath)
+	if err != nil {
+		return err
+	}
+	if (config.Webhook == nil && config.Notify == nil) || config.DatabasePath == "" ||
+		config.DatabaseBackend == store.BackendMemory {
+		return errors.New("the configuration has no webhook, notify or database_path")
+	}
+
+	db, err := store.Open(config.DatabaseBackend, config.DatabasePath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	dispatcher, err := newWebhook(config, db)
+	if err != nil {
+		return err
+	}
+
+	if *discard {
+		discarded, err := dispatcher.Discard()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Discarded %d events\n", discarded)
+		return nil
+	}
+
+	delivered, queued, err := dispatcher.Flush(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Delivered %d events, %d still queued\n", delivered, queued)
+	return nil
+}
+
+// newWebhook returns a dispatcher sending the events to the webhook and the notification
+// destinations configured. The requests go through the proxy, if it's set.
+func newWebhook(config config.Config, db store.Storage) (*webhook.Dispatcher, error) {
+	dispatcher := webhook.New(db)
+	if config.Webhook != nil {
+		client, err := webhookClient(*config.Webhook, config.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		dispatcher.AddWebhook(*config.Webhook, client)
+	}
+	if config.Notify != nil {
+		for _, destination := range config.Notify.Destinations {
+			client, err := webhookClient(destination.Webhook, config.Proxy)
+			if err != nil {
+				return nil, err
+			}
+			dispatcher.AddDestination(destination, client)
+		}
+		for _, rule := range config.Notify.Rules {
+			dispatcher.AddRule(rule)
+		}
+	}
+	return dispatcher, nil
+}
+
+func webhookClient(config config.Webhook, proxyAddress string) (*http.Client, error) {
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = webhook.DefaultTimeout
+	}
+	return proxy.HTTPClient(proxyAddress, timeout)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/metrics"
+	"github.com/aftermath2/acceptlnd/store"
+	"github.com/aftermath2/acceptlnd/webhook"
+
+	"github.com/lightningnetwork/lnd/aliasmgr"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// unmanagedAlert identifies the alerts about channels opened without a decision.
+const unmanagedAlert = "unmanaged_channel"
+
+// recordUnmanagedChannels detects the channels peers opened while acceptLND was not connected to
+// LND, which accepted them with its defaults, by comparing the open channels with the decisions
+// recorded. Each of them is recorded once, as an unmanaged decision, and alerted about.
+//
+// The decisions are not linked to channel points, so a channel is considered managed if it was
+// tagged or the peer had a channel of the same capacity accepted, each acceptance accounting for a
+// single channel. Channels opened before the oldest decision, when acceptLND wasn't running yet,
+// and the ones without a confirmation height yet are ignored.
+func (a *acceptor) recordUnmanagedChannels(ctx context.Context, channels []*lnrpc.Channel) error {
+	now := time.Now()
+	decisions, err := a.db.Decisions(now.Add(-decisionsRetention))
+	if err != nil {
+		return err
+	}
+
+	type request struct {
+		publicKey string
+		capacity  uint64
+	}
+	var since time.Time
+	recorded := make(map[string]struct{})
+	accepted := make(map[request]int)
+	for _, decision := range decisions {
+		if decision.Backfilled || decision.Unmanaged {
+			recorded[decision.ID] = struct{}{}
+			continue
+		}
+		if since.IsZero() || decision.At.Before(since) {
+			since = decision.At
+		}
+		if decision.Accepted {
+			accepted[request{decision.PublicKey, decision.Capacity}]++
+		}
+	}
+	if since.IsZero() {
+		return nil
+	}
+
+	tags, err := a.db.Tags()
+	if err != nil {
+		return err
+	}
+	node, err := a.getNodeInfo(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Tagged channels consume their acceptances first, so they aren't matched by the other
+	// channels of the peer
+	var untagged []*lnrpc.Channel
+	for _, channel := range channels {
+		if channel.Initiator {
+			continue
+		}
+		if _, ok := tags[channel.ChannelPoint]; !ok {
+			untagged = append(untagged, channel)
+			continue
+		}
+		if req := (request{channel.RemotePubkey, uint64(channel.Capacity)}); accepted[req] > 0 {
+			accepted[req]--
+		}
+	}
+
+	for _, channel := range untagged {
+		if _, ok := recorded[channel.ChannelPoint]; ok {
+			continue
+		}
+		height, ok := channelHeight(channel)
+		if !ok {
+			continue
+		}
+		if req := (request{channel.RemotePubkey, uint64(channel.Capacity)}); accepted[req] > 0 {
+			accepted[req]--
+			continue
+		}
+		openedAt := heightTime(node.BlockHeight, height, now)
+		if openedAt.Before(since) {
+			continue
+		}
+
+		decision := store.Decision{
+			ID:        channel.ChannelPoint,
+			PublicKey: channel.RemotePubkey,
+			Capacity:  uint64(channel.Capacity),
+			Accepted:  true,
+			Unmanaged: true,
+			At:        openedAt,
+		}
+		if err := a.db.AddDecision(decision); err != nil {
+			return err
+		}
+		a.alertUnmanaged(ctx, decision)
+	}
+
+	return nil
+}
+
+// channelHeight returns the height of the block that confirmed the channel, false if it's unknown
+// because the channel ID is an alias, like the ones of the unconfirmed zero-conf channels.
+func channelHeight(channel *lnrpc.Channel) (uint32, bool) {
+	scid := channel.ChanId
+	if channel.ZeroConf {
+		scid = channel.ZeroConfConfirmedScid
+	}
+	if scid == 0 || aliasmgr.IsAlias(lnwire.NewShortChanIDFromInt(scid)) {
+		return 0, false
+	}
+	return uint32(scid >> 40), true
+}
+
+// alertUnmanaged reports a channel opened without a decision.
+func (a *acceptor) alertUnmanaged(ctx context.Context, decision store.Decision) {
+	metrics.CountUnmanagedChannel()
+	slog.WarnContext(ctx, "Channel opened while acceptLND was not connected to LND",
+		slog.String("channel_point", decision.ID),
+		slog.String("public_key", decision.PublicKey),
+		slog.Uint64("capacity", decision.Capacity),
+	)
+
+	if a.webhook == nil {
+		return
+	}
+	message := fmt.Sprintf("Channel %s of %d sats opened by %s around %s without being evaluated",
+		decision.ID, decision.Capacity, decision.PublicKey, decision.At.UTC().Format(time.RFC3339))
+	alert := webhook.Alert{Alert: unmanagedAlert, Message: message, At: time.Now()}
+	if err := a.webhook.SendAlert(alert); err != nil {
+		slog.ErrorContext(ctx, "Queuing alert for the webhook", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/graph"
+	"github.com/aftermath2/acceptlnd/lightning/fake"
+	"github.com/aftermath2/acceptlnd/store"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordUnmanagedChannels(t *testing.T) {
+	const (
+		tip  = 800_000
+		peer = "peer"
+		day  = 144
+	)
+	now := time.Now()
+	daysAgo := func(days int) time.Time {
+		return now.Add(-time.Duration(days) * 24 * time.Hour)
+	}
+	channel := func(channelPoint string, capacity int64, height uint32) *lnrpc.Channel {
+		return &lnrpc.Channel{
+			RemotePubkey: peer,
+			ChannelPoint: channelPoint,
+			Capacity:     capacity,
+			ChanId:       uint64(height) << 40,
+		}
+	}
+	accepted := func(capacity uint64, at time.Time) store.Decision {
+		return store.Decision{ID: "id", PublicKey: peer, Capacity: capacity, Accepted: true, At: at}
+	}
+
+	cases := []struct {
+		desc      string
+		decisions []store.Decision
+		tagged    []string
+		channels  []*lnrpc.Channel
+		expected  []string
+	}{
+		{
+			desc:     "No decisions",
+			channels: []*lnrpc.Channel{channel("a:0", 1_000_000, tip-day)},
+		},
+		{
+			desc:      "Managed",
+			decisions: []store.Decision{accepted(1_000_000, daysAgo(2))},
+			channels:  []*lnrpc.Channel{channel("a:0", 1_000_000, tip-day)},
+		},
+		{
+			desc:      "Unmanaged",
+			decisions: []store.Decision{accepted(2_000_000, daysAgo(2))},
+			channels:  []*lnrpc.Channel{channel("a:0", 1_000_000, tip-day)},
+			expected:  []string{"a:0"},
+		},
+		{
+			desc:      "Tagged",
+			decisions: []store.Decision{accepted(2_000_000, daysAgo(2))},
+			tagged:    []string{"a:0"},
+			channels:  []*lnrpc.Channel{channel("a:0", 1_000_000, tip-day)},
+		},
+		{
+			desc:      "Before the oldest decision",
+			decisions: []store.Decision{accepted(2_000_000, daysAgo(2))},
+			channels:  []*lnrpc.Channel{channel("a:0", 1_000_000, tip-3*day)},
+		},
+		{
+			desc: "Oldest decision",
+			decisions: []store.Decision{
+				{ID: "old", PublicKey: "other", Capacity: 1_000_000, At: daysAgo(5)},
+				accepted(2_000_000, daysAgo(2)),
+			},
+			channels: []*lnrpc.Channel{channel("a:0", 1_000_000, tip-3*day)},
+			expected: []string{"a:0"},
+		},
+		{
+			desc:      "Acceptance matched once",
+			decisions: []store.Decision{accepted(1_000_000, daysAgo(2))},
+			channels: []*lnrpc.Channel{
+				channel("a:0", 1_000_000, tip-day),
+				channel("b:0", 1_000_000, tip-day),
+			},
+			expected: []string{"b:0"},
+		},
+		{
+			desc:      "Acceptance of a tagged channel",
+			decisions: []store.Decision{accepted(1_000_000, daysAgo(2))},
+			tagged:    []string{"b:0"},
+			channels: []*lnrpc.Channel{
+				channel("a:0", 1_000_000, tip-day),
+				channel("b:0", 1_000_000, tip-day),
+			},
+			expected: []string{"a:0"},
+		},
+		{
+			desc:      "Initiated by us",
+			decisions: []store.Decision{accepted(2_000_000, daysAgo(2))},
+			channels: []*lnrpc.Channel{
+				{RemotePubkey: peer, ChannelPoint: "a:0", Capacity: 1_000_000, ChanId: (tip - day) << 40, Initiator: true},
+			},
+		},
+		{
+			desc:      "Unconfirmed zero conf",
+			decisions: []store.Decision{accepted(2_000_000, daysAgo(2))},
+			channels: []*lnrpc.Channel{
+				{RemotePubkey: peer, ChannelPoint: "a:0", Capacity: 1_000_000, ChanId: 16_000_000 << 40, ZeroConf: true},
+			},
+		},
+		{
+			desc:      "Confirmed zero conf",
+			decisions: []store.Decision{accepted(2_000_000, daysAgo(2))},
+			channels: []*lnrpc.Channel{
+				{
+					RemotePubkey:          peer,
+					ChannelPoint:          "a:0",
+					Capacity:              1_000_000,
+					ChanId:                16_000_000 << 40,
+					ZeroConf:              true,
+					ZeroConfConfirmedScid: (tip - day) << 40,
+				},
+			},
+			expected: []string{"a:0"},
+		},
+		{
+			desc: "Already recorded",
+			decisions: []store.Decision{
+				accepted(2_000_000, daysAgo(2)),
+				{ID: "a:0", PublicKey: peer, Capacity: 1_000_000, Accepted: true, Unmanaged: true, At: daysAgo(1)},
+			},
+			channels: []*lnrpc.Channel{channel("a:0", 1_000_000, tip-day)},
+			expected: []string{"a:0"},
+		},
+	}
+
+	// The snapshot tip is the height of its most recent channel
+	snapshot := graph.New(&lnrpc.ChannelGraph{
+		Edges: []*lnrpc.ChannelEdge{{Node1Pub: "x", Node2Pub: "y", ChannelId: tip << 40}},
+	})
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			db := store.NewMemory()
+			for _, decision := range tc.decisions {
+				assert.NoError(t, db.AddDecision(decision))
+			}
+			for _, channelPoint := range tc.tagged {
+				tag := store.Tag{
+					PendingChanID: channelPoint,
+					PublicKey:     peer,
+					Capacity:      1_000_000,
+					AcceptedAt:    daysAgo(3),
+				}
+				assert.NoError(t, db.AddPendingTag(tag))
+				_, err := db.TagChannel(peer, channelPoint, tag.Capacity, now)
+				assert.NoError(t, err)
+			}
+
+			a := &acceptor{client: fake.New(snapshot, "us"), db: db}
+			err := a.recordUnmanagedChannels(context.Background(), tc.channels)
+			assert.NoError(t, err)
+
+			decisions, err := db.Decisions(time.Time{})
+			assert.NoError(t, err)
+			var unmanaged []string
+			for _, decision := range decisions {
+				if decision.Unmanaged {
+					unmanaged = append(unmanaged, decision.ID)
+				}
+			}
+			assert.Equal(t, tc.expected, unmanaged)
+		})
+	}
+}
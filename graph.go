@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/graph"
+	"github.com/aftermath2/acceptlnd/lightning"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/pkg/errors"
+)
+
+// runGraph dispatches the graph snapshot subcommands.
+func runGraph(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: acceptlnd graph export|import [flags]")
+	}
+
+	switch args[0] {
+	case "export":
+		return runGraphExport(args[1:])
+	case "import":
+		return runGraphImport(args[1:])
+	default:
+		return errors.Errorf("unknown graph subcommand %q, expected export or import", args[0])
+	}
+}
+
+// runGraphExport takes a snapshot of LND's channel graph and saves it in the compact format.
+func runGraphExport(args []string) error {
+	fs := flag.NewFlagSet("graph export", flag.ExitOnError)
+	configPath := fs.String("config", "acceptlnd.yml", "Path to the configuration file")
+	output := fs.String("output", "graph.snapshot", "Path the snapshot is written to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	config, err := config.Load(*configPath)
+	if err != nil {
+		return err
+	}
+
+	client, err := lightning.NewClient(config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	channelGraph, err := client.DescribeGraph(context.Background(), &lnrpc.ChannelGraphRequest{})
+	if err != nil {
+		return errors.Wrap(err, "describing graph")
+	}
+
+	return saveGraph(*output, channelGraph)
+}
+
+// runGraphImport converts a graph in JSON, like the output of `lncli describegraph`, to the
+// compact format.
+func runGraphImport(args []string) error {
+	fs := flag.NewFlagSet("graph import", flag.ExitOnError)
+	input := fs.String("input", "", "Path to the graph in JSON format (lncli describegraph)")
+	output := fs.String("output", "graph.snapshot", "Path the snapshot is written to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *input == "" {
+		return errors.New("a graph must be provided with -input")
+	}
+
+	snapshot, err := graph.Load(*input)
+	if err != nil {
+		return err
+	}
+
+	return saveGraph(*output, snapshot.Graph())
+}
+
+func saveGraph(path string, channelGraph *lnrpc.ChannelGraph) error {
+	if err := graph.Save(path, channelGraph); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return errors.Wrap(err, "reading snapshot size")
+	}
+
+	fmt.Printf("Saved %d nodes and %d channels to %s (%d KiB)\n",
+		len(channelGraph.Nodes), len(channelGraph.Edges), path, info.Size()/1024)
+	return nil
+}
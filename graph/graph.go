@@ -0,0 +1,189 @@
+// Package graph loads snapshots of the lightning network graph and derives the information the
+// policies evaluate from them, without the need of a connection to a lightning node.
+package graph
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// gzipMagic are the first bytes of gzip streams, used to tell compact snapshots from JSON ones.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Snapshot is an indexed copy of the channel graph.
+type Snapshot struct {
+	graph    *lnrpc.ChannelGraph
+	nodes    map[string]*lnrpc.LightningNode
+	channels map[string][]*lnrpc.ChannelEdge
+}
+
+// Load reads a channel graph encoded in JSON, like the output of `lncli describegraph`, or in the
+// compact format written by Save.
+func Load(path string) (*Snapshot, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading graph file")
+	}
+
+	graph := &lnrpc.ChannelGraph{}
+	if bytes.HasPrefix(content, gzipMagic) {
+		if err := decodeCompact(content, graph); err != nil {
+			return nil, errors.Wrap(err, "decoding graph")
+		}
+		return New(graph), nil
+	}
+
+	opts := protojson.UnmarshalOptions{DiscardUnknown: true}
+	if err := opts.Unmarshal(content, graph); err != nil {
+		return nil, errors.Wrap(err, "decoding graph")
+	}
+
+	return New(graph), nil
+}
+
+// Save writes the channel graph to path in a compact format, protobuf encoded and compressed with
+// gzip, which is several times smaller and faster to load than JSON. The file is replaced
+// atomically, readers never see a partial snapshot.
+func Save(path string, graph *lnrpc.ChannelGraph) error {
+	content, err := proto.Marshal(graph)
+	if err != nil {
+		return errors.Wrap(err, "encoding graph")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "creating graph file")
+	}
+	defer os.Remove(tmp.Name())
+
+	w := gzip.NewWriter(tmp)
+	if _, err := w.Write(content); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "writing graph file")
+	}
+	if err := w.Close(); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "writing graph file")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "writing graph file")
+	}
+
+	return errors.Wrap(os.Rename(tmp.Name(), path), "replacing graph file")
+}
+
+func decodeCompact(content []byte, graph *lnrpc.ChannelGraph) error {
+	r, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	uncompressed, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(uncompressed, graph)
+}
+
+// New indexes the channel graph received.
+func New(graph *lnrpc.ChannelGraph) *Snapshot {
+	s := &Snapshot{
+		graph:    graph,
+		nodes:    make(map[string]*lnrpc.LightningNode, len(graph.Nodes)),
+		channels: make(map[string][]*lnrpc.ChannelEdge, len(graph.Nodes)),
+	}
+
+	for _, node := range graph.Nodes {
+		s.nodes[node.PubKey] = node
+	}
+
+	for _, edge := range graph.Edges {
+		s.channels[edge.Node1Pub] = append(s.channels[edge.Node1Pub], edge)
+		s.channels[edge.Node2Pub] = append(s.channels[edge.Node2Pub], edge)
+	}
+
+	return s
+}
+
+// Graph returns the underlying channel graph.
+func (s *Snapshot) Graph() *lnrpc.ChannelGraph {
+	return s.graph
+}
+
+// PublicKeys returns the public keys of all the nodes in the graph.
+func (s *Snapshot) PublicKeys() []string {
+	publicKeys := make([]string, 0, len(s.graph.Nodes))
+	for _, node := range s.graph.Nodes {
+		publicKeys = append(publicKeys, node.PubKey)
+	}
+	return publicKeys
+}
+
+// NodeInfo returns the node information and its channels, just like lnd's GetNodeInfo would.
+func (s *Snapshot) NodeInfo(publicKey string) (*lnrpc.NodeInfo, bool) {
+	node, ok := s.nodes[publicKey]
+	if !ok {
+		return nil, false
+	}
+
+	channels := s.channels[publicKey]
+	var totalCapacity int64
+	for _, channel := range channels {
+		totalCapacity += channel.Capacity
+	}
+
+	return &lnrpc.NodeInfo{
+		Node:          node,
+		NumChannels:   uint32(len(channels)),
+		TotalCapacity: totalCapacity,
+		Channels:      channels,
+	}, true
+}
+
+// Neighbors returns the public keys of the nodes that have a channel with the node received.
+func (s *Snapshot) Neighbors(publicKey string) []string {
+	channels := s.channels[publicKey]
+	neighbors := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		neighbor := channel.Node1Pub
+		if neighbor == publicKey {
+			neighbor = channel.Node2Pub
+		}
+		neighbors = append(neighbors, neighbor)
+	}
+	return neighbors
+}
+
+// Reach returns the set of nodes the node received is connected to, either directly or through
+// one of its peers. The node itself is included.
+func (s *Snapshot) Reach(publicKey string) map[string]struct{} {
+	reach := map[string]struct{}{publicKey: {}}
+	for _, peer := range s.Neighbors(publicKey) {
+		reach[peer] = struct{}{}
+		for _, neighbor := range s.Neighbors(peer) {
+			reach[neighbor] = struct{}{}
+		}
+	}
+	return reach
+}
+
+// BlockHeight returns the height of the most recent channel in the graph, which is the closest
+// value to the best block height a snapshot can provide.
+func (s *Snapshot) BlockHeight() uint32 {
+	var height uint32
+	for _, edge := range s.graph.Edges {
+		if h := uint32(edge.ChannelId >> 40); h > height {
+			height = h
+		}
+	}
+	return height
+}
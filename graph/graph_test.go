@@ -0,0 +1,113 @@
+package graph
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	content := `{
+	"nodes": [
+		{"pub_key": "a", "alias": "alice", "last_update": 1700000000},
+		{"pub_key": "b", "alias": "bob"}
+	],
+	"edges": [
+		{"channel_id": "623702369048395776", "node1_pub": "a", "node2_pub": "b", "capacity": "1000000"}
+	],
+	"unknown": true
+}`
+	path := filepath.Join(t.TempDir(), "graph.json")
+	err := os.WriteFile(path, []byte(content), 0o600)
+	assert.NoError(t, err)
+
+	snapshot, err := Load(path)
+	assert.NoError(t, err)
+
+	node, ok := snapshot.NodeInfo("a")
+	assert.True(t, ok)
+	assert.Equal(t, "alice", node.Node.Alias)
+	assert.Equal(t, int64(1_000_000), node.TotalCapacity)
+	assert.Equal(t, uint32(1), node.NumChannels)
+	assert.Equal(t, uint32(567_254), snapshot.BlockHeight())
+
+	_, err = Load(filepath.Join(t.TempDir(), "none.json"))
+	assert.Error(t, err)
+}
+
+func TestSave(t *testing.T) {
+	graph := &lnrpc.ChannelGraph{
+		Nodes: []*lnrpc.LightningNode{
+			{PubKey: "a", Alias: "alice", Addresses: []*lnrpc.NodeAddress{{Network: "tcp", Addr: "1.1.1.1:9735"}}},
+			{PubKey: "b", Alias: "bob"},
+		},
+		Edges: []*lnrpc.ChannelEdge{
+			{ChannelId: 623702369048395776, Node1Pub: "a", Node2Pub: "b", Capacity: 1_000_000},
+		},
+	}
+	path := filepath.Join(t.TempDir(), "graph.snapshot")
+	assert.NoError(t, Save(path, graph))
+
+	snapshot, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, snapshot.PublicKeys())
+	assert.Equal(t, uint32(567_254), snapshot.BlockHeight())
+
+	node, ok := snapshot.NodeInfo("a")
+	assert.True(t, ok)
+	assert.Equal(t, "alice", node.Node.Alias)
+	assert.Equal(t, "1.1.1.1:9735", node.Node.Addresses[0].Addr)
+	assert.Equal(t, int64(1_000_000), node.TotalCapacity)
+
+	// Only the snapshot is left in the directory
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	assert.Error(t, Save(filepath.Join(t.TempDir(), "missing", "graph.snapshot"), graph))
+}
+
+func TestNodeInfo(t *testing.T) {
+	snapshot := New(&lnrpc.ChannelGraph{
+		Nodes: []*lnrpc.LightningNode{{PubKey: "a"}, {PubKey: "b"}, {PubKey: "c"}},
+		Edges: []*lnrpc.ChannelEdge{
+			{Node1Pub: "a", Node2Pub: "b", Capacity: 100},
+			{Node1Pub: "c", Node2Pub: "a", Capacity: 200},
+		},
+	})
+
+	node, ok := snapshot.NodeInfo("a")
+	assert.True(t, ok)
+	assert.Equal(t, uint32(2), node.NumChannels)
+	assert.Equal(t, int64(300), node.TotalCapacity)
+
+	node, ok = snapshot.NodeInfo("b")
+	assert.True(t, ok)
+	assert.Equal(t, uint32(1), node.NumChannels)
+
+	_, ok = snapshot.NodeInfo("d")
+	assert.False(t, ok)
+
+	assert.Equal(t, []string{"a", "b", "c"}, snapshot.PublicKeys())
+}
+
+func TestReach(t *testing.T) {
+	snapshot := New(&lnrpc.ChannelGraph{
+		Edges: []*lnrpc.ChannelEdge{
+			{Node1Pub: "a", Node2Pub: "b"},
+			{Node1Pub: "c", Node2Pub: "b"},
+			{Node1Pub: "c", Node2Pub: "d"},
+			{Node1Pub: "e", Node2Pub: "f"},
+		},
+	})
+
+	assert.ElementsMatch(t, []string{"a", "c"}, snapshot.Neighbors("b"))
+	assert.Empty(t, snapshot.Neighbors("g"))
+
+	expected := map[string]struct{}{"a": {}, "b": {}, "c": {}}
+	assert.Equal(t, expected, snapshot.Reach("a"))
+	assert.Equal(t, map[string]struct{}{"g": {}}, snapshot.Reach("g"))
+}
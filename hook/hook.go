@@ -0,0 +1,77 @@
+// Package hook runs a command when channels are accepted, so external tools like rebalancers or
+// fee managers can prepare for them.
+package hook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/store"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultTimeout is the time the command can run when it's not configured.
+const DefaultTimeout = 30 * time.Second
+
+// maxOutput is the number of bytes of the command output included in the errors.
+const maxOutput = 512
+
+// Runner executes the command configured for the accepted channels.
+type Runner struct {
+	command     []string
+	minCapacity uint64
+	timeout     time.Duration
+}
+
+// New returns a runner of the command configured.
+func New(config config.AcceptHook) *Runner {
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Runner{
+		command:     config.Command,
+		minCapacity: config.MinCapacity,
+		timeout:     timeout,
+	}
+}
+
+// Applies returns whether the command must be run for the decision.
+func (r *Runner) Applies(decision store.Decision) bool {
+	return decision.Accepted && decision.Capacity >= r.minCapacity
+}
+
+// Run executes the command writing the decision to its standard input as JSON, and waits for it
+// to finish.
+func (r *Runner) Run(ctx context.Context, decision store.Decision) error {
+	payload, err := json.Marshal(decision)
+	if err != nil {
+		return errors.Wrap(err, "encoding decision")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, r.command[0], r.command[1:]...)
+	cmd.Stdin = bytes.NewReader(payload)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		out := strings.TrimSpace(string(output))
+		if len(out) > maxOutput {
+			out = out[:maxOutput]
+		}
+		if out != "" {
+			return errors.Wrapf(err, "running %s: %s", r.command[0], out)
+		}
+		return errors.Wrapf(err, "running %s", r.command[0])
+	}
+
+	return nil
+}
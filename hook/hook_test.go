@@ -0,0 +1,82 @@
+package hook
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplies(t *testing.T) {
+	r := New(config.AcceptHook{Command: []string{"true"}, MinCapacity: 5_000_000})
+
+	cases := []struct {
+		desc     string
+		decision store.Decision
+		expected bool
+	}{
+		{
+			desc:     "Large channel",
+			decision: store.Decision{Accepted: true, Capacity: 5_000_000},
+			expected: true,
+		},
+		{
+			desc:     "Small channel",
+			decision: store.Decision{Accepted: true, Capacity: 1_000_000},
+		},
+		{
+			desc:     "Rejected",
+			decision: store.Decision{Capacity: 10_000_000},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.expected, r.Applies(tc.decision))
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decision.json")
+	r := New(config.AcceptHook{Command: []string{"sh", "-c", `cat > "$0"`, path}})
+	decision := store.Decision{
+		ID:       "id",
+		Capacity: 5_000_000,
+		Accepted: true,
+		Response: &store.Response{MinAcceptDepth: 3, MaxHTLCCount: 30},
+		At:       time.Unix(1_700_000_000, 0).UTC(),
+	}
+
+	err := r.Run(context.Background(), decision)
+	assert.NoError(t, err)
+
+	payload, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	var actual store.Decision
+	assert.NoError(t, json.Unmarshal(payload, &actual))
+	assert.Equal(t, decision, actual)
+}
+
+func TestRunFailure(t *testing.T) {
+	r := New(config.AcceptHook{Command: []string{"sh", "-c", "echo not ready; exit 3"}})
+
+	err := r.Run(context.Background(), store.Decision{})
+	assert.ErrorContains(t, err, "running sh: not ready")
+}
+
+func TestRunTimeout(t *testing.T) {
+	r := New(config.AcceptHook{Command: []string{"sleep", "5"}, Timeout: 50 * time.Millisecond})
+
+	start := time.Now()
+	err := r.Run(context.Background(), store.Decision{})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
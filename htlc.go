@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/metrics"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/pkg/errors"
+)
+
+// interceptHTLCs fails the HTLCs forwarded by the peers in the blocklist, up to the maximum
+// amount, and resumes the rest until the context is cancelled.
+//
+// The HTLCs are failed with a temporary channel failure, the same error a lack of liquidity
+// produces, so probing nodes learn nothing from them.
+func (a *acceptor) interceptHTLCs(ctx context.Context, interceptor config.HTLCInterceptor) error {
+	stream, err := a.client.HtlcInterceptor(ctx)
+	if err != nil {
+		return errors.Wrap(err, "registering HTLC interceptor")
+	}
+	slog.Info("Intercepting HTLCs", slog.Uint64("max_amount", interceptor.MaxAmount))
+
+	// Short channel IDs, including aliases, of our channels and the public key of their peers,
+	// empty for unknown channels
+	peers := make(map[uint64]string)
+	for {
+		htlc, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "receiving HTLC")
+		}
+
+		resp := &routerrpc.ForwardHtlcInterceptResponse{
+			IncomingCircuitKey: htlc.IncomingCircuitKey,
+			Action:             routerrpc.ResolveHoldForwardAction_RESUME,
+		}
+		if publicKey, ok := a.blockedHTLC(ctx, htlc, interceptor.MaxAmount, peers); ok {
+			resp.Action = routerrpc.ResolveHoldForwardAction_FAIL
+			resp.FailureCode = lnrpc.Failure_TEMPORARY_CHANNEL_FAILURE
+			metrics.CountFailedHTLC()
+			slog.Info("HTLC of a blocked peer failed",
+				slog.String("public_key", publicKey),
+				slog.Uint64("channel_id", htlc.IncomingCircuitKey.GetChanId()),
+				slog.Uint64("amount_msat", htlc.IncomingAmountMsat),
+			)
+		}
+
+		if err := stream.Send(resp); err != nil {
+			return errors.Wrap(err, "resolving HTLC")
+		}
+	}
+}
+
+// blockedHTLC returns the public key of the peer that forwarded the HTLC and whether it must be
+// failed. The peers are looked up again when the channel is not known yet.
+func (a *acceptor) blockedHTLC(
+	ctx context.Context,
+	htlc *routerrpc.ForwardHtlcInterceptRequest,
+	maxAmount uint64,
+	peers map[uint64]string,
+) (string, bool) {
+	if maxAmount != 0 && htlc.IncomingAmountMsat > maxAmount*1000 {
+		return "", false
+	}
+
+	chanID := htlc.IncomingCircuitKey.GetChanId()
+	publicKey, ok := peers[chanID]
+	if !ok {
+		if err := a.loadChannelPeers(ctx, peers); err != nil {
+			slog.Warn("Loading the peers of the HTLCs channels", slog.Any("error", err))
+			return "", false
+		}
+		// Remember unknown channels too, so they are not looked up for every HTLC
+		publicKey = peers[chanID]
+		peers[chanID] = publicKey
+	}
+	if publicKey == "" {
+		return "", false
+	}
+
+	_, blocked := slices.BinarySearch(a.blocklist(), publicKey)
+	return publicKey, blocked
+}
+
+// loadChannelPeers adds the peers of our channels to the map, by short channel ID and alias.
+func (a *acceptor) loadChannelPeers(ctx context.Context, peers map[uint64]string) error {
+	resp, err := a.client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+	if err != nil {
+		return errors.Wrap(err, "listing channels")
+	}
+
+	for _, channel := range resp.Channels {
+		peers[channel.ChanId] = channel.RemotePubkey
+		for _, alias := range channel.AliasScids {
+			peers[alias] = channel.RemotePubkey
+		}
+	}
+	return nil
+}
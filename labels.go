@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/aftermath2/acceptlnd/store"
+
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
+	"github.com/pkg/errors"
+)
+
+// maxLabelLength is the longest label LND accepts.
+const maxLabelLength = 500
+
+// labelChannel labels the funding transaction of the channel in LND's wallet with its tag, so the
+// policies that accepted it show up in the tools reading the wallet transactions. LND only labels
+// the transactions its wallet knows, which may not be the case of the channels opened by other
+// nodes, so failures are logged without interrupting the channels monitor.
+func (a *acceptor) labelChannel(ctx context.Context, channelPoint string) {
+	tags, err := a.db.Tags()
+	if err != nil {
+		slog.ErrorContext(ctx, "Getting channel tags", slog.Any("error", err))
+		return
+	}
+	tag, ok := tags[channelPoint]
+	if !ok {
+		return
+	}
+
+	txid, err := fundingTxid(channelPoint)
+	if err != nil {
+		slog.WarnContext(ctx, "Labeling funding transaction", slog.String("channel_point", channelPoint),
+			slog.Any("error", err))
+		return
+	}
+
+	_, err = a.client.LabelTransaction(ctx, &walletrpc.LabelTransactionRequest{
+		Txid:      txid,
+		Label:     channelLabel(tag),
+		Overwrite: true,
+	})
+	if err != nil {
+		slog.DebugContext(ctx, "Funding transaction not labeled", slog.String("channel_point", channelPoint),
+			slog.Any("error", err))
+	}
+}
+
+// fundingTxid returns the transaction ID of the channel point in the byte order LND expects, the
+// reverse of its hex representation.
+func fundingTxid(channelPoint string) ([]byte, error) {
+	txid, _, ok := strings.Cut(channelPoint, ":")
+	if !ok {
+		return nil, errors.Errorf("invalid channel point %q", channelPoint)
+	}
+	b, err := hex.DecodeString(txid)
+	if err != nil || len(b) != 32 {
+		return nil, errors.Errorf("invalid transaction ID %q", txid)
+	}
+	slices.Reverse(b)
+	return b, nil
+}
+
+// channelLabel describes the tag in a transaction label, truncated to the maximum length.
+func channelLabel(tag store.Tag) string {
+	label := fmt.Sprintf("acceptlnd: policies=%s reputation=%.2f",
+		strings.Join(tag.Policies, ","), tag.Reputation)
+	if len(tag.Tags) > 0 {
+		label += " tags=" + strings.Join(tag.Tags, ",")
+	}
+	if len(label) > maxLabelLength {
+		label = label[:maxLabelLength]
+	}
+	return label
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/lightning"
+	"github.com/aftermath2/acceptlnd/store"
+
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+// labelsClient records the labels set, the rest of the calls aren't used.
+type labelsClient struct {
+	lightning.Client
+	requests []*walletrpc.LabelTransactionRequest
+}
+
+func (c *labelsClient) LabelTransaction(
+	_ context.Context,
+	in *walletrpc.LabelTransactionRequest,
+	_ ...grpc.CallOption,
+) (*walletrpc.LabelTransactionResponse, error) {
+	c.requests = append(c.requests, in)
+	return &walletrpc.LabelTransactionResponse{}, nil
+}
+
+func TestLabelChannel(t *testing.T) {
+	ctx := context.Background()
+	txid := "00000000000000000000000000000000000000000000000000000000000000ff"
+	channelPoint := txid + ":1"
+
+	db := store.NewMemory()
+	now := time.Now()
+	tag := store.Tag{
+		PendingChanID: "id",
+		PublicKey:     "peer",
+		Capacity:      1_000_000,
+		Policies:      []string{"#0", "routing"},
+		Tags:          []string{"lsp"},
+		Reputation:    2.5,
+		AcceptedAt:    now,
+	}
+	assert.NoError(t, db.AddPendingTag(tag))
+	tagged, err := db.TagChannel("peer", channelPoint, 1_000_000, now)
+	assert.NoError(t, err)
+	assert.True(t, tagged)
+
+	client := &labelsClient{}
+	a := newAcceptor(client, db, config.Config{LabelChannels: true})
+	a.labelChannel(ctx, channelPoint)
+	// Channels without a tag aren't labeled
+	a.labelChannel(ctx, "untagged:0")
+
+	assert.Len(t, client.requests, 1)
+	req := client.requests[0]
+	assert.Equal(t, byte(0xff), req.Txid[0])
+	assert.Len(t, req.Txid, 32)
+	assert.Equal(t, "acceptlnd: policies=#0,routing reputation=2.50 tags=lsp", req.Label)
+	assert.True(t, req.Overwrite)
+}
+
+func TestFundingTxid(t *testing.T) {
+	cases := []struct {
+		desc         string
+		channelPoint string
+		fail         bool
+	}{
+		{desc: "Valid", channelPoint: strings.Repeat("0a", 32) + ":0"},
+		{desc: "Without index", channelPoint: strings.Repeat("0a", 32), fail: true},
+		{desc: "Invalid hex", channelPoint: strings.Repeat("zz", 32) + ":0", fail: true},
+		{desc: "Short", channelPoint: "0a0b:0", fail: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			txid, err := fundingTxid(tc.channelPoint)
+			if tc.fail {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, txid, 32)
+		})
+	}
+}
+
+func TestChannelLabel(t *testing.T) {
+	assert.Equal(t, "acceptlnd: policies=#0 reputation=0.00", channelLabel(store.Tag{Policies: []string{"#0"}}))
+
+	long := channelLabel(store.Tag{Policies: []string{strings.Repeat("a", 2*maxLabelLength)}})
+	assert.Len(t, long, maxLabelLength)
+}
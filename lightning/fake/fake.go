@@ -0,0 +1,395 @@
+// Package fake implements a lightning client backed by a channel graph, with a driver that emits
+// synthetic channel requests. It's meant for tests and local development, no lightning node is
+// required.
+package fake
+
+import (
+	"context"
+	"encoding/hex"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/graph"
+	"github.com/aftermath2/acceptlnd/lightning"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ lightning.Client = (*Client)(nil)
+
+// Client is a lightning client that answers using a graph snapshot, as if it was the node with the
+// public key received.
+type Client struct {
+	snapshot  *graph.Snapshot
+	publicKey string
+
+	// mu serializes the requests so every response matches its request.
+	mu        sync.Mutex
+	requests  chan *lnrpc.ChannelAcceptRequest
+	responses chan *lnrpc.ChannelAcceptResponse
+}
+
+// New returns a new fake client.
+func New(snapshot *graph.Snapshot, publicKey string) *Client {
+	return &Client{
+		snapshot:  snapshot,
+		publicKey: publicKey,
+		requests:  make(chan *lnrpc.ChannelAcceptRequest),
+		responses: make(chan *lnrpc.ChannelAcceptResponse),
+	}
+}
+
+// ChannelAcceptor returns a stream that receives the requests sent with RequestChannel.
+func (c *Client) ChannelAcceptor(
+	ctx context.Context,
+	_ ...grpc.CallOption,
+) (lnrpc.Lightning_ChannelAcceptorClient, error) {
+	return &acceptorStream{ctx: ctx, client: c}, nil
+}
+
+// GetInfo returns our node information.
+func (c *Client) GetInfo(
+	context.Context,
+	*lnrpc.GetInfoRequest,
+	...grpc.CallOption,
+) (*lnrpc.GetInfoResponse, error) {
+	info := &lnrpc.GetInfoResponse{
+		IdentityPubkey: c.publicKey,
+		BlockHeight:    c.snapshot.BlockHeight(),
+		SyncedToChain:  true,
+		SyncedToGraph:  true,
+	}
+
+	if node, ok := c.snapshot.NodeInfo(c.publicKey); ok {
+		info.Alias = node.Node.Alias
+		info.NumActiveChannels = node.NumChannels
+		info.NumPeers = node.NumChannels
+	}
+
+	return info, nil
+}
+
+// GetNodeInfo returns the node information and its channels from the graph.
+func (c *Client) GetNodeInfo(
+	_ context.Context,
+	in *lnrpc.NodeInfoRequest,
+	_ ...grpc.CallOption,
+) (*lnrpc.NodeInfo, error) {
+	node, ok := c.snapshot.NodeInfo(in.PubKey)
+	if !ok {
+		return nil, status.Error(codes.NotFound, "unable to find node")
+	}
+
+	if !in.IncludeChannels {
+		node = &lnrpc.NodeInfo{
+			Node:          node.Node,
+			NumChannels:   node.NumChannels,
+			TotalCapacity: node.TotalCapacity,
+		}
+	}
+
+	return node, nil
+}
+
+// ListChannels returns our channels in the graph.
+func (c *Client) ListChannels(
+	_ context.Context,
+	in *lnrpc.ListChannelsRequest,
+	_ ...grpc.CallOption,
+) (*lnrpc.ListChannelsResponse, error) {
+	resp := &lnrpc.ListChannelsResponse{}
+
+	node, ok := c.snapshot.NodeInfo(c.publicKey)
+	if !ok {
+		return resp, nil
+	}
+
+	peerFilter := hex.EncodeToString(in.Peer)
+	for _, edge := range node.Channels {
+		remote := edge.Node1Pub
+		if remote == c.publicKey {
+			remote = edge.Node2Pub
+		}
+		if peerFilter != "" && remote != peerFilter {
+			continue
+		}
+
+		resp.Channels = append(resp.Channels, &lnrpc.Channel{
+			Active:       true,
+			RemotePubkey: remote,
+			ChannelPoint: edge.ChanPoint,
+			ChanId:       edge.ChannelId,
+			Capacity:     edge.Capacity,
+			Initiator:    edge.Node1Pub == c.publicKey,
+		})
+	}
+
+	return resp, nil
+}
+
+// GetDebugInfo returns LND's default channel acceptor timeout.
+func (c *Client) GetDebugInfo(
+	context.Context,
+	*lnrpc.GetDebugInfoRequest,
+	...grpc.CallOption,
+) (*lnrpc.GetDebugInfoResponse, error) {
+	return &lnrpc.GetDebugInfoResponse{Config: map[string]string{"acceptortimeout": "15s"}}, nil
+}
+
+// ClosedChannels returns no channels, channels in the graph are never closed.
+func (c *Client) ClosedChannels(
+	context.Context,
+	*lnrpc.ClosedChannelsRequest,
+	...grpc.CallOption,
+) (*lnrpc.ClosedChannelsResponse, error) {
+	return &lnrpc.ClosedChannelsResponse{}, nil
+}
+
+// ForwardingHistory returns no forwarding events.
+func (c *Client) ForwardingHistory(
+	context.Context,
+	*lnrpc.ForwardingHistoryRequest,
+	...grpc.CallOption,
+) (*lnrpc.ForwardingHistoryResponse, error) {
+	return &lnrpc.ForwardingHistoryResponse{}, nil
+}
+
+// ListPeers returns every node in the graph as connected from its first announced address, any of
+// them may request a channel.
+func (c *Client) ListPeers(
+	context.Context,
+	*lnrpc.ListPeersRequest,
+	...grpc.CallOption,
+) (*lnrpc.ListPeersResponse, error) {
+	resp := &lnrpc.ListPeersResponse{}
+	for _, node := range c.snapshot.Graph().Nodes {
+		if node.PubKey == c.publicKey {
+			continue
+		}
+
+		peer := &lnrpc.Peer{PubKey: node.PubKey, Inbound: true}
+		if len(node.Addresses) > 0 {
+			peer.Address = node.Addresses[0].Addr
+		}
+		resp.Peers = append(resp.Peers, peer)
+	}
+	return resp, nil
+}
+
+// PendingChannels returns no channels, the graph only has open ones.
+func (c *Client) PendingChannels(
+	context.Context,
+	*lnrpc.PendingChannelsRequest,
+	...grpc.CallOption,
+) (*lnrpc.PendingChannelsResponse, error) {
+	return &lnrpc.PendingChannelsResponse{}, nil
+}
+
+// DescribeGraph returns the whole graph.
+func (c *Client) DescribeGraph(
+	context.Context,
+	*lnrpc.ChannelGraphRequest,
+	...grpc.CallOption,
+) (*lnrpc.ChannelGraph, error) {
+	return c.snapshot.Graph(), nil
+}
+
+// SubscribeChannelGraph returns a stream without updates, the graph snapshot doesn't change.
+func (c *Client) SubscribeChannelGraph(
+	ctx context.Context,
+	_ *lnrpc.GraphTopologySubscription,
+	_ ...grpc.CallOption,
+) (lnrpc.Lightning_SubscribeChannelGraphClient, error) {
+	return &graphStream{ctx: ctx}, nil
+}
+
+// RegisterRPCMiddleware fails, the fake node has no RPC calls to intercept.
+func (c *Client) RegisterRPCMiddleware(
+	context.Context,
+	...grpc.CallOption,
+) (lnrpc.Lightning_RegisterRPCMiddlewareClient, error) {
+	return nil, errors.New("the fake node does not support RPC middlewares")
+}
+
+// HtlcInterceptor fails, the fake node doesn't forward payments.
+func (c *Client) HtlcInterceptor(
+	context.Context,
+	...grpc.CallOption,
+) (routerrpc.Router_HtlcInterceptorClient, error) {
+	return nil, errors.New("the fake node does not forward HTLCs")
+}
+
+// FeeRate is the fee rate in satoshis per kilo-weight unit estimated by the fake client.
+const FeeRate = 2500
+
+// EstimateFeeRate returns a fixed fee rate for any confirmation target.
+func (c *Client) EstimateFeeRate(
+	context.Context,
+	*walletrpc.EstimateFeeRequest,
+	...grpc.CallOption,
+) (*walletrpc.EstimateFeeResponse, error) {
+	return &walletrpc.EstimateFeeResponse{SatPerKw: FeeRate}, nil
+}
+
+// LabelTransaction fails, the fake node has no wallet.
+func (c *Client) LabelTransaction(
+	context.Context,
+	*walletrpc.LabelTransactionRequest,
+	...grpc.CallOption,
+) (*walletrpc.LabelTransactionResponse, error) {
+	return nil, errors.New("the fake node has no wallet")
+}
+
+// RequestChannel sends the request to the channel acceptor stream and waits for its response.
+func (c *Client) RequestChannel(
+	ctx context.Context,
+	req *lnrpc.ChannelAcceptRequest,
+) (*lnrpc.ChannelAcceptResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case c.requests <- req:
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case resp := <-c.responses:
+		return resp, nil
+	}
+}
+
+// Drive sends n synthetic channel requests from random nodes of the graph, one every interval,
+// and calls fn with each response.
+func (c *Client) Drive(
+	ctx context.Context,
+	rnd *rand.Rand,
+	n int,
+	interval time.Duration,
+	fn func(req *lnrpc.ChannelAcceptRequest, resp *lnrpc.ChannelAcceptResponse),
+) error {
+	var publicKeys []string
+	for _, publicKey := range c.snapshot.PublicKeys() {
+		if publicKey != c.publicKey {
+			publicKeys = append(publicKeys, publicKey)
+		}
+	}
+	if len(publicKeys) == 0 {
+		return errors.New("the graph has no other nodes")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+		}
+
+		peer, _ := c.snapshot.NodeInfo(publicKeys[rnd.Intn(len(publicKeys))])
+		req := Request(rnd, peer)
+		resp, err := c.RequestChannel(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		if fn != nil {
+			fn(req, resp)
+		}
+	}
+
+	return nil
+}
+
+// Request generates a channel request from the peer with random parameters.
+func Request(rnd *rand.Rand, peer *lnrpc.NodeInfo) *lnrpc.ChannelAcceptRequest {
+	pendingChanID := make([]byte, 32)
+	rnd.Read(pendingChanID)
+	publicKey, _ := hex.DecodeString(peer.Node.PubKey)
+
+	var channelFlags uint32
+	if rnd.Intn(10) > 0 {
+		channelFlags = uint32(lnwire.FFAnnounceChannel)
+	}
+
+	fundingAmt := uint64(20_000 + rnd.Int63n(16_777_215-20_000))
+
+	return &lnrpc.ChannelAcceptRequest{
+		NodePubkey:       publicKey,
+		PendingChanId:    pendingChanID,
+		FundingAmt:       fundingAmt,
+		PushAmt:          0,
+		DustLimit:        354,
+		MaxValueInFlight: fundingAmt * 1000,
+		ChannelReserve:   fundingAmt / 100,
+		MinHtlc:          1000,
+		CsvDelay:         144,
+		MaxAcceptedHtlcs: 483,
+		ChannelFlags:     channelFlags,
+		CommitmentType:   lnrpc.CommitmentType_ANCHORS,
+		WantsZeroConf:    rnd.Intn(20) == 0,
+	}
+}
+
+// acceptorStream is the client side of the channel acceptor stream.
+type acceptorStream struct {
+	grpc.ClientStream
+	ctx    context.Context
+	client *Client
+}
+
+func (s *acceptorStream) Recv() (*lnrpc.ChannelAcceptRequest, error) {
+	select {
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	case req := <-s.client.requests:
+		return req, nil
+	}
+}
+
+func (s *acceptorStream) Send(resp *lnrpc.ChannelAcceptResponse) error {
+	select {
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	case s.client.responses <- resp:
+		return nil
+	}
+}
+
+func (s *acceptorStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *acceptorStream) CloseSend() error {
+	return nil
+}
+
+// graphStream is a graph topology subscription that blocks until its context is cancelled.
+type graphStream struct {
+	grpc.ClientStream
+	ctx context.Context
+}
+
+func (s *graphStream) Recv() (*lnrpc.GraphTopologyUpdate, error) {
+	<-s.ctx.Done()
+	return nil, s.ctx.Err()
+}
+
+func (s *graphStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,136 @@
+package fake
+
+import (
+	"context"
+	"encoding/hex"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/graph"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGraph(t *testing.T) {
+	channelGraph := Graph(rand.New(rand.NewSource(1)), 10, 30)
+	assert.Len(t, channelGraph.Nodes, 10)
+	assert.Len(t, channelGraph.Edges, 30)
+
+	for _, edge := range channelGraph.Edges {
+		assert.NotEqual(t, edge.Node1Pub, edge.Node2Pub)
+		assert.NotNil(t, edge.Node1Policy)
+		assert.NotNil(t, edge.Node2Policy)
+	}
+	for _, node := range channelGraph.Nodes {
+		publicKey, err := hex.DecodeString(node.PubKey)
+		assert.NoError(t, err)
+		assert.Len(t, publicKey, 33)
+		assert.NotEmpty(t, node.Addresses)
+	}
+
+	snapshot := graph.New(channelGraph)
+	assert.NotEmpty(t, snapshot.Neighbors(channelGraph.Nodes[0].PubKey))
+
+	assert.Empty(t, Graph(rand.New(rand.NewSource(1)), 1, 5).Edges)
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	channelGraph := &lnrpc.ChannelGraph{
+		Nodes: []*lnrpc.LightningNode{
+			{PubKey: "a", Alias: "alice"},
+			{PubKey: "b", Addresses: []*lnrpc.NodeAddress{{Network: "tcp", Addr: "203.0.113.5:9735"}}},
+			{PubKey: "c"},
+		},
+		Edges: []*lnrpc.ChannelEdge{
+			{Node1Pub: "a", Node2Pub: "b", ChanPoint: "ab:0", Capacity: 100},
+			{Node1Pub: "c", Node2Pub: "a", ChanPoint: "ca:0", Capacity: 200},
+			{Node1Pub: "b", Node2Pub: "c", ChanPoint: "bc:0", Capacity: 300},
+		},
+	}
+	client := New(graph.New(channelGraph), "a")
+
+	info, err := client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", info.Alias)
+	assert.Equal(t, uint32(2), info.NumActiveChannels)
+
+	node, err := client.GetNodeInfo(ctx, &lnrpc.NodeInfoRequest{PubKey: "b", IncludeChannels: true})
+	assert.NoError(t, err)
+	assert.Len(t, node.Channels, 2)
+
+	node, err = client.GetNodeInfo(ctx, &lnrpc.NodeInfoRequest{PubKey: "b"})
+	assert.NoError(t, err)
+	assert.Empty(t, node.Channels)
+	assert.Equal(t, uint32(2), node.NumChannels)
+
+	_, err = client.GetNodeInfo(ctx, &lnrpc.NodeInfoRequest{PubKey: "d"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	channels, err := client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, []*lnrpc.Channel{
+		{Active: true, RemotePubkey: "b", ChannelPoint: "ab:0", Capacity: 100, Initiator: true},
+		{Active: true, RemotePubkey: "c", ChannelPoint: "ca:0", Capacity: 200},
+	}, channels.Channels)
+
+	peers, err := client.ListPeers(ctx, &lnrpc.ListPeersRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, []*lnrpc.Peer{
+		{PubKey: "b", Address: "203.0.113.5:9735", Inbound: true},
+		{PubKey: "c", Inbound: true},
+	}, peers.Peers)
+
+	channelGraph, err = client.DescribeGraph(ctx, &lnrpc.ChannelGraphRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, channelGraph.Edges, 3)
+}
+
+func TestDrive(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rnd := rand.New(rand.NewSource(1))
+	channelGraph := Graph(rnd, 5, 10)
+	publicKey := channelGraph.Nodes[0].PubKey
+	client := New(graph.New(channelGraph), publicKey)
+
+	stream, err := client.ChannelAcceptor(ctx)
+	assert.NoError(t, err)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			resp := &lnrpc.ChannelAcceptResponse{
+				PendingChanId: req.PendingChanId,
+				Accept:        req.FundingAmt < 8_000_000,
+			}
+			if err := stream.Send(resp); err != nil {
+				return
+			}
+		}
+	}()
+
+	count := 0
+	err = client.Drive(ctx, rnd, 5, time.Millisecond, func(
+		req *lnrpc.ChannelAcceptRequest,
+		resp *lnrpc.ChannelAcceptResponse,
+	) {
+		count++
+		assert.Equal(t, req.PendingChanId, resp.PendingChanId)
+		assert.Equal(t, req.FundingAmt < 8_000_000, resp.Accept)
+		assert.NotEqual(t, publicKey, hex.EncodeToString(req.NodePubkey))
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, count)
+
+	cancel()
+	_, err = client.RequestChannel(ctx, &lnrpc.ChannelAcceptRequest{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
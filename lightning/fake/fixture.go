@@ -0,0 +1,110 @@
+package fake
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// Graph generates a random channel graph with the number of nodes and channels received. The
+// first node has at least one channel and can be used as our own node.
+func Graph(rnd *rand.Rand, nodes, channels int) *lnrpc.ChannelGraph {
+	graph := &lnrpc.ChannelGraph{
+		Nodes: make([]*lnrpc.LightningNode, 0, nodes),
+		Edges: make([]*lnrpc.ChannelEdge, 0, channels),
+	}
+	if nodes < 2 {
+		return graph
+	}
+
+	now := time.Now().Unix()
+	for i := 0; i < nodes; i++ {
+		graph.Nodes = append(graph.Nodes, randomNode(rnd, i, now))
+	}
+
+	for i := 0; i < channels; i++ {
+		node1 := rnd.Intn(nodes)
+		if i == 0 {
+			node1 = 0
+		}
+		node2 := rnd.Intn(nodes - 1)
+		if node2 >= node1 {
+			node2++
+		}
+
+		graph.Edges = append(graph.Edges, randomEdge(
+			rnd, graph.Nodes[node1].PubKey, graph.Nodes[node2].PubKey, now,
+		))
+	}
+
+	return graph
+}
+
+func randomNode(rnd *rand.Rand, index int, now int64) *lnrpc.LightningNode {
+	publicKey := make([]byte, 33)
+	rnd.Read(publicKey)
+	publicKey[0] = 0x02 + byte(rnd.Intn(2))
+
+	node := &lnrpc.LightningNode{
+		PubKey:     hex.EncodeToString(publicKey),
+		Alias:      fmt.Sprintf("node-%d", index),
+		LastUpdate: uint32(now - rnd.Int63n(30*24*60*60)),
+		Features: map[uint32]*lnrpc.Feature{
+			uint32(lnrpc.FeatureBit_DATALOSS_PROTECT_OPT):  {Name: "data-loss-protect", IsKnown: true},
+			uint32(lnrpc.FeatureBit_STATIC_REMOTE_KEY_REQ): {Name: "static-remote-key", IsKnown: true},
+		},
+	}
+
+	if rnd.Intn(2) == 0 {
+		node.Addresses = append(node.Addresses, &lnrpc.NodeAddress{
+			Network: "tcp",
+			Addr:    fmt.Sprintf("%d.%d.%d.%d:9735", rnd.Intn(223)+1, rnd.Intn(256), rnd.Intn(256), rnd.Intn(256)),
+		})
+	}
+	if rnd.Intn(2) == 0 || len(node.Addresses) == 0 {
+		onion := make([]byte, 35)
+		rnd.Read(onion)
+		node.Addresses = append(node.Addresses, &lnrpc.NodeAddress{
+			Network: "tcp",
+			Addr:    hex.EncodeToString(onion)[:56] + ".onion:9735",
+		})
+	}
+
+	return node
+}
+
+func randomEdge(rnd *rand.Rand, node1, node2 string, now int64) *lnrpc.ChannelEdge {
+	blockHeight := uint64(500_000 + rnd.Intn(350_000))
+	txIndex := uint64(rnd.Intn(3000))
+	txid := make([]byte, 32)
+	rnd.Read(txid)
+	capacity := 20_000 + rnd.Int63n(16_777_215-20_000)
+
+	return &lnrpc.ChannelEdge{
+		ChannelId:   blockHeight<<40 | txIndex<<16,
+		ChanPoint:   hex.EncodeToString(txid) + ":0",
+		Node1Pub:    node1,
+		Node2Pub:    node2,
+		Capacity:    capacity,
+		Node1Policy: randomPolicy(rnd, capacity, now),
+		Node2Policy: randomPolicy(rnd, capacity, now),
+	}
+}
+
+func randomPolicy(rnd *rand.Rand, capacity, now int64) *lnrpc.RoutingPolicy {
+	policy := &lnrpc.RoutingPolicy{
+		TimeLockDelta:    []uint32{40, 80, 144}[rnd.Intn(3)],
+		MinHtlc:          1000,
+		FeeRateMilliMsat: rnd.Int63n(2000),
+		MaxHtlcMsat:      uint64(capacity) * 990,
+		LastUpdate:       uint32(now - rnd.Int63n(14*24*60*60)),
+		Disabled:         rnd.Intn(20) == 0,
+	}
+	if rnd.Intn(2) == 0 {
+		policy.FeeBaseMsat = 1000
+	}
+	return policy
+}
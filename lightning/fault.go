@@ -0,0 +1,276 @@
+package lightning
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Faults configures the failures injected into the calls made to the lightning node.
+type Faults struct {
+	// Probability (0-1) of a call failing.
+	ErrorRate float64
+	// Probability (0-1) of the peer information being malformed.
+	MalformedRate float64
+	// Maximum delay added to every call.
+	Delay time.Duration
+}
+
+// ParseFaults parses a comma-separated list of faults, like "errors=0.1,malformed=0.2,delay=3s".
+func ParseFaults(spec string) (Faults, error) {
+	var faults Faults
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return Faults{}, errors.Errorf("invalid fault %q, expected key=value", field)
+		}
+
+		var err error
+		switch key {
+		case "errors":
+			faults.ErrorRate, err = parseRate(value)
+		case "malformed":
+			faults.MalformedRate, err = parseRate(value)
+		case "delay":
+			faults.Delay, err = time.ParseDuration(value)
+		default:
+			return Faults{}, errors.Errorf("unknown fault %q", key)
+		}
+		if err != nil {
+			return Faults{}, errors.Wrapf(err, "invalid %s value", key)
+		}
+	}
+
+	return faults, nil
+}
+
+func parseRate(value string) (float64, error) {
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if rate < 0 || rate > 1 {
+		return 0, errors.New("rate must be between 0 and 1")
+	}
+	return rate, nil
+}
+
+// faultClient wraps a client injecting failures into its calls. The channel acceptor stream is
+// left untouched.
+type faultClient struct {
+	Client
+	faults Faults
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// NewFaultClient returns a client that injects the faults received into the calls made to the one
+// wrapped, meant for manual testing only.
+func NewFaultClient(client Client, faults Faults, seed int64) Client {
+	return &faultClient{
+		Client: client,
+		faults: faults,
+		rnd:    rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (f *faultClient) GetInfo(
+	ctx context.Context,
+	in *lnrpc.GetInfoRequest,
+	opts ...grpc.CallOption,
+) (*lnrpc.GetInfoResponse, error) {
+	if err := f.inject(ctx); err != nil {
+		return nil, err
+	}
+	return f.Client.GetInfo(ctx, in, opts...)
+}
+
+func (f *faultClient) GetNodeInfo(
+	ctx context.Context,
+	in *lnrpc.NodeInfoRequest,
+	opts ...grpc.CallOption,
+) (*lnrpc.NodeInfo, error) {
+	if err := f.inject(ctx); err != nil {
+		return nil, err
+	}
+
+	peer, err := f.Client.GetNodeInfo(ctx, in, opts...)
+	if err != nil || !f.chance(f.faults.MalformedRate) {
+		return peer, err
+	}
+
+	return f.malform(peer), nil
+}
+
+func (f *faultClient) ListChannels(
+	ctx context.Context,
+	in *lnrpc.ListChannelsRequest,
+	opts ...grpc.CallOption,
+) (*lnrpc.ListChannelsResponse, error) {
+	if err := f.inject(ctx); err != nil {
+		return nil, err
+	}
+	return f.Client.ListChannels(ctx, in, opts...)
+}
+
+func (f *faultClient) ListPeers(
+	ctx context.Context,
+	in *lnrpc.ListPeersRequest,
+	opts ...grpc.CallOption,
+) (*lnrpc.ListPeersResponse, error) {
+	if err := f.inject(ctx); err != nil {
+		return nil, err
+	}
+	return f.Client.ListPeers(ctx, in, opts...)
+}
+
+func (f *faultClient) PendingChannels(
+	ctx context.Context,
+	in *lnrpc.PendingChannelsRequest,
+	opts ...grpc.CallOption,
+) (*lnrpc.PendingChannelsResponse, error) {
+	if err := f.inject(ctx); err != nil {
+		return nil, err
+	}
+	return f.Client.PendingChannels(ctx, in, opts...)
+}
+
+func (f *faultClient) ClosedChannels(
+	ctx context.Context,
+	in *lnrpc.ClosedChannelsRequest,
+	opts ...grpc.CallOption,
+) (*lnrpc.ClosedChannelsResponse, error) {
+	if err := f.inject(ctx); err != nil {
+		return nil, err
+	}
+	return f.Client.ClosedChannels(ctx, in, opts...)
+}
+
+func (f *faultClient) ForwardingHistory(
+	ctx context.Context,
+	in *lnrpc.ForwardingHistoryRequest,
+	opts ...grpc.CallOption,
+) (*lnrpc.ForwardingHistoryResponse, error) {
+	if err := f.inject(ctx); err != nil {
+		return nil, err
+	}
+	return f.Client.ForwardingHistory(ctx, in, opts...)
+}
+
+func (f *faultClient) DescribeGraph(
+	ctx context.Context,
+	in *lnrpc.ChannelGraphRequest,
+	opts ...grpc.CallOption,
+) (*lnrpc.ChannelGraph, error) {
+	if err := f.inject(ctx); err != nil {
+		return nil, err
+	}
+	return f.Client.DescribeGraph(ctx, in, opts...)
+}
+
+func (f *faultClient) EstimateFeeRate(
+	ctx context.Context,
+	in *walletrpc.EstimateFeeRequest,
+	opts ...grpc.CallOption,
+) (*walletrpc.EstimateFeeResponse, error) {
+	if err := f.inject(ctx); err != nil {
+		return nil, err
+	}
+	return f.Client.EstimateFeeRate(ctx, in, opts...)
+}
+
+func (f *faultClient) LabelTransaction(
+	ctx context.Context,
+	in *walletrpc.LabelTransactionRequest,
+	opts ...grpc.CallOption,
+) (*walletrpc.LabelTransactionResponse, error) {
+	if err := f.inject(ctx); err != nil {
+		return nil, err
+	}
+	return f.Client.LabelTransaction(ctx, in, opts...)
+}
+
+// inject delays the call and fails it randomly.
+func (f *faultClient) inject(ctx context.Context) error {
+	if f.faults.Delay > 0 {
+		f.mu.Lock()
+		delay := time.Duration(f.rnd.Int63n(int64(f.faults.Delay) + 1))
+		f.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	if f.chance(f.faults.ErrorRate) {
+		return status.Error(codes.Unavailable, "injected fault")
+	}
+
+	return nil
+}
+
+func (f *faultClient) chance(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rnd.Float64() < rate
+}
+
+// malform returns a copy of the peer information with some of its fields missing or inconsistent.
+func (f *faultClient) malform(peer *lnrpc.NodeInfo) *lnrpc.NodeInfo {
+	f.mu.Lock()
+	kind := f.rnd.Intn(3)
+	f.mu.Unlock()
+
+	malformed := &lnrpc.NodeInfo{
+		Node:          peer.Node,
+		NumChannels:   peer.NumChannels,
+		TotalCapacity: peer.TotalCapacity,
+	}
+
+	switch kind {
+	case 0:
+		// Channels without routing policies
+		for _, channel := range peer.Channels {
+			malformed.Channels = append(malformed.Channels, &lnrpc.ChannelEdge{
+				ChannelId: channel.ChannelId,
+				ChanPoint: channel.ChanPoint,
+				Node1Pub:  channel.Node1Pub,
+				Node2Pub:  channel.Node2Pub,
+				Capacity:  channel.Capacity,
+			})
+		}
+	case 1:
+		// Channels that don't belong to the node
+		malformed.Channels = []*lnrpc.ChannelEdge{{Capacity: -1}}
+		malformed.NumChannels = 0
+		malformed.TotalCapacity = -1
+	default:
+		// Missing node announcement
+		malformed.Node = nil
+		malformed.Channels = peer.Channels
+	}
+
+	return malformed
+}
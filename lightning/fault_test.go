@@ -0,0 +1,124 @@
+package lightning
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type stubClient struct {
+	Client
+}
+
+func (stubClient) GetInfo(
+	context.Context,
+	*lnrpc.GetInfoRequest,
+	...grpc.CallOption,
+) (*lnrpc.GetInfoResponse, error) {
+	return &lnrpc.GetInfoResponse{}, nil
+}
+
+func (stubClient) GetNodeInfo(
+	_ context.Context,
+	in *lnrpc.NodeInfoRequest,
+	_ ...grpc.CallOption,
+) (*lnrpc.NodeInfo, error) {
+	return &lnrpc.NodeInfo{
+		Node: &lnrpc.LightningNode{PubKey: in.PubKey},
+		Channels: []*lnrpc.ChannelEdge{
+			{Node1Pub: in.PubKey, Node1Policy: &lnrpc.RoutingPolicy{}},
+		},
+	}, nil
+}
+
+func TestParseFaults(t *testing.T) {
+	cases := []struct {
+		desc     string
+		spec     string
+		expected Faults
+		fail     bool
+	}{
+		{
+			desc: "Empty",
+		},
+		{
+			desc: "All",
+			spec: "errors=0.1, malformed=1,delay=2s",
+			expected: Faults{
+				ErrorRate:     0.1,
+				MalformedRate: 1,
+				Delay:         2 * time.Second,
+			},
+		},
+		{
+			desc: "Missing value",
+			spec: "errors",
+			fail: true,
+		},
+		{
+			desc: "Unknown fault",
+			spec: "panic=1",
+			fail: true,
+		},
+		{
+			desc: "Invalid rate",
+			spec: "errors=2",
+			fail: true,
+		},
+		{
+			desc: "Invalid delay",
+			spec: "delay=soon",
+			fail: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			actual, err := ParseFaults(tc.spec)
+			if tc.fail {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestFaultClient(t *testing.T) {
+	ctx := context.Background()
+	req := &lnrpc.NodeInfoRequest{PubKey: "public_key"}
+
+	client := NewFaultClient(stubClient{}, Faults{}, 1)
+	_, err := client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	assert.NoError(t, err)
+	peer, err := client.GetNodeInfo(ctx, req)
+	assert.NoError(t, err)
+	assert.NotNil(t, peer.Channels[0].Node1Policy)
+
+	client = NewFaultClient(stubClient{}, Faults{ErrorRate: 1}, 1)
+	_, err = client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	client = NewFaultClient(stubClient{}, Faults{MalformedRate: 1}, 1)
+	for i := 0; i < 10; i++ {
+		peer, err := client.GetNodeInfo(ctx, req)
+		assert.NoError(t, err)
+		malformed := peer.Node == nil || peer.TotalCapacity < 0 ||
+			peer.Channels[0].Node1Policy == nil
+		assert.True(t, malformed)
+	}
+
+	client = NewFaultClient(stubClient{}, Faults{Delay: time.Hour}, 1)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,104 @@
+package lightning
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/metrics"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// unaryInterceptor logs every call made to LND with its duration and outcome and records them in
+// the metrics.
+func unaryInterceptor(
+	ctx context.Context,
+	method string,
+	req, reply any,
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	observeRPC(ctx, method, time.Since(start), err)
+	return err
+}
+
+// streamInterceptor logs the streams opened with LND and counts the messages exchanged in them.
+func streamInterceptor(
+	ctx context.Context,
+	desc *grpc.StreamDesc,
+	cc *grpc.ClientConn,
+	method string,
+	streamer grpc.Streamer,
+	opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	start := time.Now()
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	observeRPC(ctx, method, time.Since(start), err)
+	if err != nil {
+		return nil, err
+	}
+
+	return &countingStream{ClientStream: stream, method: trimMethod(method)}, nil
+}
+
+func observeRPC(ctx context.Context, method string, duration time.Duration, err error) {
+	method = trimMethod(method)
+	code := status.Code(err).String()
+
+	attrs := []any{slog.String("method", method), slog.Duration("duration", duration)}
+	if err != nil {
+		attrs = append(attrs, ErrorAttrs(err))
+	} else {
+		attrs = append(attrs, slog.String("code", code))
+	}
+	slog.DebugContext(ctx, "LND call", attrs...)
+	metrics.ObserveRPC(method, code, duration)
+}
+
+// ErrorAttrs returns the attributes logged for the error of a call to LND: the gRPC code, like the
+// calls logged by the interceptors, and the error. The code is omitted for errors that didn't come
+// from LND.
+func ErrorAttrs(err error) slog.Attr {
+	s, ok := status.FromError(err)
+	if !ok {
+		return slog.Group("", slog.Any("error", err))
+	}
+	return slog.Group("", slog.String("code", s.Code().String()), slog.Any("error", err))
+}
+
+// trimMethod removes the service from the full method name, "/lnrpc.Lightning/GetInfo" becomes
+// "GetInfo".
+func trimMethod(method string) string {
+	if i := strings.LastIndex(method, "/"); i != -1 {
+		return method[i+1:]
+	}
+	return method
+}
+
+// countingStream counts the messages sent and received.
+type countingStream struct {
+	grpc.ClientStream
+	method string
+}
+
+func (s *countingStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		metrics.CountStreamMessage(s.method, true)
+	}
+	return err
+}
+
+func (s *countingStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == nil {
+		metrics.CountStreamMessage(s.method, false)
+	}
+	return err
+}
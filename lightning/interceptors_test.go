@@ -0,0 +1,72 @@
+package lightning
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryInterceptor(t *testing.T) {
+	invoked := false
+	invoker := func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+		invoked = true
+		return status.Error(codes.Unavailable, "unavailable")
+	}
+
+	err := unaryInterceptor(context.Background(), "/lnrpc.Lightning/GetInfo", nil, nil, nil, invoker)
+	assert.True(t, invoked)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestErrorAttrs(t *testing.T) {
+	cases := []struct {
+		desc     string
+		err      error
+		expected string
+	}{
+		{
+			desc:     "LND",
+			err:      status.Error(codes.Unavailable, "unavailable"),
+			expected: `level=INFO msg=call code=Unavailable error="rpc error: code = Unavailable desc = unavailable"`,
+		},
+		{
+			desc:     "Wrapped",
+			err:      fmt.Errorf("getting node: %w", status.Error(codes.NotFound, "not found")),
+			expected: `level=INFO msg=call code=NotFound error="getting node: rpc error: code = NotFound desc = not found"`,
+		},
+		{
+			desc:     "Other",
+			err:      errors.New("invalid"),
+			expected: `level=INFO msg=call error=invalid`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+				ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey {
+						return slog.Attr{}
+					}
+					return a
+				},
+			}))
+			logger.Info("call", ErrorAttrs(tc.err))
+			assert.Equal(t, tc.expected+"\n", buf.String())
+		})
+	}
+}
+
+func TestTrimMethod(t *testing.T) {
+	assert.Equal(t, "GetInfo", trimMethod("/lnrpc.Lightning/GetInfo"))
+	assert.Equal(t, "GetInfo", trimMethod("GetInfo"))
+}
@@ -5,15 +5,25 @@ package lightning
 import (
 	"context"
 	"log/slog"
+	"net"
 	"os"
+	"time"
 
 	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/metrics"
+	"github.com/aftermath2/acceptlnd/proxy"
 
 	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
 	"github.com/lightningnetwork/lnd/macaroons"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
 	"gopkg.in/macaroon.v2"
 )
 
@@ -21,29 +31,199 @@ import (
 type Client interface {
 	ChannelAcceptor(ctx context.Context, opts ...grpc.CallOption) (lnrpc.Lightning_ChannelAcceptorClient, error)
 	GetInfo(ctx context.Context, in *lnrpc.GetInfoRequest, opts ...grpc.CallOption) (*lnrpc.GetInfoResponse, error)
+	GetDebugInfo(ctx context.Context, in *lnrpc.GetDebugInfoRequest, opts ...grpc.CallOption) (*lnrpc.GetDebugInfoResponse, error)
 	GetNodeInfo(ctx context.Context, in *lnrpc.NodeInfoRequest, opts ...grpc.CallOption) (*lnrpc.NodeInfo, error)
+	DescribeGraph(ctx context.Context, in *lnrpc.ChannelGraphRequest, opts ...grpc.CallOption) (*lnrpc.ChannelGraph, error)
+	SubscribeChannelGraph(ctx context.Context, in *lnrpc.GraphTopologySubscription, opts ...grpc.CallOption) (lnrpc.Lightning_SubscribeChannelGraphClient, error)
+	ListChannels(ctx context.Context, in *lnrpc.ListChannelsRequest, opts ...grpc.CallOption) (*lnrpc.ListChannelsResponse, error)
+	ListPeers(ctx context.Context, in *lnrpc.ListPeersRequest, opts ...grpc.CallOption) (*lnrpc.ListPeersResponse, error)
+	PendingChannels(ctx context.Context, in *lnrpc.PendingChannelsRequest, opts ...grpc.CallOption) (*lnrpc.PendingChannelsResponse, error)
+	ClosedChannels(ctx context.Context, in *lnrpc.ClosedChannelsRequest, opts ...grpc.CallOption) (*lnrpc.ClosedChannelsResponse, error)
+	ForwardingHistory(ctx context.Context, in *lnrpc.ForwardingHistoryRequest, opts ...grpc.CallOption) (*lnrpc.ForwardingHistoryResponse, error)
+	RegisterRPCMiddleware(ctx context.Context, opts ...grpc.CallOption) (lnrpc.Lightning_RegisterRPCMiddlewareClient, error)
+	// EstimateFeeRate calls WalletKit's EstimateFee, renamed as it clashes with Lightning's one.
+	EstimateFeeRate(ctx context.Context, in *walletrpc.EstimateFeeRequest, opts ...grpc.CallOption) (*walletrpc.EstimateFeeResponse, error)
+	LabelTransaction(ctx context.Context, in *walletrpc.LabelTransactionRequest, opts ...grpc.CallOption) (*walletrpc.LabelTransactionResponse, error)
+	HtlcInterceptor(ctx context.Context, opts ...grpc.CallOption) (routerrpc.Router_HtlcInterceptorClient, error)
 }
 
-// NewClient returns a new lightning client.
-func NewClient(config config.Config) (Client, error) {
+// Connection is a lightning client connected to LND whose connectivity state can be monitored.
+type Connection struct {
+	lnrpc.LightningClient
+	wallet walletrpc.WalletKitClient
+	router routerrpc.RouterClient
+	state  lnrpc.StateClient
+	conn   *grpc.ClientConn
+}
+
+// NewClient returns a new lightning client. The connection is established in the background, its
+// state changes can be followed with MonitorState.
+func NewClient(config config.Config) (*Connection, error) {
 	opts, err := loadGRPCOpts(config)
 	if err != nil {
 		return nil, errors.Wrap(err, "loading GRPC options")
 	}
 
+	target := config.RPCAddress
+	if config.Proxy != "" {
+		dialer, err := proxy.Dialer(config.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "tcp", addr)
+		}))
+		// Let the proxy resolve the address, it may be an onion service
+		target = "passthrough:///" + target
+		slog.Info("Using proxy", slog.String("address", config.Proxy))
+	}
+
 	slog.Info("Connecting to LND", slog.String("address", config.RPCAddress))
-	conn, err := grpc.NewClient(config.RPCAddress, opts...)
+	conn, err := grpc.NewClient(target, opts...)
 	if err != nil {
 		return nil, err
 	}
+	conn.Connect()
+
+	return &Connection{
+		LightningClient: lnrpc.NewLightningClient(conn),
+		wallet:          walletrpc.NewWalletKitClient(conn),
+		router:          routerrpc.NewRouterClient(conn),
+		state:           lnrpc.NewStateClient(conn),
+		conn:            conn,
+	}, nil
+}
+
+// EstimateFeeRate returns the fee rate estimated by the wallet for the confirmation target.
+func (c *Connection) EstimateFeeRate(
+	ctx context.Context,
+	in *walletrpc.EstimateFeeRequest,
+	opts ...grpc.CallOption,
+) (*walletrpc.EstimateFeeResponse, error) {
+	return c.wallet.EstimateFee(ctx, in, opts...)
+}
+
+// LabelTransaction sets the label of a transaction known by the wallet.
+func (c *Connection) LabelTransaction(
+	ctx context.Context,
+	in *walletrpc.LabelTransactionRequest,
+	opts ...grpc.CallOption,
+) (*walletrpc.LabelTransactionResponse, error) {
+	return c.wallet.LabelTransaction(ctx, in, opts...)
+}
+
+// HtlcInterceptor registers as the interceptor of the HTLCs forwarded by LND.
+func (c *Connection) HtlcInterceptor(
+	ctx context.Context,
+	opts ...grpc.CallOption,
+) (routerrpc.Router_HtlcInterceptorClient, error) {
+	return c.router.HtlcInterceptor(ctx, opts...)
+}
+
+// State returns the current state of the connection.
+func (c *Connection) State() connectivity.State {
+	return c.conn.GetState()
+}
+
+// Ready returns whether the connection with LND is established.
+func (c *Connection) Ready() bool {
+	return c.State() == connectivity.Ready
+}
+
+// MonitorState logs every connectivity state change until the context is cancelled, and records
+// whether the connection is ready in the metrics.
+func (c *Connection) MonitorState(ctx context.Context) {
+	state := c.conn.GetState()
+	for {
+		metrics.SetConnected(state == connectivity.Ready)
+		switch state {
+		case connectivity.Ready:
+			slog.Info("Connected to LND")
+		case connectivity.TransientFailure:
+			slog.Warn("Connection to LND failed, retrying")
+		default:
+			slog.Debug("LND connection state changed", slog.String("state", state.String()))
+		}
+
+		if !c.conn.WaitForStateChange(ctx, state) {
+			return
+		}
+		state = c.conn.GetState()
+	}
+}
+
+// defaultStartupRetryInterval is the time between the checks of LND's state while it's starting.
+const defaultStartupRetryInterval = 5 * time.Second
+
+// WaitReady blocks until LND is ready to serve the channel acceptor, logging what it's waiting for
+// whenever it changes: LND starting, its wallet being created or unlocked or its server starting.
+// LND versions without the State service are assumed to be ready.
+func (c *Connection) WaitReady(ctx context.Context, startup config.Startup) error {
+	interval := startup.RetryInterval
+	if interval == 0 {
+		interval = defaultStartupRetryInterval
+	}
+	if startup.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, startup.Timeout)
+		defer cancel()
+	}
+
+	var waiting string
+	for {
+		resp, err := c.state.GetState(ctx, &lnrpc.GetStateRequest{})
+		if status.Code(err) == codes.Unimplemented ||
+			(err == nil && resp.State == lnrpc.WalletState_SERVER_ACTIVE) {
+			if waiting != "" {
+				slog.Info("LND is ready")
+			}
+			return nil
+		}
+
+		if message := waitingMessage(resp, err); message != waiting {
+			args := []any{slog.Duration("retry_interval", interval)}
+			if err != nil {
+				args = append(args, slog.Any("error", err))
+			} else {
+				args = append(args, slog.String("state", resp.State.String()))
+			}
+			slog.Info(message, args...)
+			waiting = message
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "waiting for LND to be ready")
+		case <-time.After(interval):
+		}
+	}
+}
+
+// waitingMessage describes what is preventing LND from serving the channel acceptor.
+func waitingMessage(resp *lnrpc.GetStateResponse, err error) string {
+	if err != nil {
+		return "Waiting for LND to start"
+	}
 
-	return lnrpc.NewLightningClient(conn), nil
+	switch resp.State {
+	case lnrpc.WalletState_NON_EXISTING:
+		return "Waiting for LND's wallet to be created"
+	case lnrpc.WalletState_LOCKED:
+		return "Waiting for LND's wallet to be unlocked"
+	default:
+		return "Waiting for LND's server to start"
+	}
+}
+
+// Close tears down the connection.
+func (c *Connection) Close() error {
+	return c.conn.Close()
 }
 
 func loadGRPCOpts(config config.Config) ([]grpc.DialOption, error) {
-	tlsCert, err := credentials.NewClientTLSFromFile(config.CertificatePath, "")
+	tlsConfig, err := loadTLSConfig(config)
 	if err != nil {
-		return nil, errors.Wrap(err, "unable to read TLS certificate")
+		return nil, err
 	}
 
 	macBytes, err := os.ReadFile(config.MacaroonPath)
@@ -62,7 +242,33 @@ func loadGRPCOpts(config config.Config) ([]grpc.DialOption, error) {
 	}
 
 	return []grpc.DialOption{
-		grpc.WithTransportCredentials(tlsCert),
+		grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)),
 		grpc.WithPerRPCCredentials(macCred),
+		grpc.WithKeepaliveParams(keepaliveParams(config.Keepalive)),
+		grpc.WithChainUnaryInterceptor(unaryInterceptor),
+		grpc.WithChainStreamInterceptor(streamInterceptor),
 	}, nil
 }
+
+// Keepalive defaults, LND rejects pings sent more often than every 5 seconds.
+const (
+	defaultKeepaliveTime    = 30 * time.Second
+	defaultKeepaliveTimeout = 20 * time.Second
+)
+
+// keepaliveParams returns the client keepalive parameters, so connections that die silently (like
+// NAT'd ones) are detected instead of blocking the channel acceptor stream forever.
+func keepaliveParams(config config.Keepalive) keepalive.ClientParameters {
+	params := keepalive.ClientParameters{
+		Time:                config.Time,
+		Timeout:             config.Timeout,
+		PermitWithoutStream: config.PermitWithoutStream,
+	}
+	if params.Time == 0 {
+		params.Time = defaultKeepaliveTime
+	}
+	if params.Timeout == 0 {
+		params.Timeout = defaultKeepaliveTimeout
+	}
+	return params
+}
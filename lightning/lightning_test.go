@@ -0,0 +1,211 @@
+package lightning
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+	"gopkg.in/macaroon.v2"
+)
+
+func TestKeepaliveParams(t *testing.T) {
+	params := keepaliveParams(config.Keepalive{})
+	assert.Equal(t, keepalive.ClientParameters{
+		Time:    defaultKeepaliveTime,
+		Timeout: defaultKeepaliveTimeout,
+	}, params)
+
+	params = keepaliveParams(config.Keepalive{
+		Time:                time.Minute,
+		Timeout:             5 * time.Second,
+		PermitWithoutStream: true,
+	})
+	assert.Equal(t, keepalive.ClientParameters{
+		Time:                time.Minute,
+		Timeout:             5 * time.Second,
+		PermitWithoutStream: true,
+	}, params)
+}
+
+// TestConfigDefaults makes sure the defaults documented in the configuration struct tags are the
+// ones applied when the fields aren't set.
+func TestConfigDefaults(t *testing.T) {
+	cases := []struct {
+		config   any
+		field    string
+		expected time.Duration
+	}{
+		{config: config.Keepalive{}, field: "Time", expected: defaultKeepaliveTime},
+		{config: config.Keepalive{}, field: "Timeout", expected: defaultKeepaliveTimeout},
+		{config: config.Startup{}, field: "RetryInterval", expected: defaultStartupRetryInterval},
+	}
+
+	for _, tc := range cases {
+		typ := reflect.TypeOf(tc.config)
+		t.Run(typ.Name()+"."+tc.field, func(t *testing.T) {
+			field, ok := typ.FieldByName(tc.field)
+			assert.True(t, ok)
+			assert.Equal(t, tc.expected.String(), field.Tag.Get("default"))
+		})
+	}
+}
+
+func TestConnection(t *testing.T) {
+	srv, config := testServer(t, func(*grpc.Server) {})
+	defer srv.Stop()
+
+	conn, err := NewClient(config)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go conn.MonitorState(ctx)
+
+	assert.Eventually(t, conn.Ready, 5*time.Second, 10*time.Millisecond)
+
+	srv.Stop()
+	assert.Eventually(t, func() bool { return !conn.Ready() }, 5*time.Second, 10*time.Millisecond)
+}
+
+// stateServer reports the states queued and then the final one.
+type stateServer struct {
+	lnrpc.UnimplementedStateServer
+	states chan lnrpc.WalletState
+	final  lnrpc.WalletState
+}
+
+func (s *stateServer) GetState(context.Context, *lnrpc.GetStateRequest) (*lnrpc.GetStateResponse, error) {
+	select {
+	case state := <-s.states:
+		return &lnrpc.GetStateResponse{State: state}, nil
+	default:
+		return &lnrpc.GetStateResponse{State: s.final}, nil
+	}
+}
+
+func TestWaitReady(t *testing.T) {
+	state := &stateServer{states: make(chan lnrpc.WalletState, 3), final: lnrpc.WalletState_SERVER_ACTIVE}
+	state.states <- lnrpc.WalletState_LOCKED
+	state.states <- lnrpc.WalletState_UNLOCKED
+	state.states <- lnrpc.WalletState_RPC_ACTIVE
+	srv, cfg := testServer(t, func(srv *grpc.Server) {
+		lnrpc.RegisterStateServer(srv, state)
+	})
+	defer srv.Stop()
+
+	conn, err := NewClient(cfg)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	startup := config.Startup{RetryInterval: 10 * time.Millisecond, Timeout: 5 * time.Second}
+	assert.NoError(t, conn.WaitReady(context.Background(), startup))
+	assert.Empty(t, state.states)
+
+	t.Run("Unimplemented", func(t *testing.T) {
+		srv, cfg := testServer(t, func(*grpc.Server) {})
+		defer srv.Stop()
+
+		conn, err := NewClient(cfg)
+		assert.NoError(t, err)
+		defer conn.Close()
+
+		assert.NoError(t, conn.WaitReady(context.Background(), startup))
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		locked := &stateServer{final: lnrpc.WalletState_LOCKED}
+		srv, cfg := testServer(t, func(srv *grpc.Server) {
+			lnrpc.RegisterStateServer(srv, locked)
+		})
+		defer srv.Stop()
+
+		conn, err := NewClient(cfg)
+		assert.NoError(t, err)
+		defer conn.Close()
+
+		startup := config.Startup{RetryInterval: 10 * time.Millisecond, Timeout: 100 * time.Millisecond}
+		assert.ErrorIs(t, conn.WaitReady(context.Background(), startup), context.DeadlineExceeded)
+	})
+}
+
+func TestWaitingMessage(t *testing.T) {
+	cases := []struct {
+		desc     string
+		resp     *lnrpc.GetStateResponse
+		err      error
+		expected string
+	}{
+		{
+			desc:     "Unavailable",
+			err:      status.Error(codes.Unavailable, "connection refused"),
+			expected: "Waiting for LND to start",
+		},
+		{
+			desc:     "Non existing",
+			resp:     &lnrpc.GetStateResponse{State: lnrpc.WalletState_NON_EXISTING},
+			expected: "Waiting for LND's wallet to be created",
+		},
+		{
+			desc:     "Locked",
+			resp:     &lnrpc.GetStateResponse{State: lnrpc.WalletState_LOCKED},
+			expected: "Waiting for LND's wallet to be unlocked",
+		},
+		{
+			desc:     "RPC active",
+			resp:     &lnrpc.GetStateResponse{State: lnrpc.WalletState_RPC_ACTIVE},
+			expected: "Waiting for LND's server to start",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.expected, waitingMessage(tc.resp, tc.err))
+		})
+	}
+}
+
+// testServer starts a TLS gRPC server with the services registered and returns the configuration
+// to connect to it.
+func testServer(t *testing.T, register func(*grpc.Server)) (*grpc.Server, config.Config) {
+	t.Helper()
+	dir := t.TempDir()
+	_, certPEM, keyPEM := selfSignedCert(t)
+	certPath := filepath.Join(dir, "tls.cert")
+	assert.NoError(t, os.WriteFile(certPath, certPEM, 0o600))
+
+	mac, err := macaroon.New([]byte("root_key"), []byte("id"), "lnd", macaroon.LatestVersion)
+	assert.NoError(t, err)
+	macBytes, err := mac.MarshalBinary()
+	assert.NoError(t, err)
+	macaroonPath := filepath.Join(dir, "acceptlnd.macaroon")
+	assert.NoError(t, os.WriteFile(macaroonPath, macBytes, 0o600))
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	serverCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	assert.NoError(t, err)
+	srv := grpc.NewServer(grpc.Creds(credentials.NewServerTLSFromCert(&serverCert)))
+	register(srv)
+	go srv.Serve(listener)
+
+	return srv, config.Config{
+		RPCAddress:      listener.Addr().String(),
+		CertificatePath: certPath,
+		MacaroonPath:    macaroonPath,
+	}
+}
@@ -0,0 +1,37 @@
+package lightning
+
+import (
+	"context"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+// DefaultAcceptorTimeout is the time LND waits for the channel acceptor response by default.
+const DefaultAcceptorTimeout = 15 * time.Second
+
+// debugInfoMaxSize is the maximum size of the GetDebugInfo response, it includes LND's log file.
+const debugInfoMaxSize = 64 << 20
+
+// AcceptorTimeout returns the time LND waits for the channel acceptor response before rejecting
+// the request (its acceptortimeout option), as reported by GetDebugInfo.
+func AcceptorTimeout(ctx context.Context, client Client) (time.Duration, error) {
+	info, err := client.GetDebugInfo(ctx, &lnrpc.GetDebugInfoRequest{},
+		grpc.MaxCallRecvMsgSize(debugInfoMaxSize))
+	if err != nil {
+		return 0, errors.Wrap(err, "getting debug information")
+	}
+
+	value, ok := info.Config["acceptortimeout"]
+	if !ok {
+		return 0, errors.New("acceptortimeout not found in LND configuration")
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.Wrap(err, "parsing acceptortimeout")
+	}
+	return timeout, nil
+}
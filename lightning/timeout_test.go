@@ -0,0 +1,69 @@
+package lightning
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+type debugInfoClient struct {
+	Client
+	config map[string]string
+	err    error
+}
+
+func (c debugInfoClient) GetDebugInfo(
+	context.Context,
+	*lnrpc.GetDebugInfoRequest,
+	...grpc.CallOption,
+) (*lnrpc.GetDebugInfoResponse, error) {
+	return &lnrpc.GetDebugInfoResponse{Config: c.config}, c.err
+}
+
+func TestAcceptorTimeout(t *testing.T) {
+	cases := []struct {
+		desc     string
+		client   debugInfoClient
+		expected time.Duration
+		fail     bool
+	}{
+		{
+			desc:     "Valid",
+			client:   debugInfoClient{config: map[string]string{"acceptortimeout": "1m0s"}},
+			expected: time.Minute,
+		},
+		{
+			desc:   "Missing",
+			client: debugInfoClient{config: map[string]string{}},
+			fail:   true,
+		},
+		{
+			desc:   "Invalid",
+			client: debugInfoClient{config: map[string]string{"acceptortimeout": "15"}},
+			fail:   true,
+		},
+		{
+			desc:   "Permission denied",
+			client: debugInfoClient{err: errors.New("permission denied")},
+			fail:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			timeout, err := AcceptorTimeout(context.Background(), tc.client)
+			if tc.fail {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, timeout)
+		})
+	}
+}
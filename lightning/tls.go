@@ -0,0 +1,118 @@
+package lightning
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/aftermath2/acceptlnd/config"
+
+	"github.com/pkg/errors"
+)
+
+// loadTLSConfig returns the TLS configuration used to connect to LND.
+func loadTLSConfig(config config.Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if config.TLS.ClientCertificatePath != "" {
+		cert, err := tls.LoadX509KeyPair(config.TLS.ClientCertificatePath, config.TLS.ClientKeyPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "loading client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if config.TLS.InsecureSkipVerify {
+		slog.Warn("LND's TLS certificate will not be verified, the connection is vulnerable to " +
+			"man-in-the-middle attacks")
+		tlsConfig.InsecureSkipVerify = true
+		return tlsConfig, nil
+	}
+
+	verifier := &certVerifier{path: config.CertificatePath, system: config.TLS.UseSystemCerts}
+	if err := verifier.load(); err != nil {
+		return nil, err
+	}
+
+	// The verification is done by the verifier so the certificate can be reloaded if it fails
+	tlsConfig.InsecureSkipVerify = true
+	tlsConfig.VerifyConnection = verifier.verify
+	return tlsConfig, nil
+}
+
+// certVerifier verifies the certificate presented by LND. If the verification fails, the
+// certificate file is read again in case LND rotated it, before giving up.
+type certVerifier struct {
+	path   string
+	system bool
+
+	mu    sync.Mutex
+	roots *x509.CertPool
+}
+
+func (c *certVerifier) load() error {
+	roots := x509.NewCertPool()
+	if c.system {
+		systemRoots, err := x509.SystemCertPool()
+		if err != nil {
+			return errors.Wrap(err, "loading system certificates")
+		}
+		roots = systemRoots
+	}
+
+	if c.path != "" {
+		content, err := os.ReadFile(c.path)
+		if err != nil {
+			return errors.Wrap(err, "unable to read TLS certificate")
+		}
+		if !roots.AppendCertsFromPEM(content) {
+			return errors.New("unable to read TLS certificate: no valid certificates found")
+		}
+	}
+
+	c.mu.Lock()
+	c.roots = roots
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *certVerifier) verify(state tls.ConnectionState) error {
+	err := c.verifyWith(c.currentRoots(), state)
+	if err == nil || c.path == "" {
+		return err
+	}
+
+	slog.Warn("LND's TLS certificate verification failed, reloading the certificate",
+		slog.Any("error", err))
+	if err := c.load(); err != nil {
+		return err
+	}
+
+	return c.verifyWith(c.currentRoots(), state)
+}
+
+func (c *certVerifier) currentRoots() *x509.CertPool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.roots
+}
+
+func (c *certVerifier) verifyWith(roots *x509.CertPool, state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("no certificates presented")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		DNSName:       state.ServerName,
+	})
+	return err
+}
@@ -0,0 +1,140 @@
+package lightning
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCertVerifierReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tls.cert")
+	oldCert, oldPEM, _ := selfSignedCert(t)
+	newCert, newPEM, _ := selfSignedCert(t)
+	assert.NoError(t, os.WriteFile(path, oldPEM, 0o600))
+
+	verifier := &certVerifier{path: path}
+	assert.NoError(t, verifier.load())
+
+	state := func(cert *x509.Certificate) tls.ConnectionState {
+		return tls.ConnectionState{ServerName: "localhost", PeerCertificates: []*x509.Certificate{cert}}
+	}
+
+	assert.NoError(t, verifier.verify(state(oldCert)))
+	assert.Error(t, verifier.verify(state(newCert)))
+
+	// LND rotated its certificate
+	assert.NoError(t, os.WriteFile(path, newPEM, 0o600))
+	assert.NoError(t, verifier.verify(state(newCert)))
+	assert.Error(t, verifier.verify(state(oldCert)))
+
+	wrongHost := state(newCert)
+	wrongHost.ServerName = "example.com"
+	assert.Error(t, verifier.verify(wrongHost))
+
+	assert.Error(t, verifier.verify(tls.ConnectionState{}))
+}
+
+func TestLoadTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "tls.cert")
+	_, certPEM, _ := selfSignedCert(t)
+	assert.NoError(t, os.WriteFile(certPath, certPEM, 0o600))
+	invalidPath := filepath.Join(dir, "invalid.cert")
+	assert.NoError(t, os.WriteFile(invalidPath, []byte("invalid"), 0o600))
+
+	cases := []struct {
+		desc     string
+		config   config.Config
+		insecure bool
+		verify   bool
+		fail     bool
+	}{
+		{
+			desc:     "Certificate",
+			config:   config.Config{CertificatePath: certPath},
+			insecure: true,
+			verify:   true,
+		},
+		{
+			desc:     "Insecure",
+			config:   config.Config{TLS: config.TLS{InsecureSkipVerify: true}},
+			insecure: true,
+		},
+		{
+			desc:   "Invalid certificate",
+			config: config.Config{CertificatePath: invalidPath},
+			fail:   true,
+		},
+		{
+			desc:   "Missing certificate",
+			config: config.Config{CertificatePath: filepath.Join(dir, "none")},
+			fail:   true,
+		},
+		{
+			desc: "Invalid client certificate",
+			config: config.Config{
+				CertificatePath: certPath,
+				TLS:             config.TLS{ClientCertificatePath: invalidPath, ClientKeyPath: invalidPath},
+			},
+			fail: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tlsConfig, err := loadTLSConfig(tc.config)
+			if tc.fail {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.insecure, tlsConfig.InsecureSkipVerify)
+			assert.Equal(t, tc.verify, tlsConfig.VerifyConnection != nil)
+		})
+	}
+}
+
+// selfSignedCert returns a certificate valid for localhost, along with it and its private key
+// encoded in PEM.
+func selfSignedCert(t *testing.T) (*x509.Certificate, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{Organization: []string{"lnd autogenerated cert"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return cert, certPEM, keyPEM
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aftermath2/acceptlnd/lightning"
+	"github.com/aftermath2/acceptlnd/policy"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/pkg/errors"
+)
+
+// lndVersion is a release of LND, major, minor and patch.
+type lndVersion [3]int
+
+func (v lndVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+}
+
+func (v lndVersion) less(o lndVersion) bool {
+	return slices.Compare(v[:], o[:]) < 0
+}
+
+// lndFeature is a field of LND's responses that some policy requirements depend on, older
+// versions leave it unset and the requirements would evaluate its zero value.
+type lndFeature struct {
+	name  string
+	since lndVersion
+	uses  func(policies []*policy.Policy) bool
+}
+
+var lndFeatures = []lndFeature{
+	{name: "zero conf channels", since: lndVersion{0, 15, 0}, uses: usesZeroConf},
+	{name: "taproot channels", since: lndVersion{0, 17, 0}, uses: usesTaproot},
+	{name: "inbound fees", since: lndVersion{0, 18, 0}, uses: usesInboundFees},
+}
+
+// parseLNDVersion parses the version reported by LND, like "0.18.0-beta commit=v0.18.0-beta".
+func parseLNDVersion(s string) (lndVersion, error) {
+	release, _, _ := strings.Cut(s, " ")
+	release, _, _ = strings.Cut(release, "-")
+	parts := strings.Split(strings.TrimPrefix(release, "v"), ".")
+	if len(parts) != 3 {
+		return lndVersion{}, errors.Errorf("invalid LND version %q", s)
+	}
+
+	var v lndVersion
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return lndVersion{}, errors.Errorf("invalid LND version %q", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// checkLNDVersion warns about the features the policies depend on that the LND version connected
+// to lacks, or fails if strict is true.
+func checkLNDVersion(
+	ctx context.Context,
+	client lightning.Client,
+	policies []*policy.Policy,
+	strict bool,
+) error {
+	node, err := client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	if err != nil {
+		return errors.Wrap(err, "getting node information")
+	}
+	version, err := parseLNDVersion(node.Version)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, feature := range lndFeatures {
+		if !version.less(feature.since) || !feature.uses(policies) {
+			continue
+		}
+		slog.Warn("The LND version does not support a feature the policies depend on",
+			slog.String("lnd_version", version.String()),
+			slog.String("feature", feature.name),
+			slog.String("required_version", feature.since.String()),
+		)
+		missing = append(missing, feature.name)
+	}
+
+	if strict && len(missing) > 0 {
+		return errors.Errorf("LND %s does not support %s", version, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// usesZeroConf returns whether any policy accepts or matches zero conf channels.
+func usesZeroConf(policies []*policy.Policy) bool {
+	for _, p := range policies {
+		if p.AcceptZeroConfChannels != nil || p.ZeroConfList != nil || p.ZeroConfScidAlias != nil {
+			return true
+		}
+		if p.Conditions == nil {
+			continue
+		}
+		for _, c := range append([]*policy.Conditions{p.Conditions}, p.Conditions.Any...) {
+			if c.WantsZeroConf != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// usesTaproot returns whether any policy lists taproot channels in the commitment types.
+func usesTaproot(policies []*policy.Policy) bool {
+	for _, p := range policies {
+		requests := []*policy.Request{p.Request}
+		if p.Conditions != nil {
+			for _, c := range append([]*policy.Conditions{p.Conditions}, p.Conditions.Any...) {
+				requests = append(requests, c.Request)
+			}
+		}
+		for _, r := range requests {
+			if r != nil && r.CommitmentTypes != nil &&
+				slices.Contains(*r.CommitmentTypes, lnrpc.CommitmentType_SIMPLE_TAPROOT) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// usesInboundFees returns whether any policy has requirements on the channels inbound fees.
+func usesInboundFees(policies []*policy.Policy) bool {
+	return anyNode(policies, func(n *policy.Node) bool {
+		c := n.Channels
+		if c == nil {
+			return false
+		}
+		if c.InboundFeeRates != nil || c.InboundBaseFees != nil || c.InboundSignaled != nil {
+			return true
+		}
+		if c.Peers != nil && (c.Peers.InboundFeeRates != nil || c.Peers.InboundBaseFees != nil) {
+			return true
+		}
+		return c.TowardUs != nil && (c.TowardUs.InboundFeeRates != nil || c.TowardUs.InboundBaseFees != nil)
+	})
+}
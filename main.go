@@ -2,24 +2,47 @@ package main
 
 import (
 	"context"
-	"encoding/hex"
 	"flag"
 	"fmt"
 	"log/slog"
 	"os"
-	"runtime/debug"
+	"time"
 
+	"github.com/aftermath2/acceptlnd/asn"
+	"github.com/aftermath2/acceptlnd/chain"
 	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/hook"
 	"github.com/aftermath2/acceptlnd/lightning"
+	"github.com/aftermath2/acceptlnd/metrics"
+	"github.com/aftermath2/acceptlnd/proxy"
+	"github.com/aftermath2/acceptlnd/reachability"
+	"github.com/aftermath2/acceptlnd/registry"
+	"github.com/aftermath2/acceptlnd/server"
+	"github.com/aftermath2/acceptlnd/store"
+	"github.com/aftermath2/acceptlnd/syslog"
 
-	"github.com/lightningnetwork/lnd/lnrpc"
 	"github.com/pkg/errors"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				fatal(err)
+			}
+			return
+		}
+	}
+
 	configPath := flag.String("config", "acceptlnd.yml", "Path to the configuration file")
 	debug := flag.Bool("debug", false, "Enable debug level logging")
+	pretty := flag.Bool("pretty", false, "Render decisions as compact colored lines")
+	pprof := flag.Bool("pprof", false, "Expose profiling endpoints on the HTTP server")
 	version := flag.Bool("version", false, "Show version")
+	faults := flag.String("fault-inject", "", "Inject faults into the calls to LND, for manual testing")
+	deterministic := flag.Bool("deterministic", false,
+		"Derive the random choices from the seed and the request IDs, so replays produce identical outcomes")
+	flag.Usage = usage
 	flag.Parse()
 
 	if *version {
@@ -35,128 +58,378 @@ func main() {
 		AddSource: *debug,
 		Level:     level,
 	}
-	logger := slog.New(slog.NewTextHandler(os.Stdout, loggerOpts))
-	slog.SetDefault(logger)
-
-	config, err := config.Load(*configPath)
-	if err != nil {
-		fatal(err)
+	var handler slog.Handler = slog.NewTextHandler(os.Stdout, loggerOpts)
+	if *pretty {
+		handler = newPrettyHandler(os.Stdout, level)
 	}
+	slog.SetDefault(slog.New(correlationHandler{handler}))
 
-	client, err := lightning.NewClient(config)
+	err := runPlatform(loggerOpts, func(ctx context.Context, reload <-chan struct{}) error {
+		return run(ctx, reload, *configPath, *pprof, *deterministic, *faults)
+	})
 	if err != nil {
 		fatal(err)
 	}
+}
 
-	if err := handleChannelRequests(config, client); err != nil {
-		fatal(err)
-	}
+// hiddenFlags are not listed in the usage message.
+var hiddenFlags = map[string]bool{"fault-inject": true}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage of %s:\n", os.Args[0])
+	flag.VisitAll(func(f *flag.Flag) {
+		if hiddenFlags[f.Name] {
+			return
+		}
+		fmt.Fprintf(out, "  -%s\n    \t%s", f.Name, f.Usage)
+		if f.DefValue != "" && f.DefValue != "false" {
+			fmt.Fprintf(out, " (default %q)", f.DefValue)
+		}
+		fmt.Fprintln(out)
+	})
 }
 
-func fatal(err error) {
-	slog.Error(err.Error())
-	os.Exit(1)
+// commands maps subcommand names to their entry points, which receive the remaining arguments.
+var commands = map[string]func(args []string) error{
+	"backfill":     runBackfill,
+	"audit":        runAudit,
+	"bench":        runBench,
+	"check":        runValidate,
+	"demo":         runDemo,
+	"flush-queue":  runFlushQueue,
+	"graph":        runGraph,
+	"override":     runOverride,
+	"peer":         runPeer,
+	"print-config": runPrintConfig,
+	"report":       runReport,
+	"validate":     runValidate,
+	"why":          runWhy,
 }
 
-// handleChannelRequests listens to the ChannnelAcceptor RPC stream and accepts/rejects requests.
-func handleChannelRequests(config config.Config, client lightning.Client) error {
-	ctx := context.Background()
+// channelsMonitorInterval is how often the channels uptime, tags and reputation events are
+// recorded.
+const channelsMonitorInterval = 10 * time.Minute
 
-	stream, err := client.ChannelAcceptor(ctx)
+// graphMonitorInterval is how often the channel graph snapshot is refreshed.
+const graphMonitorInterval = 30 * time.Minute
+
+// run starts handling channel requests, or evaluating peers in watch-only mode, until the context
+// is cancelled. Every time a value is received on the reload channel, the configuration is read
+// again and the new policies replace the current ones.
+func run(
+	ctx context.Context,
+	reload <-chan struct{},
+	configPath string,
+	pprof bool,
+	deterministic bool,
+	faultSpec string,
+) error {
+	config, err := config.Load(configPath)
 	if err != nil {
-		return errors.Wrap(err, "subscribing to the channel acceptor stream")
+		return err
 	}
 
-	slog.Info("Listening for channel requests")
-	for {
-		req, err := stream.Recv()
+	// The servers and streams running in the background report their failures here and stop the
+	// rest, so the deferred cleanups run before returning
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	failures := make(chan error, 1)
+	fail := func(err error) {
+		select {
+		case failures <- err:
+		default:
+		}
+		cancel()
+	}
+
+	if config.Syslog != nil {
+		writer, err := syslog.Dial(*config.Syslog)
 		if err != nil {
-			return errors.Wrap(err, "receiving channel request")
+			return err
 		}
-		slog.Debug("Channel opening request", slog.Any("request", req))
+		defer writer.Close()
+		previous := slog.Default()
+		slog.SetDefault(slog.New(syslogHandler{previous.Handler(), writer}))
+		defer slog.SetDefault(previous)
+	}
 
-		resp, err := handleRequest(config, client, req)
+	if config.Statsd != nil {
+		statsd, err := metrics.NewStatsd(*config.Statsd)
 		if err != nil {
-			resp.Error = err.Error()
-		} else {
-			resp.Accept = true
+			return err
+		}
+		defer statsd.Close()
+		defer metrics.AddSink(statsd)()
+	}
+	if config.InfluxDB != nil {
+		client, err := proxy.HTTPClient(config.Proxy, metrics.InfluxTimeout)
+		if err != nil {
+			return err
 		}
+		influx := metrics.NewInfluxDB(*config.InfluxDB, client)
+		defer metrics.AddSink(influx)()
+		go influx.Run(ctx)
+	}
+
+	conn, err := lightning.NewClient(config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	go conn.MonitorState(ctx)
 
-		if err := stream.Send(resp); err != nil {
-			return errors.Wrap(err, "sending channel response")
+	if err := conn.WaitReady(ctx, config.Startup); err != nil {
+		if ctx.Err() != nil {
+			return nil
 		}
+		return err
+	}
 
-		logResponse(response{
-			accepted:  resp.Accept,
-			id:        hex.EncodeToString(req.PendingChanId),
-			publicKey: hex.EncodeToString(req.NodePubkey),
-			err:       resp.Error,
-		})
+	var db store.Storage
+	if config.HasDatabase() {
+		db, err = store.Open(config.DatabaseBackend, config.DatabasePath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
 	}
-}
 
-func handleRequest(
-	config config.Config,
-	client lightning.Client,
-	req *lnrpc.ChannelAcceptRequest,
-) (*lnrpc.ChannelAcceptResponse, error) {
-	ctx := context.Background()
-	resp := &lnrpc.ChannelAcceptResponse{Accept: false, PendingChanId: req.PendingChanId}
+	var client lightning.Client = conn
+	if faultSpec != "" {
+		faults, err := lightning.ParseFaults(faultSpec)
+		if err != nil {
+			return errors.Wrap(err, "parsing faults")
+		}
+		slog.Warn("Fault injection enabled, do not use in production", slog.String("faults", faultSpec))
+		seed := config.Seed
+		if seed == 0 && !deterministic {
+			seed = time.Now().UnixNano()
+		}
+		client = lightning.NewFaultClient(client, faults, seed)
+	}
 
-	node, err := client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
-	if err != nil {
-		return resp, errors.New("Internal server error")
+	if err := checkLNDVersion(ctx, client, config.AllPolicies(), config.StrictLNDVersion); err != nil {
+		if config.StrictLNDVersion {
+			return err
+		}
+		slog.Warn("Checking LND version", slog.Any("error", err))
 	}
 
-	getPeerInfoReq := &lnrpc.NodeInfoRequest{
-		PubKey:          hex.EncodeToString(req.NodePubkey),
-		IncludeChannels: true,
+	acceptor := newAcceptor(client, db, config)
+	if deterministic {
+		slog.Info("Deterministic mode enabled", slog.Int64("seed", config.Seed))
+		acceptor.random = newRandomness(config.Seed, true)
 	}
-	peer, err := client.GetNodeInfo(ctx, getPeerInfoReq)
+	dialer, err := proxy.Dialer(config.Proxy)
 	if err != nil {
-		return resp, errors.New("Internal server error")
+		return err
 	}
-	slog.Debug("Peer node information", slog.Any("node", peer))
-
-	for _, policy := range config.Policies {
-		if err := policy.Evaluate(req, resp, node, peer); err != nil {
-			return resp, err
+	acceptor.reachability = reachability.New(config.Reachability, dialer)
+	if config.RegistryUpdate != nil {
+		client, err := proxy.HTTPClient(config.Proxy, registry.DefaultTimeout)
+		if err != nil {
+			return err
+		}
+		updater, err := registry.NewUpdater(*config.RegistryUpdate, client, acceptor.registry)
+		if err != nil {
+			return err
+		}
+		if err := updater.LoadCache(); err != nil {
+			slog.Warn("Loading registry cache", slog.Any("error", err))
+		}
+		go updater.Run(ctx)
+	}
+	if config.Webhook != nil || config.Notify != nil {
+		acceptor.webhook, err = newWebhook(config, db)
+		if err != nil {
+			return err
 		}
+		go acceptor.webhook.Run(ctx)
+	}
+	if config.AcceptHook != nil {
+		acceptor.acceptHook = hook.New(*config.AcceptHook)
+	}
+	if config.TorExitListPath != "" {
+		acceptor.torExits, err = loadTorExits(config.TorExitListPath)
+		if err != nil {
+			return err
+		}
+	}
+	if config.ASNDatabasePath != "" {
+		acceptor.asns, err = asn.Load(config.ASNDatabasePath)
+		if err != nil {
+			return err
+		}
+		slog.Info("Loaded ASN database", slog.Int("ranges", acceptor.asns.Len()))
+	}
+	if config.Chain != nil {
+		acceptor.chain, err = chain.New(*config.Chain)
+		if err != nil {
+			return err
+		}
+		go func() {
+			if err := acceptor.chain.ListenAndServe(); err != nil {
+				fail(errors.Wrap(err, "chain server"))
+			}
+		}()
+		defer acceptor.chain.Stop()
+	}
+	var watch *watcher
+	if config.WatchOnly != nil {
+		watch = newWatcher(acceptor, *config.WatchOnly)
 	}
 
-	return resp, nil
-}
+	if config.HTTPAddress != "" {
+		srv := server.New(config.HTTPAddress)
+		if pprof {
+			srv.EnablePprof()
+		}
+		srv.Handle("GET /metrics", metrics.Handler())
+		srv.Handle("GET /blocklist", server.Lines(func() ([]string, error) {
+			return acceptor.blocklist(), nil
+		}))
+		srv.Handle("GET /health", server.Health(func() (string, bool) {
+			return conn.State().String(), conn.Ready()
+		}))
+		srv.Handle("GET /stats", server.JSON(func() (any, error) {
+			return acceptor.getStats(conn.State().String()), nil
+		}))
+		srv.Handle("GET /properties", server.Properties(func() ([]server.Property, error) {
+			return acceptor.getStats(conn.State().String()).properties(), nil
+		}))
+		if db != nil {
+			srv.Handle("GET /channels/tags", server.JSON(func() (any, error) {
+				return db.Tags()
+			}))
+		}
+		if watch != nil {
+			srv.Handle("GET /watch/decisions", server.JSON(func() (any, error) {
+				return watch.getDecisions(), nil
+			}))
+		}
 
-type response struct {
-	id        string
-	publicKey string
-	err       string
-	accepted  bool
-}
+		go func() {
+			if err := srv.ListenAndServe(); err != nil {
+				fail(errors.Wrap(err, "HTTP server"))
+			}
+		}()
+		defer srv.Shutdown(context.Background())
+	} else if pprof {
+		return errors.New("profiling requires an HTTP address to be configured")
+	}
 
-func logResponse(res response) {
-	args := []any{
-		slog.Bool("accepted", res.accepted),
-		slog.String("id", res.id),
-		slog.String("public_key", res.publicKey),
+	if db != nil {
+		go acceptor.monitorChannels(ctx, channelsMonitorInterval)
+	}
+	if config.Middleware != nil {
+		go func() {
+			if err := acceptor.runMiddleware(ctx, *config.Middleware); err != nil {
+				fail(errors.Wrap(err, "RPC middleware"))
+			}
+		}()
 	}
-	if !res.accepted {
-		args = append(args, slog.String("error", res.err))
+	if config.HTLCInterceptor != nil {
+		go func() {
+			if err := acceptor.interceptHTLCs(ctx, *config.HTLCInterceptor); err != nil {
+				fail(errors.Wrap(err, "HTLC interceptor"))
+			}
+		}()
+	}
+	if config.Precompute != nil {
+		go acceptor.precomputePeers(ctx, *config.Precompute)
+		go acceptor.invalidatePeers(ctx)
+	}
+	if export := config.BlocklistExport; export != nil {
+		go acceptor.exportBlocklist(ctx, export.Path, export.Interval)
+	}
+
+	changes := make(chan struct{}, 1)
+	if config.ReloadOnChange {
+		if err := watchConfig(ctx, configPath, changes); err != nil {
+			return err
+		}
 	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-reload:
+				reloadPolicies(acceptor, configPath)
+			case <-changes:
+				slog.Info("Configuration file changed")
+				reloadPolicies(acceptor, configPath)
+			}
+		}
+	}()
 
-	slog.Info("New request received", args...)
+	if watch != nil {
+		err = watch.run(ctx)
+	} else {
+		go acceptor.monitorGraph(ctx, graphMonitorInterval)
+		go acceptor.refreshNodeInfo(ctx)
+		if config.Resubscribe != nil {
+			err = acceptor.serveChannelRequests(ctx, func(ctx context.Context) error {
+				return conn.WaitReady(ctx, config.Startup)
+			})
+		} else {
+			err = acceptor.handleChannelRequests(ctx)
+		}
+	}
+	slog.Info("Shutting down")
+	select {
+	case failure := <-failures:
+		return failure
+	default:
+		return err
+	}
 }
 
-func printVersion() {
-	bi, _ := debug.ReadBuildInfo()
+// reloadPolicies reads the configuration file again and replaces the policies enforced, keeping
+// the current ones if the new configuration is invalid.
+func reloadPolicies(acceptor *acceptor, configPath string) {
+	if configPath == "-" {
+		slog.Warn("The configuration cannot be reloaded when it's read from the standard input")
+		return
+	}
+
+	slog.Info("Reloading configuration")
+	config, err := config.Load(configPath)
+	if err != nil {
+		slog.Error("Reloading configuration, keeping the current policies", slog.Any("error", err))
+		return
+	}
 
-	var commit string
-	for _, s := range bi.Settings {
-		if s.Key == "vcs.revision" {
-			commit = s.Value
-			break
+	err = checkLNDVersion(context.Background(), acceptor.client, config.AllPolicies(), config.StrictLNDVersion)
+	if err != nil {
+		if config.StrictLNDVersion {
+			slog.Error("Reloading configuration, keeping the current policies", slog.Any("error", err))
+			return
 		}
+		slog.Warn("Checking LND version", slog.Any("error", err))
 	}
 
-	fmt.Println("AcceptLND", bi.Main.Version, commit)
+	acceptor.setPolicies(config.Policies, config.SelfServices, config.Experiment)
+	acceptor.registry.SetLocal(config.Registry)
+	slog.Info("Policies reloaded", slog.Int("policies", len(config.Policies)))
+}
+
+// watchConfig notifies the channel every time the configuration file changes.
+func watchConfig(ctx context.Context, configPath string, changes chan<- struct{}) error {
+	watcher, err := config.NewWatcher(configPath)
+	if err != nil {
+		return err
+	}
+	go watcher.Run(ctx, func() { notify(changes) })
+	return nil
+}
+
+func fatal(err error) {
+	slog.Error(err.Error())
+	os.Exit(1)
+}
+
+func printVersion() {
+	fmt.Println("AcceptLND", version())
 }
@@ -0,0 +1,219 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+
+	"github.com/pkg/errors"
+)
+
+// Defaults used when the values are not configured.
+const (
+	DefaultInfluxInterval = 10 * time.Second
+	// InfluxTimeout is the time a write can take.
+	InfluxTimeout = 10 * time.Second
+)
+
+var (
+	// measurementReplacer escapes the measurement names in the line protocol.
+	measurementReplacer = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\n`)
+	// tagReplacer escapes the tag keys and values in the line protocol.
+	tagReplacer = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+)
+
+// series is the state of a metric with a set of labels, written on every interval.
+type series struct {
+	// value is the total of a counter or the last value of a gauge.
+	value float64
+	// count and sum summarize the samples observed.
+	count    uint64
+	sum      float64
+	observed bool
+}
+
+// InfluxDB aggregates the measurements and writes them to InfluxDB using the line protocol. The
+// counters are written as their totals since the start, like Prometheus does.
+type InfluxDB struct {
+	client   *http.Client
+	url      string
+	token    string
+	interval time.Duration
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// NewInfluxDB returns a sink writing the measurements to the bucket configured through the v2
+// write API, which InfluxDB 1.8 supports as well.
+func NewInfluxDB(config config.InfluxDB, client *http.Client) *InfluxDB {
+	query := url.Values{}
+	query.Set("bucket", config.Bucket)
+	query.Set("precision", "s")
+	if config.Org != "" {
+		query.Set("org", config.Org)
+	}
+
+	interval := config.Interval
+	if interval == 0 {
+		interval = DefaultInfluxInterval
+	}
+
+	return &InfluxDB{
+		client:   client,
+		url:      strings.TrimSuffix(config.URL, "/") + "/api/v2/write?" + query.Encode(),
+		token:    config.Token,
+		interval: interval,
+		series:   make(map[string]*series),
+	}
+}
+
+// Count adds the value to the counter total.
+func (i *InfluxDB) Count(name string, labels []Label, value float64) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.get(name, labels).value += value
+}
+
+// Gauge sets the value of the gauge.
+func (i *InfluxDB) Gauge(name string, labels []Label, value float64) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.get(name, labels).value = value
+}
+
+// Observe adds the sample to the count and sum of the distribution.
+func (i *InfluxDB) Observe(name string, labels []Label, value float64) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	s := i.get(name, labels)
+	s.observed = true
+	s.count++
+	s.sum += value
+}
+
+// get returns the series of the metric with the labels, identified by its line protocol key. It
+// must be called with the lock held.
+func (i *InfluxDB) get(name string, labels []Label) *series {
+	key := seriesKey(name, labels)
+	s, ok := i.series[key]
+	if !ok {
+		s = &series{}
+		i.series[key] = s
+	}
+	return s
+}
+
+// Run writes the measurements every interval until the context is cancelled, when they are written
+// one last time.
+func (i *InfluxDB) Run(ctx context.Context) {
+	ticker := time.NewTicker(i.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			ctx, cancel := context.WithTimeout(context.Background(), InfluxTimeout)
+			defer cancel()
+			if err := i.Write(ctx, time.Now()); err != nil {
+				slog.Warn("Writing metrics to InfluxDB", slog.Any("error", err))
+			}
+			return
+		case now := <-ticker.C:
+			if err := i.Write(ctx, now); err != nil && ctx.Err() == nil {
+				slog.Warn("Writing metrics to InfluxDB", slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// Write sends the current value of every series, timestamped at the time given.
+func (i *InfluxDB) Write(ctx context.Context, now time.Time) error {
+	body := i.lines(now)
+	if len(body) == 0 {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if i.token != "" {
+		req.Header.Set("Authorization", "Token "+i.token)
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "sending request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return errors.Errorf("unexpected status code %d: %s", resp.StatusCode, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+// lines formats the series in the line protocol, sorted so the writes are deterministic.
+func (i *InfluxDB) lines(now time.Time) []byte {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	keys := make([]string, 0, len(i.series))
+	for key := range i.series {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	var b bytes.Buffer
+	for _, key := range keys {
+		s := i.series[key]
+		b.WriteString(key)
+		if s.observed {
+			fmt.Fprintf(&b, " count=%di,sum=%s ", s.count, formatFloat(s.sum))
+		} else {
+			fmt.Fprintf(&b, " value=%s ", formatFloat(s.value))
+		}
+		b.WriteString(timestamp)
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// seriesKey returns the measurement and the tags sorted by key, as InfluxDB recommends.
+func seriesKey(name string, labels []Label) string {
+	sorted := slices.Clone(labels)
+	slices.SortFunc(sorted, func(a, b Label) int { return strings.Compare(a.Name, b.Name) })
+
+	var b strings.Builder
+	b.WriteString(measurementReplacer.Replace(name))
+	for _, label := range sorted {
+		// Empty tag values are not allowed
+		if label.Value == "" {
+			continue
+		}
+		b.WriteByte(',')
+		b.WriteString(tagReplacer.Replace(label.Name))
+		b.WriteByte('=')
+		b.WriteString(tagReplacer.Replace(label.Value))
+	}
+	return b.String()
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInfluxDB(t *testing.T) {
+	var body, query, authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := io.ReadAll(r.Body)
+		body = string(content)
+		query = r.URL.RawQuery
+		authorization = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	influx := NewInfluxDB(config.InfluxDB{
+		URL:    server.URL + "/",
+		Org:    "home",
+		Bucket: "lightning",
+		Token:  "secret",
+	}, server.Client())
+
+	now := time.Unix(1700000000, 0)
+	assert.NoError(t, influx.Write(context.Background(), now))
+	assert.Empty(t, body, "nothing is written without measurements")
+
+	influx.Count(decisionsName, []Label{{"decision", "accepted"}}, 1)
+	influx.Count(decisionsName, []Label{{"decision", "accepted"}}, 1)
+	influx.Gauge(watchDecisionsName, []Label{{"decision", "rejected"}}, 3)
+	influx.Observe(responseDurationName, nil, 0.5)
+	influx.Observe(responseDurationName, nil, 1)
+	influx.Count(warningsName, []Label{{"policy", "lsp customers, tier=1"}}, 1)
+
+	assert.NoError(t, influx.Write(context.Background(), now))
+	assert.Equal(t, "bucket=lightning&org=home&precision=s", query)
+	assert.Equal(t, "Token secret", authorization)
+	expected := `acceptlnd_decisions_total,decision=accepted value=2 1700000000
+acceptlnd_policy_warnings_total,policy=lsp\ customers\,\ tier\=1 value=1 1700000000
+acceptlnd_response_duration_seconds count=2i,sum=1.5 1700000000
+acceptlnd_watch_decisions,decision=rejected value=3 1700000000
+`
+	assert.Equal(t, expected, body)
+}
+
+func TestInfluxDBError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"bucket not found"}`, http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	influx := NewInfluxDB(config.InfluxDB{URL: server.URL, Bucket: "missing"}, server.Client())
+	influx.Count(overflowsName, nil, 1)
+	err := influx.Write(context.Background(), time.Now())
+	assert.ErrorContains(t, err, "unexpected status code 404")
+	assert.ErrorContains(t, err, "bucket not found")
+}
@@ -0,0 +1,324 @@
+// Package metrics collects acceptLND's metrics, exposed to Prometheus and optionally sent to other
+// monitoring systems through sinks.
+package metrics
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "acceptlnd"
+
+var (
+	registry = prometheus.NewRegistry()
+
+	rpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "lnd",
+		Name:      "rpc_duration_seconds",
+		Help:      "Duration of the calls made to LND by method and status code.",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"method", "code"})
+
+	streamMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "lnd",
+		Name:      "stream_messages_total",
+		Help:      "Number of messages exchanged with LND in streams by method and direction.",
+	}, []string{"method", "direction"})
+
+	connected = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "lnd",
+		Name:      "connected",
+		Help:      "Whether the connection with LND is ready (1) or not (0).",
+	})
+
+	evaluations = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "evaluations_in_flight",
+		Help:      "Number of channel requests being evaluated.",
+	})
+
+	overflows = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "evaluation_overflows_total",
+		Help:      "Number of channel requests rejected because too many were being evaluated.",
+	})
+
+	floodBlocks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "flood_blocks_total",
+		Help:      "Number of nodes and funding amounts blocked for sending too many channel requests.",
+	}, []string{"kind"})
+
+	autoBlocks = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auto_blocks_total",
+		Help:      "Number of nodes blocked automatically after repeated rejections.",
+	})
+
+	decisions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "decisions_total",
+		Help:      "Number of channel requests accepted and rejected.",
+	}, []string{"decision"})
+
+	decisionTags = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "decision_tags_total",
+		Help:      "Number of channel requests decided by policies with each tag.",
+	}, []string{"tag", "decision"})
+
+	warnings = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "policy_warnings_total",
+		Help:      "Number of violations of requirements with the warn severity, which don't reject requests.",
+	}, []string{"policy"})
+
+	experimentDecisions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "experiment_decisions_total",
+		Help:      "Number of channel requests evaluated by an experiment, by the verdicts of the policies and the experimental ones.",
+	}, []string{"experiment", "policies", "variant"})
+
+	failedHTLCs = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "htlcs_failed_total",
+		Help:      "Number of HTLCs forwarded by blocked peers that were failed by the interceptor.",
+	})
+
+	precomputedLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "precomputed_peer_lookups_total",
+		Help:      "Number of channel requests whose peer information was precomputed (hit) or requested to LND (miss).",
+	}, []string{"result"})
+
+	precomputedInvalidations = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "precomputed_peer_invalidations_total",
+		Help:      "Number of precomputed peers discarded because the graph updates showed their information changed.",
+	})
+
+	responseDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "response_duration_seconds",
+		Help:      "Time from receiving a channel request to sending its response, excluding tarpit delays.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 15, 30},
+	})
+
+	sloBreaches = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "response_slo_breaches_total",
+		Help:      "Number of channel requests answered slower than the response SLO target.",
+	})
+
+	graphSyncAge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "graph_sync_age_seconds",
+		Help:      "Seconds elapsed since LND was last seen synced to the channel graph.",
+	})
+
+	unmanagedChannels = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "unmanaged_channels_total",
+		Help:      "Number of channels peers opened while acceptLND was not connected to LND.",
+	})
+
+	watchDecisions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "watch_decisions",
+		Help:      "Number of peers that would be accepted and rejected in watch-only mode.",
+	}, []string{"decision"})
+)
+
+// Metrics names, shared by all the sinks.
+const (
+	rpcDurationName              = "acceptlnd_lnd_rpc_duration_seconds"
+	streamMessagesName           = "acceptlnd_lnd_stream_messages_total"
+	connectedName                = "acceptlnd_lnd_connected"
+	evaluationsName              = "acceptlnd_evaluations_in_flight"
+	overflowsName                = "acceptlnd_evaluation_overflows_total"
+	floodBlocksName              = "acceptlnd_flood_blocks_total"
+	autoBlocksName               = "acceptlnd_auto_blocks_total"
+	decisionsName                = "acceptlnd_decisions_total"
+	decisionTagsName             = "acceptlnd_decision_tags_total"
+	warningsName                 = "acceptlnd_policy_warnings_total"
+	experimentDecisionsName      = "acceptlnd_experiment_decisions_total"
+	failedHTLCsName              = "acceptlnd_htlcs_failed_total"
+	precomputedLookupsName       = "acceptlnd_precomputed_peer_lookups_total"
+	precomputedInvalidationsName = "acceptlnd_precomputed_peer_invalidations_total"
+	responseDurationName         = "acceptlnd_response_duration_seconds"
+	sloBreachesName              = "acceptlnd_response_slo_breaches_total"
+	graphSyncAgeName             = "acceptlnd_graph_sync_age_seconds"
+	unmanagedChannelsName        = "acceptlnd_unmanaged_channels_total"
+	watchDecisionsName           = "acceptlnd_watch_decisions"
+)
+
+// byName maps the metrics names to the Prometheus collectors updated by the default sink.
+var byName = map[string]prometheus.Collector{
+	rpcDurationName:              rpcDuration,
+	streamMessagesName:           streamMessages,
+	connectedName:                connected,
+	evaluationsName:              evaluations,
+	overflowsName:                overflows,
+	floodBlocksName:              floodBlocks,
+	autoBlocksName:               autoBlocks,
+	decisionsName:                decisions,
+	decisionTagsName:             decisionTags,
+	warningsName:                 warnings,
+	experimentDecisionsName:      experimentDecisions,
+	failedHTLCsName:              failedHTLCs,
+	precomputedLookupsName:       precomputedLookups,
+	precomputedInvalidationsName: precomputedInvalidations,
+	responseDurationName:         responseDuration,
+	sloBreachesName:              sloBreaches,
+	graphSyncAgeName:             graphSyncAge,
+	unmanagedChannelsName:        unmanagedChannels,
+	watchDecisionsName:           watchDecisions,
+}
+
+// inFlight is the number of channel requests being evaluated, sent to the sinks as a gauge.
+var inFlight atomic.Int64
+
+func init() {
+	for _, collector := range byName {
+		registry.MustRegister(collector)
+	}
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler returns the handler that exposes the metrics in the Prometheus format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRPC records the duration and outcome of a call made to LND.
+func ObserveRPC(method, code string, duration time.Duration) {
+	observe(rpcDurationName, duration.Seconds(), Label{"method", method}, Label{"code", code})
+}
+
+// CountStreamMessage records a message sent or received in a stream with LND.
+func CountStreamMessage(method string, sent bool) {
+	direction := "received"
+	if sent {
+		direction = "sent"
+	}
+	count(streamMessagesName, 1, Label{"method", method}, Label{"direction", direction})
+}
+
+// EvaluationStarted records the start of a channel request evaluation.
+func EvaluationStarted() {
+	gauge(evaluationsName, float64(inFlight.Add(1)))
+}
+
+// EvaluationFinished records the end of a channel request evaluation.
+func EvaluationFinished() {
+	gauge(evaluationsName, float64(inFlight.Add(-1)))
+}
+
+// CountOverflow records a channel request rejected because too many were being evaluated.
+func CountOverflow() {
+	count(overflowsName, 1)
+}
+
+// CountFloodBlock records a node or funding amount blocked by the flood protection.
+func CountFloodBlock(kind string) {
+	count(floodBlocksName, 1, Label{"kind", kind})
+}
+
+// CountAutoBlock records a node blocked after repeated rejections.
+func CountAutoBlock() {
+	count(autoBlocksName, 1)
+}
+
+// CountDecision records a channel request decision and the tags of the policies involved.
+func CountDecision(accepted bool, tags []string) {
+	decision := verdict(accepted)
+	count(decisionsName, 1, Label{"decision", decision})
+	for _, tag := range tags {
+		count(decisionTagsName, 1, Label{"tag", tag}, Label{"decision", decision})
+	}
+}
+
+// CountWarning records a violation of a requirement with the warn severity.
+func CountWarning(policy string) {
+	count(warningsName, 1, Label{"policy", policy})
+}
+
+// CountExperimentDecision records the verdicts of the policies and the experimental ones for a
+// channel request included in an experiment.
+func CountExperimentDecision(experiment string, accepted, variantAccepted bool) {
+	count(experimentDecisionsName, 1, Label{"experiment", experiment},
+		Label{"policies", verdict(accepted)}, Label{"variant", verdict(variantAccepted)})
+}
+
+// CountFailedHTLC records an HTLC of a blocked peer failed by the interceptor.
+func CountFailedHTLC() {
+	count(failedHTLCsName, 1)
+}
+
+// CountPrecomputedLookup records whether the information of a peer requesting a channel was
+// precomputed.
+func CountPrecomputedLookup(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	count(precomputedLookupsName, 1, Label{"result", result})
+}
+
+// CountPrecomputedInvalidation records a precomputed peer discarded after a graph update.
+func CountPrecomputedInvalidation() {
+	count(precomputedInvalidationsName, 1)
+}
+
+// ObserveResponse records the time taken to respond to a channel request.
+func ObserveResponse(duration time.Duration) {
+	observe(responseDurationName, duration.Seconds())
+}
+
+// CountSLOBreach records a channel request answered slower than the response SLO target.
+func CountSLOBreach() {
+	count(sloBreachesName, 1)
+}
+
+// SetConnected records whether the connection with LND is ready.
+func SetConnected(ready bool) {
+	value := 0.0
+	if ready {
+		value = 1
+	}
+	gauge(connectedName, value)
+}
+
+// SetGraphSyncAge records the time elapsed since LND was last synced to the channel graph.
+func SetGraphSyncAge(age time.Duration) {
+	gauge(graphSyncAgeName, age.Seconds())
+}
+
+// CountUnmanagedChannel records a channel opened without a decision.
+func CountUnmanagedChannel() {
+	count(unmanagedChannelsName, 1)
+}
+
+// SetWatchDecisions records the result of the last watch-only evaluation.
+func SetWatchDecisions(accepted, rejected int) {
+	gauge(watchDecisionsName, float64(accepted), Label{"decision", "accepted"})
+	gauge(watchDecisionsName, float64(rejected), Label{"decision", "rejected"})
+}
+
+func verdict(accepted bool) string {
+	if accepted {
+		return "accepted"
+	}
+	return "rejected"
+}
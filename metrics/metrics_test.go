@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	ObserveRPC("GetInfo", "OK", 30*time.Millisecond)
+	CountStreamMessage("ChannelAcceptor", true)
+	CountStreamMessage("ChannelAcceptor", false)
+	CountDecision(true, []string{"lsp"})
+	CountDecision(false, []string{"lsp", "strict"})
+	SetWatchDecisions(3, 2)
+	SetGraphSyncAge(90 * time.Second)
+	SetConnected(true)
+	CountExperimentDecision("strict", true, false)
+	CountFailedHTLC()
+	ObserveResponse(2 * time.Second)
+	CountSLOBreach()
+	CountPrecomputedLookup(true)
+	CountPrecomputedInvalidation()
+	CountAutoBlock()
+	CountUnmanagedChannel()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	body := rec.Body.String()
+	assert.Contains(t, body, `acceptlnd_lnd_rpc_duration_seconds_count{code="OK",method="GetInfo"} 1`)
+	assert.Contains(t, body, `acceptlnd_lnd_stream_messages_total{direction="sent",method="ChannelAcceptor"} 1`)
+	assert.Contains(t, body, `acceptlnd_lnd_stream_messages_total{direction="received",method="ChannelAcceptor"} 1`)
+	assert.Contains(t, body, `acceptlnd_decisions_total{decision="rejected"} 1`)
+	assert.Contains(t, body, `acceptlnd_decision_tags_total{decision="accepted",tag="lsp"} 1`)
+	assert.Contains(t, body, `acceptlnd_decision_tags_total{decision="rejected",tag="strict"} 1`)
+	assert.Contains(t, body, `acceptlnd_watch_decisions{decision="accepted"} 3`)
+	assert.Contains(t, body, "acceptlnd_graph_sync_age_seconds 90")
+	assert.Contains(t, body, "acceptlnd_lnd_connected 1")
+	assert.Contains(t, body,
+		`acceptlnd_experiment_decisions_total{experiment="strict",policies="accepted",variant="rejected"} 1`)
+	assert.Contains(t, body, "acceptlnd_htlcs_failed_total 1")
+	assert.Contains(t, body, "acceptlnd_response_duration_seconds_count 1")
+	assert.Contains(t, body, "acceptlnd_response_slo_breaches_total 1")
+	assert.Contains(t, body, `acceptlnd_precomputed_peer_lookups_total{result="hit"} 1`)
+	assert.Contains(t, body, "acceptlnd_precomputed_peer_invalidations_total 1")
+	assert.Contains(t, body, "acceptlnd_auto_blocks_total 1")
+	assert.Contains(t, body, "acceptlnd_unmanaged_channels_total 1")
+	assert.Contains(t, body, "go_goroutines")
+}
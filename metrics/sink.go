@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Label is a dimension of a measurement.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sink receives the measurements recorded. The names are the ones of the Prometheus metrics.
+// Implementations must be safe for concurrent use and must not block, as they are called while
+// the channel requests are evaluated.
+type Sink interface {
+	// Count adds the value to a counter.
+	Count(name string, labels []Label, value float64)
+	// Gauge sets the value of a gauge.
+	Gauge(name string, labels []Label, value float64)
+	// Observe records a sample of a distribution, like a duration in seconds.
+	Observe(name string, labels []Label, value float64)
+}
+
+var (
+	sinksMu sync.RWMutex
+	sinks   = []Sink{prometheusSink{}}
+)
+
+// AddSink sends the measurements recorded from now on to the sink as well, until the function
+// returned is called.
+func AddSink(sink Sink) (remove func()) {
+	sinksMu.Lock()
+	sinks = append(slices.Clip(sinks), sink)
+	sinksMu.Unlock()
+
+	return func() {
+		sinksMu.Lock()
+		defer sinksMu.Unlock()
+		if i := slices.Index(sinks, sink); i >= 0 {
+			sinks = slices.Delete(slices.Clone(sinks), i, i+1)
+		}
+	}
+}
+
+func count(name string, value float64, labels ...Label) {
+	sinksMu.RLock()
+	defer sinksMu.RUnlock()
+	for _, sink := range sinks {
+		sink.Count(name, labels, value)
+	}
+}
+
+func gauge(name string, value float64, labels ...Label) {
+	sinksMu.RLock()
+	defer sinksMu.RUnlock()
+	for _, sink := range sinks {
+		sink.Gauge(name, labels, value)
+	}
+}
+
+func observe(name string, value float64, labels ...Label) {
+	sinksMu.RLock()
+	defer sinksMu.RUnlock()
+	for _, sink := range sinks {
+		sink.Observe(name, labels, value)
+	}
+}
+
+// prometheusSink updates the collectors exposed by Handler.
+type prometheusSink struct{}
+
+func (prometheusSink) Count(name string, labels []Label, value float64) {
+	switch c := byName[name].(type) {
+	case *prometheus.CounterVec:
+		c.With(prometheusLabels(labels)).Add(value)
+	case prometheus.Counter:
+		c.Add(value)
+	}
+}
+
+func (prometheusSink) Gauge(name string, labels []Label, value float64) {
+	switch c := byName[name].(type) {
+	case *prometheus.GaugeVec:
+		c.With(prometheusLabels(labels)).Set(value)
+	case prometheus.Gauge:
+		c.Set(value)
+	}
+}
+
+func (prometheusSink) Observe(name string, labels []Label, value float64) {
+	switch c := byName[name].(type) {
+	case *prometheus.HistogramVec:
+		c.With(prometheusLabels(labels)).Observe(value)
+	case prometheus.Histogram:
+		c.Observe(value)
+	}
+}
+
+func prometheusLabels(labels []Label) prometheus.Labels {
+	values := make(prometheus.Labels, len(labels))
+	for _, label := range labels {
+		values[label.Name] = label.Value
+	}
+	return values
+}
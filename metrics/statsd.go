@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/aftermath2/acceptlnd/config"
+
+	"github.com/pkg/errors"
+)
+
+// statsdReplacer replaces the characters with a meaning in the statsd protocol, which can't be
+// part of the names nor the labels.
+var statsdReplacer = strings.NewReplacer(":", "_", "|", "_", "@", "_", "#", "_", ",", "_", " ", "_",
+	"\n", "_")
+
+// Statsd sends the measurements to a statsd server over UDP as they are recorded, the server is
+// the one aggregating them.
+type Statsd struct {
+	conn  net.Conn
+	plain bool
+}
+
+// NewStatsd returns a sink sending the measurements to the server configured.
+func NewStatsd(config config.Statsd) (*Statsd, error) {
+	conn, err := net.Dial("udp", config.Address)
+	if err != nil {
+		return nil, errors.Wrap(err, "connecting to statsd")
+	}
+	return &Statsd{conn: conn, plain: config.Format == "plain"}, nil
+}
+
+// Count sends the value as a counter increment.
+func (s *Statsd) Count(name string, labels []Label, value float64) {
+	s.send(name, labels, value, "c")
+}
+
+// Gauge sends the value of the gauge. The gauges recorded are never negative, which statsd would
+// take as a decrement.
+func (s *Statsd) Gauge(name string, labels []Label, value float64) {
+	s.send(name, labels, value, "g")
+}
+
+// Observe sends the durations, in seconds, as timers in milliseconds.
+func (s *Statsd) Observe(name string, labels []Label, value float64) {
+	s.send(strings.TrimSuffix(name, "_seconds"), labels, value*1000, "ms")
+}
+
+// Close closes the connection.
+func (s *Statsd) Close() error {
+	return s.conn.Close()
+}
+
+func (s *Statsd) send(name string, labels []Label, value float64, kind string) {
+	if _, err := s.conn.Write(s.line(name, labels, value, kind)); err != nil {
+		slog.Debug("Sending metric to statsd", slog.String("name", name), slog.Any("error", err))
+	}
+}
+
+// line formats the measurement. The labels are sent as DogStatsD tags or, in the plain format,
+// their values are appended to the name.
+func (s *Statsd) line(name string, labels []Label, value float64, kind string) []byte {
+	var b strings.Builder
+	b.WriteString(statsdReplacer.Replace(name))
+	if s.plain {
+		for _, label := range labels {
+			b.WriteByte('.')
+			b.WriteString(strings.ReplaceAll(statsdReplacer.Replace(label.Value), ".", "_"))
+		}
+	}
+	b.WriteByte(':')
+	b.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+	b.WriteByte('|')
+	b.WriteString(kind)
+	if !s.plain && len(labels) > 0 {
+		b.WriteString("|#")
+		for i, label := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(statsdReplacer.Replace(label.Name))
+			b.WriteByte(':')
+			b.WriteString(statsdReplacer.Replace(label.Value))
+		}
+	}
+	return []byte(b.String())
+}
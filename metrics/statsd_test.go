@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsdLine(t *testing.T) {
+	labels := []Label{{"tag", "lsp customers"}, {"decision", "accepted"}}
+	cases := []struct {
+		desc     string
+		kind     string
+		expected string
+		value    float64
+		plain    bool
+	}{
+		{
+			desc:     "DogStatsD",
+			kind:     "c",
+			value:    1,
+			expected: "acceptlnd_decision_tags_total:1|c|#tag:lsp_customers,decision:accepted",
+		},
+		{
+			desc:     "Plain",
+			kind:     "c",
+			value:    1,
+			plain:    true,
+			expected: "acceptlnd_decision_tags_total.lsp_customers.accepted:1|c",
+		},
+		{
+			desc:     "Gauge",
+			kind:     "g",
+			value:    2.5,
+			expected: "acceptlnd_decision_tags_total:2.5|g|#tag:lsp_customers,decision:accepted",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s := &Statsd{plain: tc.plain}
+			line := s.line("acceptlnd_decision_tags_total", labels, tc.value, tc.kind)
+			assert.Equal(t, tc.expected, string(line))
+		})
+	}
+}
+
+func TestStatsd(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	statsd, err := NewStatsd(config.Statsd{Address: conn.LocalAddr().String()})
+	assert.NoError(t, err)
+	defer statsd.Close()
+
+	remove := AddSink(statsd)
+	ObserveResponse(1500 * time.Millisecond)
+	remove()
+	CountSLOBreach()
+
+	assert.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	buf := make([]byte, 512)
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "acceptlnd_response_duration:1500|ms", string(buf[:n]))
+
+	// Nothing is sent once the sink is removed
+	assert.NoError(t, conn.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+	_, _, err = conn.ReadFrom(buf)
+	assert.Error(t, err)
+}
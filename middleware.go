@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/aftermath2/acceptlnd/config"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+)
+
+// defaultMiddlewareName is the name the middleware registers with when it's not configured.
+const defaultMiddlewareName = "acceptlnd"
+
+// Methods opening channels intercepted by the middleware.
+const (
+	openChannelMethod      = "/lnrpc.Lightning/OpenChannel"
+	openChannelSyncMethod  = "/lnrpc.Lightning/OpenChannelSync"
+	batchOpenChannelMethod = "/lnrpc.Lightning/BatchOpenChannel"
+)
+
+// outgoingMessage is the message used to log the decisions on the channels we open.
+const outgoingMessage = "Outgoing channel evaluated"
+
+// runMiddleware registers with LND's RPC middleware and evaluates the calls opening channels
+// until the context is cancelled. In enforce mode, the calls rejected by the policies are denied.
+//
+// Only the calls authenticated with a macaroon carrying the custom caveat of the middleware name
+// are intercepted in enforce mode, in observe mode all of them are.
+func (a *acceptor) runMiddleware(ctx context.Context, middleware config.Middleware) error {
+	name := middleware.Name
+	if name == "" {
+		name = defaultMiddlewareName
+	}
+	enforce := middleware.Mode == config.MiddlewareEnforce
+
+	stream, err := a.client.RegisterRPCMiddleware(ctx)
+	if err != nil {
+		return errors.Wrap(err, "registering RPC middleware")
+	}
+
+	registration := &lnrpc.MiddlewareRegistration{MiddlewareName: name, ReadOnlyMode: !enforce}
+	if enforce {
+		registration.CustomMacaroonCaveatName = name
+	}
+	err = stream.Send(&lnrpc.RPCMiddlewareResponse{
+		MiddlewareMessage: &lnrpc.RPCMiddlewareResponse_Register{Register: registration},
+	})
+	if err != nil {
+		return errors.Wrap(err, "registering RPC middleware")
+	}
+
+	var (
+		wg     sync.WaitGroup
+		sendMu sync.Mutex
+	)
+	defer wg.Wait()
+
+	respond := func(req *lnrpc.RPCMiddlewareRequest, feedback *lnrpc.InterceptFeedback) {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		err := stream.Send(&lnrpc.RPCMiddlewareResponse{
+			RefMsgId:          req.MsgId,
+			MiddlewareMessage: &lnrpc.RPCMiddlewareResponse_Feedback{Feedback: feedback},
+		})
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Sending RPC middleware feedback", slog.Any("error", err))
+		}
+	}
+
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "receiving RPC middleware request")
+		}
+
+		intercept, ok := req.InterceptType.(*lnrpc.RPCMiddlewareRequest_Request)
+		if !ok {
+			if req.GetRegComplete() {
+				slog.Info("RPC middleware registered", slog.String("name", name),
+					slog.String("mode", middleware.Mode))
+			} else {
+				respond(req, &lnrpc.InterceptFeedback{})
+			}
+			continue
+		}
+
+		// Evaluations call LND, whose calls may be intercepted as well, so they can't block the
+		// stream
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			feedback := &lnrpc.InterceptFeedback{}
+			reason := a.evaluateOpen(ctx, intercept.Request)
+			if enforce {
+				feedback.Error = reason
+			}
+			respond(req, feedback)
+		}()
+	}
+}
+
+// evaluateOpen evaluates the channels opened by the call against the policies, as if the peers
+// were requesting them, and returns the reason why any of them was rejected or an empty string.
+// Calls not opening channels are ignored.
+func (a *acceptor) evaluateOpen(ctx context.Context, msg *lnrpc.RPCMessage) string {
+	requests, err := openRequests(msg)
+	if err != nil {
+		slog.Warn("Decoding RPC middleware request", slog.String("method", msg.MethodFullUri),
+			slog.Any("error", err))
+		return ""
+	}
+
+	for _, req := range requests {
+		idCtx := withCorrelationID(ctx)
+		_, peer, decision, err := a.handleRequest(idCtx, req)
+
+		args := []any{
+			slog.Bool("accepted", err == nil),
+			slog.String("method", msg.MethodFullUri),
+			slog.String("public_key", hex.EncodeToString(req.NodePubkey)),
+			slog.Uint64("capacity", req.FundingAmt),
+			slog.String("policies", strings.Join(decision.policies, ",")),
+		}
+		if peer != nil && peer.Node != nil {
+			args = append(args, slog.String("alias", peer.Node.Alias))
+		}
+		if err != nil {
+			args = append(args, slog.String("error", err.Error()))
+		}
+		slog.InfoContext(idCtx, outgoingMessage, args...)
+
+		if err != nil {
+			return err.Error()
+		}
+	}
+	return ""
+}
+
+// openRequests converts the channels opened by the call into channel requests, it returns none if
+// the call doesn't open channels.
+func openRequests(msg *lnrpc.RPCMessage) ([]*lnrpc.ChannelAcceptRequest, error) {
+	if msg.IsError {
+		return nil, nil
+	}
+
+	switch msg.MethodFullUri {
+	case openChannelMethod, openChannelSyncMethod:
+		var open lnrpc.OpenChannelRequest
+		if err := proto.Unmarshal(msg.Serialized, &open); err != nil {
+			return nil, err
+		}
+		// Deprecated, but still accepted by LND
+		if len(open.NodePubkey) == 0 && open.NodePubkeyString != "" {
+			publicKey, err := hex.DecodeString(open.NodePubkeyString)
+			if err != nil {
+				return nil, errors.Wrap(err, "decoding node public key")
+			}
+			open.NodePubkey = publicKey
+		}
+		batch := &lnrpc.BatchOpenChannel{
+			NodePubkey:                 open.NodePubkey,
+			LocalFundingAmount:         open.LocalFundingAmount,
+			PushSat:                    open.PushSat,
+			Private:                    open.Private,
+			MinHtlcMsat:                open.MinHtlcMsat,
+			RemoteCsvDelay:             open.RemoteCsvDelay,
+			CommitmentType:             open.CommitmentType,
+			RemoteMaxValueInFlightMsat: open.RemoteMaxValueInFlightMsat,
+			RemoteMaxHtlcs:             open.RemoteMaxHtlcs,
+			ZeroConf:                   open.ZeroConf,
+			ScidAlias:                  open.ScidAlias,
+			RemoteChanReserveSat:       open.RemoteChanReserveSat,
+		}
+		return []*lnrpc.ChannelAcceptRequest{channelRequest(batch)}, nil
+
+	case batchOpenChannelMethod:
+		var open lnrpc.BatchOpenChannelRequest
+		if err := proto.Unmarshal(msg.Serialized, &open); err != nil {
+			return nil, err
+		}
+		requests := make([]*lnrpc.ChannelAcceptRequest, 0, len(open.Channels))
+		for _, channel := range open.Channels {
+			requests = append(requests, channelRequest(channel))
+		}
+		return requests, nil
+	}
+
+	return nil, nil
+}
+
+// channelRequest returns the request the peer would receive for the channel.
+func channelRequest(channel *lnrpc.BatchOpenChannel) *lnrpc.ChannelAcceptRequest {
+	var flags uint32
+	if !channel.Private {
+		flags = uint32(lnwire.FFAnnounceChannel)
+	}
+	return &lnrpc.ChannelAcceptRequest{
+		NodePubkey:       channel.NodePubkey,
+		PendingChanId:    channel.PendingChanId,
+		FundingAmt:       uint64(channel.LocalFundingAmount),
+		PushAmt:          uint64(channel.PushSat) * 1000,
+		ChannelReserve:   channel.RemoteChanReserveSat,
+		CsvDelay:         channel.RemoteCsvDelay,
+		MaxAcceptedHtlcs: channel.RemoteMaxHtlcs,
+		MinHtlc:          uint64(channel.MinHtlcMsat),
+		MaxValueInFlight: channel.RemoteMaxValueInFlightMsat,
+		ChannelFlags:     flags,
+		CommitmentType:   channel.CommitmentType,
+		WantsZeroConf:    channel.ZeroConf,
+		WantsScidAlias:   channel.ScidAlias,
+	}
+}
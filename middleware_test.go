@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/graph"
+	"github.com/aftermath2/acceptlnd/lightning/fake"
+	"github.com/aftermath2/acceptlnd/policy"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestOpenRequests(t *testing.T) {
+	publicKey := []byte{2, 1}
+	serialize := func(m proto.Message) []byte {
+		b, err := proto.Marshal(m)
+		assert.NoError(t, err)
+		return b
+	}
+
+	cases := []struct {
+		desc     string
+		msg      *lnrpc.RPCMessage
+		expected []*lnrpc.ChannelAcceptRequest
+		fail     bool
+	}{
+		{
+			desc: "Open channel",
+			msg: &lnrpc.RPCMessage{
+				MethodFullUri: openChannelMethod,
+				Serialized: serialize(&lnrpc.OpenChannelRequest{
+					NodePubkey:         publicKey,
+					LocalFundingAmount: 1_000_000,
+					PushSat:            1_000,
+					ZeroConf:           true,
+				}),
+			},
+			expected: []*lnrpc.ChannelAcceptRequest{{
+				NodePubkey:    publicKey,
+				FundingAmt:    1_000_000,
+				PushAmt:       1_000_000,
+				ChannelFlags:  uint32(lnwire.FFAnnounceChannel),
+				WantsZeroConf: true,
+			}},
+		},
+		{
+			desc: "Deprecated public key",
+			msg: &lnrpc.RPCMessage{
+				MethodFullUri: openChannelSyncMethod,
+				Serialized: serialize(&lnrpc.OpenChannelRequest{
+					NodePubkeyString:   hex.EncodeToString(publicKey),
+					LocalFundingAmount: 1_000_000,
+					Private:            true,
+				}),
+			},
+			expected: []*lnrpc.ChannelAcceptRequest{{NodePubkey: publicKey, FundingAmt: 1_000_000}},
+		},
+		{
+			desc: "Invalid public key",
+			msg: &lnrpc.RPCMessage{
+				MethodFullUri: openChannelMethod,
+				Serialized:    serialize(&lnrpc.OpenChannelRequest{NodePubkeyString: "xyz"}),
+			},
+			fail: true,
+		},
+		{
+			desc: "Batch",
+			msg: &lnrpc.RPCMessage{
+				MethodFullUri: batchOpenChannelMethod,
+				Serialized: serialize(&lnrpc.BatchOpenChannelRequest{
+					Channels: []*lnrpc.BatchOpenChannel{
+						{NodePubkey: publicKey, LocalFundingAmount: 1_000_000, Private: true},
+						{NodePubkey: []byte{3}, LocalFundingAmount: 2_000_000, PendingChanId: []byte{1}},
+					},
+				}),
+			},
+			expected: []*lnrpc.ChannelAcceptRequest{
+				{NodePubkey: publicKey, FundingAmt: 1_000_000},
+				{
+					NodePubkey:    []byte{3},
+					FundingAmt:    2_000_000,
+					PendingChanId: []byte{1},
+					ChannelFlags:  uint32(lnwire.FFAnnounceChannel),
+				},
+			},
+		},
+		{
+			desc: "Invalid message",
+			msg:  &lnrpc.RPCMessage{MethodFullUri: batchOpenChannelMethod, Serialized: []byte{0xff}},
+			fail: true,
+		},
+		{
+			desc: "Error",
+			msg:  &lnrpc.RPCMessage{MethodFullUri: openChannelMethod, IsError: true, Serialized: []byte{0xff}},
+		},
+		{
+			desc: "Other method",
+			msg:  &lnrpc.RPCMessage{MethodFullUri: "/lnrpc.Lightning/GetInfo"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			requests, err := openRequests(tc.msg)
+			if tc.fail {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, requests, len(tc.expected))
+			for i, expected := range tc.expected {
+				assert.True(t, proto.Equal(expected, requests[i]), "%v != %v", expected, requests[i])
+			}
+		})
+	}
+}
+
+func TestEvaluateOpen(t *testing.T) {
+	ctx := context.Background()
+	peer := "02" + hex.EncodeToString(make([]byte, 32))
+	publicKey, err := hex.DecodeString(peer)
+	assert.NoError(t, err)
+
+	snapshot := graph.New(&lnrpc.ChannelGraph{
+		Nodes: []*lnrpc.LightningNode{{PubKey: "us"}, {PubKey: peer}},
+	})
+	maxCapacity := uint64(1_000_000)
+	cfg := config.Config{
+		Policies: []*policy.Policy{{
+			Request: &policy.Request{ChannelCapacity: &policy.Range[uint64]{Max: &maxCapacity}},
+		}},
+	}
+	a := newAcceptor(fake.New(snapshot, "us"), nil, cfg)
+
+	open := func(capacity int64) *lnrpc.RPCMessage {
+		b, err := proto.Marshal(&lnrpc.OpenChannelRequest{NodePubkey: publicKey, LocalFundingAmount: capacity})
+		assert.NoError(t, err)
+		return &lnrpc.RPCMessage{MethodFullUri: openChannelMethod, Serialized: b}
+	}
+
+	assert.Empty(t, a.evaluateOpen(ctx, open(500_000)))
+	assert.NotEmpty(t, a.evaluateOpen(ctx, open(2_000_000)))
+	assert.Empty(t, a.evaluateOpen(ctx, &lnrpc.RPCMessage{MethodFullUri: openChannelMethod, Serialized: []byte{0xff}}))
+}
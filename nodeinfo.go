@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/pkg/errors"
+)
+
+// nodeInfoInterval is how often our node information is refreshed. It summarizes our channels and
+// the chain tip, which change rarely, so the requests reuse it instead of calling GetInfo.
+const nodeInfoInterval = 5 * time.Second
+
+// maxNodeInfoAge is the oldest the node information can be before the requests fetch it
+// themselves, in case the refreshes failed.
+const maxNodeInfoAge = 2 * nodeInfoInterval
+
+type cachedNodeInfo struct {
+	info      *lnrpc.GetInfoResponse
+	fetchedAt time.Time
+}
+
+// getNodeInfo returns our node information, calling GetInfo only if the cached one is missing or
+// too old.
+func (a *acceptor) getNodeInfo(ctx context.Context) (*lnrpc.GetInfoResponse, error) {
+	if cached := a.nodeInfo.Load(); cached != nil && time.Since(cached.fetchedAt) <= maxNodeInfoAge {
+		return cached.info, nil
+	}
+	return a.fetchNodeInfo(ctx)
+}
+
+func (a *acceptor) fetchNodeInfo(ctx context.Context) (*lnrpc.GetInfoResponse, error) {
+	info, err := a.client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "getting node information")
+	}
+	a.nodeInfo.Store(&cachedNodeInfo{info: info, fetchedAt: time.Now()})
+	return info, nil
+}
+
+// invalidateNodeInfo discards the cached node information, so the next request counts the
+// channels accepted since it was fetched.
+func (a *acceptor) invalidateNodeInfo() {
+	a.nodeInfo.Store(nil)
+}
+
+// refreshNodeInfo fetches our node information every interval until the context is cancelled.
+func (a *acceptor) refreshNodeInfo(ctx context.Context) {
+	ticker := time.NewTicker(nodeInfoInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := a.fetchNodeInfo(ctx); err != nil && ctx.Err() == nil {
+			slog.Debug("Refreshing node information", slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/lightning"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+// infoClient returns the number of GetInfo calls as the block height.
+type infoClient struct {
+	lightning.Client
+	calls atomic.Uint32
+	err   error
+}
+
+func (c *infoClient) GetInfo(
+	context.Context,
+	*lnrpc.GetInfoRequest,
+	...grpc.CallOption,
+) (*lnrpc.GetInfoResponse, error) {
+	height := c.calls.Add(1)
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &lnrpc.GetInfoResponse{BlockHeight: height}, nil
+}
+
+func TestGetNodeInfo(t *testing.T) {
+	cached := &lnrpc.GetInfoResponse{BlockHeight: 100}
+
+	cases := []struct {
+		desc     string
+		cache    *cachedNodeInfo
+		err      error
+		expected uint32
+		calls    uint32
+	}{
+		{
+			desc:     "Cached",
+			cache:    &cachedNodeInfo{info: cached, fetchedAt: time.Now()},
+			expected: 100,
+		},
+		{
+			desc:     "Missing",
+			expected: 1,
+			calls:    1,
+		},
+		{
+			desc:     "Too old",
+			cache:    &cachedNodeInfo{info: cached, fetchedAt: time.Now().Add(-maxNodeInfoAge - time.Second)},
+			expected: 1,
+			calls:    1,
+		},
+		{
+			desc:  "Error",
+			cache: &cachedNodeInfo{info: cached, fetchedAt: time.Now().Add(-maxNodeInfoAge - time.Second)},
+			err:   errors.New("unavailable"),
+			calls: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			client := &infoClient{err: tc.err}
+			a := newAcceptor(client, nil, config.Config{})
+			a.nodeInfo.Store(tc.cache)
+
+			info, err := a.getNodeInfo(context.Background())
+			assert.Equal(t, tc.calls, client.calls.Load())
+			if tc.err != nil {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, info.BlockHeight)
+
+			// The information fetched is reused
+			info, err = a.getNodeInfo(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, info.BlockHeight)
+			assert.Equal(t, tc.calls, client.calls.Load())
+		})
+	}
+
+	t.Run("Invalidated", func(t *testing.T) {
+		client := &infoClient{}
+		a := newAcceptor(client, nil, config.Config{})
+		a.nodeInfo.Store(&cachedNodeInfo{info: cached, fetchedAt: time.Now()})
+		a.invalidateNodeInfo()
+
+		info, err := a.getNodeInfo(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(1), info.BlockHeight)
+	})
+}
+
+func TestRefreshNodeInfo(t *testing.T) {
+	client := &infoClient{}
+	a := newAcceptor(client, nil, config.Config{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		a.refreshNodeInfo(ctx)
+		close(done)
+	}()
+
+	// The information is fetched as soon as the refresh starts
+	assert.Eventually(t, func() bool { return a.nodeInfo.Load() != nil }, time.Second, time.Millisecond)
+	info, err := a.getNodeInfo(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), info.BlockHeight)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("refresh didn't stop after the context was cancelled")
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"sync"
+
+	"github.com/aftermath2/acceptlnd/asn"
+	"github.com/aftermath2/acceptlnd/policy"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/pkg/errors"
+)
+
+// peerOrigins remembers the address each peer was last connected from, so the channels of the
+// peers that are offline still count towards the limits of their network origin.
+type peerOrigins struct {
+	mu    sync.Mutex
+	addrs map[string]netip.Addr
+}
+
+// originSnapshot holds the origins of our channel peers at a point in time.
+type originSnapshot struct {
+	asns    *asn.Database
+	origins map[string]netip.Addr
+	// channels holds the remote public key of every open and pending channel.
+	channels []string
+}
+
+// channelOrigins returns the origins of our channel peers. The addresses of the peers connected
+// now replace the ones remembered, which are forgotten once the peer is neither connected nor
+// has channels with us.
+func (a *acceptor) channelOrigins(ctx context.Context) (*originSnapshot, error) {
+	peers, err := a.client.ListPeers(ctx, &lnrpc.ListPeersRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing peers")
+	}
+	open, err := a.client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing channels")
+	}
+	pending, err := a.client.PendingChannels(ctx, &lnrpc.PendingChannelsRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing pending channels")
+	}
+
+	snapshot := &originSnapshot{
+		asns:     a.asns,
+		channels: make([]string, 0, len(open.Channels)+len(pending.PendingOpenChannels)),
+	}
+	keep := make(map[string]struct{}, len(peers.Peers)+len(snapshot.channels))
+	for _, channel := range open.Channels {
+		snapshot.channels = append(snapshot.channels, channel.RemotePubkey)
+		keep[channel.RemotePubkey] = struct{}{}
+	}
+	for _, channel := range pending.PendingOpenChannels {
+		if channel.Channel == nil {
+			continue
+		}
+		snapshot.channels = append(snapshot.channels, channel.Channel.RemoteNodePub)
+		keep[channel.Channel.RemoteNodePub] = struct{}{}
+	}
+
+	a.origins.mu.Lock()
+	defer a.origins.mu.Unlock()
+	if a.origins.addrs == nil {
+		a.origins.addrs = make(map[string]netip.Addr)
+	}
+	for _, peer := range peers.Peers {
+		keep[peer.PubKey] = struct{}{}
+		if addr := a.originAddr(peer.Address); addr.IsValid() {
+			a.origins.addrs[peer.PubKey] = addr
+		} else {
+			delete(a.origins.addrs, peer.PubKey)
+		}
+	}
+	for publicKey := range a.origins.addrs {
+		if _, ok := keep[publicKey]; !ok {
+			delete(a.origins.addrs, publicKey)
+		}
+	}
+
+	snapshot.origins = make(map[string]netip.Addr, len(a.origins.addrs))
+	for publicKey, addr := range a.origins.addrs {
+		snapshot.origins[publicKey] = addr
+	}
+	return snapshot, nil
+}
+
+// originAddr returns the IP of the address if it identifies where the peer is, which Tor
+// connections and onion addresses don't.
+func (a *acceptor) originAddr(address string) netip.Addr {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return netip.Addr{}
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	addr = addr.Unmap()
+	// Connections to our onion service come from the local Tor daemon
+	if addr.IsLoopback() || !addr.IsValid() {
+		return netip.Addr{}
+	}
+	if _, ok := a.torExits[addr]; ok {
+		return netip.Addr{}
+	}
+	return addr
+}
+
+// count returns the number of channels with peers connected from the same IP address and
+// autonomous system as the node, zero if its origin is unknown.
+func (s *originSnapshot) count(publicKey string) (sameIP, sameASN uint32) {
+	addr, ok := s.origins[publicKey]
+	if !ok {
+		return 0, 0
+	}
+	number, announced := s.asns.Lookup(addr)
+
+	for _, remote := range s.channels {
+		other, ok := s.origins[remote]
+		if !ok {
+			continue
+		}
+		if other == addr {
+			sameIP++
+		}
+		if announced {
+			if otherNumber, ok := s.asns.Lookup(other); ok && otherNumber == number {
+				sameASN++
+			}
+		}
+	}
+	return sameIP, sameASN
+}
+
+// usesOrigins returns whether any policy limits the channels per network origin.
+func usesOrigins(policies []*policy.Policy) bool {
+	for _, p := range policies {
+		if p.MaxChannelsPerIP != nil || p.MaxChannelsPerASN != nil {
+			return true
+		}
+	}
+	return false
+}
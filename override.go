@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/store"
+
+	"github.com/pkg/errors"
+)
+
+// overrideLabel identifies the requests decided by an override of the operator.
+const overrideLabel = "override"
+
+// override returns the verdict the operator forced on the requests of the node, if any.
+func (a *acceptor) override(ctx context.Context, publicKey string) (store.Override, bool) {
+	if a.db == nil {
+		return store.Override{}, false
+	}
+
+	override, ok, err := a.db.Override(publicKey)
+	if err != nil {
+		slog.ErrorContext(ctx, "Reading override", slog.Any("error", err))
+		return store.Override{}, false
+	}
+	return override, ok
+}
+
+// overrides returns the overrides of every node, nil if there is no database.
+func (a *acceptor) overrides() map[string]store.Override {
+	if a.db == nil {
+		return nil
+	}
+
+	overrides, err := a.db.Overrides()
+	if err != nil {
+		slog.Error("Reading overrides", slog.Any("error", err))
+		return nil
+	}
+	return overrides
+}
+
+// runOverride forces the verdict of the requests of a node regardless of the policies, removes it
+// or lists the overrides.
+func runOverride(args []string) error {
+	fs := flag.NewFlagSet("override", flag.ExitOnError)
+	configPath := fs.String("config", "acceptlnd.yml", "Path to the configuration file")
+	reject := fs.Bool("reject", false, "Reject the node requests instead of accepting them")
+	reason := fs.String("reason", "", "Note recorded along with the override")
+	remove := fs.Bool("delete", false, "Remove the node override")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return errors.New("usage: acceptlnd override [-config path] [-reject] [-reason text] [-delete] [public key]")
+	}
+
+	config, err := config.LoadPolicies(*configPath)
+	if err != nil {
+		return err
+	}
+	if config.DatabasePath == "" || config.DatabaseBackend == store.BackendMemory {
+		return errors.New("the configuration has no database_path, overrides can't be stored")
+	}
+
+	db, err := store.Open(config.DatabaseBackend, config.DatabasePath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if fs.NArg() == 0 {
+		overrides, err := db.Overrides()
+		if err != nil {
+			return err
+		}
+		writeOverrides(os.Stdout, overrides)
+		return nil
+	}
+
+	publicKey := fs.Arg(0)
+	if b, err := hex.DecodeString(publicKey); err != nil || len(b) != 33 {
+		return errors.Errorf("invalid public key %q", publicKey)
+	}
+
+	if *remove {
+		deleted, err := db.DeleteOverride(publicKey)
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			return errors.Errorf("node %s has no override", publicKey)
+		}
+		fmt.Printf("Override of %s removed\n", publicKey)
+		return nil
+	}
+
+	override := store.Override{
+		PublicKey: publicKey,
+		Accept:    !*reject,
+		Reason:    *reason,
+		CreatedAt: time.Now(),
+	}
+	if err := db.SetOverride(override); err != nil {
+		return err
+	}
+	fmt.Printf("Requests of %s will be %s\n", publicKey, overrideVerdict(override))
+	return nil
+}
+
+func writeOverrides(w io.Writer, overrides map[string]store.Override) {
+	if len(overrides) == 0 {
+		fmt.Fprintln(w, "No overrides")
+		return
+	}
+
+	publicKeys := make([]string, 0, len(overrides))
+	for publicKey := range overrides {
+		publicKeys = append(publicKeys, publicKey)
+	}
+	slices.Sort(publicKeys)
+
+	for _, publicKey := range publicKeys {
+		override := overrides[publicKey]
+		fmt.Fprintf(w, "%s  %s  %s", publicKey, overrideVerdict(override),
+			override.CreatedAt.Format(time.RFC3339))
+		if override.Reason != "" {
+			fmt.Fprintf(w, "  %s", override.Reason)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func overrideVerdict(override store.Override) string {
+	if override.Accept {
+		return "accepted"
+	}
+	return "rejected"
+}
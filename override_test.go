@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/policy"
+	"github.com/aftermath2/acceptlnd/store"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverride(t *testing.T) {
+	ctx := context.Background()
+	accepted := "02" + hex.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	rejected := "03" + hex.EncodeToString(bytes.Repeat([]byte{2}, 32))
+
+	db := store.NewMemory()
+	assert.NoError(t, db.SetOverride(store.Override{PublicKey: accepted, Accept: true}))
+	assert.NoError(t, db.SetOverride(store.Override{PublicKey: rejected}))
+	a := newAcceptor(nil, db, config.Config{})
+
+	request := func(publicKey string) *lnrpc.ChannelAcceptRequest {
+		b, err := hex.DecodeString(publicKey)
+		assert.NoError(t, err)
+		return &lnrpc.ChannelAcceptRequest{NodePubkey: b, PendingChanId: []byte{1}}
+	}
+
+	_, _, decision, err := a.handleRequest(ctx, request(accepted))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{overrideLabel}, decision.policies)
+
+	_, _, decision, err = a.handleRequest(ctx, request(rejected))
+	assert.EqualError(t, err, blockedMessage)
+	assert.Equal(t, []string{overrideLabel}, decision.policies)
+
+	_, ok := a.override(ctx, "unknown")
+	assert.False(t, ok)
+
+	t.Run("Without database", func(t *testing.T) {
+		a := newAcceptor(nil, nil, config.Config{})
+		_, ok := a.override(ctx, accepted)
+		assert.False(t, ok)
+	})
+}
+
+func TestBlocklistOverrides(t *testing.T) {
+	db := store.NewMemory()
+	assert.NoError(t, db.SetOverride(store.Override{PublicKey: "rejected"}))
+	assert.NoError(t, db.SetOverride(store.Override{PublicKey: "accepted", Accept: true}))
+	assert.NoError(t, db.SetOverride(store.Override{PublicKey: "self"}))
+
+	cfg := config.Config{
+		SelfServices: []string{"self"},
+		Policies:     []*policy.Policy{{BlockList: &[]string{"accepted", "listed"}}},
+	}
+	a := newAcceptor(nil, db, cfg)
+
+	// Nodes accepted by an override are removed from the policies block lists
+	assert.Equal(t, []string{"listed", "rejected"}, a.blocklist())
+}
+
+func TestWriteOverrides(t *testing.T) {
+	var buf bytes.Buffer
+	writeOverrides(&buf, nil)
+	assert.Equal(t, "No overrides\n", buf.String())
+
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	buf.Reset()
+	writeOverrides(&buf, map[string]store.Override{
+		"b": {PublicKey: "b", CreatedAt: at},
+		"a": {PublicKey: "a", Accept: true, Reason: "partner", CreatedAt: at},
+	})
+	expected := "a  accepted  2024-01-01T00:00:00Z  partner\n" +
+		"b  rejected  2024-01-01T00:00:00Z\n"
+	assert.Equal(t, expected, buf.String())
+}
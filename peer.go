@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/lightning"
+	"github.com/aftermath2/acceptlnd/policy"
+	"github.com/aftermath2/acceptlnd/store"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/pkg/errors"
+)
+
+// runPeer prints the metrics the policies would compute for a node.
+func runPeer(args []string) error {
+	fs := flag.NewFlagSet("peer", flag.ExitOnError)
+	configPath := fs.String("config", "acceptlnd.yml", "Path to the configuration file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: acceptlnd peer [-config path] <public key>")
+	}
+	publicKey := fs.Arg(0)
+	nodePubkey, err := hex.DecodeString(publicKey)
+	if err != nil || len(nodePubkey) != 33 {
+		return errors.Errorf("invalid public key %q", publicKey)
+	}
+
+	config, err := config.Load(*configPath)
+	if err != nil {
+		return err
+	}
+
+	client, err := lightning.NewClient(config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	a := newAcceptor(client, openPeerDatabase(config), config)
+	if a.db != nil {
+		defer a.db.Close()
+	}
+
+	node, err := client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	if err != nil {
+		return errors.Wrap(err, "getting node information")
+	}
+
+	peer, err := client.GetNodeInfo(ctx, &lnrpc.NodeInfoRequest{PubKey: publicKey, IncludeChannels: true})
+	if err != nil {
+		return errors.Wrap(err, "getting peer information")
+	}
+	if peer.Node == nil {
+		return errors.New("the node is not in the graph")
+	}
+
+	if _, ok := a.loadGraphSnapshot(ctx); !ok {
+		if err := a.updateNeighborhood(ctx); err != nil {
+			return err
+		}
+	}
+	network := a.network.Load()
+
+	// The first seen time is not read, as doing it records it
+	facts := &policy.Facts{
+		Now:     time.Now(),
+		Peers:   network.peers,
+		Reach:   network.reach,
+		KnownAs: a.registry.Tags(publicKey),
+	}
+	if a.db != nil {
+		score, err := a.db.Score(publicKey)
+		if err != nil {
+			return err
+		}
+		facts.Reputation = score.At(facts.Now, a.halfLife)
+
+		history, err := a.db.History(publicKey)
+		if err != nil {
+			return err
+		}
+		setHistory(facts, history)
+	}
+	facts.MaxChannelUptime, err = a.maxChannelUptime(ctx, nodePubkey)
+	if err != nil {
+		return err
+	}
+
+	return writeProfile(os.Stdout, peer, policy.Profile(node, peer, facts))
+}
+
+// openPeerDatabase opens the database to read the peer reputation. A running instance may hold
+// the lock of file databases, in which case the reputation is not shown.
+func openPeerDatabase(config config.Config) store.Storage {
+	if config.DatabasePath == "" || config.DatabaseBackend == store.BackendMemory {
+		return nil
+	}
+
+	db, err := store.OpenReadOnly(config.DatabaseBackend, config.DatabasePath)
+	if err != nil {
+		slog.Warn("Opening database, the reputation is not available", slog.Any("error", err))
+		return nil
+	}
+	return db
+}
+
+func writeProfile(w io.Writer, peer *lnrpc.NodeInfo, metrics []policy.Metric) error {
+	fmt.Fprintf(w, "%s (%s)\n\n", peer.Node.PubKey, peer.Node.Alias)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Metric\tValue\tMin\tMedian\tMean\tMode\tRange\tMax\t")
+	for _, metric := range metrics {
+		if metric.Stats == nil {
+			fmt.Fprintf(tw, "%s\t%s\t\t\t\t\t\t\t\n", metric.Key, metric.Value)
+			continue
+		}
+
+		s := metric.Stats
+		values := []string{metric.Key, ""}
+		for _, v := range []float64{s.Min, s.Median, s.Mean, s.Mode, s.Range, s.Max} {
+			values = append(values, formatStat(v))
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t")+"\t")
+	}
+	return tw.Flush()
+}
+
+// formatStat prints at most two decimals, omitting them for integers.
+func formatStat(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	return strings.TrimSuffix(s, ".00")
+}
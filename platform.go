@@ -0,0 +1,13 @@
+package main
+
+// serviceName is the name used to register acceptLND as a system service.
+const serviceName = "acceptlnd"
+
+// notify sends a value to the channel without blocking, requests received while a previous one is
+// pending are merged into it.
+func notify(ch chan<- struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
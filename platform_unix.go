@@ -0,0 +1,31 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// runPlatform runs the function until an interrupt or termination signal is received, SIGHUP
+// triggers a configuration reload.
+func runPlatform(_ *slog.HandlerOptions, run func(ctx context.Context, reload <-chan struct{}) error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	reload := make(chan struct{}, 1)
+	go func() {
+		for range hup {
+			notify(reload)
+		}
+	}()
+
+	return run(ctx, reload)
+}
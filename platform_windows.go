@@ -0,0 +1,153 @@
+//go:build windows
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// runPlatform runs the function as a Windows service if the process was started by the service
+// control manager, or as a console application otherwise.
+//
+// Services are stopped through the service control manager and reloaded with the "paramchange"
+// control code (`sc control acceptlnd paramchange`). Console applications stop on Ctrl+C and
+// can't be reloaded, as there is no equivalent of SIGHUP on Windows.
+func runPlatform(opts *slog.HandlerOptions, run func(ctx context.Context, reload <-chan struct{}) error) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return errors.Wrap(err, "detecting Windows service")
+	}
+
+	if !isService {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return run(ctx, nil)
+	}
+
+	elog, err := eventlog.Open(serviceName)
+	if err != nil {
+		return errors.Wrap(err, "opening event log")
+	}
+	defer elog.Close()
+	slog.SetDefault(slog.New(correlationHandler{newEventLogHandler(elog, opts)}))
+
+	s := &service{run: run}
+	if err := svc.Run(serviceName, s); err != nil {
+		return errors.Wrap(err, "running service")
+	}
+	return s.err
+}
+
+// service implements the Windows service control handler.
+type service struct {
+	run func(ctx context.Context, reload <-chan struct{}) error
+	err error
+}
+
+// Execute is called by the service control manager when the service starts.
+func (s *service) Execute(_ []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	const accepts = svc.AcceptStop | svc.AcceptShutdown | svc.AcceptParamChange
+	status <- svc.Status{State: svc.StartPending}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reload := make(chan struct{}, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.run(ctx, reload)
+	}()
+
+	status <- svc.Status{State: svc.Running, Accepts: accepts}
+
+	for {
+		select {
+		case err := <-done:
+			s.err = err
+			status <- svc.Status{State: svc.StopPending}
+			if err != nil {
+				return true, 1
+			}
+			return false, 0
+
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.ParamChange:
+				notify(reload)
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				cancel()
+			}
+		}
+	}
+}
+
+// eventLogHandler writes log records to the Windows event log.
+type eventLogHandler struct {
+	elog    *eventlog.Log
+	handler slog.Handler
+	buf     *bytes.Buffer
+	mu      *sync.Mutex
+}
+
+func newEventLogHandler(elog *eventlog.Log, opts *slog.HandlerOptions) *eventLogHandler {
+	buf := &bytes.Buffer{}
+	return &eventLogHandler{
+		elog:    elog,
+		handler: slog.NewTextHandler(buf, opts),
+		buf:     buf,
+		mu:      &sync.Mutex{},
+	}
+}
+
+// Enabled reports whether the handler handles records at the given level.
+func (h *eventLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle formats the record and reports it to the event log with the matching event type.
+func (h *eventLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.buf.Reset()
+	if err := h.handler.Handle(ctx, r); err != nil {
+		return err
+	}
+	msg := strings.TrimSpace(h.buf.String())
+
+	switch {
+	case r.Level >= slog.LevelError:
+		return h.elog.Error(1, msg)
+	case r.Level >= slog.LevelWarn:
+		return h.elog.Warning(1, msg)
+	default:
+		return h.elog.Info(1, msg)
+	}
+}
+
+// WithAttrs returns a new handler including the attributes received.
+func (h *eventLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.handler = h.handler.WithAttrs(attrs)
+	return &h2
+}
+
+// WithGroup returns a new handler with the group name received.
+func (h *eventLogHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.handler = h.handler.WithGroup(name)
+	return &h2
+}
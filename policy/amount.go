@@ -0,0 +1,55 @@
+package policy
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+)
+
+// SuspiciousAmount describes funding amounts that identify suspicious requests, like the round
+// values or the signature amounts used by spam campaigns. An amount is suspicious if it matches
+// any of them.
+type SuspiciousAmount struct {
+	MultipleOf *uint64  `yaml:"multiple_of,omitempty" doc:"Amounts that are a multiple of this value, 1000000 flags 0.01 BTC, 0.02 BTC and so on."`
+	Values     []uint64 `yaml:"values,omitempty" doc:"Exact amounts."`
+	Patterns   []string `yaml:"patterns,omitempty" doc:"Patterns matched against the amount in sats, * matches any number of digits and ? a single one."`
+}
+
+func (s *SuspiciousAmount) match(amount uint64) bool {
+	if s.MultipleOf != nil && amount%*s.MultipleOf == 0 {
+		return true
+	}
+
+	for _, value := range s.Values {
+		if amount == value {
+			return true
+		}
+	}
+
+	text := strconv.FormatUint(amount, 10)
+	for _, pattern := range s.Patterns {
+		if ok, _ := path.Match(pattern, text); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *SuspiciousAmount) validate(field string) error {
+	if s.MultipleOf == nil && len(s.Values) == 0 && len(s.Patterns) == 0 {
+		return fmt.Errorf("%s: multiple_of, values or patterns must be set", field)
+	}
+
+	if s.MultipleOf != nil && *s.MultipleOf == 0 {
+		return fmt.Errorf("%s.multiple_of: must be positive", field)
+	}
+
+	for i, pattern := range s.Patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%s.patterns[%d]: %w", field, i, err)
+		}
+	}
+
+	return nil
+}
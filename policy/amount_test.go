@@ -0,0 +1,59 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuspiciousAmountMatch(t *testing.T) {
+	multipleOf := uint64(1_000_000)
+	suspicious := &SuspiciousAmount{
+		MultipleOf: &multipleOf,
+		Values:     []uint64{1_234_567},
+		Patterns:   []string{"*1337", "2?0000"},
+	}
+
+	cases := []struct {
+		desc     string
+		amount   uint64
+		expected bool
+	}{
+		{
+			desc:     "Multiple",
+			amount:   5_000_000,
+			expected: true,
+		},
+		{
+			desc:     "Value",
+			amount:   1_234_567,
+			expected: true,
+		},
+		{
+			desc:     "Suffix pattern",
+			amount:   4_201_337,
+			expected: true,
+		},
+		{
+			desc:     "Single digit pattern",
+			amount:   270_000,
+			expected: true,
+		},
+		{
+			desc:     "Single digit pattern length",
+			amount:   2_700_000,
+			expected: false,
+		},
+		{
+			desc:     "Not suspicious",
+			amount:   5_432_109,
+			expected: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.expected, suspicious.match(tc.amount))
+		})
+	}
+}
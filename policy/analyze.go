@@ -0,0 +1,270 @@
+package policy
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Severity of an issue found analyzing the policies.
+type Severity string
+
+// Issue severities.
+const (
+	// SeverityWarning is used for issues that make the policies behave surprisingly.
+	SeverityWarning Severity = "warning"
+	// SeverityError is used for contradictions that make the policies impossible to satisfy.
+	SeverityError Severity = "error"
+)
+
+// Issue is a problem found in a set of policies.
+type Issue struct {
+	Severity Severity
+	Message  string
+	Policy   int
+}
+
+// Error returns the issue description.
+func (i Issue) Error() string {
+	return fmt.Sprintf("policy %d: %s", i.Policy, i.Message)
+}
+
+// bounded is implemented by ranges to report whether their limits contradict each other.
+type bounded interface {
+	inverted() bool
+}
+
+func (r Range[T]) inverted() bool {
+	return r.Min != nil && r.Max != nil && *r.Min > *r.Max
+}
+
+func (a StatRange[T]) inverted() bool {
+	return a.Min != nil && a.Max != nil && *a.Min > *a.Max
+}
+
+// signed is implemented by ranges to report whether any of their limits is negative.
+type signed interface {
+	negative() bool
+}
+
+func (r Range[T]) negative() bool {
+	return (r.Min != nil && *r.Min < 0) || (r.Max != nil && *r.Max < 0)
+}
+
+func (a StatRange[T]) negative() bool {
+	return (a.Min != nil && *a.Min < 0) || (a.Max != nil && *a.Max < 0)
+}
+
+// Analyze looks for contradictions and unreachable policies.
+func Analyze(policies []*Policy) []Issue {
+	var issues []Issue
+
+	rejectAll := -1
+	allowed := make(map[string]int)
+	blocked := make(map[string]int)
+
+	for i, p := range policies {
+		if rejectAll != -1 {
+			issues = append(issues, Issue{
+				Severity: SeverityWarning,
+				Policy:   i,
+				Message: fmt.Sprintf(
+					"unreachable, policy %d rejects all requests unconditionally", rejectAll,
+				),
+			})
+		}
+
+		issues = append(issues, p.analyze(i)...)
+
+		if p.Conditions == nil && p.RejectAll != nil && *p.RejectAll && rejectAll == -1 {
+			rejectAll = i
+		}
+
+		if p.AllowList != nil {
+			for _, pubKey := range *p.AllowList {
+				if j, ok := blocked[pubKey]; ok {
+					issues = append(issues, Issue{
+						Severity: SeverityWarning,
+						Policy:   i,
+						Message:  fmt.Sprintf("%s is allowed but it's blocked by policy %d", pubKey, j),
+					})
+				}
+				allowed[pubKey] = i
+			}
+		}
+
+		if p.BlockList != nil {
+			for _, pubKey := range *p.BlockList {
+				if j, ok := allowed[pubKey]; ok && j != i {
+					issues = append(issues, Issue{
+						Severity: SeverityWarning,
+						Policy:   i,
+						Message:  fmt.Sprintf("%s is blocked but it's allowed by policy %d", pubKey, j),
+					})
+				}
+				blocked[pubKey] = i
+			}
+		}
+	}
+
+	return issues
+}
+
+func (p *Policy) analyze(index int) []Issue {
+	var issues []Issue
+
+	if p.AllowList != nil && p.BlockList != nil {
+		for _, pubKey := range *p.AllowList {
+			if contains(*p.BlockList, pubKey) {
+				issues = append(issues, Issue{
+					Severity: SeverityError,
+					Policy:   index,
+					Message:  pubKey + " is in both allow_list and block_list",
+				})
+			}
+		}
+	}
+
+	if p.ReservedSlots != nil {
+		switch {
+		case p.MaxChannels == nil:
+			issues = append(issues, Issue{
+				Severity: SeverityWarning,
+				Policy:   index,
+				Message:  "reserved_slots has no effect without max_channels",
+			})
+		case *p.ReservedSlots > *p.MaxChannels:
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Policy:   index,
+				Message:  "reserved_slots is greater than max_channels",
+			})
+		}
+	}
+
+	for _, c := range p.Conditions.groups() {
+		if c.legacy && c.Is != nil &&
+			(c.IsNot != nil || c.IsPrivate != nil || c.WantsZeroConf != nil || c.Request != nil || c.Node != nil) {
+			issues = append(issues, Issue{
+				Severity: SeverityWarning,
+				Policy:   index,
+				Message: "conditions.is matches the listed nodes ignoring the other conditions, " +
+					"set version to 2 to combine them",
+			})
+		}
+	}
+
+	lists := map[string]*[]string{
+		"allow_list":     p.AllowList,
+		"block_list":     p.BlockList,
+		"zero_conf_list": p.ZeroConfList,
+		"reserved_list":  p.ReservedList,
+	}
+	for _, name := range []string{"allow_list", "block_list", "zero_conf_list", "reserved_list"} {
+		issues = appendDuplicate(issues, index, name, lists[name])
+	}
+	for _, c := range p.Conditions.groups() {
+		issues = appendDuplicate(issues, index, "conditions.is", c.Is)
+		issues = appendDuplicate(issues, index, "conditions.is_not", c.IsNot)
+	}
+
+	checkRange := func(path string, b bounded) {
+		if b.inverted() {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Policy:   index,
+				Message:  path + " minimum is greater than its maximum",
+			})
+		}
+		if s, ok := b.(severe); ok && s.severity() == CheckWarn &&
+			(strings.HasPrefix(path, "conditions") || path == "escalation.uptime") {
+			issues = append(issues, Issue{
+				Severity: SeverityWarning,
+				Policy:   index,
+				Message:  path + ".severity has no effect, only requirements can warn",
+			})
+		}
+	}
+	walkRanges(reflect.ValueOf(p), "", checkRange)
+	if p.Conditions != nil {
+		for i, c := range p.Conditions.Any {
+			walkRanges(reflect.ValueOf(c), fmt.Sprintf("conditions[%d]", i), checkRange)
+		}
+	}
+
+	return issues
+}
+
+// appendDuplicate appends a warning if the list contains duplicated values.
+func appendDuplicate(issues []Issue, index int, name string, list *[]string) []Issue {
+	if duplicate, ok := findDuplicate(list); ok {
+		issues = append(issues, Issue{
+			Severity: SeverityWarning,
+			Policy:   index,
+			Message:  fmt.Sprintf("%s is duplicated in %s", duplicate, name),
+		})
+	}
+	return issues
+}
+
+// walkRanges calls fn with every range found in the value received, along with its YAML path.
+func walkRanges(v reflect.Value, path string, fn func(path string, b bounded)) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	if b, ok := v.Interface().(bounded); ok {
+		fn(path, b)
+		return
+	}
+
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		if path != "" {
+			name = path + "." + name
+		}
+
+		walkRanges(v.Field(i), name, fn)
+	}
+}
+
+func findDuplicate(list *[]string) (string, bool) {
+	if list == nil {
+		return "", false
+	}
+
+	seen := make(map[string]struct{}, len(*list))
+	for _, v := range *list {
+		if _, ok := seen[v]; ok {
+			return v, true
+		}
+		seen[v] = struct{}{}
+	}
+
+	return "", false
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
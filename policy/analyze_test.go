@@ -0,0 +1,209 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyze(t *testing.T) {
+	publicKey := "02b7f5b5d5e9b8dd8e0c5ec9fbc81ae29bcab0ed5d2ba469ee75ab6bec1a7a1d5d"
+	tru := true
+	min := uint64(10)
+	max := uint64(5)
+	minFloat := 0.8
+	maxFloat := 0.2
+	reserved := uint32(5)
+
+	cases := []struct {
+		desc     string
+		policies []*Policy
+		expected []Issue
+	}{
+		{
+			desc:     "No issues",
+			policies: []*Policy{{AllowList: &[]string{publicKey}}, {RejectAll: &tru}},
+		},
+		{
+			desc: "Allowed and blocked",
+			policies: []*Policy{
+				{
+					AllowList: &[]string{publicKey},
+					BlockList: &[]string{publicKey},
+				},
+			},
+			expected: []Issue{
+				{
+					Severity: SeverityError,
+					Policy:   0,
+					Message:  publicKey + " is in both allow_list and block_list",
+				},
+			},
+		},
+		{
+			desc: "Allowed and blocked in different policies",
+			policies: []*Policy{
+				{BlockList: &[]string{publicKey}},
+				{AllowList: &[]string{publicKey}},
+			},
+			expected: []Issue{
+				{
+					Severity: SeverityWarning,
+					Policy:   1,
+					Message:  publicKey + " is allowed but it's blocked by policy 0",
+				},
+			},
+		},
+		{
+			desc: "Duplicated",
+			policies: []*Policy{
+				{Conditions: &Conditions{Is: &[]string{publicKey, publicKey}}},
+			},
+			expected: []Issue{
+				{
+					Severity: SeverityWarning,
+					Policy:   0,
+					Message:  publicKey + " is duplicated in conditions.is",
+				},
+			},
+		},
+		{
+			desc: "Inverted ranges",
+			policies: []*Policy{
+				{
+					Request: &Request{
+						ChannelCapacity: &Range[uint64]{Min: &min, Max: &max},
+					},
+					Conditions: &Conditions{
+						Node: &Node{
+							Channels: &Channels{
+								Disabled: &StatRange[float64]{Min: &minFloat, Max: &maxFloat},
+							},
+						},
+					},
+				},
+			},
+			expected: []Issue{
+				{
+					Severity: SeverityError,
+					Policy:   0,
+					Message:  "conditions.node.channels.disabled minimum is greater than its maximum",
+				},
+				{
+					Severity: SeverityError,
+					Policy:   0,
+					Message:  "request.channel_capacity minimum is greater than its maximum",
+				},
+			},
+		},
+		{
+			desc: "Unreachable",
+			policies: []*Policy{
+				{RejectAll: &tru},
+				{MaxChannels: new(uint32)},
+			},
+			expected: []Issue{
+				{
+					Severity: SeverityWarning,
+					Policy:   1,
+					Message:  "unreachable, policy 0 rejects all requests unconditionally",
+				},
+			},
+		},
+		{
+			desc: "Conditions list contradiction",
+			policies: []*Policy{
+				{Conditions: &Conditions{Any: []*Conditions{
+					{IsPrivate: &tru},
+					{Request: &Request{ChannelCapacity: &Range[uint64]{Min: &min, Max: &max}}},
+				}}},
+			},
+			expected: []Issue{
+				{
+					Severity: SeverityError,
+					Policy:   0,
+					Message:  "conditions[1].request.channel_capacity minimum is greater than its maximum",
+				},
+			},
+		},
+		{
+			desc: "Legacy is",
+			policies: []*Policy{
+				{Conditions: &Conditions{Is: &[]string{"a"}, IsPrivate: &tru, legacy: true}},
+				{Conditions: &Conditions{Is: &[]string{"a"}, IsPrivate: &tru}},
+				{Conditions: &Conditions{Is: &[]string{"a"}, legacy: true}},
+			},
+			expected: []Issue{
+				{
+					Severity: SeverityWarning,
+					Policy:   0,
+					Message: "conditions.is matches the listed nodes ignoring the other conditions, " +
+						"set version to 2 to combine them",
+				},
+			},
+		},
+		{
+			desc: "Reserved slots without maximum",
+			policies: []*Policy{
+				{ReservedSlots: &reserved},
+			},
+			expected: []Issue{
+				{
+					Severity: SeverityWarning,
+					Policy:   0,
+					Message:  "reserved_slots has no effect without max_channels",
+				},
+			},
+		},
+		{
+			desc: "Reserved slots greater than maximum",
+			policies: []*Policy{
+				{ReservedSlots: &reserved, MaxChannels: new(uint32)},
+			},
+			expected: []Issue{
+				{
+					Severity: SeverityError,
+					Policy:   0,
+					Message:  "reserved_slots is greater than max_channels",
+				},
+			},
+		},
+		{
+			desc: "Warn severity in conditions",
+			policies: []*Policy{
+				{
+					Conditions: &Conditions{Request: &Request{
+						ChannelCapacity: &Range[uint64]{Min: &min, Severity: CheckWarn},
+					}},
+					Request: &Request{ChannelCapacity: &Range[uint64]{Min: &min, Severity: CheckWarn}},
+				},
+			},
+			expected: []Issue{
+				{
+					Severity: SeverityWarning,
+					Policy:   0,
+					Message:  "conditions.request.channel_capacity.severity has no effect, only requirements can warn",
+				},
+			},
+		},
+		{
+			desc: "Conditional reject all",
+			policies: []*Policy{
+				{RejectAll: &tru, Conditions: &Conditions{IsPrivate: &tru}},
+				{MaxChannels: new(uint32)},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			actual := Analyze(tc.policies)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestIssueError(t *testing.T) {
+	issue := Issue{Policy: 2, Message: "message"}
+	assert.Equal(t, "policy 2: message", issue.Error())
+}
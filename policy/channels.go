@@ -2,129 +2,242 @@ package policy
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/lightningnetwork/lnd/lnrpc"
 )
 
+// inboundFeeRecordType is the channel update TLV record type carrying the inbound fees.
+const inboundFeeRecordType = 55555
+
 // Channels represents a set of requirements that the initiator's node channels must satisfy.
 type Channels struct {
-	Number          *Range[uint32]      `yaml:"number,omitempty"`
-	Capacity        *StatRange[int64]   `yaml:"capacity,omitempty"`
-	ZeroBaseFees    *bool               `yaml:"zero_base_fees,omitempty"`
-	BlockHeight     *StatRange[uint32]  `yaml:"block_height,omitempty"`
-	TimeLockDelta   *StatRange[uint32]  `yaml:"time_lock_delta,omitempty"`
-	MinHTLC         *StatRange[int64]   `yaml:"min_htlc,omitempty"`
-	MaxHTLC         *StatRange[uint64]  `yaml:"max_htlc,omitempty"`
-	LastUpdateDiff  *StatRange[uint32]  `yaml:"last_update_diff,omitempty"`
-	Together        *Range[int]         `yaml:"together,omitempty"`
-	FeeRates        *StatRange[int64]   `yaml:"fee_rates,omitempty"`
-	BaseFees        *StatRange[int64]   `yaml:"base_fees,omitempty"`
-	Disabled        *StatRange[float64] `yaml:"disabled,omitempty"`
-	InboundFeeRates *StatRange[int32]   `yaml:"inbound_fees_rates,omitempty"`
-	InboundBaseFees *StatRange[int32]   `yaml:"inbound_base_fees,omitempty"`
-	Peers           *Peers              `yaml:"peers,omitempty"`
+	Number          *Range[uint32]      `yaml:"number,omitempty" unit:"number" doc:"Number of channels."`
+	Capacity        *StatRange[int64]   `yaml:"capacity,omitempty" unit:"amount" doc:"Channels size, in sats."`
+	ZeroBaseFees    *bool               `yaml:"zero_base_fees,omitempty" doc:"Whether all the channels must have zero base fees."`
+	BlockHeight     *StatRange[uint32]  `yaml:"block_height,omitempty" unit:"number" doc:"Channels block height."`
+	TimeLockDelta   *StatRange[uint32]  `yaml:"time_lock_delta,omitempty" unit:"number" doc:"Channels time lock delta."`
+	MinHTLC         *StatRange[int64]   `yaml:"min_htlc,omitempty" unit:"number" doc:"Channels minimum HTLC, in millisatoshis."`
+	MaxHTLC         *StatRange[uint64]  `yaml:"max_htlc,omitempty" unit:"amount" doc:"Channels maximum HTLC, in sats."`
+	LastUpdateDiff  *StatRange[uint32]  `yaml:"last_update_diff,omitempty" unit:"duration" doc:"Seconds between the channels last update and the request."`
+	Together        *Range[int]         `yaml:"together,omitempty" unit:"number" doc:"Number of channels the node has with us."`
+	FeeRates        *StatRange[int64]   `yaml:"fee_rates,omitempty" unit:"ppm" doc:"Channels fee rates, in ppm."`
+	BaseFees        *StatRange[int64]   `yaml:"base_fees,omitempty" unit:"amount" doc:"Channels base fees, in sats."`
+	EffectiveFeeAt  *EffectiveFee       `yaml:"effective_fee_ppm_at,omitempty" unit:"ppm" doc:"Channels fee rates, in ppm, including the base fee charged for forwarding a payment of the amount given."`
+	Disabled        *StatRange[float64] `yaml:"disabled,omitempty" unit:"ratio" doc:"Ratio (0-1) of disabled channels."`
+	InboundFeeRates *StatRange[int32]   `yaml:"inbound_fee_rates,omitempty" unit:"ppm" doc:"Channels inbound fee rates, in ppm."`
+	InboundBaseFees *StatRange[int32]   `yaml:"inbound_base_fees,omitempty" unit:"amount" doc:"Channels inbound base fees, in sats."`
+	InboundSignaled *StatRange[float64] `yaml:"inbound_fees_signaled,omitempty" unit:"ratio" doc:"Ratio (0-1) of channels announcing inbound fees, zero for nodes that don't support them."`
+	Peers           *Peers              `yaml:"peers,omitempty" doc:"Requirements of the channels policies on the partners side."`
+	TowardUs        *TowardUs           `yaml:"toward_us,omitempty" doc:"Requirements of the node policies on the channels it has with us."`
+	Sampling        *Sampling           `yaml:"sampling,omitempty" doc:"Compute the statistic ranges over a subset of the channels of nodes having many, trading accuracy for speed."`
+}
+
+// EffectiveFee is a statistic range over the fees the channels charge for forwarding a payment of
+// a reference amount, combining their base fees and fee rates so nodes using only one of them are
+// judged fairly.
+type EffectiveFee struct {
+	Amount           uint64 `yaml:"amount,omitempty" doc:"Amount of the reference payment, in sats. Required."`
+	StatRange[int64] `yaml:",inline"`
 }
 
 // Peers contains information about the initiator node channels peers.
 //
 // Fields must be duplicated to follow the YAML structure desired.
 type Peers struct {
-	FeeRates        *StatRange[int64]   `yaml:"fee_rates,omitempty"`
-	BaseFees        *StatRange[int64]   `yaml:"base_fees,omitempty"`
-	Disabled        *StatRange[float64] `yaml:"disabled,omitempty"`
-	InboundFeeRates *StatRange[int32]   `yaml:"inbound_fees_rates,omitempty"`
-	InboundBaseFees *StatRange[int32]   `yaml:"inbound_base_fees,omitempty"`
+	FeeRates        *StatRange[int64]   `yaml:"fee_rates,omitempty" unit:"ppm" doc:"Partners fee rates, in ppm."`
+	BaseFees        *StatRange[int64]   `yaml:"base_fees,omitempty" unit:"amount" doc:"Partners base fees, in sats."`
+	Disabled        *StatRange[float64] `yaml:"disabled,omitempty" unit:"ratio" doc:"Ratio (0-1) of channels disabled by the partners."`
+	InboundFeeRates *StatRange[int32]   `yaml:"inbound_fee_rates,omitempty" unit:"ppm" doc:"Partners inbound fee rates, in ppm."`
+	InboundBaseFees *StatRange[int32]   `yaml:"inbound_base_fees,omitempty" unit:"amount" doc:"Partners inbound base fees, in sats."`
+}
+
+// TowardUs contains the initiator node routing policies on the channels it has with our node.
+//
+// Only the initiator's side of those channels is considered, that is, the fees it charges for
+// forwarding payments to us.
+type TowardUs struct {
+	FeeRates        *StatRange[int64] `yaml:"fee_rates,omitempty" unit:"ppm" doc:"Fee rates toward us, in ppm."`
+	BaseFees        *StatRange[int64] `yaml:"base_fees,omitempty" unit:"amount" doc:"Base fees toward us, in sats."`
+	InboundFeeRates *StatRange[int32] `yaml:"inbound_fee_rates,omitempty" unit:"ppm" doc:"Inbound fee rates toward us, in ppm."`
+	InboundBaseFees *StatRange[int32] `yaml:"inbound_base_fees,omitempty" unit:"amount" doc:"Inbound base fees toward us, in sats."`
 }
 
-func (c *Channels) evaluate(nodePublicKey string, peer *lnrpc.NodeInfo) error {
+func (c *Channels) evaluate(nodePublicKey string, peer *lnrpc.NodeInfo, w *warnings) error {
 	if c == nil {
 		return nil
 	}
 
-	if !check(c.Number, peer.NumChannels) {
-		return errors.New("Node number of channels " + c.Number.Reason())
+	if err := checkRange(c.Number, peer.NumChannels, w, "Node number of channels"); err != nil {
+		return err
 	}
 
-	if !checkStat(c.Capacity, peer, capacityFunc) {
-		return errors.New("Capacity " + c.Capacity.Reason())
+	// The requirements about all the channels and the ones shared with us don't use the sample
+	sampled := c.Sampling.sample(peer)
+
+	if err := checkStatRange(c.Capacity, sampled, capacityFunc, w, "Capacity"); err != nil {
+		return err
 	}
 
 	if !c.checkZeroBaseFees(peer) {
 		return errors.New("Node has channels with base fees higher than zero")
 	}
 
-	if !checkStat(c.BlockHeight, peer, blockHeightFunc) {
-		return errors.New("Block height " + c.BlockHeight.Reason())
+	if err := checkStatRange(c.BlockHeight, sampled, blockHeightFunc, w, "Block height"); err != nil {
+		return err
 	}
 
-	if !checkStat(c.TimeLockDelta, peer, timeLockDeltaFunc()) {
-		return errors.New("Time lock delta " + c.TimeLockDelta.Reason())
+	if err := checkPolicyStatRange(c.TimeLockDelta, sampled, true, timeLockDeltaFunc(),
+		w, "Time lock delta"); err != nil {
+		return err
 	}
 
-	if !checkStat(c.MinHTLC, peer, minHTLCFunc()) {
-		return errors.New("Channels minimum HTLC " + c.MinHTLC.Reason())
+	if err := checkPolicyStatRange(c.MinHTLC, sampled, true, minHTLCFunc(),
+		w, "Channels minimum HTLC"); err != nil {
+		return err
 	}
 
-	if !checkStat(c.MaxHTLC, peer, maxHTLCFunc()) {
-		return errors.New("Channels maximum HTLC " + c.MaxHTLC.Reason())
+	if err := checkPolicyStatRange(c.MaxHTLC, sampled, true, maxHTLCFunc(),
+		w, "Channels maximum HTLC"); err != nil {
+		return err
 	}
 
-	if !checkStat(c.LastUpdateDiff, peer, lastUpdateFunc(time.Now().Unix())) {
-		return errors.New("Channels last update " + c.LastUpdateDiff.Reason())
+	if err := checkPolicyStatRange(c.LastUpdateDiff, sampled, true, lastUpdateFunc(time.Now().Unix()),
+		w, "Channels last update"); err != nil {
+		return err
 	}
 
 	if !c.checkTogether(nodePublicKey, peer) {
-		return errors.New("Channels together " + c.Together.Reason())
+		if err := w.violation(c.Together, "Channels together "+c.Together.Reason()); err != nil {
+			return err
+		}
 	}
 
-	if !checkStat(c.FeeRates, peer, feeRatesFunc(true)) {
-		return errors.New("Channels fee rates " + c.FeeRates.Reason())
+	if err := checkPolicyStatRange(c.FeeRates, sampled, true, feeRatesFunc(true),
+		w, "Channels fee rates"); err != nil {
+		return err
 	}
 
-	if !checkStat(c.BaseFees, peer, baseFeesFunc(true)) {
-		return errors.New("Channels base fees " + c.BaseFees.Reason())
+	if err := checkPolicyStatRange(c.BaseFees, sampled, true, baseFeesFunc(true),
+		w, "Channels base fees"); err != nil {
+		return err
 	}
 
-	if !checkStat(c.InboundFeeRates, peer, inboundFeeRatesFunc(true)) {
-		return errors.New("Channels inbound fee rates " + c.InboundFeeRates.Reason())
+	if err := checkPolicyStatRange(c.InboundFeeRates, sampled, true, inboundFeeRatesFunc(true),
+		w, "Channels inbound fee rates"); err != nil {
+		return err
 	}
 
-	if !checkStat(c.InboundBaseFees, peer, inboundBaseFeesFunc(true)) {
-		return errors.New("Channels inbound base fees " + c.InboundBaseFees.Reason())
+	if err := checkPolicyStatRange(c.InboundBaseFees, sampled, true, inboundBaseFeesFunc(true),
+		w, "Channels inbound base fees"); err != nil {
+		return err
 	}
 
-	if !c.checkDisabled(peer) {
-		return errors.New("Disabled channels " + c.Disabled.Reason())
+	if err := checkPolicyStatRange(c.InboundSignaled, sampled, true, inboundFeesSignaledFunc,
+		w, "Channels announcing inbound fees"); err != nil {
+		return err
+	}
+
+	if fee := c.EffectiveFeeAt; fee != nil {
+		subject := fmt.Sprintf("Channels effective fee rate at %d sats", fee.Amount)
+		err := checkPolicyStatRange(&fee.StatRange, sampled, true, effectiveFeeFunc(fee.Amount), w, subject)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := checkPolicyStatRange(c.Disabled, sampled, true, disabledFunc(true),
+		w, "Disabled channels"); err != nil {
+		return err
+	}
+
+	if err := c.TowardUs.evaluate(nodePublicKey, peer, w); err != nil {
+		return err
 	}
 
 	if c.Peers == nil {
 		return nil
 	}
 
-	if !checkStat(c.Peers.FeeRates, peer, feeRatesFunc(false)) {
-		return errors.New("Peers fee rates " + c.Peers.FeeRates.Reason())
+	if err := checkPolicyStatRange(c.Peers.FeeRates, sampled, false, feeRatesFunc(false),
+		w, "Peers fee rates"); err != nil {
+		return err
 	}
 
-	if !checkStat(c.Peers.BaseFees, peer, baseFeesFunc(false)) {
-		return errors.New("Peers base fees " + c.Peers.BaseFees.Reason())
+	if err := checkPolicyStatRange(c.Peers.BaseFees, sampled, false, baseFeesFunc(false),
+		w, "Peers base fees"); err != nil {
+		return err
 	}
 
-	if !checkStat(c.Peers.InboundFeeRates, peer, inboundFeeRatesFunc(false)) {
-		return errors.New("Peers inbound fee rates " + c.Peers.InboundFeeRates.Reason())
+	if err := checkPolicyStatRange(c.Peers.InboundFeeRates, sampled, false, inboundFeeRatesFunc(false),
+		w, "Peers inbound fee rates"); err != nil {
+		return err
 	}
 
-	if !checkStat(c.Peers.InboundBaseFees, peer, inboundBaseFeesFunc(false)) {
-		return errors.New("Peers inbound base fees " + c.Peers.InboundBaseFees.Reason())
+	if err := checkPolicyStatRange(c.Peers.InboundBaseFees, sampled, false, inboundBaseFeesFunc(false),
+		w, "Peers inbound base fees"); err != nil {
+		return err
 	}
 
-	if !c.checkPeersDisabled(peer) {
-		return errors.New("Peers disabled channels " + c.Peers.Disabled.Reason())
+	if err := checkPolicyStatRange(c.Peers.Disabled, sampled, false, disabledFunc(false),
+		w, "Peers disabled channels"); err != nil {
+		return err
 	}
 
 	return nil
 }
 
+func (t *TowardUs) evaluate(nodePublicKey string, peer *lnrpc.NodeInfo, w *warnings) error {
+	if t == nil {
+		return nil
+	}
+
+	shared := sharedChannels(nodePublicKey, peer)
+	if len(shared.Channels) == 0 {
+		return nil
+	}
+
+	if err := checkPolicyStatRange(t.FeeRates, shared, true, feeRatesFunc(true),
+		w, "Fee rates toward us"); err != nil {
+		return err
+	}
+
+	if err := checkPolicyStatRange(t.BaseFees, shared, true, baseFeesFunc(true),
+		w, "Base fees toward us"); err != nil {
+		return err
+	}
+
+	if err := checkPolicyStatRange(t.InboundFeeRates, shared, true, inboundFeeRatesFunc(true),
+		w, "Inbound fee rates toward us"); err != nil {
+		return err
+	}
+
+	if err := checkPolicyStatRange(t.InboundBaseFees, shared, true, inboundBaseFeesFunc(true),
+		w, "Inbound base fees toward us"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// sharedChannels returns a copy of the peer information containing only the channels it has with
+// our node.
+func sharedChannels(nodePublicKey string, peer *lnrpc.NodeInfo) *lnrpc.NodeInfo {
+	shared := &lnrpc.NodeInfo{Node: peer.Node}
+	for _, channel := range peer.Channels {
+		if isSharedChannel(nodePublicKey, peer.Node.PubKey, channel) {
+			shared.Channels = append(shared.Channels, channel)
+		}
+	}
+	return shared
+}
+
+func isSharedChannel(nodePublicKey, peerPublicKey string, channel *lnrpc.ChannelEdge) bool {
+	return (nodePublicKey == channel.Node1Pub && peerPublicKey == channel.Node2Pub) ||
+		(nodePublicKey == channel.Node2Pub && peerPublicKey == channel.Node1Pub)
+}
+
 func (c *Channels) checkZeroBaseFees(peer *lnrpc.NodeInfo) bool {
 	if c.ZeroBaseFees == nil {
 		return true
@@ -146,8 +259,7 @@ func (c *Channels) checkTogether(nodePublicKey string, peer *lnrpc.NodeInfo) boo
 
 	count := 0
 	for _, channel := range peer.Channels {
-		if (nodePublicKey == channel.Node1Pub && peer.Node.PubKey == channel.Node2Pub) ||
-			(nodePublicKey == channel.Node2Pub && peer.Node.PubKey == channel.Node1Pub) {
+		if isSharedChannel(nodePublicKey, peer.Node.PubKey, channel) {
 			count++
 		}
 	}
@@ -155,54 +267,31 @@ func (c *Channels) checkTogether(nodePublicKey string, peer *lnrpc.NodeInfo) boo
 	return c.Together.Contains(count)
 }
 
-func (c *Channels) checkDisabled(peer *lnrpc.NodeInfo) bool {
-	if c.Disabled == nil {
-		return true
-	}
-
-	disabledChannels := make([]float64, len(peer.Channels))
-	for i, channel := range peer.Channels {
-		policy := getNodePolicy(peer.Node.PubKey, channel, true)
-
-		if policy.Disabled {
-			disabledChannels[i] = 1
-		}
+// getNodePolicy returns the routing policy of the channel's side requested. Channels whose policy
+// is unknown are treated as having all its values set to zero.
+func getNodePolicy(peerPublicKey string, channel *lnrpc.ChannelEdge, outgoing bool) *lnrpc.RoutingPolicy {
+	if policy := nodePolicy(peerPublicKey, channel, outgoing); policy != nil {
+		return policy
 	}
-
-	return c.Disabled.Contains(disabledChannels)
+	return &lnrpc.RoutingPolicy{}
 }
 
-func (c *Channels) checkPeersDisabled(peer *lnrpc.NodeInfo) bool {
-	if c.Peers.Disabled == nil {
-		return true
-	}
-
-	disabledChannels := make([]float64, len(peer.Channels))
-	for i, channel := range peer.Channels {
-		policy := getNodePolicy(peer.Node.PubKey, channel, false)
-
-		if policy.Disabled {
-			disabledChannels[i] = 1
-		}
-	}
-
-	return c.Peers.Disabled.Contains(disabledChannels)
+// hasNodePolicy returns whether the routing policy of the channel's side requested is known.
+func hasNodePolicy(peerPublicKey string, channel *lnrpc.ChannelEdge, outgoing bool) bool {
+	return nodePolicy(peerPublicKey, channel, outgoing) != nil
 }
 
-func getNodePolicy(peerPublicKey string, channel *lnrpc.ChannelEdge, outgoing bool) *lnrpc.RoutingPolicy {
-	if outgoing {
-		if peerPublicKey == channel.Node1Pub {
-			return channel.Node1Policy
-		}
-
-		return channel.Node2Policy
+func nodePolicy(peerPublicKey string, channel *lnrpc.ChannelEdge, outgoing bool) *lnrpc.RoutingPolicy {
+	if channel == nil {
+		return nil
 	}
 
-	if peerPublicKey == channel.Node2Pub {
+	switch {
+	case outgoing && peerPublicKey == channel.Node1Pub, !outgoing && peerPublicKey == channel.Node2Pub:
 		return channel.Node1Policy
+	default:
+		return channel.Node2Policy
 	}
-
-	return channel.Node2Policy
 }
 
 func capacityFunc(_ *lnrpc.NodeInfo, channel *lnrpc.ChannelEdge) int64 {
@@ -255,6 +344,17 @@ func baseFeesFunc(outgoing bool) channelFunc[int64] {
 	}
 }
 
+// effectiveFeeFunc returns the fee rate, in ppm, charged for forwarding a payment of the amount
+// (in sats) received, the base fee included.
+func effectiveFeeFunc(amount uint64) channelFunc[int64] {
+	feeRate := feeRatesFunc(true)
+	return func(peer *lnrpc.NodeInfo, channel *lnrpc.ChannelEdge) int64 {
+		policy := getNodePolicy(peer.Node.PubKey, channel, true)
+		// The base fee in msats over the amount in msats, in parts per million
+		return feeRate(peer, channel) + policy.FeeBaseMsat*1000/int64(amount)
+	}
+}
+
 func inboundFeeRatesFunc(outgoing bool) channelFunc[int32] {
 	return func(peer *lnrpc.NodeInfo, channel *lnrpc.ChannelEdge) int32 {
 		policy := getNodePolicy(peer.Node.PubKey, channel, outgoing)
@@ -268,3 +368,26 @@ func inboundBaseFeesFunc(outgoing bool) channelFunc[int32] {
 		return policy.InboundFeeBaseMsat / 1000
 	}
 }
+
+// inboundFeesSignaledFunc returns whether the node announces inbound fees on the channel, even if
+// they are zero. Nodes that don't support them leave the fields empty, which otherwise looks like
+// a channel without inbound fees.
+func inboundFeesSignaledFunc(peer *lnrpc.NodeInfo, channel *lnrpc.ChannelEdge) float64 {
+	policy := getNodePolicy(peer.Node.PubKey, channel, true)
+	if _, ok := policy.GetCustomRecords()[inboundFeeRecordType]; ok {
+		return 1
+	}
+	if policy.GetInboundFeeBaseMsat() != 0 || policy.GetInboundFeeRateMilliMsat() != 0 {
+		return 1
+	}
+	return 0
+}
+
+func disabledFunc(outgoing bool) channelFunc[float64] {
+	return func(peer *lnrpc.NodeInfo, channel *lnrpc.ChannelEdge) float64 {
+		if getNodePolicy(peer.Node.PubKey, channel, outgoing).Disabled {
+			return 1
+		}
+		return 0
+	}
+}
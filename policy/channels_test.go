@@ -456,7 +456,7 @@ func TestEvaluateChannels(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.desc, func(t *testing.T) {
-			err := tc.channels.evaluate(nodePublicKey, tc.peer)
+			err := tc.channels.evaluate(nodePublicKey, tc.peer, nil)
 			if tc.fail {
 				assert.NotNil(t, err)
 			} else {
@@ -466,6 +466,69 @@ func TestEvaluateChannels(t *testing.T) {
 	}
 }
 
+func TestEffectiveFee(t *testing.T) {
+	peerPublicKey := "peer_public_key"
+	max := int64(150)
+
+	cases := []struct {
+		desc     string
+		policy   *lnrpc.RoutingPolicy
+		amount   uint64
+		expected int64
+		fail     bool
+	}{
+		{
+			desc:     "Fee rate only",
+			policy:   &lnrpc.RoutingPolicy{FeeRateMilliMsat: 100_000},
+			amount:   1_000_000,
+			expected: 100,
+		},
+		{
+			desc:     "Base fee only",
+			policy:   &lnrpc.RoutingPolicy{FeeBaseMsat: 1_000_000},
+			amount:   1_000_000,
+			expected: 1000,
+			fail:     true,
+		},
+		{
+			desc:     "Both",
+			policy:   &lnrpc.RoutingPolicy{FeeBaseMsat: 50_000, FeeRateMilliMsat: 100_000},
+			amount:   1_000_000,
+			expected: 150,
+		},
+		{
+			desc:     "Small amount",
+			policy:   &lnrpc.RoutingPolicy{FeeBaseMsat: 50_000, FeeRateMilliMsat: 100_000},
+			amount:   100_000,
+			expected: 600,
+			fail:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			peer := &lnrpc.NodeInfo{
+				Node:     &lnrpc.LightningNode{PubKey: peerPublicKey},
+				Channels: []*lnrpc.ChannelEdge{{Node1Pub: peerPublicKey, Node1Policy: tc.policy}},
+			}
+
+			actual := effectiveFeeFunc(tc.amount)(peer, peer.Channels[0])
+			assert.Equal(t, tc.expected, actual)
+
+			channels := &Channels{EffectiveFeeAt: &EffectiveFee{
+				Amount:    tc.amount,
+				StatRange: StatRange[int64]{Max: &max},
+			}}
+			err := channels.evaluate("node_public_key", peer, nil)
+			if tc.fail {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestCheckCapacity(t *testing.T) {
 	min := int64(100_000)
 	max := int64(1_000_000)
@@ -694,6 +757,106 @@ func TestCheckTogether(t *testing.T) {
 	})
 }
 
+func TestEvaluateTowardUs(t *testing.T) {
+	nodePublicKey := "node_public_key"
+	peerPublicKey := "peer_public_key"
+	max64 := int64(100)
+	max32 := int32(0)
+
+	peer := &lnrpc.NodeInfo{
+		Node: &lnrpc.LightningNode{
+			PubKey: peerPublicKey,
+		},
+		Channels: []*lnrpc.ChannelEdge{
+			{
+				Node1Pub: nodePublicKey,
+				Node2Pub: peerPublicKey,
+				Node1Policy: &lnrpc.RoutingPolicy{
+					FeeRateMilliMsat: 5_000_000,
+				},
+				Node2Policy: &lnrpc.RoutingPolicy{
+					FeeRateMilliMsat:        50_000,
+					FeeBaseMsat:             1000,
+					InboundFeeRateMilliMsat: -1000,
+				},
+			},
+			{
+				Node1Pub: peerPublicKey,
+				Node2Pub: "other_public_key",
+				Node1Policy: &lnrpc.RoutingPolicy{
+					FeeRateMilliMsat: 2_000_000,
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		towardUs      *TowardUs
+		desc          string
+		nodePublicKey string
+		fail          bool
+	}{
+		{
+			desc:          "Nil",
+			nodePublicKey: nodePublicKey,
+		},
+		{
+			desc:          "Fee rates",
+			nodePublicKey: nodePublicKey,
+			towardUs: &TowardUs{
+				FeeRates: &StatRange[int64]{Max: &max64},
+			},
+		},
+		{
+			desc:          "Base fees",
+			nodePublicKey: nodePublicKey,
+			towardUs: &TowardUs{
+				BaseFees: &StatRange[int64]{Max: &max64},
+			},
+		},
+		{
+			desc:          "Inbound fee rates",
+			nodePublicKey: nodePublicKey,
+			towardUs: &TowardUs{
+				InboundFeeRates: &StatRange[int32]{Max: &max32},
+			},
+		},
+		{
+			desc:          "Fee rates too high",
+			nodePublicKey: nodePublicKey,
+			towardUs: &TowardUs{
+				FeeRates: &StatRange[int64]{Max: new(int64)},
+			},
+			fail: true,
+		},
+		{
+			desc:          "Inbound base fees",
+			nodePublicKey: nodePublicKey,
+			towardUs: &TowardUs{
+				InboundBaseFees: &StatRange[int32]{Min: &max32, Max: &max32},
+			},
+		},
+		{
+			desc:          "No channels together",
+			nodePublicKey: "unknown_public_key",
+			towardUs: &TowardUs{
+				FeeRates: &StatRange[int64]{Max: new(int64)},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.towardUs.evaluate(tc.nodePublicKey, peer, nil)
+			if tc.fail {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestCheckPeersDisabled(t *testing.T) {
 	peerPublicKey := "peer_public_key"
 	value := 0.6
@@ -790,14 +953,14 @@ func TestCheckPeersDisabled(t *testing.T) {
 				},
 			}
 
-			actual := channels.checkPeersDisabled(tc.peer)
+			actual := checkStat(channels.Peers.Disabled, tc.peer, disabledFunc(false))
 			assert.Equal(t, tc.expected, actual)
 		})
 	}
 
 	t.Run("Nil", func(t *testing.T) {
 		channels := Channels{Peers: &Peers{}}
-		assert.True(t, channels.checkPeersDisabled(nil))
+		assert.True(t, checkStat(channels.Peers.Disabled, nil, disabledFunc(false)))
 	})
 }
 
@@ -943,14 +1106,14 @@ func TestCheckDisabled(t *testing.T) {
 				Disabled: tc.disabled,
 			}
 
-			actual := channels.checkDisabled(tc.peer)
+			actual := checkStat(channels.Disabled, tc.peer, disabledFunc(true))
 			assert.Equal(t, tc.expected, actual)
 		})
 	}
 
 	t.Run("Nil", func(t *testing.T) {
 		channels := Channels{}
-		assert.True(t, channels.checkDisabled(nil))
+		assert.True(t, checkStat(channels.Disabled, nil, disabledFunc(true)))
 	})
 }
 
@@ -1018,6 +1181,18 @@ func TestGetNodePolicy(t *testing.T) {
 			assert.Equal(t, expectedPolicy, actual)
 		})
 	}
+
+	t.Run("Unknown policy", func(t *testing.T) {
+		channel := &lnrpc.ChannelEdge{Node1Pub: publicKey, Node2Policy: otherPolicy}
+		actual := getNodePolicy(publicKey, channel, true)
+		assert.Equal(t, &lnrpc.RoutingPolicy{}, actual)
+		assert.False(t, hasNodePolicy(publicKey, channel, true))
+		assert.True(t, hasNodePolicy(publicKey, channel, false))
+	})
+
+	t.Run("Nil channel", func(t *testing.T) {
+		assert.Equal(t, &lnrpc.RoutingPolicy{}, getNodePolicy(publicKey, nil, true))
+	})
 }
 
 func TestBlockHeightFunc(t *testing.T) {
@@ -1192,3 +1367,85 @@ func TestInboundBaseFeesFunc(t *testing.T) {
 		assert.Equal(t, expected, actual)
 	})
 }
+
+func TestInboundFeesSignaledFunc(t *testing.T) {
+	publicKey := "public_key"
+	peer := &lnrpc.NodeInfo{Node: &lnrpc.LightningNode{PubKey: publicKey}}
+
+	cases := []struct {
+		desc     string
+		policy   *lnrpc.RoutingPolicy
+		expected float64
+	}{
+		{
+			desc:     "Not signaled",
+			policy:   &lnrpc.RoutingPolicy{FeeRateMilliMsat: 1000},
+			expected: 0,
+		},
+		{
+			desc:     "Missing policy",
+			expected: 0,
+		},
+		{
+			desc: "Zero inbound fees",
+			policy: &lnrpc.RoutingPolicy{
+				CustomRecords: map[uint64][]byte{inboundFeeRecordType: make([]byte, 8)},
+			},
+			expected: 1,
+		},
+		{
+			desc:     "Discount",
+			policy:   &lnrpc.RoutingPolicy{InboundFeeRateMilliMsat: -100_000},
+			expected: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			channel := &lnrpc.ChannelEdge{Node1Pub: publicKey, Node1Policy: tc.policy}
+			actual := inboundFeesSignaledFunc(peer, channel)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestInboundDiscounts(t *testing.T) {
+	publicKey := "public_key"
+	zero := int32(0)
+	peer := &lnrpc.NodeInfo{
+		Node: &lnrpc.LightningNode{PubKey: publicKey},
+		Channels: []*lnrpc.ChannelEdge{
+			{Node1Pub: publicKey, Node1Policy: &lnrpc.RoutingPolicy{InboundFeeRateMilliMsat: -200_000}},
+			{Node1Pub: publicKey, Node1Policy: &lnrpc.RoutingPolicy{InboundFeeRateMilliMsat: 100_000}},
+		},
+	}
+
+	cases := []struct {
+		desc      string
+		operation Operation
+		fail      bool
+	}{
+		{
+			desc: "Discounts compensate charges",
+		},
+		{
+			desc:      "No positive inbound fees",
+			operation: MaxOp,
+			fail:      true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			channels := Channels{
+				InboundFeeRates: &StatRange[int32]{Max: &zero, Operation: tc.operation},
+			}
+			err := channels.evaluate("", peer, nil)
+			if tc.fail {
+				assert.EqualError(t, err, "Channels inbound fee rates max value is higher than 0")
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
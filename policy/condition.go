@@ -6,13 +6,21 @@ import (
 )
 
 // Conditions represents a set of requirements that must be met to apply a policy.
+//
+// It may also hold a list of condition sets, in which case matching any of them is enough.
 type Conditions struct {
-	IsPrivate     *bool     `yaml:"is_private,omitempty"`
-	WantsZeroConf *bool     `yaml:"wants_zero_conf,omitempty"`
-	Is            *[]string `yaml:"is,omitempty"`
-	IsNot         *[]string `yaml:"is_not,omitempty"`
-	Request       *Request  `yaml:"request,omitempty"`
-	Node          *Node     `yaml:"node,omitempty"`
+	IsPrivate        *bool             `yaml:"is_private,omitempty" doc:"Match private channels."`
+	WantsZeroConf    *bool             `yaml:"wants_zero_conf,omitempty" doc:"Match zero confirmation channels."`
+	Is               *[]string         `yaml:"is,omitempty" doc:"Public keys of the nodes the policy applies to."`
+	IsNot            *[]string         `yaml:"is_not,omitempty" doc:"Public keys of the nodes the policy does not apply to."`
+	Request          *Request          `yaml:"request,omitempty" doc:"Match channel opening requests meeting these requirements."`
+	Node             *Node             `yaml:"node,omitempty" doc:"Match initiator nodes meeting these requirements."`
+	SuspiciousAmount *SuspiciousAmount `yaml:"suspicious_amount,omitempty" doc:"Match requests whose funding amount is suspicious."`
+	// Any contains the condition sets defined as a list.
+	Any []*Conditions `yaml:"-"`
+
+	// legacy enables the Version1 semantics, see SetVersion.
+	legacy bool
 }
 
 // Match returns true if all the conditions Match.
@@ -20,13 +28,29 @@ func (c *Conditions) Match(
 	req *lnrpc.ChannelAcceptRequest,
 	node *lnrpc.GetInfoResponse,
 	peer *lnrpc.NodeInfo,
+	facts *Facts,
 ) bool {
 	if c == nil {
 		return true
 	}
 
-	if c.checkIs(peer.Node.PubKey) {
-		return true
+	if len(c.Any) > 0 {
+		for _, group := range c.Any {
+			if group.Match(req, node, peer, facts) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if c.Is != nil {
+		isListed := c.checkIs(peer.Node.PubKey)
+		if c.legacy && isListed {
+			return true
+		}
+		if !c.legacy && !isListed {
+			return false
+		}
 	}
 
 	if !c.checkIsNot(peer.Node.PubKey) {
@@ -41,17 +65,48 @@ func (c *Conditions) Match(
 		return false
 	}
 
-	if err := c.Request.evaluate(req); err != nil {
+	if c.SuspiciousAmount != nil && !c.SuspiciousAmount.match(req.FundingAmt) {
 		return false
 	}
 
-	if err := c.Node.evaluate(node, peer); err != nil {
+	if err := c.Request.evaluate(req, nil); err != nil {
+		return false
+	}
+
+	if err := c.Node.evaluate(req, node, peer, facts, nil); err != nil {
 		return false
 	}
 
 	return true
 }
 
+// UnmarshalYAML decodes either a single set of conditions or a list of them.
+func (c *Conditions) UnmarshalYAML(unmarshal func(any) error) error {
+	var raw any
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	if _, ok := raw.([]any); ok {
+		return unmarshal(&c.Any)
+	}
+
+	type plain Conditions
+	return unmarshal((*plain)(c))
+}
+
+// groups returns the condition sets, a single one if they weren't defined as a list.
+func (c *Conditions) groups() []*Conditions {
+	switch {
+	case c == nil:
+		return nil
+	case len(c.Any) > 0:
+		return c.Any
+	default:
+		return []*Conditions{c}
+	}
+}
+
 func (c *Conditions) checkIs(publicKey string) bool {
 	if c.Is == nil {
 		return false
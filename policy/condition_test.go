@@ -6,6 +6,7 @@ import (
 	"github.com/lightningnetwork/lnd/lnrpc"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
 )
 
 func TestMatch(t *testing.T) {
@@ -95,7 +96,17 @@ func TestMatch(t *testing.T) {
 			peer:     defaultPeer,
 			expected: false,
 		},
-
+		{
+			desc: "Suspicious amount",
+			conditions: &Conditions{
+				SuspiciousAmount: &SuspiciousAmount{Values: []uint64{10_000}},
+			},
+			req: &lnrpc.ChannelAcceptRequest{
+				FundingAmt: 12_345,
+			},
+			peer:     defaultPeer,
+			expected: false,
+		},
 		{
 			desc: "Node",
 			conditions: &Conditions{
@@ -118,7 +129,39 @@ func TestMatch(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.desc, func(t *testing.T) {
-			actual := tc.conditions.Match(tc.req, node, tc.peer)
+			actual := tc.conditions.Match(tc.req, node, tc.peer, nil)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestMatchVersion(t *testing.T) {
+	node := &lnrpc.GetInfoResponse{IdentityPubkey: "node_public_key"}
+	peer := &lnrpc.NodeInfo{Node: &lnrpc.LightningNode{PubKey: "peer_public_key"}}
+	private := &lnrpc.ChannelAcceptRequest{}
+	public := &lnrpc.ChannelAcceptRequest{ChannelFlags: uint32(lnwire.FFAnnounceChannel)}
+	tru := true
+
+	cases := []struct {
+		req      *lnrpc.ChannelAcceptRequest
+		is       []string
+		desc     string
+		version  int
+		expected bool
+	}{
+		{desc: "V1 listed", version: Version1, is: []string{"peer_public_key"}, req: public, expected: true},
+		{desc: "V1 not listed", version: Version1, is: []string{"other"}, req: private, expected: true},
+		{desc: "V2 listed", version: Version2, is: []string{"peer_public_key"}, req: private, expected: true},
+		{desc: "V2 listed no match", version: Version2, is: []string{"peer_public_key"}, req: public},
+		{desc: "V2 not listed", version: Version2, is: []string{"other"}, req: private},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			policies := []*Policy{{Conditions: &Conditions{Is: &tc.is, IsPrivate: &tru}}}
+			SetVersion(policies, tc.version)
+
+			actual := policies[0].Conditions.Match(tc.req, node, peer, nil)
 			assert.Equal(t, tc.expected, actual)
 		})
 	}
@@ -303,3 +346,48 @@ func TestConditionsCheckWantsZeroConf(t *testing.T) {
 		assert.True(t, actual)
 	})
 }
+
+func TestUnmarshalConditions(t *testing.T) {
+	var p Policy
+	err := yaml.Unmarshal([]byte("conditions:\n  is_private: true\n"), &p)
+	assert.NoError(t, err)
+	assert.True(t, *p.Conditions.IsPrivate)
+	assert.Empty(t, p.Conditions.Any)
+
+	p = Policy{}
+	err = yaml.Unmarshal([]byte(`
+conditions:
+  - is_private: true
+  - wants_zero_conf: true
+    request:
+      channel_capacity:
+        min: 1m
+`), &p)
+	assert.NoError(t, err)
+	assert.Nil(t, p.Conditions.IsPrivate)
+	assert.Len(t, p.Conditions.Any, 2)
+	assert.True(t, *p.Conditions.Any[0].IsPrivate)
+	assert.Equal(t, uint64(1_000_000), *p.Conditions.Any[1].Request.ChannelCapacity.Min)
+
+	err = yaml.Unmarshal([]byte("conditions:\n  - is_private: maybe\n"), &p)
+	assert.Error(t, err)
+}
+
+func TestMatchAny(t *testing.T) {
+	node := &lnrpc.GetInfoResponse{IdentityPubkey: "node_public_key"}
+	peer := &lnrpc.NodeInfo{Node: &lnrpc.LightningNode{PubKey: "peer_public_key"}}
+	tru := true
+	conditions := &Conditions{
+		Any: []*Conditions{
+			{IsPrivate: &tru},
+			{WantsZeroConf: &tru},
+		},
+	}
+
+	public := uint32(lnwire.FFAnnounceChannel)
+	assert.True(t, conditions.Match(&lnrpc.ChannelAcceptRequest{}, node, peer, nil))
+	assert.True(t, conditions.Match(
+		&lnrpc.ChannelAcceptRequest{ChannelFlags: public, WantsZeroConf: true}, node, peer, nil,
+	))
+	assert.False(t, conditions.Match(&lnrpc.ChannelAcceptRequest{ChannelFlags: public}, node, peer, nil))
+}
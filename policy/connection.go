@@ -0,0 +1,106 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// Connection represents a set of requirements the address the peer is connected from must
+// satisfy, it may differ from the addresses announced in the graph.
+type Connection struct {
+	Networks         *[]string `yaml:"networks,omitempty" doc:"Networks (in CIDR notation) the address must belong to."`
+	ExcludedNetworks *[]string `yaml:"excluded_networks,omitempty" doc:"Networks the address must not belong to."`
+	Tor              *bool     `yaml:"tor,omitempty" doc:"Whether the peer must (or must not) be connected through Tor."`
+}
+
+func (c *Connection) evaluate(facts *Facts) error {
+	if c == nil {
+		return nil
+	}
+
+	addr, onion := facts.address()
+
+	if c.Networks != nil && !containsAddr(*c.Networks, addr) {
+		return fmt.Errorf("Node connection address is not in %s", *c.Networks)
+	}
+
+	if c.ExcludedNetworks != nil && containsAddr(*c.ExcludedNetworks, addr) {
+		return fmt.Errorf("Node connection address is in %s", *c.ExcludedNetworks)
+	}
+
+	if c.Tor != nil {
+		// Connections to our onion service come from the local Tor daemon
+		tor := onion || addr.IsLoopback() || facts.torExit()
+		if *c.Tor && !tor {
+			return errors.New("Node is not connected through Tor")
+		}
+		if !*c.Tor && tor {
+			return errors.New("Node is connected through Tor")
+		}
+	}
+
+	return nil
+}
+
+func (c *Connection) validate(field string) error {
+	if c == nil {
+		return nil
+	}
+
+	if err := validateNetworks(field+".networks", c.Networks); err != nil {
+		return err
+	}
+
+	return validateNetworks(field+".excluded_networks", c.ExcludedNetworks)
+}
+
+func validateNetworks(field string, networks *[]string) error {
+	if networks == nil {
+		return nil
+	}
+
+	for i, network := range *networks {
+		if _, err := netip.ParsePrefix(network); err != nil {
+			return fmt.Errorf("%s[%d]: invalid network %q", field, i, network)
+		}
+	}
+
+	return nil
+}
+
+// containsAddr returns whether any of the networks contains the address, invalid addresses
+// belong to none.
+func containsAddr(networks []string, addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return false
+	}
+
+	for _, network := range networks {
+		prefix, err := netip.ParsePrefix(network)
+		if err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAddress returns the IP of a host:port address and whether it's an onion service.
+func parseAddress(address string) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+
+	if strings.HasSuffix(host, ".onion") {
+		return netip.Addr{}, true
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), false
+}
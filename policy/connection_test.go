@@ -0,0 +1,93 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateConnection(t *testing.T) {
+	tru := true
+	fals := false
+
+	cases := []struct {
+		connection *Connection
+		facts      *Facts
+		desc       string
+		fail       bool
+	}{
+		{
+			desc:       "Nil",
+			connection: nil,
+			facts:      &Facts{Address: "203.0.113.5:9735"},
+		},
+		{
+			desc:       "In network",
+			connection: &Connection{Networks: &[]string{"203.0.113.0/24"}},
+			facts:      &Facts{Address: "203.0.113.5:9735"},
+		},
+		{
+			desc:       "Not in network",
+			connection: &Connection{Networks: &[]string{"198.51.100.0/24"}},
+			facts:      &Facts{Address: "203.0.113.5:9735"},
+			fail:       true,
+		},
+		{
+			desc:       "Unknown address",
+			connection: &Connection{Networks: &[]string{"0.0.0.0/0"}},
+			facts:      &Facts{},
+			fail:       true,
+		},
+		{
+			desc:       "Excluded network",
+			connection: &Connection{ExcludedNetworks: &[]string{"2001:db8::/32"}},
+			facts:      &Facts{Address: "[2001:db8::1]:9735"},
+			fail:       true,
+		},
+		{
+			desc:       "IPv4 mapped address",
+			connection: &Connection{ExcludedNetworks: &[]string{"203.0.113.0/24"}},
+			facts:      &Facts{Address: "[::ffff:203.0.113.5]:9735"},
+			fail:       true,
+		},
+		{
+			desc:       "Unknown address excluded network",
+			connection: &Connection{ExcludedNetworks: &[]string{"0.0.0.0/0"}},
+			facts:      nil,
+		},
+		{
+			desc:       "Onion",
+			connection: &Connection{Tor: &tru},
+			facts:      &Facts{Address: "abcdefghijklmnop.onion:9735"},
+		},
+		{
+			desc:       "Onion service",
+			connection: &Connection{Tor: &fals},
+			facts:      &Facts{Address: "127.0.0.1:50432"},
+			fail:       true,
+		},
+		{
+			desc:       "Tor exit",
+			connection: &Connection{Tor: &fals},
+			facts:      &Facts{Address: "198.51.100.7:45123", TorExit: true},
+			fail:       true,
+		},
+		{
+			desc:       "Clearnet",
+			connection: &Connection{Tor: &tru},
+			facts:      &Facts{Address: "198.51.100.7:45123"},
+			fail:       true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.connection.evaluate(tc.facts)
+			if tc.fail {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
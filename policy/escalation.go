@@ -0,0 +1,35 @@
+package policy
+
+import (
+	"time"
+)
+
+// Escalation limits the channels requested by peers until one of their channels with us has
+// proven to be reliable.
+type Escalation struct {
+	ChannelCapacity *Range[uint64] `yaml:"channel_capacity,omitempty" unit:"amount" doc:"Capacity allowed while the peer is not established."`
+	Uptime          *Range[uint64] `yaml:"uptime,omitempty" unit:"duration" doc:"Seconds a channel must have been active for the peer to be considered established. Having a channel is enough if not set."`
+}
+
+func (e *Escalation) evaluate(capacity uint64, facts *Facts, w *warnings) error {
+	if e == nil || e.established(facts) {
+		return nil
+	}
+
+	return checkRange(e.ChannelCapacity, capacity, w, "Channel capacity for new peers")
+}
+
+// established returns whether the longest uptime of the peer channels with us satisfies the
+// requirement. If no uptime is required, having had any channel is enough.
+func (e *Escalation) established(facts *Facts) bool {
+	if facts == nil {
+		return false
+	}
+
+	uptime := uint64(facts.MaxChannelUptime / time.Second)
+	if e.Uptime == nil {
+		return facts.MaxChannelUptime > 0
+	}
+
+	return e.Uptime.Contains(uptime)
+}
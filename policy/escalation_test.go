@@ -0,0 +1,81 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateEscalation(t *testing.T) {
+	max := uint64(1_000_000)
+	minUptime := uint64(30 * 24 * 60 * 60)
+	escalation := &Escalation{
+		ChannelCapacity: &Range[uint64]{Max: &max},
+		Uptime:          &Range[uint64]{Min: &minUptime},
+	}
+
+	cases := []struct {
+		escalation *Escalation
+		facts      *Facts
+		desc       string
+		capacity   uint64
+		fail       bool
+	}{
+		{
+			desc:     "Nil",
+			capacity: 5_000_000,
+		},
+		{
+			desc:       "New peer small channel",
+			escalation: escalation,
+			facts:      &Facts{},
+			capacity:   500_000,
+		},
+		{
+			desc:       "New peer big channel",
+			escalation: escalation,
+			facts:      &Facts{},
+			capacity:   5_000_000,
+			fail:       true,
+		},
+		{
+			desc:       "Nil facts",
+			escalation: escalation,
+			capacity:   5_000_000,
+			fail:       true,
+		},
+		{
+			desc:       "Not established yet",
+			escalation: escalation,
+			facts:      &Facts{MaxChannelUptime: 10 * 24 * time.Hour},
+			capacity:   5_000_000,
+			fail:       true,
+		},
+		{
+			desc:       "Established",
+			escalation: escalation,
+			facts:      &Facts{MaxChannelUptime: 31 * 24 * time.Hour},
+			capacity:   5_000_000,
+		},
+		{
+			desc: "Established without uptime requirement",
+			escalation: &Escalation{
+				ChannelCapacity: &Range[uint64]{Max: &max},
+			},
+			facts:    &Facts{MaxChannelUptime: time.Minute},
+			capacity: 5_000_000,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.escalation.evaluate(tc.capacity, tc.facts, nil)
+			if tc.fail {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
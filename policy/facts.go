@@ -0,0 +1,159 @@
+package policy
+
+import (
+	"net/netip"
+	"time"
+)
+
+// Facts contains information about the request gathered by acceptLND that the lightning node
+// doesn't provide.
+type Facts struct {
+	// Time the request was received at.
+	Now time.Time
+	// First time the peer requested to open a channel with us.
+	FirstSeen time.Time
+	// Longest time any of the peer channels with us has been active.
+	MaxChannelUptime time.Duration
+	// Nodes we have public channels with.
+	Peers map[string]struct{}
+	// Nodes we are already connected to either directly or through our peers, including our own
+	// node.
+	Reach map[string]struct{}
+	// Peer reputation score, decayed up to the time of the request.
+	Reputation float64
+	// Number of the peer requests we accepted and rejected before.
+	Acceptances uint64
+	Rejections  uint64
+	// Time of our last decision on a peer request, zero if there wasn't any.
+	LastDecision time.Time
+	// Categories of the peer in the registry of known services, empty if it's not known.
+	KnownAs []string
+	// Address (host:port) the peer is connected from, empty if it's unknown.
+	Address string
+	// Whether the address is a known Tor exit relay.
+	TorExit bool
+	// Number of our channels with peers connected from the same IP address and autonomous system
+	// as the peer, zero if its origin is unknown or it's connected through Tor.
+	SameIPChannels  uint32
+	SameASNChannels uint32
+	// Whether the peer accepts connections on any of its announced addresses.
+	Reachable bool
+	// Sum of the remote balances of the channels our peers opened, in satoshis.
+	InboundCapacity uint64
+	// Our onchain fee estimate in satoshis per kilo-weight unit, zero if it's unknown.
+	FeeRate uint64
+	// Violations of the requirements with the warn severity, appended by the policies enforced.
+	Warnings []string
+}
+
+// now returns the time of the request, falling back to the current time if it's not known.
+func (f *Facts) now() time.Time {
+	if f == nil || f.Now.IsZero() {
+		return time.Now()
+	}
+	return f.Now
+}
+
+// firstSeen returns the first time the peer was seen, when it's not known the peer is considered
+// to be seen for the first time now.
+func (f *Facts) firstSeen() time.Time {
+	if f == nil || f.FirstSeen.IsZero() {
+		return f.now()
+	}
+	return f.FirstSeen
+}
+
+// reaches returns whether the node is already reachable through our peers.
+func (f *Facts) reaches(publicKey string) bool {
+	if f == nil {
+		return false
+	}
+	_, ok := f.Reach[publicKey]
+	return ok
+}
+
+// isPeer returns whether we have a channel with the node.
+func (f *Facts) isPeer(publicKey string) bool {
+	if f == nil {
+		return false
+	}
+	_, ok := f.Peers[publicKey]
+	return ok
+}
+
+// reputation returns the peer reputation score, unknown peers have a neutral score.
+func (f *Facts) reputation() float64 {
+	if f == nil {
+		return 0
+	}
+	return f.Reputation
+}
+
+// rejections returns the number of the peer requests we rejected before.
+func (f *Facts) rejections() uint64 {
+	if f == nil {
+		return 0
+	}
+	return f.Rejections
+}
+
+// acceptances returns the number of the peer requests we accepted before.
+func (f *Facts) acceptances() uint64 {
+	if f == nil {
+		return 0
+	}
+	return f.Acceptances
+}
+
+// knownAs returns the categories of the peer in the registry of known services.
+func (f *Facts) knownAs() []string {
+	if f == nil {
+		return nil
+	}
+	return f.KnownAs
+}
+
+// address returns the IP the peer is connected from and whether it's an onion address. The IP is
+// invalid if it's not known.
+func (f *Facts) address() (netip.Addr, bool) {
+	if f == nil {
+		return netip.Addr{}, false
+	}
+	return parseAddress(f.Address)
+}
+
+// torExit returns whether the peer is connected from a Tor exit relay.
+func (f *Facts) torExit() bool {
+	return f != nil && f.TorExit
+}
+
+// sameIPChannels returns the number of channels with peers connected from the peer IP address.
+func (f *Facts) sameIPChannels() uint32 {
+	if f == nil {
+		return 0
+	}
+	return f.SameIPChannels
+}
+
+// sameASNChannels returns the number of channels with peers connected from the peer autonomous
+// system.
+func (f *Facts) sameASNChannels() uint32 {
+	if f == nil {
+		return 0
+	}
+	return f.SameASNChannels
+}
+
+// inboundCapacity returns the inbound capacity of the channels our peers opened, zero if it's
+// unknown.
+func (f *Facts) inboundCapacity() uint64 {
+	if f == nil {
+		return 0
+	}
+	return f.InboundCapacity
+}
+
+// reachable returns whether the peer accepts connections on its announced addresses.
+func (f *Facts) reachable() bool {
+	return f != nil && f.Reachable
+}
@@ -0,0 +1,88 @@
+package policy
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// Handling of the channels whose routing policy is unknown, as the node side never announced it
+// or the graph doesn't have it yet.
+const (
+	// MissingZero treats the values of the missing policies as zero, it's the default.
+	MissingZero = "zero"
+	// MissingSkip leaves the channels without the policy out of the statistic.
+	MissingSkip = "skip"
+	// MissingFail makes the requirement fail if any channel doesn't have the policy.
+	MissingFail = "fail"
+)
+
+// missing is implemented by the statistic ranges to report how channels without routing policy
+// are handled.
+type missing interface {
+	missingPolicy() string
+}
+
+func (a StatRange[T]) missingPolicy() string {
+	return a.MissingPolicy
+}
+
+// checkPolicyStatRange is like checkStatRange for the values read from the routing policies of
+// the channels, the outgoing one or the partners' one, handling the channels that don't have it as
+// the statistic range specifies.
+func checkPolicyStatRange[T Number](
+	sr *StatRange[T],
+	peer *lnrpc.NodeInfo,
+	outgoing bool,
+	f channelFunc[T],
+	w *warnings,
+	subject string,
+) error {
+	if sr == nil {
+		return nil
+	}
+
+	switch sr.MissingPolicy {
+	case MissingSkip:
+		peer = withPolicies(peer, outgoing)
+	case MissingFail:
+		for _, channel := range peer.Channels {
+			if !hasNodePolicy(peer.Node.PubKey, channel, outgoing) {
+				reason := fmt.Sprintf("%s unknown, channel %d has no routing policy", subject, channel.ChannelId)
+				return w.violation(sr, reason)
+			}
+		}
+	}
+
+	return checkStatRange(sr, peer, f, w, subject)
+}
+
+// withPolicies returns a copy of the peer information containing only the channels whose routing
+// policy requested is known.
+func withPolicies(peer *lnrpc.NodeInfo, outgoing bool) *lnrpc.NodeInfo {
+	known := &lnrpc.NodeInfo{Node: peer.Node}
+	for _, channel := range peer.Channels {
+		if hasNodePolicy(peer.Node.PubKey, channel, outgoing) {
+			known.Channels = append(known.Channels, channel)
+		}
+	}
+	return known
+}
+
+// validateMissingPolicies verifies the missing policy handling of the statistic ranges in the
+// value received.
+func validateMissingPolicies(v reflect.Value, path string) error {
+	var err error
+	walkRanges(v, path, func(path string, b bounded) {
+		if m, ok := b.(missing); ok && err == nil {
+			switch m.missingPolicy() {
+			case "", MissingZero, MissingSkip, MissingFail:
+			default:
+				err = fmt.Errorf("%s.missing_policy: must be %s, %s or %s",
+					path, MissingZero, MissingSkip, MissingFail)
+			}
+		}
+	})
+	return err
+}
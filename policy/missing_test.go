@@ -0,0 +1,101 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMissingPolicy(t *testing.T) {
+	publicKey := "public_key"
+	peer := &lnrpc.NodeInfo{
+		Node: &lnrpc.LightningNode{PubKey: publicKey},
+		Channels: []*lnrpc.ChannelEdge{
+			{ChannelId: 1, Node1Pub: publicKey, Node1Policy: &lnrpc.RoutingPolicy{FeeRateMilliMsat: 600_000}},
+			{ChannelId: 2, Node1Pub: publicKey, Node1Policy: &lnrpc.RoutingPolicy{FeeRateMilliMsat: 600_000}},
+			{ChannelId: 3, Node1Pub: publicKey},
+		},
+	}
+	min := int64(500)
+
+	cases := []struct {
+		desc          string
+		missingPolicy string
+		severity      string
+		err           string
+		warnings      []string
+	}{
+		{
+			desc: "Default",
+			err:  "Channels fee rates mean value is lower than 500",
+		},
+		{
+			desc:          "Zero",
+			missingPolicy: MissingZero,
+			err:           "Channels fee rates mean value is lower than 500",
+		},
+		{
+			desc:          "Skip",
+			missingPolicy: MissingSkip,
+		},
+		{
+			desc:          "Fail",
+			missingPolicy: MissingFail,
+			err:           "Channels fee rates unknown, channel 3 has no routing policy",
+		},
+		{
+			desc:          "Fail warning",
+			missingPolicy: MissingFail,
+			severity:      CheckWarn,
+			warnings:      []string{"Channels fee rates unknown, channel 3 has no routing policy"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			channels := Channels{FeeRates: &StatRange[int64]{
+				Min:           &min,
+				Severity:      tc.severity,
+				MissingPolicy: tc.missingPolicy,
+			}}
+			w := &warnings{}
+
+			err := channels.evaluate("", peer, w)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.warnings, w.messages)
+		})
+	}
+}
+
+func TestMissingPolicyPeers(t *testing.T) {
+	publicKey := "public_key"
+	peer := &lnrpc.NodeInfo{
+		Node: &lnrpc.LightningNode{PubKey: publicKey},
+		Channels: []*lnrpc.ChannelEdge{
+			{Node1Pub: publicKey, Node1Policy: &lnrpc.RoutingPolicy{}},
+		},
+	}
+	max := 0.5
+
+	channels := Channels{Peers: &Peers{Disabled: &StatRange[float64]{Max: &max, MissingPolicy: MissingFail}}}
+	err := channels.evaluate("", peer, nil)
+	assert.EqualError(t, err, "Peers disabled channels unknown, channel 0 has no routing policy")
+
+	channels.Disabled = &StatRange[float64]{Max: &max, MissingPolicy: MissingFail}
+	channels.Peers = nil
+	assert.NoError(t, channels.evaluate("", peer, nil))
+}
+
+func TestValidateMissingPolicy(t *testing.T) {
+	policy := Policy{Node: &Node{Channels: &Channels{
+		TowardUs: &TowardUs{BaseFees: &StatRange[int64]{MissingPolicy: "ignore"}},
+	}}}
+
+	err := policy.Validate()
+	assert.EqualError(t, err, "node.channels.toward_us.base_fees.missing_policy: must be zero, skip or fail")
+}
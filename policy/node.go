@@ -3,31 +3,66 @@ package policy
 import (
 	"errors"
 	"math"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
 )
 
 // Node represents a set of requirements the node requesting to open a channel must satisfy.
 type Node struct {
-	Age          *Range[uint32]      `yaml:"age,omitempty"`
-	Capacity     *Range[int64]       `yaml:"capacity,omitempty"`
-	Hybrid       *bool               `yaml:"hybrid,omitempty"`
-	FeatureFlags *[]lnrpc.FeatureBit `yaml:"feature_flags,omitempty"`
-	Channels     *Channels           `yaml:"channels,omitempty"`
+	Age            *Range[uint32]      `yaml:"age,omitempty" unit:"number" doc:"Node age in blocks, based on the oldest announced channel."`
+	Capacity       *Range[int64]       `yaml:"capacity,omitempty" unit:"amount" doc:"Node capacity, in sats."`
+	Hybrid         *bool               `yaml:"hybrid,omitempty" doc:"Whether the node must announce both clearnet and onion addresses."`
+	FeatureFlags   *[]lnrpc.FeatureBit `yaml:"feature_flags,omitempty" doc:"Feature flags the node must know, see lnrpc.FeatureBit."`
+	Channels       *Channels           `yaml:"channels,omitempty" doc:"Requirements of the node channels."`
+	FirstSeenAge   *Range[uint64]      `yaml:"first_seen_age,omitempty" unit:"duration" doc:"Seconds elapsed since the node requested to open a channel with us for the first time."`
+	GraphFreshness *Range[uint64]      `yaml:"graph_freshness,omitempty" unit:"duration" doc:"Seconds elapsed since the node announcement was last updated in our graph."`
+	NewReach       *Range[uint32]      `yaml:"new_reach,omitempty" unit:"number" doc:"Number of the node's channel partners that neither we nor any of our peers have a channel with."`
+	PeerOverlap    *Range[float64]     `yaml:"peer_overlap_ratio,omitempty" unit:"ratio" doc:"Ratio (0-1) of the node's channel partners that are also our peers."`
+	Reputation     *Range[float64]     `yaml:"reputation,omitempty" unit:"number" doc:"Node reputation score, zero if it has no history. Requires database_path."`
+	Rejections     *Range[uint64]      `yaml:"previous_rejections,omitempty" unit:"number" doc:"Number of the node channel requests we rejected before. Requires database_path."`
+	Acceptances    *Range[uint64]      `yaml:"previous_acceptances,omitempty" unit:"number" doc:"Number of the node channel requests we accepted before. Requires database_path."`
+	LastDecision   *Range[uint64]      `yaml:"last_decision_age,omitempty" unit:"duration" doc:"Seconds elapsed since we last decided a channel request of the node, the nodes never decided only pass minimums. Requires database_path."`
+	KnownAs        *[]string           `yaml:"known_as,omitempty" doc:"Categories of the registry of known services the node must be in any of, like lsp, exchange or wallet."`
+	Connection     *Connection         `yaml:"connection,omitempty" doc:"Address the node is connected from."`
+	Reachable      *bool               `yaml:"reachable,omitempty" doc:"Whether the node must accept connections on any of its announced addresses."`
+	MobileWallet   *bool               `yaml:"looks_like_mobile_wallet,omitempty" doc:"Whether the node must look like a mobile wallet: no public channels, a small zero conf channel with an SCID alias and, if it announces features, zero conf and SCID alias among them."`
+	Splicing       *bool               `yaml:"supports_splicing,omitempty" doc:"Whether the node must announce support for splicing."`
+	DualFunding    *bool               `yaml:"supports_dual_funding,omitempty" doc:"Whether the node must announce support for dual funded channels."`
 }
 
-func (n *Node) evaluate(node *lnrpc.GetInfoResponse, peer *lnrpc.NodeInfo) error {
+// splicingBits are the splicing feature bits, the ones assigned in the specification and the
+// experimental ones announced before it was merged. LND doesn't define them yet.
+var splicingBits = []lnwire.FeatureBit{62, 63, 162, 163}
+
+// dualFundingBits are the option_dual_fund feature bits.
+var dualFundingBits = []lnwire.FeatureBit{28, 29}
+
+// mobileWalletMaxCapacity is the largest channel, in sats, considered typical of mobile wallets.
+const mobileWalletMaxCapacity = 2_000_000
+
+func (n *Node) evaluate(
+	req *lnrpc.ChannelAcceptRequest,
+	node *lnrpc.GetInfoResponse,
+	peer *lnrpc.NodeInfo,
+	facts *Facts,
+	w *warnings,
+) error {
 	if n == nil {
 		return nil
 	}
 
 	if !n.checkAge(node.BlockHeight, peer.Channels) {
-		return errors.New("Node age " + n.Age.Reason())
+		if err := w.violation(n.Age, "Node age "+n.Age.Reason()); err != nil {
+			return err
+		}
 	}
 
-	if !check(n.Capacity, peer.TotalCapacity) {
-		return errors.New("Node capacity " + n.Capacity.Reason())
+	if err := checkRange(n.Capacity, peer.TotalCapacity, w, "Node capacity"); err != nil {
+		return err
 	}
 
 	if !n.checkHybrid(peer.Node.Addresses) {
@@ -38,7 +73,83 @@ func (n *Node) evaluate(node *lnrpc.GetInfoResponse, peer *lnrpc.NodeInfo) error
 		return errors.New("Node doesn't have the desired feature flags")
 	}
 
-	return n.Channels.evaluate(node.IdentityPubkey, peer)
+	if !n.checkFirstSeenAge(facts) {
+		reason := "Node first seen age " + n.FirstSeenAge.Reason()
+		if err := w.violation(n.FirstSeenAge, reason); err != nil {
+			return err
+		}
+	}
+
+	if err := checkRange(n.GraphFreshness, graphFreshness(peer, facts), w, "Node graph freshness"); err != nil {
+		return err
+	}
+
+	if !n.checkNewReach(node.IdentityPubkey, peer, facts) {
+		if err := w.violation(n.NewReach, "Node new reach "+n.NewReach.Reason()); err != nil {
+			return err
+		}
+	}
+
+	if !n.checkPeerOverlap(node.IdentityPubkey, peer, facts) {
+		reason := "Node peer overlap ratio " + n.PeerOverlap.Reason()
+		if err := w.violation(n.PeerOverlap, reason); err != nil {
+			return err
+		}
+	}
+
+	if err := checkRange(n.Reputation, facts.reputation(), w, "Node reputation"); err != nil {
+		return err
+	}
+
+	if err := checkRange(n.Rejections, facts.rejections(), w, "Node previous rejections"); err != nil {
+		return err
+	}
+
+	if err := checkRange(n.Acceptances, facts.acceptances(), w, "Node previous acceptances"); err != nil {
+		return err
+	}
+
+	if err := checkRange(n.LastDecision, lastDecisionAge(facts), w, "Node last decision age"); err != nil {
+		return err
+	}
+
+	if !n.checkKnownAs(facts) {
+		return errors.New("Node is not known as any of the categories")
+	}
+
+	if n.Reachable != nil && *n.Reachable != facts.reachable() {
+		if *n.Reachable {
+			return errors.New("Node is not reachable on its announced addresses")
+		}
+		return errors.New("Node is reachable on its announced addresses")
+	}
+
+	if n.MobileWallet != nil && *n.MobileWallet != looksLikeMobileWallet(req, peer) {
+		if *n.MobileWallet {
+			return errors.New("Node doesn't look like a mobile wallet")
+		}
+		return errors.New("Node looks like a mobile wallet")
+	}
+
+	if n.Splicing != nil && *n.Splicing != supportsSplicing(peer.Node.Features) {
+		if *n.Splicing {
+			return errors.New("Node doesn't support splicing")
+		}
+		return errors.New("Node supports splicing")
+	}
+
+	if n.DualFunding != nil && *n.DualFunding != supportsDualFunding(peer.Node.Features) {
+		if *n.DualFunding {
+			return errors.New("Node doesn't support dual funding")
+		}
+		return errors.New("Node supports dual funding")
+	}
+
+	if err := n.Connection.evaluate(facts); err != nil {
+		return err
+	}
+
+	return n.Channels.evaluate(node.IdentityPubkey, peer, w)
 }
 
 func (n *Node) checkAge(bestBlockHeight uint32, channels []*lnrpc.ChannelEdge) bool {
@@ -46,8 +157,14 @@ func (n *Node) checkAge(bestBlockHeight uint32, channels []*lnrpc.ChannelEdge) b
 		return true
 	}
 
+	return n.Age.Contains(nodeAge(bestBlockHeight, channels))
+}
+
+// nodeAge returns the number of blocks since the node oldest channel was opened, zero if it has no
+// channels.
+func nodeAge(bestBlockHeight uint32, channels []*lnrpc.ChannelEdge) uint32 {
 	if len(channels) == 0 {
-		return n.Age.Contains(0)
+		return 0
 	}
 
 	oldestChannel := uint32(math.MaxInt32)
@@ -58,14 +175,19 @@ func (n *Node) checkAge(bestBlockHeight uint32, channels []*lnrpc.ChannelEdge) b
 		}
 	}
 
-	age := (bestBlockHeight - oldestChannel) + 1
-	return n.Age.Contains(age)
+	return (bestBlockHeight - oldestChannel) + 1
 }
 
 func (n *Node) checkHybrid(addresses []*lnrpc.NodeAddress) bool {
 	if n.Hybrid == nil {
 		return true
 	}
+
+	return isHybrid(addresses) == *n.Hybrid
+}
+
+// isHybrid returns whether the node has both clearnet and tor addresses.
+func isHybrid(addresses []*lnrpc.NodeAddress) bool {
 	hasClearnet := false
 	hasTor := false
 
@@ -78,11 +200,7 @@ func (n *Node) checkHybrid(addresses []*lnrpc.NodeAddress) bool {
 		hasClearnet = true
 	}
 
-	if hasClearnet && hasTor {
-		return *n.Hybrid
-	}
-
-	return !*n.Hybrid
+	return hasClearnet && hasTor
 }
 
 func (n *Node) checkFeatureFlags(features map[uint32]*lnrpc.Feature) bool {
@@ -98,3 +216,173 @@ func (n *Node) checkFeatureFlags(features map[uint32]*lnrpc.Feature) bool {
 
 	return true
 }
+
+// checkFirstSeenAge verifies the number of seconds elapsed since the peer requested to open a
+// channel with us for the first time.
+func (n *Node) checkFirstSeenAge(facts *Facts) bool {
+	if n.FirstSeenAge == nil {
+		return true
+	}
+
+	return n.FirstSeenAge.Contains(firstSeenAge(facts))
+}
+
+// firstSeenAge returns the seconds elapsed since the peer was seen for the first time.
+func firstSeenAge(facts *Facts) uint64 {
+	age := facts.now().Sub(facts.firstSeen())
+	if age < 0 {
+		age = 0
+	}
+	return uint64(age / time.Second)
+}
+
+// lastDecisionAge returns the seconds elapsed since our last decision on a request of the peer,
+// the maximum value if there wasn't any.
+func lastDecisionAge(facts *Facts) uint64 {
+	if facts == nil || facts.LastDecision.IsZero() {
+		return math.MaxUint64
+	}
+	age := facts.now().Sub(facts.LastDecision)
+	if age < 0 {
+		age = 0
+	}
+	return uint64(age / time.Second)
+}
+
+// graphFreshness returns the seconds elapsed since the peer node announcement was last updated.
+// Nodes that never announced themselves have their age counted from the epoch, so they fail the
+// maximums.
+func graphFreshness(peer *lnrpc.NodeInfo, facts *Facts) uint64 {
+	age := facts.now().Sub(time.Unix(int64(peer.Node.LastUpdate), 0))
+	if age < 0 {
+		age = 0
+	}
+	return uint64(age / time.Second)
+}
+
+// looksLikeMobileWallet returns whether the request matches the channels LSPs open with mobile
+// wallets: unannounced nodes asking for small zero conf channels with an SCID alias. Wallets rarely
+// announce their features, but if they do they must include zero conf and SCID alias.
+func looksLikeMobileWallet(req *lnrpc.ChannelAcceptRequest, peer *lnrpc.NodeInfo) bool {
+	if peer.NumChannels > 0 || len(peer.Channels) > 0 {
+		return false
+	}
+
+	if !req.WantsZeroConf || !req.WantsScidAlias || req.FundingAmt > mobileWalletMaxCapacity {
+		return false
+	}
+
+	features := peer.Node.Features
+	if len(features) == 0 {
+		return true
+	}
+
+	wallet := [][2]lnwire.FeatureBit{
+		{lnwire.ZeroConfRequired, lnwire.ZeroConfOptional},
+		{lnwire.ScidAliasRequired, lnwire.ScidAliasOptional},
+	}
+	for _, bits := range wallet {
+		if _, ok := features[uint32(bits[0])]; ok {
+			continue
+		}
+		if _, ok := features[uint32(bits[1])]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// checkKnownAs verifies the node is in any of the categories of the registry of known services.
+func (n *Node) checkKnownAs(facts *Facts) bool {
+	if n.KnownAs == nil {
+		return true
+	}
+
+	for _, category := range facts.knownAs() {
+		if slices.Contains(*n.KnownAs, category) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkNewReach verifies the number of the peer's channel partners that none of our peers is
+// connected to.
+func (n *Node) checkNewReach(nodePublicKey string, peer *lnrpc.NodeInfo, facts *Facts) bool {
+	if n.NewReach == nil {
+		return true
+	}
+
+	return n.NewReach.Contains(newReach(nodePublicKey, peer, facts))
+}
+
+func newReach(nodePublicKey string, peer *lnrpc.NodeInfo, facts *Facts) uint32 {
+	newReach := uint32(0)
+	for partner := range partners(nodePublicKey, peer) {
+		if !facts.reaches(partner) {
+			newReach++
+		}
+	}
+	return newReach
+}
+
+// checkPeerOverlap verifies the ratio of the peer's channel partners that are also our peers.
+func (n *Node) checkPeerOverlap(nodePublicKey string, peer *lnrpc.NodeInfo, facts *Facts) bool {
+	if n.PeerOverlap == nil {
+		return true
+	}
+
+	return n.PeerOverlap.Contains(peerOverlap(nodePublicKey, peer, facts))
+}
+
+func peerOverlap(nodePublicKey string, peer *lnrpc.NodeInfo, facts *Facts) float64 {
+	peerPartners := partners(nodePublicKey, peer)
+	if len(peerPartners) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for partner := range peerPartners {
+		if facts.isPeer(partner) {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(peerPartners))
+}
+
+// partners returns the set of nodes the peer has channels with, excluding our node.
+func partners(nodePublicKey string, peer *lnrpc.NodeInfo) map[string]struct{} {
+	partners := make(map[string]struct{}, len(peer.Channels))
+	for _, channel := range peer.Channels {
+		partner := channel.Node1Pub
+		if partner == peer.Node.PubKey {
+			partner = channel.Node2Pub
+		}
+		if partner != nodePublicKey {
+			partners[partner] = struct{}{}
+		}
+	}
+	return partners
+}
+
+// supportsSplicing returns whether the node announces the splicing feature, either required or
+// optional.
+func supportsSplicing(features map[uint32]*lnrpc.Feature) bool {
+	return hasAnyFeature(features, splicingBits)
+}
+
+// supportsDualFunding returns whether the node announces the option_dual_fund feature.
+func supportsDualFunding(features map[uint32]*lnrpc.Feature) bool {
+	return hasAnyFeature(features, dualFundingBits)
+}
+
+// hasAnyFeature returns whether any of the bits is in the node features. LND doesn't mark them as
+// known, so only their presence is checked.
+func hasAnyFeature(features map[uint32]*lnrpc.Feature, bits []lnwire.FeatureBit) bool {
+	for _, bit := range bits {
+		if _, ok := features[uint32(bit)]; ok {
+			return true
+		}
+	}
+	return false
+}
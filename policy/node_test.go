@@ -2,8 +2,10 @@ package policy
 
 import (
 	"testing"
+	"time"
 
 	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -102,7 +104,7 @@ func TestEvaluateNode(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.desc, func(t *testing.T) {
-			err := tc.node.evaluate(node, tc.peer)
+			err := tc.node.evaluate(&lnrpc.ChannelAcceptRequest{}, node, tc.peer, nil, nil)
 			if tc.fail {
 				assert.NotNil(t, err)
 			} else {
@@ -353,3 +355,537 @@ func TestCheckFeatureFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckFirstSeenAge(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	min := uint64(60 * 60 * 24)
+
+	cases := []struct {
+		facts    *Facts
+		desc     string
+		expected bool
+	}{
+		{
+			desc:     "Old enough",
+			facts:    &Facts{Now: now, FirstSeen: now.Add(-48 * time.Hour)},
+			expected: true,
+		},
+		{
+			desc:     "Too recent",
+			facts:    &Facts{Now: now, FirstSeen: now.Add(-time.Hour)},
+			expected: false,
+		},
+		{
+			desc:     "Unknown",
+			facts:    &Facts{Now: now},
+			expected: false,
+		},
+		{
+			desc:     "Nil facts",
+			expected: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			node := Node{
+				FirstSeenAge: &Range[uint64]{Min: &min},
+			}
+
+			actual := node.checkFirstSeenAge(tc.facts)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+
+	t.Run("Nil", func(t *testing.T) {
+		node := Node{}
+		assert.True(t, node.checkFirstSeenAge(nil))
+	})
+}
+
+func TestGraphFreshness(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	max := uint64(60 * 60 * 24)
+
+	cases := []struct {
+		desc       string
+		lastUpdate uint32
+		fail       bool
+	}{
+		{
+			desc:       "Fresh",
+			lastUpdate: uint32(now.Add(-time.Hour).Unix()),
+		},
+		{
+			desc:       "Clock skew",
+			lastUpdate: uint32(now.Add(time.Minute).Unix()),
+		},
+		{
+			desc:       "Stale",
+			lastUpdate: uint32(now.Add(-48 * time.Hour).Unix()),
+			fail:       true,
+		},
+		{
+			desc: "Never announced",
+			fail: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			node := &Node{GraphFreshness: &Range[uint64]{Max: &max}}
+			peer := &lnrpc.NodeInfo{Node: &lnrpc.LightningNode{LastUpdate: tc.lastUpdate}}
+
+			err := node.evaluate(&lnrpc.ChannelAcceptRequest{}, &lnrpc.GetInfoResponse{}, peer, &Facts{Now: now}, nil)
+			if tc.fail {
+				assert.ErrorContains(t, err, "Node graph freshness is higher than 86400")
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCheckNewReach(t *testing.T) {
+	nodePublicKey := "node_public_key"
+	peer := &lnrpc.NodeInfo{
+		Node: &lnrpc.LightningNode{PubKey: "peer_public_key"},
+		Channels: []*lnrpc.ChannelEdge{
+			{Node1Pub: "peer_public_key", Node2Pub: nodePublicKey},
+			{Node1Pub: "peer_public_key", Node2Pub: "a"},
+			{Node1Pub: "b", Node2Pub: "peer_public_key"},
+			{Node1Pub: "b", Node2Pub: "peer_public_key"},
+			{Node1Pub: "peer_public_key", Node2Pub: "c"},
+		},
+	}
+	two := uint32(2)
+
+	cases := []struct {
+		facts    *Facts
+		desc     string
+		expected bool
+	}{
+		{
+			desc:     "New nodes",
+			facts:    &Facts{Reach: map[string]struct{}{"a": {}}},
+			expected: true,
+		},
+		{
+			desc:     "Known nodes",
+			facts:    &Facts{Reach: map[string]struct{}{"a": {}, "b": {}}},
+			expected: false,
+		},
+		{
+			desc:     "Unknown reach",
+			expected: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			node := Node{
+				NewReach: &Range[uint32]{Min: &two},
+			}
+
+			actual := node.checkNewReach(nodePublicKey, peer, tc.facts)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+
+	t.Run("Nil", func(t *testing.T) {
+		node := Node{}
+		assert.True(t, node.checkNewReach(nodePublicKey, peer, nil))
+	})
+}
+
+func TestCheckPeerOverlap(t *testing.T) {
+	nodePublicKey := "node_public_key"
+	peer := &lnrpc.NodeInfo{
+		Node: &lnrpc.LightningNode{PubKey: "peer_public_key"},
+		Channels: []*lnrpc.ChannelEdge{
+			{Node1Pub: "peer_public_key", Node2Pub: nodePublicKey},
+			{Node1Pub: "peer_public_key", Node2Pub: "a"},
+			{Node1Pub: "b", Node2Pub: "peer_public_key"},
+			{Node1Pub: "peer_public_key", Node2Pub: "c"},
+			{Node1Pub: "peer_public_key", Node2Pub: "d"},
+		},
+	}
+	max := 0.5
+
+	cases := []struct {
+		facts    *Facts
+		peer     *lnrpc.NodeInfo
+		desc     string
+		expected bool
+	}{
+		{
+			desc:     "Low overlap",
+			facts:    &Facts{Peers: map[string]struct{}{"a": {}, "e": {}}},
+			peer:     peer,
+			expected: true,
+		},
+		{
+			desc:     "High overlap",
+			facts:    &Facts{Peers: map[string]struct{}{"a": {}, "b": {}, "c": {}}},
+			peer:     peer,
+			expected: false,
+		},
+		{
+			desc:     "No channels",
+			facts:    &Facts{Peers: map[string]struct{}{"a": {}}},
+			peer:     &lnrpc.NodeInfo{Node: peer.Node},
+			expected: true,
+		},
+		{
+			desc:     "Unknown peers",
+			peer:     peer,
+			expected: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			node := Node{
+				PeerOverlap: &Range[float64]{Max: &max},
+			}
+
+			actual := node.checkPeerOverlap(nodePublicKey, tc.peer, tc.facts)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+
+	t.Run("Nil", func(t *testing.T) {
+		node := Node{}
+		assert.True(t, node.checkPeerOverlap(nodePublicKey, peer, nil))
+	})
+}
+
+func TestCheckReputation(t *testing.T) {
+	node := &lnrpc.GetInfoResponse{IdentityPubkey: "node_public_key"}
+	peer := &lnrpc.NodeInfo{Node: &lnrpc.LightningNode{PubKey: "peer_public_key"}}
+	min := 0.0
+
+	cases := []struct {
+		facts *Facts
+		desc  string
+		fail  bool
+	}{
+		{desc: "Unknown facts", facts: nil},
+		{desc: "Neutral", facts: &Facts{}},
+		{desc: "Good", facts: &Facts{Reputation: 3.5}},
+		{desc: "Bad", facts: &Facts{Reputation: -0.1}, fail: true},
+	}
+
+	n := &Node{Reputation: &Range[float64]{Min: &min}}
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := n.evaluate(&lnrpc.ChannelAcceptRequest{}, node, peer, tc.facts, nil)
+			if tc.fail {
+				assert.EqualError(t, err, "Node reputation is lower than 0")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckHistory(t *testing.T) {
+	node := &lnrpc.GetInfoResponse{IdentityPubkey: "node_public_key"}
+	peer := &lnrpc.NodeInfo{Node: &lnrpc.LightningNode{PubKey: "peer_public_key"}}
+	now := time.Unix(1_700_000_000, 0)
+	maxRejections := uint64(2)
+	minAcceptances := uint64(1)
+	cooldown := uint64(60 * 60)
+
+	cases := []struct {
+		node     *Node
+		facts    *Facts
+		desc     string
+		expected string
+	}{
+		{
+			desc:  "Unknown facts",
+			node:  &Node{Rejections: &Range[uint64]{Max: &maxRejections}},
+			facts: nil,
+		},
+		{
+			desc:  "Few rejections",
+			node:  &Node{Rejections: &Range[uint64]{Max: &maxRejections}},
+			facts: &Facts{Rejections: 2},
+		},
+		{
+			desc:     "Too many rejections",
+			node:     &Node{Rejections: &Range[uint64]{Max: &maxRejections}},
+			facts:    &Facts{Rejections: 3},
+			expected: "Node previous rejections is higher than 2",
+		},
+		{
+			desc:  "Accepted before",
+			node:  &Node{Acceptances: &Range[uint64]{Min: &minAcceptances}},
+			facts: &Facts{Acceptances: 1},
+		},
+		{
+			desc:     "Never accepted",
+			node:     &Node{Acceptances: &Range[uint64]{Min: &minAcceptances}},
+			facts:    &Facts{Rejections: 4},
+			expected: "Node previous acceptances is lower than 1",
+		},
+		{
+			desc:  "Cooled down",
+			node:  &Node{LastDecision: &Range[uint64]{Min: &cooldown}},
+			facts: &Facts{Now: now, LastDecision: now.Add(-2 * time.Hour)},
+		},
+		{
+			desc:     "Decided recently",
+			node:     &Node{LastDecision: &Range[uint64]{Min: &cooldown}},
+			facts:    &Facts{Now: now, LastDecision: now.Add(-time.Minute)},
+			expected: "Node last decision age is lower than 3600",
+		},
+		{
+			desc:  "Never decided",
+			node:  &Node{LastDecision: &Range[uint64]{Min: &cooldown}},
+			facts: &Facts{Now: now},
+		},
+		{
+			desc:     "Never decided maximum",
+			node:     &Node{LastDecision: &Range[uint64]{Max: &cooldown}},
+			facts:    &Facts{Now: now},
+			expected: "Node last decision age is higher than 3600",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.node.evaluate(&lnrpc.ChannelAcceptRequest{}, node, peer, tc.facts, nil)
+			if tc.expected != "" {
+				assert.EqualError(t, err, tc.expected)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckKnownAs(t *testing.T) {
+	cases := []struct {
+		knownAs  *[]string
+		facts    *Facts
+		desc     string
+		expected bool
+	}{
+		{
+			desc:     "Nil",
+			knownAs:  nil,
+			facts:    &Facts{},
+			expected: true,
+		},
+		{
+			desc:     "Known",
+			knownAs:  &[]string{"exchange", "lsp"},
+			facts:    &Facts{KnownAs: []string{"lsp"}},
+			expected: true,
+		},
+		{
+			desc:     "Other category",
+			knownAs:  &[]string{"exchange"},
+			facts:    &Facts{KnownAs: []string{"lsp", "wallet"}},
+			expected: false,
+		},
+		{
+			desc:     "Unknown",
+			knownAs:  &[]string{"exchange"},
+			facts:    &Facts{},
+			expected: false,
+		},
+		{
+			desc:     "Unknown facts",
+			knownAs:  &[]string{"exchange"},
+			facts:    nil,
+			expected: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			node := Node{KnownAs: tc.knownAs}
+			assert.Equal(t, tc.expected, node.checkKnownAs(tc.facts))
+		})
+	}
+}
+
+func TestCheckReachable(t *testing.T) {
+	node := &lnrpc.GetInfoResponse{IdentityPubkey: "node_public_key"}
+	peer := &lnrpc.NodeInfo{Node: &lnrpc.LightningNode{PubKey: "peer_public_key"}}
+	tru := true
+	fals := false
+
+	cases := []struct {
+		facts     *Facts
+		reachable *bool
+		desc      string
+		fail      bool
+	}{
+		{desc: "Unknown facts", reachable: &tru, facts: nil, fail: true},
+		{desc: "Reachable", reachable: &tru, facts: &Facts{Reachable: true}},
+		{desc: "Unreachable", reachable: &tru, facts: &Facts{}, fail: true},
+		{desc: "Require unreachable", reachable: &fals, facts: &Facts{Reachable: true}, fail: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			n := &Node{Reachable: tc.reachable}
+			err := n.evaluate(&lnrpc.ChannelAcceptRequest{}, node, peer, tc.facts, nil)
+			if tc.fail {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestLooksLikeMobileWallet(t *testing.T) {
+	wallet := &lnrpc.ChannelAcceptRequest{FundingAmt: 500_000, WantsZeroConf: true, WantsScidAlias: true}
+	unannounced := &lnrpc.NodeInfo{Node: &lnrpc.LightningNode{PubKey: "peer_public_key"}}
+
+	cases := []struct {
+		req      *lnrpc.ChannelAcceptRequest
+		peer     *lnrpc.NodeInfo
+		desc     string
+		expected bool
+	}{
+		{desc: "Wallet", req: wallet, peer: unannounced, expected: true},
+		{
+			desc: "Public channels",
+			req:  wallet,
+			peer: &lnrpc.NodeInfo{
+				Node:        &lnrpc.LightningNode{PubKey: "peer_public_key"},
+				NumChannels: 1,
+			},
+		},
+		{
+			desc: "Not zero conf",
+			req:  &lnrpc.ChannelAcceptRequest{FundingAmt: 500_000, WantsScidAlias: true},
+			peer: unannounced,
+		},
+		{
+			desc: "No SCID alias",
+			req:  &lnrpc.ChannelAcceptRequest{FundingAmt: 500_000, WantsZeroConf: true},
+			peer: unannounced,
+		},
+		{
+			desc: "Large channel",
+			req:  &lnrpc.ChannelAcceptRequest{FundingAmt: 5_000_000, WantsZeroConf: true, WantsScidAlias: true},
+			peer: unannounced,
+		},
+		{
+			desc: "Wallet features",
+			req:  wallet,
+			peer: &lnrpc.NodeInfo{
+				Node: &lnrpc.LightningNode{
+					Features: map[uint32]*lnrpc.Feature{
+						uint32(lnwire.ZeroConfRequired):  {IsKnown: true},
+						uint32(lnwire.ScidAliasOptional): {IsKnown: true},
+					},
+				},
+			},
+			expected: true,
+		},
+		{
+			desc: "Missing features",
+			req:  wallet,
+			peer: &lnrpc.NodeInfo{
+				Node: &lnrpc.LightningNode{
+					Features: map[uint32]*lnrpc.Feature{
+						uint32(lnwire.ZeroConfOptional): {IsKnown: true},
+					},
+				},
+			},
+		},
+	}
+
+	node := &lnrpc.GetInfoResponse{IdentityPubkey: "node_public_key"}
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.expected, looksLikeMobileWallet(tc.req, tc.peer))
+
+			tru := true
+			n := &Node{MobileWallet: &tru}
+			err := n.evaluate(tc.req, node, tc.peer, nil, nil)
+			if tc.expected {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, "Node doesn't look like a mobile wallet")
+			}
+		})
+	}
+}
+
+func TestCheckCapabilities(t *testing.T) {
+	node := &lnrpc.GetInfoResponse{IdentityPubkey: "node_public_key"}
+	tru := true
+	fals := false
+
+	cases := []struct {
+		node     *Node
+		features map[uint32]*lnrpc.Feature
+		desc     string
+		expected string
+	}{
+		{
+			desc:     "Splicing",
+			node:     &Node{Splicing: &tru},
+			features: map[uint32]*lnrpc.Feature{63: {}},
+		},
+		{
+			desc:     "Experimental splicing",
+			node:     &Node{Splicing: &tru},
+			features: map[uint32]*lnrpc.Feature{163: {}},
+		},
+		{
+			desc:     "No splicing",
+			node:     &Node{Splicing: &tru},
+			features: map[uint32]*lnrpc.Feature{29: {}},
+			expected: "Node doesn't support splicing",
+		},
+		{
+			desc:     "Require no splicing",
+			node:     &Node{Splicing: &fals},
+			features: map[uint32]*lnrpc.Feature{62: {}},
+			expected: "Node supports splicing",
+		},
+		{
+			desc:     "Dual funding",
+			node:     &Node{DualFunding: &tru},
+			features: map[uint32]*lnrpc.Feature{28: {}},
+		},
+		{
+			desc:     "No dual funding",
+			node:     &Node{DualFunding: &tru},
+			expected: "Node doesn't support dual funding",
+		},
+		{
+			desc:     "Require no dual funding",
+			node:     &Node{DualFunding: &fals},
+			features: map[uint32]*lnrpc.Feature{29: {}},
+			expected: "Node supports dual funding",
+		},
+		{
+			desc:     "Both",
+			node:     &Node{Splicing: &tru, DualFunding: &tru},
+			features: map[uint32]*lnrpc.Feature{29: {}, 63: {}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			peer := &lnrpc.NodeInfo{Node: &lnrpc.LightningNode{Features: tc.features}}
+			err := tc.node.evaluate(&lnrpc.ChannelAcceptRequest{}, node, peer, nil, nil)
+			if tc.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expected)
+			}
+		})
+	}
+}
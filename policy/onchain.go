@@ -0,0 +1,120 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// Onchain contains the requirements based on the cost of enforcing the channel on chain.
+type Onchain struct {
+	MaxSweepCostRatio *float64    `yaml:"max_sweep_cost_ratio,omitempty" doc:"Maximum ratio (0-1) between the cost of force closing the channel and sweeping our funds at the current fee rate and its capacity."`
+	DepthBumps        []DepthBump `yaml:"depth_bumps,omitempty" doc:"Confirmations required from the large channels when the mempool is congested. The highest depth of the rows matched is used."`
+}
+
+// DepthBump is a row of the matrix raising the confirmations required based on the channel value
+// and the fee environment, as the risk of the funding transaction being replaced or reorganized
+// out depends on both.
+type DepthBump struct {
+	FeeRate  uint64 `yaml:"fee_rate" doc:"Fee rate, in sat/vbyte, at or above which the row applies."`
+	Capacity uint64 `yaml:"capacity" doc:"Channel capacity, in sats, at or above which the row applies."`
+	Depth    uint32 `yaml:"depth" doc:"Confirmations required before considering the channel open."`
+}
+
+// UnmarshalYAML decodes a depth bump accepting human-friendly capacities.
+func (d *DepthBump) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw struct {
+		FeeRate  interface{} `yaml:"fee_rate"`
+		Capacity interface{} `yaml:"capacity"`
+		Depth    uint32      `yaml:"depth"`
+	}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	feeRate, err := parseOptionalValue[uint64](raw.FeeRate, unitNumber)
+	if err != nil {
+		return fmt.Errorf("fee_rate: %w", err)
+	}
+	capacity, err := parseOptionalValue[uint64](raw.Capacity, unitAmount)
+	if err != nil {
+		return fmt.Errorf("capacity: %w", err)
+	}
+
+	*d = DepthBump{Depth: raw.Depth}
+	if feeRate != nil {
+		d.FeeRate = *feeRate
+	}
+	if capacity != nil {
+		d.Capacity = *capacity
+	}
+	return nil
+}
+
+// sweepWeight is the weight of the transactions needed to enforce a channel: an anchor commitment
+// and the sweep of our delayed output into a taproot address.
+var sweepWeight = func() uint64 {
+	var sweep input.TxWeightEstimator
+	sweep.AddWitnessInput(input.ToLocalTimeoutWitnessSize)
+	sweep.AddP2TROutput()
+	return uint64(input.AnchorCommitWeight) + uint64(sweep.Weight())
+}()
+
+// SweepCost returns the satoshis spent to force close a channel and sweep its funds at the fee rate
+// in satoshis per kilo-weight unit.
+func SweepCost(feeRate uint64) uint64 {
+	return sweepWeight * feeRate / 1000
+}
+
+// evaluate rejects the channels whose capacity is too small compared to the cost of enforcing them.
+// The requirement is skipped if the fee rate is not known.
+func (o *Onchain) evaluate(capacity uint64, facts *Facts) error {
+	if o == nil || o.MaxSweepCostRatio == nil || facts == nil || facts.FeeRate == 0 || capacity == 0 {
+		return nil
+	}
+
+	ratio := float64(SweepCost(facts.FeeRate)) / float64(capacity)
+	if ratio > *o.MaxSweepCostRatio {
+		return fmt.Errorf("Channel sweep cost ratio is higher than %v", *o.MaxSweepCostRatio)
+	}
+	return nil
+}
+
+// bumpDepth raises the confirmations required from the channel to the highest depth of the rows
+// matching its capacity and the current fee rate. Zero conf channels, which are trusted explicitly,
+// and the requests evaluated without a fee rate are left as they are.
+func (o *Onchain) bumpDepth(capacity uint64, facts *Facts, resp *lnrpc.ChannelAcceptResponse) {
+	if o == nil || len(o.DepthBumps) == 0 || facts == nil || facts.FeeRate == 0 || resp.ZeroConf {
+		return
+	}
+
+	// The fee rate is estimated in sat/kw, there are four weight units per virtual byte
+	feeRate := facts.FeeRate * 4 / 1000
+	for _, bump := range o.DepthBumps {
+		if feeRate >= bump.FeeRate && capacity >= bump.Capacity && bump.Depth > resp.MinAcceptDepth {
+			resp.MinAcceptDepth = bump.Depth
+		}
+	}
+}
+
+func (o *Onchain) validate() error {
+	if o == nil {
+		return nil
+	}
+
+	if o.MaxSweepCostRatio != nil && (*o.MaxSweepCostRatio <= 0 || *o.MaxSweepCostRatio > 1) {
+		return errors.New("onchain.max_sweep_cost_ratio: must be greater than 0 and at most 1")
+	}
+
+	for i, bump := range o.DepthBumps {
+		if bump.FeeRate == 0 {
+			return fmt.Errorf("onchain.depth_bumps[%d].fee_rate: must be positive", i)
+		}
+		if bump.Depth == 0 {
+			return fmt.Errorf("onchain.depth_bumps[%d].depth: must be positive", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestSweepCost(t *testing.T) {
+	assert.Equal(t, uint64(0), SweepCost(0))
+	assert.Equal(t, sweepWeight*2, SweepCost(2000))
+	assert.Greater(t, sweepWeight, uint64(1124))
+}
+
+func TestEvaluateOnchain(t *testing.T) {
+	max := 0.01
+	onchain := &Onchain{MaxSweepCostRatio: &max}
+	// 100 sat/vbyte, sweeping costs around 41_000 sats
+	feeRate := uint64(25_000)
+
+	cases := []struct {
+		onchain  *Onchain
+		facts    *Facts
+		desc     string
+		capacity uint64
+		fail     bool
+	}{
+		{
+			desc:     "Nil",
+			facts:    &Facts{FeeRate: feeRate},
+			capacity: 100_000,
+		},
+		{
+			desc:     "Unknown fee rate",
+			onchain:  onchain,
+			facts:    &Facts{},
+			capacity: 100_000,
+		},
+		{
+			desc:     "Nil facts",
+			onchain:  onchain,
+			capacity: 100_000,
+		},
+		{
+			desc:     "Worth enforcing",
+			onchain:  onchain,
+			facts:    &Facts{FeeRate: feeRate},
+			capacity: 10_000_000,
+		},
+		{
+			desc:     "Too small",
+			onchain:  onchain,
+			facts:    &Facts{FeeRate: feeRate},
+			capacity: 1_000_000,
+			fail:     true,
+		},
+		{
+			desc:     "Low fees",
+			onchain:  onchain,
+			facts:    &Facts{FeeRate: 253},
+			capacity: 100_000,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.onchain.evaluate(tc.capacity, tc.facts)
+			if tc.fail {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestBumpDepth(t *testing.T) {
+	onchain := &Onchain{DepthBumps: []DepthBump{
+		{FeeRate: 50, Capacity: 5_000_000, Depth: 6},
+		{FeeRate: 50, Capacity: 20_000_000, Depth: 12},
+		{FeeRate: 150, Capacity: 5_000_000, Depth: 12},
+	}}
+	// 100 sat/vbyte
+	congested := &Facts{FeeRate: 25_000}
+
+	cases := []struct {
+		onchain  *Onchain
+		facts    *Facts
+		resp     *lnrpc.ChannelAcceptResponse
+		desc     string
+		capacity uint64
+		expected uint32
+	}{
+		{
+			desc:     "Nil",
+			facts:    congested,
+			resp:     &lnrpc.ChannelAcceptResponse{},
+			capacity: 10_000_000,
+		},
+		{
+			desc:     "Unknown fee rate",
+			onchain:  onchain,
+			facts:    &Facts{},
+			resp:     &lnrpc.ChannelAcceptResponse{},
+			capacity: 10_000_000,
+		},
+		{
+			desc:     "Low fees",
+			onchain:  onchain,
+			facts:    &Facts{FeeRate: 2500},
+			resp:     &lnrpc.ChannelAcceptResponse{MinAcceptDepth: 3},
+			capacity: 10_000_000,
+			expected: 3,
+		},
+		{
+			desc:     "Small channel",
+			onchain:  onchain,
+			facts:    congested,
+			resp:     &lnrpc.ChannelAcceptResponse{},
+			capacity: 1_000_000,
+		},
+		{
+			desc:     "Large channel",
+			onchain:  onchain,
+			facts:    congested,
+			resp:     &lnrpc.ChannelAcceptResponse{},
+			capacity: 10_000_000,
+			expected: 6,
+		},
+		{
+			desc:     "Highest depth",
+			onchain:  onchain,
+			facts:    congested,
+			resp:     &lnrpc.ChannelAcceptResponse{},
+			capacity: 20_000_000,
+			expected: 12,
+		},
+		{
+			desc:     "Higher depth configured",
+			onchain:  onchain,
+			facts:    congested,
+			resp:     &lnrpc.ChannelAcceptResponse{MinAcceptDepth: 9},
+			capacity: 10_000_000,
+			expected: 9,
+		},
+		{
+			desc:     "Zero conf",
+			onchain:  onchain,
+			facts:    congested,
+			resp:     &lnrpc.ChannelAcceptResponse{ZeroConf: true},
+			capacity: 10_000_000,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tc.onchain.bumpDepth(tc.capacity, tc.facts, tc.resp)
+			assert.Equal(t, tc.expected, tc.resp.MinAcceptDepth)
+		})
+	}
+}
+
+func TestDecodeDepthBumps(t *testing.T) {
+	var onchain Onchain
+	content := "depth_bumps:\n  - fee_rate: 50\n    capacity: 5m\n    depth: 6\n"
+	assert.NoError(t, yaml.UnmarshalStrict([]byte(content), &onchain))
+	assert.Equal(t, []DepthBump{{FeeRate: 50, Capacity: 5_000_000, Depth: 6}}, onchain.DepthBumps)
+	assert.NoError(t, onchain.validate())
+
+	onchain.DepthBumps[0].Depth = 0
+	assert.Error(t, onchain.validate())
+
+	assert.Error(t, yaml.Unmarshal([]byte("depth_bumps:\n  - capacity: lots\n"), &onchain))
+}
@@ -4,6 +4,8 @@ package policy
 
 import (
 	"errors"
+	"strconv"
+	"time"
 
 	"github.com/lightningnetwork/lnd/lnrpc"
 	"github.com/lightningnetwork/lnd/lnwire"
@@ -12,17 +14,50 @@ import (
 // Policy represents a set of requirements that a channel opening request must satisfy. They are
 // enforced only if the conditions are met or do not exist.
 type Policy struct {
-	Conditions             *Conditions `yaml:"conditions,omitempty"`
-	Request                *Request    `yaml:"request,omitempty"`
-	Node                   *Node       `yaml:"node,omitempty"`
-	AllowList              *[]string   `yaml:"allow_list,omitempty"`
-	BlockList              *[]string   `yaml:"block_list,omitempty"`
-	ZeroConfList           *[]string   `yaml:"zero_conf_list,omitempty"`
-	RejectAll              *bool       `yaml:"reject_all,omitempty"`
-	RejectPrivateChannels  *bool       `yaml:"reject_private_channels,omitempty"`
-	AcceptZeroConfChannels *bool       `yaml:"accept_zero_conf_channels,omitempty"`
-	MinAcceptDepth         *uint32     `yaml:"min_accept_depth,omitempty"`
-	MaxChannels            *uint32     `yaml:"max_channels,omitempty"`
+	Name                   string         `yaml:"name,omitempty" doc:"Name used to identify the policy in the logs."`
+	Description            string         `yaml:"description,omitempty" doc:"Explanation of what the policy is meant for."`
+	Tags                   []string       `yaml:"tags,omitempty" doc:"Labels attached to the decisions the policy takes part in."`
+	Conditions             *Conditions    `yaml:"conditions,omitempty" doc:"Conditions that must be met to enforce the policy. A list of condition sets matches if any of them does."`
+	Request                *Request       `yaml:"request,omitempty" doc:"Requirements of the channel opening request."`
+	Node                   *Node          `yaml:"node,omitempty" doc:"Requirements of the initiator node."`
+	Escalation             *Escalation    `yaml:"escalation,omitempty" doc:"Limits for peers that don't have an established channel with us yet."`
+	Onchain                *Onchain       `yaml:"onchain,omitempty" doc:"Requirements based on the cost of enforcing the channel on chain."`
+	AllowList              *[]string      `yaml:"allow_list,omitempty" doc:"Public keys of the nodes whose requests are accepted."`
+	BlockList              *[]string      `yaml:"block_list,omitempty" doc:"Public keys of the nodes whose requests are rejected."`
+	ZeroConfList           *[]string      `yaml:"zero_conf_list,omitempty" doc:"Public keys of the nodes whose zero conf requests are accepted. Requires accept_zero_conf_channels."`
+	RejectAll              *bool          `yaml:"reject_all,omitempty" doc:"Reject all channel requests."`
+	RejectPrivateChannels  *bool          `yaml:"reject_private_channels,omitempty" doc:"Reject private channels."`
+	AcceptZeroConfChannels *bool          `yaml:"accept_zero_conf_channels,omitempty" doc:"Accept zero confirmation channels."`
+	ZeroConfScidAlias      *bool          `yaml:"zero_conf_requires_scid_alias,omitempty" doc:"Only grant zero conf to nodes supporting option_scid_alias, the requests of the rest are answered requiring confirmations."`
+	MinAcceptDepth         *uint32        `yaml:"min_accept_depth,omitempty" doc:"Number of confirmations required before considering the channel open."`
+	MaxChannels            *uint32        `yaml:"max_channels,omitempty" doc:"Maximum number of channels, compared against the sum of our active, pending and inactive channels."`
+	ReservedSlots          *uint32        `yaml:"reserved_slots,omitempty" doc:"Number of the max_channels slots that only the nodes in reserved_list can use."`
+	ReservedList           *[]string      `yaml:"reserved_list,omitempty" doc:"Public keys of the nodes that can use the reserved slots."`
+	MaxChannelsPerIP       *uint32        `yaml:"max_channels_per_ip,omitempty" doc:"Maximum number of channels with peers connected from the same IP address as the initiator, Tor connections aren't counted."`
+	MaxChannelsPerASN      *uint32        `yaml:"max_channels_per_asn,omitempty" doc:"Maximum number of channels with peers connected from the same autonomous system as the initiator. Requires asn_database_path."`
+	MaxInboundCapacity     *uint64        `yaml:"max_total_inbound_capacity,omitempty" doc:"Maximum inbound capacity, in sats, compared against the remote balances of the channels our peers opened plus the requested channel."`
+	Tarpit                 *time.Duration `yaml:"tarpit,omitempty" doc:"Maximum time the response is delayed when the policy rejects a request, at most 10s."`
+}
+
+// MaxTarpit is the longest a rejection can be delayed, it's kept below LND's default channel
+// acceptor timeout (15 seconds) so the response arrives before LND gives up on it.
+const MaxTarpit = 10 * time.Second
+
+// TarpitError is returned when a policy with a tarpit rejects a request, the response should be
+// delayed up to Delay before being sent.
+type TarpitError struct {
+	Delay time.Duration
+	err   error
+}
+
+// Error returns the message of the rejection.
+func (e *TarpitError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the rejection error.
+func (e *TarpitError) Unwrap() error {
+	return e.err
 }
 
 // Evaluate set of policies.
@@ -31,11 +66,56 @@ func (p *Policy) Evaluate(
 	resp *lnrpc.ChannelAcceptResponse,
 	node *lnrpc.GetInfoResponse,
 	peer *lnrpc.NodeInfo,
+	facts *Facts,
 ) error {
-	if p.Conditions != nil && !p.Conditions.Match(req, node, peer) {
+	if !p.Applies(req, node, peer, facts) {
 		return nil
 	}
 
+	return p.Enforce(req, resp, node, peer, facts)
+}
+
+// Applies returns whether the policy conditions are met, or if it has none.
+func (p *Policy) Applies(
+	req *lnrpc.ChannelAcceptRequest,
+	node *lnrpc.GetInfoResponse,
+	peer *lnrpc.NodeInfo,
+	facts *Facts,
+) bool {
+	return p.Conditions == nil || p.Conditions.Match(req, node, peer, facts)
+}
+
+// Enforce verifies the request satisfies the policy requirements, regardless of its conditions.
+// The violations of the requirements with the warn severity are appended to the facts warnings
+// instead of rejecting the request.
+//
+// If the policy has a tarpit, rejections are returned as a *TarpitError.
+func (p *Policy) Enforce(
+	req *lnrpc.ChannelAcceptRequest,
+	resp *lnrpc.ChannelAcceptResponse,
+	node *lnrpc.GetInfoResponse,
+	peer *lnrpc.NodeInfo,
+	facts *Facts,
+) error {
+	w := &warnings{}
+	err := p.enforce(req, resp, node, peer, facts, w)
+	if facts != nil {
+		facts.Warnings = append(facts.Warnings, w.messages...)
+	}
+	if err != nil && p.Tarpit != nil {
+		return &TarpitError{Delay: *p.Tarpit, err: err}
+	}
+	return err
+}
+
+func (p *Policy) enforce(
+	req *lnrpc.ChannelAcceptRequest,
+	resp *lnrpc.ChannelAcceptResponse,
+	node *lnrpc.GetInfoResponse,
+	peer *lnrpc.NodeInfo,
+	facts *Facts,
+	w *warnings,
+) error {
 	if p.MinAcceptDepth != nil {
 		resp.MinAcceptDepth = *p.MinAcceptDepth
 	}
@@ -59,17 +139,47 @@ func (p *Policy) Evaluate(
 	if !p.checkZeroConf(peer.Node.PubKey, req.WantsZeroConf, resp) {
 		return errors.New("Zero conf channels are not accepted")
 	}
+	p.checkScidAlias(peer.Node.Features, resp)
 
 	numChannels := node.NumActiveChannels + node.NumInactiveChannels + node.NumPendingChannels
-	if !p.checkMaxChannels(numChannels) {
+	if !p.checkMaxChannels(numChannels, peer.Node.PubKey) {
 		return errors.New("Maximum number of channels reached")
 	}
 
-	if err := p.Request.evaluate(req); err != nil {
+	if !checkMaxOrigin(p.MaxChannelsPerIP, facts.sameIPChannels()) {
+		return errors.New("Maximum number of channels per IP address reached")
+	}
+
+	if !checkMaxOrigin(p.MaxChannelsPerASN, facts.sameASNChannels()) {
+		return errors.New("Maximum number of channels per ASN reached")
+	}
+
+	if !p.checkMaxInboundCapacity(req, facts) {
+		return errors.New("Maximum total inbound capacity reached")
+	}
+
+	if err := p.Request.evaluate(req, w); err != nil {
 		return err
 	}
 
-	return p.Node.evaluate(node, peer)
+	if err := p.Escalation.evaluate(req.FundingAmt, facts, w); err != nil {
+		return err
+	}
+
+	if err := p.Onchain.evaluate(req.FundingAmt, facts); err != nil {
+		return err
+	}
+	p.Onchain.bumpDepth(req.FundingAmt, facts, resp)
+
+	return p.Node.evaluate(req, node, peer, facts, w)
+}
+
+// Label returns the policy name or, if it has none, a description based on its position.
+func (p *Policy) Label(index int) string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return "#" + strconv.Itoa(index)
 }
 
 func (p *Policy) checkRejectAll() bool {
@@ -105,11 +215,49 @@ func (p *Policy) checkBlockList(publicKey string) bool {
 	return true
 }
 
-func (p *Policy) checkMaxChannels(numChannels uint32) bool {
+// checkMaxChannels verifies the number of channels is below the maximum. The last reserved slots
+// can only be used by the nodes in the reserved list.
+func (p *Policy) checkMaxChannels(numChannels uint32, publicKey string) bool {
 	if p.MaxChannels == nil {
 		return true
 	}
-	return numChannels < *p.MaxChannels
+
+	maxChannels := *p.MaxChannels
+	if p.ReservedSlots != nil && !p.isReserved(publicKey) {
+		if *p.ReservedSlots >= maxChannels {
+			return false
+		}
+		maxChannels -= *p.ReservedSlots
+	}
+
+	return numChannels < maxChannels
+}
+
+// checkMaxOrigin verifies the number of channels with peers connected from the same network
+// origin as the initiator is below the maximum.
+func checkMaxOrigin(maxChannels *uint32, numChannels uint32) bool {
+	if maxChannels == nil {
+		return true
+	}
+	return numChannels < *maxChannels
+}
+
+// checkMaxInboundCapacity verifies the inbound capacity we would have after accepting the channel,
+// without the amount pushed to us, doesn't exceed the maximum.
+func (p *Policy) checkMaxInboundCapacity(req *lnrpc.ChannelAcceptRequest, facts *Facts) bool {
+	if p.MaxInboundCapacity == nil {
+		return true
+	}
+
+	inbound := req.FundingAmt - min(req.PushAmt/1000, req.FundingAmt)
+	return facts.inboundCapacity()+inbound <= *p.MaxInboundCapacity
+}
+
+func (p *Policy) isReserved(publicKey string) bool {
+	if p.ReservedList == nil {
+		return false
+	}
+	return contains(*p.ReservedList, publicKey)
 }
 
 func (p *Policy) checkPrivate(private bool) bool {
@@ -147,3 +295,28 @@ func (p *Policy) checkZeroConf(
 
 	return false
 }
+
+// checkScidAlias withdraws the zero conf granted to nodes that don't support option_scid_alias,
+// which LND requires to use the channels before they are confirmed. Their channels must have at
+// least one confirmation instead.
+func (p *Policy) checkScidAlias(features map[uint32]*lnrpc.Feature, resp *lnrpc.ChannelAcceptResponse) {
+	if p.ZeroConfScidAlias == nil || !*p.ZeroConfScidAlias || !resp.ZeroConf {
+		return
+	}
+	if supportsScidAlias(features) {
+		return
+	}
+
+	resp.ZeroConf = false
+	resp.MinAcceptDepth = 1
+	if p.MinAcceptDepth != nil && *p.MinAcceptDepth > 1 {
+		resp.MinAcceptDepth = *p.MinAcceptDepth
+	}
+}
+
+// supportsScidAlias returns whether the node announces the option_scid_alias feature.
+func supportsScidAlias(features map[uint32]*lnrpc.Feature) bool {
+	_, required := features[uint32(lnwire.ScidAliasRequired)]
+	_, optional := features[uint32(lnwire.ScidAliasOptional)]
+	return required || optional
+}
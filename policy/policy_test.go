@@ -1,9 +1,12 @@
 package policy
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -137,6 +140,18 @@ func TestEvaluatePolicy(t *testing.T) {
 			},
 			fail: true,
 		},
+		{
+			desc: "Escalation",
+			policy: Policy{
+				Escalation: &Escalation{
+					ChannelCapacity: &Range[uint64]{Max: &max},
+				},
+			},
+			req: &lnrpc.ChannelAcceptRequest{
+				FundingAmt: 10_000,
+			},
+			fail: true,
+		},
 		{
 			desc: "Min accept depth",
 			policy: Policy{
@@ -158,7 +173,7 @@ func TestEvaluatePolicy(t *testing.T) {
 				tc.node = &lnrpc.GetInfoResponse{IdentityPubkey: "node_public_key"}
 			}
 
-			err := tc.policy.Evaluate(tc.req, &lnrpc.ChannelAcceptResponse{}, tc.node, tc.peer)
+			err := tc.policy.Evaluate(tc.req, &lnrpc.ChannelAcceptResponse{}, tc.node, tc.peer, nil)
 			if tc.fail {
 				assert.NotNil(t, err)
 			} else {
@@ -181,12 +196,42 @@ func TestMinAcceptDepth(t *testing.T) {
 		resp,
 		&lnrpc.GetInfoResponse{},
 		node,
+		nil,
 	)
 	assert.NoError(t, err)
 
 	assert.Equal(t, n, resp.MinAcceptDepth)
 }
 
+func TestTarpit(t *testing.T) {
+	rejectAll := true
+	tarpit := 5 * time.Second
+	policy := Policy{RejectAll: &rejectAll, Tarpit: &tarpit}
+	node := &lnrpc.NodeInfo{Node: &lnrpc.LightningNode{PubKey: ""}}
+
+	err := policy.Evaluate(
+		&lnrpc.ChannelAcceptRequest{},
+		&lnrpc.ChannelAcceptResponse{},
+		&lnrpc.GetInfoResponse{},
+		node,
+		nil,
+	)
+	var tarpitErr *TarpitError
+	assert.ErrorAs(t, err, &tarpitErr)
+	assert.Equal(t, tarpit, tarpitErr.Delay)
+	assert.EqualError(t, err, "No new channels are accepted")
+
+	policy.RejectAll = nil
+	err = policy.Evaluate(
+		&lnrpc.ChannelAcceptRequest{},
+		&lnrpc.ChannelAcceptResponse{},
+		&lnrpc.GetInfoResponse{},
+		node,
+		nil,
+	)
+	assert.NoError(t, err)
+}
+
 func TestCheckRejectAll(t *testing.T) {
 	cases := []struct {
 		desc      string
@@ -419,3 +464,254 @@ func TestCheckZeroConf(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckScidAlias(t *testing.T) {
+	enabled := true
+	minAcceptDepth := uint32(3)
+	scidAlias := map[uint32]*lnrpc.Feature{uint32(lnwire.ScidAliasOptional): {Name: "scid-alias"}}
+
+	cases := []struct {
+		desc              string
+		features          map[uint32]*lnrpc.Feature
+		zeroConfScidAlias *bool
+		minAcceptDepth    *uint32
+		zeroConf          bool
+		expectedDepth     uint32
+	}{
+		{
+			desc:              "Supported",
+			features:          scidAlias,
+			zeroConfScidAlias: &enabled,
+			zeroConf:          true,
+		},
+		{
+			desc:              "Not supported",
+			zeroConfScidAlias: &enabled,
+			expectedDepth:     1,
+		},
+		{
+			desc:              "Not supported with min accept depth",
+			zeroConfScidAlias: &enabled,
+			minAcceptDepth:    &minAcceptDepth,
+			expectedDepth:     3,
+		},
+		{
+			desc:     "Disabled",
+			zeroConf: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			policy := Policy{ZeroConfScidAlias: tc.zeroConfScidAlias, MinAcceptDepth: tc.minAcceptDepth}
+			resp := &lnrpc.ChannelAcceptResponse{ZeroConf: true}
+
+			policy.checkScidAlias(tc.features, resp)
+			assert.Equal(t, tc.zeroConf, resp.ZeroConf)
+			assert.Equal(t, tc.expectedDepth, resp.MinAcceptDepth)
+		})
+	}
+}
+
+func TestCheckMaxChannels(t *testing.T) {
+	publicKey := "reserved_public_key"
+	maxChannels := uint32(10)
+	reservedSlots := uint32(2)
+	allReserved := uint32(10)
+
+	cases := []struct {
+		reservedSlots *uint32
+		desc          string
+		publicKey     string
+		numChannels   uint32
+		expected      bool
+	}{
+		{
+			desc:        "Below maximum",
+			numChannels: 7,
+			expected:    true,
+		},
+		{
+			desc:        "Maximum reached",
+			numChannels: 10,
+			expected:    false,
+		},
+		{
+			desc:          "Below reserved slots",
+			reservedSlots: &reservedSlots,
+			numChannels:   7,
+			expected:      true,
+		},
+		{
+			desc:          "Reserved slots reached",
+			reservedSlots: &reservedSlots,
+			numChannels:   8,
+			expected:      false,
+		},
+		{
+			desc:          "Reserved node",
+			reservedSlots: &reservedSlots,
+			publicKey:     publicKey,
+			numChannels:   9,
+			expected:      true,
+		},
+		{
+			desc:          "Reserved node maximum reached",
+			reservedSlots: &reservedSlots,
+			publicKey:     publicKey,
+			numChannels:   10,
+			expected:      false,
+		},
+		{
+			desc:          "All slots reserved",
+			reservedSlots: &allReserved,
+			numChannels:   0,
+			expected:      false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			policy := Policy{
+				MaxChannels:   &maxChannels,
+				ReservedSlots: tc.reservedSlots,
+				ReservedList:  &[]string{publicKey},
+			}
+
+			actual := policy.checkMaxChannels(tc.numChannels, tc.publicKey)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+
+	t.Run("Nil", func(t *testing.T) {
+		policy := Policy{}
+		assert.True(t, policy.checkMaxChannels(100, ""))
+	})
+}
+
+func TestCheckMaxOrigin(t *testing.T) {
+	maxChannels := uint32(3)
+
+	cases := []struct {
+		policy   Policy
+		facts    *Facts
+		desc     string
+		expected error
+	}{
+		{
+			desc:   "Below maximum",
+			policy: Policy{MaxChannelsPerIP: &maxChannels, MaxChannelsPerASN: &maxChannels},
+			facts:  &Facts{SameIPChannels: 1, SameASNChannels: 2},
+		},
+		{
+			desc:     "IP maximum reached",
+			policy:   Policy{MaxChannelsPerIP: &maxChannels},
+			facts:    &Facts{SameIPChannels: 3},
+			expected: errors.New("Maximum number of channels per IP address reached"),
+		},
+		{
+			desc:     "ASN maximum reached",
+			policy:   Policy{MaxChannelsPerASN: &maxChannels},
+			facts:    &Facts{SameASNChannels: 4},
+			expected: errors.New("Maximum number of channels per ASN reached"),
+		},
+		{
+			desc:   "Unknown origin",
+			policy: Policy{MaxChannelsPerIP: &maxChannels, MaxChannelsPerASN: &maxChannels},
+		},
+		{
+			desc:   "Nil",
+			policy: Policy{},
+			facts:  &Facts{SameIPChannels: 100, SameASNChannels: 100},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			req := &lnrpc.ChannelAcceptRequest{}
+			resp := &lnrpc.ChannelAcceptResponse{}
+			peer := &lnrpc.NodeInfo{Node: &lnrpc.LightningNode{}}
+
+			err := tc.policy.Enforce(req, resp, &lnrpc.GetInfoResponse{}, peer, tc.facts)
+			assert.Equal(t, tc.expected, err)
+		})
+	}
+}
+
+func TestCheckMaxInboundCapacity(t *testing.T) {
+	maxInbound := uint64(10_000_000)
+
+	cases := []struct {
+		facts    *Facts
+		req      *lnrpc.ChannelAcceptRequest
+		desc     string
+		expected bool
+	}{
+		{
+			desc:     "Below maximum",
+			facts:    &Facts{InboundCapacity: 6_000_000},
+			req:      &lnrpc.ChannelAcceptRequest{FundingAmt: 2_000_000},
+			expected: true,
+		},
+		{
+			desc:     "Maximum reached",
+			facts:    &Facts{InboundCapacity: 10_000_000},
+			req:      &lnrpc.ChannelAcceptRequest{FundingAmt: 1},
+			expected: false,
+		},
+		{
+			desc:     "Request exceeds maximum",
+			facts:    &Facts{InboundCapacity: 6_000_000},
+			req:      &lnrpc.ChannelAcceptRequest{FundingAmt: 5_000_000},
+			expected: false,
+		},
+		{
+			desc:     "Pushed amount",
+			facts:    &Facts{InboundCapacity: 6_000_000},
+			req:      &lnrpc.ChannelAcceptRequest{FundingAmt: 5_000_000, PushAmt: 1_000_000_000},
+			expected: true,
+		},
+		{
+			desc:     "Unknown facts",
+			req:      &lnrpc.ChannelAcceptRequest{FundingAmt: 5_000_000},
+			expected: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			policy := Policy{MaxInboundCapacity: &maxInbound}
+			assert.Equal(t, tc.expected, policy.checkMaxInboundCapacity(tc.req, tc.facts))
+		})
+	}
+
+	t.Run("Nil", func(t *testing.T) {
+		policy := Policy{}
+		assert.True(t, policy.checkMaxInboundCapacity(&lnrpc.ChannelAcceptRequest{FundingAmt: 1}, nil))
+	})
+}
+
+func TestApplies(t *testing.T) {
+	tru := true
+	peer := &lnrpc.NodeInfo{Node: &lnrpc.LightningNode{PubKey: "public_key"}}
+	req := &lnrpc.ChannelAcceptRequest{ChannelFlags: 1}
+
+	policy := Policy{}
+	assert.True(t, policy.Applies(req, &lnrpc.GetInfoResponse{}, peer, nil))
+
+	policy = Policy{Conditions: &Conditions{IsPrivate: &tru}}
+	assert.False(t, policy.Applies(req, &lnrpc.GetInfoResponse{}, peer, nil))
+
+	// Requirements are enforced regardless of the conditions
+	policy.RejectAll = &tru
+	err := policy.Enforce(req, &lnrpc.ChannelAcceptResponse{}, &lnrpc.GetInfoResponse{}, peer, nil)
+	assert.Error(t, err)
+}
+
+func TestLabel(t *testing.T) {
+	policy := Policy{}
+	assert.Equal(t, "#3", policy.Label(3))
+
+	policy.Name = "routing nodes"
+	assert.Equal(t, "routing nodes", policy.Label(3))
+}
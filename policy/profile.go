@@ -0,0 +1,130 @@
+package policy
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// Metric is a value the policies compute from the peer information. Metrics computed over the
+// peer channels have their statistics instead of a single value.
+type Metric struct {
+	// Key of the requirement that evaluates the metric, like "node.channels.fee_rates".
+	Key   string
+	Value string
+	Stats *Stats
+}
+
+// Stats summarizes the values of a metric over a set of channels, with the operations the
+// policies support.
+type Stats struct {
+	Min    float64
+	Median float64
+	Mean   float64
+	Mode   float64
+	Range  float64
+	Max    float64
+}
+
+// Profile returns every metric the policies would compute for the peer, in the order they are
+// evaluated, so operators can inspect a node before adding policies about it. The node is ours.
+func Profile(node *lnrpc.GetInfoResponse, peer *lnrpc.NodeInfo, facts *Facts) []Metric {
+	now := facts.now()
+	together := len(sharedChannels(node.IdentityPubkey, peer).Channels)
+	metrics := []Metric{
+		{Key: "node.age", Value: formatUint(nodeAge(node.BlockHeight, peer.Channels))},
+		{Key: "node.capacity", Value: strconv.FormatInt(peer.TotalCapacity, 10)},
+		{Key: "node.hybrid", Value: strconv.FormatBool(isHybrid(peer.Node.Addresses))},
+		{Key: "node.supports_splicing", Value: strconv.FormatBool(supportsSplicing(peer.Node.Features))},
+		{Key: "node.supports_dual_funding", Value: strconv.FormatBool(supportsDualFunding(peer.Node.Features))},
+	}
+	if facts != nil && !facts.FirstSeen.IsZero() {
+		metrics = append(metrics,
+			Metric{Key: "node.first_seen_age", Value: formatUint(firstSeenAge(facts))})
+	}
+	metrics = append(metrics, Metric{Key: "node.graph_freshness", Value: formatUint(graphFreshness(peer, facts))})
+	if facts != nil && facts.Reach != nil {
+		metrics = append(metrics,
+			Metric{Key: "node.new_reach", Value: formatUint(newReach(node.IdentityPubkey, peer, facts))},
+			Metric{
+				Key:   "node.peer_overlap_ratio",
+				Value: strconv.FormatFloat(peerOverlap(node.IdentityPubkey, peer, facts), 'f', 2, 64),
+			},
+		)
+	}
+	metrics = append(metrics,
+		Metric{Key: "node.reputation", Value: strconv.FormatFloat(facts.reputation(), 'f', 2, 64)},
+		Metric{Key: "node.previous_rejections", Value: formatUint(facts.rejections())},
+		Metric{Key: "node.previous_acceptances", Value: formatUint(facts.acceptances())},
+	)
+	if facts != nil && !facts.LastDecision.IsZero() {
+		metrics = append(metrics,
+			Metric{Key: "node.last_decision_age", Value: formatUint(lastDecisionAge(facts))})
+	}
+	if knownAs := facts.knownAs(); len(knownAs) > 0 {
+		metrics = append(metrics, Metric{Key: "node.known_as", Value: strings.Join(knownAs, ",")})
+	}
+	metrics = append(metrics,
+		Metric{Key: "node.channels.number", Value: formatUint(peer.NumChannels)},
+		stat("node.channels.capacity", peer, capacityFunc),
+		stat("node.channels.block_height", peer, blockHeightFunc),
+		stat("node.channels.time_lock_delta", peer, timeLockDeltaFunc()),
+		stat("node.channels.min_htlc", peer, minHTLCFunc()),
+		stat("node.channels.max_htlc", peer, maxHTLCFunc()),
+		stat("node.channels.last_update_diff", peer, lastUpdateFunc(now.Unix())),
+		Metric{Key: "node.channels.together", Value: strconv.Itoa(together)},
+		stat("node.channels.fee_rates", peer, feeRatesFunc(true)),
+		stat("node.channels.base_fees", peer, baseFeesFunc(true)),
+		stat("node.channels.inbound_fee_rates", peer, inboundFeeRatesFunc(true)),
+		stat("node.channels.inbound_base_fees", peer, inboundBaseFeesFunc(true)),
+		stat("node.channels.inbound_fees_signaled", peer, inboundFeesSignaledFunc),
+		stat("node.channels.disabled", peer, disabledFunc(true)),
+		stat("node.channels.peers.fee_rates", peer, feeRatesFunc(false)),
+		stat("node.channels.peers.base_fees", peer, baseFeesFunc(false)),
+		stat("node.channels.peers.inbound_fee_rates", peer, inboundFeeRatesFunc(false)),
+		stat("node.channels.peers.inbound_base_fees", peer, inboundBaseFeesFunc(false)),
+		stat("node.channels.peers.disabled", peer, disabledFunc(false)),
+	)
+
+	if shared := sharedChannels(node.IdentityPubkey, peer); len(shared.Channels) > 0 {
+		metrics = append(metrics,
+			stat("node.channels.toward_us.fee_rates", shared, feeRatesFunc(true)),
+			stat("node.channels.toward_us.base_fees", shared, baseFeesFunc(true)),
+		)
+	}
+
+	if facts != nil {
+		uptime := uint64(facts.MaxChannelUptime / time.Second)
+		metrics = append(metrics, Metric{Key: "escalation.uptime", Value: formatUint(uptime)})
+	}
+
+	return metrics
+}
+
+// stat computes the statistics with the metric type, like the policies do, so integer means and
+// medians are truncated as well.
+func stat[T Number](key string, peer *lnrpc.NodeInfo, f channelFunc[T]) Metric {
+	values := make([]T, 0, len(peer.Channels))
+	for _, channel := range peer.Channels {
+		values = append(values, f(peer, channel))
+	}
+	if len(values) == 0 {
+		return Metric{Key: key, Stats: &Stats{}}
+	}
+
+	return Metric{Key: key, Stats: &Stats{
+		Min:    float64(slices.Min(values)),
+		Median: float64(median(values)),
+		Mean:   float64(mean(values)),
+		Mode:   float64(mode(values)),
+		Range:  float64(rangeOp(values)),
+		Max:    float64(slices.Max(values)),
+	}}
+}
+
+func formatUint[T uint32 | uint64](v T) string {
+	return strconv.FormatUint(uint64(v), 10)
+}
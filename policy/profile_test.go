@@ -0,0 +1,112 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfile(t *testing.T) {
+	node := &lnrpc.GetInfoResponse{IdentityPubkey: "us", BlockHeight: 800_100}
+	peer := &lnrpc.NodeInfo{
+		Node: &lnrpc.LightningNode{
+			PubKey: "peer",
+			Addresses: []*lnrpc.NodeAddress{
+				{Addr: "1.1.1.1:9735"},
+				{Addr: "abcdef.onion:9735"},
+			},
+		},
+		NumChannels:   3,
+		TotalCapacity: 6_000_000,
+		Channels: []*lnrpc.ChannelEdge{
+			{
+				ChannelId:   800_000 << 40,
+				Node1Pub:    "peer",
+				Node2Pub:    "us",
+				Capacity:    1_000_000,
+				Node1Policy: &lnrpc.RoutingPolicy{FeeRateMilliMsat: 100_000},
+				Node2Policy: &lnrpc.RoutingPolicy{},
+			},
+			{
+				ChannelId:   800_050 << 40,
+				Node1Pub:    "a",
+				Node2Pub:    "peer",
+				Capacity:    2_000_000,
+				Node1Policy: &lnrpc.RoutingPolicy{},
+				Node2Policy: &lnrpc.RoutingPolicy{FeeRateMilliMsat: 300_000, Disabled: true},
+			},
+			{
+				ChannelId:   800_090 << 40,
+				Node1Pub:    "peer",
+				Node2Pub:    "b",
+				Capacity:    3_000_000,
+				Node1Policy: &lnrpc.RoutingPolicy{FeeRateMilliMsat: 300_000},
+				Node2Policy: &lnrpc.RoutingPolicy{},
+			},
+		},
+	}
+	facts := &Facts{
+		Now:              time.Unix(1_700_000_000, 0),
+		FirstSeen:        time.Unix(1_699_999_000, 0),
+		Peers:            map[string]struct{}{"a": {}},
+		Reach:            map[string]struct{}{"us": {}, "a": {}},
+		Reputation:       2.5,
+		Rejections:       3,
+		LastDecision:     time.Unix(1_699_999_400, 0),
+		KnownAs:          []string{"lsp", "wallet"},
+		MaxChannelUptime: 90 * time.Second,
+	}
+
+	metrics := make(map[string]Metric)
+	for _, metric := range Profile(node, peer, facts) {
+		metrics[metric.Key] = metric
+	}
+
+	assert.Equal(t, "101", metrics["node.age"].Value)
+	assert.Equal(t, "6000000", metrics["node.capacity"].Value)
+	assert.Equal(t, "true", metrics["node.hybrid"].Value)
+	assert.Equal(t, "1000", metrics["node.first_seen_age"].Value)
+	assert.Equal(t, "1", metrics["node.new_reach"].Value)
+	assert.Equal(t, "0.50", metrics["node.peer_overlap_ratio"].Value)
+	assert.Equal(t, "2.50", metrics["node.reputation"].Value)
+	assert.Equal(t, "3", metrics["node.previous_rejections"].Value)
+	assert.Equal(t, "0", metrics["node.previous_acceptances"].Value)
+	assert.Equal(t, "600", metrics["node.last_decision_age"].Value)
+	assert.Equal(t, "lsp,wallet", metrics["node.known_as"].Value)
+	assert.Equal(t, "1", metrics["node.channels.together"].Value)
+	assert.Equal(t, "90", metrics["escalation.uptime"].Value)
+
+	capacity := metrics["node.channels.capacity"].Stats
+	assert.Equal(t, 1_000_000.0, capacity.Min)
+	assert.Equal(t, 2_000_000.0, capacity.Median)
+	assert.Equal(t, 2_000_000.0, capacity.Mean)
+	assert.Equal(t, 2_000_000.0, capacity.Range)
+	assert.Equal(t, 3_000_000.0, capacity.Max)
+
+	feeRates := metrics["node.channels.fee_rates"].Stats
+	assert.Equal(t, 100.0, feeRates.Min)
+	assert.Equal(t, 300.0, feeRates.Median)
+	assert.Equal(t, 300.0, feeRates.Mode)
+
+	assert.InDelta(t, 1.0/3, metrics["node.channels.disabled"].Stats.Mean, 0.001)
+	assert.Equal(t, 100.0, metrics["node.channels.toward_us.fee_rates"].Stats.Mean)
+}
+
+func TestProfileWithoutFacts(t *testing.T) {
+	node := &lnrpc.GetInfoResponse{IdentityPubkey: "us"}
+	peer := &lnrpc.NodeInfo{Node: &lnrpc.LightningNode{PubKey: "peer"}}
+
+	metrics := Profile(node, peer, nil)
+	keys := make([]string, 0, len(metrics))
+	for _, metric := range metrics {
+		keys = append(keys, metric.Key)
+	}
+
+	assert.NotContains(t, keys, "node.first_seen_age")
+	assert.NotContains(t, keys, "node.new_reach")
+	assert.NotContains(t, keys, "escalation.uptime")
+	assert.Contains(t, keys, "node.channels.capacity")
+	assert.NotContains(t, keys, "node.channels.toward_us.fee_rates")
+}
@@ -2,6 +2,7 @@ package policy
 
 import (
 	"fmt"
+	"reflect"
 	"slices"
 	"strings"
 
@@ -19,11 +20,40 @@ const (
 	Mode Operation = "mode"
 	// Difference between the biggest and the smallest number.
 	RangeOp Operation = "range"
+	// Smallest value on a list.
+	MinOp Operation = "min"
+	// Biggest value on a list.
+	MaxOp Operation = "max"
 )
 
 // Operation is a mathematical operation applied to a set of values.
 type Operation string
 
+// aggregated is implemented by the statistic ranges to expose the operation they apply.
+type aggregated interface {
+	operation() Operation
+}
+
+func (a StatRange[T]) operation() Operation {
+	return a.Operation
+}
+
+// validateOperations verifies the operations of the statistic ranges in the value received.
+func validateOperations(v reflect.Value, path string) error {
+	var err error
+	walkRanges(v, path, func(path string, b bounded) {
+		if a, ok := b.(aggregated); ok && err == nil {
+			switch a.operation() {
+			case "", Mean, Median, Mode, RangeOp, MinOp, MaxOp:
+			default:
+				err = fmt.Errorf("%s.operation: invalid operation %q, expected %s, %s, %s, %s, %s or %s",
+					path, a.operation(), Mean, Median, Mode, RangeOp, MinOp, MaxOp)
+			}
+		}
+	})
+	return err
+}
+
 // Number is an integer or float.
 type Number interface {
 	constraints.Integer | constraints.Float
@@ -31,8 +61,9 @@ type Number interface {
 
 // Range represents the limits of a series.
 type Range[T Number] struct {
-	Min *T `yaml:"min,omitempty"`
-	Max *T `yaml:"max,omitempty"`
+	Min      *T     `yaml:"min,omitempty" doc:"Minimum value, inclusive. Accepts unit suffixes like 2m, 0.05btc, 500ppm or 30d."`
+	Max      *T     `yaml:"max,omitempty" doc:"Maximum value, inclusive."`
+	Severity string `yaml:"severity,omitempty" default:"reject" doc:"Whether the requests violating the range are rejected (reject) or only reported (warn). Ignored in conditions."`
 }
 
 // Contains returns whether the received value is within the range.
@@ -71,9 +102,11 @@ func check[T Number](r *Range[T], v T) bool {
 
 // StatRange is like a range but received multiple values and applies an operation to them.
 type StatRange[T Number] struct {
-	Min       *T        `yaml:"min,omitempty"`
-	Max       *T        `yaml:"max,omitempty"`
-	Operation Operation `yaml:"operation,omitempty"`
+	Min           *T        `yaml:"min,omitempty" doc:"Minimum value of the aggregate, inclusive."`
+	Max           *T        `yaml:"max,omitempty" doc:"Maximum value of the aggregate, inclusive."`
+	Operation     Operation `yaml:"operation,omitempty" default:"mean" doc:"Operation aggregating the channels values: mean, median, mode, range, min or max."`
+	Severity      string    `yaml:"severity,omitempty" default:"reject" doc:"Whether the requests violating the range are rejected (reject) or only reported (warn). Ignored in conditions."`
+	MissingPolicy string    `yaml:"missing_policy,omitempty" default:"zero" doc:"How metrics read from routing policies treat channels whose policy is unknown. zero uses zero values, skip leaves the channels out and fail makes the requirement fail."`
 }
 
 // Contains returns whether the aggregated value is within the range.
@@ -86,6 +119,10 @@ func (a StatRange[T]) Contains(values []T) bool {
 		v = mode(values)
 	case RangeOp:
 		v = rangeOp(values)
+	case MinOp:
+		v = minOp(values)
+	case MaxOp:
+		v = maxOp(values)
 	default:
 		v = mean(values)
 	}
@@ -192,3 +229,19 @@ func rangeOp[T Number](values []T) T {
 
 	return values[len(values)-1] - values[0]
 }
+
+func minOp[T Number](values []T) T {
+	if len(values) == 0 {
+		return 0
+	}
+
+	return slices.Min(values)
+}
+
+func maxOp[T Number](values []T) T {
+	if len(values) == 0 {
+		return 0
+	}
+
+	return slices.Max(values)
+}
@@ -337,6 +337,34 @@ func TestStatRangeContains(t *testing.T) {
 			values:    []int{0, 4},
 			expected:  false,
 		},
+		{
+			desc:      "Min",
+			operation: MinOp,
+			min:       -3,
+			values:    []int{-2, 4, 7},
+			expected:  true,
+		},
+		{
+			desc:      "Min out",
+			operation: MinOp,
+			min:       -1,
+			values:    []int{-2, 4, 7},
+			expected:  false,
+		},
+		{
+			desc:      "Max",
+			operation: MaxOp,
+			max:       -1,
+			values:    []int{-5, -3, -1},
+			expected:  true,
+		},
+		{
+			desc:      "Max out",
+			operation: MaxOp,
+			max:       5,
+			values:    []int{-10, -10, 6},
+			expected:  false,
+		},
 	}
 
 	for _, tc := range cases {
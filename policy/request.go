@@ -1,7 +1,6 @@
 package policy
 
 import (
-	"errors"
 	"fmt"
 
 	"github.com/lightningnetwork/lnd/lnrpc"
@@ -9,52 +8,57 @@ import (
 
 // Request represents the desired values in a channel request.
 type Request struct {
-	ChannelCapacity  *Range[uint64]          `yaml:"channel_capacity,omitempty"`
-	ChannelReserve   *Range[uint64]          `yaml:"channel_reserve,omitempty"`
-	CSVDelay         *Range[uint32]          `yaml:"csv_delay,omitempty"`
-	PushAmount       *Range[uint64]          `yaml:"push_amount,omitempty"`
-	MaxAcceptedHTLCs *Range[uint32]          `yaml:"max_accepted_htlcs,omitempty"`
-	MinHTLC          *Range[uint64]          `yaml:"min_htlc,omitempty"`
-	MaxValueInFlight *Range[uint64]          `yaml:"max_value_in_flight,omitempty"`
-	DustLimit        *Range[uint64]          `yaml:"dust_limit,omitempty"`
-	CommitmentTypes  *[]lnrpc.CommitmentType `yaml:"commitment_types,omitempty"`
+	ChannelCapacity  *Range[uint64]          `yaml:"channel_capacity,omitempty" unit:"amount" doc:"Requested channel size, in sats."`
+	ChannelReserve   *Range[uint64]          `yaml:"channel_reserve,omitempty" unit:"amount" doc:"Requested channel reserve, in sats."`
+	CSVDelay         *Range[uint32]          `yaml:"csv_delay,omitempty" unit:"number" doc:"Requested CSV delay, in blocks."`
+	PushAmount       *Range[uint64]          `yaml:"push_amount,omitempty" unit:"amount" doc:"Amount pushed to us, in sats."`
+	MaxAcceptedHTLCs *Range[uint32]          `yaml:"max_accepted_htlcs,omitempty" unit:"number" doc:"Total number of incoming HTLCs the initiator will accept."`
+	MinHTLC          *Range[uint64]          `yaml:"min_htlc,omitempty" unit:"number" doc:"Smallest HTLC the initiator will accept, in millisatoshis."`
+	MaxValueInFlight *Range[uint64]          `yaml:"max_value_in_flight,omitempty" unit:"number" doc:"Maximum amount that can be pending in the channel, in millisatoshis."`
+	DustLimit        *Range[uint64]          `yaml:"dust_limit,omitempty" unit:"amount" doc:"Dust limit of the initiator's commitment transaction, in sats."`
+	CommitmentTypes  *[]lnrpc.CommitmentType `yaml:"commitment_types,omitempty" doc:"Accepted channel commitment types, see lnrpc.CommitmentType."`
 }
 
-func (r *Request) evaluate(req *lnrpc.ChannelAcceptRequest) error {
+func (r *Request) evaluate(req *lnrpc.ChannelAcceptRequest, w *warnings) error {
 	if r == nil {
 		return nil
 	}
 
-	if !check(r.ChannelCapacity, req.FundingAmt) {
-		return errors.New("Channel capacity " + r.ChannelCapacity.Reason())
+	if err := checkRange(r.ChannelCapacity, req.FundingAmt, w, "Channel capacity"); err != nil {
+		return err
 	}
 
 	if !check(r.PushAmount, req.PushAmt) {
-		return errors.New("Pushed amount lower than expected")
+		if err := w.violation(r.PushAmount, "Pushed amount lower than expected"); err != nil {
+			return err
+		}
 	}
 
-	if !check(r.ChannelReserve, req.ChannelReserve) {
-		return errors.New("Channel reserve " + r.ChannelReserve.Reason())
+	if err := checkRange(r.ChannelReserve, req.ChannelReserve, w, "Channel reserve"); err != nil {
+		return err
 	}
 
-	if !check(r.CSVDelay, req.CsvDelay) {
-		return errors.New("Check sequence verify delay " + r.CSVDelay.Reason())
+	if err := checkRange(r.CSVDelay, req.CsvDelay, w, "Check sequence verify delay"); err != nil {
+		return err
 	}
 
-	if !check(r.MaxAcceptedHTLCs, req.MaxAcceptedHtlcs) {
-		return errors.New("Maximum accepted HTLCs " + r.MaxAcceptedHTLCs.Reason())
+	if err := checkRange(r.MaxAcceptedHTLCs, req.MaxAcceptedHtlcs,
+		w, "Maximum accepted HTLCs"); err != nil {
+		return err
 	}
 
-	if !check(r.MinHTLC, req.MinHtlc) {
-		return errors.New("Minimum HTLCs " + r.MinHTLC.Reason())
+	if err := checkRange(r.MinHTLC, req.MinHtlc, w, "Minimum HTLCs"); err != nil {
+		return err
 	}
 
-	if !check(r.MaxValueInFlight, req.MaxValueInFlight) {
-		return errors.New("Maximum value in flight " + r.MaxValueInFlight.Reason())
+	if err := checkRange(r.MaxValueInFlight, req.MaxValueInFlight,
+		w, "Maximum value in flight"); err != nil {
+		return err
 	}
 
-	if !check(r.DustLimit, req.DustLimit) {
-		return errors.New("Commitment transaction dust limit " + r.DustLimit.Reason())
+	if err := checkRange(r.DustLimit, req.DustLimit,
+		w, "Commitment transaction dust limit"); err != nil {
+		return err
 	}
 
 	if !r.checkCommitmentType(req.CommitmentType) {
@@ -140,7 +140,7 @@ func TestEvaluateRequest(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.desc, func(t *testing.T) {
-			err := tc.req.evaluate(tc.chanReq)
+			err := tc.req.evaluate(tc.chanReq, nil)
 			if tc.fail {
 				assert.NotNil(t, err)
 			} else {
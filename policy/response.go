@@ -0,0 +1,60 @@
+package policy
+
+import (
+	"fmt"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// Limits of the channel parameters accepted by LND and the other implementations.
+const (
+	// MaxCSVDelay is the default maximum delay LND (maxlocaldelay) accepts for its funds.
+	MaxCSVDelay = 2016
+	// MaxHTLCCount is the maximum number of HTLCs a channel side can have, as of BOLT 2.
+	MaxHTLCCount = 483
+)
+
+// FixResponse corrects the parameters of an accepting response that LND would refuse, failing the
+// channel opening without telling why, and returns the descriptions of the changes made.
+//
+// The policies and the chained acceptor set the parameters independently, so combinations like a
+// zero conf channel with a minimum accept depth are possible.
+func FixResponse(req *lnrpc.ChannelAcceptRequest, resp *lnrpc.ChannelAcceptResponse) []string {
+	if !resp.Accept {
+		return nil
+	}
+
+	var fixes []string
+	if resp.ZeroConf && !req.WantsZeroConf {
+		resp.ZeroConf = false
+		fixes = append(fixes, "zero conf disabled as it wasn't requested")
+	}
+
+	if resp.ZeroConf && resp.MinAcceptDepth != 0 {
+		fixes = append(fixes, fmt.Sprintf("min accept depth %d set to 0 for zero conf", resp.MinAcceptDepth))
+		resp.MinAcceptDepth = 0
+	}
+
+	if resp.ReserveSat != 0 {
+		if maxReserve := req.FundingAmt / 5; resp.ReserveSat > maxReserve {
+			fixes = append(fixes, fmt.Sprintf("reserve %d lowered to 20%% of the capacity", resp.ReserveSat))
+			resp.ReserveSat = maxReserve
+		}
+		if resp.ReserveSat < req.DustLimit {
+			fixes = append(fixes, fmt.Sprintf("reserve %d raised to the dust limit", resp.ReserveSat))
+			resp.ReserveSat = req.DustLimit
+		}
+	}
+
+	if resp.CsvDelay > MaxCSVDelay {
+		fixes = append(fixes, fmt.Sprintf("csv delay %d lowered to %d", resp.CsvDelay, MaxCSVDelay))
+		resp.CsvDelay = MaxCSVDelay
+	}
+
+	if resp.MaxHtlcCount > MaxHTLCCount {
+		fixes = append(fixes, fmt.Sprintf("max htlc count %d lowered to %d", resp.MaxHtlcCount, MaxHTLCCount))
+		resp.MaxHtlcCount = MaxHTLCCount
+	}
+
+	return fixes
+}
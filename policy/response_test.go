@@ -0,0 +1,74 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixResponse(t *testing.T) {
+	cases := []struct {
+		req      *lnrpc.ChannelAcceptRequest
+		resp     *lnrpc.ChannelAcceptResponse
+		expected *lnrpc.ChannelAcceptResponse
+		desc     string
+		fixes    int
+	}{
+		{
+			desc:     "Rejected",
+			req:      &lnrpc.ChannelAcceptRequest{},
+			resp:     &lnrpc.ChannelAcceptResponse{ZeroConf: true, MinAcceptDepth: 3},
+			expected: &lnrpc.ChannelAcceptResponse{ZeroConf: true, MinAcceptDepth: 3},
+		},
+		{
+			desc:     "Consistent",
+			req:      &lnrpc.ChannelAcceptRequest{FundingAmt: 1_000_000, DustLimit: 354},
+			resp:     &lnrpc.ChannelAcceptResponse{Accept: true, ReserveSat: 10_000, CsvDelay: 144},
+			expected: &lnrpc.ChannelAcceptResponse{Accept: true, ReserveSat: 10_000, CsvDelay: 144},
+		},
+		{
+			desc:     "Zero conf with depth",
+			req:      &lnrpc.ChannelAcceptRequest{WantsZeroConf: true},
+			resp:     &lnrpc.ChannelAcceptResponse{Accept: true, ZeroConf: true, MinAcceptDepth: 3},
+			expected: &lnrpc.ChannelAcceptResponse{Accept: true, ZeroConf: true},
+			fixes:    1,
+		},
+		{
+			desc:     "Zero conf not requested",
+			req:      &lnrpc.ChannelAcceptRequest{},
+			resp:     &lnrpc.ChannelAcceptResponse{Accept: true, ZeroConf: true, MinAcceptDepth: 3},
+			expected: &lnrpc.ChannelAcceptResponse{Accept: true, MinAcceptDepth: 3},
+			fixes:    1,
+		},
+		{
+			desc:     "Reserve below dust",
+			req:      &lnrpc.ChannelAcceptRequest{FundingAmt: 1_000_000, DustLimit: 354},
+			resp:     &lnrpc.ChannelAcceptResponse{Accept: true, ReserveSat: 100},
+			expected: &lnrpc.ChannelAcceptResponse{Accept: true, ReserveSat: 354},
+			fixes:    1,
+		},
+		{
+			desc:     "Reserve too high",
+			req:      &lnrpc.ChannelAcceptRequest{FundingAmt: 1_000_000, DustLimit: 354},
+			resp:     &lnrpc.ChannelAcceptResponse{Accept: true, ReserveSat: 500_000},
+			expected: &lnrpc.ChannelAcceptResponse{Accept: true, ReserveSat: 200_000},
+			fixes:    1,
+		},
+		{
+			desc:     "Limits",
+			req:      &lnrpc.ChannelAcceptRequest{FundingAmt: 1_000_000},
+			resp:     &lnrpc.ChannelAcceptResponse{Accept: true, CsvDelay: 10_000, MaxHtlcCount: 1000},
+			expected: &lnrpc.ChannelAcceptResponse{Accept: true, CsvDelay: MaxCSVDelay, MaxHtlcCount: MaxHTLCCount},
+			fixes:    2,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			fixes := FixResponse(tc.req, tc.resp)
+			assert.Len(t, fixes, tc.fixes)
+			assert.Equal(t, tc.expected, tc.resp)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package policy
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// Strategies to pick the channels sampled.
+const (
+	// SampleRandom picks channels uniformly, it's the default.
+	SampleRandom = "random"
+	// SampleLargest picks the channels with the biggest capacity.
+	SampleLargest = "largest"
+)
+
+// Sampling bounds the number of channels the statistics of a node are computed over.
+type Sampling struct {
+	MaxChannels int    `yaml:"max_channels,omitempty" doc:"Maximum number of channels the statistic ranges are computed over. Required."`
+	Strategy    string `yaml:"strategy,omitempty" default:"random" doc:"How the channels are picked: random or largest (capacity)."`
+}
+
+// sample returns a copy of the peer information with at most the maximum number of channels, or
+// the peer itself if it doesn't have more.
+//
+// The random sample is derived from the channel IDs, so the same channels are picked every time the
+// node is evaluated.
+func (s *Sampling) sample(peer *lnrpc.NodeInfo) *lnrpc.NodeInfo {
+	if s == nil || s.MaxChannels <= 0 || len(peer.Channels) <= s.MaxChannels {
+		return peer
+	}
+
+	channels := slices.Clone(peer.Channels)
+	switch s.Strategy {
+	case SampleLargest:
+		slices.SortFunc(channels, func(a, b *lnrpc.ChannelEdge) int {
+			if c := cmp.Compare(b.Capacity, a.Capacity); c != 0 {
+				return c
+			}
+			return cmp.Compare(a.ChannelId, b.ChannelId)
+		})
+	default:
+		slices.SortFunc(channels, func(a, b *lnrpc.ChannelEdge) int {
+			return cmp.Compare(mix(a.ChannelId), mix(b.ChannelId))
+		})
+	}
+
+	return &lnrpc.NodeInfo{
+		Node:          peer.Node,
+		NumChannels:   peer.NumChannels,
+		TotalCapacity: peer.TotalCapacity,
+		Channels:      channels[:s.MaxChannels],
+	}
+}
+
+func (s *Sampling) validate(field string) error {
+	if s == nil {
+		return nil
+	}
+
+	if s.MaxChannels <= 0 {
+		return errors.New(field + ".max_channels: must be positive")
+	}
+
+	switch s.Strategy {
+	case "", SampleRandom, SampleLargest:
+		return nil
+	default:
+		return errors.New(field + ".strategy: must be random or largest")
+	}
+}
+
+// mix scrambles the bits of the value (splitmix64 finalizer), so ordering by it shuffles channel
+// IDs, which otherwise follow their block heights.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,91 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSample(t *testing.T) {
+	peer := &lnrpc.NodeInfo{
+		Node:        &lnrpc.LightningNode{PubKey: "public_key"},
+		NumChannels: 5,
+		Channels: []*lnrpc.ChannelEdge{
+			{ChannelId: 1, Capacity: 100},
+			{ChannelId: 2, Capacity: 500},
+			{ChannelId: 3, Capacity: 300},
+			{ChannelId: 4, Capacity: 500},
+			{ChannelId: 5, Capacity: 200},
+		},
+	}
+
+	cases := []struct {
+		desc     string
+		sampling *Sampling
+		expected []uint64
+	}{
+		{
+			desc:     "Nil",
+			expected: []uint64{1, 2, 3, 4, 5},
+		},
+		{
+			desc:     "Fewer channels",
+			sampling: &Sampling{MaxChannels: 5},
+			expected: []uint64{1, 2, 3, 4, 5},
+		},
+		{
+			desc:     "Largest",
+			sampling: &Sampling{MaxChannels: 3, Strategy: SampleLargest},
+			expected: []uint64{2, 4, 3},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			sampled := tc.sampling.sample(peer)
+			assert.Equal(t, tc.expected, channelIDs(sampled))
+			assert.Equal(t, peer.NumChannels, sampled.NumChannels)
+		})
+	}
+
+	t.Run("Random", func(t *testing.T) {
+		sampling := &Sampling{MaxChannels: 3, Strategy: SampleRandom}
+		sampled := sampling.sample(peer)
+		assert.Len(t, sampled.Channels, 3)
+		assert.Equal(t, channelIDs(sampled), channelIDs(sampling.sample(peer)))
+		assert.Len(t, peer.Channels, 5)
+	})
+}
+
+func TestEvaluateSampledChannels(t *testing.T) {
+	publicKey := "public_key"
+	peer := &lnrpc.NodeInfo{
+		Node: &lnrpc.LightningNode{PubKey: publicKey},
+		Channels: []*lnrpc.ChannelEdge{
+			{ChannelId: 1, Capacity: 1_000_000, Node1Pub: publicKey, Node2Pub: "node"},
+			{ChannelId: 2, Capacity: 100_000, Node1Pub: publicKey, Node2Pub: "node"},
+			{ChannelId: 3, Capacity: 100_000, Node1Pub: publicKey, Node2Pub: "node"},
+		},
+	}
+	min := int64(500_000)
+	together := 3
+
+	channels := &Channels{
+		Capacity: &StatRange[int64]{Operation: MinOp, Min: &min},
+		Together: &Range[int]{Min: &together},
+		Sampling: &Sampling{MaxChannels: 1, Strategy: SampleLargest},
+	}
+	assert.NoError(t, channels.evaluate("node", peer, &warnings{}))
+
+	channels.Sampling = nil
+	assert.EqualError(t, channels.evaluate("node", peer, &warnings{}), "Capacity min value is lower than 500000")
+}
+
+func channelIDs(peer *lnrpc.NodeInfo) []uint64 {
+	ids := make([]uint64, 0, len(peer.Channels))
+	for _, channel := range peer.Channels {
+		ids = append(ids, channel.ChannelId)
+	}
+	return ids
+}
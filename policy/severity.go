@@ -0,0 +1,86 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// Severities of the range requirements.
+const (
+	// CheckReject makes the requests violating the range be rejected, it's the default.
+	CheckReject = "reject"
+	// CheckWarn makes the violations of the range be reported without rejecting the requests,
+	// useful while phasing in new criteria.
+	CheckWarn = "warn"
+)
+
+// severe is implemented by the ranges to report the severity of their violations.
+type severe interface {
+	severity() string
+}
+
+func (r Range[T]) severity() string {
+	return r.Severity
+}
+
+func (a StatRange[T]) severity() string {
+	return a.Severity
+}
+
+// warnings collects the violations of the requirements with the warn severity. Conditions use a
+// nil collector, every range is strict in them.
+type warnings struct {
+	messages []string
+}
+
+// violation returns the rejection of a failed requirement, or records it and returns nil if the
+// requirement only warns.
+func (w *warnings) violation(s severe, reason string) error {
+	if w == nil || s.severity() != CheckWarn {
+		return errors.New(reason)
+	}
+
+	w.messages = append(w.messages, reason)
+	return nil
+}
+
+// checkRange returns the violation of the range if the value is not within it.
+func checkRange[T Number](r *Range[T], v T, w *warnings, subject string) error {
+	if check(r, v) {
+		return nil
+	}
+	return w.violation(r, subject+" "+r.Reason())
+}
+
+// checkStatRange returns the violation of the statistic range if the aggregate of the peer
+// channels values is not within it.
+func checkStatRange[T Number](
+	sr *StatRange[T],
+	peer *lnrpc.NodeInfo,
+	f channelFunc[T],
+	w *warnings,
+	subject string,
+) error {
+	if checkStat(sr, peer, f) {
+		return nil
+	}
+	return w.violation(sr, subject+" "+sr.Reason())
+}
+
+// validateSeverities verifies the severities of the ranges in the value received.
+func validateSeverities(v reflect.Value, path string) error {
+	var err error
+	walkRanges(v, path, func(path string, b bounded) {
+		if s, ok := b.(severe); ok && err == nil {
+			switch s.severity() {
+			case "", CheckReject, CheckWarn:
+			default:
+				err = fmt.Errorf("%s.severity: must be %s or %s", path, CheckReject, CheckWarn)
+			}
+		}
+	})
+	return err
+}
@@ -0,0 +1,95 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeverity(t *testing.T) {
+	minCapacity := uint64(1_000_000)
+	maxFeeRate := int64(100)
+	maxChannels := uint32(0)
+	peer := &lnrpc.NodeInfo{
+		Node: &lnrpc.LightningNode{PubKey: "peer"},
+		Channels: []*lnrpc.ChannelEdge{
+			{Node1Pub: "peer", Node1Policy: &lnrpc.RoutingPolicy{FeeRateMilliMsat: 500_000}},
+		},
+	}
+
+	cases := []struct {
+		desc     string
+		policy   Policy
+		warnings []string
+		fail     bool
+	}{
+		{
+			desc: "Warn",
+			policy: Policy{
+				Request: &Request{ChannelCapacity: &Range[uint64]{Min: &minCapacity, Severity: CheckWarn}},
+				Node: &Node{Channels: &Channels{
+					FeeRates: &StatRange[int64]{Max: &maxFeeRate, Severity: CheckWarn},
+				}},
+			},
+			warnings: []string{
+				"Channel capacity is lower than 1000000",
+				"Channels fee rates mean value is higher than 100",
+			},
+		},
+		{
+			desc: "Reject",
+			policy: Policy{
+				Request: &Request{ChannelCapacity: &Range[uint64]{Min: &minCapacity, Severity: CheckReject}},
+			},
+			fail: true,
+		},
+		{
+			desc: "Warn and reject",
+			policy: Policy{
+				Request: &Request{ChannelCapacity: &Range[uint64]{Min: &minCapacity, Severity: CheckWarn}},
+				Node:    &Node{Channels: &Channels{FeeRates: &StatRange[int64]{Max: &maxFeeRate}}},
+			},
+			warnings: []string{"Channel capacity is lower than 1000000"},
+			fail:     true,
+		},
+		{
+			desc: "Strict checks first",
+			policy: Policy{
+				MaxChannels: &maxChannels,
+				Request:     &Request{ChannelCapacity: &Range[uint64]{Min: &minCapacity, Severity: CheckWarn}},
+			},
+			fail: true,
+		},
+		{
+			desc: "Conditions ignore severity",
+			policy: Policy{
+				Conditions: &Conditions{Request: &Request{
+					ChannelCapacity: &Range[uint64]{Min: &minCapacity, Severity: CheckWarn},
+				}},
+				RejectAll: new(bool),
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			req := &lnrpc.ChannelAcceptRequest{FundingAmt: 500_000}
+			facts := &Facts{}
+			err := tc.policy.Evaluate(req, &lnrpc.ChannelAcceptResponse{}, &lnrpc.GetInfoResponse{}, peer, facts)
+			if tc.fail {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.warnings, facts.Warnings)
+		})
+	}
+}
+
+func TestValidateSeverity(t *testing.T) {
+	policy := Policy{Node: &Node{Channels: &Channels{FeeRates: &StatRange[int64]{Severity: "ignore"}}}}
+
+	err := policy.Validate()
+	assert.EqualError(t, err, "node.channels.fee_rates.severity: must be reject or warn")
+}
@@ -0,0 +1,309 @@
+package policy
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// unitKind is the kind of quantity a range holds, set in the unit tag of its field, which limits the
+// suffixes its values accept.
+type unitKind string
+
+// Unit kinds.
+const (
+	// unitAny accepts every suffix, it's used when the kind of the value isn't known.
+	unitAny      unitKind = ""
+	unitAmount   unitKind = "amount"
+	unitDuration unitKind = "duration"
+	unitPPM      unitKind = "ppm"
+	unitRatio    unitKind = "ratio"
+	unitNumber   unitKind = "number"
+)
+
+// unit is a suffix that can be appended to numbers in the configuration, the factor used to
+// convert them to the base unit and the kinds of values it can be used in.
+type unit struct {
+	suffix string
+	factor float64
+	kinds  []unitKind
+}
+
+// units is the list of suffixes accepted in range values. Longer suffixes must come first so they
+// are matched before their shorter counterparts (e.g. "min" before "m").
+//
+// Amounts are converted to satoshis, durations to seconds, percentages to ratios and parts per
+// million are left as they are, since fee rates are already expressed in that unit.
+var units = []unit{
+	{suffix: "btc", factor: 100_000_000, kinds: []unitKind{unitAmount}},
+	{suffix: "sats", factor: 1, kinds: []unitKind{unitAmount}},
+	{suffix: "sat", factor: 1, kinds: []unitKind{unitAmount}},
+	{suffix: "ppm", factor: 1, kinds: []unitKind{unitPPM}},
+	{suffix: "min", factor: 60, kinds: []unitKind{unitDuration}},
+	{suffix: "k", factor: 1_000, kinds: []unitKind{unitAmount, unitPPM, unitNumber}},
+	{suffix: "m", factor: 1_000_000, kinds: []unitKind{unitAmount, unitPPM, unitNumber}},
+	{suffix: "s", factor: 1, kinds: []unitKind{unitDuration}},
+	{suffix: "h", factor: 60 * 60, kinds: []unitKind{unitDuration}},
+	{suffix: "d", factor: 24 * 60 * 60, kinds: []unitKind{unitDuration}},
+	{suffix: "w", factor: 7 * 24 * 60 * 60, kinds: []unitKind{unitDuration}},
+	{suffix: "%", factor: 0.01, kinds: []unitKind{unitRatio}},
+}
+
+// UnmarshalYAML decodes the policy, rejecting the range values whose suffix doesn't match the kind
+// of the field they are set in, like a duration in a capacity.
+func (p *Policy) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawPolicy Policy
+	if err := unmarshal((*rawPolicy)(p)); err != nil {
+		return err
+	}
+
+	var raw interface{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	return checkUnits(reflect.TypeOf(p), raw, "")
+}
+
+// UnmarshalYAML decodes a range accepting human-friendly values.
+func (r *Range[T]) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw struct {
+		Min      interface{} `yaml:"min"`
+		Max      interface{} `yaml:"max"`
+		Severity string      `yaml:"severity"`
+	}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	min, err := parseOptionalValue[T](raw.Min, unitAny)
+	if err != nil {
+		return fmt.Errorf("min: %w", err)
+	}
+	max, err := parseOptionalValue[T](raw.Max, unitAny)
+	if err != nil {
+		return fmt.Errorf("max: %w", err)
+	}
+
+	r.Min = min
+	r.Max = max
+	r.Severity = raw.Severity
+	return nil
+}
+
+// rawStatRange contains the fields of a statistic range before their values are parsed.
+type rawStatRange struct {
+	Min           interface{} `yaml:"min"`
+	Max           interface{} `yaml:"max"`
+	Operation     Operation   `yaml:"operation"`
+	Severity      string      `yaml:"severity"`
+	MissingPolicy string      `yaml:"missing_policy"`
+}
+
+// UnmarshalYAML decodes a statistic range accepting human-friendly values.
+func (a *StatRange[T]) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw rawStatRange
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	return a.set(raw)
+}
+
+// UnmarshalYAML decodes the reference amount and the statistic range of an effective fee.
+func (e *EffectiveFee) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw struct {
+		Amount       interface{} `yaml:"amount"`
+		rawStatRange `yaml:",inline"`
+	}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	amount, err := parseOptionalValue[uint64](raw.Amount, unitAmount)
+	if err != nil {
+		return fmt.Errorf("amount: %w", err)
+	}
+	if amount != nil {
+		e.Amount = *amount
+	}
+
+	return e.StatRange.set(raw.rawStatRange)
+}
+
+func (a *StatRange[T]) set(raw rawStatRange) error {
+	min, err := parseOptionalValue[T](raw.Min, unitAny)
+	if err != nil {
+		return fmt.Errorf("min: %w", err)
+	}
+	max, err := parseOptionalValue[T](raw.Max, unitAny)
+	if err != nil {
+		return fmt.Errorf("max: %w", err)
+	}
+
+	a.Min = min
+	a.Max = max
+	a.Operation = raw.Operation
+	a.Severity = raw.Severity
+	a.MissingPolicy = raw.MissingPolicy
+	return nil
+}
+
+func parseOptionalValue[T Number](raw interface{}, kind unitKind) (*T, error) {
+	if raw == nil {
+		return nil, nil
+	}
+
+	v, err := parseValue[T](raw, kind)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// parseValue converts a decoded YAML value into a number, expanding the unit suffix if it is a
+// string like "5m", "0.05btc", "500ppm" or "30d". Suffixes of other kinds are rejected.
+func parseValue[T Number](raw interface{}, kind unitKind) (T, error) {
+	switch v := raw.(type) {
+	case int:
+		return convertInt[T](int64(v))
+	case int64:
+		return convertInt[T](v)
+	case uint64:
+		t := T(v)
+		if uint64(t) != v {
+			return 0, fmt.Errorf("value %d is out of range", v)
+		}
+		return t, nil
+	case float64:
+		return convert[T](v, strconv.FormatFloat(v, 'f', -1, 64))
+	case string:
+		f, err := parseUnit(v, kind)
+		if err != nil {
+			return 0, err
+		}
+		return convert[T](f, v)
+	default:
+		return 0, fmt.Errorf("invalid value %v", raw)
+	}
+}
+
+func parseUnit(s string, kind unitKind) (float64, error) {
+	value := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), "_", ""))
+
+	factor := float64(1)
+	for _, u := range units {
+		if number, ok := strings.CutSuffix(value, u.suffix); ok {
+			if kind != unitAny && !slices.Contains(u.kinds, kind) {
+				return 0, fmt.Errorf("unit %q can't be used in %s values", u.suffix, kind)
+			}
+			value = strings.TrimSpace(number)
+			factor = u.factor
+			break
+		}
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+
+	return f * factor, nil
+}
+
+// checkUnits walks the raw YAML document decoded into a value of the type, making sure the ranges
+// of the fields with a unit tag only use the suffixes of their kind.
+func checkUnits(t reflect.Type, raw interface{}, path string) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	list, isList := raw.([]interface{})
+	switch {
+	case isList && (t.Kind() == reflect.Slice || t.Kind() == reflect.Struct):
+		// Structs may be defined as a list too, like the condition sets
+		elem := t
+		if t.Kind() == reflect.Slice {
+			elem = t.Elem()
+		}
+		for i, item := range list {
+			if err := checkUnits(elem, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case t.Kind() == reflect.Struct:
+		fields, _ := raw.(map[interface{}]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if options == "inline" {
+				if err := checkUnits(field.Type, raw, path); err != nil {
+					return err
+				}
+				continue
+			}
+
+			value, ok := fields[name]
+			if name == "" || name == "-" || !ok {
+				continue
+			}
+			key := name
+			if path != "" {
+				key = path + "." + name
+			}
+
+			if kind, ok := field.Tag.Lookup("unit"); ok {
+				if err := checkRangeUnits(value, unitKind(kind), key); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := checkUnits(field.Type, value, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkRangeUnits returns an error if the range limits have a suffix of a different kind.
+func checkRangeUnits(raw interface{}, kind unitKind, path string) error {
+	limits, _ := raw.(map[interface{}]interface{})
+	for _, limit := range []string{"min", "max"} {
+		s, ok := limits[limit].(string)
+		if !ok {
+			continue
+		}
+		if _, err := parseUnit(s, kind); err != nil {
+			return fmt.Errorf("%s.%s: %w", path, limit, err)
+		}
+	}
+	return nil
+}
+
+func convertInt[T Number](v int64) (T, error) {
+	t := T(v)
+	if int64(t) != v || (v < 0 && t > 0) {
+		return 0, fmt.Errorf("value %d is out of range", v)
+	}
+	return t, nil
+}
+
+// convert casts the float into the generic type, failing if the value doesn't fit into it.
+func convert[T Number](f float64, original string) (T, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid value %q", original)
+	}
+
+	// Round to remove the imprecision introduced by the unit factors (e.g. 0.07btc)
+	if rounded := math.Round(f); math.Abs(f-rounded) < 1e-6 {
+		f = rounded
+	}
+
+	v := T(f)
+	if float64(v) != f {
+		return 0, fmt.Errorf("value %q is out of range or not an integer", original)
+	}
+	return v, nil
+}
@@ -0,0 +1,284 @@
+package policy
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseValue(t *testing.T) {
+	cases := []struct {
+		raw      interface{}
+		desc     string
+		expected uint64
+		fail     bool
+	}{
+		{
+			desc:     "Integer",
+			raw:      5_000_000,
+			expected: 5_000_000,
+		},
+		{
+			desc:     "Numeric string",
+			raw:      "5_000_000",
+			expected: 5_000_000,
+		},
+		{
+			desc:     "Thousands",
+			raw:      "500k",
+			expected: 500_000,
+		},
+		{
+			desc:     "Millions",
+			raw:      "5m",
+			expected: 5_000_000,
+		},
+		{
+			desc:     "Bitcoin",
+			raw:      "0.05btc",
+			expected: 5_000_000,
+		},
+		{
+			desc:     "Bitcoin imprecision",
+			raw:      "0.07 BTC",
+			expected: 7_000_000,
+		},
+		{
+			desc:     "Satoshis",
+			raw:      "1000 sats",
+			expected: 1000,
+		},
+		{
+			desc:     "Parts per million",
+			raw:      "500ppm",
+			expected: 500,
+		},
+		{
+			desc:     "Minutes",
+			raw:      "5min",
+			expected: 300,
+		},
+		{
+			desc:     "Days",
+			raw:      "30d",
+			expected: 2_592_000,
+		},
+		{
+			desc:     "Weeks",
+			raw:      "1w",
+			expected: 604_800,
+		},
+		{
+			desc: "Negative",
+			raw:  -1,
+			fail: true,
+		},
+		{
+			desc: "Fraction",
+			raw:  "0.5sat",
+			fail: true,
+		},
+		{
+			desc: "Invalid",
+			raw:  "minimum",
+			fail: true,
+		},
+		{
+			desc: "Invalid type",
+			raw:  []string{},
+			fail: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			actual, err := parseValue[uint64](tc.raw, unitAny)
+			if tc.fail {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestParseValueFloat(t *testing.T) {
+	actual, err := parseValue[float64]("25%", unitAny)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.25, actual)
+
+	actual, err = parseValue[float64](0.5, unitAny)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, actual)
+}
+
+func TestParseValueOverflow(t *testing.T) {
+	_, err := parseValue[uint32]("50btc", unitAny)
+	assert.Error(t, err)
+
+	_, err = parseValue[int32](int64(1<<40), unitAny)
+	assert.Error(t, err)
+}
+
+func TestUnmarshalRange(t *testing.T) {
+	var r Range[uint64]
+	err := yaml.Unmarshal([]byte("min: 2m\nmax: 0.5btc"), &r)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2_000_000), *r.Min)
+	assert.Equal(t, uint64(50_000_000), *r.Max)
+
+	var r2 Range[uint64]
+	err = yaml.Unmarshal([]byte("max: 1m"), &r2)
+	assert.NoError(t, err)
+	assert.Nil(t, r2.Min)
+	assert.Equal(t, uint64(1_000_000), *r2.Max)
+
+	var r3 Range[uint64]
+	err = yaml.Unmarshal([]byte("min: minimum"), &r3)
+	assert.Error(t, err)
+
+	var r4 Range[uint64]
+	err = yaml.Unmarshal([]byte("min: 1m\nseverity: warn"), &r4)
+	assert.NoError(t, err)
+	assert.Equal(t, CheckWarn, r4.Severity)
+}
+
+func TestUnmarshalStatRange(t *testing.T) {
+	var sr StatRange[int64]
+	err := yaml.Unmarshal([]byte("operation: median\nmin: 1m\nmax: 500ppm"), &sr)
+	assert.NoError(t, err)
+	assert.Equal(t, Median, sr.Operation)
+	assert.Equal(t, int64(1_000_000), *sr.Min)
+	assert.Equal(t, int64(500), *sr.Max)
+	assert.Empty(t, sr.Severity)
+	assert.Empty(t, sr.MissingPolicy)
+
+	var skip StatRange[int64]
+	err = yaml.UnmarshalStrict([]byte("max: 1000\nmissing_policy: skip"), &skip)
+	assert.NoError(t, err)
+	assert.Equal(t, MissingSkip, skip.MissingPolicy)
+
+	var fee EffectiveFee
+	err = yaml.UnmarshalStrict([]byte("amount: 1m\noperation: median\nmax: 1500"), &fee)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1_000_000), fee.Amount)
+	assert.Equal(t, Median, fee.Operation)
+	assert.Equal(t, int64(1500), *fee.Max)
+
+	var sr2 StatRange[int64]
+	err = yaml.Unmarshal([]byte("max: big"), &sr2)
+	assert.Error(t, err)
+
+	var discount StatRange[int32]
+	err = yaml.Unmarshal([]byte("operation: max\nmin: -1k\nmax: -100ppm"), &discount)
+	assert.NoError(t, err)
+	assert.Equal(t, MaxOp, discount.Operation)
+	assert.Equal(t, int32(-1000), *discount.Min)
+	assert.Equal(t, int32(-100), *discount.Max)
+}
+
+func TestUnitKinds(t *testing.T) {
+	cases := []struct {
+		desc   string
+		policy string
+		fail   bool
+	}{
+		{
+			desc:   "Matching units",
+			policy: "node:\n  age:\n    min: 1k\n  first_seen_age:\n    min: 30d\n  channels:\n    capacity:\n      min: 1m\n    fee_rates:\n      max: 500ppm\n    disabled:\n      max: 25%",
+		},
+		{
+			desc:   "Duration in an amount",
+			policy: "node:\n  channels:\n    capacity:\n      min: 30d",
+			fail:   true,
+		},
+		{
+			desc:   "Amount in a ppm",
+			policy: "node:\n  channels:\n    fee_rates:\n      max: 0.05btc",
+			fail:   true,
+		},
+		{
+			desc:   "Percentage in a number",
+			policy: "node:\n  age:\n    min: 5%",
+			fail:   true,
+		},
+		{
+			desc:   "Millions in a duration",
+			policy: "escalation:\n  uptime:\n    min: 5m",
+			fail:   true,
+		},
+		{
+			desc:   "Conditions list",
+			policy: "conditions:\n  - request:\n      channel_capacity:\n        min: 1h",
+			fail:   true,
+		},
+		{
+			desc:   "Effective fee amount",
+			policy: "node:\n  channels:\n    effective_fee_ppm_at:\n      amount: 1d\n      max: 1000",
+			fail:   true,
+		},
+		{
+			desc:   "Depth bump capacity",
+			policy: "onchain:\n  depth_bumps:\n    - fee_rate: 50\n      capacity: 10%\n      depth: 6",
+			fail:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var p Policy
+			err := yaml.Unmarshal([]byte(tc.policy), &p)
+			if tc.fail {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	var p Policy
+	err := yaml.Unmarshal([]byte("node:\n  channels:\n    capacity:\n      min: 30d"), &p)
+	assert.EqualError(t, err, `node.channels.capacity.min: unit "d" can't be used in amount values`)
+}
+
+// TestUnitTags makes sure every range has a valid unit kind.
+func TestUnitTags(t *testing.T) {
+	kinds := []unitKind{unitAmount, unitDuration, unitPPM, unitRatio, unitNumber}
+	ranges := []string{"Range", "StatRange", "EffectiveFee"}
+
+	visited := make(map[reflect.Type]bool)
+	var walk func(typ reflect.Type)
+	walk = func(typ reflect.Type) {
+		for typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice {
+			typ = typ.Elem()
+		}
+		if typ.Kind() != reflect.Struct || typ.PkgPath() != reflect.TypeOf(Policy{}).PkgPath() ||
+			visited[typ] {
+			return
+		}
+		visited[typ] = true
+
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			fieldType := field.Type
+			for fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			name, _, _ := strings.Cut(fieldType.Name(), "[")
+			if !slices.Contains(ranges, name) || field.Anonymous {
+				walk(field.Type)
+				continue
+			}
+
+			kind := unitKind(field.Tag.Get("unit"))
+			assert.Contains(t, kinds, kind, "%s.%s has no valid unit kind", typ.Name(), field.Name)
+		}
+	}
+	walk(reflect.TypeOf(Policy{}))
+}
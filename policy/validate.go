@@ -0,0 +1,238 @@
+package policy
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// PublicKeyError is returned when a list contains a value that is not a valid public key.
+type PublicKeyError struct {
+	Field     string
+	PublicKey string
+}
+
+// Error returns the error message.
+func (e *PublicKeyError) Error() string {
+	return fmt.Sprintf("%s: invalid public key %q", e.Field, e.PublicKey)
+}
+
+// Validate verifies the policy values are correct.
+func (p *Policy) Validate() error {
+	if err := validatePublicKeys("allow_list", p.AllowList); err != nil {
+		return err
+	}
+
+	if err := validatePublicKeys("block_list", p.BlockList); err != nil {
+		return err
+	}
+
+	if err := validatePublicKeys("zero_conf_list", p.ZeroConfList); err != nil {
+		return err
+	}
+
+	if err := validatePublicKeys("reserved_list", p.ReservedList); err != nil {
+		return err
+	}
+
+	for i, tag := range p.Tags {
+		if strings.TrimSpace(tag) == "" || strings.Contains(tag, ",") {
+			return fmt.Errorf("tags[%d]: tags must not be empty nor contain commas", i)
+		}
+	}
+
+	if p.Tarpit != nil && (*p.Tarpit <= 0 || *p.Tarpit > MaxTarpit) {
+		return fmt.Errorf("tarpit: must be positive and at most %s", MaxTarpit)
+	}
+
+	if err := p.Onchain.validate(); err != nil {
+		return err
+	}
+
+	if err := p.Node.validate("node"); err != nil {
+		return err
+	}
+
+	if p.AllowList != nil && len(*p.AllowList) == 0 {
+		return errors.New("allow_list: must not be empty, use reject_all to reject every request")
+	}
+
+	if p.RejectAll != nil && *p.RejectAll && p.AllowList != nil {
+		return errors.New("allow_list: reject_all rejects the nodes listed too, remove one of them")
+	}
+
+	if err := p.validateRanges(); err != nil {
+		return err
+	}
+
+	return p.Conditions.validate()
+}
+
+// validateRanges verifies the ranges of the policy and of its condition sets, which can't be
+// reached through the YAML fields.
+func (p *Policy) validateRanges() error {
+	type target struct {
+		value reflect.Value
+		path  string
+	}
+	targets := []target{{reflect.ValueOf(p), ""}}
+	if p.Conditions != nil {
+		for i, c := range p.Conditions.Any {
+			targets = append(targets, target{reflect.ValueOf(c), fmt.Sprintf("conditions[%d]", i)})
+		}
+	}
+
+	validations := []func(reflect.Value, string) error{
+		validateSeverities,
+		validateMissingPolicies,
+		validateOperations,
+		validateCapacities,
+	}
+	for _, t := range targets {
+		for _, validate := range validations {
+			if err := validate(t.value, t.path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateCapacities verifies the capacity ranges in the value received have no negative limits,
+// which no channel nor node can meet.
+func validateCapacities(v reflect.Value, path string) error {
+	var err error
+	walkRanges(v, path, func(path string, b bounded) {
+		if s, ok := b.(signed); ok && err == nil && strings.HasSuffix(path, "capacity") && s.negative() {
+			err = fmt.Errorf("%s: capacities must not be negative", path)
+		}
+	})
+	return err
+}
+
+// ValidateStrict verifies the policy is named, described and does something, catching the empty
+// blocks that would silently accept every request.
+func (p *Policy) ValidateStrict() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("name: must be set")
+	}
+	if strings.TrimSpace(p.Description) == "" {
+		return errors.New("description: must be set")
+	}
+	if p.Conditions == nil && !p.hasRequirements() {
+		return errors.New("the policy has neither conditions nor requirements")
+	}
+	return nil
+}
+
+// nonRequirements are the policy fields that identify it or scope when and how it's enforced, the
+// rest are requirements.
+var nonRequirements = []string{"Name", "Description", "Tags", "Conditions", "Tarpit"}
+
+// hasRequirements returns whether the policy enforces anything on the requests it applies to, that
+// is, if any field other than the nonRequirements is set.
+func (p *Policy) hasRequirements() bool {
+	v := reflect.ValueOf(p).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if !slices.Contains(nonRequirements, v.Type().Field(i).Name) && !v.Field(i).IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Conditions) validate() error {
+	if c == nil {
+		return nil
+	}
+
+	for _, group := range c.Any {
+		if group == nil {
+			return errors.New("conditions: empty condition set")
+		}
+		if len(group.Any) > 0 {
+			return errors.New("conditions: nested lists are not supported")
+		}
+		if err := group.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.SuspiciousAmount != nil {
+		if err := c.SuspiciousAmount.validate("conditions.suspicious_amount"); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Node.validate("conditions.node"); err != nil {
+		return err
+	}
+
+	if err := validatePublicKeys("conditions.is", c.Is); err != nil {
+		return err
+	}
+
+	return validatePublicKeys("conditions.is_not", c.IsNot)
+}
+
+func (n *Node) validate(field string) error {
+	if n == nil {
+		return nil
+	}
+
+	if n.Channels != nil && n.Channels.EffectiveFeeAt != nil && n.Channels.EffectiveFeeAt.Amount == 0 {
+		return errors.New(field + ".channels.effective_fee_ppm_at.amount: must be positive")
+	}
+
+	if n.KnownAs != nil && (len(*n.KnownAs) == 0 || slices.Contains(*n.KnownAs, "")) {
+		return errors.New(field + ".known_as: categories must be set")
+	}
+
+	if n.Channels != nil {
+		if err := n.Channels.Sampling.validate(field + ".channels.sampling"); err != nil {
+			return err
+		}
+	}
+
+	return n.Connection.validate(field + ".connection")
+}
+
+// ValidatePublicKeys verifies all the values in the list are valid public keys.
+func ValidatePublicKeys(field string, publicKeys []string) error {
+	return validatePublicKeys(field, &publicKeys)
+}
+
+// IsPublicKey returns whether the string is a hex encoded 33 bytes compressed public key.
+func IsPublicKey(publicKey string) bool {
+	return isPublicKey(publicKey)
+}
+
+func validatePublicKeys(field string, list *[]string) error {
+	if list == nil {
+		return nil
+	}
+
+	for i, publicKey := range *list {
+		if !isPublicKey(publicKey) {
+			return &PublicKeyError{
+				Field:     fmt.Sprintf("%s[%d]", field, i),
+				PublicKey: publicKey,
+			}
+		}
+	}
+
+	return nil
+}
+
+// isPublicKey returns whether the string is a hex encoded 33 bytes compressed public key.
+func isPublicKey(publicKey string) bool {
+	b, err := hex.DecodeString(publicKey)
+	if err != nil || len(b) != 33 {
+		return false
+	}
+
+	return b[0] == 0x02 || b[0] == 0x03
+}
@@ -0,0 +1,369 @@
+package policy
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePolicy(t *testing.T) {
+	publicKey := "02b7f5b5d5e9b8dd8e0c5ec9fbc81ae29bcab0ed5d2ba469ee75ab6bec1a7a1d5d"
+	tarpit := 5 * time.Second
+	longTarpit := time.Minute
+	sweepRatio := 0.01
+	wrongSweepRatio := 1.5
+	tru := true
+	capacity, negativeCapacity := int64(1_000_000), int64(-1)
+
+	cases := []struct {
+		desc   string
+		policy Policy
+		fail   bool
+	}{
+		{
+			desc:   "Empty",
+			policy: Policy{},
+		},
+		{
+			desc: "Valid lists",
+			policy: Policy{
+				AllowList:    &[]string{publicKey},
+				BlockList:    &[]string{publicKey},
+				ZeroConfList: &[]string{publicKey},
+				Conditions: &Conditions{
+					Is:    &[]string{publicKey},
+					IsNot: &[]string{publicKey},
+				},
+			},
+		},
+		{
+			desc: "Suspicious amount",
+			policy: Policy{
+				Conditions: &Conditions{
+					SuspiciousAmount: &SuspiciousAmount{Patterns: []string{"*1337"}},
+				},
+			},
+		},
+		{
+			desc: "Empty suspicious amount",
+			policy: Policy{
+				Conditions: &Conditions{SuspiciousAmount: &SuspiciousAmount{}},
+			},
+			fail: true,
+		},
+		{
+			desc: "Suspicious amount multiple of zero",
+			policy: Policy{
+				Conditions: &Conditions{SuspiciousAmount: &SuspiciousAmount{MultipleOf: new(uint64)}},
+			},
+			fail: true,
+		},
+		{
+			desc: "Suspicious amount invalid pattern",
+			policy: Policy{
+				Conditions: &Conditions{SuspiciousAmount: &SuspiciousAmount{Patterns: []string{"[1-"}}},
+			},
+			fail: true,
+		},
+		{
+			desc: "Connection networks",
+			policy: Policy{
+				Node: &Node{Connection: &Connection{
+					Networks:         &[]string{"203.0.113.0/24", "2001:db8::/32"},
+					ExcludedNetworks: &[]string{"203.0.113.7/32"},
+				}},
+			},
+		},
+		{
+			desc: "Invalid connection network",
+			policy: Policy{
+				Conditions: &Conditions{
+					Node: &Node{Connection: &Connection{Networks: &[]string{"203.0.113.0"}}},
+				},
+			},
+			fail: true,
+		},
+		{
+			desc: "Allow list",
+			policy: Policy{
+				AllowList: &[]string{publicKey, "02b7f5"},
+			},
+			fail: true,
+		},
+		{
+			desc: "Block list",
+			policy: Policy{
+				BlockList: &[]string{publicKey + "0"},
+			},
+			fail: true,
+		},
+		{
+			desc: "Zero conf list",
+			policy: Policy{
+				ZeroConfList: &[]string{"peer_public_key"},
+			},
+			fail: true,
+		},
+		{
+			desc: "Reserved list",
+			policy: Policy{
+				ReservedList: &[]string{"reserved"},
+			},
+			fail: true,
+		},
+		{
+			desc: "Conditions is",
+			policy: Policy{
+				Conditions: &Conditions{
+					Is: &[]string{"04" + publicKey[2:]},
+				},
+			},
+			fail: true,
+		},
+		{
+			desc: "Effective fee without amount",
+			policy: Policy{
+				Node: &Node{Channels: &Channels{EffectiveFeeAt: &EffectiveFee{}}},
+			},
+			fail: true,
+		},
+		{
+			desc: "Sampling",
+			policy: Policy{
+				Node: &Node{Channels: &Channels{Sampling: &Sampling{MaxChannels: 500, Strategy: SampleLargest}}},
+			},
+		},
+		{
+			desc: "Sampling without maximum",
+			policy: Policy{
+				Node: &Node{Channels: &Channels{Sampling: &Sampling{}}},
+			},
+			fail: true,
+		},
+		{
+			desc: "Sampling unknown strategy",
+			policy: Policy{
+				Conditions: &Conditions{
+					Node: &Node{Channels: &Channels{Sampling: &Sampling{MaxChannels: 10, Strategy: "smallest"}}},
+				},
+			},
+			fail: true,
+		},
+		{
+			desc:   "Known as",
+			policy: Policy{Node: &Node{KnownAs: &[]string{"lsp", "exchange"}}},
+		},
+		{
+			desc:   "Known as nothing",
+			policy: Policy{Node: &Node{KnownAs: &[]string{}}},
+			fail:   true,
+		},
+		{
+			desc:   "Tags",
+			policy: Policy{Tags: []string{"lsp", "strict"}},
+		},
+		{
+			desc:   "Empty tag",
+			policy: Policy{Tags: []string{"lsp", " "}},
+			fail:   true,
+		},
+		{
+			desc:   "Tarpit",
+			policy: Policy{Tarpit: &tarpit},
+		},
+		{
+			desc:   "Long tarpit",
+			policy: Policy{Tarpit: &longTarpit},
+			fail:   true,
+		},
+		{
+			desc:   "Sweep cost ratio",
+			policy: Policy{Onchain: &Onchain{MaxSweepCostRatio: &sweepRatio}},
+		},
+		{
+			desc:   "Sweep cost ratio above one",
+			policy: Policy{Onchain: &Onchain{MaxSweepCostRatio: &wrongSweepRatio}},
+			fail:   true,
+		},
+		{
+			desc:   "Conditions list",
+			policy: Policy{Conditions: &Conditions{Any: []*Conditions{{Is: &[]string{"key"}}}}},
+			fail:   true,
+		},
+		{
+			desc:   "Nested conditions list",
+			policy: Policy{Conditions: &Conditions{Any: []*Conditions{{Any: []*Conditions{{}}}}}},
+			fail:   true,
+		},
+		{
+			desc: "Conditions is not",
+			policy: Policy{
+				Conditions: &Conditions{
+					IsNot: &[]string{""},
+				},
+			},
+			fail: true,
+		},
+		{
+			desc:   "Empty allow list",
+			policy: Policy{AllowList: &[]string{}},
+			fail:   true,
+		},
+		{
+			desc:   "Allow list with reject all",
+			policy: Policy{AllowList: &[]string{publicKey}, RejectAll: &tru},
+			fail:   true,
+		},
+		{
+			desc:   "Block list with reject all",
+			policy: Policy{BlockList: &[]string{publicKey}, RejectAll: &tru},
+		},
+		{
+			desc: "Operation",
+			policy: Policy{
+				Node: &Node{Channels: &Channels{Capacity: &StatRange[int64]{Min: &capacity, Operation: Median}}},
+			},
+		},
+		{
+			desc: "Invalid operation",
+			policy: Policy{
+				Node: &Node{Channels: &Channels{Capacity: &StatRange[int64]{Min: &capacity, Operation: "average"}}},
+			},
+			fail: true,
+		},
+		{
+			desc: "Negative node capacity",
+			policy: Policy{
+				Node: &Node{Capacity: &Range[int64]{Min: &negativeCapacity}},
+			},
+			fail: true,
+		},
+		{
+			desc: "Negative channels capacity",
+			policy: Policy{
+				Node: &Node{Channels: &Channels{Capacity: &StatRange[int64]{Max: &negativeCapacity}}},
+			},
+			fail: true,
+		},
+		{
+			desc: "Conditions list range",
+			policy: Policy{
+				Conditions: &Conditions{Any: []*Conditions{
+					{Node: &Node{Capacity: &Range[int64]{Min: &capacity}}},
+					{Node: &Node{Capacity: &Range[int64]{Min: &capacity, Severity: "ignore"}}},
+				}},
+			},
+			fail: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.policy.Validate()
+			if tc.fail {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateStrict(t *testing.T) {
+	tru := true
+
+	cases := []struct {
+		desc   string
+		policy Policy
+		fail   bool
+	}{
+		{
+			desc:   "Valid",
+			policy: Policy{Name: "private", Description: "No private channels", RejectPrivateChannels: &tru},
+		},
+		{
+			desc: "Conditions only",
+			policy: Policy{
+				Name:        "tag",
+				Description: "Tag private channels",
+				Conditions:  &Conditions{IsPrivate: &tru},
+				Tags:        []string{"private"},
+			},
+		},
+		{
+			desc:   "Unnamed",
+			policy: Policy{Description: "No private channels", RejectPrivateChannels: &tru},
+			fail:   true,
+		},
+		{
+			desc:   "No description",
+			policy: Policy{Name: "private", RejectPrivateChannels: &tru},
+			fail:   true,
+		},
+		{
+			desc:   "Empty",
+			policy: Policy{Name: "empty", Description: "Does nothing", Tags: []string{"empty"}},
+			fail:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.policy.ValidateStrict()
+			if tc.fail {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestHasRequirements(t *testing.T) {
+	policyType := reflect.TypeOf(Policy{})
+	for _, name := range nonRequirements {
+		_, ok := policyType.FieldByName(name)
+		assert.True(t, ok, "%s is not a policy field", name)
+	}
+
+	// Every policy field must count as a requirement unless it's listed in nonRequirements
+	for i := 0; i < policyType.NumField(); i++ {
+		field := policyType.Field(i)
+		var p Policy
+		v := reflect.ValueOf(&p).Elem().Field(i)
+		switch v.Kind() {
+		case reflect.Pointer:
+			v.Set(reflect.New(field.Type.Elem()))
+		case reflect.Slice:
+			v.Set(reflect.MakeSlice(field.Type, 1, 1))
+		case reflect.String:
+			v.SetString("set")
+		default:
+			t.Fatalf("unexpected kind of the %s field: %s", field.Name, v.Kind())
+		}
+
+		expected := !slices.Contains(nonRequirements, field.Name)
+		assert.Equal(t, expected, p.hasRequirements(), field.Name)
+	}
+}
+
+func TestPublicKeyError(t *testing.T) {
+	policy := Policy{AllowList: &[]string{"abc"}}
+
+	err := policy.Validate()
+	assert.Equal(t, &PublicKeyError{Field: "allow_list[0]", PublicKey: "abc"}, err)
+	assert.Equal(t, `allow_list[0]: invalid public key "abc"`, err.Error())
+}
+
+func TestValidatePublicKeys(t *testing.T) {
+	publicKey := "03b7f5b5d5e9b8dd8e0c5ec9fbc81ae29bcab0ed5d2ba469ee75ab6bec1a7a1d5d"
+
+	assert.NoError(t, ValidatePublicKeys("list", nil))
+	assert.NoError(t, ValidatePublicKeys("list", []string{publicKey}))
+
+	err := ValidatePublicKeys("list", []string{publicKey, "invalid"})
+	assert.Equal(t, &PublicKeyError{Field: "list[1]", PublicKey: "invalid"}, err)
+}
@@ -0,0 +1,21 @@
+package policy
+
+// Versions of the policies semantics, selected with the configuration version.
+const (
+	// Version1 is the original semantics: if the node is in the `is` list, the conditions match
+	// regardless of the rest of them.
+	Version1 = 1
+	// Version2 evaluates `is` like any other condition, all of them must match.
+	Version2 = 2
+	// LatestVersion is the most recent version available.
+	LatestVersion = Version2
+)
+
+// SetVersion configures the policies to follow the semantics of the version.
+func SetVersion(policies []*Policy, version int) {
+	for _, p := range policies {
+		for _, c := range p.Conditions.groups() {
+			c.legacy = version < Version2
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/metrics"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Defaults of the precomputation job.
+const (
+	defaultPrecomputePeers    = 100
+	defaultPrecomputeInterval = 10 * time.Minute
+)
+
+// Windows used to pick the nodes likely to request channels.
+const (
+	// rejectersWindow is how far back the rejected requests are looked at.
+	rejectersWindow = 7 * 24 * time.Hour
+	// openersWindow is how many blocks back the channels opened in the network are counted,
+	// about a week.
+	openersWindow = 1008
+)
+
+// graphRetryInterval is the time waited before subscribing to the graph updates again after the
+// subscription fails.
+const graphRetryInterval = time.Minute
+
+// peerCache holds the information of the nodes likely to request channels, loaded in the
+// background so their requests don't wait for LND's GetNodeInfo, which is slow for large nodes.
+type peerCache struct {
+	maxAge time.Duration
+
+	mu    sync.RWMutex
+	peers map[string]cachedPeer
+}
+
+type cachedPeer struct {
+	info     *lnrpc.NodeInfo
+	loadedAt time.Time
+}
+
+func newPeerCache(precompute config.Precompute) *peerCache {
+	maxAge := precompute.Interval
+	if maxAge == 0 {
+		maxAge = defaultPrecomputeInterval
+	}
+	return &peerCache{maxAge: maxAge, peers: make(map[string]cachedPeer)}
+}
+
+// get returns the information of the node if it was loaded within the maximum age. The cache may
+// be nil, in which case nothing is found.
+func (c *peerCache) get(publicKey string, now time.Time) (*lnrpc.NodeInfo, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	peer, ok := c.peers[publicKey]
+	if !ok || now.Sub(peer.loadedAt) > c.maxAge {
+		return nil, false
+	}
+	return peer.info, true
+}
+
+func (c *peerCache) replace(peers map[string]cachedPeer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.peers = peers
+}
+
+// invalidate removes the nodes whose announcement or channels changed in the update and returns
+// their public keys. Closed channels are matched against the channels of the nodes cached, as their
+// updates don't include the nodes.
+func (c *peerCache) invalidate(update *lnrpc.GraphTopologyUpdate) []string {
+	changed := make(map[string]struct{})
+	for _, node := range update.NodeUpdates {
+		changed[node.IdentityKey] = struct{}{}
+	}
+	for _, channel := range update.ChannelUpdates {
+		changed[channel.AdvertisingNode] = struct{}{}
+		changed[channel.ConnectingNode] = struct{}{}
+	}
+	closed := make(map[uint64]struct{}, len(update.ClosedChans))
+	for _, channel := range update.ClosedChans {
+		closed[channel.ChanId] = struct{}{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var invalidated []string
+	for publicKey, peer := range c.peers {
+		_, ok := changed[publicKey]
+		if !ok && len(closed) > 0 {
+			ok = slices.ContainsFunc(peer.info.Channels, func(edge *lnrpc.ChannelEdge) bool {
+				_, ok := closed[edge.ChannelId]
+				return ok
+			})
+		}
+		if ok {
+			delete(c.peers, publicKey)
+			invalidated = append(invalidated, publicKey)
+		}
+	}
+	return invalidated
+}
+
+// precomputePeers loads the information of the nodes likely to request channels every interval
+// until the context is cancelled.
+func (a *acceptor) precomputePeers(ctx context.Context, precompute config.Precompute) {
+	peers := precompute.Peers
+	if peers == 0 {
+		peers = defaultPrecomputePeers
+	}
+	ticker := time.NewTicker(a.precomputed.maxAge)
+	defer ticker.Stop()
+
+	for {
+		if err := a.loadLikelyPeers(ctx, peers); err != nil {
+			slog.Warn("Precomputing peers", slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// loadLikelyPeers replaces the cached peers with the information of the nodes likely to request
+// channels.
+func (a *acceptor) loadLikelyPeers(ctx context.Context, n int) error {
+	publicKeys, err := a.likelyPeers(ctx, n, time.Now())
+	if err != nil {
+		return err
+	}
+
+	peers := make(map[string]cachedPeer, len(publicKeys))
+	for _, publicKey := range publicKeys {
+		req := &lnrpc.NodeInfoRequest{PubKey: publicKey, IncludeChannels: true}
+		info, err := a.client.GetNodeInfo(ctx, req)
+		if status.Code(err) == codes.NotFound {
+			continue
+		}
+		if err != nil {
+			return errors.Wrap(err, "getting peer information")
+		}
+		peers[publicKey] = cachedPeer{info: info, loadedAt: time.Now()}
+	}
+
+	a.precomputed.replace(peers)
+	slog.Debug("Peers precomputed", slog.Int("peers", len(peers)))
+	return nil
+}
+
+// invalidatePeers discards the precomputed information of the nodes as soon as the graph updates
+// show it changed, until the context is cancelled. The subscription is retried if it fails, in the
+// meantime the information expires after the interval.
+func (a *acceptor) invalidatePeers(ctx context.Context) {
+	for {
+		err := a.followGraph(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("Following graph updates", slog.Any("error", err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(graphRetryInterval):
+		}
+	}
+}
+
+func (a *acceptor) followGraph(ctx context.Context) error {
+	stream, err := a.client.SubscribeChannelGraph(ctx, &lnrpc.GraphTopologySubscription{})
+	if err != nil {
+		return errors.Wrap(err, "subscribing to graph updates")
+	}
+
+	for {
+		update, err := stream.Recv()
+		if err != nil {
+			return errors.Wrap(err, "receiving graph update")
+		}
+
+		for _, publicKey := range a.precomputed.invalidate(update) {
+			metrics.CountPrecomputedInvalidation()
+			slog.Debug("Precomputed peer invalidated", slog.String("public_key", publicKey))
+		}
+	}
+}
+
+// likelyPeers returns up to n nodes likely to request channels: the ones whose requests were
+// rejected recently, newest first, followed by the ones with the most channels opened in the
+// network within the last week.
+func (a *acceptor) likelyPeers(ctx context.Context, n int, now time.Time) ([]string, error) {
+	node, err := a.client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "getting node information")
+	}
+
+	seen := map[string]struct{}{node.IdentityPubkey: {}}
+	publicKeys := make([]string, 0, n)
+	add := func(publicKey string) {
+		if _, ok := seen[publicKey]; ok || len(publicKeys) >= n || a.isSelfService(publicKey) {
+			return
+		}
+		seen[publicKey] = struct{}{}
+		publicKeys = append(publicKeys, publicKey)
+	}
+
+	if a.db != nil {
+		decisions, err := a.db.Decisions(now.Add(-rejectersWindow))
+		if err != nil {
+			return nil, err
+		}
+		for i := len(decisions) - 1; i >= 0; i-- {
+			if !decisions[i].Accepted {
+				add(decisions[i].PublicKey)
+			}
+		}
+	}
+	if len(publicKeys) >= n {
+		return publicKeys, nil
+	}
+
+	channelGraph, err := a.client.DescribeGraph(ctx, &lnrpc.ChannelGraphRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "describing graph")
+	}
+	for _, publicKey := range activeOpeners(channelGraph, node.BlockHeight) {
+		add(publicKey)
+	}
+	return publicKeys, nil
+}
+
+// activeOpeners returns the nodes with channels opened within the openers window, sorted by the
+// number of them. The graph doesn't tell which side opened a channel, so both are counted.
+func activeOpeners(channelGraph *lnrpc.ChannelGraph, blockHeight uint32) []string {
+	counts := make(map[string]int)
+	for _, edge := range channelGraph.Edges {
+		height := uint32(edge.ChannelId >> 40)
+		if height > blockHeight || blockHeight-height > openersWindow {
+			continue
+		}
+		counts[edge.Node1Pub]++
+		counts[edge.Node2Pub]++
+	}
+
+	openers := make([]string, 0, len(counts))
+	for publicKey := range counts {
+		openers = append(openers, publicKey)
+	}
+	slices.SortFunc(openers, func(a, b string) int {
+		if c := cmp.Compare(counts[b], counts[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+	return openers
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/graph"
+	"github.com/aftermath2/acceptlnd/lightning/fake"
+	"github.com/aftermath2/acceptlnd/store"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerCache(t *testing.T) {
+	now := time.Now()
+	cache := newPeerCache(config.Precompute{Interval: time.Minute})
+	info := &lnrpc.NodeInfo{Channels: []*lnrpc.ChannelEdge{{ChannelId: 1}}}
+	cache.replace(map[string]cachedPeer{
+		"a": {info: info, loadedAt: now},
+		"b": {info: &lnrpc.NodeInfo{}, loadedAt: now.Add(-2 * time.Minute)},
+		"c": {info: &lnrpc.NodeInfo{}, loadedAt: now},
+		"d": {info: &lnrpc.NodeInfo{}, loadedAt: now},
+	})
+
+	got, ok := cache.get("a", now)
+	assert.True(t, ok)
+	assert.Equal(t, info, got)
+
+	// Expired
+	_, ok = cache.get("b", now)
+	assert.False(t, ok)
+
+	_, ok = cache.get("unknown", now)
+	assert.False(t, ok)
+
+	var disabled *peerCache
+	_, ok = disabled.get("a", now)
+	assert.False(t, ok)
+
+	invalidated := cache.invalidate(&lnrpc.GraphTopologyUpdate{
+		NodeUpdates:    []*lnrpc.NodeUpdate{{IdentityKey: "c"}},
+		ChannelUpdates: []*lnrpc.ChannelEdgeUpdate{{AdvertisingNode: "x", ConnectingNode: "d"}},
+	})
+	assert.ElementsMatch(t, []string{"c", "d"}, invalidated)
+
+	// Closed channels invalidate the nodes cached that had them
+	invalidated = cache.invalidate(&lnrpc.GraphTopologyUpdate{
+		ClosedChans: []*lnrpc.ClosedChannelUpdate{{ChanId: 1}},
+	})
+	assert.Equal(t, []string{"a"}, invalidated)
+
+	_, ok = cache.get("a", now)
+	assert.False(t, ok)
+}
+
+func TestLikelyPeers(t *testing.T) {
+	ctx := context.Background()
+	const tip = 800_000
+	snapshot := graph.New(&lnrpc.ChannelGraph{
+		Nodes: []*lnrpc.LightningNode{{PubKey: "us"}, {PubKey: "a"}, {PubKey: "b"}, {PubKey: "c"}},
+		Edges: []*lnrpc.ChannelEdge{
+			{Node1Pub: "us", Node2Pub: "a", ChannelId: tip << 40},
+			{Node1Pub: "a", Node2Pub: "b", ChannelId: (tip - 10) << 40},
+			{Node1Pub: "self", Node2Pub: "a", ChannelId: (tip - 5) << 40},
+			// Opened before the window
+			{Node1Pub: "b", Node2Pub: "c", ChannelId: (tip - openersWindow - 1) << 40},
+		},
+	})
+
+	db := store.NewMemory()
+	now := time.Now()
+	decisions := []store.Decision{
+		{ID: "1", PublicKey: "old", At: now.Add(-rejectersWindow - time.Hour)},
+		{ID: "2", PublicKey: "c", At: now.Add(-2 * time.Hour)},
+		{ID: "3", PublicKey: "a", Accepted: true, At: now.Add(-time.Hour)},
+		{ID: "4", PublicKey: "d", At: now.Add(-time.Minute)},
+	}
+	for _, decision := range decisions {
+		assert.NoError(t, db.AddDecision(decision))
+	}
+
+	cfg := config.Config{SelfServices: []string{"self"}, Precompute: &config.Precompute{}}
+	a := newAcceptor(fake.New(snapshot, "us"), db, cfg)
+
+	// The nodes rejected come first, newest first, followed by the most active openers
+	peers, err := a.likelyPeers(ctx, 10, now)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"d", "c", "a", "b"}, peers)
+
+	peers, err = a.likelyPeers(ctx, 1, now)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"d"}, peers)
+
+	// Nodes missing from the graph are skipped
+	assert.NoError(t, a.loadLikelyPeers(ctx, 10))
+	for _, publicKey := range []string{"a", "b", "c"} {
+		info, ok := a.precomputed.get(publicKey, time.Now())
+		assert.True(t, ok, publicKey)
+		assert.Equal(t, publicKey, info.Node.PubKey)
+	}
+	_, ok := a.precomputed.get("d", time.Now())
+	assert.False(t, ok)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	colorReset = "\033[0m"
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorGray  = "\033[90m"
+)
+
+// prettyHandler is a slog handler that renders channel request decisions as compact colored lines,
+// meant for operators watching acceptlnd in an interactive terminal.
+type prettyHandler struct {
+	w     io.Writer
+	mu    *sync.Mutex
+	level slog.Leveler
+	// attrs are flattened, their keys qualified with the groups they were added in.
+	attrs []slog.Attr
+	// prefix is the qualifier of the keys of the groups opened, like "group.".
+	prefix string
+	colors bool
+}
+
+func newPrettyHandler(w io.Writer, level slog.Leveler) *prettyHandler {
+	return &prettyHandler{
+		w:      w,
+		mu:     &sync.Mutex{},
+		level:  level,
+		colors: useColors(w),
+	}
+}
+
+// useColors reports whether the output is a terminal and the NO_COLOR (https://no-color.org)
+// environment variable is not set.
+func useColors(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Enabled reports whether the handler handles records at the given level.
+func (h *prettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle formats the record and writes it.
+func (h *prettyHandler) Handle(_ context.Context, r slog.Record) error {
+	flattened := h.attrs[:len(h.attrs):len(h.attrs)]
+	r.Attrs(func(a slog.Attr) bool {
+		flattened = appendAttr(flattened, h.prefix, a)
+		return true
+	})
+
+	attrs := make(map[string]slog.Value, len(flattened))
+	keys := make([]string, 0, len(flattened))
+	for _, a := range flattened {
+		if _, ok := attrs[a.Key]; !ok {
+			keys = append(keys, a.Key)
+		}
+		attrs[a.Key] = a.Value
+	}
+
+	var sb strings.Builder
+	sb.WriteString(h.paint(colorGray, r.Time.Format(time.TimeOnly)))
+	sb.WriteByte(' ')
+
+	if r.Message == decisionMessage {
+		h.writeDecision(&sb, attrs)
+	} else {
+		h.writeRecord(&sb, r, keys, attrs)
+	}
+	sb.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, sb.String())
+	return err
+}
+
+// WithAttrs returns a new handler including the attributes received.
+func (h *prettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = h.attrs[:len(h.attrs):len(h.attrs)]
+	for _, a := range attrs {
+		h2.attrs = appendAttr(h2.attrs, h.prefix, a)
+	}
+	return &h2
+}
+
+// WithGroup returns a new handler qualifying the keys of the attributes added later with the group
+// name, like "group.key".
+func (h *prettyHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// appendAttr appends the attribute with its key qualified by the prefix, expanding the groups into
+// their attributes. The attributes of inline groups, without a key, aren't qualified by them.
+func appendAttr(attrs []slog.Attr, prefix string, a slog.Attr) []slog.Attr {
+	value := a.Value.Resolve()
+	if value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, attr := range value.Group() {
+			attrs = appendAttr(attrs, prefix, attr)
+		}
+		return attrs
+	}
+	if a.Key == "" && value.Any() == nil {
+		return attrs
+	}
+	return append(attrs, slog.Attr{Key: prefix + a.Key, Value: value})
+}
+
+// paint wraps the text in the color escape sequences if the colors are enabled.
+func (h *prettyHandler) paint(color, text string) string {
+	if !h.colors {
+		return text
+	}
+	return color + text + colorReset
+}
+
+func (h *prettyHandler) writeDecision(sb *strings.Builder, attrs map[string]slog.Value) {
+	if accepted, _ := attrs["accepted"].Any().(bool); accepted {
+		sb.WriteString(h.paint(colorGreen, "✓"))
+	} else {
+		sb.WriteString(h.paint(colorRed, "✗"))
+	}
+
+	alias, _ := attrs["alias"].Any().(string)
+	publicKey, _ := attrs["public_key"].Any().(string)
+	if len(publicKey) > 16 {
+		publicKey = publicKey[:16]
+	}
+	if alias == "" {
+		alias = publicKey
+	}
+	capacity, _ := attrs["capacity"].Any().(uint64)
+	fmt.Fprintf(sb, " %-32s %s", alias, h.paint(colorGray, fmt.Sprintf("%s %14d sats", publicKey, capacity)))
+
+	if reason, _ := attrs["error"].Any().(string); reason != "" {
+		sb.WriteString(" " + h.paint(colorRed, reason))
+		if policy, _ := attrs["rejected_by"].Any().(string); policy != "" {
+			sb.WriteString(h.paint(colorGray, " ("+policy+")"))
+		}
+	}
+}
+
+func (h *prettyHandler) writeRecord(
+	sb *strings.Builder,
+	r slog.Record,
+	keys []string,
+	attrs map[string]slog.Value,
+) {
+	level := r.Level.String()
+	if r.Level >= slog.LevelError {
+		level = h.paint(colorRed, level)
+	}
+	sb.WriteString(level)
+	sb.WriteByte(' ')
+	sb.WriteString(r.Message)
+
+	for _, key := range keys {
+		fmt.Fprintf(sb, " %s%v", h.paint(colorGray, key+"="), attrs[key])
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrettyHandler(t *testing.T) {
+	at := time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC)
+
+	cases := []struct {
+		desc     string
+		log      func(logger *slog.Logger)
+		colors   bool
+		expected string
+	}{
+		{
+			desc: "Attributes",
+			log: func(logger *slog.Logger) {
+				logger.Info("Message", slog.String("key", "value"), slog.Int("number", 1))
+			},
+			expected: "12:30:00 INFO Message key=value number=1",
+		},
+		{
+			desc: "Handler attributes",
+			log: func(logger *slog.Logger) {
+				logger.With(slog.String("a", "1")).Warn("Message", slog.String("a", "2"), slog.String("b", "3"))
+			},
+			expected: "12:30:00 WARN Message a=2 b=3",
+		},
+		{
+			desc: "Group attribute",
+			log: func(logger *slog.Logger) {
+				logger.Info("Message", slog.Group("peer", slog.String("alias", "node"), slog.Int("channels", 2)))
+			},
+			expected: "12:30:00 INFO Message peer.alias=node peer.channels=2",
+		},
+		{
+			desc: "Inline group",
+			log: func(logger *slog.Logger) {
+				logger.Error("Message", slog.Group("", slog.String("code", "Unavailable")), slog.Attr{})
+			},
+			expected: "12:30:00 ERROR Message code=Unavailable",
+		},
+		{
+			desc: "Handler groups",
+			log: func(logger *slog.Logger) {
+				logger.With(slog.String("top", "1")).WithGroup("lnd").With(slog.String("host", "localhost")).
+					WithGroup("rpc").Info("Message", slog.String("method", "GetInfo"))
+			},
+			expected: "12:30:00 INFO Message top=1 lnd.host=localhost lnd.rpc.method=GetInfo",
+		},
+		{
+			desc: "Decision",
+			log: func(logger *slog.Logger) {
+				logger.Info(decisionMessage, slog.Bool("accepted", false), slog.String("alias", "node"),
+					slog.String("public_key", "02aaaaaaaaaaaaaaaaaaaa"), slog.Uint64("capacity", 1_000_000),
+					slog.String("error", "Channel capacity"), slog.String("rejected_by", "#0"))
+			},
+			expected: "12:30:00 ✗ node                             02aaaaaaaaaaaaaa        1000000 sats" +
+				" Channel capacity (#0)",
+		},
+		{
+			desc: "Colors",
+			log: func(logger *slog.Logger) {
+				logger.Error("Message", slog.String("key", "value"))
+			},
+			colors: true,
+			expected: colorGray + "12:30:00" + colorReset + " " + colorRed + "ERROR" + colorReset +
+				" Message " + colorGray + "key=" + colorReset + "value",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler := newPrettyHandler(&buf, slog.LevelInfo)
+			handler.colors = tc.colors
+			tc.log(slog.New(timeHandler{handler, at}))
+			assert.Equal(t, tc.expected+"\n", buf.String())
+		})
+	}
+}
+
+func TestUseColors(t *testing.T) {
+	var buf bytes.Buffer
+	assert.False(t, useColors(&buf))
+
+	f, err := os.CreateTemp(t.TempDir(), "log")
+	assert.NoError(t, err)
+	defer f.Close()
+	assert.False(t, useColors(f))
+
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		t.Skip("No terminal available")
+	}
+	defer tty.Close()
+	assert.True(t, useColors(tty))
+
+	t.Setenv("NO_COLOR", "1")
+	assert.False(t, useColors(tty))
+}
+
+// timeHandler sets a fixed time to the records, so the output can be compared.
+type timeHandler struct {
+	slog.Handler
+	at time.Time
+}
+
+func (h timeHandler) Handle(ctx context.Context, r slog.Record) error {
+	r.Time = h.at
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h timeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return timeHandler{h.Handler.WithAttrs(attrs), h.at}
+}
+
+func (h timeHandler) WithGroup(name string) slog.Handler {
+	return timeHandler{h.Handler.WithGroup(name), h.at}
+}
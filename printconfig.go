@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/aftermath2/acceptlnd/config"
+)
+
+// runPrintConfig prints the configuration reference, generated from the configuration schema.
+func runPrintConfig(args []string) error {
+	fs := flag.NewFlagSet("print-config", flag.ExitOnError)
+	full := fs.Bool("full", false, "Include the nested sections and every policy requirement")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	return config.WriteReference(os.Stdout, *full)
+}
@@ -0,0 +1,79 @@
+// Package proxy routes acceptLND's outbound connections through a SOCKS5 proxy, like the one Tor
+// exposes.
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	xproxy "golang.org/x/net/proxy"
+)
+
+// Validate verifies the proxy URL is valid.
+func Validate(address string) error {
+	_, err := parse(address)
+	return err
+}
+
+// Dialer returns a dialer that connects through the proxy located at the address received, or
+// directly if it's empty. Host names are resolved by the proxy, so onion addresses can be used.
+func Dialer(address string) (xproxy.ContextDialer, error) {
+	if address == "" {
+		return &net.Dialer{}, nil
+	}
+
+	u, err := parse(address)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer, err := xproxy.FromURL(u, &net.Dialer{})
+	if err != nil {
+		return nil, errors.Wrap(err, "creating proxy dialer")
+	}
+
+	contextDialer, ok := dialer.(xproxy.ContextDialer)
+	if !ok {
+		return nil, errors.New("the proxy dialer doesn't support contexts")
+	}
+
+	return contextDialer, nil
+}
+
+// HTTPClient returns an HTTP client whose connections go through the proxy, or directly if the
+// address is empty.
+func HTTPClient(address string, timeout time.Duration) (*http.Client, error) {
+	dialer, err := Dialer(address)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+func parse(address string) (*url.URL, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid proxy address")
+	}
+
+	if u.Scheme != "socks5" && u.Scheme != "socks5h" {
+		return nil, errors.Errorf("unsupported proxy scheme %q, expected socks5", u.Scheme)
+	}
+
+	if _, _, err := net.SplitHostPort(u.Host); err != nil {
+		return nil, errors.Wrap(err, "invalid proxy address")
+	}
+
+	return u, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/rand/v2"
+	"sync"
+)
+
+// randomness makes the random choices of the evaluations, like the requests sampled by
+// experiments and the tarpit delays.
+type randomness struct {
+	// rnd is nil if no seed was configured, in which case the global generator is used.
+	rnd *rand.Rand
+	mu  sync.Mutex
+	// deterministic derives the choices from the seed and the request IDs, so they don't depend on
+	// the order the requests are received in.
+	deterministic bool
+	seed          uint64
+}
+
+func newRandomness(seed int64, deterministic bool) *randomness {
+	r := &randomness{deterministic: deterministic, seed: uint64(seed)}
+	if seed != 0 && !deterministic {
+		r.rnd = rand.New(rand.NewPCG(uint64(seed), 0))
+	}
+	return r
+}
+
+// float64 returns a number in [0, 1) for the choice made about the request with the ID received.
+func (r *randomness) float64(id []byte, choice string) float64 {
+	if r.deterministic {
+		h := sha256.New()
+		_ = binary.Write(h, binary.BigEndian, r.seed)
+		h.Write(id)
+		h.Write([]byte(choice))
+		// The 53 most significant bits fill the float64 mantissa
+		return float64(binary.BigEndian.Uint64(h.Sum(nil))>>11) / (1 << 53)
+	}
+	if r.rnd == nil {
+		return rand.Float64()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rnd.Float64()
+}
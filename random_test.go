@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRandomness(t *testing.T) {
+	cases := []struct {
+		desc          string
+		seed          int64
+		otherSeed     int64
+		deterministic bool
+		same          bool
+	}{
+		{desc: "Same seed", seed: 42, otherSeed: 42, deterministic: true, same: true},
+		{desc: "Different seed", seed: 42, otherSeed: 43, deterministic: true},
+		{desc: "Same seed in order", seed: 42, otherSeed: 42, same: true},
+		{desc: "Different seed in order", seed: 42, otherSeed: 43},
+	}
+
+	// choices returns the experiment assignment and the tarpit delay of every request
+	choices := func(r *randomness) ([]bool, []time.Duration) {
+		sampled := make([]bool, 64)
+		delays := make([]time.Duration, 64)
+		for i := range sampled {
+			id := binary.BigEndian.AppendUint64(nil, uint64(i))
+			sampled[i] = r.float64(id, "experiment") < 0.5
+			delays[i] = tarpitDelay(10*time.Second, r.float64(id, "tarpit"))
+		}
+		return sampled, delays
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			sampled, delays := choices(newRandomness(tc.seed, tc.deterministic))
+			otherSampled, otherDelays := choices(newRandomness(tc.otherSeed, tc.deterministic))
+
+			if tc.same {
+				assert.Equal(t, sampled, otherSampled)
+				assert.Equal(t, delays, otherDelays)
+			} else {
+				assert.NotEqual(t, sampled, otherSampled)
+				assert.NotEqual(t, delays, otherDelays)
+			}
+
+			for _, delay := range delays {
+				assert.GreaterOrEqual(t, delay, 5*time.Second)
+				assert.Less(t, delay, 10*time.Second)
+			}
+		})
+	}
+}
+
+func TestRandomnessRequestOrder(t *testing.T) {
+	first, second := []byte("first"), []byte("second")
+
+	r := newRandomness(42, true)
+	a, b := r.float64(first, "experiment"), r.float64(second, "experiment")
+
+	// The choices made in deterministic mode don't depend on the order of the requests
+	r = newRandomness(42, true)
+	assert.Equal(t, b, r.float64(second, "experiment"))
+	assert.Equal(t, a, r.float64(first, "experiment"))
+	// Nor are they shared between choices
+	assert.NotEqual(t, a, r.float64(first, "tarpit"))
+}
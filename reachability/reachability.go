@@ -0,0 +1,122 @@
+// Package reachability tests whether nodes accept connections on the addresses they announce.
+package reachability
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+
+	"golang.org/x/net/proxy"
+)
+
+// Defaults used when the values are not configured.
+const (
+	DefaultTimeout       = 5 * time.Second
+	DefaultCacheDuration = time.Hour
+)
+
+type result struct {
+	reachable bool
+	checkedAt time.Time
+}
+
+// Checker dials the addresses announced by the nodes and caches the results, so nodes requesting
+// several channels are not tested every time.
+type Checker struct {
+	dialer        proxy.ContextDialer
+	timeout       time.Duration
+	cacheDuration time.Duration
+	now           func() time.Time
+
+	mu        sync.Mutex
+	results   map[string]result
+	lastSweep time.Time
+}
+
+// New returns a reachability checker whose connections are made with the dialer received.
+func New(config config.Reachability, dialer proxy.ContextDialer) *Checker {
+	c := &Checker{
+		dialer:        dialer,
+		timeout:       config.Timeout,
+		cacheDuration: config.CacheDuration,
+		now:           time.Now,
+		results:       make(map[string]result),
+	}
+	if c.timeout == 0 {
+		c.timeout = DefaultTimeout
+	}
+	if c.cacheDuration == 0 {
+		c.cacheDuration = DefaultCacheDuration
+	}
+	return c
+}
+
+// Reachable returns whether the node accepts TCP connections on any of its addresses. Nodes
+// without addresses are unreachable.
+func (c *Checker) Reachable(ctx context.Context, publicKey string, addresses []string) bool {
+	now := c.now()
+	c.mu.Lock()
+	res, ok := c.results[publicKey]
+	c.mu.Unlock()
+	if ok && now.Sub(res.checkedAt) < c.cacheDuration {
+		return res.reachable
+	}
+
+	reachable := c.dialAny(ctx, addresses)
+	// Do not remember the result of checks cut short by the caller
+	if ctx.Err() != nil {
+		return reachable
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweep(now)
+	c.results[publicKey] = result{reachable: reachable, checkedAt: now}
+	return reachable
+}
+
+// dialAny dials all the addresses at the same time and returns as soon as one connects.
+func (c *Checker) dialAny(ctx context.Context, addresses []string) bool {
+	if len(addresses) == 0 {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	connected := make(chan bool, len(addresses))
+	for _, address := range addresses {
+		go func() {
+			conn, err := c.dialer.DialContext(ctx, "tcp", address)
+			if err != nil {
+				connected <- false
+				return
+			}
+			conn.Close()
+			connected <- true
+		}()
+	}
+
+	for range addresses {
+		if <-connected {
+			return true
+		}
+	}
+	return false
+}
+
+// sweep removes the expired results once every cache duration.
+func (c *Checker) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < c.cacheDuration {
+		return
+	}
+	c.lastSweep = now
+
+	for publicKey, res := range c.results {
+		if now.Sub(res.checkedAt) >= c.cacheDuration {
+			delete(c.results, publicKey)
+		}
+	}
+}
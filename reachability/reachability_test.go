@@ -0,0 +1,49 @@
+package reachability
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	// Reserve an address and release it so nothing listens on it
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	closedAddress := closed.Addr().String()
+	closed.Close()
+
+	ctx := context.Background()
+	c := New(config.Reachability{Timeout: time.Second}, &net.Dialer{})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	assert.False(t, c.Reachable(ctx, "none", nil))
+	assert.False(t, c.Reachable(ctx, "dead", []string{closedAddress}))
+	assert.True(t, c.Reachable(ctx, "alive", []string{closedAddress, listener.Addr().String()}))
+
+	// Results are cached
+	assert.False(t, c.Reachable(ctx, "dead", []string{listener.Addr().String()}))
+
+	now = now.Add(DefaultCacheDuration)
+	assert.True(t, c.Reachable(ctx, "dead", []string{listener.Addr().String()}))
+	assert.Len(t, c.results, 1)
+}
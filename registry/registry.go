@@ -0,0 +1,140 @@
+// Package registry identifies the well-known services of the network, like LSPs, exchanges and
+// wallets, so the policies can treat them differently. The registry is embedded in acceptLND and
+// may be refreshed from a signed remote document.
+package registry
+
+import (
+	_ "embed"
+	"slices"
+	"sync"
+
+	"github.com/aftermath2/acceptlnd/config"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+//go:embed known.yml
+var known []byte
+
+// Registry maps the public keys of the known services to their entries. The entries come from
+// the embedded registry, or the remote document once it's loaded, and the local ones replace them.
+type Registry struct {
+	embedded map[string]config.RegistryEntry
+
+	mu sync.RWMutex
+	// base holds the embedded or remote entries, before the local ones are applied.
+	base    map[string]config.RegistryEntry
+	local   []config.RegistryEntry
+	entries map[string]config.RegistryEntry
+	version uint64
+}
+
+// New returns the embedded registry extended with the entries received, which replace the
+// embedded ones with the same public key.
+func New(extra []config.RegistryEntry) *Registry {
+	embedded := Embedded()
+	r := &Registry{
+		embedded: make(map[string]config.RegistryEntry, len(embedded)),
+		local:    extra,
+	}
+	for _, entry := range embedded {
+		r.embedded[entry.PublicKey] = entry
+	}
+	r.base = r.embedded
+	r.entries = r.merge()
+	return r
+}
+
+// Embedded returns the entries shipped with acceptLND.
+func Embedded() []config.RegistryEntry {
+	var entries []config.RegistryEntry
+	if err := yaml.UnmarshalStrict(known, &entries); err != nil {
+		panic("parsing the embedded registry: " + err.Error())
+	}
+	return entries
+}
+
+// Update replaces the embedded entries with the remote ones, except for the pinned nodes, which
+// keep their embedded entries. Documents older than the one loaded are rejected, so an attacker
+// can't roll the registry back replaying them.
+func (r *Registry) Update(version uint64, remote []config.RegistryEntry, pinned []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if version < r.version {
+		return errors.Errorf("document version %d is older than the current one, %d", version, r.version)
+	}
+
+	base := make(map[string]config.RegistryEntry, len(remote))
+	for _, entry := range remote {
+		base[entry.PublicKey] = entry
+	}
+	for _, publicKey := range pinned {
+		if entry, ok := r.embedded[publicKey]; ok {
+			base[publicKey] = entry
+		} else {
+			delete(base, publicKey)
+		}
+	}
+
+	r.base = base
+	r.entries = r.merge()
+	r.version = version
+	return nil
+}
+
+// SetLocal replaces the entries configured locally, keeping the embedded or remote ones.
+func (r *Registry) SetLocal(local []config.RegistryEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.local = local
+	r.entries = r.merge()
+}
+
+// merge returns the base entries with the local ones on top.
+func (r *Registry) merge() map[string]config.RegistryEntry {
+	entries := make(map[string]config.RegistryEntry, len(r.base)+len(r.local))
+	for publicKey, entry := range r.base {
+		entries[publicKey] = entry
+	}
+	for _, entry := range r.local {
+		entries[entry.PublicKey] = entry
+	}
+	return entries
+}
+
+// Version returns the version of the remote document loaded, zero if it's the embedded registry.
+func (r *Registry) Version() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.version
+}
+
+// Len returns the number of known services.
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.entries)
+}
+
+// Lookup returns the entry of the node and whether it's a known service.
+func (r *Registry) Lookup(publicKey string) (config.RegistryEntry, bool) {
+	if r == nil {
+		return config.RegistryEntry{}, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry, ok := r.entries[publicKey]
+	return entry, ok
+}
+
+// Tags returns the categories of the node, nil if it's not a known service.
+func (r *Registry) Tags(publicKey string) []string {
+	entry, ok := r.Lookup(publicKey)
+	if !ok {
+		return nil
+	}
+	return slices.Clone(entry.Tags)
+}
@@ -0,0 +1,121 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/aftermath2/acceptlnd/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	acinq   = "03864ef025fde8fb587d989186ce6a4a186895ee44a926bfc370e2c366597a3f8f"
+	unknown = "02a2f2e5d7e1fbda0a7ef1e5d4b1f9c8e4c1e9b6e3a1f1a5d3b9c6e0f2d4c6b8a0"
+)
+
+func TestEmbedded(t *testing.T) {
+	entries := Embedded()
+	assert.NotEmpty(t, entries)
+	assert.NoError(t, config.ValidateRegistry(entries))
+
+	seen := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		_, ok := seen[entry.PublicKey]
+		assert.False(t, ok, "duplicate entry %s", entry.PublicKey)
+		seen[entry.PublicKey] = struct{}{}
+	}
+}
+
+func TestTags(t *testing.T) {
+	cases := []struct {
+		desc      string
+		extra     []config.RegistryEntry
+		publicKey string
+		expected  []string
+	}{
+		{
+			desc:      "Embedded",
+			publicKey: acinq,
+			expected:  []string{"lsp"},
+		},
+		{
+			desc:      "Unknown",
+			publicKey: unknown,
+			expected:  nil,
+		},
+		{
+			desc:      "Extra",
+			extra:     []config.RegistryEntry{{PublicKey: unknown, Tags: []string{"friend"}}},
+			publicKey: unknown,
+			expected:  []string{"friend"},
+		},
+		{
+			desc:      "Override",
+			extra:     []config.RegistryEntry{{PublicKey: acinq, Tags: []string{"lsp", "routing"}}},
+			publicKey: acinq,
+			expected:  []string{"lsp", "routing"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			r := New(tc.extra)
+			assert.Equal(t, tc.expected, r.Tags(tc.publicKey))
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	local := []config.RegistryEntry{{PublicKey: unknown, Tags: []string{"friend"}}}
+	other := "03bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	remote := []config.RegistryEntry{
+		{PublicKey: acinq, Tags: []string{"exchange"}},
+		{PublicKey: unknown, Tags: []string{"exchange"}},
+		{PublicKey: other, Tags: []string{"wallet"}},
+	}
+
+	t.Run("Replaces embedded", func(t *testing.T) {
+		r := New(local)
+		assert.NoError(t, r.Update(2, remote, nil))
+		assert.Equal(t, uint64(2), r.Version())
+		assert.Equal(t, []string{"exchange"}, r.Tags(acinq))
+		assert.Equal(t, []string{"wallet"}, r.Tags(other))
+		assert.Equal(t, []string{"friend"}, r.Tags(unknown))
+		assert.Equal(t, 3, r.Len())
+	})
+
+	t.Run("Pinned", func(t *testing.T) {
+		r := New(nil)
+		assert.NoError(t, r.Update(2, remote, []string{acinq, other}))
+		assert.Equal(t, []string{"lsp"}, r.Tags(acinq))
+		assert.Nil(t, r.Tags(other))
+		assert.Equal(t, []string{"exchange"}, r.Tags(unknown))
+	})
+
+	t.Run("Older version", func(t *testing.T) {
+		r := New(nil)
+		assert.NoError(t, r.Update(2, remote, nil))
+		assert.Error(t, r.Update(1, nil, nil))
+		assert.Equal(t, []string{"wallet"}, r.Tags(other))
+		assert.NoError(t, r.Update(2, remote, nil))
+	})
+}
+
+func TestSetLocal(t *testing.T) {
+	r := New([]config.RegistryEntry{{PublicKey: unknown, Tags: []string{"friend"}}})
+	assert.NoError(t, r.Update(1, []config.RegistryEntry{{PublicKey: acinq, Tags: []string{"exchange"}}}, nil))
+
+	r.SetLocal([]config.RegistryEntry{{PublicKey: acinq, Tags: []string{"friend"}}})
+	assert.Equal(t, []string{"friend"}, r.Tags(acinq))
+	assert.Nil(t, r.Tags(unknown))
+
+	r.SetLocal(nil)
+	assert.Equal(t, []string{"exchange"}, r.Tags(acinq))
+}
+
+func TestNilRegistry(t *testing.T) {
+	var r *Registry
+	_, ok := r.Lookup(acinq)
+	assert.False(t, ok)
+	assert.Nil(t, r.Tags(acinq))
+}
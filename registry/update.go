@@ -0,0 +1,218 @@
+package registry
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+
+	"github.com/pkg/errors"
+)
+
+// Defaults used when the values are not configured.
+const (
+	DefaultInterval = 24 * time.Hour
+	DefaultTimeout  = 30 * time.Second
+)
+
+// maxDocumentSize is the largest remote document read, in bytes.
+const maxDocumentSize = 4 << 20
+
+// Document is the remote registry, signed as a whole.
+type Document struct {
+	// Version increases with every change of the document.
+	Version uint64  `json:"version"`
+	Entries []Entry `json:"entries"`
+}
+
+// Entry describes a known service in the remote document.
+type Entry struct {
+	PublicKey string   `json:"public_key"`
+	Name      string   `json:"name,omitempty"`
+	Tags      []string `json:"tags"`
+}
+
+// Updater refreshes a registry from a remote document, verifying its signature before using it.
+type Updater struct {
+	config       config.RegistryUpdate
+	signatureURL string
+	publicKey    ed25519.PublicKey
+	client       *http.Client
+	registry     *Registry
+}
+
+// NewUpdater returns an updater of the registry fetching the documents with the client received.
+func NewUpdater(config config.RegistryUpdate, client *http.Client, registry *Registry) (*Updater, error) {
+	publicKey, err := hex.DecodeString(config.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return nil, errors.Errorf("invalid ed25519 public key %q", config.PublicKey)
+	}
+
+	signatureURL := config.SignatureURL
+	if signatureURL == "" {
+		signatureURL = config.URL + ".sig"
+	}
+	if config.Interval == 0 {
+		config.Interval = DefaultInterval
+	}
+
+	return &Updater{
+		config:       config,
+		signatureURL: signatureURL,
+		publicKey:    ed25519.PublicKey(publicKey),
+		client:       client,
+		registry:     registry,
+	}, nil
+}
+
+// Run refreshes the registry right away and then every interval until the context is cancelled.
+// The entries in use are kept when a refresh fails.
+func (u *Updater) Run(ctx context.Context) {
+	ticker := time.NewTicker(u.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := u.Update(ctx); err != nil && ctx.Err() == nil {
+			slog.Warn("Updating registry, keeping the current entries", slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Update fetches the remote document and loads it into the registry if its signature is valid.
+func (u *Updater) Update(ctx context.Context) error {
+	document, err := u.get(ctx, u.config.URL)
+	if err != nil {
+		return errors.Wrap(err, "fetching document")
+	}
+	signature, err := u.get(ctx, u.signatureURL)
+	if err != nil {
+		return errors.Wrap(err, "fetching signature")
+	}
+
+	version, err := u.load(document, signature)
+	if err != nil {
+		return err
+	}
+	slog.Info("Registry updated", slog.Uint64("version", version), slog.Int("entries", u.registry.Len()))
+
+	if u.config.CachePath == "" {
+		return nil
+	}
+	if err := writeFile(u.config.CachePath, document); err != nil {
+		return err
+	}
+	return writeFile(u.config.CachePath+".sig", signature)
+}
+
+// LoadCache loads the last document verified, if it was saved.
+func (u *Updater) LoadCache() error {
+	if u.config.CachePath == "" {
+		return nil
+	}
+
+	document, err := os.ReadFile(u.config.CachePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrap(err, "reading registry cache")
+	}
+	signature, err := os.ReadFile(u.config.CachePath + ".sig")
+	if err != nil {
+		return errors.Wrap(err, "reading registry cache signature")
+	}
+
+	_, err = u.load(document, signature)
+	return err
+}
+
+// load verifies the document was signed with the pinned key and replaces the registry entries,
+// returning its version.
+func (u *Updater) load(document, signature []byte) (uint64, error) {
+	sig, err := hex.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil || !ed25519.Verify(u.publicKey, document, sig) {
+		return 0, errors.New("invalid registry signature")
+	}
+
+	var doc Document
+	if err := json.Unmarshal(document, &doc); err != nil {
+		return 0, errors.Wrap(err, "decoding registry document")
+	}
+
+	entries := make([]config.RegistryEntry, 0, len(doc.Entries))
+	for _, entry := range doc.Entries {
+		entries = append(entries, config.RegistryEntry{
+			PublicKey: entry.PublicKey,
+			Name:      entry.Name,
+			Tags:      entry.Tags,
+		})
+	}
+	if err := config.ValidateRegistry(entries); err != nil {
+		return 0, errors.Wrap(err, "registry document entries")
+	}
+
+	if err := u.registry.Update(doc.Version, entries, u.config.Pinned); err != nil {
+		return 0, err
+	}
+	return doc.Version, nil
+}
+
+func (u *Updater) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating request")
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "sending request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDocumentSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "reading response")
+	}
+	if len(body) > maxDocumentSize {
+		return nil, errors.Errorf("response is larger than %d bytes", maxDocumentSize)
+	}
+	return body, nil
+}
+
+// writeFile replaces the file with the content received.
+func writeFile(path string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "creating registry cache")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "writing registry cache")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "writing registry cache")
+	}
+
+	return errors.Wrap(os.Rename(tmp.Name(), path), "replacing registry cache")
+}
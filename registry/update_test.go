@@ -0,0 +1,123 @@
+package registry
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aftermath2/acceptlnd/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const document = `{"version":3,"entries":[{"public_key":"` + unknown + `","name":"Exchange","tags":["exchange"]}]}`
+
+func TestUpdater(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	signature := hex.EncodeToString(ed25519.Sign(privateKey, []byte(document)))
+	_, otherKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	forged := hex.EncodeToString(ed25519.Sign(otherKey, []byte(document)))
+
+	cases := []struct {
+		desc      string
+		document  string
+		signature string
+		fail      bool
+	}{
+		{
+			desc:      "Valid",
+			document:  document,
+			signature: signature + "\n",
+		},
+		{
+			desc:      "Forged signature",
+			document:  document,
+			signature: forged,
+			fail:      true,
+		},
+		{
+			desc:      "Tampered document",
+			document:  document + " ",
+			signature: signature,
+			fail:      true,
+		},
+		{
+			desc:      "Missing signature",
+			document:  document,
+			signature: "",
+			fail:      true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /known.json", func(w http.ResponseWriter, _ *http.Request) {
+				w.Write([]byte(tc.document))
+			})
+			mux.HandleFunc("GET /known.json.sig", func(w http.ResponseWriter, _ *http.Request) {
+				if tc.signature == "" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.Write([]byte(tc.signature))
+			})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			registry := New(nil)
+			cachePath := filepath.Join(t.TempDir(), "known.json")
+			updater, err := NewUpdater(config.RegistryUpdate{
+				URL:       server.URL + "/known.json",
+				PublicKey: hex.EncodeToString(publicKey),
+				CachePath: cachePath,
+			}, server.Client(), registry)
+			assert.NoError(t, err)
+
+			err = updater.Update(context.Background())
+			if tc.fail {
+				assert.Error(t, err)
+				assert.Nil(t, registry.Tags(unknown))
+				assert.Equal(t, []string{"lsp"}, registry.Tags(acinq))
+				assert.NoFileExists(t, cachePath)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, uint64(3), registry.Version())
+			assert.Equal(t, []string{"exchange"}, registry.Tags(unknown))
+			assert.Nil(t, registry.Tags(acinq))
+
+			// A new registry loads the verified document from the cache
+			cached := New(nil)
+			updater, err = NewUpdater(config.RegistryUpdate{
+				URL:       server.URL + "/known.json",
+				PublicKey: hex.EncodeToString(publicKey),
+				CachePath: cachePath,
+			}, server.Client(), cached)
+			assert.NoError(t, err)
+			assert.NoError(t, updater.LoadCache())
+			assert.Equal(t, []string{"exchange"}, cached.Tags(unknown))
+
+			assert.NoError(t, os.WriteFile(cachePath, []byte(tc.document+" "), 0o600))
+			assert.Error(t, updater.LoadCache())
+		})
+	}
+}
+
+func TestLoadCacheMissing(t *testing.T) {
+	updater, err := NewUpdater(config.RegistryUpdate{
+		URL:       "https://example.com/known.json",
+		PublicKey: hex.EncodeToString(make([]byte, ed25519.PublicKeySize)),
+		CachePath: filepath.Join(t.TempDir(), "known.json"),
+	}, http.DefaultClient, New(nil))
+	assert.NoError(t, err)
+	assert.NoError(t, updater.LoadCache())
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/graph"
+	"github.com/aftermath2/acceptlnd/report"
+	"github.com/aftermath2/acceptlnd/store"
+
+	"github.com/pkg/errors"
+)
+
+// runReport analyzes the decisions stored in the database and suggests adjustments to the
+// policies.
+func runReport(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	configPath := fs.String("config", "acceptlnd.yml", "Path to the configuration file")
+	peersPath := fs.String("peers", "", "Path to a graph snapshot, in JSON format (lncli describegraph) or exported by acceptlnd graph")
+	since := fs.Duration("since", 30*24*time.Hour, "Period of time analyzed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	config, err := config.LoadPolicies(*configPath)
+	if err != nil {
+		return err
+	}
+	if config.DatabasePath == "" || config.DatabaseBackend == store.BackendMemory {
+		return errors.New("the configuration has no database_path, decisions are not recorded")
+	}
+
+	var snapshot *graph.Snapshot
+	if *peersPath != "" {
+		snapshot, err = graph.Load(*peersPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	db, err := store.OpenReadOnly(config.DatabaseBackend, config.DatabasePath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	decisions, err := db.Decisions(time.Now().Add(-*since))
+	if err != nil {
+		return err
+	}
+
+	return report.Generate(decisions, config.Policies, snapshot).Write(os.Stdout)
+}
@@ -0,0 +1,236 @@
+// Package report analyzes the decisions history to help operators tune their policies.
+package report
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/graph"
+	"github.com/aftermath2/acceptlnd/policy"
+	"github.com/aftermath2/acceptlnd/store"
+)
+
+// Thresholds used to make recommendations.
+const (
+	// Minimum share of the rejections a reason must have to be analyzed.
+	significantShare = 0.25
+	// Share of requests failing with internal errors considered abnormal.
+	internalErrorsShare = 0.1
+	// Share of rejected requests considered too high.
+	strictShare = 0.9
+	// Minimum number of requests needed to recommend anything.
+	minRequests = 10
+)
+
+const internalError = "Internal server error"
+
+// Report summarizes the decisions taken. Backfilled and unmanaged decisions are counted apart, as
+// no policy evaluated them.
+type Report struct {
+	Requests   int
+	Accepted   int
+	Backfilled int
+	// Unmanaged are the channels opened while acceptLND was not connected to LND.
+	Unmanaged       []store.Decision
+	Rejections      []Rejection
+	Recommendations []string
+}
+
+// Rejection groups the requests rejected by the same policy for the same reason.
+type Rejection struct {
+	Policy         string
+	Reason         string
+	Count          int
+	MedianCapacity uint64
+}
+
+// Generate analyzes the decisions, the policies currently configured and, optionally, a snapshot
+// of the graph.
+func Generate(decisions []store.Decision, policies []*policy.Policy, snapshot *graph.Snapshot) Report {
+	var report Report
+
+	type key struct{ policy, reason string }
+	capacities := make(map[key][]uint64)
+	for _, decision := range decisions {
+		if decision.Backfilled {
+			report.Backfilled++
+			continue
+		}
+		if decision.Unmanaged {
+			report.Unmanaged = append(report.Unmanaged, decision)
+			continue
+		}
+
+		report.Requests++
+		if decision.Accepted {
+			report.Accepted++
+			continue
+		}
+
+		k := key{reason: decision.Error}
+		if len(decision.Policies) > 0 {
+			k.policy = decision.Policies[len(decision.Policies)-1]
+		}
+		capacities[k] = append(capacities[k], decision.Capacity)
+	}
+
+	for k, values := range capacities {
+		report.Rejections = append(report.Rejections, Rejection{
+			Policy:         k.policy,
+			Reason:         k.reason,
+			Count:          len(values),
+			MedianCapacity: median(values),
+		})
+	}
+	slices.SortFunc(report.Rejections, func(a, b Rejection) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Reason, b.Reason))
+	})
+
+	report.Recommendations = recommend(report, policies, snapshot)
+	return report
+}
+
+func recommend(report Report, policies []*policy.Policy, snapshot *graph.Snapshot) []string {
+	if report.Requests < minRequests {
+		return nil
+	}
+
+	var recommendations []string
+	rejected := report.Requests - report.Accepted
+	if share := float64(rejected) / float64(report.Requests); share >= strictShare {
+		recommendations = append(recommendations, fmt.Sprintf(
+			"%.0f%% of the requests were rejected, the policies might be too strict", share*100,
+		))
+	}
+
+	for _, rejection := range report.Rejections {
+		if rejection.Reason == internalError {
+			if share := float64(rejection.Count) / float64(report.Requests); share >= internalErrorsShare {
+				recommendations = append(recommendations, fmt.Sprintf(
+					"%.0f%% of the requests failed with internal errors, check the connection with LND",
+					share*100,
+				))
+			}
+			continue
+		}
+
+		share := float64(rejection.Count) / float64(rejected)
+		if share < significantShare {
+			continue
+		}
+
+		recommendation := fmt.Sprintf("%.0f%% of the rejections were due to %q (policy %s)",
+			share*100, rejection.Reason, rejection.Policy)
+
+		if minCapacity, ok := minChannelCapacity(policies, rejection); ok {
+			recommendation += fmt.Sprintf(
+				"; the median requested capacity was %d sats, lowering channel_capacity.min to it "+
+					"would have accepted up to half of them", rejection.MedianCapacity)
+			if snapshot != nil {
+				recommendation += fmt.Sprintf(
+					"; %.0f%% of the public channels in the graph have at least %d sats",
+					graphShare(snapshot, minCapacity)*100, minCapacity)
+			}
+		}
+		recommendations = append(recommendations, recommendation)
+	}
+
+	return recommendations
+}
+
+// minChannelCapacity returns the minimum channel capacity of the policy that caused the
+// rejection, if that's what rejected the requests.
+func minChannelCapacity(policies []*policy.Policy, rejection Rejection) (uint64, bool) {
+	if !strings.HasPrefix(rejection.Reason, "Channel capacity is lower than") {
+		return 0, false
+	}
+
+	for i, p := range policies {
+		if p.Label(i) != rejection.Policy {
+			continue
+		}
+		if p.Request == nil || p.Request.ChannelCapacity == nil || p.Request.ChannelCapacity.Min == nil {
+			return 0, false
+		}
+		return *p.Request.ChannelCapacity.Min, true
+	}
+
+	return 0, false
+}
+
+// graphShare returns the share of channels in the graph with a capacity of at least min.
+func graphShare(snapshot *graph.Snapshot, min uint64) float64 {
+	edges := snapshot.Graph().Edges
+	if len(edges) == 0 {
+		return 0
+	}
+
+	count := 0
+	for _, edge := range edges {
+		if uint64(edge.Capacity) >= min {
+			count++
+		}
+	}
+	return float64(count) / float64(len(edges))
+}
+
+func median(values []uint64) uint64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	return sorted[len(sorted)/2]
+}
+
+// Write prints the report in a human readable format.
+func (r Report) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Requests\t%d\n", r.Requests)
+	fmt.Fprintf(tw, "Accepted\t%d\n", r.Accepted)
+	fmt.Fprintf(tw, "Rejected\t%d\n", r.Requests-r.Accepted)
+	if r.Backfilled > 0 {
+		fmt.Fprintf(tw, "Backfilled\t%d\n", r.Backfilled)
+	}
+	if len(r.Unmanaged) > 0 {
+		fmt.Fprintf(tw, "Unmanaged\t%d\n", len(r.Unmanaged))
+	}
+
+	if len(r.Rejections) > 0 {
+		fmt.Fprintf(tw, "\nPolicy\tReason\tCount\tMedian capacity\n")
+		for _, rejection := range r.Rejections {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\n",
+				rejection.Policy, rejection.Reason, rejection.Count, rejection.MedianCapacity)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(r.Unmanaged) > 0 {
+		fmt.Fprintln(w, "\nUnmanaged channels opened during downtime, accepted by LND with its defaults:")
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "  Channel point\tPeer\tCapacity\tOpened\n")
+		for _, decision := range r.Unmanaged {
+			fmt.Fprintf(tw, "  %s\t%s\t%d\t%s\n",
+				decision.ID, decision.PublicKey, decision.Capacity, decision.At.Format(time.DateOnly))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(r.Recommendations) > 0 {
+		fmt.Fprintln(w, "\nRecommendations:")
+		for _, recommendation := range r.Recommendations {
+			fmt.Fprintf(w, "  - %s\n", recommendation)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/graph"
+	"github.com/aftermath2/acceptlnd/policy"
+	"github.com/aftermath2/acceptlnd/store"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	min := uint64(5_000_000)
+	policies := []*policy.Policy{
+		{Name: "capacity", Request: &policy.Request{ChannelCapacity: &policy.Range[uint64]{Min: &min}}},
+	}
+	snapshot := graph.New(&lnrpc.ChannelGraph{
+		Edges: []*lnrpc.ChannelEdge{{Capacity: 10_000_000}, {Capacity: 1_000_000}},
+	})
+
+	var decisions []store.Decision
+	for _, capacity := range []uint64{1_000_000, 2_000_000, 3_200_000, 4_000_000, 4_500_000} {
+		decisions = append(decisions, store.Decision{
+			Capacity: capacity,
+			Error:    "Channel capacity is lower than 5000000",
+			Policies: []string{"capacity"},
+		})
+	}
+	for range 2 {
+		decisions = append(decisions, store.Decision{Capacity: 1, Error: "Internal server error"})
+	}
+	for range 3 {
+		decisions = append(decisions, store.Decision{Capacity: 6_000_000, Accepted: true})
+	}
+	decisions = append(decisions, store.Decision{Capacity: 2_000_000, Accepted: true, Backfilled: true})
+	unmanaged := store.Decision{
+		ID:        "a1b2:0",
+		PublicKey: "02aa",
+		Capacity:  3_000_000,
+		Accepted:  true,
+		Unmanaged: true,
+		At:        time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC),
+	}
+	decisions = append(decisions, unmanaged)
+
+	report := Generate(decisions, policies, snapshot)
+	assert.Equal(t, 10, report.Requests)
+	assert.Equal(t, 3, report.Accepted)
+	assert.Equal(t, 1, report.Backfilled)
+	assert.Equal(t, []store.Decision{unmanaged}, report.Unmanaged)
+	assert.Equal(t, []Rejection{
+		{
+			Policy:         "capacity",
+			Reason:         "Channel capacity is lower than 5000000",
+			Count:          5,
+			MedianCapacity: 3_200_000,
+		},
+		{Reason: "Internal server error", Count: 2, MedianCapacity: 1},
+	}, report.Rejections)
+	assert.Equal(t, []string{
+		`71% of the rejections were due to "Channel capacity is lower than 5000000" (policy capacity); ` +
+			"the median requested capacity was 3200000 sats, lowering channel_capacity.min to it would " +
+			"have accepted up to half of them; 50% of the public channels in the graph have at least " +
+			"5000000 sats",
+		"20% of the requests failed with internal errors, check the connection with LND",
+	}, report.Recommendations)
+
+	var buf bytes.Buffer
+	assert.NoError(t, report.Write(&buf))
+	assert.Contains(t, buf.String(), "Rejected    7\n")
+	assert.Contains(t, buf.String(), "Backfilled  1\n")
+	assert.Contains(t, buf.String(), "Unmanaged   1\n")
+	assert.Contains(t, buf.String(), "a1b2:0         02aa  3000000   2024-05-10\n")
+	assert.Contains(t, buf.String(), "Recommendations:")
+}
+
+func TestGenerateFewRequests(t *testing.T) {
+	report := Generate([]store.Decision{{Error: "Node is blocked"}}, nil, nil)
+	assert.Equal(t, 1, report.Requests)
+	assert.Len(t, report.Rejections, 1)
+	assert.Empty(t, report.Recommendations)
+
+	var buf bytes.Buffer
+	assert.NoError(t, report.Write(&buf))
+	assert.NotContains(t, buf.String(), "Recommendations:")
+}
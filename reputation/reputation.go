@@ -0,0 +1,58 @@
+// Package reputation scores nodes based on the events observed about them, like the channels they
+// opened with us or how they behaved afterwards. Scores decay exponentially so that old events
+// weigh less than recent ones.
+package reputation
+
+import (
+	"math"
+	"time"
+)
+
+// Event is something a node did that affects its reputation.
+type Event string
+
+// Events tracked.
+const (
+	Accepted    Event = "accepted"
+	Rejected    Event = "rejected"
+	Flapped     Event = "flapped"
+	ForceClosed Event = "force_closed"
+	Routed      Event = "routed"
+)
+
+// weights contains the impact of every event on the score.
+var weights = map[Event]float64{
+	Accepted:    1,
+	Rejected:    -1,
+	Flapped:     -2,
+	ForceClosed: -10,
+	Routed:      2,
+}
+
+// DefaultHalfLife is the time it takes for an event to lose half of its weight.
+const DefaultHalfLife = 30 * 24 * time.Hour
+
+// Weight returns the impact of the event on the score.
+func (e Event) Weight() float64 {
+	return weights[e]
+}
+
+// Score is the reputation of a node at a point in time.
+type Score struct {
+	Value     float64   `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// At returns the score value decayed up to t.
+func (s Score) At(t time.Time, halfLife time.Duration) float64 {
+	elapsed := t.Sub(s.UpdatedAt)
+	if s.UpdatedAt.IsZero() || elapsed <= 0 || halfLife <= 0 {
+		return s.Value
+	}
+	return s.Value * math.Pow(0.5, float64(elapsed)/float64(halfLife))
+}
+
+// Add returns the score after applying the event at time t.
+func (s Score) Add(event Event, t time.Time, halfLife time.Duration) Score {
+	return Score{Value: s.At(t, halfLife) + event.Weight(), UpdatedAt: t}
+}
@@ -0,0 +1,33 @@
+package reputation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScore(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	halfLife := 24 * time.Hour
+
+	var score Score
+	assert.Equal(t, 0.0, score.At(now, halfLife))
+
+	score = score.Add(Routed, now, halfLife).Add(ForceClosed, now, halfLife)
+	assert.Equal(t, Score{Value: -8, UpdatedAt: now}, score)
+
+	assert.Equal(t, -8.0, score.At(now.Add(-time.Hour), halfLife))
+	assert.Equal(t, -4.0, score.At(now.Add(halfLife), halfLife))
+	assert.Equal(t, -2.0, score.At(now.Add(2*halfLife), halfLife))
+	assert.Equal(t, -8.0, score.At(now.Add(halfLife), 0))
+
+	score = score.Add(Accepted, now.Add(halfLife), halfLife)
+	assert.Equal(t, Score{Value: -3, UpdatedAt: now.Add(halfLife)}, score)
+}
+
+func TestWeight(t *testing.T) {
+	assert.Positive(t, Accepted.Weight())
+	assert.Negative(t, Rejected.Weight())
+	assert.Equal(t, 0.0, Event("unknown").Weight())
+}
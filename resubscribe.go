@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/metrics"
+)
+
+// gracePeriodLabel identifies the requests decided during the grace period, as LND couldn't
+// provide the information to evaluate them.
+const gracePeriodLabel = "grace_period"
+
+// resubscribeDelay is the time waited before registering the channel acceptor again, so a stream
+// failing right away isn't registered in a loop.
+const resubscribeDelay = 5 * time.Second
+
+// serveChannelRequests handles the channel requests until the context is cancelled, registering
+// the channel acceptor again whenever its stream fails, once LND is ready.
+func (a *acceptor) serveChannelRequests(ctx context.Context, waitReady func(context.Context) error) error {
+	for {
+		err := a.handleChannelRequests(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		slog.Warn("Channel acceptor stream closed, registering again once LND is ready",
+			slog.Any("error", err))
+		// The connection state may not change if only the stream broke
+		metrics.SetConnected(false)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(resubscribeDelay):
+		}
+		if err := waitReady(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		// LND may have restarted, the number of channels could have changed
+		a.invalidateNodeInfo()
+	}
+}
+
+// inGracePeriod returns whether the channel acceptor was registered within the grace period.
+func (a *acceptor) inGracePeriod(now time.Time) bool {
+	if a.resubscribe == nil {
+		return false
+	}
+	registered := time.Unix(0, a.registered.Load())
+	return now.Sub(registered) < a.resubscribe.GracePeriod
+}
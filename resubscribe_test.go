@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/lightning"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+// unavailableClient fails to register the channel acceptor, as LND does while it restarts.
+type unavailableClient struct {
+	lightning.Client
+	registrations atomic.Int32
+}
+
+func (c *unavailableClient) ChannelAcceptor(
+	context.Context,
+	...grpc.CallOption,
+) (lnrpc.Lightning_ChannelAcceptorClient, error) {
+	c.registrations.Add(1)
+	return nil, errors.New("unavailable")
+}
+
+func TestServeChannelRequests(t *testing.T) {
+	t.Run("Registers again", func(t *testing.T) {
+		t.Parallel()
+		client := &unavailableClient{}
+		a := newAcceptor(client, nil, config.Config{})
+		a.nodeInfo.Store(&cachedNodeInfo{info: &lnrpc.GetInfoResponse{}, fetchedAt: time.Now()})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		waits := 0
+		err := a.serveChannelRequests(ctx, func(context.Context) error {
+			waits++
+			// The stream fails again right away, stop after registering it
+			cancel()
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, waits)
+		assert.Equal(t, int32(2), client.registrations.Load())
+		assert.Nil(t, a.nodeInfo.Load())
+	})
+
+	t.Run("Not ready", func(t *testing.T) {
+		t.Parallel()
+		client := &unavailableClient{}
+		a := newAcceptor(client, nil, config.Config{})
+
+		notReady := errors.New("not ready")
+		err := a.serveChannelRequests(context.Background(), func(context.Context) error {
+			return notReady
+		})
+		assert.ErrorIs(t, err, notReady)
+		assert.Equal(t, int32(1), client.registrations.Load())
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		t.Parallel()
+		client := &unavailableClient{}
+		a := newAcceptor(client, nil, config.Config{})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := a.serveChannelRequests(ctx, func(context.Context) error {
+			t.Error("waiting for LND after the context was cancelled")
+			return nil
+		})
+		assert.NoError(t, err)
+	})
+}
+
+func TestInGracePeriod(t *testing.T) {
+	registered := time.Unix(1_700_000_000, 0)
+
+	cases := []struct {
+		desc        string
+		resubscribe *config.Resubscribe
+		now         time.Time
+		expected    bool
+	}{
+		{
+			desc: "Disabled",
+			now:  registered,
+		},
+		{
+			desc:        "Within",
+			resubscribe: &config.Resubscribe{GracePeriod: time.Minute},
+			now:         registered.Add(30 * time.Second),
+			expected:    true,
+		},
+		{
+			desc:        "After",
+			resubscribe: &config.Resubscribe{GracePeriod: time.Minute},
+			now:         registered.Add(time.Minute),
+		},
+		{
+			desc:        "Without grace period",
+			resubscribe: &config.Resubscribe{},
+			now:         registered,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			a := newAcceptor(nil, nil, config.Config{Resubscribe: tc.resubscribe})
+			a.registered.Store(registered.UnixNano())
+			assert.Equal(t, tc.expected, a.inGracePeriod(tc.now))
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// Package server exposes acceptLND's optional HTTP endpoints.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+// Server is the HTTP server.
+type Server struct {
+	srv *http.Server
+	mux *http.ServeMux
+}
+
+// New returns a new HTTP server listening on the address received.
+func New(address string) *Server {
+	mux := http.NewServeMux()
+	return &Server{
+		mux: mux,
+		srv: &http.Server{
+			Addr:              address,
+			Handler:           mux,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+	}
+}
+
+// Handle registers the handler for the given pattern.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// EnablePprof registers the runtime profiling endpoints under /debug/pprof/.
+func (s *Server) EnablePprof() {
+	s.mux.HandleFunc("GET /debug/pprof/", pprof.Index)
+	s.mux.HandleFunc("GET /debug/pprof/cmdline", pprof.Cmdline)
+	s.mux.HandleFunc("GET /debug/pprof/profile", pprof.Profile)
+	s.mux.HandleFunc("GET /debug/pprof/symbol", pprof.Symbol)
+	s.mux.HandleFunc("GET /debug/pprof/trace", pprof.Trace)
+}
+
+// JSON returns a handler that responds with the value returned by fn encoded as JSON.
+func JSON(fn func() (any, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		v, err := fn()
+		if err != nil {
+			slog.Error("Handling HTTP request", slog.Any("error", err))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(v); err != nil {
+			slog.Error("Encoding HTTP response", slog.Any("error", err))
+		}
+	})
+}
+
+// Lines returns a handler that responds with the lines returned by fn as plain text.
+func Lines(fn func() ([]string, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		lines, err := fn()
+		if err != nil {
+			slog.Error("Handling HTTP request", slog.Any("error", err))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, line := range lines {
+			if _, err := io.WriteString(w, line+"\n"); err != nil {
+				slog.Error("Writing HTTP response", slog.Any("error", err))
+				return
+			}
+		}
+	})
+}
+
+// Property is a value shown by the node platforms in the service page.
+type Property struct {
+	Name        string
+	Value       string
+	Description string
+	Copyable    bool
+}
+
+// property is the encoding of a property in the Start9 properties format, version 2.
+type property struct {
+	Type        string `json:"type"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+	Copyable    bool   `json:"copyable"`
+	QR          bool   `json:"qr"`
+	Masked      bool   `json:"masked"`
+}
+
+// Properties returns a handler that responds with the properties returned by fn in the format
+// the Start9 service page reads.
+func Properties(fn func() ([]Property, error)) http.Handler {
+	return JSON(func() (any, error) {
+		properties, err := fn()
+		if err != nil {
+			return nil, err
+		}
+
+		data := make(map[string]property, len(properties))
+		for _, p := range properties {
+			data[p.Name] = property{
+				Type:        "string",
+				Value:       p.Value,
+				Description: p.Description,
+				Copyable:    p.Copyable,
+			}
+		}
+		return map[string]any{"version": 2, "data": data}, nil
+	})
+}
+
+// Health returns a handler that reports the status returned by check, responding with a 503
+// status code if it's not healthy.
+func Health(check func() (status string, healthy bool)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		status, healthy := check()
+
+		w.Header().Set("Content-Type", "application/json")
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(w).Encode(map[string]string{"status": status}); err != nil {
+			slog.Error("Encoding HTTP response", slog.Any("error", err))
+		}
+	})
+}
+
+// ServeHTTP dispatches the request to the handler whose pattern matches the request URL.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe starts listening for requests, it blocks until the server is shut down.
+func (s *Server) ListenAndServe() error {
+	slog.Info("Starting HTTP server", slog.String("address", s.srv.Addr))
+	if err := s.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Shutdown gracefully stops the server.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnablePprof(t *testing.T) {
+	srv := New("127.0.0.1:0")
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	srv.EnablePprof()
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestHandle(t *testing.T) {
+	srv := New("127.0.0.1:0")
+	srv.Handle("GET /test", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+}
+
+func TestJSON(t *testing.T) {
+	srv := New("127.0.0.1:0")
+	srv.Handle("GET /ok", JSON(func() (any, error) {
+		return map[string]int{"a": 1}, nil
+	}))
+	srv.Handle("GET /fail", JSON(func() (any, error) {
+		return nil, errors.New("fail")
+	}))
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"a":1}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestProperties(t *testing.T) {
+	srv := New("127.0.0.1:0")
+	srv.Handle("GET /properties", Properties(func() ([]Property, error) {
+		return []Property{{Name: "Accepted", Value: "3", Description: "Channels accepted"}}, nil
+	}))
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/properties", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	expected := `{"version":2,"data":{"Accepted":{"type":"string","value":"3","description":"Channels accepted","copyable":false,"qr":false,"masked":false}}}`
+	assert.JSONEq(t, expected, rec.Body.String())
+}
+
+func TestLines(t *testing.T) {
+	srv := New("127.0.0.1:0")
+	srv.Handle("GET /ok", Lines(func() ([]string, error) {
+		return []string{"a", "b"}, nil
+	}))
+	srv.Handle("GET /fail", Lines(func() ([]string, error) {
+		return nil, errors.New("fail")
+	}))
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "a\nb\n", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestHealth(t *testing.T) {
+	healthy := true
+	srv := New("127.0.0.1:0")
+	srv.Handle("GET /health", Health(func() (string, bool) {
+		if healthy {
+			return "READY", true
+		}
+		return "TRANSIENT_FAILURE", false
+	}))
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"READY"}`, rec.Body.String())
+
+	healthy = false
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"status":"TRANSIENT_FAILURE"}`, rec.Body.String())
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/metrics"
+	"github.com/aftermath2/acceptlnd/webhook"
+)
+
+// defaultSLOBreaches is the number of consecutive slow responses that raise an alert when it's
+// not configured.
+const defaultSLOBreaches = 3
+
+// sloAlert identifies the response SLO alerts sent to the webhook.
+const sloAlert = "response_slo"
+
+// sloTracker counts the consecutive responses slower than the target.
+type sloTracker struct {
+	target   time.Duration
+	breaches int
+
+	mu          sync.Mutex
+	consecutive int
+}
+
+func newSLOTracker(slo config.ResponseSLO) *sloTracker {
+	if slo.Breaches == 0 {
+		slo.Breaches = defaultSLOBreaches
+	}
+	return &sloTracker{target: slo.Target, breaches: slo.Breaches}
+}
+
+// observe records the response time and returns whether it completes a streak of as many
+// consecutive breaches as configured. Longer streaks are not reported again until a response
+// meets the target.
+func (s *sloTracker) observe(duration time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if duration <= s.target {
+		s.consecutive = 0
+		return false
+	}
+
+	metrics.CountSLOBreach()
+	s.consecutive++
+	return s.consecutive == s.breaches
+}
+
+// observeResponse records the time taken to respond to a request and alerts when the responses
+// are breaching the SLO repeatedly, the requests LND gives up on are decided by its defaults.
+func (a *acceptor) observeResponse(ctx context.Context, duration time.Duration) {
+	metrics.ObserveResponse(duration)
+	if a.slo == nil || !a.slo.observe(duration) {
+		return
+	}
+
+	slog.ErrorContext(ctx, "Response latency SLO breached repeatedly",
+		slog.Int("consecutive", a.slo.breaches),
+		slog.Duration("target", a.slo.target),
+		slog.Duration("duration", duration),
+	)
+	if a.webhook == nil {
+		return
+	}
+
+	alert := webhook.Alert{
+		Alert: sloAlert,
+		Message: fmt.Sprintf("%d consecutive responses took longer than %s, the last one %s",
+			a.slo.breaches, a.slo.target, duration.Round(time.Millisecond)),
+		At: time.Now(),
+	}
+	if err := a.webhook.SendAlert(alert); err != nil {
+		slog.ErrorContext(ctx, "Queuing alert for the webhook", slog.Any("error", err))
+	}
+}
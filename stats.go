@@ -0,0 +1,93 @@
+package main
+
+import (
+	"runtime/debug"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/server"
+)
+
+// decisionStats counts the decisions taken since acceptLND started.
+type decisionStats struct {
+	startedAt time.Time
+	accepted  atomic.Uint64
+	rejected  atomic.Uint64
+	// lastDecision is the time of the last decision in nanoseconds since the epoch, zero if there
+	// wasn't any.
+	lastDecision atomic.Int64
+}
+
+func (s *decisionStats) count(accepted bool, at time.Time) {
+	if accepted {
+		s.accepted.Add(1)
+	} else {
+		s.rejected.Add(1)
+	}
+	s.lastDecision.Store(at.UnixNano())
+}
+
+// stats is the summary of acceptLND's activity the node platforms show.
+type stats struct {
+	Version        string     `json:"version"`
+	StartedAt      time.Time  `json:"started_at"`
+	UptimeSeconds  uint64     `json:"uptime_seconds"`
+	LNDState       string     `json:"lnd_state"`
+	Policies       int        `json:"policies"`
+	Accepted       uint64     `json:"accepted"`
+	Rejected       uint64     `json:"rejected"`
+	LastDecisionAt *time.Time `json:"last_decision_at,omitempty"`
+	Blocked        int        `json:"blocked"`
+}
+
+// getStats returns the summary of the activity, with the LND state received.
+func (a *acceptor) getStats(lndState string) stats {
+	s := stats{
+		Version:       version(),
+		StartedAt:     a.stats.startedAt,
+		UptimeSeconds: uint64(time.Since(a.stats.startedAt) / time.Second),
+		LNDState:      lndState,
+		Policies:      len(a.getPolicies()),
+		Accepted:      a.stats.accepted.Load(),
+		Rejected:      a.stats.rejected.Load(),
+		Blocked:       len(a.blocklist()),
+	}
+	if last := a.stats.lastDecision.Load(); last != 0 {
+		at := time.Unix(0, last)
+		s.LastDecisionAt = &at
+	}
+	return s
+}
+
+// properties returns the summary of the activity in the format of the Start9 service page.
+func (s stats) properties() []server.Property {
+	lastDecision := "Never"
+	if s.LastDecisionAt != nil {
+		lastDecision = s.LastDecisionAt.UTC().Format(time.RFC3339)
+	}
+	return []server.Property{
+		{Name: "Version", Value: s.Version, Description: "AcceptLND version"},
+		{Name: "LND state", Value: s.LNDState, Description: "State of the connection to LND"},
+		{Name: "Policies", Value: strconv.Itoa(s.Policies), Description: "Number of policies enforced"},
+		{Name: "Accepted", Value: strconv.FormatUint(s.Accepted, 10), Description: "Channel requests accepted since the start"},
+		{Name: "Rejected", Value: strconv.FormatUint(s.Rejected, 10), Description: "Channel requests rejected since the start"},
+		{Name: "Last decision", Value: lastDecision, Description: "Time of the last channel request decided"},
+		{Name: "Blocked", Value: strconv.Itoa(s.Blocked), Description: "Nodes whose requests are rejected whatever they ask for"},
+	}
+}
+
+// version returns the version acceptLND was built from, followed by the commit if it's known.
+func version() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	for _, s := range bi.Settings {
+		if s.Key == "vcs.revision" {
+			return bi.Main.Version + " " + s.Value
+		}
+	}
+	return bi.Main.Version
+}
@@ -0,0 +1,674 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/reputation"
+
+	"github.com/pkg/errors"
+	"go.etcd.io/bbolt"
+)
+
+var (
+	firstSeenBucket = []byte("first_seen")
+	uptimeBucket    = []byte("uptime")
+	pendingBucket   = []byte("pending_tags")
+	tagsBucket      = []byte("tags")
+	scoresBucket    = []byte("reputation")
+	eventsBucket    = []byte("reputation_events")
+	historyBucket   = []byte("history")
+	decisionsBucket = []byte("decisions")
+	queueBucket     = []byte("webhook_queue")
+	requestsBucket  = []byte("flood_requests")
+	blocksBucket    = []byte("flood_blocks")
+	overridesBucket = []byte("overrides")
+)
+
+// buckets are all the buckets of the database.
+var buckets = [][]byte{
+	firstSeenBucket, uptimeBucket, pendingBucket, tagsBucket, scoresBucket, eventsBucket,
+	historyBucket, decisionsBucket, queueBucket, requestsBucket, blocksBucket, overridesBucket,
+}
+
+var _ Storage = (*Bolt)(nil)
+
+// Bolt is a key-value database stored in a single file.
+type Bolt struct {
+	db *bbolt.DB
+}
+
+// OpenBolt opens the database located at path, creating it if it doesn't exist.
+func OpenBolt(path string) (*Bolt, error) {
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, errors.Wrap(err, "opening database")
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, bucket := range buckets {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "creating buckets")
+	}
+
+	return &Bolt{db: db}, nil
+}
+
+// OpenBoltReadOnly opens the database located at path for reading. bbolt files can't be read while
+// another process has them open for writing, so it fails after a second if acceptLND is running.
+func OpenBoltReadOnly(path string) (*Bolt, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, errors.Wrap(err, "opening database")
+	}
+
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{ReadOnly: true, Timeout: time.Second})
+	if errors.Is(err, bbolt.ErrTimeout) {
+		return nil, errors.Errorf("database %s is locked by another process, stop acceptlnd to read it", path)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "opening database")
+	}
+
+	err = db.View(func(tx *bbolt.Tx) error {
+		for _, bucket := range buckets {
+			if tx.Bucket(bucket) == nil {
+				return errors.Errorf("bucket %s not found, start acceptlnd to upgrade the database", bucket)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Bolt{db: db}, nil
+}
+
+// Close releases the database file.
+func (d *Bolt) Close() error {
+	return d.db.Close()
+}
+
+// FirstSeen returns the first time the node requested to open a channel. If it's the first time,
+// the time received is recorded and returned.
+func (d *Bolt) FirstSeen(publicKey string, t time.Time) (time.Time, error) {
+	firstSeen := t
+	err := d.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(firstSeenBucket)
+		if v := bucket.Get([]byte(publicKey)); v != nil {
+			firstSeen = decodeTime(v)
+			return nil
+		}
+
+		return bucket.Put([]byte(publicKey), encodeTime(t))
+	})
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "updating first seen time")
+	}
+
+	return firstSeen, nil
+}
+
+// MaxUptime records the uptime of a channel with the node if it's the longest seen, and returns the
+// longest one.
+func (d *Bolt) MaxUptime(publicKey string, uptime time.Duration) (time.Duration, error) {
+	maxUptime := uptime
+	err := d.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(uptimeBucket)
+		if v := bucket.Get([]byte(publicKey)); v != nil {
+			if stored := time.Duration(binary.BigEndian.Uint64(v)); stored >= uptime {
+				maxUptime = stored
+				return nil
+			}
+		}
+
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, uint64(uptime))
+		return bucket.Put([]byte(publicKey), b)
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "updating channel uptime")
+	}
+
+	return maxUptime, nil
+}
+
+// AddPendingTag stores the tag of a channel that was accepted but whose channel point isn't known
+// yet, indexed by its pending channel ID.
+func (d *Bolt) AddPendingTag(tag Tag) error {
+	v, err := json.Marshal(tag)
+	if err != nil {
+		return errors.Wrap(err, "encoding tag")
+	}
+
+	err = d.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(pendingBucket).Put([]byte(tag.PendingChanID), v)
+	})
+	return errors.Wrap(err, "storing pending tag")
+}
+
+// TagChannel assigns the channel the oldest pending tag of the node with the same capacity that
+// was accepted before the channel was opened, if the channel isn't tagged already. It returns
+// whether the channel was tagged.
+func (d *Bolt) TagChannel(publicKey, channelPoint string, capacity uint64, openedAt time.Time) (bool, error) {
+	tagged := false
+	err := d.db.Update(func(tx *bbolt.Tx) error {
+		tags := tx.Bucket(tagsBucket)
+		if tags.Get([]byte(channelPoint)) != nil {
+			return nil
+		}
+
+		var (
+			key   []byte
+			value []byte
+			tag   Tag
+		)
+		pending := tx.Bucket(pendingBucket)
+		err := pending.ForEach(func(k, v []byte) error {
+			var candidate Tag
+			if err := json.Unmarshal(v, &candidate); err != nil {
+				return err
+			}
+			if candidate.matches(publicKey, capacity, openedAt) && (key == nil || candidate.older(tag)) {
+				key, value, tag = k, v, candidate
+			}
+			return nil
+		})
+		if err != nil || key == nil {
+			return err
+		}
+
+		if err := tags.Put([]byte(channelPoint), value); err != nil {
+			return err
+		}
+		tagged = true
+		return pending.Delete(key)
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "tagging channel")
+	}
+
+	return tagged, nil
+}
+
+// PrunePendingTags deletes the pending tags of the channels accepted before the time received.
+func (d *Bolt) PrunePendingTags(before time.Time) error {
+	err := d.db.Update(func(tx *bbolt.Tx) error {
+		pending := tx.Bucket(pendingBucket)
+		var expired [][]byte
+		err := pending.ForEach(func(k, v []byte) error {
+			var tag Tag
+			if err := json.Unmarshal(v, &tag); err != nil {
+				return err
+			}
+			if tag.AcceptedAt.Before(before) {
+				expired = append(expired, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			if err := pending.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return errors.Wrap(err, "pruning pending tags")
+}
+
+// Tags returns the channels tags indexed by channel point.
+func (d *Bolt) Tags() (map[string]Tag, error) {
+	tags := make(map[string]Tag)
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(tagsBucket).ForEach(func(k, v []byte) error {
+			var tag Tag
+			if err := json.Unmarshal(v, &tag); err != nil {
+				return err
+			}
+			tags[string(k)] = tag
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "reading tags")
+	}
+
+	return tags, nil
+}
+
+// AddEvent applies the event to the node reputation score at time t and returns the new score.
+func (d *Bolt) AddEvent(
+	publicKey string,
+	event reputation.Event,
+	t time.Time,
+	halfLife time.Duration,
+) (reputation.Score, error) {
+	var score reputation.Score
+	err := d.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(scoresBucket)
+		if v := bucket.Get([]byte(publicKey)); v != nil {
+			if err := json.Unmarshal(v, &score); err != nil {
+				return err
+			}
+		}
+
+		score = score.Add(event, t, halfLife)
+		v, err := json.Marshal(score)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(publicKey), v)
+	})
+	if err != nil {
+		return reputation.Score{}, errors.Wrap(err, "updating reputation")
+	}
+
+	return score, nil
+}
+
+// AddEventOnce is like AddEvent, but the event is applied only the first time its id is seen.
+// It returns whether the event was applied.
+func (d *Bolt) AddEventOnce(
+	id, publicKey string,
+	event reputation.Event,
+	t time.Time,
+	halfLife time.Duration,
+) (bool, error) {
+	seen := false
+	err := d.db.Update(func(tx *bbolt.Tx) error {
+		events := tx.Bucket(eventsBucket)
+		if events.Get([]byte(id)) != nil {
+			seen = true
+			return nil
+		}
+		return events.Put([]byte(id), encodeTime(t))
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "recording reputation event")
+	}
+	if seen {
+		return false, nil
+	}
+
+	if _, err := d.AddEvent(publicKey, event, t, halfLife); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Score returns the node reputation score, nodes without events have a zero score.
+func (d *Bolt) Score(publicKey string) (reputation.Score, error) {
+	var score reputation.Score
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(scoresBucket).Get([]byte(publicKey))
+		if v == nil {
+			return nil
+		}
+		return json.Unmarshal(v, &score)
+	})
+	if err != nil {
+		return reputation.Score{}, errors.Wrap(err, "reading reputation")
+	}
+
+	return score, nil
+}
+
+// CountDecision adds a decision taken on a request of the node to its history.
+func (d *Bolt) CountDecision(publicKey string, accepted bool, t time.Time) error {
+	err := d.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(historyBucket)
+		var history History
+		if v := bucket.Get([]byte(publicKey)); v != nil {
+			if err := json.Unmarshal(v, &history); err != nil {
+				return err
+			}
+		}
+
+		v, err := json.Marshal(history.add(accepted, t))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(publicKey), v)
+	})
+	return errors.Wrap(err, "updating history")
+}
+
+// History returns the counts of the decisions taken on the node requests.
+func (d *Bolt) History(publicKey string) (History, error) {
+	var history History
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(historyBucket).Get([]byte(publicKey))
+		if v == nil {
+			return nil
+		}
+		return json.Unmarshal(v, &history)
+	})
+	if err != nil {
+		return History{}, errors.Wrap(err, "reading history")
+	}
+
+	return history, nil
+}
+
+// AddDecision records a channel request decision.
+func (d *Bolt) AddDecision(decision Decision) error {
+	v, err := json.Marshal(decision)
+	if err != nil {
+		return errors.Wrap(err, "encoding decision")
+	}
+
+	// Keys start with the time so decisions are sorted chronologically
+	key := append(encodeTime(decision.At), decision.ID...)
+	err = d.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(decisionsBucket).Put(key, v)
+	})
+	return errors.Wrap(err, "storing decision")
+}
+
+// Decisions returns the decisions taken since the time received, from oldest to newest.
+func (d *Bolt) Decisions(since time.Time) ([]Decision, error) {
+	var decisions []Decision
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(decisionsBucket).Cursor()
+		k, v := c.First()
+		if since.After(time.Unix(0, 0)) {
+			k, v = c.Seek(encodeTime(since))
+		}
+		for ; k != nil; k, v = c.Next() {
+			var decision Decision
+			if err := json.Unmarshal(v, &decision); err != nil {
+				return err
+			}
+			decisions = append(decisions, decision)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "reading decisions")
+	}
+
+	return decisions, nil
+}
+
+// PruneDecisions deletes the decisions taken before the time received.
+func (d *Bolt) PruneDecisions(before time.Time) error {
+	err := d.db.Update(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(decisionsBucket).Cursor()
+		end := encodeTime(before)
+		for k, _ := c.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = c.Next() {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return errors.Wrap(err, "pruning decisions")
+}
+
+// Enqueue adds an event to the end of the delivery queue.
+func (d *Bolt) Enqueue(event QueuedEvent) error {
+	err := d.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(queueBucket)
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		event.ID = id
+		return putQueuedEvent(bucket, event)
+	})
+	return errors.Wrap(err, "enqueuing event")
+}
+
+// Queue returns the events waiting to be delivered, from oldest to newest.
+func (d *Bolt) Queue() ([]QueuedEvent, error) {
+	var events []QueuedEvent
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(queueBucket).ForEach(func(k, v []byte) error {
+			event := QueuedEvent{ID: binary.BigEndian.Uint64(k)}
+			if err := json.Unmarshal(v, &event); err != nil {
+				return err
+			}
+			events = append(events, event)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "reading queue")
+	}
+
+	return events, nil
+}
+
+// UpdateQueuedEvent stores the delivery attempts of an event still in the queue.
+func (d *Bolt) UpdateQueuedEvent(event QueuedEvent) error {
+	err := d.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(queueBucket)
+		if bucket.Get(encodeID(event.ID)) == nil {
+			return nil
+		}
+		return putQueuedEvent(bucket, event)
+	})
+	return errors.Wrap(err, "updating queued event")
+}
+
+// Dequeue removes an event from the queue.
+func (d *Bolt) Dequeue(id uint64) error {
+	err := d.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(queueBucket).Delete(encodeID(id))
+	})
+	return errors.Wrap(err, "dequeuing event")
+}
+
+// CountRequest records a request for the key and returns the number of requests in the window.
+func (d *Bolt) CountRequest(key string, at time.Time, window time.Duration) (int, error) {
+	count := 0
+	err := d.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(requestsBucket)
+		// The value is the concatenation of the requests times, from oldest to newest
+		requests := keepAfter(bucket.Get([]byte(key)), at.Add(-window))
+		requests = append(requests, encodeTime(at)...)
+		count = len(requests) / 8
+		return bucket.Put([]byte(key), requests)
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "counting request")
+	}
+
+	return count, nil
+}
+
+// Block blocks the key until the time received, unless it's already blocked.
+func (d *Bolt) Block(key string, at, until time.Time) (bool, error) {
+	blocked := false
+	err := d.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(blocksBucket)
+		if v := bucket.Get([]byte(key)); v != nil && at.Before(decodeTime(v)) {
+			return nil
+		}
+		blocked = true
+		return bucket.Put([]byte(key), encodeTime(until))
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "blocking key")
+	}
+
+	return blocked, nil
+}
+
+// Blocks returns the keys still blocked and when their blocks expire.
+func (d *Bolt) Blocks(at time.Time) (map[string]time.Time, error) {
+	blocks := make(map[string]time.Time)
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(blocksBucket).ForEach(func(k, v []byte) error {
+			if until := decodeTime(v); at.Before(until) {
+				blocks[string(k)] = until
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "reading blocks")
+	}
+
+	return blocks, nil
+}
+
+// PruneRequests deletes the requests recorded and the blocks expired before the time received,
+// of the keys starting with the prefix.
+func (d *Bolt) PruneRequests(prefix string, before time.Time) error {
+	err := d.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(requestsBucket)
+		// Modifying the bucket while iterating it is not supported, updates are applied afterwards
+		updates := make(map[string][]byte)
+		c := bucket.Cursor()
+		for k, v := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, v = c.Next() {
+			if requests := keepAfter(v, before.Add(-1)); len(requests) != len(v) {
+				updates[string(k)] = requests
+			}
+		}
+		var err error
+		for k, requests := range updates {
+			if len(requests) == 0 {
+				err = bucket.Delete([]byte(k))
+			} else {
+				err = bucket.Put([]byte(k), requests)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		c = tx.Bucket(blocksBucket).Cursor()
+		for k, v := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, v = c.Next() {
+			if decodeTime(v).Before(before) {
+				if err := c.Delete(); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	return errors.Wrap(err, "pruning requests")
+}
+
+// keepAfter returns a copy of the encoded times that are after t.
+func keepAfter(times []byte, t time.Time) []byte {
+	i := 0
+	for i+8 <= len(times) && !decodeTime(times[i:i+8]).After(t) {
+		i += 8
+	}
+	return append([]byte(nil), times[i:]...)
+}
+
+func putQueuedEvent(bucket *bbolt.Bucket, event QueuedEvent) error {
+	v, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	// Sequence keys keep the events in the order they were added
+	return bucket.Put(encodeID(event.ID), v)
+}
+
+func encodeID(id uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, id)
+	return b
+}
+
+func encodeTime(t time.Time) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(t.UnixNano()))
+	return b
+}
+
+func decodeTime(b []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(b)))
+}
+
+// SetOverride records the verdict forced on the requests of the node, replacing its previous
+// override.
+func (d *Bolt) SetOverride(override Override) error {
+	v, err := json.Marshal(override)
+	if err != nil {
+		return errors.Wrap(err, "encoding override")
+	}
+
+	err = d.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(overridesBucket).Put([]byte(override.PublicKey), v)
+	})
+	return errors.Wrap(err, "storing override")
+}
+
+// DeleteOverride removes the override of the node and returns whether it had one.
+func (d *Bolt) DeleteOverride(publicKey string) (bool, error) {
+	deleted := false
+	err := d.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(overridesBucket)
+		if bucket.Get([]byte(publicKey)) == nil {
+			return nil
+		}
+		deleted = true
+		return bucket.Delete([]byte(publicKey))
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "deleting override")
+	}
+
+	return deleted, nil
+}
+
+// Override returns the override of the node and whether it has one.
+func (d *Bolt) Override(publicKey string) (Override, bool, error) {
+	var (
+		override Override
+		found    bool
+	)
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(overridesBucket).Get([]byte(publicKey))
+		if v == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(v, &override)
+	})
+	if err != nil {
+		return Override{}, false, errors.Wrap(err, "reading override")
+	}
+
+	return override, found, nil
+}
+
+// Overrides returns the overrides indexed by node public key.
+func (d *Bolt) Overrides() (map[string]Override, error) {
+	overrides := make(map[string]Override)
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(overridesBucket).ForEach(func(k, v []byte) error {
+			var override Override
+			if err := json.Unmarshal(v, &override); err != nil {
+				return err
+			}
+			overrides[string(k)] = override
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "reading overrides")
+	}
+
+	return overrides, nil
+}
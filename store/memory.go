@@ -0,0 +1,394 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/reputation"
+)
+
+var _ Storage = (*Memory)(nil)
+
+// Memory keeps the information in memory, it's lost when the process exits.
+type Memory struct {
+	mu        sync.Mutex
+	firstSeen map[string]time.Time
+	uptime    map[string]time.Duration
+	pending   map[string]Tag
+	tags      map[string]Tag
+	scores    map[string]reputation.Score
+	events    map[string]struct{}
+	histories map[string]History
+	// decisions are sorted chronologically.
+	decisions []Decision
+	queue     []QueuedEvent
+	sequence  uint64
+	requests  map[string][]time.Time
+	blocks    map[string]time.Time
+	overrides map[string]Override
+}
+
+// NewMemory returns an empty in-memory storage.
+func NewMemory() *Memory {
+	return &Memory{
+		firstSeen: make(map[string]time.Time),
+		uptime:    make(map[string]time.Duration),
+		pending:   make(map[string]Tag),
+		tags:      make(map[string]Tag),
+		scores:    make(map[string]reputation.Score),
+		events:    make(map[string]struct{}),
+		histories: make(map[string]History),
+		requests:  make(map[string][]time.Time),
+		blocks:    make(map[string]time.Time),
+		overrides: make(map[string]Override),
+	}
+}
+
+// Close does nothing, the information is kept until the storage is garbage collected.
+func (m *Memory) Close() error {
+	return nil
+}
+
+// FirstSeen returns the first time the node requested to open a channel.
+func (m *Memory) FirstSeen(publicKey string, t time.Time) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if firstSeen, ok := m.firstSeen[publicKey]; ok {
+		return firstSeen, nil
+	}
+	m.firstSeen[publicKey] = t
+	return t, nil
+}
+
+// MaxUptime records the uptime of a channel with the node if it's the longest seen.
+func (m *Memory) MaxUptime(publicKey string, uptime time.Duration) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stored := m.uptime[publicKey]; stored >= uptime {
+		return stored, nil
+	}
+	m.uptime[publicKey] = uptime
+	return uptime, nil
+}
+
+// AddPendingTag stores the tag of a channel whose channel point isn't known yet.
+func (m *Memory) AddPendingTag(tag Tag) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pending[tag.PendingChanID] = tag
+	return nil
+}
+
+// TagChannel assigns the oldest matching pending tag to the channel, if it isn't tagged already.
+func (m *Memory) TagChannel(publicKey, channelPoint string, capacity uint64, openedAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.tags[channelPoint]; ok {
+		return false, nil
+	}
+	var (
+		tag   Tag
+		found bool
+	)
+	for _, pending := range m.pending {
+		if pending.matches(publicKey, capacity, openedAt) && (!found || pending.older(tag)) {
+			tag, found = pending, true
+		}
+	}
+	if !found {
+		return false, nil
+	}
+
+	m.tags[channelPoint] = tag
+	delete(m.pending, tag.PendingChanID)
+	return true, nil
+}
+
+// PrunePendingTags deletes the pending tags of the channels accepted before the time received.
+func (m *Memory) PrunePendingTags(before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, tag := range m.pending {
+		if tag.AcceptedAt.Before(before) {
+			delete(m.pending, id)
+		}
+	}
+	return nil
+}
+
+// Tags returns the channels tags indexed by channel point.
+func (m *Memory) Tags() (map[string]Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tags := make(map[string]Tag, len(m.tags))
+	for channelPoint, tag := range m.tags {
+		tags[channelPoint] = tag
+	}
+	return tags, nil
+}
+
+// AddEvent applies the event to the node reputation score at time t.
+func (m *Memory) AddEvent(
+	publicKey string,
+	event reputation.Event,
+	t time.Time,
+	halfLife time.Duration,
+) (reputation.Score, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.addEvent(publicKey, event, t, halfLife), nil
+}
+
+func (m *Memory) addEvent(
+	publicKey string,
+	event reputation.Event,
+	t time.Time,
+	halfLife time.Duration,
+) reputation.Score {
+	score := m.scores[publicKey].Add(event, t, halfLife)
+	m.scores[publicKey] = score
+	return score
+}
+
+// AddEventOnce is like AddEvent, but the event is applied only the first time its id is seen.
+func (m *Memory) AddEventOnce(
+	id, publicKey string,
+	event reputation.Event,
+	t time.Time,
+	halfLife time.Duration,
+) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.events[id]; ok {
+		return false, nil
+	}
+	m.events[id] = struct{}{}
+	m.addEvent(publicKey, event, t, halfLife)
+	return true, nil
+}
+
+// Score returns the node reputation score.
+func (m *Memory) Score(publicKey string) (reputation.Score, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.scores[publicKey], nil
+}
+
+// CountDecision adds a decision taken on a request of the node to its history.
+func (m *Memory) CountDecision(publicKey string, accepted bool, t time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.histories[publicKey] = m.histories[publicKey].add(accepted, t)
+	return nil
+}
+
+// History returns the counts of the decisions taken on the node requests.
+func (m *Memory) History(publicKey string) (History, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.histories[publicKey], nil
+}
+
+// AddDecision records a channel request decision.
+func (m *Memory) AddDecision(decision Decision) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := sort.Search(len(m.decisions), func(i int) bool {
+		return m.decisions[i].At.After(decision.At)
+	})
+	m.decisions = append(m.decisions, Decision{})
+	copy(m.decisions[i+1:], m.decisions[i:])
+	m.decisions[i] = decision
+	return nil
+}
+
+// Decisions returns the decisions taken since the time received, from oldest to newest.
+func (m *Memory) Decisions(since time.Time) ([]Decision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Decision(nil), m.decisions[m.index(since):]...), nil
+}
+
+// PruneDecisions deletes the decisions taken before the time received.
+func (m *Memory) PruneDecisions(before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.decisions = append([]Decision(nil), m.decisions[m.index(before):]...)
+	return nil
+}
+
+// index returns the position of the first decision taken at or after t.
+func (m *Memory) index(t time.Time) int {
+	return sort.Search(len(m.decisions), func(i int) bool {
+		return !m.decisions[i].At.Before(t)
+	})
+}
+
+// Enqueue adds an event to the end of the delivery queue.
+func (m *Memory) Enqueue(event QueuedEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sequence++
+	event.ID = m.sequence
+	m.queue = append(m.queue, event)
+	return nil
+}
+
+// Queue returns the events waiting to be delivered, from oldest to newest.
+func (m *Memory) Queue() ([]QueuedEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]QueuedEvent(nil), m.queue...), nil
+}
+
+// UpdateQueuedEvent stores the delivery attempts of an event still in the queue.
+func (m *Memory) UpdateQueuedEvent(event QueuedEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.queue {
+		if m.queue[i].ID == event.ID {
+			m.queue[i] = event
+		}
+	}
+	return nil
+}
+
+// Dequeue removes an event from the queue.
+func (m *Memory) Dequeue(id uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.queue {
+		if m.queue[i].ID == id {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// CountRequest records a request for the key and returns the number of requests in the window.
+func (m *Memory) CountRequest(key string, at time.Time, window time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	requests := append(after(m.requests[key], at.Add(-window)), at)
+	m.requests[key] = requests
+	return len(requests), nil
+}
+
+// Block blocks the key until the time received, unless it's already blocked.
+func (m *Memory) Block(key string, at, until time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if blocked, ok := m.blocks[key]; ok && at.Before(blocked) {
+		return false, nil
+	}
+	m.blocks[key] = until
+	return true, nil
+}
+
+// Blocks returns the keys still blocked and when their blocks expire.
+func (m *Memory) Blocks(at time.Time) (map[string]time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	blocks := make(map[string]time.Time)
+	for key, until := range m.blocks {
+		if at.Before(until) {
+			blocks[key] = until
+		}
+	}
+	return blocks, nil
+}
+
+// PruneRequests deletes the requests recorded and the blocks expired before the time received,
+// of the keys starting with the prefix.
+func (m *Memory) PruneRequests(prefix string, before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, requests := range m.requests {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if requests = after(requests, before.Add(-1)); len(requests) > 0 {
+			m.requests[key] = requests
+		} else {
+			delete(m.requests, key)
+		}
+	}
+	for key, until := range m.blocks {
+		if strings.HasPrefix(key, prefix) && until.Before(before) {
+			delete(m.blocks, key)
+		}
+	}
+	return nil
+}
+
+// after returns the times that are after t, they must be sorted.
+func after(times []time.Time, t time.Time) []time.Time {
+	i := sort.Search(len(times), func(i int) bool { return times[i].After(t) })
+	return times[i:]
+}
+
+// SetOverride records the verdict forced on the requests of the node, replacing its previous
+// override.
+func (m *Memory) SetOverride(override Override) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.overrides[override.PublicKey] = override
+	return nil
+}
+
+// DeleteOverride removes the override of the node and returns whether it had one.
+func (m *Memory) DeleteOverride(publicKey string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.overrides[publicKey]
+	delete(m.overrides, publicKey)
+	return ok, nil
+}
+
+// Override returns the override of the node and whether it has one.
+func (m *Memory) Override(publicKey string) (Override, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	override, ok := m.overrides[publicKey]
+	return override, ok, nil
+}
+
+// Overrides returns the overrides indexed by node public key.
+func (m *Memory) Overrides() (map[string]Override, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	overrides := make(map[string]Override, len(m.overrides))
+	for publicKey, override := range m.overrides {
+		overrides[publicKey] = override
+	}
+	return overrides, nil
+}
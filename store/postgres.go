@@ -0,0 +1,608 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/reputation"
+
+	"github.com/jackc/pgx/v4"
+	// Also registers the pgx driver in database/sql
+	"github.com/jackc/pgx/v4/stdlib"
+	"github.com/pkg/errors"
+)
+
+var _ Storage = (*Postgres)(nil)
+
+// postgresSchema has the same tables as the SQLite one, with the values stored as JSONB so they
+// can be queried.
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS first_seen (public_key TEXT PRIMARY KEY, at BIGINT NOT NULL);
+CREATE TABLE IF NOT EXISTS uptime (public_key TEXT PRIMARY KEY, uptime BIGINT NOT NULL);
+CREATE TABLE IF NOT EXISTS pending_tags (
+	pending_chan_id TEXT PRIMARY KEY,
+	public_key TEXT NOT NULL,
+	capacity BIGINT NOT NULL,
+	accepted_at BIGINT NOT NULL,
+	tag JSONB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS pending_tags_public_key ON pending_tags (public_key, capacity);
+CREATE TABLE IF NOT EXISTS tags (channel_point TEXT PRIMARY KEY, tag JSONB NOT NULL);
+CREATE TABLE IF NOT EXISTS reputation (public_key TEXT PRIMARY KEY, score JSONB NOT NULL);
+CREATE TABLE IF NOT EXISTS reputation_events (id TEXT PRIMARY KEY, at BIGINT NOT NULL);
+CREATE TABLE IF NOT EXISTS history (public_key TEXT PRIMARY KEY, history JSONB NOT NULL);
+CREATE TABLE IF NOT EXISTS decisions (
+	at BIGINT NOT NULL,
+	id TEXT NOT NULL,
+	decision JSONB NOT NULL,
+	PRIMARY KEY (at, id)
+);
+CREATE TABLE IF NOT EXISTS webhook_queue (id BIGSERIAL PRIMARY KEY, event JSONB NOT NULL);
+CREATE TABLE IF NOT EXISTS flood_requests (key TEXT NOT NULL, at BIGINT NOT NULL);
+CREATE INDEX IF NOT EXISTS flood_requests_key_at ON flood_requests (key, at);
+CREATE TABLE IF NOT EXISTS flood_blocks (key TEXT PRIMARY KEY, blocked_until BIGINT NOT NULL);
+CREATE TABLE IF NOT EXISTS overrides (public_key TEXT PRIMARY KEY, override JSONB NOT NULL);
+`
+
+// Postgres stores the information in a PostgreSQL server, so several instances can share it.
+// Every operation is atomic, they can be executed concurrently by different instances.
+type Postgres struct {
+	db *sql.DB
+}
+
+// OpenPostgres connects to the database in the URL or DSN received and creates the tables
+// missing.
+func OpenPostgres(url string) (*Postgres, error) {
+	db, err := sql.Open("pgx", url)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening database")
+	}
+
+	// Instances starting at the same time could race creating the tables, the lock serializes them
+	err = inTx(db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(7175726)`); err != nil {
+			return err
+		}
+		_, err := tx.Exec(postgresSchema)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "creating tables")
+	}
+
+	return &Postgres{db: db}, nil
+}
+
+// OpenPostgresReadOnly connects to the database in the URL or DSN received with read-only
+// transactions by default, so the server rejects any write. The tables are not created.
+func OpenPostgresReadOnly(url string) (*Postgres, error) {
+	config, err := pgx.ParseConfig(url)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing database URL")
+	}
+	config.RuntimeParams["default_transaction_read_only"] = "on"
+
+	db := stdlib.OpenDB(*config)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "opening database")
+	}
+
+	return &Postgres{db: db}, nil
+}
+
+// Close closes the connections to the server.
+func (p *Postgres) Close() error {
+	return p.db.Close()
+}
+
+// FirstSeen returns the first time the node requested to open a channel.
+func (p *Postgres) FirstSeen(publicKey string, t time.Time) (time.Time, error) {
+	var at int64
+	err := p.db.QueryRow(`INSERT INTO first_seen (public_key, at) VALUES ($1, $2)
+		ON CONFLICT (public_key) DO UPDATE SET at = first_seen.at RETURNING at`,
+		publicKey, t.UnixNano()).Scan(&at)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "updating first seen time")
+	}
+
+	return time.Unix(0, at), nil
+}
+
+// MaxUptime records the uptime of a channel with the node if it's the longest seen.
+func (p *Postgres) MaxUptime(publicKey string, uptime time.Duration) (time.Duration, error) {
+	var maxUptime int64
+	err := p.db.QueryRow(`INSERT INTO uptime (public_key, uptime) VALUES ($1, $2)
+		ON CONFLICT (public_key) DO UPDATE SET uptime = GREATEST(uptime.uptime, excluded.uptime)
+		RETURNING uptime`, publicKey, int64(uptime)).Scan(&maxUptime)
+	if err != nil {
+		return 0, errors.Wrap(err, "updating channel uptime")
+	}
+
+	return time.Duration(maxUptime), nil
+}
+
+// AddPendingTag stores the tag of a channel whose channel point isn't known yet.
+func (p *Postgres) AddPendingTag(tag Tag) error {
+	v, err := json.Marshal(tag)
+	if err != nil {
+		return errors.Wrap(err, "encoding tag")
+	}
+
+	_, err = p.db.Exec(`INSERT INTO pending_tags (pending_chan_id, public_key, capacity, accepted_at, tag)
+		VALUES ($1, $2, $3, $4, $5) ON CONFLICT (pending_chan_id) DO UPDATE SET tag = excluded.tag`,
+		tag.PendingChanID, tag.PublicKey, int64(tag.Capacity), unixNano(tag.AcceptedAt), string(v))
+	return errors.Wrap(err, "storing pending tag")
+}
+
+// TagChannel assigns the oldest matching pending tag to the channel, if it isn't tagged already.
+func (p *Postgres) TagChannel(publicKey, channelPoint string, capacity uint64, openedAt time.Time) (bool, error) {
+	tagged := false
+	err := inTx(p.db, func(tx *sql.Tx) error {
+		var exists bool
+		err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM tags WHERE channel_point = $1)`, channelPoint).
+			Scan(&exists)
+		if err != nil || exists {
+			return err
+		}
+
+		// Deleting the row locks it, other instances tagging the same channel wait for the result
+		var tag []byte
+		err = tx.QueryRow(`DELETE FROM pending_tags WHERE pending_chan_id = (
+			SELECT pending_chan_id FROM pending_tags
+			WHERE public_key = $1 AND capacity = $2 AND accepted_at <= $3
+			ORDER BY accepted_at, pending_chan_id LIMIT 1
+		) RETURNING tag`, publicKey, int64(capacity), unixNano(openedAt)).Scan(&tag)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.Exec(`INSERT INTO tags (channel_point, tag) VALUES ($1, $2)
+			ON CONFLICT (channel_point) DO NOTHING`, channelPoint, string(tag))
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			// Tagged by another instance in the meantime, keep the pending tag
+			return errTagged
+		}
+		tagged = true
+		return nil
+	})
+	if err == errTagged {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "tagging channel")
+	}
+
+	return tagged, nil
+}
+
+// errTagged rolls back the transaction tagging a channel that another instance already tagged.
+var errTagged = errors.New("channel already tagged")
+
+// Tags returns the channels tags indexed by channel point.
+func (p *Postgres) Tags() (map[string]Tag, error) {
+	rows, err := p.db.Query(`SELECT channel_point, tag FROM tags`)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading tags")
+	}
+	defer rows.Close()
+
+	tags := make(map[string]Tag)
+	for rows.Next() {
+		var channelPoint string
+		var v []byte
+		if err := rows.Scan(&channelPoint, &v); err != nil {
+			return nil, errors.Wrap(err, "reading tags")
+		}
+		var tag Tag
+		if err := json.Unmarshal(v, &tag); err != nil {
+			return nil, errors.Wrap(err, "decoding tag")
+		}
+		tags[channelPoint] = tag
+	}
+
+	return tags, errors.Wrap(rows.Err(), "reading tags")
+}
+
+// AddEvent applies the event to the node reputation score at time t.
+func (p *Postgres) AddEvent(
+	publicKey string,
+	event reputation.Event,
+	t time.Time,
+	halfLife time.Duration,
+) (reputation.Score, error) {
+	var score reputation.Score
+	err := inTx(p.db, func(tx *sql.Tx) error {
+		var err error
+		score, err = addPostgresEvent(tx, publicKey, event, t, halfLife)
+		return err
+	})
+	if err != nil {
+		return reputation.Score{}, errors.Wrap(err, "updating reputation")
+	}
+
+	return score, nil
+}
+
+// AddEventOnce is like AddEvent, but the event is applied only the first time its id is seen.
+func (p *Postgres) AddEventOnce(
+	id, publicKey string,
+	event reputation.Event,
+	t time.Time,
+	halfLife time.Duration,
+) (bool, error) {
+	applied := false
+	err := inTx(p.db, func(tx *sql.Tx) error {
+		res, err := tx.Exec(`INSERT INTO reputation_events (id, at) VALUES ($1, $2)
+			ON CONFLICT (id) DO NOTHING`, id, t.UnixNano())
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+
+		applied = true
+		_, err = addPostgresEvent(tx, publicKey, event, t, halfLife)
+		return err
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "recording reputation event")
+	}
+
+	return applied, nil
+}
+
+// addPostgresEvent locks the node score row while it's updated, so events added concurrently
+// aren't lost.
+func addPostgresEvent(
+	tx *sql.Tx,
+	publicKey string,
+	event reputation.Event,
+	t time.Time,
+	halfLife time.Duration,
+) (reputation.Score, error) {
+	_, err := tx.Exec(`INSERT INTO reputation (public_key, score) VALUES ($1, '{}')
+		ON CONFLICT (public_key) DO NOTHING`, publicKey)
+	if err != nil {
+		return reputation.Score{}, err
+	}
+
+	var v []byte
+	err = tx.QueryRow(`SELECT score FROM reputation WHERE public_key = $1 FOR UPDATE`, publicKey).
+		Scan(&v)
+	if err != nil {
+		return reputation.Score{}, err
+	}
+	var score reputation.Score
+	if err := json.Unmarshal(v, &score); err != nil {
+		return reputation.Score{}, err
+	}
+
+	score = score.Add(event, t, halfLife)
+	if v, err = json.Marshal(score); err != nil {
+		return reputation.Score{}, err
+	}
+
+	_, err = tx.Exec(`UPDATE reputation SET score = $2 WHERE public_key = $1`, publicKey, string(v))
+	return score, err
+}
+
+// Score returns the node reputation score.
+func (p *Postgres) Score(publicKey string) (reputation.Score, error) {
+	var score reputation.Score
+	var v []byte
+	err := p.db.QueryRow(`SELECT score FROM reputation WHERE public_key = $1`, publicKey).Scan(&v)
+	if err == sql.ErrNoRows {
+		return score, nil
+	}
+	if err != nil {
+		return score, errors.Wrap(err, "reading reputation")
+	}
+
+	return score, errors.Wrap(json.Unmarshal(v, &score), "decoding reputation")
+}
+
+// CountDecision adds a decision taken on a request of the node to its history.
+func (p *Postgres) CountDecision(publicKey string, accepted bool, t time.Time) error {
+	err := inTx(p.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`INSERT INTO history (public_key, history) VALUES ($1, '{}')
+			ON CONFLICT (public_key) DO NOTHING`, publicKey)
+		if err != nil {
+			return err
+		}
+
+		var v []byte
+		err = tx.QueryRow(`SELECT history FROM history WHERE public_key = $1 FOR UPDATE`, publicKey).
+			Scan(&v)
+		if err != nil {
+			return err
+		}
+		var history History
+		if err := json.Unmarshal(v, &history); err != nil {
+			return err
+		}
+
+		if v, err = json.Marshal(history.add(accepted, t)); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE history SET history = $2 WHERE public_key = $1`, publicKey, string(v))
+		return err
+	})
+	return errors.Wrap(err, "updating history")
+}
+
+// History returns the counts of the decisions taken on the node requests.
+func (p *Postgres) History(publicKey string) (History, error) {
+	var history History
+	var v []byte
+	err := p.db.QueryRow(`SELECT history FROM history WHERE public_key = $1`, publicKey).Scan(&v)
+	if err == sql.ErrNoRows {
+		return history, nil
+	}
+	if err != nil {
+		return history, errors.Wrap(err, "reading history")
+	}
+
+	return history, errors.Wrap(json.Unmarshal(v, &history), "decoding history")
+}
+
+// AddDecision records a channel request decision.
+func (p *Postgres) AddDecision(decision Decision) error {
+	v, err := json.Marshal(decision)
+	if err != nil {
+		return errors.Wrap(err, "encoding decision")
+	}
+
+	_, err = p.db.Exec(`INSERT INTO decisions (at, id, decision) VALUES ($1, $2, $3)
+		ON CONFLICT (at, id) DO UPDATE SET decision = excluded.decision`,
+		decision.At.UnixNano(), decision.ID, string(v))
+	return errors.Wrap(err, "storing decision")
+}
+
+// Decisions returns the decisions taken since the time received, from oldest to newest.
+func (p *Postgres) Decisions(since time.Time) ([]Decision, error) {
+	rows, err := p.db.Query(`SELECT decision FROM decisions WHERE at >= $1 ORDER BY at, id`,
+		unixNano(since))
+	if err != nil {
+		return nil, errors.Wrap(err, "reading decisions")
+	}
+	defer rows.Close()
+
+	var decisions []Decision
+	for rows.Next() {
+		var v []byte
+		if err := rows.Scan(&v); err != nil {
+			return nil, errors.Wrap(err, "reading decisions")
+		}
+		var decision Decision
+		if err := json.Unmarshal(v, &decision); err != nil {
+			return nil, errors.Wrap(err, "decoding decision")
+		}
+		decisions = append(decisions, decision)
+	}
+
+	return decisions, errors.Wrap(rows.Err(), "reading decisions")
+}
+
+// PruneDecisions deletes the decisions taken before the time received.
+func (p *Postgres) PruneDecisions(before time.Time) error {
+	_, err := p.db.Exec(`DELETE FROM decisions WHERE at < $1`, unixNano(before))
+	return errors.Wrap(err, "pruning decisions")
+}
+
+// PrunePendingTags deletes the pending tags of the channels accepted before the time received.
+func (p *Postgres) PrunePendingTags(before time.Time) error {
+	_, err := p.db.Exec(`DELETE FROM pending_tags WHERE accepted_at < $1`, unixNano(before))
+	return errors.Wrap(err, "pruning pending tags")
+}
+
+// Enqueue adds an event to the end of the delivery queue.
+func (p *Postgres) Enqueue(event QueuedEvent) error {
+	v, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "encoding event")
+	}
+
+	_, err = p.db.Exec(`INSERT INTO webhook_queue (event) VALUES ($1)`, string(v))
+	return errors.Wrap(err, "enqueuing event")
+}
+
+// Queue returns the events waiting to be delivered, from oldest to newest.
+func (p *Postgres) Queue() ([]QueuedEvent, error) {
+	rows, err := p.db.Query(`SELECT id, event FROM webhook_queue ORDER BY id`)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading queue")
+	}
+	defer rows.Close()
+
+	var events []QueuedEvent
+	for rows.Next() {
+		var id int64
+		var v []byte
+		if err := rows.Scan(&id, &v); err != nil {
+			return nil, errors.Wrap(err, "reading queue")
+		}
+		event := QueuedEvent{ID: uint64(id)}
+		if err := json.Unmarshal(v, &event); err != nil {
+			return nil, errors.Wrap(err, "decoding event")
+		}
+		events = append(events, event)
+	}
+
+	return events, errors.Wrap(rows.Err(), "reading queue")
+}
+
+// UpdateQueuedEvent stores the delivery attempts of an event still in the queue.
+func (p *Postgres) UpdateQueuedEvent(event QueuedEvent) error {
+	v, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "encoding event")
+	}
+
+	_, err = p.db.Exec(`UPDATE webhook_queue SET event = $1 WHERE id = $2`, string(v), int64(event.ID))
+	return errors.Wrap(err, "updating queued event")
+}
+
+// Dequeue removes an event from the queue.
+func (p *Postgres) Dequeue(id uint64) error {
+	_, err := p.db.Exec(`DELETE FROM webhook_queue WHERE id = $1`, int64(id))
+	return errors.Wrap(err, "dequeuing event")
+}
+
+// CountRequest records a request for the key and returns the number of requests in the window.
+func (p *Postgres) CountRequest(key string, at time.Time, window time.Duration) (int, error) {
+	count := 0
+	err := inTx(p.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM flood_requests WHERE key = $1 AND at <= $2`,
+			key, at.Add(-window).UnixNano())
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO flood_requests (key, at) VALUES ($1, $2)`, key, at.UnixNano())
+		if err != nil {
+			return err
+		}
+		return tx.QueryRow(`SELECT count(*) FROM flood_requests WHERE key = $1`, key).Scan(&count)
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "counting request")
+	}
+
+	return count, nil
+}
+
+// Block blocks the key until the time received, unless it's already blocked.
+func (p *Postgres) Block(key string, at, until time.Time) (bool, error) {
+	res, err := p.db.Exec(`INSERT INTO flood_blocks (key, blocked_until) VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET blocked_until = excluded.blocked_until
+		WHERE flood_blocks.blocked_until <= $3`, key, until.UnixNano(), at.UnixNano())
+	if err != nil {
+		return false, errors.Wrap(err, "blocking key")
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "blocking key")
+	}
+	return n > 0, nil
+}
+
+// Blocks returns the keys still blocked and when their blocks expire.
+func (p *Postgres) Blocks(at time.Time) (map[string]time.Time, error) {
+	rows, err := p.db.Query(`SELECT key, blocked_until FROM flood_blocks WHERE blocked_until > $1`,
+		at.UnixNano())
+	if err != nil {
+		return nil, errors.Wrap(err, "reading blocks")
+	}
+	defer rows.Close()
+
+	blocks := make(map[string]time.Time)
+	for rows.Next() {
+		var key string
+		var until int64
+		if err := rows.Scan(&key, &until); err != nil {
+			return nil, errors.Wrap(err, "reading blocks")
+		}
+		blocks[key] = time.Unix(0, until)
+	}
+
+	return blocks, errors.Wrap(rows.Err(), "reading blocks")
+}
+
+// PruneRequests deletes the requests recorded and the blocks expired before the time received,
+// of the keys starting with the prefix.
+func (p *Postgres) PruneRequests(prefix string, before time.Time) error {
+	err := inTx(p.db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM flood_requests WHERE key LIKE $1 || '%' AND at < $2`,
+			prefix, before.UnixNano()); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM flood_blocks WHERE key LIKE $1 || '%' AND blocked_until < $2`,
+			prefix, before.UnixNano())
+		return err
+	})
+	return errors.Wrap(err, "pruning requests")
+}
+
+// SetOverride records the verdict forced on the requests of the node, replacing its previous
+// override.
+func (p *Postgres) SetOverride(override Override) error {
+	v, err := json.Marshal(override)
+	if err != nil {
+		return errors.Wrap(err, "encoding override")
+	}
+
+	_, err = p.db.Exec(`INSERT INTO overrides (public_key, override) VALUES ($1, $2)
+		ON CONFLICT (public_key) DO UPDATE SET override = excluded.override`, override.PublicKey, string(v))
+	return errors.Wrap(err, "storing override")
+}
+
+// DeleteOverride removes the override of the node and returns whether it had one.
+func (p *Postgres) DeleteOverride(publicKey string) (bool, error) {
+	res, err := p.db.Exec(`DELETE FROM overrides WHERE public_key = $1`, publicKey)
+	if err != nil {
+		return false, errors.Wrap(err, "deleting override")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "deleting override")
+	}
+
+	return n > 0, nil
+}
+
+// Override returns the override of the node and whether it has one.
+func (p *Postgres) Override(publicKey string) (Override, bool, error) {
+	var v []byte
+	err := p.db.QueryRow(`SELECT override FROM overrides WHERE public_key = $1`, publicKey).Scan(&v)
+	if err == sql.ErrNoRows {
+		return Override{}, false, nil
+	}
+	if err != nil {
+		return Override{}, false, errors.Wrap(err, "reading override")
+	}
+
+	var override Override
+	if err := json.Unmarshal(v, &override); err != nil {
+		return Override{}, false, errors.Wrap(err, "decoding override")
+	}
+	return override, true, nil
+}
+
+// Overrides returns the overrides indexed by node public key.
+func (p *Postgres) Overrides() (map[string]Override, error) {
+	rows, err := p.db.Query(`SELECT public_key, override FROM overrides`)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading overrides")
+	}
+	defer rows.Close()
+
+	overrides := make(map[string]Override)
+	for rows.Next() {
+		var publicKey string
+		var v []byte
+		if err := rows.Scan(&publicKey, &v); err != nil {
+			return nil, errors.Wrap(err, "reading overrides")
+		}
+		var override Override
+		if err := json.Unmarshal(v, &override); err != nil {
+			return nil, errors.Wrap(err, "decoding override")
+		}
+		overrides[publicKey] = override
+	}
+
+	return overrides, errors.Wrap(rows.Err(), "reading overrides")
+}
@@ -0,0 +1,607 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/reputation"
+
+	"github.com/pkg/errors"
+	// Registers the pure Go SQLite driver, no cgo is required
+	_ "modernc.org/sqlite"
+)
+
+var _ Storage = (*SQLite)(nil)
+
+// sqliteSchema mirrors the bbolt buckets, values are stored encoded as JSON the same way.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS first_seen (public_key TEXT PRIMARY KEY, at INTEGER NOT NULL);
+CREATE TABLE IF NOT EXISTS uptime (public_key TEXT PRIMARY KEY, uptime INTEGER NOT NULL);
+CREATE TABLE IF NOT EXISTS pending_tags (
+	pending_chan_id TEXT PRIMARY KEY,
+	public_key TEXT NOT NULL,
+	capacity INTEGER NOT NULL,
+	accepted_at INTEGER NOT NULL,
+	tag TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS pending_tags_public_key ON pending_tags (public_key, capacity);
+CREATE TABLE IF NOT EXISTS tags (channel_point TEXT PRIMARY KEY, tag TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS reputation (public_key TEXT PRIMARY KEY, score TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS reputation_events (id TEXT PRIMARY KEY, at INTEGER NOT NULL);
+CREATE TABLE IF NOT EXISTS history (public_key TEXT PRIMARY KEY, history TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS decisions (
+	at INTEGER NOT NULL,
+	id TEXT NOT NULL,
+	decision TEXT NOT NULL,
+	PRIMARY KEY (at, id)
+);
+CREATE TABLE IF NOT EXISTS webhook_queue (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	event TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS flood_requests (key TEXT NOT NULL, at INTEGER NOT NULL);
+CREATE INDEX IF NOT EXISTS flood_requests_key_at ON flood_requests (key, at);
+CREATE TABLE IF NOT EXISTS flood_blocks (key TEXT PRIMARY KEY, blocked_until INTEGER NOT NULL);
+CREATE TABLE IF NOT EXISTS overrides (public_key TEXT PRIMARY KEY, override TEXT NOT NULL);
+`
+
+// SQLite is a relational database stored in a single file, it can be queried with any SQLite
+// client.
+type SQLite struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens the database located at path, creating it if it doesn't exist.
+func OpenSQLite(path string) (*SQLite, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, errors.Wrap(err, "opening database")
+	}
+	// SQLite allows a single writer, sharing the connection avoids busy errors
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "creating tables")
+	}
+
+	return &SQLite{db: db}, nil
+}
+
+// OpenSQLiteReadOnly opens the database located at path for reading, without creating it or its
+// tables. Unlike bbolt, SQLite lets it be read while acceptLND writes to it.
+func OpenSQLiteReadOnly(path string) (*SQLite, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, errors.Wrap(err, "opening database")
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, errors.Wrap(err, "opening database")
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "opening database")
+	}
+
+	return &SQLite{db: db}, nil
+}
+
+// Close releases the database file.
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+// FirstSeen returns the first time the node requested to open a channel.
+func (s *SQLite) FirstSeen(publicKey string, t time.Time) (time.Time, error) {
+	var at int64
+	err := s.db.QueryRow(`INSERT INTO first_seen (public_key, at) VALUES (?, ?)
+		ON CONFLICT (public_key) DO UPDATE SET at = at RETURNING at`, publicKey, t.UnixNano()).Scan(&at)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "updating first seen time")
+	}
+
+	return time.Unix(0, at), nil
+}
+
+// MaxUptime records the uptime of a channel with the node if it's the longest seen.
+func (s *SQLite) MaxUptime(publicKey string, uptime time.Duration) (time.Duration, error) {
+	var maxUptime int64
+	err := s.db.QueryRow(`INSERT INTO uptime (public_key, uptime) VALUES (?, ?)
+		ON CONFLICT (public_key) DO UPDATE SET uptime = max(uptime, excluded.uptime)
+		RETURNING uptime`, publicKey, int64(uptime)).Scan(&maxUptime)
+	if err != nil {
+		return 0, errors.Wrap(err, "updating channel uptime")
+	}
+
+	return time.Duration(maxUptime), nil
+}
+
+// AddPendingTag stores the tag of a channel whose channel point isn't known yet.
+func (s *SQLite) AddPendingTag(tag Tag) error {
+	v, err := json.Marshal(tag)
+	if err != nil {
+		return errors.Wrap(err, "encoding tag")
+	}
+
+	_, err = s.db.Exec(`INSERT INTO pending_tags (pending_chan_id, public_key, capacity, accepted_at, tag)
+		VALUES (?, ?, ?, ?, ?) ON CONFLICT (pending_chan_id) DO UPDATE SET tag = excluded.tag`,
+		tag.PendingChanID, tag.PublicKey, int64(tag.Capacity), unixNano(tag.AcceptedAt), string(v))
+	return errors.Wrap(err, "storing pending tag")
+}
+
+// TagChannel assigns the oldest matching pending tag to the channel, if it isn't tagged already.
+func (s *SQLite) TagChannel(publicKey, channelPoint string, capacity uint64, openedAt time.Time) (bool, error) {
+	tagged := false
+	err := inTx(s.db, func(tx *sql.Tx) error {
+		var tag string
+		err := tx.QueryRow(`SELECT tag FROM tags WHERE channel_point = ?`, channelPoint).Scan(&tag)
+		if err == nil {
+			return nil
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+
+		err = tx.QueryRow(`DELETE FROM pending_tags WHERE pending_chan_id = (
+			SELECT pending_chan_id FROM pending_tags
+			WHERE public_key = ? AND capacity = ? AND accepted_at <= ?
+			ORDER BY accepted_at, pending_chan_id LIMIT 1
+		) RETURNING tag`, publicKey, int64(capacity), unixNano(openedAt)).Scan(&tag)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`INSERT INTO tags (channel_point, tag) VALUES (?, ?)`, channelPoint, tag); err != nil {
+			return err
+		}
+		tagged = true
+		return nil
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "tagging channel")
+	}
+
+	return tagged, nil
+}
+
+// Tags returns the channels tags indexed by channel point.
+func (s *SQLite) Tags() (map[string]Tag, error) {
+	rows, err := s.db.Query(`SELECT channel_point, tag FROM tags`)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading tags")
+	}
+	defer rows.Close()
+
+	tags := make(map[string]Tag)
+	for rows.Next() {
+		var channelPoint, v string
+		if err := rows.Scan(&channelPoint, &v); err != nil {
+			return nil, errors.Wrap(err, "reading tags")
+		}
+		var tag Tag
+		if err := json.Unmarshal([]byte(v), &tag); err != nil {
+			return nil, errors.Wrap(err, "decoding tag")
+		}
+		tags[channelPoint] = tag
+	}
+
+	return tags, errors.Wrap(rows.Err(), "reading tags")
+}
+
+// AddEvent applies the event to the node reputation score at time t.
+func (s *SQLite) AddEvent(
+	publicKey string,
+	event reputation.Event,
+	t time.Time,
+	halfLife time.Duration,
+) (reputation.Score, error) {
+	var score reputation.Score
+	err := inTx(s.db, func(tx *sql.Tx) error {
+		var err error
+		score, err = addEvent(tx, publicKey, event, t, halfLife)
+		return err
+	})
+	if err != nil {
+		return reputation.Score{}, errors.Wrap(err, "updating reputation")
+	}
+
+	return score, nil
+}
+
+// AddEventOnce is like AddEvent, but the event is applied only the first time its id is seen.
+func (s *SQLite) AddEventOnce(
+	id, publicKey string,
+	event reputation.Event,
+	t time.Time,
+	halfLife time.Duration,
+) (bool, error) {
+	applied := false
+	err := inTx(s.db, func(tx *sql.Tx) error {
+		res, err := tx.Exec(`INSERT INTO reputation_events (id, at) VALUES (?, ?)
+			ON CONFLICT (id) DO NOTHING`, id, t.UnixNano())
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+
+		applied = true
+		_, err = addEvent(tx, publicKey, event, t, halfLife)
+		return err
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "recording reputation event")
+	}
+
+	return applied, nil
+}
+
+func addEvent(
+	tx *sql.Tx,
+	publicKey string,
+	event reputation.Event,
+	t time.Time,
+	halfLife time.Duration,
+) (reputation.Score, error) {
+	score, err := readScore(tx.QueryRow(`SELECT score FROM reputation WHERE public_key = ?`, publicKey))
+	if err != nil {
+		return reputation.Score{}, err
+	}
+
+	score = score.Add(event, t, halfLife)
+	v, err := json.Marshal(score)
+	if err != nil {
+		return reputation.Score{}, err
+	}
+
+	_, err = tx.Exec(`INSERT INTO reputation (public_key, score) VALUES (?, ?)
+		ON CONFLICT (public_key) DO UPDATE SET score = excluded.score`, publicKey, string(v))
+	return score, err
+}
+
+// Score returns the node reputation score.
+func (s *SQLite) Score(publicKey string) (reputation.Score, error) {
+	score, err := readScore(s.db.QueryRow(`SELECT score FROM reputation WHERE public_key = ?`, publicKey))
+	if err != nil {
+		return reputation.Score{}, errors.Wrap(err, "reading reputation")
+	}
+
+	return score, nil
+}
+
+// readScore decodes the score in the row, nodes without one have a zero score.
+func readScore(row *sql.Row) (reputation.Score, error) {
+	var score reputation.Score
+	var v string
+	if err := row.Scan(&v); err != nil {
+		if err == sql.ErrNoRows {
+			return score, nil
+		}
+		return score, err
+	}
+
+	err := json.Unmarshal([]byte(v), &score)
+	return score, err
+}
+
+// CountDecision adds a decision taken on a request of the node to its history.
+func (s *SQLite) CountDecision(publicKey string, accepted bool, t time.Time) error {
+	err := inTx(s.db, func(tx *sql.Tx) error {
+		history, err := readHistory(tx.QueryRow(`SELECT history FROM history WHERE public_key = ?`, publicKey))
+		if err != nil {
+			return err
+		}
+
+		v, err := json.Marshal(history.add(accepted, t))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`INSERT INTO history (public_key, history) VALUES (?, ?)
+			ON CONFLICT (public_key) DO UPDATE SET history = excluded.history`, publicKey, string(v))
+		return err
+	})
+	return errors.Wrap(err, "updating history")
+}
+
+// History returns the counts of the decisions taken on the node requests.
+func (s *SQLite) History(publicKey string) (History, error) {
+	history, err := readHistory(s.db.QueryRow(`SELECT history FROM history WHERE public_key = ?`, publicKey))
+	if err != nil {
+		return History{}, errors.Wrap(err, "reading history")
+	}
+
+	return history, nil
+}
+
+// readHistory decodes the history in the row, nodes without one have an empty history.
+func readHistory(row *sql.Row) (History, error) {
+	var history History
+	var v string
+	if err := row.Scan(&v); err != nil {
+		if err == sql.ErrNoRows {
+			return history, nil
+		}
+		return history, err
+	}
+
+	err := json.Unmarshal([]byte(v), &history)
+	return history, err
+}
+
+// AddDecision records a channel request decision.
+func (s *SQLite) AddDecision(decision Decision) error {
+	v, err := json.Marshal(decision)
+	if err != nil {
+		return errors.Wrap(err, "encoding decision")
+	}
+
+	_, err = s.db.Exec(`INSERT INTO decisions (at, id, decision) VALUES (?, ?, ?)
+		ON CONFLICT (at, id) DO UPDATE SET decision = excluded.decision`,
+		decision.At.UnixNano(), decision.ID, string(v))
+	return errors.Wrap(err, "storing decision")
+}
+
+// Decisions returns the decisions taken since the time received, from oldest to newest.
+func (s *SQLite) Decisions(since time.Time) ([]Decision, error) {
+	rows, err := s.db.Query(`SELECT decision FROM decisions WHERE at >= ? ORDER BY at, id`,
+		unixNano(since))
+	if err != nil {
+		return nil, errors.Wrap(err, "reading decisions")
+	}
+	defer rows.Close()
+
+	var decisions []Decision
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, errors.Wrap(err, "reading decisions")
+		}
+		var decision Decision
+		if err := json.Unmarshal([]byte(v), &decision); err != nil {
+			return nil, errors.Wrap(err, "decoding decision")
+		}
+		decisions = append(decisions, decision)
+	}
+
+	return decisions, errors.Wrap(rows.Err(), "reading decisions")
+}
+
+// PruneDecisions deletes the decisions taken before the time received.
+func (s *SQLite) PruneDecisions(before time.Time) error {
+	_, err := s.db.Exec(`DELETE FROM decisions WHERE at < ?`, unixNano(before))
+	return errors.Wrap(err, "pruning decisions")
+}
+
+// PrunePendingTags deletes the pending tags of the channels accepted before the time received.
+func (s *SQLite) PrunePendingTags(before time.Time) error {
+	_, err := s.db.Exec(`DELETE FROM pending_tags WHERE accepted_at < ?`, unixNano(before))
+	return errors.Wrap(err, "pruning pending tags")
+}
+
+// Enqueue adds an event to the end of the delivery queue.
+func (s *SQLite) Enqueue(event QueuedEvent) error {
+	v, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "encoding event")
+	}
+
+	_, err = s.db.Exec(`INSERT INTO webhook_queue (event) VALUES (?)`, string(v))
+	return errors.Wrap(err, "enqueuing event")
+}
+
+// Queue returns the events waiting to be delivered, from oldest to newest.
+func (s *SQLite) Queue() ([]QueuedEvent, error) {
+	rows, err := s.db.Query(`SELECT id, event FROM webhook_queue ORDER BY id`)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading queue")
+	}
+	defer rows.Close()
+
+	var events []QueuedEvent
+	for rows.Next() {
+		var id uint64
+		var v string
+		if err := rows.Scan(&id, &v); err != nil {
+			return nil, errors.Wrap(err, "reading queue")
+		}
+		event := QueuedEvent{ID: id}
+		if err := json.Unmarshal([]byte(v), &event); err != nil {
+			return nil, errors.Wrap(err, "decoding event")
+		}
+		events = append(events, event)
+	}
+
+	return events, errors.Wrap(rows.Err(), "reading queue")
+}
+
+// UpdateQueuedEvent stores the delivery attempts of an event still in the queue.
+func (s *SQLite) UpdateQueuedEvent(event QueuedEvent) error {
+	v, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "encoding event")
+	}
+
+	_, err = s.db.Exec(`UPDATE webhook_queue SET event = ? WHERE id = ?`, string(v), event.ID)
+	return errors.Wrap(err, "updating queued event")
+}
+
+// Dequeue removes an event from the queue.
+func (s *SQLite) Dequeue(id uint64) error {
+	_, err := s.db.Exec(`DELETE FROM webhook_queue WHERE id = ?`, id)
+	return errors.Wrap(err, "dequeuing event")
+}
+
+// unixNano is like t.UnixNano, but times before the Unix epoch, like the zero time, are clamped
+// to it instead of overflowing.
+func unixNano(t time.Time) int64 {
+	if t.Before(time.Unix(0, 0)) {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// inTx runs fn in a transaction, which is committed if fn succeeds.
+func inTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// CountRequest records a request for the key and returns the number of requests in the window.
+func (s *SQLite) CountRequest(key string, at time.Time, window time.Duration) (int, error) {
+	count := 0
+	err := inTx(s.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM flood_requests WHERE key = ? AND at <= ?`,
+			key, at.Add(-window).UnixNano())
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO flood_requests (key, at) VALUES (?, ?)`, key, at.UnixNano())
+		if err != nil {
+			return err
+		}
+		return tx.QueryRow(`SELECT count(*) FROM flood_requests WHERE key = ?`, key).Scan(&count)
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "counting request")
+	}
+
+	return count, nil
+}
+
+// Block blocks the key until the time received, unless it's already blocked.
+func (s *SQLite) Block(key string, at, until time.Time) (bool, error) {
+	res, err := s.db.Exec(`INSERT INTO flood_blocks (key, blocked_until) VALUES (?, ?)
+		ON CONFLICT (key) DO UPDATE SET blocked_until = excluded.blocked_until
+		WHERE flood_blocks.blocked_until <= ?`, key, until.UnixNano(), at.UnixNano())
+	if err != nil {
+		return false, errors.Wrap(err, "blocking key")
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "blocking key")
+	}
+	return n > 0, nil
+}
+
+// Blocks returns the keys still blocked and when their blocks expire.
+func (s *SQLite) Blocks(at time.Time) (map[string]time.Time, error) {
+	rows, err := s.db.Query(`SELECT key, blocked_until FROM flood_blocks WHERE blocked_until > ?`,
+		at.UnixNano())
+	if err != nil {
+		return nil, errors.Wrap(err, "reading blocks")
+	}
+	defer rows.Close()
+
+	blocks := make(map[string]time.Time)
+	for rows.Next() {
+		var key string
+		var until int64
+		if err := rows.Scan(&key, &until); err != nil {
+			return nil, errors.Wrap(err, "reading blocks")
+		}
+		blocks[key] = time.Unix(0, until)
+	}
+
+	return blocks, errors.Wrap(rows.Err(), "reading blocks")
+}
+
+// PruneRequests deletes the requests recorded and the blocks expired before the time received,
+// of the keys starting with the prefix.
+func (s *SQLite) PruneRequests(prefix string, before time.Time) error {
+	err := inTx(s.db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM flood_requests WHERE key LIKE ? || '%' AND at < ?`,
+			prefix, before.UnixNano()); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM flood_blocks WHERE key LIKE ? || '%' AND blocked_until < ?`,
+			prefix, before.UnixNano())
+		return err
+	})
+	return errors.Wrap(err, "pruning requests")
+}
+
+// SetOverride records the verdict forced on the requests of the node, replacing its previous
+// override.
+func (s *SQLite) SetOverride(override Override) error {
+	v, err := json.Marshal(override)
+	if err != nil {
+		return errors.Wrap(err, "encoding override")
+	}
+
+	_, err = s.db.Exec(`INSERT INTO overrides (public_key, override) VALUES (?, ?)
+		ON CONFLICT (public_key) DO UPDATE SET override = excluded.override`, override.PublicKey, string(v))
+	return errors.Wrap(err, "storing override")
+}
+
+// DeleteOverride removes the override of the node and returns whether it had one.
+func (s *SQLite) DeleteOverride(publicKey string) (bool, error) {
+	res, err := s.db.Exec(`DELETE FROM overrides WHERE public_key = ?`, publicKey)
+	if err != nil {
+		return false, errors.Wrap(err, "deleting override")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "deleting override")
+	}
+
+	return n > 0, nil
+}
+
+// Override returns the override of the node and whether it has one.
+func (s *SQLite) Override(publicKey string) (Override, bool, error) {
+	var v string
+	err := s.db.QueryRow(`SELECT override FROM overrides WHERE public_key = ?`, publicKey).Scan(&v)
+	if err == sql.ErrNoRows {
+		return Override{}, false, nil
+	}
+	if err != nil {
+		return Override{}, false, errors.Wrap(err, "reading override")
+	}
+
+	var override Override
+	if err := json.Unmarshal([]byte(v), &override); err != nil {
+		return Override{}, false, errors.Wrap(err, "decoding override")
+	}
+	return override, true, nil
+}
+
+// Overrides returns the overrides indexed by node public key.
+func (s *SQLite) Overrides() (map[string]Override, error) {
+	rows, err := s.db.Query(`SELECT public_key, override FROM overrides`)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading overrides")
+	}
+	defer rows.Close()
+
+	overrides := make(map[string]Override)
+	for rows.Next() {
+		var publicKey, v string
+		if err := rows.Scan(&publicKey, &v); err != nil {
+			return nil, errors.Wrap(err, "reading overrides")
+		}
+		var override Override
+		if err := json.Unmarshal([]byte(v), &override); err != nil {
+			return nil, errors.Wrap(err, "decoding override")
+		}
+		overrides[publicKey] = override
+	}
+
+	return overrides, errors.Wrap(rows.Err(), "reading overrides")
+}
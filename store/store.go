@@ -0,0 +1,239 @@
+// Package store persists the information acceptLND collects about the channel requests it
+// handles.
+package store
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/reputation"
+
+	"github.com/pkg/errors"
+)
+
+// Storage backends.
+const (
+	BackendBolt     = "bbolt"
+	BackendSQLite   = "sqlite"
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+)
+
+// Storage is the persistence layer shared by all the stateful features.
+type Storage interface {
+	// FirstSeen returns the first time the node requested to open a channel. If it's the first
+	// time, the time received is recorded and returned.
+	FirstSeen(publicKey string, t time.Time) (time.Time, error)
+	// MaxUptime records the uptime of a channel with the node if it's the longest seen, and
+	// returns the longest one.
+	MaxUptime(publicKey string, uptime time.Duration) (time.Duration, error)
+
+	// AddPendingTag stores the tag of a channel that was accepted but whose channel point isn't
+	// known yet, indexed by its pending channel ID.
+	AddPendingTag(tag Tag) error
+	// TagChannel assigns the channel the oldest pending tag of the node with the same capacity
+	// that was accepted before the channel was opened, if the channel isn't tagged already. It
+	// returns whether the channel was tagged.
+	TagChannel(publicKey, channelPoint string, capacity uint64, openedAt time.Time) (bool, error)
+	// PrunePendingTags deletes the pending tags of the channels accepted before the time
+	// received.
+	PrunePendingTags(before time.Time) error
+	// Tags returns the channels tags indexed by channel point.
+	Tags() (map[string]Tag, error)
+
+	// AddEvent applies the event to the node reputation score at time t and returns the new
+	// score.
+	AddEvent(publicKey string, event reputation.Event, t time.Time, halfLife time.Duration) (reputation.Score, error)
+	// AddEventOnce is like AddEvent, but the event is applied only the first time its id is
+	// seen. It returns whether the event was applied.
+	AddEventOnce(id, publicKey string, event reputation.Event, t time.Time, halfLife time.Duration) (bool, error)
+	// Score returns the node reputation score, nodes without events have a zero score.
+	Score(publicKey string) (reputation.Score, error)
+
+	// AddDecision records a channel request decision.
+	AddDecision(decision Decision) error
+	// Decisions returns the decisions taken since the time received, from oldest to newest.
+	Decisions(since time.Time) ([]Decision, error)
+	// PruneDecisions deletes the decisions taken before the time received.
+	PruneDecisions(before time.Time) error
+	// CountDecision adds a decision taken on a request of the node at time t to its history. The
+	// history isn't pruned with the decisions.
+	CountDecision(publicKey string, accepted bool, t time.Time) error
+	// History returns the counts of the decisions taken on the node requests, nodes without
+	// decisions have an empty history.
+	History(publicKey string) (History, error)
+
+	// Enqueue adds an event to the end of the delivery queue.
+	Enqueue(event QueuedEvent) error
+	// Queue returns the events waiting to be delivered, from oldest to newest.
+	Queue() ([]QueuedEvent, error)
+	// UpdateQueuedEvent stores the delivery attempts of an event still in the queue.
+	UpdateQueuedEvent(event QueuedEvent) error
+	// Dequeue removes an event from the queue.
+	Dequeue(id uint64) error
+
+	// CountRequest records a request for the key at the time received and returns the number of
+	// requests for it in the window ending then, so instances sharing the storage count them
+	// together.
+	CountRequest(key string, at time.Time, window time.Duration) (int, error)
+	// Block blocks the key until the time received, unless it's already blocked at the time at.
+	// It returns whether the key was blocked by this call.
+	Block(key string, at, until time.Time) (bool, error)
+	// Blocks returns the keys still blocked at the time received and when their blocks expire.
+	Blocks(at time.Time) (map[string]time.Time, error)
+	// PruneRequests deletes the requests recorded and the blocks expired before the time received,
+	// of the keys starting with the prefix.
+	PruneRequests(prefix string, before time.Time) error
+
+	// SetOverride records the verdict forced by the operator on the requests of the node,
+	// replacing its previous override.
+	SetOverride(override Override) error
+	// DeleteOverride removes the override of the node. It returns whether the node had one.
+	DeleteOverride(publicKey string) (bool, error)
+	// Override returns the override of the node and whether it has one.
+	Override(publicKey string) (Override, bool, error)
+	// Overrides returns the overrides indexed by node public key.
+	Overrides() (map[string]Override, error)
+
+	// Close releases the resources used by the storage.
+	Close() error
+}
+
+// Open returns the storage backend requested, bbolt if it's empty. The path is the connection URL
+// for the postgres backend and it's ignored by the memory one.
+func Open(backend, path string) (Storage, error) {
+	switch backend {
+	case "", BackendBolt:
+		return OpenBolt(path)
+	case BackendSQLite:
+		return OpenSQLite(path)
+	case BackendMemory:
+		return NewMemory(), nil
+	case BackendPostgres:
+		return OpenPostgres(path)
+	default:
+		return nil, errors.Errorf("unknown storage backend %q", backend)
+	}
+}
+
+// OpenReadOnly returns the storage backend requested for the commands that only read it. The
+// databases are neither created nor upgraded and the writes fail, except in the memory backend,
+// which has nothing to protect.
+func OpenReadOnly(backend, path string) (Storage, error) {
+	switch backend {
+	case "", BackendBolt:
+		return OpenBoltReadOnly(path)
+	case BackendSQLite:
+		return OpenSQLiteReadOnly(path)
+	case BackendPostgres:
+		return OpenPostgresReadOnly(path)
+	default:
+		return Open(backend, path)
+	}
+}
+
+// Override is a verdict the operator forces on the requests of a node, regardless of the policies.
+type Override struct {
+	PublicKey string    `json:"public_key"`
+	Accept    bool      `json:"accept"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Tag records which policies accepted a channel.
+type Tag struct {
+	PendingChanID string    `json:"pending_chan_id"`
+	PublicKey     string    `json:"public_key"`
+	Capacity      uint64    `json:"capacity"`
+	Policies      []string  `json:"policies"`
+	Tags          []string  `json:"tags,omitempty"`
+	Reputation    float64   `json:"reputation"`
+	AcceptedAt    time.Time `json:"accepted_at"`
+}
+
+// matches returns whether the tag belongs to a channel with the node of the capacity received
+// opened at openedAt.
+func (t Tag) matches(publicKey string, capacity uint64, openedAt time.Time) bool {
+	return t.PublicKey == publicKey && t.Capacity == capacity && !t.AcceptedAt.After(openedAt)
+}
+
+// older returns whether the tag was accepted before the other one, the pending channel IDs break
+// the ties so the same tag is always chosen.
+func (t Tag) older(other Tag) bool {
+	if t.AcceptedAt.Equal(other.AcceptedAt) {
+		return t.PendingChanID < other.PendingChanID
+	}
+	return t.AcceptedAt.Before(other.AcceptedAt)
+}
+
+// History counts the decisions taken on the requests of a node.
+type History struct {
+	Accepted     uint64    `json:"accepted"`
+	Rejected     uint64    `json:"rejected"`
+	LastDecision time.Time `json:"last_decision"`
+}
+
+// add returns the history with a decision taken at time t counted.
+func (h History) add(accepted bool, t time.Time) History {
+	if accepted {
+		h.Accepted++
+	} else {
+		h.Rejected++
+	}
+	if t.After(h.LastDecision) {
+		h.LastDecision = t
+	}
+	return h
+}
+
+// Decision records how a channel request was handled. Unmanaged decisions record the channels LND
+// accepted with its defaults while acceptLND was not connected instead, identified by their
+// channel point.
+type Decision struct {
+	ID            string      `json:"id"`
+	CorrelationID string      `json:"correlation_id,omitempty"`
+	PublicKey     string      `json:"public_key"`
+	Capacity      uint64      `json:"capacity"`
+	Accepted      bool        `json:"accepted"`
+	Error         string      `json:"error,omitempty"`
+	Policies      []string    `json:"policies,omitempty"`
+	Tags          []string    `json:"tags,omitempty"`
+	Warnings      []string    `json:"warnings,omitempty"`
+	Response      *Response   `json:"response,omitempty"`
+	Experiment    *Experiment `json:"experiment,omitempty"`
+	Backfilled    bool        `json:"backfilled,omitempty"`
+	Unmanaged     bool        `json:"unmanaged,omitempty"`
+	At            time.Time   `json:"at"`
+}
+
+// Experiment records the verdict of the set of policies that wasn't enforced in a request included
+// in an experiment: the experimental one in shadow mode and the regular one in live mode.
+type Experiment struct {
+	Name     string   `json:"name"`
+	Mode     string   `json:"mode"`
+	Accepted bool     `json:"accepted"`
+	Error    string   `json:"error,omitempty"`
+	Policies []string `json:"policies,omitempty"`
+}
+
+// Response contains the channel parameters sent to LND with an accepted request, zero values are
+// left to LND's defaults.
+type Response struct {
+	CSVDelay        uint32 `json:"csv_delay,omitempty"`
+	ReserveSat      uint64 `json:"reserve_sat,omitempty"`
+	InFlightMaxMsat uint64 `json:"in_flight_max_msat,omitempty"`
+	MaxHTLCCount    uint32 `json:"max_htlc_count,omitempty"`
+	MinHTLCIn       uint64 `json:"min_htlc_in,omitempty"`
+	MinAcceptDepth  uint32 `json:"min_accept_depth,omitempty"`
+	ZeroConf        bool   `json:"zero_conf,omitempty"`
+	UpfrontShutdown string `json:"upfront_shutdown,omitempty"`
+}
+
+// QueuedEvent is an event waiting to be delivered.
+type QueuedEvent struct {
+	ID          uint64          `json:"-"`
+	Destination string          `json:"destination,omitempty"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts,omitempty"`
+	NextAttempt time.Time       `json:"next_attempt"`
+}
@@ -0,0 +1,511 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/reputation"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// backends lists the storage implementations, every test runs against all of them.
+var backends = []string{BackendBolt, BackendSQLite, BackendMemory, BackendPostgres}
+
+// postgresURLEnv is the environment variable with the URL of the database used to test the
+// postgres backend, its tests are skipped if it's not set. The tables are dropped by every test.
+const postgresURLEnv = "ACCEPTLND_TEST_POSTGRES_URL"
+
+// forEachBackend runs fn in a subtest for each backend with a database in a temporary directory.
+func forEachBackend(t *testing.T, fn func(t *testing.T, backend, path string)) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			if backend == BackendPostgres {
+				fn(t, backend, resetPostgres(t))
+				return
+			}
+			fn(t, backend, filepath.Join(t.TempDir(), "acceptlnd.db"))
+		})
+	}
+}
+
+func resetPostgres(t *testing.T) string {
+	t.Helper()
+
+	url := os.Getenv(postgresURLEnv)
+	if url == "" {
+		t.Skipf("%s not set", postgresURLEnv)
+	}
+
+	db, err := OpenPostgres(url)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.db.Exec(`DROP TABLE first_seen, uptime, pending_tags, tags, reputation,
+		reputation_events, history, decisions, webhook_queue, flood_requests, flood_blocks, overrides`)
+	assert.NoError(t, err)
+	return url
+}
+
+func TestFirstSeen(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend, path string) {
+		db, err := Open(backend, path)
+		assert.NoError(t, err)
+
+		first := time.Unix(1_700_000_000, 0)
+		firstSeen, err := db.FirstSeen("public_key", first)
+		assert.NoError(t, err)
+		assert.True(t, first.Equal(firstSeen))
+
+		firstSeen, err = db.FirstSeen("public_key", first.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.True(t, first.Equal(firstSeen))
+
+		other := first.Add(2 * time.Hour)
+		firstSeen, err = db.FirstSeen("other_public_key", other)
+		assert.NoError(t, err)
+		assert.True(t, other.Equal(firstSeen))
+
+		// Values must survive reopening the database, except in memory
+		assert.NoError(t, db.Close())
+		if backend == BackendMemory {
+			return
+		}
+		db, err = Open(backend, path)
+		assert.NoError(t, err)
+		defer db.Close()
+
+		firstSeen, err = db.FirstSeen("public_key", time.Now())
+		assert.NoError(t, err)
+		assert.True(t, first.Equal(firstSeen))
+	})
+}
+
+func TestMaxUptime(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend, path string) {
+		db, err := Open(backend, path)
+		assert.NoError(t, err)
+		defer db.Close()
+
+		uptime, err := db.MaxUptime("public_key", time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Hour, uptime)
+
+		uptime, err = db.MaxUptime("public_key", time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Hour, uptime)
+
+		uptime, err = db.MaxUptime("public_key", 2*time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, 2*time.Hour, uptime)
+
+		uptime, err = db.MaxUptime("other_public_key", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Duration(0), uptime)
+	})
+}
+
+func TestTagChannel(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend, path string) {
+		db, err := Open(backend, path)
+		assert.NoError(t, err)
+		defer db.Close()
+
+		now := time.Unix(1_700_000_000, 0).UTC()
+		tagged, err := db.TagChannel("public_key", "txid:0", 1_000_000, now)
+		assert.NoError(t, err)
+		assert.False(t, tagged)
+
+		// Two channels of the same peer, accepted by different policies
+		small := Tag{
+			PendingChanID: "01",
+			PublicKey:     "public_key",
+			Capacity:      1_000_000,
+			Policies:      []string{"#0", "routing"},
+			AcceptedAt:    now,
+		}
+		large := Tag{
+			PendingChanID: "02",
+			PublicKey:     "public_key",
+			Capacity:      5_000_000,
+			Policies:      []string{"#1"},
+			Tags:          []string{"large"},
+			AcceptedAt:    now.Add(time.Minute),
+		}
+		later := Tag{
+			PendingChanID: "03",
+			PublicKey:     "public_key",
+			Capacity:      1_000_000,
+			Policies:      []string{"#2"},
+			AcceptedAt:    now.Add(time.Hour),
+		}
+		assert.NoError(t, db.AddPendingTag(small))
+		assert.NoError(t, db.AddPendingTag(large))
+		assert.NoError(t, db.AddPendingTag(later))
+
+		tagged, err = db.TagChannel("other_public_key", "txid:1", 1_000_000, now.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.False(t, tagged)
+
+		// The large channel confirms first, it must not take the tag of the small one
+		tagged, err = db.TagChannel("public_key", "txid:2", 5_000_000, now.Add(10*time.Minute))
+		assert.NoError(t, err)
+		assert.True(t, tagged)
+
+		// A channel opened before the request was accepted doesn't belong to it
+		tagged, err = db.TagChannel("public_key", "txid:3", 5_000_000, now.Add(2*time.Hour))
+		assert.NoError(t, err)
+		assert.False(t, tagged)
+
+		// The oldest of the tags of the same capacity is assigned first
+		tagged, err = db.TagChannel("public_key", "txid:4", 1_000_000, now.Add(2*time.Hour))
+		assert.NoError(t, err)
+		assert.True(t, tagged)
+
+		// Tagged channels keep their tag
+		tagged, err = db.TagChannel("public_key", "txid:4", 1_000_000, now.Add(2*time.Hour))
+		assert.NoError(t, err)
+		assert.False(t, tagged)
+
+		// The tag accepted later doesn't match a channel opened before it
+		tagged, err = db.TagChannel("public_key", "txid:5", 1_000_000, now.Add(30*time.Minute))
+		assert.NoError(t, err)
+		assert.False(t, tagged)
+
+		tagged, err = db.TagChannel("public_key", "txid:5", 1_000_000, now.Add(2*time.Hour))
+		assert.NoError(t, err)
+		assert.True(t, tagged)
+
+		tags, err := db.Tags()
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]Tag{"txid:2": large, "txid:4": small, "txid:5": later}, tags)
+	})
+}
+
+func TestPrunePendingTags(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend, path string) {
+		db, err := Open(backend, path)
+		assert.NoError(t, err)
+		defer db.Close()
+
+		now := time.Unix(1_700_000_000, 0).UTC()
+		for i, at := range []time.Time{now.Add(-time.Hour), now} {
+			tag := Tag{
+				PendingChanID: fmt.Sprint(i),
+				PublicKey:     "public_key",
+				Capacity:      1_000_000,
+				AcceptedAt:    at,
+			}
+			assert.NoError(t, db.AddPendingTag(tag))
+		}
+
+		assert.NoError(t, db.PrunePendingTags(now))
+
+		tagged, err := db.TagChannel("public_key", "txid:0", 1_000_000, now)
+		assert.NoError(t, err)
+		assert.True(t, tagged)
+
+		tags, err := db.Tags()
+		assert.NoError(t, err)
+		assert.Equal(t, "1", tags["txid:0"].PendingChanID)
+
+		tagged, err = db.TagChannel("public_key", "txid:1", 1_000_000, now)
+		assert.NoError(t, err)
+		assert.False(t, tagged)
+	})
+}
+
+func TestReputation(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend, path string) {
+		db, err := Open(backend, path)
+		assert.NoError(t, err)
+		defer db.Close()
+
+		now := time.Unix(1_700_000_000, 0)
+		halfLife := time.Hour
+
+		score, err := db.Score("public_key")
+		assert.NoError(t, err)
+		assert.Zero(t, score)
+
+		score, err = db.AddEvent("public_key", reputation.ForceClosed, now, halfLife)
+		assert.NoError(t, err)
+		assert.Equal(t, -10.0, score.Value)
+
+		applied, err := db.AddEventOnce("routed:1", "public_key", reputation.Routed, now.Add(halfLife), halfLife)
+		assert.NoError(t, err)
+		assert.True(t, applied)
+
+		applied, err = db.AddEventOnce("routed:1", "public_key", reputation.Routed, now.Add(halfLife), halfLife)
+		assert.NoError(t, err)
+		assert.False(t, applied)
+
+		score, err = db.Score("public_key")
+		assert.NoError(t, err)
+		assert.Equal(t, -3.0, score.Value)
+		assert.True(t, now.Add(halfLife).Equal(score.UpdatedAt))
+	})
+}
+
+func TestDecisions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend, path string) {
+		db, err := Open(backend, path)
+		assert.NoError(t, err)
+		defer db.Close()
+
+		start := time.Unix(1_700_000_000, 0).UTC()
+		for i, id := range []string{"c", "a", "b"} {
+			decision := Decision{ID: id, Capacity: uint64(i), At: start.Add(time.Duration(i) * time.Hour)}
+			assert.NoError(t, db.AddDecision(decision))
+		}
+
+		decisions, err := db.Decisions(time.Time{})
+		assert.NoError(t, err)
+		assert.Len(t, decisions, 3)
+		assert.Equal(t, "c", decisions[0].ID)
+		assert.Equal(t, "b", decisions[2].ID)
+
+		decisions, err = db.Decisions(start.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Len(t, decisions, 2)
+		assert.Equal(t, "a", decisions[0].ID)
+
+		assert.NoError(t, db.PruneDecisions(start.Add(2*time.Hour)))
+		decisions, err = db.Decisions(time.Time{})
+		assert.NoError(t, err)
+		assert.Len(t, decisions, 1)
+		assert.Equal(t, "b", decisions[0].ID)
+		assert.True(t, start.Add(2*time.Hour).Equal(decisions[0].At))
+	})
+}
+
+func TestHistory(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend, path string) {
+		db, err := Open(backend, path)
+		assert.NoError(t, err)
+		defer db.Close()
+
+		history, err := db.History("public_key")
+		assert.NoError(t, err)
+		assert.Equal(t, History{}, history)
+
+		start := time.Unix(1_700_000_000, 0).UTC()
+		assert.NoError(t, db.CountDecision("public_key", false, start))
+		assert.NoError(t, db.CountDecision("public_key", true, start.Add(2*time.Hour)))
+		// Decisions recorded out of order keep the latest time
+		assert.NoError(t, db.CountDecision("public_key", false, start.Add(time.Hour)))
+		assert.NoError(t, db.CountDecision("other", true, start))
+
+		history, err = db.History("public_key")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), history.Accepted)
+		assert.Equal(t, uint64(2), history.Rejected)
+		assert.True(t, start.Add(2*time.Hour).Equal(history.LastDecision))
+	})
+}
+
+func TestOverrides(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend, path string) {
+		db, err := Open(backend, path)
+		assert.NoError(t, err)
+		defer db.Close()
+
+		overrides, err := db.Overrides()
+		assert.NoError(t, err)
+		assert.Empty(t, overrides)
+
+		now := time.Unix(1_700_000_000, 0).UTC()
+		accept := Override{PublicKey: "public_key", Accept: true, Reason: "partner", CreatedAt: now}
+		reject := Override{PublicKey: "other", CreatedAt: now}
+		assert.NoError(t, db.SetOverride(accept))
+		assert.NoError(t, db.SetOverride(reject))
+
+		// Setting it again replaces the previous one
+		accept.Accept = false
+		accept.CreatedAt = now.Add(time.Hour)
+		assert.NoError(t, db.SetOverride(accept))
+
+		overrides, err = db.Overrides()
+		assert.NoError(t, err)
+		assert.Len(t, overrides, 2)
+		assert.False(t, overrides["public_key"].Accept)
+		assert.Equal(t, "partner", overrides["public_key"].Reason)
+		assert.True(t, accept.CreatedAt.Equal(overrides["public_key"].CreatedAt))
+		assert.Equal(t, "other", overrides["other"].PublicKey)
+
+		override, ok, err := db.Override("public_key")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "partner", override.Reason)
+
+		_, ok, err = db.Override("unknown")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		deleted, err := db.DeleteOverride("public_key")
+		assert.NoError(t, err)
+		assert.True(t, deleted)
+
+		deleted, err = db.DeleteOverride("public_key")
+		assert.NoError(t, err)
+		assert.False(t, deleted)
+
+		overrides, err = db.Overrides()
+		assert.NoError(t, err)
+		assert.Len(t, overrides, 1)
+		assert.Contains(t, overrides, "other")
+	})
+}
+
+func TestQueue(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend, path string) {
+		db, err := Open(backend, path)
+		assert.NoError(t, err)
+		defer db.Close()
+
+		now := time.Unix(1_700_000_000, 0).UTC()
+		for i, payload := range []string{`{"id":"a"}`, `{"id":"b"}`, `{"id":"c"}`} {
+			event := QueuedEvent{Payload: []byte(payload), NextAttempt: now}
+			if i == 2 {
+				event.Destination = "telegram"
+			}
+			assert.NoError(t, db.Enqueue(event))
+		}
+
+		events, err := db.Queue()
+		assert.NoError(t, err)
+		assert.Len(t, events, 3)
+		assert.JSONEq(t, `{"id":"a"}`, string(events[0].Payload))
+		assert.Empty(t, events[0].Destination)
+		assert.Equal(t, "telegram", events[2].Destination)
+
+		events[1].Attempts = 2
+		events[1].NextAttempt = now.Add(time.Minute)
+		assert.NoError(t, db.UpdateQueuedEvent(events[1]))
+		assert.NoError(t, db.Dequeue(events[0].ID))
+		// Updating a delivered event doesn't add it back
+		assert.NoError(t, db.UpdateQueuedEvent(events[0]))
+
+		events, err = db.Queue()
+		assert.NoError(t, err)
+		assert.Len(t, events, 2)
+		assert.Equal(t, 2, events[0].Attempts)
+		assert.True(t, now.Add(time.Minute).Equal(events[0].NextAttempt))
+		assert.JSONEq(t, `{"id":"c"}`, string(events[1].Payload))
+	})
+}
+
+func TestOpenInvalidPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "acceptlnd.db")
+	for _, backend := range []string{BackendBolt, BackendSQLite} {
+		_, err := Open(backend, path)
+		assert.Error(t, err, backend)
+	}
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend, path string) {
+		if backend == BackendBolt || backend == BackendSQLite {
+			_, err := OpenReadOnly(backend, path)
+			assert.Error(t, err, "the database must exist")
+		}
+
+		db, err := Open(backend, path)
+		assert.NoError(t, err)
+		decision := Decision{ID: "1", PublicKey: "public_key", At: time.Now()}
+		assert.NoError(t, db.AddDecision(decision))
+
+		if backend == BackendBolt {
+			_, err := OpenReadOnly(backend, path)
+			assert.ErrorContains(t, err, "locked by another process")
+		}
+		if backend == BackendSQLite {
+			// SQLite databases can be read while they are open for writing
+			reader, err := OpenReadOnly(backend, path)
+			assert.NoError(t, err)
+			decisions, err := reader.Decisions(time.Time{})
+			assert.NoError(t, err)
+			assert.Len(t, decisions, 1)
+			assert.NoError(t, reader.Close())
+		}
+		assert.NoError(t, db.Close())
+
+		db, err = OpenReadOnly(backend, path)
+		assert.NoError(t, err)
+		defer db.Close()
+
+		decisions, err := db.Decisions(time.Time{})
+		assert.NoError(t, err)
+		if backend != BackendMemory {
+			assert.Len(t, decisions, 1)
+			assert.Error(t, db.AddDecision(Decision{ID: "2", At: time.Now()}), "writes must fail")
+		}
+	})
+}
+
+func TestOpenUnknownBackend(t *testing.T) {
+	_, err := Open("mysql", filepath.Join(t.TempDir(), "acceptlnd.db"))
+	assert.EqualError(t, err, "unknown storage backend \"mysql\"")
+}
+
+func TestFloodCounters(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend, path string) {
+		db, err := Open(backend, path)
+		assert.NoError(t, err)
+		defer db.Close()
+
+		now := time.Unix(1_700_000_000, 0)
+		window := time.Minute
+		for i := range 3 {
+			count, err := db.CountRequest("node:a", now.Add(time.Duration(i)*time.Second), window)
+			assert.NoError(t, err)
+			assert.Equal(t, i+1, count)
+		}
+
+		// The first requests fall out of the window
+		count, err := db.CountRequest("node:a", now.Add(window+time.Second), window)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		count, err = db.CountRequest("node:b", now, window)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+
+		blocked, err := db.Block("node:a", now, now.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.True(t, blocked)
+
+		// Already blocked
+		blocked, err = db.Block("node:a", now.Add(time.Minute), now.Add(2*time.Hour))
+		assert.NoError(t, err)
+		assert.False(t, blocked)
+
+		blocks, err := db.Blocks(now.Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Len(t, blocks, 1)
+		assert.True(t, now.Add(time.Hour).Equal(blocks["node:a"]))
+
+		// The block expired
+		blocked, err = db.Block("node:a", now.Add(time.Hour), now.Add(2*time.Hour))
+		assert.NoError(t, err)
+		assert.True(t, blocked)
+
+		blocked, err = db.Block("auto:a", now, now.Add(2*time.Hour))
+		assert.NoError(t, err)
+		assert.True(t, blocked)
+
+		assert.NoError(t, db.PruneRequests("node:", now.Add(3*time.Hour)))
+		blocks, err = db.Blocks(now)
+		assert.NoError(t, err)
+		// Only the keys with the prefix are pruned
+		assert.Len(t, blocks, 1)
+		assert.True(t, now.Add(2*time.Hour).Equal(blocks["auto:a"]))
+
+		count, err = db.CountRequest("node:a", now.Add(3*time.Hour), window)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+}
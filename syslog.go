@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/syslog"
+)
+
+// syslogHandler sends the decisions logged to syslog, along with passing them and the rest of the
+// records to the wrapped handler.
+type syslogHandler struct {
+	slog.Handler
+	writer *syslog.Writer
+}
+
+func (h syslogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Message == decisionMessage {
+		if err := h.writer.Write(decisionSeverity(record), syslogMessage(ctx, record)); err != nil {
+			failure := slog.NewRecord(time.Now(), slog.LevelWarn, "Sending decision to syslog", 0)
+			failure.AddAttrs(slog.Any("error", err))
+			_ = h.Handler.Handle(ctx, failure)
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return syslogHandler{h.Handler.WithAttrs(attrs), h.writer}
+}
+
+func (h syslogHandler) WithGroup(name string) slog.Handler {
+	return syslogHandler{h.Handler.WithGroup(name), h.writer}
+}
+
+// decisionSeverity returns the severity of the decision, rejections are notices.
+func decisionSeverity(record slog.Record) syslog.Severity {
+	severity := syslog.SeverityInfo
+	record.Attrs(func(a slog.Attr) bool {
+		if a.Key == "accepted" {
+			if !a.Value.Bool() {
+				severity = syslog.SeverityNotice
+			}
+			return false
+		}
+		return true
+	})
+	return severity
+}
+
+// syslogMessage returns the attributes of the decision in the logfmt format the log lines use,
+// the time and level are already part of the syslog message.
+func syslogMessage(ctx context.Context, record slog.Record) string {
+	var buf bytes.Buffer
+	handler := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey) {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	_ = correlationHandler{handler}.Handle(ctx, record)
+	return buf.String()
+}
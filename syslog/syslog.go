@@ -0,0 +1,172 @@
+// Package syslog sends messages to the local syslog daemon, in the traditional format its socket
+// expects, or to a remote server following RFC 5424.
+package syslog
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+
+	"github.com/pkg/errors"
+)
+
+// Defaults used when the values are not configured.
+const (
+	DefaultFacility = "daemon"
+	DefaultTag      = "acceptlnd"
+)
+
+// dialTimeout is the time waited for the connection to the server to be established.
+const dialTimeout = 5 * time.Second
+
+// Severity of a message.
+type Severity int
+
+// Severities used by acceptLND.
+const (
+	SeverityWarning Severity = 4
+	SeverityNotice  Severity = 5
+	SeverityInfo    Severity = 6
+)
+
+// facilities maps the names of the facilities to their codes.
+var facilities = map[string]int{
+	"user":   1,
+	"daemon": 3,
+	"local0": 16,
+	"local1": 17,
+	"local2": 18,
+	"local3": 19,
+	"local4": 20,
+	"local5": 21,
+	"local6": 22,
+	"local7": 23,
+}
+
+// localPaths are the sockets the local daemon listens on in the supported platforms.
+var localPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// Writer sends messages to syslog, reconnecting if the connection is lost.
+type Writer struct {
+	network  string
+	address  string
+	facility int
+	tag      string
+	hostname string
+	pid      int
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// Dial connects to the local daemon or to the remote server configured.
+func Dial(config config.Syslog) (*Writer, error) {
+	facility := config.Facility
+	if facility == "" {
+		facility = DefaultFacility
+	}
+	code, ok := facilities[facility]
+	if !ok {
+		return nil, errors.Errorf("unknown facility %q", facility)
+	}
+
+	w := &Writer{
+		network:  config.Network,
+		address:  config.Address,
+		facility: code,
+		tag:      config.Tag,
+		pid:      os.Getpid(),
+	}
+	if w.tag == "" {
+		w.tag = DefaultTag
+	}
+	w.hostname, _ = os.Hostname()
+	if w.hostname == "" {
+		w.hostname = "-"
+	}
+
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) connect() error {
+	if w.network != "" {
+		conn, err := net.DialTimeout(w.network, w.address, dialTimeout)
+		if err != nil {
+			return errors.Wrap(err, "connecting to the syslog server")
+		}
+		w.conn = conn
+		return nil
+	}
+
+	for _, path := range localPaths {
+		for _, network := range []string{"unixgram", "unix"} {
+			conn, err := net.DialTimeout(network, path, dialTimeout)
+			if err == nil {
+				w.conn = conn
+				return nil
+			}
+		}
+	}
+	return errors.New("no local syslog daemon found")
+}
+
+// Write sends the message with the severity received. If it fails, the connection is established
+// again and the message sent once more.
+func (w *Writer) Write(severity Severity, message string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	line := w.format(severity, message, time.Now())
+	if w.conn != nil {
+		if _, err := w.conn.Write(line); err == nil {
+			return nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+
+	if err := w.connect(); err != nil {
+		return err
+	}
+	_, err := w.conn.Write(line)
+	return errors.Wrap(err, "writing to syslog")
+}
+
+// format returns the message framed for the connection: the traditional format for the local
+// daemon, RFC 5424 for the remote servers, prefixed by its length over TCP (RFC 6587).
+func (w *Writer) format(severity Severity, message string, t time.Time) []byte {
+	priority := w.facility*8 + int(severity)
+	message = strings.TrimRight(message, "\n")
+
+	if w.network == "" {
+		return []byte(fmt.Sprintf("<%d>%s %s[%d]: %s\n", priority, t.Format(time.Stamp), w.tag, w.pid, message))
+	}
+
+	line := fmt.Sprintf("<%d>1 %s %s %s %d - - %s",
+		priority, t.UTC().Format(time.RFC3339Nano), w.hostname, w.tag, w.pid, message)
+	if w.network == "tcp" {
+		line = fmt.Sprintf("%d %s", len(line), line)
+	}
+	return []byte(line)
+}
+
+// Close closes the connection.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
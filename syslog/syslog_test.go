@@ -0,0 +1,116 @@
+package syslog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	w, err := Dial(config.Syslog{Network: "udp", Address: conn.LocalAddr().String(), Facility: "local3"})
+	assert.NoError(t, err)
+	defer w.Close()
+
+	assert.NoError(t, w.Write(SeverityInfo, "accepted=true id=abc\n"))
+
+	buf := make([]byte, 1024)
+	assert.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+
+	// local3 (19) * 8 + info (6)
+	expected := regexp.MustCompile(`^<158>1 \S+Z \S+ acceptlnd ` + strconv.Itoa(os.Getpid()) + ` - - accepted=true id=abc$`)
+	assert.Regexp(t, expected, string(buf[:n]))
+}
+
+func TestWriteTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	lines := make(chan string, 2)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			length, err := reader.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(length[:len(length)-1])
+			buf := make([]byte, n)
+			if _, err := io.ReadFull(reader, buf); err != nil {
+				return
+			}
+			lines <- string(buf)
+		}
+	}()
+
+	w, err := Dial(config.Syslog{Network: "tcp", Address: listener.Addr().String(), Tag: "node"})
+	assert.NoError(t, err)
+	defer w.Close()
+
+	assert.NoError(t, w.Write(SeverityNotice, "accepted=false"))
+	select {
+	case line := <-lines:
+		// daemon (3) * 8 + notice (5)
+		assert.Regexp(t, `^<29>1 \S+ \S+ node \d+ - - accepted=false$`, line)
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not received")
+	}
+}
+
+func TestWriteLocal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	conn, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Skip("unix sockets not supported")
+	}
+	defer conn.Close()
+
+	previous := localPaths
+	localPaths = []string{filepath.Join(t.TempDir(), "missing"), path}
+	defer func() { localPaths = previous }()
+
+	w, err := Dial(config.Syslog{})
+	assert.NoError(t, err)
+	defer w.Close()
+
+	assert.NoError(t, w.Write(SeverityWarning, "accepted=false"))
+
+	buf := make([]byte, 1024)
+	assert.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	// daemon (3) * 8 + warning (4)
+	assert.Regexp(t, `^<28>\w{3} [ \d]\d \d\d:\d\d:\d\d acceptlnd\[\d+\]: accepted=false\n$`, string(buf[:n]))
+}
+
+func TestDialErrors(t *testing.T) {
+	_, err := Dial(config.Syslog{Facility: "kern"})
+	assert.Error(t, err)
+
+	previous := localPaths
+	localPaths = []string{filepath.Join(t.TempDir(), "missing")}
+	defer func() { localPaths = previous }()
+	_, err = Dial(config.Syslog{})
+	assert.Error(t, err)
+}
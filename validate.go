@@ -0,0 +1,301 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/policy"
+	"github.com/aftermath2/acceptlnd/registry"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Synthetic graph the policy tests are evaluated in.
+const (
+	testNodePublicKey = "03" + "1111111111111111111111111111111111111111111111111111111111111111"
+	testPeerPublicKey = "02" + "2222222222222222222222222222222222222222222222222222222222222222"
+	testBlockHeight   = 850_000
+)
+
+// runValidate validates the configuration and, optionally, evaluates the policy tests against the
+// policies.
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	configPath := fs.String("config", "acceptlnd.yml", "Path to the configuration file")
+	policiesOnly := fs.Bool("policies-only", false, "Validate only the policies, skipping the LND connection options")
+	runTests := fs.Bool("run-tests", false, "Evaluate the tests of the configuration against the policies")
+	testsPath := fs.String("tests", "", "File with more tests to evaluate, under the tests key, implies -run-tests")
+	peerPath := fs.String("peer-json", "", "Path to the node information in JSON format (lncli getnodeinfo --include_channels), used as the peer of the tests that don't set node_info")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	load := config.Load
+	if *policiesOnly {
+		load = config.LoadPolicies
+	}
+	cfg, err := load(*configPath)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Configuration is valid")
+
+	if !*runTests && *testsPath == "" {
+		return nil
+	}
+
+	tests := cfg.Tests
+	if *testsPath != "" {
+		more, err := config.LoadTests(*testsPath)
+		if err != nil {
+			return err
+		}
+		tests = append(tests, more...)
+	}
+	if len(tests) == 0 {
+		return errors.New("there are no tests to run")
+	}
+
+	now := time.Now()
+	known := registry.New(cfg.Registry)
+	failed := 0
+	for _, test := range tests {
+		if test.Peer.NodeInfo == "" {
+			test.Peer.NodeInfo = *peerPath
+		}
+		if err := runPolicyTest(cfg.Policies, known, test, now); err != nil {
+			fmt.Printf("FAIL %s: %v\n", test.Name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("PASS %s\n", test.Name)
+	}
+
+	if failed > 0 {
+		return errors.Errorf("%d of %d tests failed", failed, len(tests))
+	}
+	fmt.Printf("All %d tests passed\n", len(tests))
+	return nil
+}
+
+// runPolicyTest evaluates the test request and returns an error describing how the decision
+// differs from the expected one.
+func runPolicyTest(
+	policies []*policy.Policy,
+	known *registry.Registry,
+	test config.PolicyTest,
+	now time.Time,
+) error {
+	peer := testPeer(test.Peer, now)
+	if test.Peer.NodeInfo != "" {
+		var err error
+		peer, err = loadNodeInfo(test.Peer.NodeInfo)
+		if err != nil {
+			return err
+		}
+	}
+
+	req := testRequest(test, peer)
+	resp := &lnrpc.ChannelAcceptResponse{PendingChanId: req.PendingChanId}
+	node := &lnrpc.GetInfoResponse{IdentityPubkey: testNodePublicKey, BlockHeight: testBlockHeight}
+	facts := testFacts(test.Peer, now)
+
+	var (
+		decision decision
+		err      error
+	)
+	if peer.Node == nil {
+		// Like the acceptor, which can't evaluate the policies without the node information
+		err = errors.New("Internal server error")
+	} else {
+		facts.KnownAs = known.Tags(peer.Node.PubKey)
+		decision, err = evaluatePolicies(policies, req, resp, node, peer, facts)
+	}
+	expect := test.Expect
+
+	if expect.Accept && err != nil {
+		return errors.Errorf("expected the request to be accepted, rejected with %q", err)
+	}
+	if !expect.Accept && err == nil {
+		return errors.New("expected the request to be rejected, it was accepted")
+	}
+	if expect.Reason != "" && !strings.Contains(err.Error(), expect.Reason) {
+		return errors.Errorf("expected the reason to contain %q, got %q", expect.Reason, err)
+	}
+
+	if expect.Policy != "" {
+		applied := decision.policies
+		if err != nil {
+			// The policy that rejected the request is the last one applied
+			applied = applied[len(applied)-1:]
+		}
+		if !slices.Contains(applied, expect.Policy) {
+			return errors.Errorf("expected policy %s to decide the request, applied: %s",
+				expect.Policy, strings.Join(decision.policies, ", "))
+		}
+	}
+
+	return nil
+}
+
+// testRequest returns the channel request of the test, the parameters not set take the values
+// LND usually sends.
+func testRequest(test config.PolicyTest, peer *lnrpc.NodeInfo) *lnrpc.ChannelAcceptRequest {
+	r := test.Request
+	publicKey, _ := hex.DecodeString(testPublicKey(test.Peer))
+	if peer.Node.GetPubKey() != "" {
+		publicKey, _ = hex.DecodeString(peer.Node.PubKey)
+	}
+	pendingChanID := sha256.Sum256([]byte(test.Name))
+
+	req := &lnrpc.ChannelAcceptRequest{
+		NodePubkey:       publicKey,
+		PendingChanId:    pendingChanID[:],
+		FundingAmt:       r.Capacity,
+		PushAmt:          r.PushAmount * 1000,
+		DustLimit:        r.DustLimit,
+		MaxValueInFlight: r.MaxValueInFlight,
+		ChannelReserve:   r.ChannelReserve,
+		MinHtlc:          r.MinHTLC,
+		CsvDelay:         r.CSVDelay,
+		MaxAcceptedHtlcs: r.MaxAcceptedHTLCs,
+		CommitmentType:   r.CommitmentType,
+		WantsZeroConf:    r.ZeroConf,
+		WantsScidAlias:   r.ScidAlias,
+	}
+	if !r.Private {
+		req.ChannelFlags = uint32(lnwire.FFAnnounceChannel)
+	}
+
+	if req.DustLimit == 0 {
+		req.DustLimit = 354
+	}
+	if req.MaxValueInFlight == 0 {
+		req.MaxValueInFlight = r.Capacity * 1000
+	}
+	if req.ChannelReserve == 0 {
+		req.ChannelReserve = r.Capacity / 100
+	}
+	if req.MinHtlc == 0 {
+		req.MinHtlc = 1000
+	}
+	if req.CsvDelay == 0 {
+		req.CsvDelay = 144
+	}
+	if req.MaxAcceptedHtlcs == 0 {
+		req.MaxAcceptedHtlcs = policy.MaxHTLCCount
+	}
+	if req.CommitmentType == lnrpc.CommitmentType_UNKNOWN_COMMITMENT_TYPE {
+		req.CommitmentType = lnrpc.CommitmentType_ANCHORS
+	}
+	return req
+}
+
+// testPeer returns the graph information of the test peer. Its policies and announcement are
+// updated at the time of the test unless they say otherwise.
+func testPeer(p config.TestPeer, now time.Time) *lnrpc.NodeInfo {
+	publicKey := testPublicKey(p)
+	info := &lnrpc.NodeInfo{
+		Node: &lnrpc.LightningNode{
+			PubKey:     publicKey,
+			Alias:      p.Alias,
+			LastUpdate: uint32(now.Unix()),
+			Features:   make(map[uint32]*lnrpc.Feature, len(p.FeatureFlags)),
+		},
+		NumChannels: uint32(len(p.Channels)),
+	}
+	for _, flag := range p.FeatureFlags {
+		info.Node.Features[flag] = &lnrpc.Feature{IsKnown: true}
+	}
+	for _, address := range p.Addresses {
+		info.Node.Addresses = append(info.Node.Addresses, &lnrpc.NodeAddress{Network: "tcp", Addr: address})
+	}
+
+	for i, c := range p.Channels {
+		partner := c.Partner
+		switch partner {
+		case "":
+			partner = fmt.Sprintf("02%064x", i+1)
+		case "self":
+			partner = testNodePublicKey
+		}
+
+		height := uint64(testBlockHeight - min(c.Age, testBlockHeight))
+		info.Channels = append(info.Channels, &lnrpc.ChannelEdge{
+			ChannelId: height<<40 | uint64(i),
+			ChanPoint: fmt.Sprintf("%064x:0", i+1),
+			Capacity:  c.Capacity,
+			Node1Pub:  publicKey,
+			Node2Pub:  partner,
+			Node1Policy: &lnrpc.RoutingPolicy{
+				TimeLockDelta:    c.TimeLockDelta,
+				MinHtlc:          c.MinHTLC,
+				MaxHtlcMsat:      c.MaxHTLC,
+				FeeBaseMsat:      c.BaseFee,
+				FeeRateMilliMsat: c.FeeRate,
+				Disabled:         c.Disabled,
+				LastUpdate:       uint32(now.Unix()) - c.LastUpdateDiff,
+			},
+			Node2Policy: &lnrpc.RoutingPolicy{LastUpdate: uint32(now.Unix())},
+		})
+		info.TotalCapacity += c.Capacity
+	}
+
+	return info
+}
+
+// loadNodeInfo reads the information of a peer encoded in JSON, like the output of
+// `lncli getnodeinfo --include_channels`. Missing fields, like the channel policies, are left nil.
+func loadNodeInfo(path string) (*lnrpc.NodeInfo, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading node information file")
+	}
+
+	info := &lnrpc.NodeInfo{}
+	opts := protojson.UnmarshalOptions{DiscardUnknown: true}
+	if err := opts.Unmarshal(content, info); err != nil {
+		return nil, errors.Wrap(err, "decoding node information")
+	}
+	return info, nil
+}
+
+func testFacts(p config.TestPeer, now time.Time) *policy.Facts {
+	facts := &policy.Facts{
+		Now:             now,
+		Peers:           make(map[string]struct{}),
+		Reach:           map[string]struct{}{testNodePublicKey: {}},
+		Reputation:      p.Reputation,
+		Rejections:      p.Rejections,
+		Acceptances:     p.Acceptances,
+		Address:         p.Address,
+		TorExit:         p.TorExit,
+		Reachable:       p.Reachable,
+		SameIPChannels:  p.SameIP,
+		SameASNChannels: p.SameASN,
+	}
+	if p.FirstSeenAge > 0 {
+		facts.FirstSeen = now.Add(-p.FirstSeenAge)
+	}
+	if p.LastDecision > 0 {
+		facts.LastDecision = now.Add(-p.LastDecision)
+	}
+	return facts
+}
+
+func testPublicKey(p config.TestPeer) string {
+	if p.PublicKey != "" {
+		return p.PublicKey
+	}
+	return testPeerPublicKey
+}
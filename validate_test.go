@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/policy"
+	"github.com/aftermath2/acceptlnd/registry"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadNodeInfo(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	cases := []struct {
+		desc     string
+		path     string
+		channels int
+		fail     bool
+	}{
+		{
+			desc: "Node information",
+			path: write("node.json", `{"node": {"pub_key": "`+testPeerPublicKey+`"}, "num_channels": 1,
+				"channels": [{"channel_id": "1", "capacity": "1000000", "node1_policy": null}],
+				"unknown": true}`),
+			channels: 1,
+		},
+		{desc: "Missing file", path: filepath.Join(dir, "missing.json"), fail: true},
+		{desc: "Invalid JSON", path: write("invalid.json", "{"), fail: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			info, err := loadNodeInfo(tc.path)
+			if tc.fail {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testPeerPublicKey, info.Node.PubKey)
+			assert.Len(t, info.Channels, tc.channels)
+			assert.Nil(t, info.Channels[0].Node1Policy)
+		})
+	}
+}
+
+func TestRunPolicyTestNodeInfo(t *testing.T) {
+	dir := t.TempDir()
+	withNode := filepath.Join(dir, "node.json")
+	assert.NoError(t, os.WriteFile(withNode, []byte(`{"node": {"pub_key": "`+testPeerPublicKey+`"}}`), 0o600))
+	withoutNode := filepath.Join(dir, "empty.json")
+	assert.NoError(t, os.WriteFile(withoutNode, []byte(`{"num_channels": 3}`), 0o600))
+
+	reject := true
+	policies := []*policy.Policy{{Name: "private", RejectPrivateChannels: &reject}}
+	known := registry.New(nil)
+	now := time.Now()
+
+	cases := []struct {
+		desc     string
+		nodeInfo string
+		expect   config.TestExpect
+		fail     bool
+	}{
+		{
+			desc:     "Accepted",
+			nodeInfo: withNode,
+			expect:   config.TestExpect{Accept: true, Policy: "private"},
+		},
+		{
+			desc:     "Node missing",
+			nodeInfo: withoutNode,
+			expect:   config.TestExpect{Reason: "Internal server error"},
+		},
+		{
+			desc:     "Node missing expected to be accepted",
+			nodeInfo: withoutNode,
+			expect:   config.TestExpect{Accept: true},
+			fail:     true,
+		},
+		{
+			desc:     "Missing file",
+			nodeInfo: filepath.Join(dir, "missing.json"),
+			expect:   config.TestExpect{Accept: true},
+			fail:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			test := config.PolicyTest{
+				Name:    tc.desc,
+				Request: config.TestRequest{Capacity: 1_000_000},
+				Peer:    config.TestPeer{NodeInfo: tc.nodeInfo},
+				Expect:  tc.expect,
+			}
+			err := runPolicyTest(policies, known, test, now)
+			if tc.fail {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/graph"
+	"github.com/aftermath2/acceptlnd/metrics"
+	"github.com/aftermath2/acceptlnd/policy"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/pkg/errors"
+)
+
+// defaultWatchInterval is how often the peers are evaluated in watch-only mode by default.
+const defaultWatchInterval = 30 * time.Minute
+
+// watchDecision is the decision that would be taken if the node requested to open a channel.
+type watchDecision struct {
+	PublicKey   string    `json:"public_key"`
+	Alias       string    `json:"alias,omitempty"`
+	Accepted    bool      `json:"accepted"`
+	Error       string    `json:"error,omitempty"`
+	Policies    []string  `json:"policies,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Warnings    []string  `json:"warnings,omitempty"`
+	Reputation  float64   `json:"reputation"`
+	EvaluatedAt time.Time `json:"evaluated_at"`
+}
+
+// watcher evaluates peers periodically against the policies without handling channel requests,
+// so AcceptLND can be staged alongside another channel acceptor.
+type watcher struct {
+	acceptor  *acceptor
+	config    config.WatchOnly
+	decisions atomic.Pointer[[]watchDecision]
+}
+
+func newWatcher(acceptor *acceptor, config config.WatchOnly) *watcher {
+	if config.Interval == 0 {
+		config.Interval = defaultWatchInterval
+	}
+
+	w := &watcher{acceptor: acceptor, config: config}
+	w.decisions.Store(&[]watchDecision{})
+	return w
+}
+
+// getDecisions returns the decisions of the last evaluation.
+func (w *watcher) getDecisions() []watchDecision {
+	return *w.decisions.Load()
+}
+
+// run evaluates the peers every interval until the context is cancelled.
+func (w *watcher) run(ctx context.Context) error {
+	slog.Info("Watch-only mode, channel requests will not be handled",
+		slog.Duration("interval", w.config.Interval))
+
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.evaluate(ctx); err != nil {
+			slog.Warn("Evaluating peers", slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// evaluate takes a snapshot of the graph and evaluates the configured peers, or every node in the
+// graph if there are none, as if they requested to open a channel of the configured capacity.
+func (w *watcher) evaluate(ctx context.Context) error {
+	a := w.acceptor
+	node, err := a.client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	if err != nil {
+		return errors.Wrap(err, "getting node information")
+	}
+
+	channelGraph, err := a.client.DescribeGraph(ctx, &lnrpc.ChannelGraphRequest{})
+	if err != nil {
+		return errors.Wrap(err, "describing graph")
+	}
+	snapshot := graph.New(channelGraph)
+	network := newNeighborhood(snapshot, node.IdentityPubkey)
+
+	uptimes, err := w.channelUptimes(ctx)
+	if err != nil {
+		return err
+	}
+
+	publicKeys := w.config.Peers
+	if len(publicKeys) == 0 {
+		publicKeys = snapshot.PublicKeys()
+	}
+
+	policies := a.getPolicies()
+	now := time.Now()
+	decisions := make([]watchDecision, 0, len(publicKeys))
+	accepted := 0
+	for _, publicKey := range publicKeys {
+		if publicKey == node.IdentityPubkey {
+			continue
+		}
+
+		decision := watchDecision{PublicKey: publicKey, EvaluatedAt: now}
+		peer, ok := snapshot.NodeInfo(publicKey)
+		if !ok {
+			decision.Error = "Node not found in the graph"
+			decisions = append(decisions, decision)
+			continue
+		}
+		decision.Alias = peer.Node.Alias
+
+		facts := &policy.Facts{
+			Now:              now,
+			Peers:            network.peers,
+			Reach:            network.reach,
+			MaxChannelUptime: uptimes[publicKey],
+			KnownAs:          a.registry.Tags(publicKey),
+		}
+		if a.db != nil {
+			score, err := a.db.Score(publicKey)
+			if err != nil {
+				return err
+			}
+			facts.Reputation = score.At(now, a.halfLife)
+
+			history, err := a.db.History(publicKey)
+			if err != nil {
+				return err
+			}
+			setHistory(facts, history)
+		}
+		decision.Reputation = facts.Reputation
+
+		req := w.request(publicKey)
+		resp := &lnrpc.ChannelAcceptResponse{PendingChanId: req.PendingChanId}
+		evaluation, err := evaluatePolicies(policies, req, resp, node, peer, facts)
+		decision.Policies = evaluation.policies
+		decision.Tags = evaluation.tags
+		decision.Warnings = evaluation.warnings
+		if err != nil {
+			decision.Error = err.Error()
+		} else {
+			decision.Accepted = true
+			accepted++
+		}
+		decisions = append(decisions, decision)
+	}
+
+	w.decisions.Store(&decisions)
+	metrics.SetWatchDecisions(accepted, len(decisions)-accepted)
+	slog.Info("Peers evaluated", slog.Int("accepted", accepted),
+		slog.Int("rejected", len(decisions)-accepted))
+	return nil
+}
+
+// request returns the channel request the peer would send.
+func (w *watcher) request(publicKey string) *lnrpc.ChannelAcceptRequest {
+	nodePubkey, _ := hex.DecodeString(publicKey)
+	return &lnrpc.ChannelAcceptRequest{
+		NodePubkey:   nodePubkey,
+		FundingAmt:   w.config.ChannelCapacity,
+		ChannelFlags: uint32(lnwire.FFAnnounceChannel),
+	}
+}
+
+// channelUptimes returns the longest uptime of our channels with each peer.
+func (w *watcher) channelUptimes(ctx context.Context) (map[string]time.Duration, error) {
+	resp, err := w.acceptor.client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing channels")
+	}
+
+	uptimes := make(map[string]time.Duration, len(resp.Channels))
+	for _, channel := range resp.Channels {
+		if uptime := time.Duration(channel.Uptime) * time.Second; uptime > uptimes[channel.RemotePubkey] {
+			uptimes[channel.RemotePubkey] = uptime
+		}
+	}
+	return uptimes, nil
+}
@@ -0,0 +1,381 @@
+// Package webhook delivers acceptLND's decisions to HTTP endpoints: the webhook, which receives
+// every event, and the notification destinations, which receive the events matching their rules.
+// Events are persisted in a queue before being sent, so they survive outages of the endpoints and
+// restarts.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/store"
+
+	"github.com/pkg/errors"
+)
+
+// Retry delays, doubled after every failed attempt.
+const (
+	minBackoff = 5 * time.Second
+	maxBackoff = time.Hour
+)
+
+// pollInterval is how often the queue is checked for events due, new events are sent right away.
+const pollInterval = 10 * time.Second
+
+// DefaultTimeout is the time waited for the endpoint to respond when it's not configured.
+const DefaultTimeout = 10 * time.Second
+
+// Alert is an operational event sent along with the decisions, told apart from them by the alert
+// field.
+type Alert struct {
+	Alert   string    `json:"alert"`
+	Message string    `json:"message"`
+	At      time.Time `json:"at"`
+}
+
+// webhookDestination is the name of the webhook in the queue, the notification destinations
+// can't have an empty name.
+const webhookDestination = ""
+
+// Dispatcher posts the events in the queue to their destinations, retrying with backoff.
+type Dispatcher struct {
+	db           store.Storage
+	destinations map[string]*destination
+	rules        []config.NotifyRule
+	now          func() time.Time
+	wake         chan struct{}
+}
+
+type destination struct {
+	url         string
+	client      *http.Client
+	maxAttempts int
+	format      string
+	chatID      string
+}
+
+// New returns a dispatcher without destinations.
+func New(db store.Storage) *Dispatcher {
+	return &Dispatcher{
+		db:           db,
+		destinations: make(map[string]*destination),
+		now:          time.Now,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// AddWebhook registers the endpoint every event is sent to, the requests are sent with the client
+// received.
+func (d *Dispatcher) AddWebhook(config config.Webhook, client *http.Client) {
+	d.destinations[webhookDestination] = &destination{
+		url:         config.URL,
+		client:      client,
+		maxAttempts: config.MaxAttempts,
+	}
+}
+
+// AddDestination registers a destination the rules can send events to, the requests are sent with
+// the client received.
+func (d *Dispatcher) AddDestination(config config.Destination, client *http.Client) {
+	d.destinations[config.Name] = &destination{
+		url:         config.URL,
+		client:      client,
+		maxAttempts: config.MaxAttempts,
+		format:      config.Format,
+		chatID:      config.ChatID,
+	}
+}
+
+// AddRule sends the events matching the rule to its destination.
+func (d *Dispatcher) AddRule(rule config.NotifyRule) {
+	d.rules = append(d.rules, rule)
+}
+
+// Send queues the decision for delivery.
+func (d *Dispatcher) Send(decision store.Decision) error {
+	payload, err := json.Marshal(decision)
+	if err != nil {
+		return errors.Wrap(err, "encoding decision")
+	}
+
+	event := config.EventRejected
+	if decision.Accepted {
+		event = config.EventAccepted
+	}
+	return d.enqueue(payload, d.route(event, &decision))
+}
+
+// SendAlert queues the alert for delivery.
+func (d *Dispatcher) SendAlert(alert Alert) error {
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return errors.Wrap(err, "encoding alert")
+	}
+	return d.enqueue(payload, d.route(config.EventAlert, nil))
+}
+
+// route returns the names of the destinations the event is sent to. The decision is nil for
+// alerts.
+func (d *Dispatcher) route(event string, decision *store.Decision) []string {
+	var names []string
+	if _, ok := d.destinations[webhookDestination]; ok {
+		names = append(names, webhookDestination)
+	}
+	for _, rule := range d.rules {
+		if matches(rule, event, decision) && !slices.Contains(names, rule.Destination) {
+			names = append(names, rule.Destination)
+		}
+	}
+	return names
+}
+
+func matches(rule config.NotifyRule, event string, decision *store.Decision) bool {
+	if len(rule.Events) > 0 && !slices.Contains(rule.Events, event) {
+		return false
+	}
+	if decision == nil {
+		return rule.MinCapacity == 0 && rule.MaxCapacity == 0 && len(rule.Policies) == 0 &&
+			len(rule.Tags) == 0
+	}
+
+	if decision.Capacity < rule.MinCapacity || (rule.MaxCapacity != 0 && decision.Capacity > rule.MaxCapacity) {
+		return false
+	}
+	if len(rule.Policies) > 0 && !containsAny(decision.Policies, rule.Policies) {
+		return false
+	}
+	if len(rule.Tags) > 0 && !containsAny(decision.Tags, rule.Tags) {
+		return false
+	}
+	return true
+}
+
+func containsAny(values, wanted []string) bool {
+	for _, v := range values {
+		if slices.Contains(wanted, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Dispatcher) enqueue(payload []byte, destinations []string) error {
+	if len(destinations) == 0 {
+		return nil
+	}
+
+	for _, name := range destinations {
+		event := store.QueuedEvent{Destination: name, Payload: payload, NextAttempt: d.now()}
+		if err := d.db.Enqueue(event); err != nil {
+			return err
+		}
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run delivers the events that are due until the context is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.deliverDue(ctx); err != nil {
+			slog.Error("Delivering webhook events", slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// deliverDue attempts to deliver the events whose retry time has come, in order. After a failure,
+// the rest of the events of the same destination are skipped, it's likely down.
+func (d *Dispatcher) deliverDue(ctx context.Context) error {
+	events, err := d.db.Queue()
+	if err != nil {
+		return err
+	}
+
+	failed := make(map[string]bool)
+	for _, event := range events {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if failed[event.Destination] || event.NextAttempt.After(d.now()) {
+			continue
+		}
+		ok, err := d.attempt(ctx, event)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			failed[event.Destination] = true
+		}
+	}
+	return nil
+}
+
+// Flush attempts to deliver every queued event once, regardless of their retry times, and returns
+// the number of events delivered and the number still queued.
+func (d *Dispatcher) Flush(ctx context.Context) (delivered, queued int, err error) {
+	events, err := d.db.Queue()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, event := range events {
+		ok, err := d.attempt(ctx, event)
+		if err != nil {
+			return delivered, queued, err
+		}
+		if ok {
+			delivered++
+		} else {
+			queued++
+		}
+	}
+	return delivered, queued, nil
+}
+
+// Discard deletes every queued event without delivering them, and returns how many there were.
+func (d *Dispatcher) Discard() (int, error) {
+	events, err := d.db.Queue()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, event := range events {
+		if err := d.db.Dequeue(event.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(events), nil
+}
+
+// attempt posts the event and updates the queue with the result. It reports whether the event
+// was delivered, the error returned is only about the queue.
+func (d *Dispatcher) attempt(ctx context.Context, event store.QueuedEvent) (bool, error) {
+	dest, ok := d.destinations[event.Destination]
+	if !ok {
+		// The destination was removed from the configuration while the event was queued
+		slog.Warn("Discarding webhook event, unknown destination",
+			slog.String("destination", event.Destination))
+		return false, d.db.Dequeue(event.ID)
+	}
+
+	err := dest.post(ctx, event.Payload)
+	if err == nil {
+		return true, d.db.Dequeue(event.ID)
+	}
+
+	event.Attempts++
+	if dest.maxAttempts > 0 && event.Attempts >= dest.maxAttempts {
+		slog.Error("Discarding webhook event, maximum attempts reached",
+			slog.String("destination", event.Destination), slog.Int("attempts", event.Attempts),
+			slog.Any("error", err))
+		return false, d.db.Dequeue(event.ID)
+	}
+
+	event.NextAttempt = d.now().Add(backoff(event.Attempts))
+	slog.Warn("Delivering webhook event failed, retrying later",
+		slog.String("destination", event.Destination), slog.Int("attempts", event.Attempts),
+		slog.Time("next_attempt", event.NextAttempt), slog.Any("error", err))
+	return false, d.db.UpdateQueuedEvent(event)
+}
+
+func (d *destination) post(ctx context.Context, payload []byte) error {
+	if d.format == config.FormatTelegram {
+		var err error
+		payload, err = telegramMessage(d.chatID, payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// telegramMessage returns the body of the Bot API sendMessage request describing the event.
+func telegramMessage(chatID string, payload []byte) ([]byte, error) {
+	var alert Alert
+	if err := json.Unmarshal(payload, &alert); err != nil {
+		return nil, errors.Wrap(err, "decoding event")
+	}
+
+	var text string
+	if alert.Alert != "" {
+		text = fmt.Sprintf("Alert %s: %s", alert.Alert, alert.Message)
+	} else {
+		var decision store.Decision
+		if err := json.Unmarshal(payload, &decision); err != nil {
+			return nil, errors.Wrap(err, "decoding decision")
+		}
+		text = decisionText(decision)
+	}
+
+	return json.Marshal(struct {
+		ChatID string `json:"chat_id"`
+		Text   string `json:"text"`
+	}{ChatID: chatID, Text: text})
+}
+
+func decisionText(decision store.Decision) string {
+	var sb strings.Builder
+	if decision.Accepted {
+		sb.WriteString("Accepted")
+	} else {
+		sb.WriteString("Rejected")
+	}
+	fmt.Fprintf(&sb, " channel of %d sats from %s", decision.Capacity, decision.PublicKey)
+	if decision.Error != "" {
+		fmt.Fprintf(&sb, ": %s", decision.Error)
+	}
+	if len(decision.Policies) > 0 {
+		fmt.Fprintf(&sb, "\nPolicies: %s", strings.Join(decision.Policies, ", "))
+	}
+	if len(decision.Tags) > 0 {
+		fmt.Fprintf(&sb, "\nTags: %s", strings.Join(decision.Tags, ", "))
+	}
+	return sb.String()
+}
+
+// backoff returns the delay before the next delivery attempt.
+func backoff(attempts int) time.Duration {
+	delay := minBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
@@ -0,0 +1,285 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// endpoint records the decisions received, failing while down is true.
+type endpoint struct {
+	mu        sync.Mutex
+	down      bool
+	decisions []store.Decision
+}
+
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	var decision store.Decision
+	if err := json.NewDecoder(r.Body).Decode(&decision); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	e.decisions = append(e.decisions, decision)
+}
+
+func newDispatcher(t *testing.T, endpoint *endpoint, maxAttempts int) (*Dispatcher, store.Storage) {
+	t.Helper()
+
+	srv := httptest.NewServer(endpoint)
+	t.Cleanup(srv.Close)
+
+	db, err := store.Open(store.BackendBolt, filepath.Join(t.TempDir(), "acceptlnd.db"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	d := New(db)
+	d.AddWebhook(config.Webhook{URL: srv.URL, MaxAttempts: maxAttempts}, srv.Client())
+	return d, db
+}
+
+func TestDeliverDue(t *testing.T) {
+	ctx := context.Background()
+	e := &endpoint{down: true}
+	d, db := newDispatcher(t, e, 0)
+	now := time.Unix(1_700_000_000, 0)
+	d.now = func() time.Time { return now }
+
+	assert.NoError(t, d.Send(store.Decision{ID: "a"}))
+	assert.NoError(t, d.Send(store.Decision{ID: "b"}))
+
+	assert.NoError(t, d.deliverDue(ctx))
+	events, err := db.Queue()
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, 1, events[0].Attempts)
+	assert.Equal(t, now.Add(minBackoff), events[0].NextAttempt.Local())
+	// The rest are not attempted after a failure
+	assert.Equal(t, 0, events[1].Attempts)
+
+	e.down = false
+	assert.NoError(t, d.deliverDue(ctx))
+	assert.Len(t, e.decisions, 1)
+	assert.Equal(t, "b", e.decisions[0].ID)
+
+	now = now.Add(minBackoff)
+	assert.NoError(t, d.deliverDue(ctx))
+	assert.Len(t, e.decisions, 2)
+	events, err = db.Queue()
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestDeliverDueDestinations(t *testing.T) {
+	ctx := context.Background()
+	webhook := &endpoint{down: true}
+	d, db := newDispatcher(t, webhook, 0)
+
+	notifications := &endpoint{}
+	srv := httptest.NewServer(notifications)
+	t.Cleanup(srv.Close)
+	d.AddDestination(config.Destination{Name: "big", Webhook: config.Webhook{URL: srv.URL}}, srv.Client())
+	d.AddRule(config.NotifyRule{Events: []string{config.EventAccepted}, MinCapacity: 1_000_000, Destination: "big"})
+
+	assert.NoError(t, d.Send(store.Decision{ID: "a", Accepted: true, Capacity: 5_000_000}))
+	assert.NoError(t, d.Send(store.Decision{ID: "b", Accepted: true, Capacity: 500_000}))
+	assert.NoError(t, d.Send(store.Decision{ID: "c", Accepted: true, Capacity: 2_000_000}))
+
+	// The webhook being down doesn't delay the other destination
+	assert.NoError(t, d.deliverDue(ctx))
+	assert.Len(t, notifications.decisions, 2)
+	assert.Equal(t, "a", notifications.decisions[0].ID)
+	assert.Equal(t, "c", notifications.decisions[1].ID)
+
+	events, err := db.Queue()
+	assert.NoError(t, err)
+	assert.Len(t, events, 3)
+	for _, event := range events {
+		assert.Equal(t, webhookDestination, event.Destination)
+	}
+}
+
+func TestRoute(t *testing.T) {
+	d := New(nil)
+	for _, name := range []string{"log", "telegram"} {
+		d.AddDestination(config.Destination{Name: name}, http.DefaultClient)
+	}
+	d.AddRule(config.NotifyRule{Events: []string{config.EventRejected}, Destination: "log"})
+	d.AddRule(config.NotifyRule{Policies: []string{"whales"}, Destination: "telegram"})
+	d.AddRule(config.NotifyRule{Tags: []string{"lsp"}, MaxCapacity: 1_000_000, Destination: "telegram"})
+	d.AddRule(config.NotifyRule{Events: []string{config.EventAlert}, Destination: "telegram"})
+
+	cases := []struct {
+		desc     string
+		event    string
+		decision *store.Decision
+		expected []string
+	}{
+		{
+			desc:     "Rejected",
+			event:    config.EventRejected,
+			decision: &store.Decision{Capacity: 2_000_000},
+			expected: []string{"log"},
+		},
+		{
+			desc:     "Policy",
+			event:    config.EventAccepted,
+			decision: &store.Decision{Accepted: true, Policies: []string{"default", "whales"}},
+			expected: []string{"telegram"},
+		},
+		{
+			desc:     "Rejected by policy",
+			event:    config.EventRejected,
+			decision: &store.Decision{Policies: []string{"whales"}},
+			expected: []string{"log", "telegram"},
+		},
+		{
+			desc:     "Tag",
+			event:    config.EventAccepted,
+			decision: &store.Decision{Accepted: true, Capacity: 500_000, Tags: []string{"lsp"}},
+			expected: []string{"telegram"},
+		},
+		{
+			desc:     "Tag above maximum capacity",
+			event:    config.EventAccepted,
+			decision: &store.Decision{Accepted: true, Capacity: 2_000_000, Tags: []string{"lsp"}},
+		},
+		{
+			desc:     "Alert",
+			event:    config.EventAlert,
+			expected: []string{"telegram"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.expected, d.route(tc.event, tc.decision))
+		})
+	}
+
+	d.AddWebhook(config.Webhook{}, http.DefaultClient)
+	assert.Equal(t, []string{webhookDestination}, d.route(config.EventAccepted, &store.Decision{}))
+}
+
+func TestTelegramMessage(t *testing.T) {
+	cases := []struct {
+		desc     string
+		payload  any
+		expected string
+	}{
+		{
+			desc: "Accepted",
+			payload: store.Decision{
+				PublicKey: "02abc",
+				Capacity:  5_000_000,
+				Accepted:  true,
+				Policies:  []string{"whales"},
+			},
+			expected: "Accepted channel of 5000000 sats from 02abc\nPolicies: whales",
+		},
+		{
+			desc: "Rejected",
+			payload: store.Decision{
+				PublicKey: "02abc",
+				Capacity:  100_000,
+				Error:     "Channel capacity is too low",
+				Tags:      []string{"new"},
+			},
+			expected: "Rejected channel of 100000 sats from 02abc: Channel capacity is too low\nTags: new",
+		},
+		{
+			desc:     "Alert",
+			payload:  Alert{Alert: "response_slo", Message: "Slow responses"},
+			expected: "Alert response_slo: Slow responses",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			payload, err := json.Marshal(tc.payload)
+			assert.NoError(t, err)
+
+			body, err := telegramMessage("-100123", payload)
+			assert.NoError(t, err)
+
+			var message struct {
+				ChatID string `json:"chat_id"`
+				Text   string `json:"text"`
+			}
+			assert.NoError(t, json.Unmarshal(body, &message))
+			assert.Equal(t, "-100123", message.ChatID)
+			assert.Equal(t, tc.expected, message.Text)
+		})
+	}
+}
+
+func TestSendAlert(t *testing.T) {
+	d, db := newDispatcher(t, &endpoint{}, 0)
+	at := time.Unix(1_700_000_000, 0).UTC()
+
+	assert.NoError(t, d.SendAlert(Alert{Alert: "response_slo", Message: "Slow responses", At: at}))
+	events, err := db.Queue()
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.JSONEq(t, `{"alert":"response_slo","message":"Slow responses","at":"2023-11-14T22:13:20Z"}`,
+		string(events[0].Payload))
+}
+
+func TestMaxAttempts(t *testing.T) {
+	d, db := newDispatcher(t, &endpoint{down: true}, 2)
+
+	assert.NoError(t, d.Send(store.Decision{ID: "a"}))
+	delivered, queued, err := d.Flush(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+	assert.Equal(t, 1, queued)
+
+	_, _, err = d.Flush(context.Background())
+	assert.NoError(t, err)
+	events, err := db.Queue()
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestFlushAndDiscard(t *testing.T) {
+	e := &endpoint{}
+	d, _ := newDispatcher(t, e, 0)
+
+	for _, id := range []string{"a", "b", "c"} {
+		assert.NoError(t, d.Send(store.Decision{ID: id}))
+	}
+	delivered, queued, err := d.Flush(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, delivered)
+	assert.Equal(t, 0, queued)
+	assert.Len(t, e.decisions, 3)
+
+	assert.NoError(t, d.Send(store.Decision{ID: "d"}))
+	discarded, err := d.Discard()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, discarded)
+	assert.Len(t, e.decisions, 3)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, minBackoff, backoff(1))
+	assert.Equal(t, 4*minBackoff, backoff(3))
+	assert.Equal(t, maxBackoff, backoff(100))
+}
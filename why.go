@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/config"
+	"github.com/aftermath2/acceptlnd/lightning"
+	"github.com/aftermath2/acceptlnd/store"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/pkg/errors"
+)
+
+// runWhy prints the decision that accepted the open channels matching a channel point or a peer
+// public key.
+func runWhy(args []string) error {
+	fs := flag.NewFlagSet("why", flag.ExitOnError)
+	configPath := fs.String("config", "acceptlnd.yml", "Path to the configuration file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: acceptlnd why [-config path] <channel point|public key>")
+	}
+	target := fs.Arg(0)
+	if !strings.Contains(target, ":") {
+		if b, err := hex.DecodeString(target); err != nil || len(b) != 33 {
+			return errors.Errorf("invalid channel point or public key %q", target)
+		}
+	}
+
+	config, err := config.Load(*configPath)
+	if err != nil {
+		return err
+	}
+	if config.DatabasePath == "" || config.DatabaseBackend == store.BackendMemory {
+		return errors.New("the configuration has no database_path, decisions are not recorded")
+	}
+
+	client, err := lightning.NewClient(config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	resp, err := client.ListChannels(context.Background(), &lnrpc.ListChannelsRequest{})
+	if err != nil {
+		return errors.Wrap(err, "listing channels")
+	}
+
+	var channels []*lnrpc.Channel
+	for _, channel := range resp.Channels {
+		if channel.ChannelPoint == target || channel.RemotePubkey == target {
+			channels = append(channels, channel)
+		}
+	}
+	if len(channels) == 0 {
+		return errors.Errorf("no open channel matches %q", target)
+	}
+
+	db, err := store.OpenReadOnly(config.DatabaseBackend, config.DatabasePath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tags, err := db.Tags()
+	if err != nil {
+		return err
+	}
+	decisions, err := db.Decisions(time.Time{})
+	if err != nil {
+		return err
+	}
+
+	for i, channel := range channels {
+		if i > 0 {
+			fmt.Println()
+		}
+		tag, tagged := tags[channel.ChannelPoint]
+		writeWhy(os.Stdout, channel, acceptance(decisions, channel, tag, tagged))
+	}
+	return nil
+}
+
+// acceptance returns the decision that accepted the channel, nil if there is none. The decisions
+// are not linked to channel points, so they are matched by peer and capacity, picking the closest
+// one to the time the channel was tagged or the latest one if it wasn't.
+func acceptance(
+	decisions []store.Decision,
+	channel *lnrpc.Channel,
+	tag store.Tag,
+	tagged bool,
+) *store.Decision {
+	var match *store.Decision
+	for i := len(decisions) - 1; i >= 0; i-- {
+		d := &decisions[i]
+		if !d.Accepted || d.PublicKey != channel.RemotePubkey || uint64(channel.Capacity) != d.Capacity {
+			continue
+		}
+		if !tagged {
+			return d
+		}
+		if match == nil || absDuration(d.At.Sub(tag.AcceptedAt)) < absDuration(match.At.Sub(tag.AcceptedAt)) {
+			match = d
+		}
+	}
+	return match
+}
+
+func writeWhy(w io.Writer, channel *lnrpc.Channel, decision *store.Decision) {
+	fmt.Fprintf(w, "%s\n", channel.ChannelPoint)
+	fmt.Fprintf(w, "  peer:      %s\n", channel.RemotePubkey)
+	fmt.Fprintf(w, "  capacity:  %d\n", channel.Capacity)
+
+	if decision == nil {
+		if channel.Initiator {
+			fmt.Fprintln(w, "  No decision recorded, the channel was opened by us")
+		} else {
+			fmt.Fprintln(w, "  No decision recorded, the channel may predate the database")
+		}
+		return
+	}
+
+	fmt.Fprintf(w, "  accepted:  %s\n", decision.At.Format(time.RFC3339))
+	if decision.Backfilled {
+		fmt.Fprintln(w, "  Backfilled, the channel was opened before decisions were recorded")
+		return
+	}
+	if decision.Unmanaged {
+		fmt.Fprintln(w, "  Unmanaged, LND accepted the channel with its defaults while acceptLND was not connected")
+		return
+	}
+	fmt.Fprintf(w, "  request:   %s\n", decision.ID)
+	if len(decision.Policies) > 0 {
+		fmt.Fprintf(w, "  policies:  %s\n", strings.Join(decision.Policies, ", "))
+	}
+	if len(decision.Tags) > 0 {
+		fmt.Fprintf(w, "  tags:      %s\n", strings.Join(decision.Tags, ", "))
+	}
+	for _, warning := range decision.Warnings {
+		fmt.Fprintf(w, "  warning:   %s\n", warning)
+	}
+	if r := decision.Response; r != nil {
+		fmt.Fprintf(w, "  response:  %s\n", formatResponse(r))
+	}
+}
+
+// formatResponse lists the parameters of the response that were not left to LND's defaults.
+func formatResponse(r *store.Response) string {
+	var params []string
+	add := func(key string, value uint64) {
+		if value != 0 {
+			params = append(params, fmt.Sprintf("%s=%d", key, value))
+		}
+	}
+	add("min_accept_depth", uint64(r.MinAcceptDepth))
+	add("csv_delay", uint64(r.CSVDelay))
+	add("reserve_sat", r.ReserveSat)
+	add("in_flight_max_msat", r.InFlightMaxMsat)
+	add("max_htlc_count", uint64(r.MaxHTLCCount))
+	add("min_htlc_in", r.MinHTLCIn)
+	if r.ZeroConf {
+		params = append(params, "zero_conf")
+	}
+	if r.UpfrontShutdown != "" {
+		params = append(params, "upfront_shutdown="+r.UpfrontShutdown)
+	}
+	if len(params) == 0 {
+		return "defaults"
+	}
+	return strings.Join(params, " ")
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/aftermath2/acceptlnd/store"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcceptance(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	decisions := []store.Decision{
+		{ID: "old", PublicKey: "peer", Capacity: 1_000_000, Accepted: true, At: at},
+		{ID: "rejected", PublicKey: "peer", Capacity: 1_000_000, At: at.Add(time.Hour)},
+		{ID: "other capacity", PublicKey: "peer", Capacity: 2_000_000, Accepted: true, At: at.Add(time.Hour)},
+		{ID: "other peer", PublicKey: "other", Capacity: 1_000_000, Accepted: true, At: at.Add(time.Hour)},
+		{ID: "closest", PublicKey: "peer", Capacity: 1_000_000, Accepted: true, At: at.Add(2 * time.Hour)},
+		{ID: "latest", PublicKey: "peer", Capacity: 1_000_000, Accepted: true, At: at.Add(24 * time.Hour)},
+	}
+	channel := &lnrpc.Channel{RemotePubkey: "peer", Capacity: 1_000_000}
+
+	cases := []struct {
+		desc     string
+		channel  *lnrpc.Channel
+		tag      store.Tag
+		tagged   bool
+		expected string
+	}{
+		{
+			desc:     "Untagged",
+			channel:  channel,
+			expected: "latest",
+		},
+		{
+			desc:     "Tagged",
+			channel:  channel,
+			tag:      store.Tag{AcceptedAt: at.Add(3 * time.Hour)},
+			tagged:   true,
+			expected: "closest",
+		},
+		{
+			desc:     "Tagged before the decisions",
+			channel:  channel,
+			tag:      store.Tag{AcceptedAt: at.Add(-time.Hour)},
+			tagged:   true,
+			expected: "old",
+		},
+		{
+			desc:    "No match",
+			channel: &lnrpc.Channel{RemotePubkey: "peer", Capacity: 3_000_000},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			decision := acceptance(decisions, tc.channel, tc.tag, tc.tagged)
+			if tc.expected == "" {
+				assert.Nil(t, decision)
+				return
+			}
+			assert.Equal(t, tc.expected, decision.ID)
+		})
+	}
+}
+
+func TestWriteWhy(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	channel := &lnrpc.Channel{ChannelPoint: "txid:0", RemotePubkey: "peer", Capacity: 1_000_000}
+	header := "txid:0\n  peer:      peer\n  capacity:  1000000\n"
+
+	cases := []struct {
+		desc     string
+		channel  *lnrpc.Channel
+		decision *store.Decision
+		expected string
+	}{
+		{
+			desc:     "Remote without decision",
+			channel:  channel,
+			expected: header + "  No decision recorded, the channel may predate the database\n",
+		},
+		{
+			desc: "Local without decision",
+			channel: &lnrpc.Channel{
+				ChannelPoint: "txid:0",
+				RemotePubkey: "peer",
+				Capacity:     1_000_000,
+				Initiator:    true,
+			},
+			expected: header + "  No decision recorded, the channel was opened by us\n",
+		},
+		{
+			desc:     "Backfilled",
+			channel:  channel,
+			decision: &store.Decision{Backfilled: true, At: at},
+			expected: header + "  accepted:  2024-01-01T00:00:00Z\n" +
+				"  Backfilled, the channel was opened before decisions were recorded\n",
+		},
+		{
+			desc:     "Unmanaged",
+			channel:  channel,
+			decision: &store.Decision{Unmanaged: true, At: at},
+			expected: header + "  accepted:  2024-01-01T00:00:00Z\n" +
+				"  Unmanaged, LND accepted the channel with its defaults while acceptLND was not connected\n",
+		},
+		{
+			desc:    "Decision",
+			channel: channel,
+			decision: &store.Decision{
+				ID:       "id",
+				Policies: []string{"lsp", "#1"},
+				Tags:     []string{"lsp"},
+				Warnings: []string{"Channel capacity"},
+				Response: &store.Response{MinAcceptDepth: 3},
+				At:       at,
+			},
+			expected: header + "  accepted:  2024-01-01T00:00:00Z\n" +
+				"  request:   id\n" +
+				"  policies:  lsp, #1\n" +
+				"  tags:      lsp\n" +
+				"  warning:   Channel capacity\n" +
+				"  response:  min_accept_depth=3\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			writeWhy(&buf, tc.channel, tc.decision)
+			assert.Equal(t, tc.expected, buf.String())
+		})
+	}
+}
+
+func TestFormatResponse(t *testing.T) {
+	cases := []struct {
+		desc     string
+		response store.Response
+		expected string
+	}{
+		{desc: "Defaults", expected: "defaults"},
+		{
+			desc: "Parameters",
+			response: store.Response{
+				CSVDelay:        144,
+				ReserveSat:      10_000,
+				InFlightMaxMsat: 1_000_000,
+				MaxHTLCCount:    30,
+				MinHTLCIn:       1,
+				MinAcceptDepth:  3,
+				ZeroConf:        true,
+				UpfrontShutdown: "bc1q",
+			},
+			expected: "min_accept_depth=3 csv_delay=144 reserve_sat=10000 in_flight_max_msat=1000000 " +
+				"max_htlc_count=30 min_htlc_in=1 zero_conf upfront_shutdown=bc1q",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.expected, formatResponse(&tc.response))
+		})
+	}
+}